@@ -0,0 +1,114 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"math/rand"
+	"reflect"
+	"runtime/debug"
+	"time"
+)
+
+// ContainerName is the name of the scope in which a constructor is called,
+// or an empty string for root containers. See WithAmbientValues.
+type ContainerName string
+
+// BuildInfo is the build information embedded in the running binary, as
+// returned by debug.ReadBuildInfo. See WithAmbientValues.
+type BuildInfo debug.BuildInfo
+
+// StartTime is the time at which the root container was created. See
+// WithAmbientValues.
+type StartTime time.Time
+
+var (
+	_randType          = reflect.TypeOf((*rand.Rand)(nil))
+	_containerNameType = reflect.TypeOf(ContainerName(""))
+	_buildInfoType     = reflect.TypeOf(BuildInfo{})
+	_startTimeType     = reflect.TypeOf(StartTime{})
+)
+
+// WithAmbientValues is an Option that lets the container provide values of
+// a few well-known types that nothing else provides:
+//
+//   *rand.Rand     the source of randomness of the container
+//   ContainerName  the name of the scope the constructor was provided to
+//   BuildInfo      the build information of the binary, if it has any
+//   StartTime      the time at which the root container was created
+//
+// Constructors provided to the container or its parents take precedence
+// over ambient values, even if they are provided after an ambient value was
+// used: values built from the ambient value are then built again.
+//
+//   c := dig.New(dig.WithAmbientValues())
+//   c.Provide(func(name dig.ContainerName) *Logger {
+//     return newLogger(string(name))
+//   })
+//
+// Ambient values are drawn as dashed "ambient" constructors by Visualize.
+func WithAmbientValues() Option {
+	return optionFunc(func(c *Container) {
+		c.ambientValues = true
+	})
+}
+
+// ambientConstructor returns a constructor of the ambient value of type t
+// for this container, or nil if there is none.
+func (c *Container) ambientConstructor(t reflect.Type) interface{} {
+	switch t {
+	case _randType:
+		return func() *rand.Rand { return c.rand }
+	case _containerNameType:
+		return func() ContainerName { return ContainerName(c.name) }
+	case _buildInfoType:
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return nil
+		}
+		return func() BuildInfo { return BuildInfo(*info) }
+	case _startTimeType:
+		return func() StartTime { return StartTime(c.startTime) }
+	}
+	return nil
+}
+
+// resolveAmbient provides the constructor of the ambient value of type t if
+// this container was created with WithAmbientValues, and reports whether
+// there is one.
+func (c *Container) resolveAmbient(t reflect.Type) (bool, error) {
+	if !c.ambientValues {
+		return false, nil
+	}
+	ctor := c.ambientConstructor(t)
+	if ctor == nil {
+		return false, nil
+	}
+	if err := c.provide(ctor, provideOptions{IfNotPresent: true, Ambient: true}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// isAmbient reports whether p provides an ambient value.
+func isAmbient(p provider) bool {
+	n, ok := p.(*node)
+	return ok && n.ambient
+}
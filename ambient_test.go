@@ -0,0 +1,110 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"math/rand"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmbientValues(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		c := New()
+		err := c.Invoke(func(ContainerName) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "type dig.ContainerName is not in the container")
+	})
+
+	t.Run("values", func(t *testing.T) {
+		r := rand.New(rand.NewSource(1))
+		before := time.Now()
+		c := New(WithAmbientValues(), setRand(r))
+
+		require.NoError(t, c.Invoke(func(got *rand.Rand, name ContainerName, start StartTime) {
+			assert.True(t, got == r, "must receive the rand of the container")
+			assert.Empty(t, name)
+			assert.False(t, time.Time(start).Before(before))
+			assert.False(t, time.Time(start).After(time.Now()))
+		}))
+
+		if info, ok := debug.ReadBuildInfo(); ok {
+			require.NoError(t, c.Invoke(func(got BuildInfo) {
+				assert.Equal(t, info.GoVersion, got.GoVersion)
+			}))
+		}
+
+		g := Graph(c)
+		require.NotEmpty(t, g.Ctors)
+		for _, ctor := range g.Ctors {
+			assert.True(t, ctor.Ambient, "%v must be ambient", ctor.Name)
+		}
+	})
+
+	t.Run("scope", func(t *testing.T) {
+		c := New(WithAmbientValues())
+		var rootStart StartTime
+		require.NoError(t, c.Invoke(func(s StartTime) { rootStart = s }))
+
+		s := c.Scope("request")
+		require.NoError(t, s.Invoke(func(name ContainerName, start StartTime) {
+			assert.Equal(t, ContainerName("request"), name)
+			assert.Equal(t, rootStart, start)
+		}))
+	})
+
+	t.Run("provided before", func(t *testing.T) {
+		c := New(WithAmbientValues())
+		require.NoError(t, c.Provide(func() ContainerName { return "app" }))
+
+		require.NoError(t, c.Invoke(func(name ContainerName) {
+			assert.Equal(t, ContainerName("app"), name)
+		}))
+	})
+
+	t.Run("provided after use", func(t *testing.T) {
+		type logger struct{ name ContainerName }
+
+		c := New(WithAmbientValues())
+		require.NoError(t, c.Provide(func(name ContainerName) *logger { return &logger{name: name} }))
+		require.NoError(t, c.Invoke(func(l *logger) {
+			assert.Empty(t, l.name)
+		}))
+
+		require.NoError(t, c.Provide(func() ContainerName { return "app" }))
+		require.NoError(t, c.Invoke(func(l *logger) {
+			assert.Equal(t, ContainerName("app"), l.name, "values built from ambient values must be rebuilt")
+		}))
+	})
+
+	t.Run("provided by parent", func(t *testing.T) {
+		c := New(WithAmbientValues())
+		require.NoError(t, c.Provide(func() ContainerName { return "app" }))
+
+		require.NoError(t, c.Scope("request").Invoke(func(name ContainerName) {
+			assert.Equal(t, ContainerName("app"), name)
+		}))
+	})
+}
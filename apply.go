@@ -0,0 +1,206 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// WiringSpec describes constructors to provide to a container by the names
+// under which they are registered, for example in a configuration file. See
+// Container.Apply.
+type WiringSpec struct {
+	Constructors []WiringEntry `json:"constructors"`
+}
+
+// WiringEntry is a constructor in a WiringSpec.
+type WiringEntry struct {
+	// ID identifies this entry across calls to Apply. It defaults to the
+	// name of the constructor, so it only needs to be set if the same
+	// constructor is provided more than once.
+	ID string `json:"id,omitempty"`
+
+	// Name of the constructor in the registry passed to Apply.
+	Constructor string `json:"constructor"`
+
+	// Options for the constructor. See the Name and Group ProvideOptions.
+	Name   string   `json:"name,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+func (e WiringEntry) id() string {
+	if e.ID != "" {
+		return e.ID
+	}
+	return e.Constructor
+}
+
+// WiringEntryError is a failure to parse or apply a single entry of a
+// WiringSpec. Apply and ParseWiringSpec report all failed entries in a
+// MultiError.
+type WiringEntryError struct {
+	// Position of the entry in the spec, or -1 for entries of previous
+	// specs that are missing from the spec.
+	Index int
+
+	ID     string
+	Reason error
+}
+
+func (e WiringEntryError) cause() error  { return e.Reason }
+func (e WiringEntryError) Unwrap() error { return e.Reason }
+
+func (e WiringEntryError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("entry %q: %v", e.ID, e.Reason)
+	}
+	return fmt.Sprintf("entry %d (%q): %v", e.Index, e.ID, e.Reason)
+}
+
+// ParseWiringSpec reads a WiringSpec in JSON format from r. Entries that
+// can't be parsed are reported individually.
+func ParseWiringSpec(r io.Reader) (WiringSpec, error) {
+	var doc struct {
+		Constructors []json.RawMessage `json:"constructors"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return WiringSpec{}, errWrapf(err, "failed to read wiring spec")
+	}
+
+	var (
+		spec WiringSpec
+		errs []error
+	)
+	for i, raw := range doc.Constructors {
+		var e WiringEntry
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&e)
+		if err == nil && e.Constructor == "" {
+			err = errors.New("constructor is required")
+		}
+		if err != nil {
+			errs = append(errs, WiringEntryError{Index: i, ID: e.id(), Reason: err})
+			continue
+		}
+		spec.Constructors = append(spec.Constructors, e)
+	}
+	if len(errs) > 0 {
+		return WiringSpec{}, newWiringSpecError("failed to parse %d entries of the wiring spec:", errs)
+	}
+	return spec, nil
+}
+
+// Apply provides the constructors described by the given spec to the
+// container. Constructors are looked up by name in the given registry.
+//
+//   registry := map[string]interface{}{
+//     "logger": newLogger,
+//     "server": newServer,
+//   }
+//   spec, err := dig.ParseWiringSpec(f)
+//   // ...
+//   err = c.Apply(spec, registry)
+//
+// Apply is idempotent: entries applied by a previous call are skipped if
+// they did not change. Constructors cannot be removed from a container once
+// they were provided, so changing or leaving out an entry that was already
+// applied fails.
+//
+// Entries that can't be applied, for example because their constructor is
+// not in the registry, are reported together in a MultiError of
+// WiringEntryErrors. The other entries are applied regardless.
+func (c *Container) Apply(spec WiringSpec, registry map[string]interface{}) error {
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+
+	var errs []error
+	seen := make(map[string]struct{}, len(spec.Constructors))
+	for i, e := range spec.Constructors {
+		id := e.id()
+		if _, ok := seen[id]; ok {
+			errs = append(errs, WiringEntryError{Index: i, ID: id, Reason: errors.New(
+				"entry is listed more than once, set distinct IDs to provide a constructor more than once")})
+			continue
+		}
+		seen[id] = struct{}{}
+
+		if err := c.applyEntry(e, registry); err != nil {
+			errs = append(errs, WiringEntryError{Index: i, ID: id, Reason: err})
+		}
+	}
+
+	for _, e := range c.appliedEntries {
+		if _, ok := seen[e.id()]; !ok {
+			errs = append(errs, WiringEntryError{Index: -1, ID: e.id(), Reason: errors.New(
+				"cannot remove an entry that was already applied")})
+		}
+	}
+
+	if len(errs) > 0 {
+		return newWiringSpecError("failed to apply %d entries of the wiring spec:", errs)
+	}
+	return nil
+}
+
+func (c *Container) applyEntry(e WiringEntry, registry map[string]interface{}) error {
+	for _, applied := range c.appliedEntries {
+		if applied.id() != e.id() {
+			continue
+		}
+		if !reflect.DeepEqual(applied, e) {
+			return errors.New("cannot change an entry that was already applied")
+		}
+		return nil
+	}
+
+	ctor, ok := registry[e.Constructor]
+	if !ok {
+		return fmt.Errorf("constructor %q is not in the registry", e.Constructor)
+	}
+
+	var opts []ProvideOption
+	if e.Name != "" {
+		opts = append(opts, Name(e.Name))
+	}
+	for _, g := range e.Groups {
+		opts = append(opts, Group(g))
+	}
+	if err := c.Provide(ctor, opts...); err != nil {
+		return err
+	}
+
+	c.appliedEntries = append(c.appliedEntries, e)
+	return nil
+}
+
+func newWiringSpecError(header string, errs []error) MultiError {
+	err := newMultiError(errs)
+	err.header = fmt.Sprintf(header, len(errs))
+	err.prefix = "\n\t"
+	return err
+}
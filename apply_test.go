@@ -0,0 +1,148 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	type in struct {
+		In
+
+		Primary *bytes.Buffer   `name:"primary"`
+		All     []*bytes.Buffer `group:"buffers"`
+	}
+
+	registry := map[string]interface{}{
+		"buffer": func() *bytes.Buffer { return new(bytes.Buffer) },
+		"string": func() string { return "hello" },
+	}
+
+	t.Run("provides constructors", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Apply(WiringSpec{Constructors: []WiringEntry{
+			{Constructor: "buffer", Name: "primary"},
+			{ID: "grouped", Constructor: "buffer", Groups: []string{"buffers"}},
+			{Constructor: "string"},
+		}}, registry))
+
+		require.NoError(t, c.Invoke(func(in in, s string) {
+			assert.NotNil(t, in.Primary)
+			assert.Len(t, in.All, 1)
+			assert.Equal(t, "hello", s)
+		}))
+	})
+
+	t.Run("idempotent", func(t *testing.T) {
+		c := New()
+		spec := WiringSpec{Constructors: []WiringEntry{{Constructor: "string"}}}
+		require.NoError(t, c.Apply(spec, registry))
+		require.NoError(t, c.Apply(spec, registry), "applying the same spec again must be a no-op")
+
+		spec.Constructors = append(spec.Constructors, WiringEntry{Constructor: "buffer"})
+		require.NoError(t, c.Apply(spec, registry), "new entries must be applied")
+		assert.Equal(t, 2, c.Metrics().Providers)
+	})
+
+	t.Run("entry errors", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Apply(WiringSpec{Constructors: []WiringEntry{
+			{Constructor: "string"},
+			{Constructor: "buffer"},
+		}}, registry))
+
+		err := c.Apply(WiringSpec{Constructors: []WiringEntry{
+			{Constructor: "string", Name: "changed"},
+			{Constructor: "unknown"},
+			{Constructor: "unknown"},
+		}}, registry)
+		require.Error(t, err)
+		assert.Equal(t, `failed to apply 4 entries of the wiring spec:`+
+			"\n\t"+`entry 0 ("string"): cannot change an entry that was already applied`+
+			"\n\t"+`entry 1 ("unknown"): constructor "unknown" is not in the registry`+
+			"\n\t"+`entry 2 ("unknown"): entry is listed more than once, set distinct IDs to provide a constructor more than once`+
+			"\n\t"+`entry "buffer": cannot remove an entry that was already applied`,
+			err.Error())
+
+		var multiErr MultiError
+		require.True(t, errors.As(err, &multiErr))
+		require.Len(t, multiErr.Unwrap(), 4)
+		var entryErr WiringEntryError
+		require.True(t, errors.As(multiErr.Unwrap()[1], &entryErr))
+		assert.Equal(t, 1, entryErr.Index)
+		assert.Equal(t, "unknown", entryErr.ID)
+	})
+
+	t.Run("provide errors", func(t *testing.T) {
+		c := New()
+		err := c.Apply(WiringSpec{Constructors: []WiringEntry{
+			{Constructor: "string"},
+			{ID: "again", Constructor: "string"},
+		}}, registry)
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`failed to apply 1 entries of the wiring spec:`,
+			`entry 1 \("again"\): function "go.uber.org/dig".TestApply\S+ \(\S+:\d+\) cannot be provided:`,
+			`cannot provide string from \[0\]: already provided by`,
+		)
+	})
+}
+
+func TestParseWiringSpec(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		spec, err := ParseWiringSpec(strings.NewReader(`{"constructors": [
+			{"constructor": "buffer", "name": "primary"},
+			{"id": "grouped", "constructor": "buffer", "groups": ["buffers"]}
+		]}`))
+		require.NoError(t, err)
+		assert.Equal(t, WiringSpec{Constructors: []WiringEntry{
+			{Constructor: "buffer", Name: "primary"},
+			{ID: "grouped", Constructor: "buffer", Groups: []string{"buffers"}},
+		}}, spec)
+	})
+
+	t.Run("invalid entries", func(t *testing.T) {
+		_, err := ParseWiringSpec(strings.NewReader(`{"constructors": [
+			{"constructor": "buffer"},
+			{"constructor": "buffer", "nmae": "primary"},
+			{"name": "primary"}
+		]}`))
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`failed to parse 2 entries of the wiring spec:`,
+			`entry 1 \("buffer"\): json: unknown field "nmae"`,
+			`entry 2 \(""\): constructor is required`,
+		)
+	})
+
+	t.Run("invalid document", func(t *testing.T) {
+		_, err := ParseWiringSpec(strings.NewReader(`[`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read wiring spec")
+	})
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+)
+
+// AuditTrail is an InvokeOption that fills the given audit with how each
+// value needed by the Invoke was obtained: from the container's cache, by
+// calling its constructor, or as the zero value of an optional dependency
+// that nothing provides.
+//
+//   var audit dig.InvokeAudit
+//   err := c.Invoke(handleRequest, dig.AuditTrail(&audit))
+//   audit.WriteJSONLines(auditLog)
+//
+// The audit is filled in as the Invoke runs, so it's complete up to the
+// failure if the Invoke fails. Invokes without this option don't pay for it.
+func AuditTrail(audit *InvokeAudit) InvokeOption {
+	return invokeOptionFunc(func(opts *invokeOptions) {
+		opts.Audit = audit
+	})
+}
+
+// AuditKind describes how a value was obtained during an Invoke.
+type AuditKind int
+
+const (
+	// AuditCached values were already in the container.
+	AuditCached AuditKind = iota + 1
+
+	// AuditConstructed values were built by calling their constructor
+	// during the Invoke.
+	AuditConstructed
+
+	// AuditDefaulted values are optional dependencies that nothing provides,
+	// and empty value groups. They were filled with zero values.
+	AuditDefaulted
+)
+
+func (k AuditKind) String() string {
+	switch k {
+	case AuditCached:
+		return "cached"
+	case AuditConstructed:
+		return "constructed"
+	case AuditDefaulted:
+		return "defaulted"
+	default:
+		return "unknown"
+	}
+}
+
+// AuditEntry describes how a value was obtained during an Invoke.
+type AuditEntry struct {
+	Key  Key
+	Kind AuditKind
+
+	// Name, package, and location of the constructor that built the value,
+	// the time at which it was called, and the time it took. These are only
+	// set for constructed values.
+	Name     string
+	Package  string
+	File     string
+	Line     int
+	Time     time.Time
+	Duration time.Duration
+}
+
+// InvokeAudit lists how the values touched by an Invoke were obtained. See
+// AuditTrail.
+type InvokeAudit struct {
+	// Entries in the order in which the values were first touched. Each key
+	// is listed at most once.
+	//
+	// Constructors also list the values they produce that weren't needed by
+	// the Invoke.
+	Entries []AuditEntry
+
+	seen map[key]struct{}
+
+	// Keys of the values and value groups handed to the functions that
+	// depend on them. See RecordDependencies.
+	resolved map[key]struct{}
+}
+
+// add records the given entry unless its key was already recorded.
+func (a *InvokeAudit) add(k key, e AuditEntry) {
+	if _, ok := a.seen[k]; ok {
+		return
+	}
+	if a.seen == nil {
+		a.seen = make(map[key]struct{})
+	}
+	a.seen[k] = struct{}{}
+	e.Key = k.exported()
+	a.Entries = append(a.Entries, e)
+}
+
+// resolve records that the value or value group with the given key was handed
+// to a function that depends on it.
+func (a *InvokeAudit) resolve(k key) {
+	if a.resolved == nil {
+		a.resolved = make(map[key]struct{})
+	}
+	a.resolved[k] = struct{}{}
+}
+
+// constructed records the values produced by a call to the constructor of
+// the given node.
+func (a *InvokeAudit) constructed(n *node, sr *stagingContainerWriter) {
+	for _, k := range sr.sortedKeys() {
+		a.add(k, AuditEntry{
+			Kind:     AuditConstructed,
+			Name:     n.location.Name,
+			Package:  n.location.Package,
+			File:     n.location.File,
+			Line:     n.location.Line,
+			Time:     sr.built,
+			Duration: sr.duration,
+		})
+	}
+}
+
+// auditLine is the JSON representation of an AuditEntry.
+type auditLine struct {
+	Key      string     `json:"key"`
+	Kind     string     `json:"kind"`
+	Name     string     `json:"name,omitempty"`
+	Package  string     `json:"package,omitempty"`
+	File     string     `json:"file,omitempty"`
+	Line     int        `json:"line,omitempty"`
+	Time     *time.Time `json:"time,omitempty"`
+	Duration int64      `json:"durationNanos,omitempty"`
+}
+
+// WriteJSONLines writes the entries of the audit to w as JSON objects, one
+// per line.
+//
+//   {"key":"*sql.DB","kind":"cached"}
+//   {"key":"*http.Server","kind":"constructed","name":"NewServer",...}
+func (a *InvokeAudit) WriteJSONLines(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, e := range a.Entries {
+		line := auditLine{
+			Key:      e.Key.String(),
+			Kind:     e.Kind.String(),
+			Name:     e.Name,
+			Package:  e.Package,
+			File:     e.File,
+			Line:     e.Line,
+			Duration: int64(e.Duration),
+		}
+		if !e.Time.IsZero() {
+			line.Time = &e.Time
+		}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordDependencies is an InvokeOption that sets used to the keys of the
+// values and value groups that the Invoke resolved from the container's
+// cache or by calling their constructors, sorted by type, name, and group.
+// Optional dependencies that nothing provides and empty value groups are
+// left out.
+//
+//   var used []dig.Key
+//   err := c.Invoke(handler.Run, dig.RecordDependencies(&used))
+//
+// This includes the dependencies of the constructors called along the way.
+// Tests can compare the keys with the dependencies they expect so that new
+// dependencies of the function don't go unnoticed. Values that constructors
+// produce without being depended on are not recorded.
+func RecordDependencies(used *[]Key) InvokeOption {
+	return invokeOptionFunc(func(opts *invokeOptions) {
+		opts.Dependencies = used
+	})
+}
+
+// dependencies returns the keys of the values and value groups resolved
+// during the Invoke, sorted. See RecordDependencies.
+func (a *InvokeAudit) dependencies() []Key {
+	keys := make([]key, 0, len(a.resolved))
+	for k := range a.resolved {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+
+	used := make([]Key, len(keys))
+	for i, k := range keys {
+		used[i] = k.exported()
+	}
+	return used
+}
+
+// audit returns the audit filled in by the innermost Invoke, or nil if it
+// wasn't called with AuditTrail.
+func (c *Container) audit() *InvokeAudit {
+	return c.calls.audit
+}
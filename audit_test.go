@@ -0,0 +1,224 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditTrail(t *testing.T) {
+	type Config struct{}
+	type DB struct{}
+	type Cache struct{}
+	type Handler struct{}
+
+	newContainer := func(t *testing.T) *Container {
+		c := New()
+		require.NoError(t, c.Provide(func() *Config { return &Config{} }))
+		require.NoError(t, c.Provide(func(*Config) *DB { return &DB{} }))
+		require.NoError(t, c.Provide(func() *Handler { return &Handler{} }, Group("handlers")))
+		return c
+	}
+
+	type params struct {
+		In
+
+		DB       *DB
+		Cache    *Cache     `optional:"true"`
+		Handlers []*Handler `group:"handlers"`
+		Missing  []*Cache   `group:"caches"`
+	}
+
+	kinds := func(audit InvokeAudit) map[string]AuditKind {
+		kinds := make(map[string]AuditKind)
+		for _, e := range audit.Entries {
+			kinds[e.Key.String()] = e.Kind
+		}
+		return kinds
+	}
+
+	t.Run("constructed, then cached", func(t *testing.T) {
+		c := newContainer(t)
+
+		var audit InvokeAudit
+		require.NoError(t, c.Invoke(func(params) {}, AuditTrail(&audit)))
+		assert.Equal(t, map[string]AuditKind{
+			"*dig.Config":                    AuditConstructed,
+			"*dig.DB":                        AuditConstructed,
+			"*dig.Cache":                     AuditDefaulted,
+			`*dig.Handler[group="handlers"]`: AuditConstructed,
+			`*dig.Cache[group="caches"]`:     AuditDefaulted,
+		}, kinds(audit))
+
+		for _, e := range audit.Entries {
+			if e.Kind == AuditConstructed {
+				assert.NotEmpty(t, e.Name, "constructor of %v", e.Key)
+				assert.False(t, e.Time.IsZero(), "time of %v", e.Key)
+			}
+		}
+
+		audit = InvokeAudit{}
+		require.NoError(t, c.Invoke(func(params) {}, AuditTrail(&audit)))
+		assert.Equal(t, map[string]AuditKind{
+			"*dig.DB":                        AuditCached,
+			"*dig.Cache":                     AuditDefaulted,
+			`*dig.Handler[group="handlers"]`: AuditCached,
+			`*dig.Cache[group="caches"]`:     AuditDefaulted,
+		}, kinds(audit))
+	})
+
+	t.Run("each key once", func(t *testing.T) {
+		c := newContainer(t)
+
+		var audit InvokeAudit
+		require.NoError(t, c.Invoke(func(*DB, *DB, *Config) {}, AuditTrail(&audit)))
+		require.Len(t, audit.Entries, 2)
+		assert.Equal(t, "*dig.Config", audit.Entries[0].Key.String())
+		assert.Equal(t, "*dig.DB", audit.Entries[1].Key.String())
+	})
+
+	t.Run("without the option", func(t *testing.T) {
+		c := newContainer(t)
+
+		var audit InvokeAudit
+		require.NoError(t, c.Invoke(func(*Config) {
+			require.NoError(t, c.Invoke(func(*DB) {}))
+		}, AuditTrail(&audit)))
+		require.NoError(t, c.Invoke(func(params) {}))
+		assert.Equal(t, map[string]AuditKind{
+			"*dig.Config": AuditConstructed,
+			"*dig.DB":     AuditConstructed,
+		}, kinds(audit), "nested Invokes must be recorded")
+	})
+
+	t.Run("scope", func(t *testing.T) {
+		c := newContainer(t)
+		require.NoError(t, c.Invoke(func(*Config) {}))
+
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func() *Cache { return &Cache{} }))
+
+		var audit InvokeAudit
+		require.NoError(t, s.Invoke(func(*Config, *Cache) {}, AuditTrail(&audit)))
+		assert.Equal(t, map[string]AuditKind{
+			"*dig.Config": AuditCached,
+			"*dig.Cache":  AuditConstructed,
+		}, kinds(audit))
+	})
+
+	t.Run("JSON lines", func(t *testing.T) {
+		c := newContainer(t)
+		require.NoError(t, c.Invoke(func(*Config) {}))
+
+		var audit InvokeAudit
+		require.NoError(t, c.Invoke(func(*DB) {}, AuditTrail(&audit)))
+
+		var buf bytes.Buffer
+		require.NoError(t, audit.WriteJSONLines(&buf))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.JSONEq(t, `{"key":"*dig.Config","kind":"cached"}`, lines[0])
+
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &line))
+		assert.Equal(t, "*dig.DB", line["key"])
+		assert.Equal(t, "constructed", line["kind"])
+		assert.Contains(t, line["name"], "TestAuditTrail")
+		assert.Contains(t, line, "time")
+	})
+}
+
+func TestRecordDependencies(t *testing.T) {
+	type Clock struct{}
+	type Cache struct{}
+	type DB struct{}
+	type Handler struct{}
+
+	newContainer := func(t *testing.T) *Container {
+		c := New()
+		require.NoError(t, c.Provide(func() *Clock { return &Clock{} }))
+		require.NoError(t, c.Provide(func(*Clock) (*Cache, *DB) { return &Cache{}, &DB{} }))
+		require.NoError(t, c.Provide(func() *Handler { return &Handler{} }, Group("handlers")))
+		return c
+	}
+
+	keyOf := func(v interface{}) Key { return Key{Type: reflect.TypeOf(v)} }
+
+	t.Run("resolved keys", func(t *testing.T) {
+		c := newContainer(t)
+
+		var used []Key
+		require.NoError(t, c.Invoke(func(p struct {
+			In
+
+			Cache    *Cache
+			Clock    *Clock
+			Again    *Cache
+			Missing  string     `optional:"true"`
+			Handlers []*Handler `group:"handlers"`
+			Empty    []string   `group:"empty"`
+		}) {
+		}, RecordDependencies(&used)))
+
+		assert.Equal(t, []Key{
+			keyOf(&Cache{}),
+			keyOf(&Clock{}),
+			{Type: reflect.TypeOf(&Handler{}), Group: "handlers"},
+		}, used, "the DB built along the Cache must not be recorded")
+	})
+
+	t.Run("cached values", func(t *testing.T) {
+		c := newContainer(t)
+		require.NoError(t, c.Invoke(func(*Cache) {}))
+
+		var used []Key
+		require.NoError(t, c.Invoke(func(*Cache) {}, RecordDependencies(&used)))
+		assert.Equal(t, []Key{keyOf(&Cache{})}, used)
+	})
+
+	t.Run("with AuditTrail", func(t *testing.T) {
+		c := newContainer(t)
+
+		var (
+			used  []Key
+			audit InvokeAudit
+		)
+		require.NoError(t, c.Invoke(func(*Cache) {}, AuditTrail(&audit), RecordDependencies(&used)))
+		assert.Equal(t, []Key{keyOf(&Cache{}), keyOf(&Clock{})}, used)
+		assert.Len(t, audit.Entries, 3)
+	})
+
+	t.Run("failed Invoke", func(t *testing.T) {
+		c := newContainer(t)
+
+		used := []Key{keyOf(&DB{})}
+		require.Error(t, c.Invoke(func(*Clock, string) {}, RecordDependencies(&used)))
+		assert.Empty(t, used)
+	})
+}
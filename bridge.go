@@ -0,0 +1,240 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// bridge makes the values of a value group available as named values. See
+// BridgeGroupToNames.
+type bridge struct {
+	Group  string
+	Type   reflect.Type
+	NameOf func(v interface{}) string
+
+	// Location of the call to BridgeGroupToNames.
+	Caller *digreflect.Func
+
+	// Whether the named values were provided.
+	Resolved bool
+}
+
+// BridgeGroupToNames makes each value of the value group with the given name
+// and the type pointed to by of available as a named value, so that code
+// migrating from the group to named values can use both. The name of each
+// value is computed by nameFn.
+//
+//   c.BridgeGroupToNames("codecs", new(Codec), func(v interface{}) string {
+//     return v.(Codec).Name()
+//   })
+//
+// The names are only known once the values are built, so the value group is
+// built the first time a named value of that type is requested and nothing
+// provides it. A constructor is then provided for each value of the group as
+// of that moment, which fails if another constructor already provides a
+// value of that type under the same name, or if two values of the group have
+// the same name. Visualize marks these constructors as bridges.
+func (c *Container) BridgeGroupToNames(group string, of interface{}, nameFn func(v interface{}) string) error {
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+	if err := c.checkMutable(); err != nil {
+		return err
+	}
+
+	t := pointedType(of)
+	switch {
+	case group == "":
+		return errors.New("cannot bridge a value group without a name")
+	case t == nil:
+		return fmt.Errorf("cannot bridge value group %q of %v (type %T): must be a pointer", group, of, of)
+	case nameFn == nil:
+		return fmt.Errorf("cannot bridge value group %q of %v without a function to name its values", group, t)
+	}
+
+	c.bridges = append(c.bridges, &bridge{
+		Group:  group,
+		Type:   t,
+		NameOf: nameFn,
+		Caller: digreflect.InspectCaller(1),
+	})
+	return nil
+}
+
+// BridgeNamesToGroup adds the named values with the given names and the type
+// pointed to by of to the value group with the given name, so that code
+// migrating from named values to the group can use both.
+//
+//   c.BridgeNamesToGroup([]string{"json", "yaml"}, new(Codec), "codecs")
+//
+// A constructor is provided for each name that submits the named value to
+// the group. This fails if the constructor of one of the named values
+// already submits values to the group. Visualize marks these constructors
+// as bridges.
+func (c *Container) BridgeNamesToGroup(names []string, of interface{}, group string) error {
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+	if err := c.checkMutable(); err != nil {
+		return err
+	}
+
+	t := pointedType(of)
+	switch {
+	case group == "":
+		return errors.New("cannot bridge named values to a value group without a name")
+	case t == nil:
+		return fmt.Errorf("cannot bridge named values of %v (type %T) to value group %q: must be a pointer", of, of, group)
+	}
+
+	caller := digreflect.InspectCaller(1)
+	gk := key{group: group, t: t}
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		k := key{name: name, t: t}
+		if name == "" {
+			return fmt.Errorf("cannot bridge %v to value group %q: unnamed values cannot be bridged", k, group)
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("cannot bridge %v to value group %q: it was listed more than once", k, group)
+		}
+		seen[name] = struct{}{}
+
+		for _, p := range c.getValueProviders(name, t) {
+			if countGroupResults(p.ResultList(), gk) > 0 {
+				return fmt.Errorf("cannot bridge %v to value group %q: %v already submits it to the group",
+					k, group, p.Location())
+			}
+		}
+	}
+
+	for _, name := range names {
+		if err := c.bridgeNameToGroup(name, t, group, caller); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bridgeNameToGroup provides a constructor that submits the value with the
+// given name and type to the value group.
+func (c *Container) bridgeNameToGroup(name string, t reflect.Type, group string, caller *digreflect.Func) error {
+	in := reflect.StructOf([]reflect.StructField{
+		{Name: "In", Type: _inType, Anonymous: true},
+		{Name: "Value", Type: t, Tag: reflect.StructTag(_nameTag + ":" + strconv.Quote(name))},
+	})
+	ftype := reflect.FuncOf([]reflect.Type{in}, []reflect.Type{t}, false /* variadic */)
+	ctor := reflect.MakeFunc(ftype, func(args []reflect.Value) []reflect.Value {
+		return []reflect.Value{args[0].Field(1)}
+	})
+
+	return c.provide(ctor.Interface(), provideOptions{
+		Groups:   []string{group},
+		Location: caller,
+		Bridge:   fmt.Sprintf("bridge from %v to group %q", key{name: name, t: t}, group),
+	})
+}
+
+// resolveBridge provides the named values of the value groups bridged with
+// BridgeGroupToNames to values of the given type by this container or its
+// parents, unless they were already provided, and reports whether one of
+// them has the given name.
+func (c *Container) resolveBridge(name string, t reflect.Type) (bool, error) {
+	var resolved bool
+	for s := c; s != nil; s = s.parent {
+		for _, b := range s.bridges {
+			if b.Type != t || b.Resolved {
+				continue
+			}
+			// Mark the bridge first so that building the group doesn't
+			// resolve it again.
+			b.Resolved = true
+
+			names, err := s.provideBridge(b)
+			if err != nil {
+				return false, err
+			}
+			if _, ok := names[name]; ok {
+				resolved = true
+			}
+		}
+	}
+	return resolved, nil
+}
+
+// provideBridge builds the value group of the bridge b and provides a
+// constructor for each of its values under the name given to it by b. It
+// returns the names.
+func (c *Container) provideBridge(b *bridge) (map[string]struct{}, error) {
+	gk := key{group: b.Group, t: b.Type}
+	v, err := paramGroupedSlice{Group: b.Group, Type: reflect.SliceOf(b.Type)}.Build(c)
+	if err != nil {
+		return nil, errWrapf(err, "cannot bridge value group %v to named values (bridged at %v)", gk, b.Caller)
+	}
+
+	values := make(map[string]reflect.Value, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		name := b.NameOf(v.Index(i).Interface())
+		if _, ok := values[name]; ok {
+			return nil, fmt.Errorf("cannot bridge value group %v to named values (bridged at %v): several values are named %q",
+				gk, b.Caller, name)
+		}
+		values[name] = v.Index(i)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	provided := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		k := key{name: name, t: b.Type}
+		if ps := c.getValueProviders(name, b.Type); len(ps) > 0 {
+			return nil, fmt.Errorf("cannot bridge value group %v to %v (bridged at %v): it is already provided by %v",
+				gk, k, b.Caller, ps[0].Location())
+		}
+
+		value := values[name]
+		ftype := reflect.FuncOf(nil /* in */, []reflect.Type{b.Type}, false /* variadic */)
+		ctor := reflect.MakeFunc(ftype, func([]reflect.Value) []reflect.Value {
+			return []reflect.Value{value}
+		})
+		err := c.provide(ctor.Interface(), provideOptions{
+			Name:     name,
+			Location: b.Caller,
+			Bridge:   fmt.Sprintf("bridge from group %q to %v", b.Group, k),
+		})
+		if err != nil {
+			return nil, errWrapf(err, "cannot bridge value group %v to %v", gk, k)
+		}
+		provided[name] = struct{}{}
+	}
+	return provided, nil
+}
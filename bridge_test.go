@@ -0,0 +1,187 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bridgeCodec struct{ name string }
+
+func bridgeCodecName(v interface{}) string { return v.(*bridgeCodec).name }
+
+func TestBridgeGroupToNames(t *testing.T) {
+	type codecOut struct {
+		Out
+
+		Codec *bridgeCodec `group:"codecs"`
+	}
+	provideCodec := func(t *testing.T, c *Container, name string) {
+		require.NoError(t, c.Provide(func() codecOut {
+			return codecOut{Codec: &bridgeCodec{name: name}}
+		}))
+	}
+
+	type params struct {
+		In
+
+		JSON   *bridgeCodec   `name:"json"`
+		YAML   *bridgeCodec   `name:"yaml" optional:"true"`
+		Codecs []*bridgeCodec `group:"codecs"`
+	}
+
+	t.Run("success", func(t *testing.T) {
+		c := New()
+		provideCodec(t, c, "json")
+		provideCodec(t, c, "yaml")
+
+		var named int
+		require.NoError(t, c.BridgeGroupToNames("codecs", new(*bridgeCodec), func(v interface{}) string {
+			named++
+			return bridgeCodecName(v)
+		}))
+		assert.Zero(t, named, "names must be computed lazily")
+
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.Equal(t, "json", p.JSON.name)
+			assert.Equal(t, "yaml", p.YAML.name)
+			assert.Contains(t, p.Codecs, p.JSON, "named values must be the members of the group")
+			assert.Contains(t, p.Codecs, p.YAML, "named values must be the members of the group")
+		}))
+		require.NoError(t, c.Invoke(func(p params) {}))
+		assert.Equal(t, 2, named, "names must be computed once")
+
+		var bridges []string
+		for _, ctor := range Graph(c).Ctors {
+			if ctor.Bridge != "" {
+				bridges = append(bridges, ctor.Bridge)
+			}
+		}
+		assert.Equal(t, []string{
+			`bridge from group "codecs" to *dig.bridgeCodec[name="json"]`,
+			`bridge from group "codecs" to *dig.bridgeCodec[name="yaml"]`,
+		}, bridges)
+	})
+
+	t.Run("scope", func(t *testing.T) {
+		c := New()
+		provideCodec(t, c, "json")
+		require.NoError(t, c.BridgeGroupToNames("codecs", new(*bridgeCodec), bridgeCodecName))
+
+		require.NoError(t, c.Scope("child").Invoke(func(p params) {
+			assert.Equal(t, "json", p.JSON.name)
+			assert.Nil(t, p.YAML)
+		}))
+	})
+
+	t.Run("name already provided", func(t *testing.T) {
+		c := New()
+		provideCodec(t, c, "json")
+		provideCodec(t, c, "yaml")
+		require.NoError(t, c.Provide(func() *bridgeCodec { return &bridgeCodec{name: "other"} }, Name("yaml")))
+		require.NoError(t, c.BridgeGroupToNames("codecs", new(*bridgeCodec), bridgeCodecName))
+
+		err := c.Invoke(func(p params) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`cannot bridge value group \*dig.bridgeCodec\[group="codecs"\] to \*dig.bridgeCodec\[name="yaml"\] `+
+				`\(bridged at "go.uber.org/dig".TestBridgeGroupToNames\S+ \(\S+/bridge_test.go:\d+\)\): `+
+				`it is already provided by "go.uber.org/dig".TestBridgeGroupToNames\S+ \(\S+/bridge_test.go:\d+\)`)
+	})
+
+	t.Run("duplicate names", func(t *testing.T) {
+		c := New()
+		provideCodec(t, c, "json")
+		provideCodec(t, c, "json")
+		require.NoError(t, c.BridgeGroupToNames("codecs", new(*bridgeCodec), bridgeCodecName))
+
+		err := c.Invoke(func(p params) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `several values are named "json"`)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		c := New()
+		assert.EqualError(t, c.BridgeGroupToNames("", new(*bridgeCodec), bridgeCodecName),
+			"cannot bridge a value group without a name")
+		assert.EqualError(t, c.BridgeGroupToNames("codecs", bridgeCodec{}, bridgeCodecName),
+			`cannot bridge value group "codecs" of {} (type dig.bridgeCodec): must be a pointer`)
+		assert.EqualError(t, c.BridgeGroupToNames("codecs", new(*bridgeCodec), nil),
+			`cannot bridge value group "codecs" of *dig.bridgeCodec without a function to name its values`)
+	})
+}
+
+func TestBridgeNamesToGroup(t *testing.T) {
+	type params struct {
+		In
+
+		Codecs []*bridgeCodec `group:"codecs"`
+	}
+
+	t.Run("success", func(t *testing.T) {
+		c := New()
+		json, yaml := &bridgeCodec{name: "json"}, &bridgeCodec{name: "yaml"}
+		require.NoError(t, c.Provide(func() *bridgeCodec { return json }, Name("json")))
+		require.NoError(t, c.Provide(func() *bridgeCodec { return yaml }, Name("yaml")))
+		require.NoError(t, c.BridgeNamesToGroup([]string{"json", "yaml"}, new(*bridgeCodec), "codecs"))
+
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.ElementsMatch(t, []*bridgeCodec{json, yaml}, p.Codecs)
+		}))
+
+		g := Graph(c)
+		require.Len(t, g.Ctors, 4)
+		assert.Equal(t, `bridge from *dig.bridgeCodec[name="json"] to group "codecs"`, g.Ctors[2].Bridge)
+		assert.Equal(t, `bridge from *dig.bridgeCodec[name="yaml"] to group "codecs"`, g.Ctors[3].Bridge)
+	})
+
+	t.Run("already in the group", func(t *testing.T) {
+		type out struct {
+			Out
+
+			JSON  *bridgeCodec `name:"json"`
+			Codec *bridgeCodec `group:"codecs"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() out { return out{} }))
+		err := c.BridgeNamesToGroup([]string{"json"}, new(*bridgeCodec), "codecs")
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`cannot bridge \*dig.bridgeCodec\[name="json"\] to value group "codecs": `+
+				`"go.uber.org/dig".TestBridgeNamesToGroup\S+ \(\S+/bridge_test.go:\d+\) already submits it to the group`)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		c := New()
+		assert.EqualError(t, c.BridgeNamesToGroup([]string{"json"}, new(*bridgeCodec), ""),
+			"cannot bridge named values to a value group without a name")
+		assert.EqualError(t, c.BridgeNamesToGroup([]string{"json"}, bridgeCodec{}, "codecs"),
+			`cannot bridge named values of {} (type dig.bridgeCodec) to value group "codecs": must be a pointer`)
+		assert.EqualError(t, c.BridgeNamesToGroup([]string{""}, new(*bridgeCodec), "codecs"),
+			`cannot bridge *dig.bridgeCodec to value group "codecs": unnamed values cannot be bridged`)
+		assert.EqualError(t, c.BridgeNamesToGroup([]string{"json", "json"}, new(*bridgeCodec), "codecs"),
+			`cannot bridge *dig.bridgeCodec[name="json"] to value group "codecs": it was listed more than once`)
+	})
+}
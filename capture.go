@@ -0,0 +1,213 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// Maximum length in bytes of the rendering of a captured argument.
+const _maxCapturedArgLen = 256
+
+// CaptureArgsOnError is an Option that records the arguments passed to
+// constructors that return an error. The failure of such a constructor
+// includes a ConstructorArgsError, which can be retrieved with errors.As.
+//
+//   c := dig.New(dig.CaptureArgsOnError())
+//   // ...
+//   var argsErr dig.ConstructorArgsError
+//   if errors.As(err, &argsErr) {
+//     log.Printf("%+v", argsErr)
+//   }
+//
+// Formatting the error returned by Invoke with %+v includes the arguments as
+// well. Arguments are rendered with the %+v verb and truncated to a few
+// hundred bytes. Use RedactArgs to keep sensitive values out of errors.
+func CaptureArgsOnError() Option {
+	return optionFunc(func(c *Container) {
+		c.captureArgsOnError = true
+	})
+}
+
+// RedactArgs is an Option that omits the values of arguments captured by
+// CaptureArgsOnError for which the given function returns true. The function
+// is called with the type of each argument and its name, which is empty for
+// unnamed values and value groups.
+//
+//   dig.RedactArgs(func(t reflect.Type, name string) bool {
+//     return t == reflect.TypeOf(Credentials{})
+//   })
+//
+// Fields of parameter objects are captured and redacted individually.
+func RedactArgs(f func(t reflect.Type, name string) bool) Option {
+	return optionFunc(func(c *Container) {
+		c.redactArg = f
+	})
+}
+
+// CapturedArg is an argument passed to a constructor that failed. See
+// CaptureArgsOnError.
+type CapturedArg struct {
+	// Type of the argument.
+	Type reflect.Type
+
+	// Name of the value or value group the argument was resolved from, if
+	// any.
+	Name  string
+	Group string
+
+	// Rendering of the value with the %+v verb, possibly truncated. This is
+	// empty if the value was redacted.
+	Value    string
+	Redacted bool
+}
+
+func (a CapturedArg) String() string {
+	b := new(bytes.Buffer)
+	fmt.Fprint(b, a.Type)
+	switch {
+	case a.Name != "":
+		fmt.Fprintf(b, "[name=%q]", a.Name)
+	case a.Group != "":
+		fmt.Fprintf(b, "[group=%q]", a.Group)
+	}
+	if a.Redacted {
+		b.WriteString(" = <redacted>")
+	} else {
+		fmt.Fprintf(b, " = %v", a.Value)
+	}
+	return b.String()
+}
+
+// ConstructorArgsError wraps the error returned by a constructor with the
+// arguments that were passed to it. It is only produced by containers built
+// with CaptureArgsOnError.
+//
+// The message of a ConstructorArgsError is that of the constructor's error.
+// Format it with %+v to include the arguments.
+type ConstructorArgsError struct {
+	// Arguments passed to the constructor, in order. Parameter objects are
+	// replaced by their fields.
+	Args []CapturedArg
+
+	// Error returned by the constructor.
+	Reason error
+}
+
+func (e ConstructorArgsError) cause() error { return e.Reason }
+
+// Unwrap returns the error returned by the constructor.
+func (e ConstructorArgsError) Unwrap() error { return e.Reason }
+
+func (e ConstructorArgsError) Error() string {
+	return e.Reason.Error()
+}
+
+// Format implements fmt.Formatter. The %+v verb lists the captured arguments
+// after the error message, one per line.
+func (e ConstructorArgsError) Format(w fmt.State, c rune) {
+	if c != 'v' || !w.Flag('+') {
+		io.WriteString(w, e.Error())
+		return
+	}
+
+	fmt.Fprintf(w, "%+v", e.Reason)
+	for i, a := range e.Args {
+		fmt.Fprintf(w, "\n\targ %d: %v", i, a)
+	}
+}
+
+func (c *Container) captureArgs(pl paramList, args []reflect.Value) []CapturedArg {
+	if !c.captureArgsOnError {
+		return nil
+	}
+
+	captured := make([]CapturedArg, 0, len(args))
+	for i, p := range pl.Params {
+		captured = c.appendCapturedArgs(captured, p, args[i])
+	}
+	return captured
+}
+
+func (c *Container) appendCapturedArgs(captured []CapturedArg, p param, v reflect.Value) []CapturedArg {
+	var (
+		arg  CapturedArg
+		iter bool
+	)
+	switch p := p.(type) {
+	case paramObject:
+		for _, f := range p.Fields {
+			captured = c.appendCapturedArgs(captured, f.Param, v.Field(f.FieldIndex))
+		}
+		return captured
+	case paramSingle:
+		arg = CapturedArg{Type: p.Type, Name: p.Name}
+	case paramNamedSlice:
+		names := make([]string, len(p.Params))
+		for i, ps := range p.Params {
+			names[i] = ps.Name
+		}
+		arg = CapturedArg{Type: p.Type, Name: strings.Join(names, ",")}
+	case paramGroupedSlice:
+		arg = CapturedArg{Type: p.Type, Group: p.Group}
+		if p.Iter != nil {
+			arg.Type = p.Iter
+			iter = true
+		}
+		if p.Chan != nil {
+			arg.Type = p.Chan
+		}
+	default:
+		return captured
+	}
+
+	switch {
+	case c.redactArg != nil && c.redactArg(arg.Type, arg.Name):
+		arg.Redacted = true
+	case iter:
+		// Rendering group iterators would call the constructors of the
+		// group.
+		arg.Value = "<iterator>"
+	default:
+		arg.Value = truncateArg(fmt.Sprintf("%+v", v.Interface()))
+	}
+	return append(captured, arg)
+}
+
+// truncateArg truncates the rendering of an argument to at most
+// _maxCapturedArgLen bytes without splitting runes.
+func truncateArg(s string) string {
+	if len(s) <= _maxCapturedArgLen {
+		return s
+	}
+
+	const ellipsis = "..."
+	n := _maxCapturedArgLen - len(ellipsis)
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + ellipsis
+}
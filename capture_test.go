@@ -0,0 +1,190 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureArgsOnError(t *testing.T) {
+	type password string
+
+	type config struct {
+		Addr string
+		Port int
+	}
+
+	type in struct {
+		In
+
+		Secret password `name:"secret"`
+		Tags   []string `group:"tags"`
+	}
+
+	newContainer := func(t *testing.T, opts ...Option) *Container {
+		c := New(opts...)
+		require.NoError(t, c.Provide(func() config { return config{Addr: "localhost", Port: 8080} }))
+		require.NoError(t, c.Provide(func() password { return "hunter2" }, Name("secret")))
+		require.NoError(t, c.Provide(func() string { return "a" }, Group("tags")))
+		require.NoError(t, c.Provide(func(config, in) (int, error) {
+			return 0, errors.New("great sadness")
+		}))
+		return c
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		err := newContainer(t).Invoke(func(int) {})
+		require.Error(t, err)
+
+		var argsErr ConstructorArgsError
+		assert.False(t, errors.As(err, &argsErr))
+		assert.Equal(t, "great sadness", RootCause(err).Error())
+	})
+
+	t.Run("captures arguments", func(t *testing.T) {
+		err := newContainer(t, CaptureArgsOnError()).Invoke(func(int) {})
+		require.Error(t, err)
+		assert.Equal(t, "great sadness", RootCause(err).Error())
+		assert.NotContains(t, err.Error(), "hunter2",
+			"arguments must only be included with %+v")
+
+		var argsErr ConstructorArgsError
+		require.True(t, errors.As(err, &argsErr))
+		assert.Equal(t, []CapturedArg{
+			{Type: reflect.TypeOf(config{}), Value: "{Addr:localhost Port:8080}"},
+			{Type: reflect.TypeOf(password("")), Name: "secret", Value: "hunter2"},
+			{Type: reflect.TypeOf([]string{}), Group: "tags", Value: "[a]"},
+		}, argsErr.Args)
+
+		out := fmt.Sprintf("%+v", argsErr)
+		assert.Contains(t, out, "great sadness")
+		assert.Contains(t, out, "arg 0: dig.config = {Addr:localhost Port:8080}")
+		assert.Contains(t, out, `arg 1: dig.password[name="secret"] = hunter2`)
+		assert.Contains(t, out, `arg 2: []string[group="tags"] = [a]`)
+		assert.Equal(t, "great sadness", fmt.Sprintf("%v", argsErr))
+	})
+
+	t.Run("formatted from the invoke error", func(t *testing.T) {
+		for _, opts := range [][]Option{
+			{CaptureArgsOnError()},
+			{CaptureArgsOnError(), AttachGraphToErrors()},
+		} {
+			err := newContainer(t, opts...).Invoke(func(int) {})
+			require.Error(t, err)
+
+			out := fmt.Sprintf("%+v", err)
+			assert.True(t, strings.HasPrefix(out, err.Error()),
+				"message must be kept: %v", out)
+			assert.Contains(t, out, "arg 0: dig.config = {Addr:localhost Port:8080}")
+			assert.Contains(t, out, `arg 1: dig.password[name="secret"] = hunter2`)
+			assert.Equal(t, err.Error(), fmt.Sprintf("%v", err))
+			assert.Equal(t, err.Error(), fmt.Sprintf("%s", err))
+		}
+	})
+
+	t.Run("formatted from value group failures", func(t *testing.T) {
+		c := New(CaptureArgsOnError())
+		require.NoError(t, c.Provide(func() string { return "foo" }))
+		for i := 0; i < 2; i++ {
+			require.NoError(t, c.Provide(func(string) (int, error) {
+				return 0, errors.New("great sadness")
+			}, Group("ints")))
+		}
+
+		type in struct {
+			In
+
+			Ints []int `group:"ints"`
+		}
+		err := c.Invoke(func(in) {})
+		require.Error(t, err)
+
+		out := fmt.Sprintf("%+v", err)
+		assert.Contains(t, out, "2 constructors failed")
+		assert.Equal(t, 2, strings.Count(out, "arg 0: string = foo"), out)
+	})
+
+	t.Run("redacted", func(t *testing.T) {
+		var calls []string
+		err := newContainer(t,
+			CaptureArgsOnError(),
+			RedactArgs(func(t reflect.Type, name string) bool {
+				calls = append(calls, fmt.Sprintf("%v:%v", t, name))
+				return name == "secret"
+			}),
+		).Invoke(func(int) {})
+		require.Error(t, err)
+		assert.Equal(t, []string{"dig.config:", "dig.password:secret", "[]string:"}, calls)
+
+		var argsErr ConstructorArgsError
+		require.True(t, errors.As(err, &argsErr))
+		require.Len(t, argsErr.Args, 3)
+		assert.True(t, argsErr.Args[1].Redacted)
+		assert.Empty(t, argsErr.Args[1].Value)
+
+		out := fmt.Sprintf("%+v", argsErr)
+		assert.NotContains(t, out, "hunter2")
+		assert.Contains(t, out, `dig.password[name="secret"] = <redacted>`)
+	})
+
+	t.Run("inherited by scopes", func(t *testing.T) {
+		c := New(CaptureArgsOnError())
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func() string { return "foo" }))
+		require.NoError(t, s.Provide(func(string) (int, error) {
+			return 0, errors.New("great sadness")
+		}))
+
+		err := s.Invoke(func(int) {})
+		var argsErr ConstructorArgsError
+		require.True(t, errors.As(err, &argsErr))
+		assert.Equal(t, []CapturedArg{
+			{Type: reflect.TypeOf(""), Value: "foo"},
+		}, argsErr.Args)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		c := New(CaptureArgsOnError())
+		require.NoError(t, c.Provide(func() string { return strings.Repeat("é", 500) }))
+		require.NoError(t, c.Provide(func(string) (int, error) {
+			return 0, errors.New("great sadness")
+		}))
+
+		err := c.Invoke(func(int) {})
+		var argsErr ConstructorArgsError
+		require.True(t, errors.As(err, &argsErr))
+		require.Len(t, argsErr.Args, 1)
+
+		v := argsErr.Args[0].Value
+		assert.True(t, len(v) <= _maxCapturedArgLen, "value must be truncated")
+		assert.True(t, strings.HasSuffix(v, "..."), "value must end with an ellipsis")
+		assert.True(t, strings.HasPrefix(v, "éé"))
+		assert.Equal(t, strings.Repeat("é", (len(v)-3)/2)+"...", v,
+			"runes must not be split")
+	})
+}
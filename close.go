@@ -0,0 +1,103 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import "fmt"
+
+// ClosedContainerError is returned by the methods of a container, and of
+// its scopes, after the container was closed with Close.
+type ClosedContainerError struct {
+	// Name of the closed scope. This is empty if a root container was
+	// closed.
+	Scope string
+}
+
+func (e ClosedContainerError) Error() string {
+	if e.Scope == "" {
+		return "cannot use dig.Container after it was closed"
+	}
+	return fmt.Sprintf("cannot use scope %q after it was closed", e.Scope)
+}
+
+// OnClose registers a function to be called by Close, such as one that
+// shuts down a server built by the container. Functions are called in the
+// reverse order in which they were registered.
+func (c *Container) OnClose(f func() error) error {
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+	c.onClose = append(c.onClose, f)
+	return nil
+}
+
+// Close calls the functions registered with OnClose and releases all
+// constructors, values, and options of the container so that they may be
+// garbage collected even if the container itself is still referenced.
+//
+//   c := dig.New()
+//   defer c.Close()
+//
+// The container and its scopes may not be used after Close: their methods
+// fail with a ClosedContainerError. Closing a container doesn't call the
+// OnClose functions of its scopes; close them first if needed. Scopes
+// obtained from a ScopePool must be returned to their pool instead, with
+// ScopePool.Release to call their OnClose functions.
+//
+// All functions are called even if some of them fail, and their errors are
+// combined in the returned error. The container is closed either way.
+//
+// Close must not be called concurrently with other methods of the
+// container or its scopes.
+func (c *Container) Close() error {
+	if err := c.checkInitialized(); err != nil {
+		return err
+	}
+	if c.pool != nil {
+		return fmt.Errorf("cannot close scope %q obtained from a pool: return it with ScopePool.Put", c.name)
+	}
+
+	errs := c.callOnClose()
+
+	// Drop every reference held by the container, including the
+	// constructors, the values they produced, and the options that may
+	// capture arbitrary state. Only the name is kept for errors.
+	*c = Container{name: c.name, closed: true}
+
+	if len(errs) > 0 {
+		err := newMultiError(errs)
+		err.header = "failed to close the container:"
+		err.prefix = "\n\t"
+		return err
+	}
+	return nil
+}
+
+// callOnClose calls the functions registered with OnClose in reverse order
+// and returns their errors.
+func (c *Container) callOnClose() []error {
+	var errs []error
+	for i := len(c.onClose) - 1; i >= 0; i-- {
+		if err := c.onClose[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClose(t *testing.T) {
+	type A struct{}
+
+	t.Run("calls functions in reverse order", func(t *testing.T) {
+		c := New()
+		var calls []string
+		require.NoError(t, c.OnClose(func() error {
+			calls = append(calls, "first")
+			return errors.New("great sadness")
+		}))
+		require.NoError(t, c.OnClose(func() error {
+			calls = append(calls, "second")
+			return nil
+		}))
+
+		err := c.Close()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to close the container:")
+		assert.Contains(t, err.Error(), "great sadness")
+		assert.Equal(t, []string{"second", "first"}, calls)
+	})
+
+	t.Run("unusable after close", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		s := c.Scope("child")
+		require.NoError(t, c.Close())
+
+		err := c.Invoke(func(*A) {})
+		require.Error(t, err)
+		assert.Equal(t, ClosedContainerError{}, err)
+		assert.Error(t, c.Provide(func() string { return "" }))
+		assert.Error(t, c.OnClose(func() error { return nil }))
+		assert.Error(t, c.Close(), "closing twice must fail")
+
+		err = s.Invoke(func(*A) {})
+		require.Error(t, err)
+		assert.EqualError(t, err, "cannot use dig.Container after it was closed")
+	})
+
+	t.Run("closed scope", func(t *testing.T) {
+		c := New()
+		s := c.Scope("child")
+		require.NoError(t, s.Close())
+
+		assert.EqualError(t, s.Invoke(func() {}), `cannot use scope "child" after it was closed`)
+		assert.NoError(t, c.Invoke(func() {}), "parent must remain usable")
+	})
+
+	t.Run("pooled scope", func(t *testing.T) {
+		pool := NewScopePool(New())
+		s := pool.Get()
+		err := s.Close()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "return it with ScopePool.Put")
+		pool.Put(s)
+	})
+
+	t.Run("values become collectable", func(t *testing.T) {
+		type fixture struct{ data [1 << 20]byte }
+		type value struct{ data [1 << 20]byte }
+
+		collected := make(chan string, 2)
+		newValue := func() func() *value {
+			f := &fixture{}
+			runtime.SetFinalizer(f, func(*fixture) { collected <- "fixture" })
+			return func() *value {
+				_ = f.data[0] // the constructor captures the fixture
+				v := &value{}
+				runtime.SetFinalizer(v, func(*value) { collected <- "value" })
+				return v
+			}
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(newValue()))
+		require.NoError(t, c.Invoke(func(*value) {}))
+		require.NoError(t, c.Close())
+
+		var got []string
+		timeout := time.After(5 * time.Second)
+		for len(got) < 2 {
+			runtime.GC()
+			select {
+			case name := <-collected:
+				got = append(got, name)
+			case <-time.After(10 * time.Millisecond):
+			case <-timeout:
+				t.Fatalf("only %v were collected", got)
+			}
+		}
+		assert.ElementsMatch(t, []string{"fixture", "value"}, got)
+		runtime.KeepAlive(c)
+	})
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// OnValueCommitted is an Option that calls the given function with every
+// value produced by a constructor once the value was added to the container,
+// so that it's visible to Invoke and to other constructors.
+//
+//   c := dig.New(dig.OnValueCommitted(func(k dig.Key, v interface{}) {
+//     if c, ok := v.(prometheus.Collector); ok {
+//       registry.MustRegister(c)
+//     }
+//   }))
+//
+// The function is called once for every value. Values submitted to a value
+// group have the Group field of their key set, and the function is called
+// once for every value submitted to the group.
+//
+// If the function panics, the panic is reported by the Provide or Invoke
+// that caused the value to be built as a CommitHookPanicError. The value is
+// still committed to the container.
+//
+// This option may be specified multiple times. Functions are called in the
+// order in which they were specified. Scopes inherit the functions of their
+// parents.
+func OnValueCommitted(f func(k Key, v interface{})) Option {
+	return optionFunc(func(c *Container) {
+		c.valueCommittedHooks = append(c.valueCommittedHooks, f)
+	})
+}
+
+// CommitHookPanicError is returned when a function specified with
+// OnValueCommitted panics.
+type CommitHookPanicError struct {
+	// Key of the value the function was called with.
+	Key Key
+
+	// Value passed to panic.
+	Panic interface{}
+}
+
+func (e CommitHookPanicError) Error() string {
+	return fmt.Sprintf("OnValueCommitted function panicked for %v: %v", e.Key, e.Panic)
+}
+
+func (c *Container) commitHooks() []func(Key, interface{}) {
+	return c.valueCommittedHooks
+}
+
+// ValueInterceptor is an Option that calls the given function with every
+// value produced by a constructor before the value is added to the
+// container. The function may return a different value to commit in its
+// place, or an error to reject the value.
+//
+//   c := dig.New(dig.ValueInterceptor(func(k dig.Key, v interface{}) (interface{}, error) {
+//     if db, ok := v.(*sql.DB); ok {
+//       return instrument(db), nil
+//     }
+//     return v, nil
+//   }))
+//
+// Values submitted to a value group have the Group field of their key set.
+// Replacement values must be assignable to the type of the key.
+//
+// If the function returns an error or a value of the wrong type, the
+// constructor that produced the value fails and none of its values are
+// committed.
+//
+// This option may be specified multiple times. Functions are called in the
+// order in which they were specified, each with the value returned by the
+// previous one. Scopes inherit the functions of their parents.
+func ValueInterceptor(f func(k Key, v interface{}) (interface{}, error)) Option {
+	return optionFunc(func(c *Container) {
+		c.valueInterceptors = append(c.valueInterceptors, f)
+	})
+}
+
+func (c *Container) commitInterceptors() []func(Key, interface{}) (interface{}, error) {
+	return c.valueInterceptors
+}
+
+// errValueIntercepted is returned when a function specified with
+// ValueInterceptor rejected a value.
+type errValueIntercepted struct {
+	Key    key
+	Reason error
+}
+
+func (e errValueIntercepted) cause() error  { return e.Reason }
+func (e errValueIntercepted) Unwrap() error { return e.Reason }
+
+func (e errValueIntercepted) Error() string {
+	return fmt.Sprintf("value interceptor rejected %v: %v", e.Key, e.Reason)
+}
+
+func (e errValueIntercepted) Format(w fmt.State, c rune) { formatCause(e, e.Reason, w, c) }
+
+// intercept passes the values staged by this writer through the given
+// functions, and returns a writer holding the values they returned. The
+// writer itself is returned unchanged if there are no functions.
+func (sr *stagingContainerWriter) intercept(interceptors []func(Key, interface{}) (interface{}, error)) (*stagingContainerWriter, error) {
+	if len(interceptors) == 0 {
+		return sr, nil
+	}
+
+	intercept := func(k key, v reflect.Value) (reflect.Value, error) {
+		for _, f := range interceptors {
+			nv, err := f(k.exported(), v.Interface())
+			if err != nil {
+				return _noValue, errValueIntercepted{Key: k, Reason: err}
+			}
+
+			rv := reflect.ValueOf(nv)
+			if !rv.IsValid() {
+				rv = reflect.Zero(k.t)
+			}
+			if !rv.Type().AssignableTo(k.t) {
+				return _noValue, fmt.Errorf(
+					"value interceptor returned %v, which is not assignable to %v", rv.Type(), k)
+			}
+			v = reflect.New(k.t).Elem()
+			v.Set(rv)
+		}
+		return v, nil
+	}
+
+	out := newStagingContainerWriter()
+	out.errors = sr.errors
+	out.built, out.duration, out.invoke = sr.built, sr.duration, sr.invoke
+	for _, k := range sr.sortedKeys() {
+		if k.group == "" {
+			v, err := intercept(k, sr.values[k])
+			if err != nil {
+				return nil, err
+			}
+			out.values[k] = v
+			continue
+		}
+		for _, v := range sr.groups[k] {
+			v, err := intercept(k, v)
+			if err != nil {
+				return nil, err
+			}
+			out.groups[k] = append(out.groups[k], v)
+		}
+	}
+	return out, nil
+}
+
+func callCommitHook(f func(Key, interface{}), k key, v reflect.Value) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = CommitHookPanicError{Key: k.exported(), Panic: p}
+		}
+	}()
+	f(k.exported(), v.Interface())
+	return nil
+}
+
+// notify calls the OnValueCommitted functions of the given container with
+// the values committed by this writer, sorted by key. Values in the same
+// group are reported in the order in which they were submitted.
+//
+// All functions are called even if some of them panic; the first panic is
+// returned as an error.
+func (sr *stagingContainerWriter) notify(c containerStore) error {
+	hooks := c.commitHooks()
+	if len(hooks) == 0 && !c.subscribed() {
+		return nil
+	}
+
+	var err error
+	notify := func(k key, v reflect.Value) {
+		c.emitCommitted(k)
+		for _, f := range hooks {
+			if e := callCommitHook(f, k, v); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	for _, k := range sr.sortedKeys() {
+		if k.group == "" {
+			notify(k, sr.values[k])
+			continue
+		}
+		for _, v := range sr.groups[k] {
+			notify(k, v)
+		}
+	}
+	return err
+}
+
+// sortedKeys returns the keys of the values and value groups staged in this
+// writer, sorted by key.
+func (sr *stagingContainerWriter) sortedKeys() []key {
+	keys := make([]key, 0, len(sr.values)+len(sr.groups))
+	for k := range sr.values {
+		keys = append(keys, k)
+	}
+	for k := range sr.groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+	return keys
+}
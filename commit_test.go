@@ -0,0 +1,232 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnValueCommitted(t *testing.T) {
+	type committed struct {
+		Key   Key
+		Value interface{}
+	}
+
+	type out struct {
+		Out
+
+		Buffer *bytes.Buffer `name:"buf"`
+		First  int           `group:"values"`
+		Second int           `group:"values"`
+	}
+	type in struct {
+		In
+
+		Values []int `group:"values"`
+	}
+
+	t.Run("values and groups", func(t *testing.T) {
+		var (
+			c   *Container
+			got []committed
+		)
+		c = New(OnValueCommitted(func(k Key, v interface{}) {
+			// The value must be visible when the function is called.
+			ok := k.Group != "" || c.isBuilt(key{t: k.Type, name: k.Name})
+			assert.True(t, ok, "%v must be committed", k)
+			got = append(got, committed{Key: k, Value: v})
+		}))
+		buf := new(bytes.Buffer)
+		require.NoError(t, c.Provide(func() out { return out{Buffer: buf, First: 1, Second: 2} }))
+
+		require.NoError(t, c.Invoke(func(in) {}))
+		assert.Equal(t, []committed{
+			{Key: Key{Type: reflect.TypeOf(buf), Name: "buf"}, Value: buf},
+			{Key: Key{Type: reflect.TypeOf(0), Group: "values"}, Value: 1},
+			{Key: Key{Type: reflect.TypeOf(0), Group: "values"}, Value: 2},
+		}, got)
+
+		// Values are only reported when they're committed.
+		require.NoError(t, c.Invoke(func(in) {}))
+		assert.Len(t, got, 3)
+	})
+
+	t.Run("functions compose in order", func(t *testing.T) {
+		var calls []string
+		c := New(
+			OnValueCommitted(func(Key, interface{}) { calls = append(calls, "first") }),
+			OnValueCommitted(func(Key, interface{}) { calls = append(calls, "second") }),
+		)
+		require.NoError(t, c.Provide(func() int { return 42 }))
+
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func(int) string { return "" }))
+		require.NoError(t, s.Invoke(func(string) {}))
+		assert.Equal(t, []string{"first", "second", "first", "second"}, calls)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		var calls int
+		c := New(
+			OnValueCommitted(func(Key, interface{}) { panic("great sadness") }),
+			OnValueCommitted(func(Key, interface{}) { calls++ }),
+		)
+		require.NoError(t, c.Provide(func() int { return 42 }))
+
+		err := c.Invoke(func(int) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "OnValueCommitted function panicked for int: great sadness")
+
+		var panicErr CommitHookPanicError
+		require.True(t, errors.As(err, &panicErr), "error must be a CommitHookPanicError")
+		assert.Equal(t, Key{Type: reflect.TypeOf(0)}, panicErr.Key)
+		assert.Equal(t, "great sadness", panicErr.Panic)
+
+		// The value was committed anyway, and following functions were
+		// still called.
+		assert.Equal(t, 1, calls)
+		require.NoError(t, c.Invoke(func(i int) {
+			assert.Equal(t, 42, i)
+		}))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("panic in a value group", func(t *testing.T) {
+		c := New(OnValueCommitted(func(k Key, v interface{}) {
+			if v == 2 {
+				panic("great sadness")
+			}
+		}))
+		for i := 1; i <= 3; i++ {
+			i := i
+			require.NoError(t, c.Provide(func() int { return i }, Group("values")))
+		}
+
+		err := c.Invoke(func(in) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `OnValueCommitted function panicked for int[group="values"]: great sadness`)
+
+		// All values of the group were committed.
+		require.NoError(t, c.Invoke(func(in in) {
+			assert.ElementsMatch(t, []int{1, 2, 3}, in.Values)
+		}))
+	})
+}
+
+func TestValueInterceptor(t *testing.T) {
+	type DB struct{ instrumented bool }
+	type Handler interface{ Name() string }
+
+	t.Run("replace values", func(t *testing.T) {
+		var seen []Key
+		c := New(ValueInterceptor(func(k Key, v interface{}) (interface{}, error) {
+			seen = append(seen, k)
+			if _, ok := v.(*DB); ok {
+				return &DB{instrumented: true}, nil
+			}
+			return v, nil
+		}))
+		require.NoError(t, c.Provide(func() *DB { return &DB{} }))
+		require.NoError(t, c.Provide(func() string { return "a" }, Group("names")))
+
+		type params struct {
+			In
+
+			DB    *DB
+			Names []string `group:"names"`
+		}
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.True(t, p.DB.instrumented)
+			assert.Equal(t, []string{"a"}, p.Names)
+		}))
+		assert.ElementsMatch(t, []Key{
+			{Type: reflect.TypeOf(&DB{})},
+			{Type: reflect.TypeOf(""), Group: "names"},
+		}, seen)
+	})
+
+	t.Run("functions chain in order", func(t *testing.T) {
+		appendTo := func(suffix string) func(Key, interface{}) (interface{}, error) {
+			return func(_ Key, v interface{}) (interface{}, error) {
+				return v.(string) + suffix, nil
+			}
+		}
+		c := New(ValueInterceptor(appendTo("b")), ValueInterceptor(appendTo("c")))
+		require.NoError(t, c.Provide(func() string { return "a" }))
+		require.NoError(t, c.Invoke(func(s string) {
+			assert.Equal(t, "abc", s)
+		}))
+	})
+
+	t.Run("veto", func(t *testing.T) {
+		c := New(ValueInterceptor(func(k Key, v interface{}) (interface{}, error) {
+			return nil, errors.New("great sadness")
+		}))
+		require.NoError(t, c.Provide(func() (*DB, string) { return &DB{}, "a" }))
+
+		err := c.Invoke(func(*DB) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "value interceptor rejected *dig.DB: great sadness")
+		assert.Equal(t, "great sadness", RootCause(err).Error())
+
+		_, ok := c.values[key{t: reflect.TypeOf("")}]
+		assert.False(t, ok, "other values of the constructor must not be committed")
+	})
+
+	t.Run("not assignable", func(t *testing.T) {
+		c := New(ValueInterceptor(func(k Key, v interface{}) (interface{}, error) {
+			return 42, nil
+		}))
+		require.NoError(t, c.Provide(func() *DB { return &DB{} }))
+
+		err := c.Invoke(func(*DB) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "value interceptor returned int, which is not assignable to *dig.DB")
+	})
+
+	t.Run("nil replacement of interface", func(t *testing.T) {
+		c := New(ValueInterceptor(func(k Key, v interface{}) (interface{}, error) {
+			return nil, nil
+		}))
+		require.NoError(t, c.Provide(func() Handler { return nil }))
+		require.NoError(t, c.Invoke(func(h Handler) {
+			assert.Nil(t, h)
+		}))
+	})
+
+	t.Run("scopes inherit functions", func(t *testing.T) {
+		var calls int
+		c := New(ValueInterceptor(func(k Key, v interface{}) (interface{}, error) {
+			calls++
+			return v, nil
+		}))
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func() *DB { return &DB{} }))
+		require.NoError(t, s.Invoke(func(*DB) {}))
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// When is a ProvideOption that only enables the constructor while the given
+// condition holds, such as a feature flag. The constructor is provided
+// either way, but its values are treated as absent while it's disabled:
+// optional parameters receive their zero value, and required ones fail with
+// an error naming the disabled constructor.
+//
+//   c.Provide(NewRedisCache, dig.When(func() bool { return flags.Redis }))
+//   c.Provide(NewMemoryCache, dig.When(func() bool { return !flags.Redis }))
+//
+// The condition is evaluated when the constructor is provided and again at
+// the start of every Invoke and WarmUp, unless EvaluateOnce is given. When
+// the condition changes, the values built from the constructor's values are
+// built again. Conditional constructors don't conflict with other
+// constructors of the same values when they are provided; they are
+// reported as ambiguous if more than one of them is enabled when one of the
+// values is needed.
+//
+// Visualize draws disabled constructors dimmed. Panics in the condition are
+// reported as ConditionErrors.
+func When(cond func() bool, opts ...ConditionOption) ProvideOption {
+	return provideOptionFunc(func(o *provideOptions) {
+		o.Conditional = true
+		o.When = cond
+		o.WhenOnce = false
+		for _, opt := range opts {
+			opt.applyConditionOption(o)
+		}
+	})
+}
+
+// A ConditionOption modifies the default behavior of When.
+type ConditionOption interface {
+	applyConditionOption(*provideOptions)
+}
+
+type conditionOptionFunc func(*provideOptions)
+
+func (f conditionOptionFunc) applyConditionOption(o *provideOptions) { f(o) }
+
+// EvaluateOnce is a ConditionOption that evaluates the condition given to
+// When only once, when the constructor is provided, rather than at the
+// start of every Invoke.
+//
+//   c.Provide(NewTracer, dig.When(tracingEnabled, dig.EvaluateOnce()))
+func EvaluateOnce() ConditionOption {
+	return conditionOptionFunc(func(o *provideOptions) {
+		o.WhenOnce = true
+	})
+}
+
+// ConditionError is returned when the condition given to When panics.
+type ConditionError struct {
+	// Value passed to panic.
+	Panic interface{}
+
+	// Constructor the condition belongs to.
+	fn *digreflect.Func
+}
+
+func (e ConditionError) Error() string {
+	return fmt.Sprintf("condition of function %v panicked: %v", e.fn, e.Panic)
+}
+
+// condition is the condition of a constructor provided with When.
+type condition struct {
+	fn   func() bool
+	once bool
+
+	// Guards evaluated and enabled so that graphs can be built while Invoke
+	// evaluates the condition.
+	mu        sync.RWMutex
+	evaluated bool
+	enabled   bool
+}
+
+// disabled reports whether this node was provided with When and its
+// condition didn't hold when it was last evaluated.
+func (n *node) disabled() bool {
+	cond := n.condition
+	if cond == nil {
+		return false
+	}
+	cond.mu.RLock()
+	defer cond.mu.RUnlock()
+	return !cond.enabled
+}
+
+// evaluateCondition evaluates the condition of this node, if it has one that
+// must be evaluated again, and reports whether the result changed.
+func (n *node) evaluateCondition() (changed bool, err error) {
+	cond := n.condition
+	if cond == nil {
+		return false, nil
+	}
+	cond.mu.RLock()
+	skip := cond.once && cond.evaluated
+	cond.mu.RUnlock()
+	if skip {
+		return false, nil
+	}
+
+	var enabled bool
+	func() {
+		defer func() {
+			if p := recover(); p != nil {
+				err = ConditionError{Panic: p, fn: n.location}
+			}
+		}()
+		enabled = cond.fn()
+	}()
+	if err != nil {
+		return false, err
+	}
+
+	cond.mu.Lock()
+	defer cond.mu.Unlock()
+	changed = cond.evaluated && enabled != cond.enabled
+	cond.evaluated, cond.enabled = true, enabled
+	return changed, nil
+}
+
+// evaluateConditions evaluates the conditions of the constructors provided
+// to this container and its parents. Values built from constructors whose
+// condition changed are discarded, so that they're built again from the
+// constructors that are enabled.
+func (c *Container) evaluateConditions() error {
+	for _, s := range c.scopeChain() {
+		s.nodesMu.RLock()
+		nodes := append([]*node(nil), s.nodes...)
+		s.nodesMu.RUnlock()
+
+		var changed []*node
+		for _, n := range nodes {
+			ok, err := n.evaluateCondition()
+			if err != nil {
+				return err
+			}
+			if ok {
+				changed = append(changed, n)
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		s.providersMu.Lock()
+		s.providersVersion++
+		s.providersMu.Unlock()
+		for _, n := range changed {
+			n.called = false
+			n.groupValues = nil
+			for _, r := range n.resultList.DotResult() {
+				s.invalidate(key{name: r.Name, group: r.Group, t: r.Type})
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Container) getDisabledProviders(k key) []provider {
+	var providers []provider
+	for s := c; s != nil; s = s.parent {
+		s.providersMu.RLock()
+		for _, n := range s.providers[k] {
+			if n.disabled() {
+				providers = append(providers, n)
+			}
+		}
+		s.providersMu.RUnlock()
+	}
+	return providers
+}
+
+// isConditional reports whether p was provided with When.
+func isConditional(p provider) bool {
+	n, ok := p.(*node)
+	return ok && n.condition != nil
+}
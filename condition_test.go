@@ -0,0 +1,177 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhen(t *testing.T) {
+	type cache struct{ kind string }
+	type server struct{ cache *cache }
+
+	newRedis := func() *cache { return &cache{kind: "redis"} }
+	newMemory := func() *cache { return &cache{kind: "memory"} }
+
+	t.Run("disabled", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newRedis, When(func() bool { return false })))
+
+		err := c.Invoke(func(*cache) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`type \*dig.cache is not in the container: it is provided by `+
+				`"go.uber.org/dig".TestWhen\S+ \(\S+/condition_test.go:\d+\), disabled by dig.When`)
+
+		require.NoError(t, c.Invoke(func(p struct {
+			In
+
+			Cache *cache `optional:"true"`
+		}) {
+			assert.Nil(t, p.Cache)
+		}))
+	})
+
+	t.Run("alternatives", func(t *testing.T) {
+		redis := true
+		c := New()
+		require.NoError(t, c.Provide(newRedis, When(func() bool { return redis })))
+		require.NoError(t, c.Provide(newMemory, When(func() bool { return !redis })))
+		require.NoError(t, c.Provide(func(c *cache) *server { return &server{cache: c} }))
+
+		require.NoError(t, c.Invoke(func(s *server) {
+			assert.Equal(t, "redis", s.cache.kind)
+		}))
+
+		redis = false
+		require.NoError(t, c.Invoke(func(s *server) {
+			assert.Equal(t, "memory", s.cache.kind, "values must be rebuilt when conditions change")
+		}))
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newRedis, When(func() bool { return true })))
+		require.NoError(t, c.Provide(newMemory))
+
+		err := c.Invoke(func(*cache) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "type *dig.cache is provided by multiple constructors")
+	})
+
+	t.Run("EvaluateOnce", func(t *testing.T) {
+		enabled := true
+		var calls int
+		c := New()
+		require.NoError(t, c.Provide(newRedis, When(func() bool {
+			calls++
+			return enabled
+		}, EvaluateOnce())))
+
+		enabled = false
+		require.NoError(t, c.Invoke(func(*cache) {}))
+		require.NoError(t, c.Invoke(func(*cache) {}))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("value group", func(t *testing.T) {
+		enabled := false
+		c := New()
+		require.NoError(t, c.Provide(newRedis, Group("caches")))
+		require.NoError(t, c.Provide(newMemory, Group("caches"), When(func() bool { return enabled })))
+
+		type params struct {
+			In
+
+			Caches []*cache `group:"caches"`
+		}
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.Len(t, p.Caches, 1)
+		}))
+
+		enabled = true
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.Len(t, p.Caches, 2)
+		}))
+	})
+
+	t.Run("WarmUp", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *cache {
+			t.Error("disabled constructor must not be called")
+			return nil
+		}, When(func() bool { return false })))
+
+		require.NoError(t, c.WarmUp(context.Background()))
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		fail := false
+		c := New()
+		require.NoError(t, c.Provide(newRedis, When(func() bool {
+			if fail {
+				panic("great sadness")
+			}
+			return true
+		})))
+
+		fail = true
+		err := c.Invoke(func(*cache) {})
+		require.Error(t, err)
+		var condErr ConditionError
+		require.True(t, errors.As(err, &condErr))
+		assert.Equal(t, "great sadness", condErr.Panic)
+		assertErrorMatches(t, err,
+			`condition of function "go.uber.org/dig".TestWhen\S+ \(\S+/condition_test.go:\d+\) panicked: great sadness`)
+
+		err = New().Provide(newRedis, When(func() bool { panic("great sadness") }))
+		require.Error(t, err)
+		assert.True(t, errors.As(err, &condErr))
+	})
+
+	t.Run("graph", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newRedis, When(func() bool { return false })))
+		require.NoError(t, c.Provide(func() *server { return nil }, When(func() bool { return true })))
+
+		g := Graph(c)
+		require.Len(t, g.Ctors, 2)
+		assert.True(t, g.Ctors[0].Disabled)
+		assert.False(t, g.Ctors[1].Disabled)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		c := New()
+		err := c.Provide(newRedis, When(nil))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot use dig.When with a nil condition")
+
+		c = New(DefaultProvideOptions(When(func() bool { return true })))
+		err = c.Provide(newRedis)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "dig.When cannot be applied to all constructors")
+	})
+}
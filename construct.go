@@ -0,0 +1,63 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// Construct calls the constructor of the value of the type pointed to by
+// target, along with the constructors it depends on, even if nothing else
+// depends on that value yet. The value is kept in the container as usual.
+//
+// Use it to run constructors for their side effects, such as running
+// database migrations.
+//
+//   err := c.Construct(new(*Migrations))
+//
+// target is only used for its type and is not filled; use a Locator to
+// retrieve values. Named values are constructed with ResolveName, and all
+// constructors of a value group with ResolveGroup.
+//
+//   err := c.Construct(new([]http.Handler), dig.ResolveGroup("routes"))
+//
+// Construct is equivalent to invoking a function that only accepts the
+// value, and returns the same errors as Invoke. Like Invoke, it calls each
+// constructor at most once.
+func (c *Container) Construct(target interface{}, opts ...ResolveOption) error {
+	t := reflect.TypeOf(target)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return fmt.Errorf("can't construct %v (type %T): must be a pointer", target, target)
+	}
+
+	f, err := newResolveFunc(t.Elem(), newResolveOptions(opts), func(reflect.Value) {})
+	if err != nil {
+		return err
+	}
+
+	caller := digreflect.InspectCaller(1)
+	return c.Invoke(f, invokeOptionFunc(func(opts *invokeOptions) {
+		opts.caller = caller
+	}))
+}
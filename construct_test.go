@@ -0,0 +1,116 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstruct(t *testing.T) {
+	type Migrations struct{}
+
+	t.Run("calls the constructor once", func(t *testing.T) {
+		var calls int
+		c := New()
+		require.NoError(t, c.Provide(func(*bytes.Buffer) *Migrations {
+			calls++
+			return &Migrations{}
+		}))
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }))
+
+		require.NoError(t, c.Construct(new(*Migrations)))
+		assert.Equal(t, 1, calls)
+		require.NoError(t, c.Construct((**Migrations)(nil)))
+		require.NoError(t, c.Invoke(func(*Migrations) {}))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("named", func(t *testing.T) {
+		var calls int
+		c := New()
+		require.NoError(t, c.Provide(func() *Migrations {
+			calls++
+			return &Migrations{}
+		}, Name("ro")))
+
+		require.Error(t, c.Construct(new(*Migrations)))
+		require.NoError(t, c.Construct(new(*Migrations), ResolveName("ro")))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("group", func(t *testing.T) {
+		var calls []string
+		c := New()
+		for _, name := range []string{"a", "b"} {
+			name := name
+			require.NoError(t, c.Provide(func() string {
+				calls = append(calls, name)
+				return name
+			}, Group("names")))
+		}
+
+		require.NoError(t, c.Construct(new([]string), ResolveGroup("names")))
+		assert.ElementsMatch(t, []string{"a", "b"}, calls)
+	})
+
+	t.Run("constructor errors", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() (*Migrations, error) {
+			return nil, errors.New("great sadness")
+		}))
+
+		err := c.Construct(new(*Migrations))
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestConstruct\S+ \(\S+/construct_test.go:\d+\):`,
+			`failed to build \*dig.Migrations:`,
+			`great sadness`)
+		assert.Equal(t, "great sadness", RootCause(err).Error())
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		err := New().Construct(new(*Migrations))
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`missing dependencies for function "go.uber.org/dig".TestConstruct\S+ \(\S+/construct_test.go:\d+\):`,
+			`type \*dig.Migrations is not in the container`)
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		c := New()
+		err := c.Construct(Migrations{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "can't construct {} (type dig.Migrations): must be a pointer")
+		assert.Error(t, c.Construct(nil))
+	})
+
+	t.Run("not recorded as an invoke", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *Migrations { return &Migrations{} }))
+		require.NoError(t, c.Construct(new(*Migrations)))
+		assert.Empty(t, c.invokes)
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.uber.org/dig/internal/digreflect"
+	"go.uber.org/dig/internal/dot"
+)
+
+// ConstructionInfo describes why a constructor is being called. Constructors
+// receive it by accepting a parameter of this type, or a dig.In field of
+// this type, and dig fills it in without looking it up in the container.
+//
+//   func NewCache(info dig.ConstructionInfo, cfg *Config) *Cache {
+//     return newCache(cfg.Caches[info.Key.Name])
+//   }
+//
+// Because constructors are only called once, the Key differs between calls
+// only for constructors provided with MemoizeByArgs, for which the
+// ConstructionInfo is part of the arguments the results are cached by, or
+// for constructors that produce more than one value. Only constructors may
+// depend on a ConstructionInfo: functions that depend on it can't be
+// invoked.
+//
+// ConstructionInfo is not a dependency of the constructor: it isn't
+// reported as missing, doesn't take part in cycle detection, and isn't
+// drawn by Visualize.
+type ConstructionInfo struct {
+	// Value the constructor is called to build. For constructors called
+	// for another reason, such as building all the values of a value group,
+	// this is the first value the constructor produces.
+	Key Key
+
+	// Name and location of the constructor whose arguments required the
+	// value, as formatted in error messages. This is empty if the value was
+	// required by the invoked function itself.
+	Requester string
+
+	// Name and location of the function being invoked, or an empty string
+	// if the constructor is called outside of an Invoke.
+	Invoke string
+}
+
+var _constructionInfoType = reflect.TypeOf(ConstructionInfo{})
+
+// paramConstructionInfo is a ConstructionInfo parameter of a constructor.
+type paramConstructionInfo struct{}
+
+var _ param = paramConstructionInfo{}
+
+func (paramConstructionInfo) String() string { return _constructionInfoType.String() }
+
+// DotParam returns nothing: ConstructionInfo is not a dependency.
+func (paramConstructionInfo) DotParam() []*dot.Param { return nil }
+
+func (paramConstructionInfo) Build(c containerStore) (reflect.Value, error) {
+	return reflect.ValueOf(c.constructionInfo()), nil
+}
+
+// checkInvokable returns an error if a function with the given parameters
+// can't be invoked because it depends on a ConstructionInfo.
+func checkInvokable(pl paramList) error {
+	var found bool
+	walkParam(pl, paramVisitorFunc(func(p param) bool {
+		if _, ok := p.(paramConstructionInfo); ok {
+			found = true
+		}
+		return !found
+	}))
+	if found {
+		return fmt.Errorf("only constructors can depend on %v", _constructionInfoType)
+	}
+	return nil
+}
+
+// requestValue marks k as the value that the next constructor entered with
+// enterConstructor is called to build, and returns a function that clears
+// it.
+func (c *Container) requestValue(k key) (restore func()) {
+	return c.calls.constructing.request(k)
+}
+
+// request is requestValue for the Invoke this stack belongs to.
+func (s *constructionStack) request(k key) (restore func()) {
+	prev := s.requested
+	s.requested = k
+	return func() { s.requested = prev }
+}
+
+// enterInvoke marks fn as being invoked, and returns a function that
+// restores the function invoked previously.
+func (c *Container) enterInvoke(fn *digreflect.Func) (restore func()) {
+	s := &c.calls.constructing
+	prev, prevAt := s.invoked, s.invokedAt
+	s.invoked, s.invokedAt = fn, len(s.fns)
+	return func() { s.invoked, s.invokedAt = prev, prevAt }
+}
+
+// constructionInfo returns the ConstructionInfo of the innermost running
+// constructor.
+func (c *Container) constructionInfo() ConstructionInfo {
+	return c.calls.constructing.info()
+}
+
+// info is constructionInfo for the Invoke this stack belongs to.
+func (s *constructionStack) info() ConstructionInfo {
+	var info ConstructionInfo
+	top := len(s.fns) - 1
+	if top < 0 {
+		return info
+	}
+	info.Key = s.keys[top].exported()
+	if top > s.invokedAt {
+		info.Requester = s.fns[top-1].String()
+	}
+	if s.invoked != nil {
+		info.Invoke = s.invoked.String()
+	}
+	return info
+}
+
+// firstResultKey returns the key of the first value produced by a
+// constructor with the given results.
+func firstResultKey(rl resultList) key {
+	var k key
+	walkResult(rl, firstKeyFinder{key: &k})
+	return k
+}
+
+// firstKeyFinder is a resultVisitor that finds the first value produced by
+// a result.
+type firstKeyFinder struct {
+	key *key
+}
+
+func (f firstKeyFinder) Visit(res result) resultVisitor {
+	if *f.key != (key{}) {
+		return nil
+	}
+	switch r := res.(type) {
+	case resultSingle:
+		*f.key = key{name: r.Name, t: r.Type}
+	case resultGrouped:
+		if len(r.Groups) > 0 {
+			*f.key = key{group: r.Groups[0], t: r.Type}
+		} else if len(r.As) > 0 {
+			*f.key = key{group: r.As[0].Group, t: r.As[0].Type}
+		}
+	}
+	return f
+}
+
+func (f firstKeyFinder) AnnotateWithField(resultObjectField) resultVisitor { return f }
+func (f firstKeyFinder) AnnotateWithPosition(int) resultVisitor            { return f }
@@ -0,0 +1,149 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstructionInfo(t *testing.T) {
+	type cache struct{ info ConstructionInfo }
+	newCache := func(info ConstructionInfo) *cache { return &cache{info: info} }
+	cacheType := reflect.TypeOf(&cache{})
+
+	t.Run("named value", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newCache, Name("sessions")))
+
+		require.NoError(t, c.Invoke(func(p struct {
+			In
+
+			Cache *cache `name:"sessions"`
+		}) {
+			info := p.Cache.info
+			assert.Equal(t, Key{Type: cacheType, Name: "sessions"}, info.Key)
+			assert.Empty(t, info.Requester)
+			assert.Contains(t, info.Invoke, "TestConstructionInfo")
+		}))
+	})
+
+	t.Run("requester", func(t *testing.T) {
+		type server struct{ cache *cache }
+
+		c := New()
+		require.NoError(t, c.Provide(newCache))
+		require.NoError(t, c.Provide(func(c *cache) *server { return &server{cache: c} }))
+
+		require.NoError(t, c.Invoke(func(s *server) {
+			info := s.cache.info
+			assert.Equal(t, Key{Type: cacheType}, info.Key)
+			assert.Contains(t, info.Requester, "TestConstructionInfo")
+			assert.Contains(t, info.Requester, "constructioninfo_test.go")
+			assert.NotEqual(t, info.Requester, info.Invoke)
+		}))
+	})
+
+	t.Run("requested result", func(t *testing.T) {
+		type out struct {
+			Out
+
+			Sessions *cache `name:"sessions"`
+			Users    *cache `name:"users"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func(info ConstructionInfo) out {
+			return out{Sessions: &cache{info: info}, Users: &cache{info: info}}
+		}))
+
+		require.NoError(t, c.Invoke(func(p struct {
+			In
+
+			Users *cache `name:"users"`
+		}) {
+			assert.Equal(t, Key{Type: cacheType, Name: "users"}, p.Users.info.Key)
+		}))
+	})
+
+	t.Run("value group", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newCache, Group("caches")))
+
+		require.NoError(t, c.Invoke(func(p struct {
+			In
+
+			Caches []*cache `group:"caches"`
+		}) {
+			require.Len(t, p.Caches, 1)
+			assert.Equal(t, Key{Type: cacheType, Group: "caches"}, p.Caches[0].info.Key)
+		}))
+	})
+
+	t.Run("parameter object", func(t *testing.T) {
+		type params struct {
+			In
+
+			Info ConstructionInfo
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func(p params) *cache { return &cache{info: p.Info} }))
+
+		require.NoError(t, c.Invoke(func(c *cache) {
+			assert.Equal(t, Key{Type: cacheType}, c.info.Key)
+		}))
+	})
+
+	t.Run("not a dependency", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newCache))
+
+		g := Graph(c)
+		require.Len(t, g.Ctors, 1)
+		assert.Empty(t, g.Ctors[0].Params)
+		assert.NoError(t, c.Invoke(func(*cache) {}))
+	})
+
+	t.Run("invoke", func(t *testing.T) {
+		c := New()
+		err := c.Invoke(func(ConstructionInfo) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "only constructors can depend on dig.ConstructionInfo")
+	})
+
+	t.Run("named field", func(t *testing.T) {
+		type params struct {
+			In
+
+			Info ConstructionInfo `name:"info"`
+		}
+
+		c := New()
+		err := c.Provide(func(params) *cache { return nil })
+		require.Error(t, err)
+		assert.Contains(t, err.Error(),
+			`field "Info" of type dig.ConstructionInfo cannot be named or optional: it's always provided by dig`)
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// CopyOnInject is a ProvideOption for constructors of maps and slices. Each
+// consumer of the values receives its own shallow copy of them, so that
+// consumers can't observe each other's changes.
+//
+//   c.Provide(NewDefaultHeaders, dig.CopyOnInject())
+//
+// Only the map or slice itself is copied: consumers still share the values
+// that its elements point to. Constructors provided with this option may
+// only produce maps and slices, and the option cannot be combined with Group
+// or AsGroup.
+func CopyOnInject() ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.CopyOnInject = true
+	})
+}
+
+// checkCopyOnInject returns an error if the values with the given keys,
+// produced by n, can't be copied for their consumers.
+func checkCopyOnInject(n *node, keys map[key]struct{}) error {
+	for k := range keys {
+		switch k.t.Kind() {
+		case reflect.Map, reflect.Slice:
+			continue
+		}
+		return fmt.Errorf("cannot use dig.CopyOnInject with %v: %v is not a map or a slice", n.location, k)
+	}
+	return nil
+}
+
+// markCopyOnInject makes the consumers of the values with the given keys
+// receive copies of them.
+func (c *Container) markCopyOnInject(keys map[key]struct{}) {
+	if c.copyOnInject == nil {
+		c.copyOnInject = make(map[key]struct{}, len(keys))
+	}
+	for k := range keys {
+		c.copyOnInject[k] = struct{}{}
+	}
+}
+
+func (c *Container) copiesOnInject(k key) bool {
+	for s := c; s != nil; s = s.parent {
+		if _, ok := s.copyOnInject[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// shallowCopy returns a copy of the given map or slice that shares its
+// elements.
+func shallowCopy(v reflect.Value) reflect.Value {
+	if v.IsNil() {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			cp.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return cp
+	case reflect.Slice:
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(cp, v)
+		return cp
+	default:
+		return v
+	}
+}
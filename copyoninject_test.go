@@ -0,0 +1,114 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyOnInject(t *testing.T) {
+	type Headers map[string]string
+	type Hosts []string
+
+	newContainer := func(t *testing.T, opts ...ProvideOption) *Container {
+		c := New()
+		require.NoError(t, c.Provide(func() Headers {
+			return Headers{"Accept": "*/*"}
+		}, opts...))
+		require.NoError(t, c.Provide(func() Hosts {
+			return Hosts{"a", "b"}
+		}, opts...))
+		return c
+	}
+
+	mutate := func(h Headers, hosts Hosts) {
+		h["Accept"] = "text/plain"
+		hosts[0] = "c"
+	}
+
+	t.Run("shared by default", func(t *testing.T) {
+		c := newContainer(t)
+		require.NoError(t, c.Invoke(mutate))
+		require.NoError(t, c.Invoke(func(h Headers, hosts Hosts) {
+			assert.Equal(t, Headers{"Accept": "text/plain"}, h)
+			assert.Equal(t, Hosts{"c", "b"}, hosts)
+		}))
+	})
+
+	t.Run("copied", func(t *testing.T) {
+		c := newContainer(t, CopyOnInject())
+		require.NoError(t, c.Invoke(mutate))
+		require.NoError(t, c.Invoke(func(h Headers, hosts Hosts) {
+			assert.Equal(t, Headers{"Accept": "*/*"}, h)
+			assert.Equal(t, Hosts{"a", "b"}, hosts)
+		}))
+	})
+
+	t.Run("copied for constructors", func(t *testing.T) {
+		type Client struct{ headers Headers }
+
+		c := newContainer(t, CopyOnInject())
+		require.NoError(t, c.Provide(func(h Headers) *Client {
+			h["User-Agent"] = "client"
+			return &Client{headers: h}
+		}))
+		require.NoError(t, c.Invoke(func(cl *Client, h Headers) {
+			assert.Equal(t, "client", cl.headers["User-Agent"])
+			assert.NotContains(t, h, "User-Agent")
+		}))
+	})
+
+	t.Run("scope", func(t *testing.T) {
+		c := newContainer(t, CopyOnInject())
+		s := c.Scope("child")
+		require.NoError(t, s.Invoke(mutate))
+		require.NoError(t, c.Invoke(func(h Headers) {
+			assert.Equal(t, Headers{"Accept": "*/*"}, h)
+		}))
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() Headers { return nil }, CopyOnInject()))
+		require.NoError(t, c.Invoke(func(h Headers) {
+			assert.Nil(t, h)
+		}))
+	})
+
+	t.Run("not a map or a slice", func(t *testing.T) {
+		c := New()
+		err := c.Provide(func() (Headers, *Hosts) { return nil, nil }, CopyOnInject())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot use dig.CopyOnInject with")
+		assert.Contains(t, err.Error(), "*dig.Hosts is not a map or a slice")
+		assert.Error(t, c.Invoke(func(Headers) {}), "nothing must be provided")
+	})
+
+	t.Run("value groups", func(t *testing.T) {
+		c := New()
+		err := c.Provide(func() Hosts { return nil }, CopyOnInject(), Group("hosts"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot use dig.CopyOnInject with value groups")
+	})
+}
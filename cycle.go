@@ -55,6 +55,26 @@ func (e errCycleDetected) Error() string {
 	return b.String()
 }
 
+// errRecursiveCall is returned when a constructor is needed to build its own
+// arguments. This happens only through optional dependencies, since cycles
+// of required dependencies are rejected. The optional dependency that closes
+// the loop is built as its zero value.
+type errRecursiveCall struct {
+	Func *digreflect.Func
+}
+
+func (e errRecursiveCall) Error() string {
+	return fmt.Sprintf("cannot call function %v while it builds its own arguments: "+
+		"its optional dependencies lead back to it", e.Func)
+}
+
+// isRecursiveCall reports whether the given error was caused by a
+// constructor needed to build its own arguments.
+func isRecursiveCall(err error) bool {
+	_, ok := RootCause(err).(errRecursiveCall)
+	return ok
+}
+
 // IsCycleDetected returns a boolean as to whether the provided error indicates
 // a cycle was detected in the container graph.
 func IsCycleDetected(err error) bool {
@@ -62,6 +82,97 @@ func IsCycleDetected(err error) bool {
 	return ok
 }
 
+// cycle returns the constructors forming the cycle described by this error.
+//
+// The first entry of a path only leads to the cycle: it's either the
+// constructor that introduced the cycle with the key it provides, or a
+// constructor that depends on a constructor in the cycle. Every following
+// entry is a constructor with the key it depends on, and the key of the last
+// entry is provided by the constructor of the second one.
+func (e errCycleDetected) cycle() *Cycle {
+	cy := &Cycle{Edges: make([]CycleEdge, 0, len(e.Path)-1)}
+	for _, entry := range e.Path[1:] {
+		cy.Edges = append(cy.Edges, CycleEdge{
+			Name:    entry.Func.Name,
+			Package: entry.Func.Package,
+			File:    entry.Func.File,
+			Line:    entry.Func.Line,
+			Key:     entry.Key.exported(),
+		})
+	}
+	return cy
+}
+
+// Cycle describes a dependency cycle in a container. See HasCycle.
+type Cycle struct {
+	// Constructors forming the cycle, in order. Each constructor depends on
+	// a value produced by the constructor of the next edge, and the last
+	// one depends on a value produced by the first one.
+	Edges []CycleEdge
+}
+
+// CycleEdge is a constructor in a Cycle with the value through which it
+// depends on the next constructor of the cycle.
+type CycleEdge struct {
+	// Name, package, and location at which the constructor was defined.
+	Name    string
+	Package string
+	File    string
+	Line    int
+
+	// Value or value group the constructor depends on.
+	Key Key
+}
+
+func (cy *Cycle) String() string {
+	// We get something like,
+	//
+	//   "path/to/package".NewFoo (path/to/file.go:42) depends on bar
+	//   	provided by "another/package".NewBar (somefile.go:1) depends on foo
+	//   	provided by "path/to/package".NewFoo (path/to/file.go:42)
+	//
+	b := new(bytes.Buffer)
+	for i, e := range cy.Edges {
+		if i > 0 {
+			b.WriteString("\n\tprovided by ")
+		}
+		fmt.Fprintf(b, "%q.%v (%v:%v) depends on %v", e.Package, e.Name, e.File, e.Line, e.Key)
+	}
+	if len(cy.Edges) > 0 {
+		e := cy.Edges[0]
+		fmt.Fprintf(b, "\n\tprovided by %q.%v (%v:%v)", e.Package, e.Name, e.File, e.Line)
+	}
+	return b.String()
+}
+
+// HasCycle reports whether the dependency graph of this container, including
+// the constructors of its parents, contains a cycle, and describes one of the
+// cycles if so.
+//
+// This is useful with DeferAcyclicVerification, which otherwise only reports
+// cycles when Invoke is called:
+//
+//   c := dig.New(dig.DeferAcyclicVerification())
+//   // ... provide many constructors ...
+//   if cy, ok := c.HasCycle(); ok {
+//     log.Fatalf("dependency cycle: %v", cy)
+//   }
+//
+// Like Invoke, HasCycle remembers that the graph is free of cycles until more
+// constructors are provided, so subsequent checks and Invokes are cheap.
+func (c *Container) HasCycle() (*Cycle, bool) {
+	for _, s := range c.scopeChain() {
+		if s.verifiedAcyclic() {
+			continue
+		}
+		if err, ok := s.detectCycle(); ok {
+			return err.cycle(), true
+		}
+		s.setVerifiedAcyclic()
+	}
+	return nil, false
+}
+
 func verifyAcyclic(c containerStore, n provider, k key) error {
 	visited := make(map[key]struct{})
 	err := detectCycles(n, c, []cycleEntry{
@@ -73,59 +184,65 @@ func verifyAcyclic(c containerStore, n provider, k key) error {
 	return err
 }
 
+// dependencyEdge returns the key through which a constructor depends on the
+// given parameter and the providers of that key. It returns false for
+// parameters that aren't edges of the graph, like parameter objects.
+func dependencyEdge(c containerStore, param param) (key, []provider, bool) {
+	switch p := param.(type) {
+	case paramSingle:
+		providers := c.getValueProviders(p.Name, p.Type)
+		if impls := c.getImplementations(p.Name, p.Type); len(providers) == 0 && len(impls) == 1 {
+			// Interfaces resolved by implementation depend on the
+			// implementation.
+			return key{name: p.Name, t: impls[0]}, c.getValueProviders(p.Name, impls[0]), true
+		}
+		return key{name: p.Name, t: p.Type}, providers, true
+	case paramGroupedSlice:
+		// NOTE: The key uses the element type, not the slice type.
+		k := key{group: p.Group, t: p.Type.Elem()}
+		return k, c.getGroupProviders(p.Group, p.Type.Elem()), true
+	default:
+		return key{}, nil, false
+	}
+}
+
+// isOptionalEdge reports whether the given parameter is an optional
+// dependency. Cycles through optional dependencies are not reported: the
+// dependency that closes the loop is built as its zero value instead. See
+// errRecursiveCall.
+func isOptionalEdge(param param) bool {
+	ps, ok := param.(paramSingle)
+	return ok && ps.Optional
+}
+
 func detectCycles(n provider, c containerStore, path []cycleEntry, visited map[key]struct{}) error {
 	var err error
 	walkParam(n.ParamList(), paramVisitorFunc(func(param param) bool {
-		if err != nil {
+		if err != nil || isOptionalEdge(param) {
 			return false
 		}
 
-		var (
-			k         key
-			providers []provider
-		)
-		switch p := param.(type) {
-		case paramSingle:
-			k = key{name: p.Name, t: p.Type}
-			if _, ok := visited[k]; ok {
-				// We've already checked the dependencies for this type.
-				return false
-			}
-			providers = c.getValueProviders(p.Name, p.Type)
-		case paramGroupedSlice:
-			// NOTE: The key uses the element type, not the slice type.
-			k = key{group: p.Group, t: p.Type.Elem()}
-			if _, ok := visited[k]; ok {
-				// We've already checked the dependencies for this type.
-				return false
-			}
-			providers = c.getGroupProviders(p.Group, p.Type.Elem())
-		default:
+		k, providers, ok := dependencyEdge(c, param)
+		if !ok {
 			// Recurse for non-edge params.
 			return true
 		}
+		if _, ok := visited[k]; ok {
+			// We've already checked the dependencies for this key.
+			return false
+		}
 
 		entry := cycleEntry{Func: n.Location(), Key: k}
 
-		if len(path) > 0 {
-			// Only mark a key as visited if path exists, i.e. this is not the
-			// first iteration through the c.verifyAcyclic() check. Otherwise the
-			// early exit from checking visited above will short circuit the
-			// cycle check below.
-			visited[k] = struct{}{}
-
-			// If it exists, the first element of path is the new addition to the
-			// graph, therefore it must be in any cycle that exists, assuming
-			// verifyAcyclic has been run for every previous Provide.
-			//
-			// Alternatively, if deferAcyclicVerification was set and detectCycles
-			// is only being called before the first Invoke, each node in the
-			// graph will be tested as the first element of the path, so any
-			// cycle that exists is guaranteed to trip the following condition.
-			if path[0].Key == k {
-				err = errCycleDetected{Path: append(path, entry)}
-				return false
-			}
+		visited[k] = struct{}{}
+
+		// The first element of path is the new addition to the graph,
+		// therefore it must be in any cycle that exists, assuming
+		// verifyAcyclic has been run for every previous Provide. Graphs
+		// verified all at once use cycleDetector instead.
+		if path[0].Key == k {
+			err = errCycleDetected{Path: append(path, entry)}
+			return false
 		}
 
 		for _, n := range providers {
@@ -140,3 +257,85 @@ func detectCycles(n provider, c containerStore, path []cycleEntry, visited map[k
 
 	return err
 }
+
+// cycleState is the progress of a cycleDetector on a key.
+type cycleState int
+
+const (
+	// The key was not reached yet.
+	cycleUnvisited cycleState = iota
+
+	// The key is on the current path: reaching it again closes a cycle.
+	cycleVisiting
+
+	// The key and everything it depends on are free of cycles.
+	cycleVisited
+)
+
+// cycleDetector finds cycles anywhere in the graph of a container with a
+// single depth-first search, visiting every key and its providers at most
+// once. Unlike verifyAcyclic, it does not need to know which constructor was
+// added last, so it's used to verify the graph all at once when acyclic
+// verification was deferred.
+type cycleDetector struct {
+	c      containerStore
+	states map[key]cycleState
+
+	// Edges leading from the constructor the search started with to the
+	// key being visited.
+	path []cycleEntry
+}
+
+func newCycleDetector(c containerStore) *cycleDetector {
+	return &cycleDetector{c: c, states: make(map[key]cycleState)}
+}
+
+// visit searches for cycles through the dependencies of the given
+// constructor.
+func (d *cycleDetector) visit(n provider) (err errCycleDetected, found bool) {
+	walkParam(n.ParamList(), paramVisitorFunc(func(param param) bool {
+		if found || isOptionalEdge(param) {
+			return false
+		}
+
+		k, providers, ok := dependencyEdge(d.c, param)
+		if !ok {
+			// Recurse for non-edge params.
+			return true
+		}
+
+		entry := cycleEntry{Func: n.Location(), Key: k}
+		switch d.states[k] {
+		case cycleVisited:
+			return false
+		case cycleVisiting:
+			err, found = d.cycleTo(entry), true
+			return false
+		}
+
+		d.states[k] = cycleVisiting
+		d.path = append(d.path, entry)
+		for _, p := range providers {
+			if err, found = d.visit(p); found {
+				return false
+			}
+		}
+		d.path = d.path[:len(d.path)-1]
+		d.states[k] = cycleVisited
+		return false
+	}))
+	return err, found
+}
+
+// cycleTo returns the cycle closed by the given entry, whose key is on the
+// current path.
+func (d *cycleDetector) cycleTo(entry cycleEntry) errCycleDetected {
+	for i, e := range d.path {
+		if e.Key == entry.Key {
+			path := make([]cycleEntry, 0, len(d.path)-i+1)
+			path = append(path, d.path[i:]...)
+			return errCycleDetected{Path: append(path, entry)}
+		}
+	}
+	panic(fmt.Sprintf("dig: key %v is not on the current path", entry.Key))
+}
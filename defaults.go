@@ -0,0 +1,114 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultProvideOptions is an Option that applies the given ProvideOptions
+// to every constructor provided to the container and its scopes.
+//
+//   c := dig.New(dig.DefaultProvideOptions(dig.MemoizeByArgs()))
+//
+// The defaults are combined with the options passed to Provide, so Provide
+// fails for constructors that can't be used with them, as it would if they
+// were passed to Provide.
+//
+// Options that only make sense for a single constructor, such as dig.Name,
+// dig.Group and dig.Doc, cannot be used as defaults. Provide fails if they
+// are.
+func DefaultProvideOptions(opts ...ProvideOption) Option {
+	return optionFunc(func(c *Container) {
+		c.defaultProvideOptions = append(c.defaultProvideOptions, opts...)
+	})
+}
+
+// provideOptions builds the options for a single call to Provide from the
+// defaults of the container and the given options.
+func (c *Container) provideOptions(opts []ProvideOption) (provideOptions, error) {
+	var defaults provideOptions
+	for _, o := range c.defaultProvideOptions {
+		o.applyProvideOption(&defaults)
+	}
+	if err := defaults.validateDefaults(); err != nil {
+		return provideOptions{}, err
+	}
+
+	var options provideOptions
+	for _, o := range opts {
+		o.applyProvideOption(&options)
+	}
+	options.mergeDefaults(defaults)
+	if err := options.Validate(); err != nil {
+		return provideOptions{}, err
+	}
+	return options, nil
+}
+
+// validateDefaults verifies that these options may be used with
+// DefaultProvideOptions.
+func (o *provideOptions) validateDefaults() error {
+	switch {
+	case o.Name != "":
+		return fmt.Errorf("invalid dig.DefaultProvideOptions: "+
+			"dig.Name(%q) cannot be applied to all constructors", o.Name)
+	case len(o.Groups) > 0:
+		return fmt.Errorf("invalid dig.DefaultProvideOptions: "+
+			"dig.Group(%q) cannot be applied to all constructors", o.Groups[0])
+	case len(o.AsGroups) > 0:
+		return fmt.Errorf("invalid dig.DefaultProvideOptions: "+
+			"dig.AsGroup(%q, %v) cannot be applied to all constructors", o.AsGroups[0].Group, o.AsGroups[0].Target)
+	case o.Conditional || o.When != nil || o.WhenOnce:
+		return fmt.Errorf("invalid dig.DefaultProvideOptions: " +
+			"dig.When cannot be applied to all constructors")
+	case o.NameAll:
+		return fmt.Errorf("invalid dig.DefaultProvideOptions: " +
+			"dig.NameAllResults cannot be applied to all constructors")
+	case len(o.ResultNames) > 0:
+		return fmt.Errorf("invalid dig.DefaultProvideOptions: "+
+			"dig.NameResult(%d, %q) cannot be applied to all constructors", o.ResultNames[0].Index, o.ResultNames[0].Name)
+	case o.Doc != "":
+		return fmt.Errorf("invalid dig.DefaultProvideOptions: "+
+			"dig.Doc(%q) cannot be applied to all constructors", o.Doc)
+	case o.Location != nil || o.Mount != "" || o.Bridge != "" || o.Ambient:
+		// These are only set for the constructors that dig synthesizes.
+		return errors.New("invalid dig.DefaultProvideOptions: " +
+			"options of synthesized constructors cannot be applied to all constructors")
+	}
+	if err := o.Validate(); err != nil {
+		return fmt.Errorf("invalid dig.DefaultProvideOptions: %v", err)
+	}
+	return nil
+}
+
+// mergeDefaults fills in the settings that weren't specified in these
+// options from the given defaults.
+func (o *provideOptions) mergeDefaults(defaults provideOptions) {
+	o.PopulateFields = o.PopulateFields || defaults.PopulateFields
+	o.MemoizeByArgs = o.MemoizeByArgs || defaults.MemoizeByArgs
+	o.IfNotPresent = o.IfNotPresent || defaults.IfNotPresent
+	o.AllowUnbuffered = o.AllowUnbuffered || defaults.AllowUnbuffered
+	o.CopyOnInject = o.CopyOnInject || defaults.CopyOnInject
+	o.CommitPartialResults = o.CommitPartialResults || defaults.CommitPartialResults
+	o.AllowNoResults = o.AllowNoResults || defaults.AllowNoResults
+}
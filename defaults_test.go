@@ -0,0 +1,236 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultProvideOptions(t *testing.T) {
+	type Config struct{ N int }
+	type A struct {
+		Config *Config `inject:"true"`
+	}
+	type B struct {
+		Config *Config `inject:"true"`
+	}
+	newConfig := func() *Config { return &Config{N: 42} }
+
+	t.Run("applied to every constructor", func(t *testing.T) {
+		c := New(DefaultProvideOptions(PopulateFields()))
+		require.NoError(t, c.Provide(newConfig))
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Provide(func() *B { return &B{} }))
+
+		require.NoError(t, c.Invoke(func(a *A, b *B) {
+			require.NotNil(t, a.Config)
+			require.NotNil(t, b.Config)
+			assert.Equal(t, 42, a.Config.N)
+			assert.Equal(t, 42, b.Config.N)
+		}))
+	})
+
+	t.Run("inherited by scopes", func(t *testing.T) {
+		c := New(DefaultProvideOptions(PopulateFields()))
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(newConfig))
+		require.NoError(t, s.Provide(func() *A { return &A{} }))
+
+		require.NoError(t, s.Invoke(func(a *A) {
+			require.NotNil(t, a.Config)
+			assert.Equal(t, 42, a.Config.N)
+		}))
+	})
+
+	t.Run("repeated option", func(t *testing.T) {
+		c := New(
+			DefaultProvideOptions(PopulateFields()),
+			DefaultProvideOptions(AllowNoResults()),
+		)
+		require.NoError(t, c.Provide(newConfig))
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.Len(t, c.nodes, 2)
+		assert.True(t, c.nodes[1].allowNoResults, "later defaults must be applied")
+
+		require.NoError(t, c.Invoke(func(a *A) {
+			require.NotNil(t, a.Config)
+			assert.Equal(t, 42, a.Config.N)
+		}))
+	})
+
+	t.Run("combined with per-call options", func(t *testing.T) {
+		c := New(DefaultProvideOptions(PopulateFields()))
+		require.NoError(t, c.Provide(newConfig))
+		require.NoError(t, c.Provide(func() *A { return &A{} }, Name("a")))
+
+		type params struct {
+			In
+
+			A *A `name:"a"`
+		}
+		require.NoError(t, c.Invoke(func(p params) {
+			require.NotNil(t, p.A.Config)
+			assert.Equal(t, 42, p.A.Config.N)
+		}))
+	})
+
+	t.Run("if not present", func(t *testing.T) {
+		c := New(DefaultProvideOptions(IfNotPresent()))
+		require.NoError(t, c.Provide(newConfig))
+		require.Len(t, c.nodes, 1)
+		assert.True(t, c.nodes[0].Fallback(), "constructor must be a fallback")
+
+		err := c.Provide(func() *A { return &A{} }, Group("a"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot use dig.IfNotPresent with value groups")
+	})
+
+	t.Run("allow unbuffered", func(t *testing.T) {
+		c := New(DefaultProvideOptions(AllowUnbuffered()))
+		require.NoError(t, c.Provide(func() chan int { return make(chan int) }))
+		require.Len(t, c.nodes, 1)
+		assert.True(t, c.nodes[0].allowUnbuffered, "unbuffered channels must be allowed")
+	})
+
+	t.Run("copy on inject", func(t *testing.T) {
+		type Headers map[string]string
+
+		c := New(DefaultProvideOptions(CopyOnInject()))
+		require.NoError(t, c.Provide(func() Headers {
+			return Headers{"Accept": "*/*"}
+		}))
+		require.NoError(t, c.Invoke(func(h Headers) {
+			h["Accept"] = "text/plain"
+		}))
+		require.NoError(t, c.Invoke(func(h Headers) {
+			assert.Equal(t, Headers{"Accept": "*/*"}, h)
+		}))
+	})
+
+	t.Run("commit partial results", func(t *testing.T) {
+		c := New(DefaultProvideOptions(CommitPartialResults()))
+		require.NoError(t, c.Provide(func() (*Config, *A, error) {
+			return &Config{N: 42}, nil, errors.New("great sadness")
+		}))
+		require.NoError(t, c.Invoke(func(cfg *Config) {
+			assert.Equal(t, 42, cfg.N)
+		}))
+	})
+
+	t.Run("allow no results", func(t *testing.T) {
+		c := New(DefaultProvideOptions(AllowNoResults()))
+		require.NoError(t, c.Provide(newConfig))
+		require.Len(t, c.nodes, 1)
+		assert.True(t, c.nodes[0].allowNoResults, "constructor must be allowed to commit no values")
+	})
+
+	t.Run("every option is applied or rejected", func(t *testing.T) {
+		// Options that are neither merged nor rejected would be dropped
+		// silently.
+		typ := reflect.TypeOf(provideOptions{})
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			t.Run(f.Name, func(t *testing.T) {
+				var defaults provideOptions
+				v := reflect.ValueOf(&defaults).Elem().Field(i)
+				switch f.Type.Kind() {
+				case reflect.Bool:
+					v.SetBool(true)
+				case reflect.String:
+					v.SetString("foo")
+				case reflect.Slice:
+					v.Set(reflect.MakeSlice(f.Type, 1, 1))
+				case reflect.Func:
+					v.Set(reflect.MakeFunc(f.Type, func([]reflect.Value) []reflect.Value {
+						return []reflect.Value{reflect.Zero(f.Type.Out(0))}
+					}))
+				case reflect.Ptr:
+					v.Set(reflect.New(f.Type.Elem()))
+				default:
+					require.FailNow(t, "unsupported field type", "%v", f.Type)
+				}
+
+				if defaults.validateDefaults() != nil {
+					return
+				}
+				var options provideOptions
+				options.mergeDefaults(defaults)
+				assert.False(t, reflect.ValueOf(options).Field(i).IsZero(),
+					"%v must be merged into the options or rejected by validateDefaults", f.Name)
+			})
+		}
+	})
+
+	t.Run("rejected defaults", func(t *testing.T) {
+		tests := []struct {
+			desc string
+			opts []ProvideOption
+			err  string
+		}{
+			{
+				desc: "name",
+				opts: []ProvideOption{Name("foo")},
+				err:  `invalid dig.DefaultProvideOptions: dig.Name("foo") cannot be applied to all constructors`,
+			},
+			{
+				desc: "group",
+				opts: []ProvideOption{Group("foo")},
+				err:  `invalid dig.DefaultProvideOptions: dig.Group("foo") cannot be applied to all constructors`,
+			},
+			{
+				desc: "as group",
+				opts: []ProvideOption{AsGroup("foo", new(fmt.Stringer))},
+				err:  `invalid dig.DefaultProvideOptions: dig.AsGroup("foo", *fmt.Stringer) cannot be applied to all constructors`,
+			},
+			{
+				desc: "name all results",
+				opts: []ProvideOption{NameAllResults()},
+				err:  `invalid dig.DefaultProvideOptions: dig.NameAllResults cannot be applied to all constructors`,
+			},
+			{
+				desc: "name result",
+				opts: []ProvideOption{NameResult(0, "foo")},
+				err:  `invalid dig.DefaultProvideOptions: dig.NameResult(0, "foo") cannot be applied to all constructors`,
+			},
+			{
+				desc: "doc",
+				opts: []ProvideOption{Doc("foo")},
+				err:  `invalid dig.DefaultProvideOptions: dig.Doc("foo") cannot be applied to all constructors`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				c := New(DefaultProvideOptions(tt.opts...))
+				err := c.Provide(newConfig)
+				require.Error(t, err)
+				assert.Equal(t, tt.err, err.Error())
+				assert.Empty(t, Graph(c).Ctors, "nothing must be provided")
+			})
+		}
+	})
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// _defaultMaxDepth is the default maximum number of nested constructor
+// calls. See MaxConstructionDepth.
+const _defaultMaxDepth = 512
+
+// _depthExceededChain is the number of constructors reported by a
+// DepthExceededError.
+const _depthExceededChain = 10
+
+// MaxConstructionDepth is an Option that limits how deeply constructor
+// calls may be nested to build a value, including through Invokes made from
+// inside constructors. Deeper chains fail with a DepthExceededError instead
+// of exhausting the stack.
+//
+//   c := dig.New(dig.MaxConstructionDepth(1024))
+//
+// The default limit is 512. Limits below one are ignored.
+func MaxConstructionDepth(n int) Option {
+	return optionFunc(func(c *Container) {
+		if n > 0 {
+			c.maxDepth = n
+		}
+	})
+}
+
+// DepthExceededError is returned by Invoke when building its arguments
+// requires nesting more constructor calls than allowed by
+// MaxConstructionDepth.
+type DepthExceededError struct {
+	// Maximum number of nested constructor calls.
+	Limit int
+
+	// Last constructors on the chain, outermost first.
+	chain []*digreflect.Func
+}
+
+func (e DepthExceededError) Error() string {
+	fns := make([]string, len(e.chain))
+	for i, fn := range e.chain {
+		fns[i] = fn.String()
+	}
+	return fmt.Sprintf("cannot nest more than %d constructor calls; last %d constructors on the chain: %v",
+		e.Limit, len(e.chain), strings.Join(fns, " -> "))
+}
+
+// constructionStack holds the constructors that are running or building
+// their arguments for an Invoke. It's part of the callLog of the Invoke, so
+// that concurrent Invokes of scopes each have their own.
+type constructionStack struct {
+	fns []*digreflect.Func
+
+	// Values the constructors in fns are called to build. See
+	// ConstructionInfo.
+	keys []key
+
+	// Value the next constructor entered is called to build, if it was
+	// requested with requestValue.
+	requested key
+
+	// Innermost function being invoked, and the number of constructors
+	// that were running when it was invoked.
+	invoked   *digreflect.Func
+	invokedAt int
+}
+
+// enterConstructor marks the constructor fn as running. k is the value it's
+// called to build, unless another one was requested with requestValue.
+func (c *Container) enterConstructor(fn *digreflect.Func, k key) error {
+	return c.calls.constructing.enter(c.maxDepth, fn, k)
+}
+
+func (c *Container) exitConstructor() {
+	c.calls.constructing.exit()
+}
+
+// enter marks the constructor fn as running, unless more than max
+// constructors would be running. See enterConstructor.
+func (s *constructionStack) enter(max int, fn *digreflect.Func, k key) error {
+	if len(s.fns) >= max {
+		start := len(s.fns) - _depthExceededChain + 1
+		if start < 0 {
+			start = 0
+		}
+		chain := append(append([]*digreflect.Func(nil), s.fns[start:]...), fn)
+		return DepthExceededError{Limit: max, chain: chain}
+	}
+	if s.requested != (key{}) {
+		k, s.requested = s.requested, key{}
+	}
+	s.fns = append(s.fns, fn)
+	s.keys = append(s.keys, k)
+	return nil
+}
+
+func (s *constructionStack) exit() {
+	s.fns[len(s.fns)-1] = nil
+	s.fns = s.fns[:len(s.fns)-1]
+	s.keys = s.keys[:len(s.keys)-1]
+}
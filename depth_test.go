@@ -0,0 +1,136 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// depthType returns a distinct type for each level of a constructor chain.
+func depthType(i int) reflect.Type {
+	return reflect.ArrayOf(i, reflect.TypeOf(0))
+}
+
+// provideChain provides constructors for the types of levels 0 to n-1,
+// where each level depends on the next.
+func provideChain(t *testing.T, c *Container, n int) {
+	for i := 0; i < n; i++ {
+		var in []reflect.Type
+		if i+1 < n {
+			in = []reflect.Type{depthType(i + 1)}
+		}
+		out := depthType(i)
+		ctor := reflect.MakeFunc(
+			reflect.FuncOf(in, []reflect.Type{out}, false /* variadic */),
+			func([]reflect.Value) []reflect.Value { return []reflect.Value{reflect.Zero(out)} },
+		)
+		require.NoError(t, c.Provide(ctor.Interface()))
+	}
+}
+
+func TestMaxConstructionDepth(t *testing.T) {
+	invokeLevel := func(c *Container, i int) error {
+		f := reflect.MakeFunc(
+			reflect.FuncOf([]reflect.Type{depthType(i)}, nil /* out */, false /* variadic */),
+			func([]reflect.Value) []reflect.Value { return nil },
+		)
+		return c.Invoke(f.Interface())
+	}
+
+	t.Run("chain within the limit", func(t *testing.T) {
+		c := New()
+		provideChain(t, c, _defaultMaxDepth)
+		require.NoError(t, invokeLevel(c, 0))
+	})
+
+	t.Run("chain beyond the limit", func(t *testing.T) {
+		c := New()
+		provideChain(t, c, 600)
+
+		err := invokeLevel(c, 0)
+		require.Error(t, err)
+
+		var derr DepthExceededError
+		require.True(t, errors.As(err, &derr), "expected a DepthExceededError, got %v", err)
+		assert.Equal(t, 512, derr.Limit)
+		assert.Len(t, derr.chain, 10)
+		assertErrorMatches(t, err,
+			`could not build arguments for function "reflect".makeFuncStub \(\S+\): `,
+			`cannot nest more than 512 constructor calls; last 10 constructors on the chain: `)
+		assert.Empty(t, c.calls.constructing.fns, "stack must be empty after the failure")
+
+		// Values past the limit can still be built from a shallower level.
+		require.NoError(t, invokeLevel(c, 100))
+	})
+
+	t.Run("custom limit", func(t *testing.T) {
+		c := New(MaxConstructionDepth(5))
+		provideChain(t, c, 6)
+
+		err := invokeLevel(c, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot nest more than 5 constructor calls; last 6 constructors")
+		require.NoError(t, invokeLevel(c, 1))
+	})
+
+	t.Run("re-entrant invokes", func(t *testing.T) {
+		c := New(MaxConstructionDepth(50))
+		for i := 0; i < 100; i++ {
+			i := i
+			out := depthType(i)
+			ctor := reflect.MakeFunc(
+				reflect.FuncOf(nil /* in */, []reflect.Type{out, _errType}, false /* variadic */),
+				func([]reflect.Value) []reflect.Value {
+					err := invokeLevel(c, i+1)
+					errv := reflect.Zero(_errType)
+					if err != nil {
+						errv = reflect.ValueOf(&err).Elem()
+					}
+					return []reflect.Value{reflect.Zero(out), errv}
+				},
+			)
+			require.NoError(t, c.Provide(ctor.Interface()))
+		}
+
+		err := invokeLevel(c, 0)
+		require.Error(t, err)
+		var derr DepthExceededError
+		require.True(t, errors.As(err, &derr), "expected a DepthExceededError, got %v", err)
+		assert.Equal(t, 50, derr.Limit)
+		assert.Empty(t, c.calls.constructing.fns, "stack must be empty after the failure")
+	})
+
+	t.Run("scopes share the limit", func(t *testing.T) {
+		c := New(MaxConstructionDepth(3))
+		provideChain(t, c, 3)
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func([0]int) string { return "" }))
+
+		err := s.Invoke(func(string) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot nest more than 3 constructor calls")
+	})
+}
@@ -26,9 +26,10 @@ import (
 	"io"
 	"math/rand"
 	"reflect"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 
@@ -40,6 +41,13 @@ const (
 	_optionalTag = "optional"
 	_nameTag     = "name"
 	_groupTag    = "group"
+	_errorForTag = "errorfor"
+	_prefixTag   = "nameprefix"
+	_namesTag    = "names"
+	_indexTag    = "index"
+	_compactTag  = "compact"
+	_docTag      = "doc"
+	_envTag      = "env"
 )
 
 // Unique identification of an object in the graph.
@@ -51,6 +59,25 @@ type key struct {
 	group string
 }
 
+// Key identifies a value or a value group in the container.
+type Key struct {
+	// Type of the value. For value groups, this is the type of the values
+	// in the group rather than the slice type.
+	Type reflect.Type
+
+	// Only one of Name or Group will be set.
+	Name  string
+	Group string
+}
+
+func (k key) exported() Key {
+	return Key{Type: k.t, Name: k.name, Group: k.group}
+}
+
+func (k Key) String() string {
+	return key{t: k.Type, name: k.Name, group: k.Group}.String()
+}
+
 // Option configures a Container. It's included for future functionality;
 // currently, there are no concrete implementations.
 type Option interface {
@@ -62,7 +89,51 @@ type optionFunc func(*Container)
 func (f optionFunc) applyOption(c *Container) { f(c) }
 
 type provideOptions struct {
-	Name string
+	Name           string
+	Groups         []string
+	AsGroups       []asGroupOption
+	NameAll        bool
+	ResultNames    []resultName
+	PopulateFields bool
+	MemoizeByArgs  bool
+	IfNotPresent   bool
+
+	// Whether values returned along with an error are kept. See
+	// CommitPartialResults.
+	CommitPartialResults bool
+
+	// Whether the constructor may commit none of its values. See
+	// AllowNoResults.
+	AllowNoResults bool
+
+	// Condition under which the constructor is enabled, and whether it's
+	// only evaluated once. See When.
+	Conditional bool
+	When        func() bool
+	WhenOnce    bool
+
+	// See AllowUnbuffered and CopyOnInject.
+	AllowUnbuffered bool
+	CopyOnInject    bool
+
+	// If set, reported as the location of the constructor instead of the
+	// location of the constructor function itself.
+	Location *digreflect.Func
+
+	// If set, the constructor builds its values in the container mounted
+	// with this prefix. See Container.Mount.
+	Mount string
+
+	// If set, the constructor bridges a value group and named values. This
+	// describes the bridge. See Container.BridgeGroupToNames.
+	Bridge string
+
+	// If set, the constructor provides an ambient value. See
+	// WithAmbientValues.
+	Ambient bool
+
+	// Description of the constructor. See Doc.
+	Doc string
 }
 
 func (o *provideOptions) Validate() error {
@@ -73,9 +144,88 @@ func (o *provideOptions) Validate() error {
 	if strings.ContainsRune(o.Name, '`') {
 		return fmt.Errorf("invalid dig.Name(%q): names cannot contain backquotes", o.Name)
 	}
+	if o.NameAll && o.Name == "" {
+		return errors.New("cannot use dig.NameAllResults without dig.Name")
+	}
+
+	named := make(map[int]struct{}, len(o.ResultNames))
+	for _, rn := range o.ResultNames {
+		switch {
+		case rn.Index < 0:
+			return fmt.Errorf("invalid dig.NameResult(%d, %q): result indexes cannot be negative", rn.Index, rn.Name)
+		case rn.Name == "":
+			return fmt.Errorf("invalid dig.NameResult(%d, \"\"): names cannot be empty", rn.Index)
+		case strings.ContainsRune(rn.Name, '`'):
+			return fmt.Errorf("invalid dig.NameResult(%d, %q): names cannot contain backquotes", rn.Index, rn.Name)
+		case o.Name != "":
+			return fmt.Errorf("cannot use dig.Name(%q) and dig.NameResult(%d, %q) together", o.Name, rn.Index, rn.Name)
+		case len(o.Groups) > 0 || len(o.AsGroups) > 0:
+			return fmt.Errorf("cannot use named values with value groups: dig.NameResult(%d, %q) provided with a group", rn.Index, rn.Name)
+		}
+		if _, ok := named[rn.Index]; ok {
+			return fmt.Errorf("invalid dig.NameResult(%d, %q): result %d named more than once", rn.Index, rn.Name, rn.Index)
+		}
+		named[rn.Index] = struct{}{}
+	}
+
+	seen := make(map[string]struct{}, len(o.Groups))
+	for _, g := range o.Groups {
+		switch {
+		case g == "":
+			return errors.New("invalid dig.Group(\"\"): group names cannot be empty")
+		case strings.ContainsRune(g, '`'):
+			return fmt.Errorf("invalid dig.Group(%q): group names cannot contain backquotes", g)
+		case g == _warningsGroup:
+			return fmt.Errorf("invalid dig.Group(%q): the group is reserved for dig.Warning", g)
+		case o.Name != "":
+			return fmt.Errorf(
+				"cannot use named values with value groups: dig.Name(%q) provided with dig.Group(%q)", o.Name, g)
+		}
+		if _, ok := seen[g]; ok {
+			return fmt.Errorf("invalid dig.Group(%q): group specified more than once", g)
+		}
+		seen[g] = struct{}{}
+	}
+
+	for _, as := range o.AsGroups {
+		switch {
+		case as.Group == "":
+			return fmt.Errorf("invalid dig.AsGroup(\"\", %v): group names cannot be empty", as.Target)
+		case strings.ContainsRune(as.Group, '`'):
+			return fmt.Errorf("invalid dig.AsGroup(%q, %v): group names cannot contain backquotes", as.Group, as.Target)
+		case as.Group == _warningsGroup:
+			return fmt.Errorf("invalid dig.AsGroup(%q, %v): the group is reserved for dig.Warning", as.Group, as.Target)
+		case as.Target == nil || as.Target.Kind() != reflect.Ptr || as.Target.Elem().Kind() != reflect.Interface:
+			return fmt.Errorf("invalid dig.AsGroup(%q, %v): expected a pointer to an interface", as.Group, as.Target)
+		case o.Name != "":
+			return fmt.Errorf(
+				"cannot use named values with value groups: dig.Name(%q) provided with dig.AsGroup(%q, %v)",
+				o.Name, as.Group, as.Target)
+		}
+	}
+
+	if o.IfNotPresent && (len(o.Groups) > 0 || len(o.AsGroups) > 0) {
+		return errors.New("cannot use dig.IfNotPresent with value groups")
+	}
+	if o.CopyOnInject && (len(o.Groups) > 0 || len(o.AsGroups) > 0) {
+		return errors.New("cannot use dig.CopyOnInject with value groups")
+	}
+	if o.Conditional && o.When == nil {
+		return errors.New("cannot use dig.When with a nil condition")
+	}
 	return nil
 }
 
+// resultAsGroups returns the value groups specified with dig.AsGroup. The
+// options must be valid.
+func (o *provideOptions) resultAsGroups() []groupAs {
+	var groups []groupAs
+	for _, as := range o.AsGroups {
+		groups = append(groups, groupAs{Group: as.Group, Type: as.Target.Elem()})
+	}
+	return groups
+}
+
 // A ProvideOption modifies the default behavior of Provide.
 type ProvideOption interface {
 	applyProvideOption(*provideOptions)
@@ -101,42 +251,385 @@ func (f provideOptionFunc) applyProvideOption(opts *provideOptions) { f(opts) }
 //   c.Provide(NewReadWriteConnection, dig.Name("rw"))
 //
 // This option cannot be provided for constructors which produce result
-// objects.
+// objects. Constructors that return more than one value must also be
+// provided with NameAllResults to confirm that all of the values are named.
+// NameResult names one of them instead.
 func Name(name string) ProvideOption {
 	return provideOptionFunc(func(opts *provideOptions) {
 		opts.Name = name
 	})
 }
 
-// An InvokeOption modifies the default behavior of Invoke. It's included for
-// future functionality; currently, there are no concrete implementations.
+// NameAllResults is a ProvideOption that confirms that Name applies to all
+// values returned by a constructor that returns more than one.
+//
+//   c.Provide(NewReadOnlyConnections, dig.Name("ro"), dig.NameAllResults())
+//
+// Without it, Provide fails for such constructors, because naming all of
+// their values is rarely intended.
+func NameAllResults() ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.NameAll = true
+	})
+}
+
+// NameResult is a ProvideOption that names the value returned by a
+// constructor at the given position, starting at 0. Other values returned by
+// the constructor are unnamed.
+//
+//   // func NewConnections() (*sql.DB, *redis.Client, error)
+//   c.Provide(NewConnections, dig.NameResult(0, "primary"))
+//
+// This option may be specified multiple times to name several values. It
+// cannot be combined with Name, and cannot name result objects or the error
+// returned by a constructor.
+func NameResult(index int, name string) ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.ResultNames = append(opts.ResultNames, resultName{Index: index, Name: name})
+	})
+}
+
+// Group is a ProvideOption that specifies that all values produced by a
+// constructor should be added to the value group with the given name. See
+// also the package documentation about Value Groups.
+//
+// This option may be specified multiple times to add the values to multiple
+// groups at once. The constructor is still called at most once.
+//
+//   c.Provide(NewAuthMiddleware, dig.Group("http-middleware"), dig.Group("grpc-middleware"))
+//
+// This option cannot be combined with Name, and cannot be provided for
+// constructors which produce result objects.
+//
+// Provide fails if the values are of a different type than those that
+// other constructors submit to or consume from the group, but one that is
+// easily mistaken for it, such as a type implementing the interface the
+// group is consumed as. Use AsGroup to submit the values as the interface.
+func Group(group string) ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.Groups = append(opts.Groups, group)
+	})
+}
+
+// Doc is a ProvideOption that describes the constructor. The description is
+// shown as a tooltip by Visualize and reported by Graph.
+//
+//   c.Provide(NewPrimaryDB, dig.Doc("opens the primary OLTP database"))
+//
+// See also the package documentation about Documentation Tags.
+func Doc(doc string) ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.Doc = doc
+	})
+}
+
+// AsGroup is a ProvideOption that specifies that the value produced by a
+// constructor should be added to the value group with the given name as the
+// interface pointed to by iface.
+//
+// Given,
+//
+//   func NewHealthHandler(...) *HealthHandler
+//
+// The following adds the *HealthHandler to the "handlers" value group of
+// http.Handlers, so that consumers can request []http.Handler.
+//
+//   c.Provide(NewHealthHandler, dig.AsGroup("handlers", new(http.Handler)))
+//
+// Provide fails if the value doesn't implement the interface. Like Group,
+// this option may be specified multiple times and cannot be combined with
+// Name or provided for constructors which produce result objects. The value
+// is only added to the given groups and is not available as its own type.
+func AsGroup(group string, iface interface{}) ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.AsGroups = append(opts.AsGroups, asGroupOption{
+			Group:  group,
+			Target: reflect.TypeOf(iface),
+		})
+	})
+}
+
+// asGroupOption is a dig.AsGroup option.
+type asGroupOption struct {
+	Group  string
+	Target reflect.Type // pointer to the interface
+}
+
+// An InvokeOption modifies the default behavior of Invoke.
 type InvokeOption interface {
-	unimplemented()
+	applyInvokeOption(*invokeOptions)
+}
+
+type invokeOptions struct {
+	// If set, filled with a report of the work the Invoke will do.
+	Preflight *PreflightReport
+
+	// If set, filled with how the Invoke obtains values. See AuditTrail.
+	Audit *InvokeAudit
+
+	// If set, filled with the keys of the values the Invoke resolved. See
+	// RecordDependencies.
+	Dependencies *[]Key
+
+	// If set, the function was generated by a Locator or by Construct
+	// called at this location. Errors report this location instead of the function's, and
+	// the function is not recorded for DumpWiring.
+	caller *digreflect.Func
 }
 
+type invokeOptionFunc func(*invokeOptions)
+
+func (f invokeOptionFunc) applyInvokeOption(opts *invokeOptions) { f(opts) }
+
 // Container is a directed acyclic graph of types and their dependencies.
+//
+// Containers must be created with New. Methods of nil containers and of
+// containers created otherwise return errors.
 type Container struct {
 	// Mapping from key to all the nodes that can provide a value for that
 	// key.
-	providers map[key][]*node
+	//
+	// Changes to providers, providersVersion, and isVerifiedAcyclic are
+	// guarded by providersMu so that value groups can be consumed while
+	// constructors are provided from another goroutine.
+	providers   map[key][]*node
+	providersMu sync.RWMutex
 
 	// All nodes in the container.
-	nodes []*node
+	//
+	// Changes to nodes are guarded by nodesMu so that graphs can be
+	// snapshotted while constructors are provided from another goroutine.
+	nodes   []*node
+	nodesMu sync.RWMutex
 
 	// Values that have already been generated in the container.
+	//
+	// Changes to values, groups, and valueErrors are guarded by valuesMu so
+	// that scopes can read them while the constructors of this container
+	// are called for another scope.
 	values map[key]reflect.Value
 
 	// Values groups that have already been generated in the container.
-	groups map[key][]reflect.Value
+	groups map[key][]groupValue
+
+	// Errors for values that failed to build although their constructors
+	// succeeded. See the errorfor tag on dig.Out fields.
+	valueErrors map[key]error
+	valuesMu    sync.RWMutex
 
 	// Source of randomness.
 	rand *rand.Rand
 
+	// Order value groups deterministically instead of shuffling them. See
+	// NoGroupShuffle.
+	noGroupShuffle bool
+
 	// Flag indicating whether the graph has been checked for cycles.
 	isVerifiedAcyclic bool
 
 	// Defer acyclic check on provide until Invoke.
 	deferAcyclicVerification bool
+
+	// Resolve values with multiple providers instead of failing with an
+	// AmbiguousProviderError.
+	lastProviderWins bool
+
+	// Resolve interfaces without providers to the only provided type that
+	// implements them.
+	resolveByImplementation bool
+
+	// Resolve values without providers to the only provided type that is
+	// assignable to them.
+	allowAssignableTypes bool
+
+	// Fail to build parameter objects whose fields with different names
+	// resolve to the same value.
+	distinctNamedValues bool
+
+	// If set, constructors that request the same value more than once are
+	// reported to this function instead of failing to Provide.
+	duplicateParamsHandler func(error)
+
+	// Applied to every call to Provide before its own options. See
+	// DefaultProvideOptions.
+	defaultProvideOptions []ProvideOption
+
+	// Incremented every time the providers of this container change.
+	providersVersion int
+
+	// Keys of the values discarded because the values they were built from
+	// were removed, in order, guarded by providersMu. Scopes discard the
+	// values they built from them with discardRemovedValues. Only the most
+	// recent keys are kept; invalidatedBase counts those that were dropped.
+	invalidated     []key
+	invalidatedBase int
+
+	// Number of keys invalidated by each parent of this scope, starting
+	// with the root container, that this scope already discarded values for.
+	replayed []int
+
+	// Cache of the provided implementations of interfaces and of the
+	// provided types assignable to other types, valid as long as
+	// the sum of the providersVersion of this container and its parents is
+	// implementationsVersion.
+	implementations        map[key][]reflect.Type
+	implementationsVersion int
+
+	// Keys of values without providers in this container and its parents,
+	// valid under the same condition as implementations. Value groups are
+	// never added to this.
+	missingProviders        map[key]struct{}
+	missingProvidersVersion int
+
+	// Guards the implementations and missingProviders caches.
+	cacheMu sync.Mutex
+
+	// Numbers the constructors provided to this container and its scopes.
+	// See getGroupContributors.
+	versions *groupVersions
+
+	// Params of the functions invoked on this container and its scopes, by
+	// function type. See invokeParamList.
+	invokeParams *sync.Map
+
+	// Operational counters for this container.
+	metrics *containerMetrics
+
+	// If non-nil, called with a snapshot of the metrics after each Invoke.
+	metricsSink func(Metrics)
+
+	// Subscribers to the events of this container. See Subscribe.
+	events *eventStream
+
+	// Location of the call that froze this container, if any. See Freeze.
+	frozenAt       *digreflect.Func
+	freezeOnInvoke bool
+	freezeScopes   bool
+
+	// Constructor calls made by the last Invoke of this container. See
+	// FailureReport.
+	calls *callLog
+
+	// Held while an Invoke calls the constructors of this container or its
+	// parents on behalf of a scope. Shared by all scopes of the root
+	// container.
+	parentCalls *parentCalls
+
+	// Containers mounted into this one. See Mount.
+	mounts []*Container
+
+	// Maximum number of constructors being called at once. See
+	// MaxConstructionDepth.
+	maxDepth int
+
+	// Called in order before each Invoke. See WithInvokeInterceptor.
+	invokeInterceptors []func(InvokeInfo) error
+
+	// Called in order with every committed value. See OnValueCommitted.
+	valueCommittedHooks []func(Key, interface{})
+
+	// Called in order with every value before it's committed. See
+	// ValueInterceptor.
+	valueInterceptors []func(Key, interface{}) (interface{}, error)
+
+	// Maximum number of values in a value group, or zero if unlimited. See
+	// GroupSizeLimit.
+	groupSizeLimit int
+
+	// Attach a snapshot of the graph to errors returned by Invoke.
+	attachGraphToErrors bool
+
+	// Report panics in constructors and invoked functions as errors. See
+	// RecoverFromPanics.
+	recoverFromPanics bool
+
+	// Attach the arguments of failed constructors to their errors, except
+	// for the values for which redactArg returns true. See
+	// CaptureArgsOnError.
+	captureArgsOnError bool
+	redactArg          func(t reflect.Type, name string) bool
+
+	// How much context errors returned by Invoke include. See
+	// ErrorVerbosity.
+	errorVerbosity Verbosity
+
+	// Constructors that produced the values of this container, keyed by
+	// the identity of the values. See TrackProvenance.
+	trackProvenance bool
+	provenance      map[provenanceKey]ValueProvenance
+
+	// Resolvers registered with ProvideGeneric.
+	genericResolvers []GenericResolver
+
+	// Value groups bridged to named values with BridgeGroupToNames.
+	bridges []*bridge
+
+	// Whether values of well-known types are provided when nothing else
+	// provides them, and the time at which the root container was created.
+	// See WithAmbientValues.
+	ambientValues bool
+	startTime     time.Time
+
+	// Dependencies consumed by Invokes made from inside constructors, and
+	// whether such Invokes fail instead. See HiddenEdges.
+	hidden                *hiddenEdges
+	disallowNestedInvokes bool
+
+	// Whether names must be unique across types. See GloballyUniqueNames.
+	globallyUniqueNames bool
+
+	// Environment of the container, and whether fields tagged with
+	// `env:".."` must list it. See EnvironmentName.
+	env       string
+	strictEnv bool
+
+	// Asks for values that nothing provides, if set. See
+	// WithFallbackResolver.
+	fallback *fallbackResolver
+
+	// Writes the decisions made while building values, if set. Scopes
+	// share the tracer of their root container. See TraceConstruction.
+	trace *constructionTracer
+
+	// Types with which the constructors provided to this container submit
+	// to and consume value groups, keyed by group name. See
+	// checkGroupTypes. Guarded by providersMu so that scopes may be
+	// provided to while constructors are provided to their parents.
+	groupTypes map[string][]groupTypeUse
+
+	// Keys of the values provided to this container whose consumers
+	// receive copies of them. See CopyOnInject.
+	copyOnInject map[key]struct{}
+
+	// Functions passed to Invoke, for DumpWiring.
+	invokes     []invokeRecord
+	invokesSeen map[uintptr]struct{}
+
+	// Entries of WiringSpecs applied to this container, in order. See
+	// Apply.
+	appliedEntries []WiringEntry
+
+	// Parent of this container if it's a scope created with Scope.
+	parent *Container
+
+	// Name of this scope. This is empty for root containers.
+	name string
+
+	// Pool that owns this scope, if any, and whether the scope was returned
+	// to it.
+	pool     *ScopePool
+	released bool
+
+	// Constructors of a pooled scope that are kept when it is returned to
+	// its pool, and how many of them were provided again since it was last
+	// handed out. See providePooled.
+	kept       []keptProvider
+	reprovided int
+
+	// Called in reverse order by Close, after which the container is
+	// closed. See OnClose.
+	onClose []func() error
+	closed  bool
 }
 
 // containerWriter provides write access to the Container's underlying data
@@ -145,11 +638,18 @@ type containerWriter interface {
 	// setValue sets the value with the given name and type in the container.
 	// If a value with the same name and type already exists, it will be
 	// overwritten.
-	setValue(name string, t reflect.Type, v reflect.Value)
+	//
+	// This fails without changing the container if v is invalid or can't be
+	// assigned to t.
+	setValue(name string, t reflect.Type, v reflect.Value) error
 
 	// submitGroupedValue submits a value to the value group with the provided
-	// name.
-	submitGroupedValue(name string, t reflect.Type, v reflect.Value)
+	// name. This fails like setValue.
+	submitGroupedValue(name string, t reflect.Type, v reflect.Value) error
+
+	// setValueError records that the value with the given name and type
+	// failed to build with the provided error.
+	setValueError(name string, t reflect.Type, err error)
 }
 
 // containerStore provides access to the Container's underlying data store.
@@ -159,9 +659,26 @@ type containerStore interface {
 	// Returns a slice containing all known types.
 	knownTypes() []reflect.Type
 
+	// Returns the keys of all known values and value groups, sorted by type.
+	KnownKeys() []KnownKey
+
 	// Retrieves the value with the provided name and type, if any.
 	getValue(name string, t reflect.Type) (v reflect.Value, ok bool)
 
+	// Returns the providers of the given key in this container and its
+	// parents that are disabled by their conditions. See When.
+	getDisabledProviders(k key) []provider
+
+	// Retrieves the error recorded for the value with the provided name and
+	// type, if any.
+	getValueError(name string, t reflect.Type) error
+
+	// Returns the container values are read from and stored into.
+	container() *Container
+
+	// Returns the log of the running Invoke. See invocationStore.
+	invocation() *callLog
+
 	// Retrieves all values for the provided group and type.
 	//
 	// The order in which the values are returned is undefined.
@@ -175,7 +692,114 @@ type containerStore interface {
 	// type.
 	getGroupProviders(name string, t reflect.Type) []provider
 
+	// Returns the providers of the given group and type whose values are
+	// consumed by the running Invoke. See getGroupContributors.
+	getGroupContributors(name string, t reflect.Type) []provider
+
+	// Submits a value produced by the given provider to the value group
+	// with the provided name, like submitGroupedValue.
+	submitProvidedValue(p provider, name string, t reflect.Type, v reflect.Value) error
+
 	createGraph() *dot.Graph
+
+	// Reports whether values with multiple providers may be resolved
+	// instead of failing.
+	allowsLastProviderWins() bool
+
+	// Returns the types with providers for the given name that implement the
+	// interface t if the container resolves interfaces by implementation, or
+	// that are assignable to the non-interface type t if the container allows
+	// assignable types.
+	getImplementations(name string, t reflect.Type) []reflect.Type
+
+	// Reports whether fields of parameter objects with different names must
+	// resolve to different values.
+	checksDistinctNamedValues() bool
+
+	// Returns the arguments passed to a constructor with the given
+	// parameters if the container captures the arguments of failed
+	// constructors, or nil otherwise.
+	captureArgs(pl paramList, args []reflect.Value) []CapturedArg
+
+	// Calls the function f described by fn with the given arguments. If the
+	// container recovers from panics, a panic is returned as a PanicError.
+	call(fn *digreflect.Func, f interface{}, args []reflect.Value) ([]reflect.Value, error)
+
+	// Marks the constructor fn as being called until exitConstructor is
+	// called. Returns a DepthExceededError if too many constructors are
+	// being called.
+	enterConstructor(fn *digreflect.Func, k key) error
+	exitConstructor()
+
+	// Marks k as the value the next constructor is called to build until
+	// the returned function is called.
+	requestValue(k key) (restore func())
+
+	// Returns the ConstructionInfo of the innermost running constructor.
+	constructionInfo() ConstructionInfo
+
+	// Records a single call to the constructor fn that ran for the given
+	// duration and produced the given number of values.
+	recordConstructor(fn *digreflect.Func, d time.Duration, values int, err error)
+
+	// Counts the values staged by a call to the constructor of the given
+	// node. See ConstructionCounts.
+	recordConstructions(n *node, sr *stagingContainerWriter)
+
+	// Sends an event about the function fn to the subscribers of the
+	// container. See Subscribe.
+	emit(kind EventKind, fn *digreflect.Func, d time.Duration, err error)
+
+	// Sends an EventValueCommitted for the given key to the subscribers of
+	// the container.
+	emitCommitted(k key)
+
+	// Reports whether anyone subscribed to the events of the container.
+	subscribed() bool
+
+	// Returns the functions to call with every committed value. See
+	// OnValueCommitted.
+	commitHooks() []func(Key, interface{})
+
+	// Returns the functions to pass every value through before it's
+	// committed. See ValueInterceptor.
+	commitInterceptors() []func(Key, interface{}) (interface{}, error)
+
+	// Records that the values committed by the given writer were produced
+	// by the given node. See TrackProvenance.
+	recordProvenance(n *node, sr *stagingContainerWriter)
+
+	// Returns the innermost function being invoked, if provenance is
+	// tracked.
+	currentInvoke() *InvokeInfo
+
+	// Returns the audit of the innermost Invoke called with AuditTrail, or
+	// nil if there is none.
+	audit() *InvokeAudit
+
+	// Returns the tracer of the container, or nil if it isn't traced. See
+	// TraceConstruction.
+	tracer() *constructionTracer
+
+	// Returns the resolver to ask for values that nothing provides, or nil
+	// if there is none. See WithFallbackResolver.
+	fallbackResolver() *fallbackResolver
+
+	// Reports whether consumers of the value with the given key receive
+	// copies of it. See CopyOnInject.
+	copiesOnInject(k key) bool
+
+	// Provides a constructor of the given type from the resolvers registered
+	// with ProvideGeneric, and reports whether one supported the type.
+	resolveGeneric(t reflect.Type) (bool, error)
+
+	// Provides the named values of the value groups bridged to values of the
+	// given type, and reports whether one of them has the given name.
+	resolveBridge(name string, t reflect.Type) (bool, error)
+
+	// Returns an error if the values submitted to value groups by the
+	// given provider would exceed the limit set with GroupSizeLimit.
+	checkGroupSizes(p provider, groups map[key][]reflect.Value) error
 }
 
 // provider encapsulates a user-provided constructor.
@@ -194,6 +818,35 @@ type provider interface {
 	// constructor.
 	ResultList() resultList
 
+	// Called returns true if the constructor has already been called
+	// successfully.
+	Called() bool
+
+	// Fallback returns true if this constructor was provided with
+	// IfNotPresent.
+	Fallback() bool
+
+	// OrigScope returns the container into which this constructor was
+	// provided. Constructors read their dependencies from and store their
+	// results into this container.
+	OrigScope() containerStore
+
+	// GroupValues returns the values this constructor submitted to the value
+	// group with the given name and type, if it was called successfully.
+	GroupValues(name string, t reflect.Type) []reflect.Value
+
+	// Version returns the position of this constructor in the order in
+	// which constructors were provided. See getGroupContributors.
+	Version() uint64
+
+	// Stage calls the underlying constructor like Call, but instead of
+	// submitting the values it produced into the containerStore, returns a
+	// function that does so.
+	//
+	// The returned function does nothing if the constructor was called in
+	// the meantime. Its error is reported after the values were submitted.
+	Stage(containerStore) (commit func() error, err error)
+
 	// Calls the underlying constructor, reading values from the
 	// containerStore as needed.
 	//
@@ -205,10 +858,20 @@ type provider interface {
 // New constructs a Container.
 func New(opts ...Option) *Container {
 	c := &Container{
-		providers: make(map[key][]*node),
-		values:    make(map[key]reflect.Value),
-		groups:    make(map[key][]reflect.Value),
-		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		providers:    make(map[key][]*node),
+		values:       make(map[key]reflect.Value),
+		groups:       make(map[key][]groupValue),
+		valueErrors:  make(map[key]error),
+		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		startTime:    time.Now(),
+		metrics:      new(containerMetrics),
+		events:       new(eventStream),
+		calls:        new(callLog),
+		parentCalls:  newParentCalls(),
+		invokeParams: new(sync.Map),
+		versions:     new(groupVersions),
+		hidden:       new(hiddenEdges),
+		maxDepth:     _defaultMaxDepth,
 	}
 
 	for _, opt := range opts {
@@ -217,6 +880,24 @@ func New(opts ...Option) *Container {
 	return c
 }
 
+// checkInitialized returns an error if this container is nil, or if it or
+// one of its parents wasn't created with New. Methods of such containers fail
+// instead of panicking.
+func (c *Container) checkInitialized() error {
+	if c == nil {
+		return errors.New("cannot use nil *dig.Container")
+	}
+	for s := c; s != nil; s = s.parent {
+		if s.closed {
+			return ClosedContainerError{Scope: s.name}
+		}
+		if s.providers == nil {
+			return errors.New("cannot use dig.Container that was not created with dig.New")
+		}
+	}
+	return nil
+}
+
 // DeferAcyclicVerification is an Option to override the default behavior
 // of container.Provide, deferring the dependency graph validation to no longer
 // run after each call to container.Provide. The container will instead verify
@@ -230,13 +911,113 @@ func DeferAcyclicVerification() Option {
 	})
 }
 
+// AllowLastProviderWins is an Option that restores the lenient resolution of
+// values for which the container knows more than one provider. Without this
+// option, such values fail to build with an AmbiguousProviderError.
+//
+// This option exists for legacy code only. Use dig.Name to disambiguate
+// values of the same type instead.
+func AllowLastProviderWins() Option {
+	return optionFunc(func(c *Container) {
+		c.lastProviderWins = true
+	})
+}
+
+// ResolveByImplementation is an Option that allows interface parameters
+// without providers to be satisfied by the only provided type that implements
+// the interface.
+//
+// Given,
+//
+//   c := dig.New(dig.ResolveByImplementation())
+//   c.Provide(func() *bytes.Buffer { ... })
+//
+// The following will receive the *bytes.Buffer.
+//
+//   c.Invoke(func(w io.Writer) { ... })
+//
+// Providers of the interface type itself always take precedence. Parameters
+// fail to build if more than one provided type implements the interface.
+func ResolveByImplementation() Option {
+	return optionFunc(func(c *Container) {
+		c.resolveByImplementation = true
+	})
+}
+
+// AllowAssignableTypes is an Option that resolves values that nothing
+// provides to the only provided type that is assignable to them.
+//
+//   type Handlers []http.Handler
+//
+//   c.Provide(func() Handlers { ... })
+//
+// The following will receive the Handlers.
+//
+//   c.Invoke(func(hs []http.Handler) { ... })
+//
+// Only assignable types are considered, not merely convertible ones, so a
+// provided "type Port int" still won't satisfy an int. Providers of the
+// requested type itself always take precedence. Parameters fail to build if
+// more than one provided type is assignable to them. Interfaces are resolved
+// this way only with ResolveByImplementation.
+func AllowAssignableTypes() Option {
+	return optionFunc(func(c *Container) {
+		c.allowAssignableTypes = true
+	})
+}
+
+// DistinctNamedValues is an Option that verifies that fields of the same type
+// requested under different names by a parameter object resolve to different
+// values. This catches providers that accidentally return the same pointer
+// for different names.
+//
+//   type DBParams struct {
+//     dig.In
+//
+//     Primary *sql.DB
+//     Replica *sql.DB `name:"replica"`
+//   }
+//
+// With this option, DBParams fails to build if Primary and Replica are the
+// same *sql.DB. Only pointers to non-empty types, maps, and channels, or
+// interfaces holding them, are compared. Fields with the same name may share
+// a value.
+func DistinctNamedValues() Option {
+	return optionFunc(func(c *Container) {
+		c.distinctNamedValues = true
+	})
+}
+
+// WarnDuplicateParams is an Option that allows constructors to request the
+// same value more than once, for example both as an argument and as a field
+// of a parameter object,
+//
+//   func NewServer(cfg *Config, p ServerParams) *Server
+//
+//   type ServerParams struct {
+//     dig.In
+//
+//     Config *Config
+//   }
+//
+// Provide fails for such constructors by default, as this is usually an
+// accident. With this option, the error describing the duplicate parameters
+// is passed to the given function and the constructor is provided anyway.
+func WarnDuplicateParams(f func(error)) Option {
+	return optionFunc(func(c *Container) {
+		c.duplicateParamsHandler = f
+	})
+}
+
 // A VisualizeOption modifies the default behavior of Visualize.
 type VisualizeOption interface {
 	applyVisualizeOption(*visualizeOptions)
 }
 
 type visualizeOptions struct {
-	VisualizeError error
+	VisualizeError  error
+	CollapseParents bool
+	LabelParams     bool
 }
 
 type visualizeOptionFunc func(*visualizeOptions)
@@ -258,7 +1039,36 @@ func VisualizeError(err error) VisualizeOption {
 	})
 }
 
-func updateGraph(dg *dot.Graph, err error) error {
+// CollapseParentScopes renders the constructors provided to the parents of
+// a scope as a single "inherited" node in the output of Visualize, rather
+// than in gray alongside the constructors of the scope.
+//
+//   dig.Visualize(c.Scope("request"), w, dig.CollapseParentScopes())
+//
+// Failures of the collapsed constructors are reported on that node. This
+// option has no effect on root containers or on the output of Graph.
+func CollapseParentScopes() VisualizeOption {
+	return visualizeOptionFunc(func(opts *visualizeOptions) {
+		opts.CollapseParents = true
+	})
+}
+
+// LabelParamEdges labels the edges from constructors to the values they
+// consume in the output of Visualize with the parameter each edge stands
+// for: the position of the argument, or the dig.In struct and the field
+// through which the value is consumed.
+//
+//   dig.Visualize(c, w, dig.LabelParamEdges())
+//
+// This helps tell apart edges to different named values of the same type,
+// at the cost of busier output.
+func LabelParamEdges() VisualizeOption {
+	return visualizeOptionFunc(func(opts *visualizeOptions) {
+		opts.LabelParams = true
+	})
+}
+
+func updateGraph(dg *dot.Graph, err error) {
 	var errors []errVisualizer
 	// Unwrap error to find the root cause.
 	for {
@@ -272,17 +1082,11 @@ func updateGraph(dg *dot.Graph, err error) error {
 		err = e.cause()
 	}
 
-	// If there are no errVisualizers included, we do not modify the graph.
-	if len(errors) == 0 {
-		return nil
-	}
-
-	// We iterate in reverse because the last element is the root cause.
+	// We iterate in reverse because the last element is the root cause. If
+	// there are no errVisualizers included, we do not modify the graph.
 	for i := len(errors) - 1; i >= 0; i-- {
 		errors[i].updateGraph(dg)
 	}
-
-	return nil
 }
 
 var _graphTmpl = template.Must(
@@ -293,51 +1097,93 @@ var _graphTmpl = template.Must(
 		Parse(`digraph {
 	graph [compound=true];
 	{{range $g := .Groups}}
-		{{- quote .String}} [{{.Attributes}}];
+		{{- quote .ID}} [{{.Attributes}}];
 		{{range .Results}}
-			{{- quote $g.String}} -> {{quote .String}};
+			{{- quote $g.ID}} -> {{quote .ID}}{{if .Indexed}} [label={{.Index}}]{{end}};
 		{{end}}
 	{{end -}}
+	{{with .Inherited -}}
+		{{quote $.InheritedID}} [{{.Attributes}}];
+	{{end -}}
 	{{range $index, $ctor := .Ctors}}
 		subgraph cluster_{{$index}} {
-			constructor_{{$index}} [shape=plaintext label={{quote .Name}}];
-			{{with .ErrorType}}color={{.Color}};{{end}}
+			constructor_{{$index}} [shape=plaintext label={{quote .Name}}{{if .Inherited}} fontcolor=gray{{end}}{{with .Doc}} tooltip={{quote .}}{{end}}];
+			{{with .ErrorType}}color={{.Color}};{{else}}{{if .Disabled}}color=lightgray;fontcolor=gray;{{else if .Inherited}}color=gray;{{end}}{{end}}{{with .Bridge}}label={{quote .}};style=dashed;{{else}}{{if .Ambient}}label="ambient";style=dashed;{{else}}{{with .Mount}}label={{quote .}};{{else}}{{with .Scope}}label={{quote .}};{{end}}{{end}}{{end}}{{end}}
 			{{range .Results}}
-				{{- quote .String}} [{{.Attributes}}];
+				{{- quote .ID}} [{{.Attributes}}{{if $ctor.Inherited}} color=gray fontcolor=gray{{end}}];
 			{{end}}
 		}
-		{{range .Params}}
-			constructor_{{$index}} -> {{quote .String}} [ltail=cluster_{{$index}}{{if .Optional}} style=dashed{{end}}];
+		{{range $p := .Params}}
+			{{- range $.ParamIDs $p}}
+			constructor_{{$index}} -> {{quote .}} [ltail=cluster_{{$index}}{{if $p.Optional}} style=dashed{{end}}{{if $.LabelParams}} label={{quote $p.Path}}{{end}}{{with $p.Doc}} tooltip={{quote .}}{{end}}];
+			{{- end}}
 		{{end}}
-		{{range .GroupParams}}
-			constructor_{{$index}} -> {{quote .String}} [ltail=cluster_{{$index}}];
+		{{range $i, $g := .GroupParams}}
+			constructor_{{$index}} -> {{quote .ID}} [ltail=cluster_{{$index}}{{if $.LabelParams}} label={{quote (index $ctor.GroupParamPaths $i)}}{{end}}];
 		{{end -}}
 	{{end}}
 	{{range .Failed.TransitiveFailures}}
-		{{- quote .String}} [color=orange];
+		{{- quote .ID}} [{{.Attributes}} color=orange];
 	{{end -}}
 	{{range .Failed.RootCauses}}
-		{{- quote .String}} [color=red];
+		{{- quote .ID}} [{{.Attributes}} color=red];
 	{{end}}
 }`))
 
 // Visualize parses the graph in Container c into DOT format and writes it to
 // io.Writer w.
+//
+// Visualize may be called while constructors are being provided to c from
+// another goroutine. The graph includes the constructors provided so far.
 func Visualize(c *Container, w io.Writer, opts ...VisualizeOption) error {
-	dg := c.createGraph()
+	if err := c.checkInitialized(); err != nil {
+		return err
+	}
+	return renderGraph(w, c.snapshotGraph(), opts)
+}
+
+// renderGraph writes the graph built from the given snapshot in DOT format
+// to w.
+func renderGraph(w io.Writer, gs graphSnapshot, opts []VisualizeOption) error {
+	dg := newVisualizedGraph(gs, opts)
+	if newVisualizeOptions(opts).CollapseParents {
+		dg.CollapseInherited()
+	}
+	return _graphTmpl.Execute(w, visualizedGraph{
+		Graph:       dg,
+		LabelParams: newVisualizeOptions(opts).LabelParams,
+	})
+}
+
+// visualizedGraph is the data passed to _graphTmpl.
+type visualizedGraph struct {
+	*dot.Graph
+
+	// Label the edges to params with their paths.
+	LabelParams bool
+}
 
+// InheritedID returns the ID of the node replacing collapsed parent scopes.
+func (visualizedGraph) InheritedID() string { return dot.InheritedID }
+
+func newVisualizeOptions(opts []VisualizeOption) visualizeOptions {
 	var options visualizeOptions
 	for _, o := range opts {
 		o.applyVisualizeOption(&options)
 	}
+	return options
+}
 
-	if options.VisualizeError != nil {
-		if err := updateGraph(dg, options.VisualizeError); err != nil {
-			return err
-		}
+// newVisualizedGraph builds the graph from the given snapshot with the
+// changes requested by the given VisualizeOptions applied.
+func newVisualizedGraph(gs graphSnapshot, opts []VisualizeOption) *dot.Graph {
+	dg := gs.build()
+
+	if options := newVisualizeOptions(opts); options.VisualizeError != nil {
+		updateGraph(dg, options.VisualizeError)
 	}
 
-	return _graphTmpl.Execute(w, dg)
+	return dg
 }
 
 // CanVisualizeError returns true if the error is an errVisualizer.
@@ -357,13 +1203,7 @@ func CanVisualizeError(err error) bool {
 }
 
 func (c *Container) createGraph() *dot.Graph {
-	dg := dot.NewGraph()
-
-	for _, n := range c.nodes {
-		dg.AddCtor(newDotCtor(n), n.paramList.DotParam(), n.resultList.DotResult())
-	}
-
-	return dg
+	return c.snapshotGraph().build()
 }
 
 // Changes the source of randomness for the container.
@@ -375,53 +1215,221 @@ func setRand(r *rand.Rand) Option {
 	})
 }
 
-func (c *Container) knownTypes() []reflect.Type {
-	typeSet := make(map[reflect.Type]struct{}, len(c.providers))
-	for k := range c.providers {
-		typeSet[k.t] = struct{}{}
+func (c *Container) getValue(name string, t reflect.Type) (v reflect.Value, ok bool) {
+	for s := c; s != nil; s = s.parent {
+		s.valuesMu.RLock()
+		v, ok = s.values[key{name: name, t: t}]
+		s.valuesMu.RUnlock()
+		if ok {
+			// setValue never stores invalid values, but the zero Value
+			// must never be reported as present regardless.
+			return v, v.IsValid()
+		}
 	}
+	return
+}
 
-	types := make([]reflect.Type, 0, len(typeSet))
-	for t := range typeSet {
-		types = append(types, t)
+func (c *Container) setValue(name string, t reflect.Type, v reflect.Value) error {
+	k := key{name: name, t: t}
+	if err := checkStoredValue(k, v); err != nil {
+		return err
 	}
-	sort.Sort(byTypeName(types))
-	return types
+	c.valuesMu.Lock()
+	c.values[k] = v
+	c.valuesMu.Unlock()
+	return nil
 }
 
-func (c *Container) getValue(name string, t reflect.Type) (v reflect.Value, ok bool) {
-	v, ok = c.values[key{name: name, t: t}]
-	return
+func (c *Container) allowsLastProviderWins() bool {
+	return c.lastProviderWins
 }
 
-func (c *Container) setValue(name string, t reflect.Type, v reflect.Value) {
-	c.values[key{name: name, t: t}] = v
+func (c *Container) getImplementations(name string, t reflect.Type) []reflect.Type {
+	if t.Kind() == reflect.Interface && !c.resolveByImplementation ||
+		t.Kind() != reflect.Interface && !c.allowAssignableTypes {
+		return nil
+	}
+
+	version := c.chainProvidersVersion()
+	k := key{name: name, t: t}
+	c.cacheMu.Lock()
+	if c.implementations == nil || c.implementationsVersion != version {
+		c.implementations = make(map[key][]reflect.Type)
+		c.implementationsVersion = version
+	}
+	impls, ok := c.implementations[k]
+	c.cacheMu.Unlock()
+	if ok {
+		return impls
+	}
+
+	for _, kt := range c.knownTypes() {
+		if kt != t && kt.AssignableTo(t) && len(c.getValueProviders(name, kt)) > 0 {
+			impls = append(impls, kt)
+		}
+	}
+	c.cacheMu.Lock()
+	if c.implementationsVersion == version {
+		c.implementations[k] = impls
+	}
+	c.cacheMu.Unlock()
+	return impls
+}
+
+func (c *Container) checksDistinctNamedValues() bool {
+	return c.distinctNamedValues
+}
+
+func (c *Container) getValueError(name string, t reflect.Type) error {
+	for s := c; s != nil; s = s.parent {
+		s.valuesMu.RLock()
+		err, ok := s.valueErrors[key{name: name, t: t}]
+		s.valuesMu.RUnlock()
+		if ok {
+			return err
+		}
+	}
+	return nil
 }
 
+func (c *Container) setValueError(name string, t reflect.Type, err error) {
+	c.valuesMu.Lock()
+	c.valueErrors[key{name: name, t: t}] = err
+	c.valuesMu.Unlock()
+}
+
+func (c *Container) container() *Container { return c }
+func (c *Container) invocation() *callLog  { return c.calls }
+
 func (c *Container) getValueGroup(name string, t reflect.Type) []reflect.Value {
-	items := c.groups[key{group: name, t: t}]
+	return c.valueGroup(c.calls, name, t)
+}
+
+// valueGroup is getValueGroup for the Invoke with the given log.
+func (c *Container) valueGroup(l *callLog, name string, t reflect.Type) []reflect.Value {
+	if c.noGroupShuffle {
+		return c.canonicalValueGroup(l, name, t)
+	}
+
+	k := key{group: name, t: t}
+	version, versioned := l.groupVersion()
+	var items []reflect.Value
+	for s := c; s != nil; s = s.parent {
+		s.valuesMu.RLock()
+		for _, gv := range s.groups[k] {
+			// Leave out the values of constructors provided after the
+			// running Invoke started.
+			if !versioned || gv.visibleAt(version) {
+				items = append(items, gv.Value)
+			}
+		}
+		s.valuesMu.RUnlock()
+	}
 	// shuffle the list so users don't rely on the ordering of grouped values
 	return shuffledCopy(c.rand, items)
 }
 
-func (c *Container) submitGroupedValue(name string, t reflect.Type, v reflect.Value) {
+func (c *Container) submitGroupedValue(name string, t reflect.Type, v reflect.Value) error {
+	return c.submitProvidedValue(nil, name, t, v)
+}
+
+func (c *Container) submitProvidedValue(p provider, name string, t reflect.Type, v reflect.Value) error {
 	k := key{group: name, t: t}
-	c.groups[k] = append(c.groups[k], v)
+	if err := checkStoredValue(k, v); err != nil {
+		return err
+	}
+	c.valuesMu.Lock()
+	c.groups[k] = append(c.groups[k], groupValue{Value: v, Provider: p})
+	c.valuesMu.Unlock()
+	c.metrics.recordGroupValue(k)
+	return nil
+}
+
+// checkStoredValue returns an error if v can't be stored in the container
+// under the given key.
+func checkStoredValue(k key, v reflect.Value) error {
+	if !v.IsValid() {
+		return fmt.Errorf("cannot store an invalid reflect.Value as %v", k)
+	}
+	if !v.Type().AssignableTo(k.t) {
+		return fmt.Errorf("cannot store a value of type %v as %v", v.Type(), k)
+	}
+	return nil
+}
+
+func (c *Container) recordConstructor(fn *digreflect.Func, d time.Duration, values int, err error) {
+	c.recordCall(c.calls, fn, d, values, err)
+}
+
+// recordCall is recordConstructor for the Invoke with the given log.
+func (c *Container) recordCall(l *callLog, fn *digreflect.Func, d time.Duration, values int, err error) {
+	c.metrics.recordConstructor(d, values, err)
+	l.record(fn, d, err)
+	if err != nil {
+		c.emit(EventFailed, fn, d, err)
+	} else {
+		c.emit(EventConstructed, fn, d, nil)
+	}
+}
+
+func (c *Container) recordConstructions(n *node, sr *stagingContainerWriter) {
+	c.metrics.recordConstructions(n, sr)
 }
 
 func (c *Container) getValueProviders(name string, t reflect.Type) []provider {
-	return c.getProviders(key{name: name, t: t})
+	version := c.chainProvidersVersion()
+	k := key{name: name, t: t}
+	c.cacheMu.Lock()
+	if c.missingProviders == nil || c.missingProvidersVersion != version {
+		c.missingProviders = make(map[key]struct{})
+		c.missingProvidersVersion = version
+	}
+	_, missing := c.missingProviders[k]
+	c.cacheMu.Unlock()
+	if missing {
+		return nil
+	}
+
+	providers := withoutFallbacks(c.getProviders(k))
+	if len(providers) == 0 {
+		c.cacheMu.Lock()
+		if c.missingProvidersVersion == version {
+			c.missingProviders[k] = struct{}{}
+		}
+		c.cacheMu.Unlock()
+	}
+	return providers
 }
 
 func (c *Container) getGroupProviders(name string, t reflect.Type) []provider {
 	return c.getProviders(key{group: name, t: t})
 }
 
+// chainProvidersVersion returns the sum of the providersVersion of this
+// container and its parents. It changes whenever the providers visible to
+// this container change.
+func (c *Container) chainProvidersVersion() int {
+	var version int
+	for s := c; s != nil; s = s.parent {
+		s.providersMu.RLock()
+		version += s.providersVersion
+		s.providersMu.RUnlock()
+	}
+	return version
+}
+
+// getProviders returns the providers for the given key in this container
+// and all its parents.
 func (c *Container) getProviders(k key) []provider {
-	nodes := c.providers[k]
-	providers := make([]provider, len(nodes))
-	for i, n := range nodes {
-		providers[i] = n
+	var providers []provider
+	for s := c; s != nil; s = s.parent {
+		s.providersMu.RLock()
+		for _, n := range s.providers[k] {
+			if !n.disabled() {
+				providers = append(providers, n)
+			}
+		}
+		s.providersMu.RUnlock()
 	}
 	return providers
 }
@@ -442,21 +1450,51 @@ func (c *Container) getProviders(k key) []provider {
 // arguments and produce results as separate return values, Provide also
 // accepts constructors that specify dependencies as dig.In structs and/or
 // specify results as dig.Out structs.
+//
+// Values that don't need a constructor may be provided by wrapping them with
+// dig.Value.
 func (c *Container) Provide(constructor interface{}, opts ...ProvideOption) error {
+	// Unlike checkUsable, leave the constructors this scope kept from an
+	// earlier use to providePooled, which matches them.
+	if err := c.checkReleased(); err != nil {
+		return err
+	}
+	if err := c.parent.dropUnprovided(); err != nil {
+		return err
+	}
+	if err := c.checkMutable(); err != nil {
+		return err
+	}
+
 	ctype := reflect.TypeOf(constructor)
 	if ctype == nil {
 		return errors.New("can't provide an untyped nil")
 	}
-	if ctype.Kind() != reflect.Func {
-		return fmt.Errorf("must provide constructor function, got %v (type %v)", constructor, ctype)
+
+	options, err := c.provideOptions(opts)
+	if err != nil {
+		return err
 	}
 
-	var options provideOptions
-	for _, o := range opts {
-		o.applyProvideOption(&options)
+	var caller *digreflect.Func
+	if _, ok := constructor.(*providedValue); ok {
+		caller = digreflect.InspectCaller(1)
 	}
-	if err := options.Validate(); err != nil {
-		return err
+	if c.pool != nil {
+		return c.providePooled(constructor, options, caller)
+	}
+	return c.provideConstructor(constructor, options, caller)
+}
+
+// provideConstructor provides a constructor or a value wrapped by Value,
+// passed to Provide from the given caller, to the container.
+func (c *Container) provideConstructor(constructor interface{}, options provideOptions, caller *digreflect.Func) error {
+	if v, ok := constructor.(*providedValue); ok {
+		return c.provideValue(v, options, caller)
+	}
+
+	if ctype := reflect.TypeOf(constructor); ctype.Kind() != reflect.Func {
+		return fmt.Errorf("must provide constructor function, got %v (type %v)", constructor, ctype)
 	}
 
 	if err := c.provide(constructor, options); err != nil {
@@ -476,7 +1514,43 @@ func (c *Container) Provide(constructor interface{}, opts ...ProvideOption) erro
 //
 // The function may return an error to indicate failure. The error will be
 // returned to the caller as-is.
+//
+// Value groups consumed by the function and its dependencies only include
+// the values of constructors provided before Invoke started building them,
+// even if more constructors are provided to the group in the meantime, for
+// example from another goroutine. Those contribute to later Invokes.
 func (c *Container) Invoke(function interface{}, opts ...InvokeOption) error {
+	if err := c.checkInitialized(); err != nil {
+		return err
+	}
+
+	c.calls.enter()
+	defer c.calls.exit()
+
+	if c.freezeOnInvoke && c.frozenAt == nil {
+		// invoke reports the cycles that prevent freezing.
+		_ = c.freeze(digreflect.InspectCaller(1))
+	}
+
+	err := c.invoke(function, opts...)
+	if err != nil {
+		err = c.withVerbosity(err)
+	}
+	if err != nil && c.attachGraphToErrors {
+		err = errWithGraph{err: err, snapshot: c.snapshotGraph()}
+	}
+	c.metrics.recordInvoke(err)
+	if c.metricsSink != nil {
+		c.metricsSink(c.Metrics())
+	}
+	return err
+}
+
+func (c *Container) invoke(function interface{}, opts ...InvokeOption) error {
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+
 	ftype := reflect.TypeOf(function)
 	if ftype == nil {
 		return errors.New("can't invoke an untyped nil")
@@ -485,33 +1559,99 @@ func (c *Container) Invoke(function interface{}, opts ...InvokeOption) error {
 		return fmt.Errorf("can't invoke non-function %v (type %v)", function, ftype)
 	}
 
-	pl, err := newParamList(ftype)
+	var options invokeOptions
+	for _, o := range opts {
+		o.applyInvokeOption(&options)
+	}
+	if options.Dependencies != nil {
+		if options.Audit == nil {
+			options.Audit = new(InvokeAudit)
+		}
+		defer func() { *options.Dependencies = options.Audit.dependencies() }()
+	}
+
+	fn := options.caller
+	if fn == nil {
+		fn = digreflect.InspectFunc(function)
+	}
+
+	pl, err := c.invokeParamList(ftype)
+	if err == nil {
+		err = checkInvokable(pl)
+	}
 	if err != nil {
+		return errWrapf(err, "function %v cannot be invoked", fn)
+	}
+	if options.caller == nil {
+		c.recordInvoke(function, pl)
+	}
+	if err := c.checkNestedInvoke(fn, pl); err != nil {
 		return err
 	}
+	if c.calls.depth == 1 {
+		// Both change the state of parents, which their other scopes may
+		// be using.
+		c.parentCalls.lock(c.calls)
+		c.discardRemovedValues()
+		err := c.evaluateConditions()
+		c.parentCalls.unlock()
+		if err != nil {
+			return err
+		}
+	}
 
+	if options.Preflight != nil {
+		*options.Preflight = newPreflightReport(c, pl)
+	}
+
+	if err := resolveGenericDependencies(c, pl); err != nil {
+		return errArgumentsFailed{
+			Func:   fn,
+			Reason: err,
+		}
+	}
 	if err := shallowCheckDependencies(c, pl); err != nil {
 		return errMissingDependencies{
-			Func:   digreflect.InspectFunc(function),
+			Func:   fn,
 			Reason: err,
 		}
 	}
 
-	if !c.isVerifiedAcyclic {
-		if err := c.verifyAcyclic(); err != nil {
-			return err
-		}
+	if err := c.intercept(fn, pl); err != nil {
+		return err
+	}
+
+	if c.trackProvenance {
+		info := newInvokeInfo(fn, pl)
+		defer c.calls.setInvoke(&info)()
+	}
+	if options.Audit != nil {
+		defer c.calls.setAudit(options.Audit)()
 	}
 
+	if err := c.verifyChainAcyclic(); err != nil {
+		return err
+	}
+
+	c.emit(EventInvoking, fn, 0, nil)
+	defer c.calls.setGroupVersion(c.versions.current())()
+	defer c.enterInvoke(fn)()
 	args, err := pl.BuildList(c)
 	if err != nil {
+		// Report runaway recursion once rather than at every level.
+		if derr, ok := RootCause(err).(DepthExceededError); ok {
+			err = derr
+		}
 		return errArgumentsFailed{
-			Func:   digreflect.InspectFunc(function),
+			Func:   fn,
 			Reason: err,
 		}
 	}
 
-	returned := reflect.ValueOf(function).Call(args)
+	returned, err := c.call(fn, function, args)
+	if err != nil {
+		return err
+	}
 	if len(returned) == 0 {
 		return nil
 	}
@@ -524,23 +1664,95 @@ func (c *Container) Invoke(function interface{}, opts ...InvokeOption) error {
 }
 
 func (c *Container) verifyAcyclic() error {
-	visited := make(map[key]struct{})
-	for _, n := range c.nodes {
-		if err := detectCycles(n, c, nil /* path */, visited); err != nil {
-			return errWrapf(err, "cycle detected in dependency graph")
-		}
+	if err, ok := c.detectCycle(); ok {
+		return errWrapf(err, "cycle detected in dependency graph")
 	}
 
+	c.setVerifiedAcyclic()
+	return nil
+}
+
+// verifiedAcyclic reports whether the graph of this container was verified
+// to be acyclic since constructors were last provided to it.
+func (c *Container) verifiedAcyclic() bool {
+	c.providersMu.RLock()
+	defer c.providersMu.RUnlock()
+	return c.isVerifiedAcyclic
+}
+
+func (c *Container) setVerifiedAcyclic() {
+	c.providersMu.Lock()
 	c.isVerifiedAcyclic = true
+	c.providersMu.Unlock()
+}
+
+// verifyChainAcyclic verifies that this container and its parents are
+// acyclic unless they were already verified.
+func (c *Container) verifyChainAcyclic() error {
+	for _, s := range c.scopeChain() {
+		if !s.verifiedAcyclic() {
+			if err := s.verifyAcyclic(); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// detectCycle returns a description of a cycle between the constructors of
+// this container if there is one.
+func (c *Container) detectCycle() (errCycleDetected, bool) {
+	d := newCycleDetector(c)
+	c.nodesMu.RLock()
+	nodes := c.nodes
+	c.nodesMu.RUnlock()
+	for _, n := range nodes {
+		if err, ok := d.visit(n); ok {
+			return err, true
+		}
+	}
+	return errCycleDetected{}, false
+}
+
 func (c *Container) provide(ctor interface{}, opts provideOptions) error {
-	n, err := newNode(ctor, nodeOptions{ResultName: opts.Name})
+	n, err := newNode(ctor, nodeOptions{
+		ResultName:     opts.Name,
+		NameAll:        opts.NameAll,
+		ResultNames:    opts.ResultNames,
+		ResultGroups:   opts.Groups,
+		ResultAsGroups: opts.resultAsGroups(),
+		PopulateFields: opts.PopulateFields,
+		Location:       opts.Location,
+		Mount:          opts.Mount,
+		Bridge:         opts.Bridge,
+		Ambient:        opts.Ambient,
+		MemoizeByArgs:  opts.MemoizeByArgs,
+		CommitPartial:  opts.CommitPartialResults,
+		AllowNoResults: opts.AllowNoResults,
+		Env:            c.env,
+		StrictEnv:      c.strictEnv,
+		Fallback:       opts.IfNotPresent,
+		When:           opts.When,
+		WhenOnce:       opts.WhenOnce,
+		Doc:            opts.Doc,
+
+		AllowUnbuffered: opts.AllowUnbuffered,
+	})
 	if err != nil {
 		return err
 	}
 
+	if _, err := n.evaluateCondition(); err != nil {
+		return err
+	}
+
+	if err := n.paramList.checkDuplicates(); err != nil {
+		if c.duplicateParamsHandler == nil {
+			return err
+		}
+		c.duplicateParamsHandler(err)
+	}
+
 	keys, err := c.findAndValidateResults(n)
 	if err != nil {
 		return err
@@ -550,23 +1762,66 @@ func (c *Container) provide(ctor interface{}, opts provideOptions) error {
 	if len(keys) == 0 {
 		return fmt.Errorf("%v must provide at least one non-error type", ctype)
 	}
+	if err := c.checkUniqueNames(keys); err != nil {
+		return err
+	}
+	uses := groupTypeUses(n, keys)
+	if err := c.checkGroupTypes(uses); err != nil {
+		return err
+	}
+	if opts.CopyOnInject {
+		if err := checkCopyOnInject(n, keys); err != nil {
+			return err
+		}
+	}
 
+	n.scope = c
+	oldProviders := make(map[key][]*node, len(keys))
 	for k := range keys {
+		c.providersMu.Lock()
 		c.isVerifiedAcyclic = false
-		oldProviders := c.providers[k]
+		oldProviders[k] = c.providers[k]
 		c.providers[k] = append(c.providers[k], n)
+		c.providersVersion++
+		c.providersMu.Unlock()
 
 		if c.deferAcyclicVerification {
 			continue
 		}
 		if err := verifyAcyclic(c, n, k); err != nil {
-			c.providers[k] = oldProviders
+			// Roll back all keys, not just this one, so that a failed
+			// Provide leaves no trace of the constructor in the container.
+			c.providersMu.Lock()
+			for k, ps := range oldProviders {
+				if len(ps) == 0 {
+					delete(c.providers, k)
+				} else {
+					c.providers[k] = ps
+				}
+			}
+			c.providersVersion++
+			c.providersMu.Unlock()
 			return err
 		}
-		c.isVerifiedAcyclic = true
+		c.setVerifiedAcyclic()
+	}
+
+	for k := range keys {
+		if k.group == "" {
+			c.supersedeFallbacks(n, k)
+		}
+	}
+	if opts.CopyOnInject {
+		c.markCopyOnInject(keys)
 	}
 
+	c.nodesMu.Lock()
 	c.nodes = append(c.nodes, n)
+	c.nodesMu.Unlock()
+	c.recordGroupTypes(uses)
+	c.versions.publish(n)
+	c.metrics.recordProvide()
+	c.emit(EventProvided, n.location, 0, nil)
 
 	return nil
 }
@@ -588,6 +1843,11 @@ func (c *Container) findAndValidateResults(n *node) (map[key]struct{}, error) {
 
 	keys := make(map[key]struct{}, len(keyPaths))
 	for k := range keyPaths {
+		if k.group != "" {
+			if err := c.checkGroupIndexes(n, k); err != nil {
+				return nil, err
+			}
+		}
 		keys[k] = struct{}{}
 	}
 	return keys, nil
@@ -656,12 +1916,23 @@ func (cv connectionVisitor) Visit(res result) resultVisitor {
 			return nil
 		}
 
-		if ps := cv.c.providers[k]; len(ps) > 0 {
-			cons := make([]string, len(ps))
-			for i, p := range ps {
-				cons[i] = fmt.Sprint(p.Location())
+		var cons []string
+		for _, p := range cv.c.getProviders(k) {
+			if cv.n.condition != nil || isConditional(p) {
+				// Conflicts are reported when the values are built.
+				continue
 			}
-
+			if !supersedes(cv.c, cv.n, p) {
+				cons = append(cons, fmt.Sprint(p.Location()))
+				continue
+			}
+			if !cv.n.fallback && p.Called() && !isAmbient(p) {
+				*cv.err = fmt.Errorf(
+					"cannot provide %v from %v: fallback %v was already called", k, path, p.Location())
+				return nil
+			}
+		}
+		if len(cons) > 0 {
 			*cv.err = fmt.Errorf(
 				"cannot provide %v from %v: already provided by %v",
 				k, path, strings.Join(cons, "; "))
@@ -674,8 +1945,14 @@ func (cv connectionVisitor) Visit(res result) resultVisitor {
 		// we don't really care about the path for this since conflicts are
 		// okay for group results. We'll track it for the sake of having a
 		// value there.
-		k := key{group: r.Group, t: r.Type}
-		cv.keyPaths[k] = path
+		for _, g := range r.Groups {
+			k := key{group: g, t: r.Type}
+			cv.keyPaths[k] = path
+		}
+		for _, as := range r.As {
+			k := key{group: as.Group, t: as.Type}
+			cv.keyPaths[k] = path
+		}
 	}
 
 	return cv
@@ -700,6 +1977,72 @@ type node struct {
 	// Whether the constructor owned by this node was already called.
 	called bool
 
+	// Whether the constructor owned by this node is running. See
+	// ReentrancyError.
+	running bool
+
+	// Whether the constructor is building its arguments or running. See
+	// errRecursiveCall.
+	building bool
+
+	// Container into which this node was provided.
+	scope *Container
+
+	// Prefix of the mounted container this node builds its values in, if
+	// any. See Container.Mount.
+	mount string
+
+	// Description of the bridge between a value group and named values
+	// this node belongs to, if any. See Container.BridgeGroupToNames.
+	bridge string
+
+	// Whether the node provides an ambient value. See WithAmbientValues.
+	ambient bool
+
+	// Condition under which the node is enabled, if it was provided with
+	// When.
+	condition *condition
+
+	// Whether the node only provides values that no other node provides.
+	// See IfNotPresent.
+	fallback bool
+
+	// Description of the constructor, if any. See Doc.
+	doc string
+
+	// Position of the node in the order in which nodes were provided, or
+	// _unpublished while it is being provided. Accessed atomically. See
+	// getGroupContributors.
+	version uint64
+
+	// Whether unbuffered channels produced by the node are intended, and
+	// the keys of the unbuffered channels it produced otherwise. See
+	// AllowUnbuffered. Changes to unbuffered are guarded by unbufferedMu so
+	// that graphs can be built while Invoke calls the constructor.
+	allowUnbuffered bool
+	unbuffered      map[key]struct{}
+	unbufferedMu    sync.Mutex
+
+	// Keys of values produced by this node that were removed with
+	// RemoveNamed or RemoveAll, or superseded by other nodes. These are
+	// never submitted to the container.
+	removedKeys map[key]struct{}
+
+	// Values submitted to value groups by the constructor once it was
+	// called.
+	groupValues map[key][]reflect.Value
+
+	// If non-nil, results of previous calls to the constructor keyed by
+	// the values of their arguments. See MemoizeByArgs.
+	memo map[interface{}]*stagingContainerWriter
+
+	// Whether values returned along with an error are kept. See
+	// CommitPartialResults.
+	commitPartial bool
+
+	// Whether the node may commit none of its values. See AllowNoResults.
+	allowNoResults bool
+
 	// Type information about constructor parameters.
 	paramList paramList
 
@@ -710,6 +2053,70 @@ type node struct {
 type nodeOptions struct {
 	// If specified, all values produced by this node have the provided name.
 	ResultName string
+
+	// Whether ResultName may apply to more than one value. See
+	// NameAllResults.
+	NameAll bool
+
+	// Names of the values produced by this node at specific positions. See
+	// NameResult.
+	ResultNames []resultName
+
+	// If specified, all values produced by this node are submitted to the
+	// provided value groups.
+	ResultGroups []string
+
+	// If specified, all values produced by this node are submitted to the
+	// provided value groups as an interface they implement.
+	ResultAsGroups []groupAs
+
+	// If set, the tagged fields of values produced by this node are
+	// populated from the container before they are committed.
+	PopulateFields bool
+
+	// If specified, this is reported as the location of the constructor.
+	// This is used for constructors synthesized by dig.
+	Location *digreflect.Func
+
+	// Prefix of the mounted container the constructor builds its values in.
+	Mount string
+
+	// Description of the bridge the node belongs to, if any.
+	Bridge string
+
+	// Whether the node provides an ambient value.
+	Ambient bool
+
+	// Condition under which the node is enabled, if any. See When.
+	When     func() bool
+	WhenOnce bool
+
+	// If set, results are cached by the values of the arguments. See
+	// MemoizeByArgs.
+	MemoizeByArgs bool
+
+	// If set, values returned along with an error are kept. See
+	// CommitPartialResults.
+	CommitPartial bool
+
+	// If set, the node may commit none of its values. See AllowNoResults.
+	AllowNoResults bool
+
+	// Environment whose names fields tagged with `env:".."` consume. See
+	// EnvironmentName.
+	Env       string
+	StrictEnv bool
+
+	// If set, the node only provides values that no other node provides.
+	// See IfNotPresent.
+	Fallback bool
+
+	// If set, unbuffered channels produced by the node are intended. See
+	// AllowUnbuffered.
+	AllowUnbuffered bool
+
+	// Description of the constructor. See Doc.
+	Doc string
 }
 
 func newNode(ctor interface{}, opts nodeOptions) (*node, error) {
@@ -722,25 +2129,81 @@ func newNode(ctor interface{}, opts nodeOptions) (*node, error) {
 		return nil, err
 	}
 
-	results, err := newResultList(ctype, resultOptions{Name: opts.ResultName})
+	if opts.PopulateFields {
+		params.Populated, err = newPopulatedResults(ctype)
+		if err != nil {
+			return nil, err
+		}
+	}
+	params, err = params.withEnvironment(opts.Env, opts.StrictEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := newResultList(ctype, resultOptions{
+		Name:     opts.ResultName,
+		NameAll:  opts.NameAll,
+		Names:    opts.ResultNames,
+		Groups:   opts.ResultGroups,
+		AsGroups: opts.ResultAsGroups,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &node{
+	n := &node{
 		ctor:       ctor,
 		ctype:      ctype,
-		location:   digreflect.InspectFunc(ctor),
+		location:   opts.Location,
+		mount:      opts.Mount,
+		bridge:     opts.Bridge,
+		ambient:    opts.Ambient,
+		fallback:   opts.Fallback,
+		doc:        opts.Doc,
+		version:    _unpublished,
 		id:         dot.CtorID(cptr),
 		paramList:  params,
 		resultList: results,
-	}, err
+
+		allowUnbuffered: opts.AllowUnbuffered,
+		allowNoResults:  opts.AllowNoResults,
+	}
+	if opts.MemoizeByArgs {
+		if err := checkMemoizable(params, results); err != nil {
+			return nil, err
+		}
+		n.memo = make(map[interface{}]*stagingContainerWriter)
+	}
+	if opts.CommitPartial {
+		if err := checkPartialResults(results); err != nil {
+			return nil, err
+		}
+		n.commitPartial = true
+	}
+	if opts.When != nil {
+		n.condition = &condition{fn: opts.When, once: opts.WhenOnce}
+	}
+	if n.location == nil {
+		n.location = digreflect.InspectFunc(ctor)
+	} else {
+		// Synthesized constructors share their code pointer so we identify
+		// them by their node instead.
+		n.id = dot.CtorID(reflect.ValueOf(n).Pointer())
+	}
+	return n, err
 }
 
 func (n *node) Location() *digreflect.Func { return n.location }
 func (n *node) ParamList() paramList       { return n.paramList }
 func (n *node) ResultList() resultList     { return n.resultList }
 func (n *node) ID() dot.CtorID             { return n.id }
+func (n *node) Called() bool               { return n.called }
+func (n *node) OrigScope() containerStore  { return n.scope }
+func (n *node) Version() uint64            { return atomic.LoadUint64(&n.version) }
+
+func (n *node) GroupValues(name string, t reflect.Type) []reflect.Value {
+	return n.groupValues[key{group: name, t: t}]
+}
 
 // Call calls this node's constructor if it hasn't already been called and
 // injects any values produced by it into the provided container.
@@ -749,8 +2212,49 @@ func (n *node) Call(c containerStore) error {
 		return nil
 	}
 
+	receiver, err := n.stage(c)
+	if err != nil {
+		return err
+	}
+	return n.commit(c, receiver)
+}
+
+func (n *node) Stage(c containerStore) (commit func() error, err error) {
+	if n.called {
+		return func() error { return nil }, nil
+	}
+
+	receiver, err := n.stage(c)
+	if err != nil {
+		return nil, err
+	}
+	return func() error { return n.commit(c, receiver) }, nil
+}
+
+// stage calls this node's constructor and returns the values produced by it
+// without injecting them into the container.
+func (n *node) stage(c containerStore) (*stagingContainerWriter, error) {
+	if n.running {
+		return nil, ReentrancyError{fn: n.location}
+	}
+	if n.building {
+		return nil, errRecursiveCall{Func: n.location}
+	}
+	n.building = true
+	defer func() { n.building = false }()
+	if err := c.enterConstructor(n.location, firstResultKey(n.resultList)); err != nil {
+		return nil, err
+	}
+	defer c.exitConstructor()
+
+	if err := resolveGenericDependencies(c, n.paramList); err != nil {
+		return nil, errArgumentsFailed{
+			Func:   n.location,
+			Reason: err,
+		}
+	}
 	if err := shallowCheckDependencies(c, n.paramList); err != nil {
-		return errMissingDependencies{
+		return nil, errMissingDependencies{
 			Func:   n.location,
 			Reason: err,
 		}
@@ -758,20 +2262,119 @@ func (n *node) Call(c containerStore) error {
 
 	args, err := n.paramList.BuildList(c)
 	if err != nil {
-		return errArgumentsFailed{
+		return nil, errArgumentsFailed{
 			Func:   n.location,
 			Reason: err,
 		}
 	}
 
+	var memoKey interface{}
+	if n.memo != nil {
+		memoKey = newMemoKey(args)
+		if receiver, ok := n.memo[memoKey]; ok && memoKey != nil {
+			return receiver, nil
+		}
+	}
+
 	receiver := newStagingContainerWriter()
-	results := reflect.ValueOf(n.ctor).Call(args)
-	if err := n.resultList.ExtractList(receiver, results); err != nil {
+	c.emit(EventConstructing, n.location, 0, nil)
+	c.tracer().started(n)
+	start := time.Now()
+	results, panicErr := n.callConstructor(c, args)
+	if err = panicErr; err == nil {
+		err = n.resultList.ConstructorError(results)
+	}
+	var storeErr error
+	switch {
+	case err == nil:
+		storeErr = n.resultList.ExtractList(receiver, results)
+	case panicErr == nil && n.commitPartial:
+		// The error is reported as a Warning if any values are kept.
+		var salvaged bool
+		salvaged, storeErr = n.resultList.ExtractPartial(receiver, results, err)
+		if salvaged {
+			err = nil
+		}
+	}
+	if storeErr != nil {
+		c.recordConstructor(n.location, time.Since(start), 0, storeErr)
+		c.tracer().finished(n, time.Since(start), storeErr, nil)
+		return nil, errWrapf(storeErr, "cannot store the results of function %v", n.location)
+	}
+	receiver.annotateWarnings(n.location)
+	receiver.built, receiver.duration = start, time.Since(start)
+	receiver.invoke = c.currentInvoke()
+	c.recordConstructor(n.location, receiver.duration, receiver.Len(), err)
+	c.tracer().finished(n, receiver.duration, err, receiver)
+	if err != nil {
+		if captured := c.captureArgs(n.paramList, args); captured != nil {
+			err = ConstructorArgsError{Args: captured, Reason: err}
+		}
+		if panicErr != nil {
+			// PanicError already names the constructor.
+			return nil, err
+		}
+		return nil, errConstructorFailed{Func: n.location, Reason: err}
+	}
+
+	// Values are only committed after their fields are populated so that
+	// consumers never observe partially populated values.
+	if err := n.paramList.PopulateResults(c, results); err != nil {
+		return nil, errArgumentsFailed{
+			Func:   n.location,
+			Reason: err,
+		}
+	}
+
+	c.recordConstructions(n, receiver)
+	if memoKey != nil {
+		n.memo[memoKey] = receiver
+	}
+	return receiver, nil
+}
+
+// commit injects the values staged by a call to this node's constructor into
+// the container and marks the node called.
+//
+// Nodes commit at most once: if the node was called since the values were
+// staged, for example because another parameter required one of its values,
+// the staged values are discarded. This guarantees that the node contributes
+// to its value groups exactly once.
+//
+// If a ValueInterceptor rejects one of the values, or if the values would
+// exceed the limit set with GroupSizeLimit, none of them are committed. Other
+// errors are only returned by OnValueCommitted functions, after the values
+// were committed.
+func (n *node) commit(c containerStore, receiver *stagingContainerWriter) error {
+	if n.called {
+		return nil
+	}
+	if err := n.checkResultsCommitted(receiver); err != nil {
+		return err
+	}
+	receiver, err := receiver.intercept(c.commitInterceptors())
+	if err != nil {
 		return errConstructorFailed{Func: n.location, Reason: err}
 	}
-	receiver.Commit(c)
+	if err := c.checkGroupSizes(n, receiver.groups); err != nil {
+		return err
+	}
+
+	for k := range n.removedKeys {
+		delete(receiver.values, k)
+		delete(receiver.groups, k)
+	}
+	if err := receiver.Commit(providerWriter{c, n}); err != nil {
+		return err
+	}
+	c.recordProvenance(n, receiver)
+	n.recordUnbuffered(receiver)
+	if a := c.audit(); a != nil {
+		a.constructed(n, receiver)
+	}
+	n.groupValues = receiver.groups
 	n.called = true
-	return nil
+	return receiver.notify(c)
 }
 
 // Checks if a field of an In struct is optional.
@@ -794,24 +2397,39 @@ func isFieldOptional(f reflect.StructField) (bool, error) {
 // Checks that all direct dependencies of the provided param are present in
 // the container. Returns an error if not.
 func shallowCheckDependencies(c containerStore, p param) error {
-	var missing errMissingManyTypes
-	var addMissingNodes []*dot.Param
-	walkParam(p, paramVisitorFunc(func(p param) bool {
+	var missing []error
+	check := func(p param, path paramPath) {
 		ps, ok := p.(paramSingle)
 		if !ok {
-			return true
+			return
 		}
 
-		if ns := c.getValueProviders(ps.Name, ps.Type); len(ns) == 0 && !ps.Optional {
-			missing = append(missing, newErrMissingType(c, key{name: ps.Name, t: ps.Type}))
-			addMissingNodes = append(addMissingNodes, ps.DotParam()...)
+		// Values that nothing provides may still be served by the
+		// fallback resolver when they're built.
+		if ns := c.getValueProviders(ps.Name, ps.Type); len(ns) == 0 && !ps.Optional &&
+			len(c.getImplementations(ps.Name, ps.Type)) == 0 && c.fallbackResolver() == nil {
+			err := newErrMissingType(c, key{name: ps.Name, t: ps.Type})
+			err.Doc = ps.Doc
+			if !path.IsZero() {
+				err.Paths = []paramPath{path}
+			}
+			missing = append(missing, err)
 		}
+	}
 
-		return true
-	}))
+	if pl, ok := p.(paramList); ok {
+		for i, p := range pl.Params {
+			walkParamPaths(p, paramPath{Arg: i + 1}, check)
+		}
+		for _, pr := range pl.Populated {
+			walkParamPaths(pr.Object, paramPath{}, check)
+		}
+	} else {
+		walkParamPaths(p, paramPath{}, check)
+	}
 
 	if len(missing) > 0 {
-		return missing
+		return newErrMissingTypes(missing)
 	}
 	return nil
 }
@@ -821,6 +2439,13 @@ func shallowCheckDependencies(c containerStore, p param) error {
 type stagingContainerWriter struct {
 	values map[key]reflect.Value
 	groups map[key][]reflect.Value
+	errors map[key]error
+
+	// When and for how long the constructor that produced the values ran,
+	// and the Invoke that caused it to run, if any.
+	built    time.Time
+	duration time.Duration
+	invoke   *InvokeInfo
 }
 
 var _ containerWriter = (*stagingContainerWriter)(nil)
@@ -829,29 +2454,65 @@ func newStagingContainerWriter() *stagingContainerWriter {
 	return &stagingContainerWriter{
 		values: make(map[key]reflect.Value),
 		groups: make(map[key][]reflect.Value),
+		errors: make(map[key]error),
 	}
 }
 
-func (sr *stagingContainerWriter) setValue(name string, t reflect.Type, v reflect.Value) {
-	sr.values[key{t: t, name: name}] = v
+func (sr *stagingContainerWriter) setValue(name string, t reflect.Type, v reflect.Value) error {
+	k := key{t: t, name: name}
+	if err := checkStoredValue(k, v); err != nil {
+		return err
+	}
+	sr.values[k] = v
+	return nil
 }
 
-func (sr *stagingContainerWriter) submitGroupedValue(group string, t reflect.Type, v reflect.Value) {
+func (sr *stagingContainerWriter) submitGroupedValue(group string, t reflect.Type, v reflect.Value) error {
 	k := key{t: t, group: group}
+	if err := checkStoredValue(k, v); err != nil {
+		return err
+	}
 	sr.groups[k] = append(sr.groups[k], v)
+	return nil
+}
+
+func (sr *stagingContainerWriter) setValueError(name string, t reflect.Type, err error) {
+	sr.errors[key{t: t, name: name}] = err
+}
+
+// Len returns the number of values staged in this writer, counting each
+// member of a value group separately.
+func (sr *stagingContainerWriter) Len() int {
+	n := len(sr.values)
+	for _, vs := range sr.groups {
+		n += len(vs)
+	}
+	return n
 }
 
 // Commit commits the received results to the provided containerWriter.
-func (sr *stagingContainerWriter) Commit(cw containerWriter) {
+//
+// The values were validated when they were staged, so this only fails if the
+// containerWriter rejects them for other reasons.
+func (sr *stagingContainerWriter) Commit(cw containerWriter) error {
 	for k, v := range sr.values {
-		cw.setValue(k.name, k.t, v)
+		if err := cw.setValue(k.name, k.t, v); err != nil {
+			return err
+		}
 	}
 
 	for k, vs := range sr.groups {
 		for _, v := range vs {
-			cw.submitGroupedValue(k.group, k.t, v)
+			if err := cw.submitGroupedValue(k.group, k.t, v); err != nil {
+				return err
+			}
 		}
 	}
+
+	for k, err := range sr.errors {
+		cw.setValueError(k.name, k.t, err)
+	}
+	return nil
 }
 
 type byTypeName []reflect.Type
@@ -877,11 +2538,20 @@ func shuffledCopy(rand *rand.Rand, items []reflect.Value) []reflect.Value {
 }
 
 func newDotCtor(n *node) *dot.Ctor {
-	return &dot.Ctor{
+	ctor := &dot.Ctor{
 		ID:      n.id,
 		Name:    n.location.Name,
 		Package: n.location.Package,
 		File:    n.location.File,
 		Line:    n.location.Line,
 	}
+	if n.scope != nil {
+		ctor.Scope = n.scope.name
+	}
+	ctor.Mount = n.mount
+	ctor.Bridge = n.bridge
+	ctor.Ambient = n.ambient
+	ctor.Disabled = n.disabled()
+	ctor.Doc = n.doc
+	return ctor
 }
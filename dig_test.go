@@ -874,6 +874,387 @@ func TestEndToEndSuccess(t *testing.T) {
 	})
 }
 
+func TestGroupFailures(t *testing.T) {
+	type in struct {
+		In
+
+		Values []string `group:"values"`
+	}
+
+	type out struct {
+		Out
+
+		Value string `group:"values"`
+		Name  string `name:"name"`
+	}
+
+	newContainer := func(t *testing.T, seed int64, calls *int) *Container {
+		c := New(setRand(rand.New(rand.NewSource(seed))))
+		require.NoError(t, c.Provide(func() (string, error) {
+			return "", errors.New("first failure")
+		}, Group("values")))
+		require.NoError(t, c.Provide(func() out {
+			*calls++
+			return out{Value: "ok", Name: "name"}
+		}))
+		require.NoError(t, c.Provide(func() (string, error) {
+			return "", errors.New("second failure")
+		}, Group("values")))
+		return c
+	}
+
+	t.Run("failures are aggregated", func(t *testing.T) {
+		var calls int
+		err := newContainer(t, 0, &calls).Invoke(func(in) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestGroupFailures\S+`,
+			`could not build value group string\[group="values"\]: 2 constructors failed: `,
+			`\[0\] function "go.uber.org/dig".TestGroupFailures\S+ \(\S+\) returned a non-nil error: first failure; `,
+			`\[1\] function "go.uber.org/dig".TestGroupFailures\S+ \(\S+\) returned a non-nil error: second failure`)
+		assert.Equal(t, "first failure", RootCause(err).Error())
+		assert.Equal(t, 1, calls, "constructors must be called even after failures")
+	})
+
+	t.Run("stable across seeds", func(t *testing.T) {
+		var calls int
+		invoke := func(in) {}
+		want := newContainer(t, 0, &calls).Invoke(invoke)
+		require.Error(t, want)
+
+		for seed := int64(1); seed < 10; seed++ {
+			err := newContainer(t, seed, &calls).Invoke(invoke)
+			require.Error(t, err)
+			assert.Equal(t, want.Error(), err.Error(), "seed %v", seed)
+		}
+	})
+
+	t.Run("successes are not committed", func(t *testing.T) {
+		var calls int
+		c := newContainer(t, 0, &calls)
+		require.Error(t, c.Invoke(func(in) {}))
+
+		type named struct {
+			In
+
+			Name string `name:"name"`
+		}
+
+		require.NoError(t, c.Invoke(func(n named) {
+			assert.Equal(t, "name", n.Name)
+		}))
+		assert.Equal(t, 2, calls, "values of the first call must have been discarded")
+
+		// Constructors that were called since are not called again.
+		require.Error(t, c.Invoke(func(in) {}))
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestGroupValuesExactlyOnce(t *testing.T) {
+	type in struct {
+		In
+
+		Values []string `group:"values"`
+	}
+
+	type out struct {
+		Out
+
+		A string `group:"values"`
+		B string `group:"values"`
+		C string `group:"values"`
+	}
+
+	// flaky returns a constructor for out that fails until fail is false.
+	flaky := func(fail *bool, calls *int) func() (out, error) {
+		return func() (out, error) {
+			*calls++
+			if *fail {
+				return out{A: "a", B: "b"}, errors.New("great sadness")
+			}
+			return out{A: "a", B: "b", C: "c"}, nil
+		}
+	}
+
+	t.Run("retry after failure", func(t *testing.T) {
+		var (
+			fail  = true
+			calls int
+		)
+		c := New()
+		require.NoError(t, c.Provide(flaky(&fail, &calls)))
+
+		require.Error(t, c.Invoke(func(in) {}))
+		require.Error(t, c.Invoke(func(in) {}))
+		assert.Equal(t, 2, calls, "failed constructors must be retried")
+
+		fail = false
+		for i := 0; i < 2; i++ {
+			require.NoError(t, c.Invoke(func(i in) {
+				assert.ElementsMatch(t, []string{"a", "b", "c"}, i.Values)
+			}))
+		}
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("retry after sibling failure", func(t *testing.T) {
+		var (
+			fail                = true
+			okCalls, flakyCalls int
+		)
+		c := New()
+		require.NoError(t, c.Provide(func() string {
+			okCalls++
+			return "ok"
+		}, Group("values")))
+		require.NoError(t, c.Provide(flaky(&fail, &flakyCalls)))
+
+		require.Error(t, c.Invoke(func(in) {}))
+		fail = false
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.ElementsMatch(t, []string{"ok", "a", "b", "c"}, i.Values)
+		}))
+		assert.Equal(t, 2, okCalls,
+			"values of the successful constructor must have been discarded")
+		assert.Equal(t, 2, flakyCalls)
+	})
+
+	t.Run("retry after partial iteration", func(t *testing.T) {
+		type iterIn struct {
+			In
+
+			Values func(yield func(string) bool) error `group:"values"`
+		}
+
+		var (
+			fail  = true
+			calls int
+		)
+		c := New()
+		require.NoError(t, c.Provide(func() string { return "ok" }, Group("values")))
+		require.NoError(t, c.Provide(flaky(&fail, &calls)))
+
+		require.NoError(t, c.Invoke(func(i iterIn) {
+			assert.Error(t, i.Values(func(string) bool { return true }))
+		}))
+
+		fail = false
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.ElementsMatch(t, []string{"ok", "a", "b", "c"}, i.Values)
+		}))
+		require.NoError(t, c.Invoke(func(i iterIn) {
+			var got []string
+			assert.NoError(t, i.Values(func(v string) bool {
+				got = append(got, v)
+				return true
+			}))
+			assert.ElementsMatch(t, []string{"ok", "a", "b", "c"}, got)
+		}))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("called while staged", func(t *testing.T) {
+		// The constructor of "b" depends on a value produced by the
+		// constructor of "a", so it's called again while the values of
+		// its first call are staged. Only one call may contribute to the
+		// group.
+		type aOut struct {
+			Out
+
+			Value string `group:"values"`
+			Count int
+		}
+
+		var calls int
+		c := New()
+		require.NoError(t, c.Provide(func() aOut {
+			calls++
+			return aOut{Value: "a", Count: calls}
+		}))
+		require.NoError(t, c.Provide(func(int) string { return "b" }, Group("values")))
+
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.ElementsMatch(t, []string{"a", "b"}, i.Values)
+		}))
+	})
+
+	t.Run("failed provide is rolled back", func(t *testing.T) {
+		type A struct{}
+		type B struct{}
+		type C struct{}
+
+		// Cycles are detected one key at a time. Whether B or C is checked
+		// first, neither may remain provided.
+		for i := 0; i < 10; i++ {
+			c := New()
+			require.NoError(t, c.Provide(func(C) A { return A{} }))
+			require.Error(t, c.Provide(func(A) (B, C) { return B{}, C{} }))
+
+			err := c.Invoke(func(B) {})
+			require.Error(t, err, "B must not be provided")
+			assert.Contains(t, err.Error(), "type dig.B is not in the container")
+		}
+	})
+}
+
+func TestGroupIterators(t *testing.T) {
+	type in struct {
+		In
+
+		Values    func(yield func(string) bool)       `group:"values"`
+		ValuesErr func(yield func(string) bool) error `group:"values"`
+	}
+
+	t.Run("constructors are called lazily", func(t *testing.T) {
+		c := New()
+		calls := make(map[string]int)
+		for _, v := range []string{"a", "b", "c"} {
+			v := v
+			require.NoError(t, c.Provide(func() string {
+				calls[v]++
+				return v
+			}, Group("values")))
+		}
+
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Empty(t, calls, "constructors must not be called before iteration")
+
+			var first string
+			i.Values(func(v string) bool {
+				first = v
+				return false
+			})
+			assert.NotEmpty(t, first)
+			assert.Len(t, calls, 1, "only the first constructor must be called")
+
+			var all []string
+			require.NoError(t, i.ValuesErr(func(v string) bool {
+				all = append(all, v)
+				return true
+			}))
+			assert.ElementsMatch(t, []string{"a", "b", "c"}, all)
+		}))
+
+		assert.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1}, calls,
+			"constructors must be called once")
+	})
+
+	t.Run("constructor errors", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() (string, error) {
+			return "", errors.New("great sadness")
+		}, Group("values")))
+
+		require.NoError(t, c.Invoke(func(i in) {
+			err := i.ValuesErr(func(string) bool { return true })
+			require.Error(t, err)
+			assertErrorMatches(t, err,
+				`could not build value group string\[group="values"\]:`,
+				`function "go.uber.org/dig".TestGroupIterators\S+ \(\S+\) returned a non-nil error:`,
+				"great sadness")
+			assert.Equal(t, "great sadness", RootCause(err).Error())
+
+			assert.Panics(t, func() {
+				i.Values(func(string) bool { return true })
+			})
+		}))
+	})
+
+	t.Run("result objects", func(t *testing.T) {
+		type out struct {
+			Out
+
+			A string `group:"values"`
+			B string `group:"values"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() out { return out{A: "a", B: "b"} }))
+		require.NoError(t, c.Provide(func() string { return "c" }, Group("values")))
+
+		require.NoError(t, c.Invoke(func(i in) {
+			var got []string
+			require.NoError(t, i.ValuesErr(func(v string) bool {
+				got = append(got, v)
+				return true
+			}))
+			assert.ElementsMatch(t, []string{"a", "b", "c"}, got)
+		}))
+	})
+}
+
+func TestGroupChannels(t *testing.T) {
+	type in struct {
+		In
+
+		Values <-chan string `group:"values"`
+	}
+
+	t.Run("values are delivered", func(t *testing.T) {
+		c := New()
+		for _, v := range []string{"a", "b", "c"} {
+			v := v
+			require.NoError(t, c.Provide(func() string { return v }, Group("values")))
+		}
+
+		require.NoError(t, c.Invoke(func(i in) {
+			var got []string
+			for v := range i.Values {
+				got = append(got, v)
+			}
+			assert.ElementsMatch(t, []string{"a", "b", "c"}, got)
+		}))
+	})
+
+	t.Run("empty group", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Invoke(func(i in) {
+			_, ok := <-i.Values
+			assert.False(t, ok, "channel must be closed")
+		}))
+	})
+
+	t.Run("consumer stops reading", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() string { return "a" }, Group("values")))
+		require.NoError(t, c.Provide(func() string { return "b" }, Group("values")))
+		require.NoError(t, c.Invoke(func(i in) { <-i.Values }))
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Len(t, i.Values, 2, "every consumer must receive all values")
+		}))
+	})
+
+	t.Run("constructor errors", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() string { return "a" }, Group("values")))
+		require.NoError(t, c.Provide(func() (string, error) {
+			return "", errors.New("great sadness")
+		}, Group("values")))
+
+		err := c.Invoke(func(i in) {
+			t.Fatal("function must not be called")
+		})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not build value group string\[group="values"\]:`,
+			"great sadness")
+	})
+
+	t.Run("send-only channels", func(t *testing.T) {
+		c := New()
+		err := c.Invoke(func(struct {
+			In
+
+			Values chan<- string `group:"values"`
+		}) {
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(),
+			`field "Values" (chan<- string) must be of type <-chan string`)
+	})
+}
+
 func TestGroups(t *testing.T) {
 	t.Run("empty slice received without provides", func(t *testing.T) {
 		c := New()
@@ -1152,77 +1533,321 @@ func TestGroups(t *testing.T) {
 		)
 		assert.Equal(t, gaveErr, RootCause(err))
 	})
-}
 
-// --- END OF END TO END TESTS
-
-func TestProvideConstructorErrors(t *testing.T) {
-	t.Run("multiple-type constructor returns multiple objects of same type", func(t *testing.T) {
+	t.Run("group option", func(t *testing.T) {
 		c := New()
-		type A struct{}
-		constructor := func() (*A, *A, error) {
-			return &A{}, &A{}, nil
+
+		require.NoError(t, c.Provide(func() string { return "foo" }, Group("strings")))
+		require.NoError(t, c.Provide(func() (string, error) { return "bar", nil }, Group("strings")))
+
+		type in struct {
+			In
+
+			Strings []string `group:"strings"`
 		}
-		require.Error(t, c.Provide(constructor), "provide failed")
+
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.ElementsMatch(t, []string{"foo", "bar"}, i.Strings)
+		}), "invoke failed")
 	})
 
-	t.Run("constructor consumes a dig.Out", func(t *testing.T) {
+	t.Run("multiple group options", func(t *testing.T) {
+		type middleware struct{ name string }
+
 		c := New()
-		type out struct {
-			Out
 
-			Reader io.Reader
+		calls := 0
+		require.NoError(t, c.Provide(func() *middleware {
+			calls++
+			return &middleware{name: "auth"}
+		}, Group("http"), Group("grpc")))
+
+		type in struct {
+			In
+
+			HTTP []*middleware `group:"http"`
+			GRPC []*middleware `group:"grpc"`
 		}
 
-		type outPtr struct {
-			*Out
+		require.NoError(t, c.Invoke(func(i in) {
+			require.Len(t, i.HTTP, 1)
+			require.Len(t, i.GRPC, 1)
+			assert.True(t, i.HTTP[0] == i.GRPC[0], "groups must share the same value")
+		}), "invoke failed")
+		assert.Equal(t, 1, calls, "constructor must be called exactly once")
+	})
 
-			Reader io.Reader
+	t.Run("invalid group options", func(t *testing.T) {
+		type out struct {
+			Out
+
+			S string
 		}
 
 		tests := []struct {
 			desc        string
 			constructor interface{}
-			msg         string
+			opts        []ProvideOption
+			err         string
 		}{
 			{
-				desc:        "dig.Out",
-				constructor: func(out) io.Writer { return nil },
-				msg:         "dig.out embeds a dig.Out",
+				desc:        "empty group",
+				constructor: func() string { return "" },
+				opts:        []ProvideOption{Group("")},
+				err:         `invalid dig.Group(""): group names cannot be empty`,
 			},
 			{
-				desc:        "*dig.Out",
-				constructor: func(*out) io.Writer { return nil },
-				msg:         `\*dig.out embeds a dig.Out`,
+				desc:        "backquote",
+				constructor: func() string { return "" },
+				opts:        []ProvideOption{Group("foo`bar")},
+				err:         "invalid dig.Group(\"foo`bar\"): group names cannot contain backquotes",
 			},
 			{
-				desc:        "embeds *dig.Out",
-				constructor: func(outPtr) io.Writer { return nil },
-				msg:         `dig.outPtr embeds a dig.Out`,
+				desc:        "same group twice",
+				constructor: func() string { return "" },
+				opts:        []ProvideOption{Group("foo"), Group("foo")},
+				err:         `invalid dig.Group("foo"): group specified more than once`,
+			},
+			{
+				desc:        "with name",
+				constructor: func() string { return "" },
+				opts:        []ProvideOption{Name("bar"), Group("foo")},
+				err:         `cannot use named values with value groups: dig.Name("bar") provided with dig.Group("foo")`,
+			},
+			{
+				desc:        "result object",
+				constructor: func() out { return out{} },
+				opts:        []ProvideOption{Group("foo")},
+				err:         "cannot specify a group for result objects: dig.out embeds dig.Out",
+			},
+			{
+				desc:        "as group without pointer",
+				constructor: func() *bytes.Buffer { return nil },
+				opts:        []ProvideOption{AsGroup("foo", io.Reader(nil))},
+				err:         `invalid dig.AsGroup("foo", <nil>): expected a pointer to an interface`,
+			},
+			{
+				desc:        "as group of a pointer to a struct",
+				constructor: func() *bytes.Buffer { return nil },
+				opts:        []ProvideOption{AsGroup("foo", new(bytes.Buffer))},
+				err:         `invalid dig.AsGroup("foo", *bytes.Buffer): expected a pointer to an interface`,
+			},
+			{
+				desc:        "as group with empty group",
+				constructor: func() *bytes.Buffer { return nil },
+				opts:        []ProvideOption{AsGroup("", new(io.Reader))},
+				err:         `invalid dig.AsGroup("", *io.Reader): group names cannot be empty`,
+			},
+			{
+				desc:        "as group with name",
+				constructor: func() *bytes.Buffer { return nil },
+				opts:        []ProvideOption{Name("bar"), AsGroup("foo", new(io.Reader))},
+				err: `cannot use named values with value groups: ` +
+					`dig.Name("bar") provided with dig.AsGroup("foo", *io.Reader)`,
+			},
+			{
+				desc:        "as group of result object",
+				constructor: func() out { return out{} },
+				opts:        []ProvideOption{AsGroup("foo", new(io.Reader))},
+				err:         "cannot specify a group for result objects: dig.out embeds dig.Out",
+			},
+			{
+				desc:        "as group of unimplemented interface",
+				constructor: func() *bytes.Reader { return nil },
+				opts:        []ProvideOption{AsGroup("foo", new(io.Writer))},
+				err: `cannot provide *bytes.Reader as io.Writer in value group "foo": ` +
+					`*bytes.Reader does not implement io.Writer`,
 			},
 		}
 
 		for _, tt := range tests {
 			t.Run(tt.desc, func(t *testing.T) {
-				err := c.Provide(tt.constructor)
-				require.Error(t, err, "provide should fail")
-				assertErrorMatches(t, err,
-					`function "go.uber.org/dig".TestProvideConstructorErrors\S+ \(\S+:\d+\) cannot be provided:`,
-					`bad argument 1:`,
-					`cannot depend on result objects:`,
-					tt.msg)
+				err := New().Provide(tt.constructor, tt.opts...)
+				require.Error(t, err, "provide must fail")
+				assert.Contains(t, err.Error(), tt.err)
 			})
 		}
 	})
 
-	t.Run("name option cannot be provided for result structs", func(t *testing.T) {
+	t.Run("as group option", func(t *testing.T) {
 		c := New()
-		type A struct{ idx int }
 
-		type out struct {
-			Out
+		buf := bytes.NewBufferString("foo")
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return buf },
+			AsGroup("readers", new(io.Reader)), AsGroup("writers", new(io.Writer))))
+		require.NoError(t, c.Provide(func() (*bytes.Reader, error) {
+			return bytes.NewReader([]byte("bar")), nil
+		}, AsGroup("readers", new(io.Reader)), Group("bytes")))
 
-			A A
+		type in struct {
+			In
+
+			Readers []io.Reader     `group:"readers"`
+			Writers []io.Writer     `group:"writers"`
+			Bytes   []*bytes.Reader `group:"bytes"`
+		}
+
+		require.NoError(t, c.Invoke(func(i in) {
+			require.Len(t, i.Readers, 2)
+			require.Len(t, i.Writers, 1)
+			require.Len(t, i.Bytes, 1)
+			assert.Contains(t, i.Readers, io.Reader(buf))
+			assert.Contains(t, i.Readers, io.Reader(i.Bytes[0]))
+			assert.True(t, i.Writers[0] == io.Writer(buf), "groups must share the same value")
+		}), "invoke failed")
+
+		err := c.Invoke(func(*bytes.Buffer) {})
+		require.Error(t, err, "value must only be added to groups")
+		assert.Contains(t, err.Error(), "type *bytes.Buffer is not in the container")
+	})
+
+	t.Run("as group location in errors", func(t *testing.T) {
+		err := New().Provide(func() *bytes.Reader { return nil }, AsGroup("foo", new(io.Writer)))
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestGroups\S+ \(\S+:\d+\) cannot be provided: `,
+			`cannot provide \*bytes.Reader as io.Writer in value group "foo"`)
+	})
+}
+
+func TestResultObjectErrorFields(t *testing.T) {
+	type tenant struct{ name string }
+
+	type out struct {
+		Out
+
+		Foo    *tenant `name:"foo"`
+		FooErr error   `errorfor:"Foo"`
+		Bar    *tenant `name:"bar"`
+		BarErr error   `errorfor:"Bar"`
+	}
+
+	c := New()
+	calls := 0
+	require.NoError(t, c.Provide(func() out {
+		calls++
+		return out{
+			Foo:    &tenant{name: "foo"},
+			BarErr: errors.New("great sadness"),
+		}
+	}))
+
+	t.Run("successful fields are committed", func(t *testing.T) {
+		type in struct {
+			In
+
+			Foo *tenant `name:"foo"`
+		}
+
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Equal(t, "foo", i.Foo.name)
+		}))
+	})
+
+	t.Run("failed fields report their error", func(t *testing.T) {
+		type in struct {
+			In
+
+			Bar *tenant `name:"bar"`
+		}
+
+		err := c.Invoke(func(i in) {
+			require.FailNow(t, "invoke must not be called")
+		})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`failed to build \*dig.tenant\[name="bar"\]:`,
+			`function "go.uber.org/dig".TestResultObjectErrorFields\S+ \(\S+:\d+\) returned a non-nil error:`,
+			"field Bar of dig.out failed:",
+			"great sadness",
+		)
+		assert.Equal(t, errors.New("great sadness"), RootCause(err))
+	})
+
+	t.Run("failed fields fail optional consumers", func(t *testing.T) {
+		type in struct {
+			In
+
+			Bar *tenant `name:"bar" optional:"true"`
+		}
+
+		require.Error(t, c.Invoke(func(i in) {
+			require.FailNow(t, "invoke must not be called")
+		}))
+	})
+
+	assert.Equal(t, 1, calls, "constructor must be called once")
+}
+
+// --- END OF END TO END TESTS
+
+func TestProvideConstructorErrors(t *testing.T) {
+	t.Run("multiple-type constructor returns multiple objects of same type", func(t *testing.T) {
+		c := New()
+		type A struct{}
+		constructor := func() (*A, *A, error) {
+			return &A{}, &A{}, nil
+		}
+		require.Error(t, c.Provide(constructor), "provide failed")
+	})
+
+	t.Run("constructor consumes a dig.Out", func(t *testing.T) {
+		c := New()
+		type out struct {
+			Out
+
+			Reader io.Reader
+		}
+
+		type outPtr struct {
+			*Out
+
+			Reader io.Reader
+		}
+
+		tests := []struct {
+			desc        string
+			constructor interface{}
+			msg         string
+		}{
+			{
+				desc:        "dig.Out",
+				constructor: func(out) io.Writer { return nil },
+				msg:         "dig.out embeds a dig.Out",
+			},
+			{
+				desc:        "*dig.Out",
+				constructor: func(*out) io.Writer { return nil },
+				msg:         `\*dig.out embeds a dig.Out`,
+			},
+			{
+				desc:        "embeds *dig.Out",
+				constructor: func(outPtr) io.Writer { return nil },
+				msg:         `dig.outPtr embeds a dig.Out`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				err := c.Provide(tt.constructor)
+				require.Error(t, err, "provide should fail")
+				assertErrorMatches(t, err,
+					`function "go.uber.org/dig".TestProvideConstructorErrors\S+ \(\S+:\d+\) cannot be provided:`,
+					`bad argument 1:`,
+					`cannot depend on result objects:`,
+					tt.msg)
+			})
+		}
+	})
+
+	t.Run("name option cannot be provided for result structs", func(t *testing.T) {
+		c := New()
+		type A struct{ idx int }
+
+		type out struct {
+			Out
+
+			A A
 		}
 
 		err := c.Provide(func() out {
@@ -1265,6 +1890,89 @@ func TestProvideConstructorErrors(t *testing.T) {
 	})
 }
 
+func TestMismatchedMarkers(t *testing.T) {
+	type inAsOut struct {
+		In
+
+		Reader io.Reader
+	}
+
+	type outAsIn struct {
+		Out
+
+		Reader io.Reader
+	}
+
+	type inAndOut struct {
+		In
+		Out
+
+		Reader io.Reader
+	}
+
+	tests := []struct {
+		desc        string
+		constructor interface{}
+		msgs        []string
+	}{
+		{
+			desc:        "dig.Out as a parameter",
+			constructor: func(outAsIn) io.Writer { return nil },
+			msgs: []string{
+				"bad argument 1:",
+				"dig.outAsIn embeds a dig.Out, expected a dig.In:",
+				"embed dig.In instead of dig.Out",
+			},
+		},
+		{
+			desc:        "dig.In as a result",
+			constructor: func() inAsOut { return inAsOut{} },
+			msgs: []string{
+				"bad result 1:",
+				"dig.inAsOut embeds a dig.In, expected a dig.Out:",
+				"embed dig.Out instead of dig.In",
+			},
+		},
+		{
+			desc:        "dig.In and dig.Out as a parameter",
+			constructor: func(inAndOut) io.Writer { return nil },
+			msgs: []string{
+				"bad argument 1:",
+				"cannot depend on dig.inAndOut: it embeds both dig.In and dig.Out, expected only dig.In:",
+				"remove the dig.Out embed",
+			},
+		},
+		{
+			desc:        "dig.In and dig.Out as a result",
+			constructor: func() inAndOut { return inAndOut{} },
+			msgs: []string{
+				"bad result 1:",
+				"cannot provide dig.inAndOut: it embeds both dig.In and dig.Out, expected only dig.Out:",
+				"remove the dig.In embed",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			err := New().Provide(tt.constructor)
+			require.Error(t, err, "provide should fail")
+			assertErrorMatches(t, err,
+				`function "go.uber.org/dig".TestMismatchedMarkers\S+ \(\S+/dig_test.go:\d+\) cannot be provided:`,
+				tt.msgs...)
+		})
+	}
+
+	t.Run("invoke reports the function", func(t *testing.T) {
+		err := New().Invoke(func(outAsIn) {})
+		require.Error(t, err, "invoke should fail")
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestMismatchedMarkers\S+ \(\S+/dig_test.go:\d+\) cannot be invoked:`,
+			"bad argument 1:",
+			"dig.outAsIn embeds a dig.Out, expected a dig.In")
+	})
+}
+
 func TestProvideRespectsConstructorErrors(t *testing.T) {
 	t.Run("constructor succeeds", func(t *testing.T) {
 		c := New()
@@ -1372,6 +2080,111 @@ func TestProvideInvalidName(t *testing.T) {
 	assert.Contains(t, err.Error(), "invalid dig.Name(\"foo`bar\"): names cannot contain backquotes")
 }
 
+func TestProvideNamedResults(t *testing.T) {
+	t.Parallel()
+
+	type A struct{}
+	type B struct{}
+
+	t.Run("name with multiple results", func(t *testing.T) {
+		c := New()
+		err := c.Provide(func() (*A, *B, error) { return &A{}, &B{}, nil }, Name("x"))
+		require.Error(t, err, "Provide must fail")
+		assert.Contains(t, err.Error(), `cannot apply dig.Name("x") to the 2 results of func() (*dig.A, *dig.B, error): *dig.A, *dig.B; `+
+			"use dig.NameResult to name one of them, or dig.NameAllResults to name all of them")
+	})
+
+	t.Run("name with a single result and an error", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() (*A, error) { return &A{}, nil }, Name("x")))
+	})
+
+	t.Run("name all results", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() (*A, *B) { return &A{}, &B{} }, Name("x"), NameAllResults()))
+
+		type params struct {
+			In
+
+			A *A `name:"x"`
+			B *B `name:"x"`
+		}
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.NotNil(t, p.A)
+			assert.NotNil(t, p.B)
+		}))
+	})
+
+	t.Run("name one result", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() (*A, *B, error) { return &A{}, &B{}, nil }, NameResult(1, "x")))
+
+		type params struct {
+			In
+
+			A *A
+			B *B `name:"x"`
+		}
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.NotNil(t, p.A)
+			assert.NotNil(t, p.B)
+		}))
+		assert.Error(t, c.Invoke(func(*B) {}), "B must only be provided under its name")
+	})
+
+	tests := []struct {
+		desc string
+		opts []ProvideOption
+		err  string
+	}{
+		{
+			desc: "name all results without a name",
+			opts: []ProvideOption{NameAllResults()},
+			err:  "cannot use dig.NameAllResults without dig.Name",
+		},
+		{
+			desc: "negative index",
+			opts: []ProvideOption{NameResult(-1, "x")},
+			err:  `invalid dig.NameResult(-1, "x"): result indexes cannot be negative`,
+		},
+		{
+			desc: "empty name",
+			opts: []ProvideOption{NameResult(0, "")},
+			err:  `invalid dig.NameResult(0, ""): names cannot be empty`,
+		},
+		{
+			desc: "same result twice",
+			opts: []ProvideOption{NameResult(0, "x"), NameResult(0, "y")},
+			err:  `invalid dig.NameResult(0, "y"): result 0 named more than once`,
+		},
+		{
+			desc: "with a name",
+			opts: []ProvideOption{Name("x"), NameResult(0, "y")},
+			err:  `cannot use dig.Name("x") and dig.NameResult(0, "y") together`,
+		},
+		{
+			desc: "out of range",
+			opts: []ProvideOption{NameResult(3, "x")},
+			err:  `cannot apply dig.NameResult(3, "x") to func() (*dig.A, *dig.B, error): it returns 3 values`,
+		},
+		{
+			desc: "error",
+			opts: []ProvideOption{NameResult(2, "x")},
+			err:  `cannot apply dig.NameResult(2, "x") to func() (*dig.A, *dig.B, error): errors cannot be named`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			c := New()
+			err := c.Provide(func() (*A, *B, error) { return &A{}, &B{}, nil }, tt.opts...)
+			require.Error(t, err, "Provide must fail")
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
 func TestCantProvideUntypedNil(t *testing.T) {
 	t.Parallel()
 	c := New()
@@ -1632,27 +2445,330 @@ func TestProvideCycleFails(t *testing.T) {
 			`depends on \*dig.C provided by "go.uber.org/dig".TestProvideCycleFails.\S+ \(\S+\)`,
 		)
 	})
-}
 
-func TestIncompleteGraphIsOkay(t *testing.T) {
-	t.Parallel()
+	t.Run("DeferAcyclicVerification catches cycles behind checked keys", func(t *testing.T) {
+		// A -> B -> C <-> D
+		//
+		// The cycle is only reachable through keys that were already
+		// checked from A.
+		type A struct{}
+		type B struct{}
+		type C struct{}
+		type D struct{}
 
-	// A <- B <- C
-	// Even if we don't provide B, we should be able to resolve A.
-	type A struct{}
-	type B struct{}
-	type C struct{}
-	newA := func() *A { return &A{} }
-	newC := func(*B) *C { return &C{} }
+		c := New(DeferAcyclicVerification())
+		require.NoError(t, c.Provide(func(*B) *A { return &A{} }))
+		require.NoError(t, c.Provide(func(*C) *B { return &B{} }))
+		require.NoError(t, c.Provide(func(*D) *C { return &C{} }))
+		require.NoError(t, c.Provide(func(*C) *D { return &D{} }))
 
-	c := New()
-	assert.NoError(t, c.Provide(newA), "provide failed")
-	assert.NoError(t, c.Provide(newC), "provide failed")
-	assert.NoError(t, c.Invoke(func(*A) {}), "invoke failed")
-}
+		err := c.Invoke(func(*A) {})
+		require.Error(t, err, "expected error when introducing cycle")
+		assert.True(t, IsCycleDetected(err))
+		assertErrorMatches(t, err,
+			`cycle detected in dependency graph:`,
+			`\*dig.C provided by "go.uber.org/dig".TestProvideCycleFails.\S+ \(\S+\)`,
+			`depends on \*dig.D provided by "go.uber.org/dig".TestProvideCycleFails.\S+ \(\S+\)`,
+			`depends on \*dig.C provided by "go.uber.org/dig".TestProvideCycleFails.\S+ \(\S+\)`,
+		)
+	})
 
-func TestProvideFuncsWithoutReturnsFails(t *testing.T) {
-	t.Parallel()
+	t.Run("DeferAcyclicVerification catches cycles through value groups", func(t *testing.T) {
+		// A -> B -> [int] -> C -> D -> [int]
+		type A struct{}
+		type B struct{}
+		type D struct{}
+		type in struct {
+			In
+
+			Values []int `group:"values"`
+		}
+		type out struct {
+			Out
+
+			Value int `group:"values"`
+		}
+
+		c := New(DeferAcyclicVerification())
+		require.NoError(t, c.Provide(func(*B) *A { return &A{} }))
+		require.NoError(t, c.Provide(func(in) *B { return &B{} }))
+		require.NoError(t, c.Provide(func(*D) out { return out{} }))
+		require.NoError(t, c.Provide(func(in) *D { return &D{} }))
+
+		_, ok := c.HasCycle()
+		assert.True(t, ok)
+
+		err := c.Invoke(func(*A) {})
+		require.Error(t, err, "expected error when introducing cycle")
+		assert.True(t, IsCycleDetected(err))
+		assertErrorMatches(t, err,
+			`cycle detected in dependency graph:`,
+			`int\[group="values"\] provided by "go.uber.org/dig".TestProvideCycleFails.\S+ \(\S+\)`,
+			`depends on \*dig.D provided by "go.uber.org/dig".TestProvideCycleFails.\S+ \(\S+\)`,
+			`depends on int\[group="values"\] provided by "go.uber.org/dig".TestProvideCycleFails.\S+ \(\S+\)`,
+		)
+	})
+
+	t.Run("DeferAcyclicVerification catches group consumers contributing to the group", func(t *testing.T) {
+		type in struct {
+			In
+
+			Values []int `group:"values"`
+		}
+		type out struct {
+			Out
+
+			Value int `group:"values"`
+		}
+
+		c := New(DeferAcyclicVerification())
+		for i := 0; i < 10; i++ {
+			require.NoError(t, c.Provide(func() out { return out{} }))
+		}
+		require.NoError(t, c.Provide(func(in) out { return out{} }))
+
+		err := c.Invoke(func(in) {})
+		require.Error(t, err, "expected error when introducing cycle")
+		assert.True(t, IsCycleDetected(err))
+		assertErrorMatches(t, err,
+			`cycle detected in dependency graph:`,
+			`int\[group="values"\] provided by "go.uber.org/dig".TestProvideCycleFails.\S+ \(\S+\)`,
+			`depends on int\[group="values"\] provided by "go.uber.org/dig".TestProvideCycleFails.\S+ \(\S+\)`,
+		)
+	})
+
+	t.Run("DeferAcyclicVerification with many group contributors", func(t *testing.T) {
+		type in struct {
+			In
+
+			Values []int `group:"values"`
+		}
+		type out struct {
+			Out
+
+			Value int `group:"values"`
+		}
+
+		c := New(DeferAcyclicVerification())
+		require.NoError(t, c.Provide(func() string { return "" }))
+		for i := 0; i < 10000; i++ {
+			require.NoError(t, c.Provide(func(string) out { return out{Value: 1} }))
+		}
+
+		require.NoError(t, c.Invoke(func(in in) {
+			assert.Len(t, in.Values, 10000)
+		}))
+	})
+}
+
+func TestHasCycle(t *testing.T) {
+	t.Parallel()
+
+	type A struct{}
+	type B struct{}
+	type C struct{}
+
+	// A <- B <- C
+	// |         ^
+	// |_________|
+	newA := func(*C) *A { return &A{} }
+	newB := func(*A) *B { return &B{} }
+	newC := func(*B) *C { return &C{} }
+
+	t.Run("no cycle", func(t *testing.T) {
+		c := New(DeferAcyclicVerification())
+		require.NoError(t, c.Provide(newA))
+		require.NoError(t, c.Provide(newB))
+
+		cy, ok := c.HasCycle()
+		assert.False(t, ok)
+		assert.Nil(t, cy)
+		assert.True(t, c.isVerifiedAcyclic, "result must be cached")
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		c := New(DeferAcyclicVerification())
+		require.NoError(t, c.Provide(newA))
+		require.NoError(t, c.Provide(newB))
+		require.NoError(t, c.Provide(newC))
+
+		cy, ok := c.HasCycle()
+		require.True(t, ok)
+		require.Len(t, cy.Edges, 3)
+		assert.False(t, c.isVerifiedAcyclic)
+
+		// Every constructor depends on the next one, so the keys must be a
+		// rotation of *C, *B, *A.
+		want := []reflect.Type{
+			reflect.TypeOf(&C{}),
+			reflect.TypeOf(&B{}),
+			reflect.TypeOf(&A{}),
+		}
+		start := -1
+		for i, tt := range want {
+			if cy.Edges[0].Key.Type == tt {
+				start = i
+			}
+		}
+		require.NotEqual(t, -1, start, "unexpected key %v", cy.Edges[0].Key)
+		for i, e := range cy.Edges {
+			assert.Equal(t, want[(start+i)%len(want)], e.Key.Type, "edge %d", i)
+			assert.Equal(t, "go.uber.org/dig", e.Package)
+			assert.Contains(t, e.Name, "TestHasCycle")
+			assert.Contains(t, e.File, "dig_test.go")
+			assert.NotZero(t, e.Line)
+		}
+
+		assertErrorMatches(t, errors.New(cy.String()),
+			`"go.uber.org/dig".TestHasCycle\S+ \(\S+:\d+\) depends on \*dig.[ABC]`,
+			`provided by "go.uber.org/dig".TestHasCycle\S+ \(\S+:\d+\) depends on \*dig.[ABC]`,
+			`provided by "go.uber.org/dig".TestHasCycle\S+ \(\S+:\d+\) depends on \*dig.[ABC]`,
+			`provided by "go.uber.org/dig".TestHasCycle\S+ \(\S+:\d+\)$`,
+		)
+
+		err := c.Invoke(func(*A) {})
+		require.Error(t, err)
+		assert.True(t, IsCycleDetected(err), "Invoke must still fail")
+	})
+
+	t.Run("cycle in parent", func(t *testing.T) {
+		c := New(DeferAcyclicVerification())
+		require.NoError(t, c.Provide(newA))
+		require.NoError(t, c.Provide(newB))
+		require.NoError(t, c.Provide(newC))
+
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func() string { return "" }))
+
+		_, ok := s.HasCycle()
+		assert.True(t, ok)
+	})
+}
+
+func TestOptionalCycles(t *testing.T) {
+	t.Parallel()
+
+	type Node struct{ Children []*Node }
+	type B struct{ A interface{} }
+	type A struct{ B *B }
+
+	type optionalNode struct {
+		In
+
+		Parent *Node `optional:"true"`
+	}
+	type optionalB struct {
+		In
+
+		B *B `optional:"true"`
+	}
+	type optionalA struct {
+		In
+
+		A *A `optional:"true"`
+	}
+
+	t.Run("self-referential", func(t *testing.T) {
+		c := New()
+		var calls int
+		require.NoError(t, c.Provide(func(p optionalNode) *Node {
+			calls++
+			assert.Nil(t, p.Parent, "the constructor's own value must be absent")
+			return &Node{}
+		}))
+		require.NoError(t, c.Invoke(func(n *Node) {
+			assert.NotNil(t, n)
+		}))
+		assert.Equal(t, 1, calls)
+
+		_, ok := c.HasCycle()
+		assert.False(t, ok)
+	})
+
+	t.Run("optional edge breaks the loop", func(t *testing.T) {
+		// A -(optional)-> B -> A
+		c := New()
+		require.NoError(t, c.Provide(func(p optionalB) *A { return &A{B: p.B} }))
+		require.NoError(t, c.Provide(func(a *A) *B { return &B{A: a} }))
+
+		require.NoError(t, c.Invoke(func(a *A) {
+			assert.Nil(t, a.B, "B depends on A, so it must be absent while A is built")
+		}))
+		require.NoError(t, c.Invoke(func(b *B) {
+			assert.NotNil(t, b.A)
+		}))
+	})
+
+	t.Run("optional edge built first", func(t *testing.T) {
+		// A -(optional)-> B -> A, building B first.
+		c := New()
+		require.NoError(t, c.Provide(func(p optionalB) *A { return &A{B: p.B} }))
+		require.NoError(t, c.Provide(func(a *A) *B { return &B{A: a} }))
+
+		require.NoError(t, c.Invoke(func(b *B) {
+			require.NotNil(t, b.A)
+			assert.Nil(t, b.A.(*A).B)
+		}))
+	})
+
+	t.Run("all edges optional", func(t *testing.T) {
+		c := New(DeferAcyclicVerification())
+		require.NoError(t, c.Provide(func(p optionalB) *A { return &A{B: p.B} }))
+		require.NoError(t, c.Provide(func(p optionalA) *B { return &B{A: p.A} }))
+
+		_, ok := c.HasCycle()
+		assert.False(t, ok)
+		require.NoError(t, c.Invoke(func(a *A, b *B) {
+			assert.True(t, a.B == b, "A must be built with B")
+			assert.Nil(t, b.A)
+		}))
+	})
+
+	t.Run("required cycles are still reported", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func(*B) *A { return &A{} }))
+		err := c.Provide(func(*A) *B { return &B{} })
+		require.Error(t, err)
+		assert.True(t, IsCycleDetected(err))
+
+		c = New(DeferAcyclicVerification())
+		require.NoError(t, c.Provide(func(*B) *A { return &A{} }))
+		require.NoError(t, c.Provide(func(*A) *B { return &B{} }))
+		_, ok := c.HasCycle()
+		assert.True(t, ok)
+	})
+
+	t.Run("required loop next to an optional one", func(t *testing.T) {
+		// A -(optional)-> B -> C -> B
+		type C struct{}
+		c := New()
+		require.NoError(t, c.Provide(func(p optionalB) *A { return &A{B: p.B} }))
+		require.NoError(t, c.Provide(func(*C) *B { return &B{} }))
+		err := c.Provide(func(*B) *C { return &C{} })
+		require.Error(t, err)
+		assert.True(t, IsCycleDetected(err))
+	})
+}
+
+func TestIncompleteGraphIsOkay(t *testing.T) {
+	t.Parallel()
+
+	// A <- B <- C
+	// Even if we don't provide B, we should be able to resolve A.
+	type A struct{}
+	type B struct{}
+	type C struct{}
+	newA := func() *A { return &A{} }
+	newC := func(*B) *C { return &C{} }
+
+	c := New()
+	assert.NoError(t, c.Provide(newA), "provide failed")
+	assert.NoError(t, c.Provide(newC), "provide failed")
+	assert.NoError(t, c.Invoke(func(*A) {}), "invoke failed")
+}
+
+func TestProvideFuncsWithoutReturnsFails(t *testing.T) {
+	t.Parallel()
 
 	c := New()
 	assert.Error(t, c.Provide(func(*bytes.Buffer) {}))
@@ -1712,82 +2828,339 @@ func TestInvokesUseCachedObjects(t *testing.T) {
 	}
 }
 
-func TestProvideFailures(t *testing.T) {
-	t.Run("out returning multiple instances of the same type", func(t *testing.T) {
-		c := New()
-		type A struct{ idx int }
-		type ret struct {
-			Out
+func TestInvokeParamsCache(t *testing.T) {
+	t.Parallel()
 
-			A1 A // sampe type A provided three times
-			A2 A
-			A3 A
-		}
+	type params struct {
+		In
 
-		err := c.Provide(func() ret {
-			return ret{
-				A1: A{idx: 1},
-				A2: A{idx: 2},
-				A3: A{idx: 3},
-			}
-		})
-		require.Error(t, err, "provide must return error")
-		assertErrorMatches(t, err,
-			`function "go.uber.org/dig".TestProvideFailures\S+ \(\S+:\d+\) cannot be provided:`,
-			`cannot provide dig.A from \[0\].A2:`,
-			`already provided by \[0\].A1`,
-		)
+		Buffer *bytes.Buffer `name:"buf"`
+	}
+	invoke := func(params) {}
+
+	c := New()
+	require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }, Name("buf")))
+	s := c.Scope("child")
+	require.NoError(t, s.Invoke(invoke))
+
+	cached, ok := c.invokeParams.Load(reflect.TypeOf(invoke))
+	require.True(t, ok, "params must be cached for the container and its scopes")
+	pl, err := c.invokeParamList(reflect.TypeOf(invoke))
+	require.NoError(t, err)
+	assert.Equal(t, cached, pl)
+	require.NoError(t, c.Invoke(invoke))
+
+	t.Run("failures are not cached", func(t *testing.T) {
+		type bad struct {
+			In
+
+			buffer *bytes.Buffer
+		}
+		invoke := func(bad) {}
+		require.Error(t, c.Invoke(invoke))
+		_, ok := c.invokeParams.Load(reflect.TypeOf(invoke))
+		assert.False(t, ok)
 	})
+}
 
-	t.Run("provide multiple instances with the same name", func(t *testing.T) {
+func TestMissingProvidersCache(t *testing.T) {
+	type A struct{}
+	type params struct {
+		In
+
+		A *A `optional:"true"`
+	}
+
+	t.Run("provided between invokes", func(t *testing.T) {
 		c := New()
-		type A struct{}
-		type ret1 struct {
-			Out
-			*A `name:"foo"`
-		}
-		type ret2 struct {
-			Out
-			*A `name:"foo"`
-		}
-		require.NoError(t, c.Provide(func() ret1 {
-			return ret1{A: &A{}}
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.Nil(t, p.A, "A must be missing")
+		}))
+		assert.Contains(t, c.missingProviders, key{t: reflect.TypeOf(&A{})})
+
+		a := &A{}
+		require.NoError(t, c.Provide(func() *A { return a }))
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.True(t, p.A == a, "A must be provided")
 		}))
-		err := c.Provide(func() ret2 {
-			return ret2{A: &A{}}
-		})
-		require.Error(t, err, "expected error on the second provide")
-		assertErrorMatches(t, err,
-			`function "go.uber.org/dig".TestProvideFailures\S+ \(\S+:\d+\) cannot be provided:`,
-			`cannot provide \*dig.A\[name="foo"\] from \[0\].A:`,
-			`already provided by "go.uber.org/dig".TestProvideFailures\S+`,
-		)
 	})
 
-	t.Run("out with unexported field should error", func(t *testing.T) {
+	t.Run("provided to parent", func(t *testing.T) {
 		c := New()
+		s := c.Scope("child")
+		require.NoError(t, s.Invoke(func(p params) {
+			assert.Nil(t, p.A, "A must be missing")
+		}))
 
-		type A struct{ idx int }
-		type out1 struct {
-			Out
+		a := &A{}
+		require.NoError(t, c.Provide(func() *A { return a }))
+		require.NoError(t, s.Invoke(func(p params) {
+			assert.True(t, p.A == a, "A must be provided")
+		}))
+	})
 
-			A1 A // should be ok
-			a2 A // oops, unexported field. should generate an error
+	t.Run("value groups are not cached", func(t *testing.T) {
+		type in struct {
+			In
+
+			Values []string `group:"values"`
 		}
-		err := c.Provide(func() out1 { return out1{a2: A{77}} })
-		require.Error(t, err)
-		assertErrorMatches(t, err,
-			`function "go.uber.org/dig".TestProvideFailures\S+ \(\S+:\d+\) cannot be provided:`,
-			"bad result 1:",
-			`bad field "a2" of dig.out1:`,
-			`unexported fields not allowed in dig.Out, did you mean to export "a2" \(dig.A\)\?`,
-		)
-	})
 
-	t.Run("providing pointer to out should fail", func(t *testing.T) {
 		c := New()
-		type out struct {
-			Out
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Empty(t, i.Values)
+		}))
+		assert.Empty(t, c.missingProviders)
+
+		require.NoError(t, c.Provide(func() string { return "foo" }, Group("values")))
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Equal(t, []string{"foo"}, i.Values)
+		}))
+	})
+}
+
+func TestUnresolvedKeys(t *testing.T) {
+	type A struct{}
+	type B struct{}
+	type C struct{}
+
+	t.Run("all present", func(t *testing.T) {
+		type params struct {
+			In
+
+			A       *A `optional:"true"`
+			B       *B `name:"b" optional:"true"`
+			Missing UnresolvedKeys
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Provide(func() *B { return &B{} }, Name("b")))
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.NotNil(t, p.A)
+			assert.NotNil(t, p.B)
+			assert.Empty(t, p.Missing)
+		}))
+	})
+
+	t.Run("some missing", func(t *testing.T) {
+		type params struct {
+			In
+
+			A       *A `optional:"true"`
+			B       *B `name:"b" optional:"true"`
+			C       *C `optional:"true"`
+			Missing UnresolvedKeys
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		// C can't be built because its own dependency is missing.
+		require.NoError(t, c.Provide(func(*B) *C { return &C{} }))
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.NotNil(t, p.A)
+			assert.Equal(t, UnresolvedKeys{
+				{Type: reflect.TypeOf(&B{}), Name: "b"},
+				{Type: reflect.TypeOf(&C{})},
+			}, p.Missing)
+		}))
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		type inner struct {
+			In
+
+			B       *B `optional:"true"`
+			Missing UnresolvedKeys
+		}
+		type params struct {
+			In
+
+			A       *A `optional:"true"`
+			C       *C `optional:"true"`
+			Inner   inner
+			Missing UnresolvedKeys
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() *C { return &C{} }))
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.Equal(t, UnresolvedKeys{{Type: reflect.TypeOf(&B{})}}, p.Inner.Missing)
+			assert.Equal(t, UnresolvedKeys{
+				{Type: reflect.TypeOf(&A{})},
+				{Type: reflect.TypeOf(&B{})},
+			}, p.Missing)
+		}))
+	})
+
+	t.Run("not a dependency", func(t *testing.T) {
+		type params struct {
+			In
+
+			A       *A `optional:"true"`
+			Missing UnresolvedKeys
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func(params) *B { return &B{} }))
+
+		g := Graph(c)
+		require.Len(t, g.Ctors, 1)
+		require.Len(t, g.Ctors[0].Params, 1)
+		assert.Equal(t, "*dig.A", g.Ctors[0].Params[0].Type)
+	})
+
+	t.Run("more than one field", func(t *testing.T) {
+		type params struct {
+			In
+
+			Missing1 UnresolvedKeys
+			Missing2 UnresolvedKeys
+		}
+
+		err := New().Invoke(func(params) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(),
+			`bad field "Missing2" of dig.params: cannot have more than one dig.UnresolvedKeys field`)
+	})
+}
+
+func TestUninitializedContainer(t *testing.T) {
+	tests := []struct {
+		desc string
+		give *Container
+		err  string
+	}{
+		{
+			desc: "nil",
+			give: nil,
+			err:  "cannot use nil *dig.Container",
+		},
+		{
+			desc: "zero value",
+			give: &Container{},
+			err:  "cannot use dig.Container that was not created with dig.New",
+		},
+		{
+			desc: "scope of zero value",
+			give: (&Container{}).Scope("child"),
+			err:  "cannot use dig.Container that was not created with dig.New",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			c := tt.give
+
+			err := c.Provide(func() string { return "" })
+			assert.EqualError(t, err, tt.err, "Provide")
+
+			err = c.Invoke(func() {})
+			assert.EqualError(t, err, tt.err, "Invoke")
+
+			err = c.RemoveNamed(new(string), "foo")
+			assert.EqualError(t, err, tt.err, "RemoveNamed")
+
+			err = Visualize(c, ioutil.Discard)
+			assert.EqualError(t, err, tt.err, "Visualize")
+
+			err = c.DumpWiring(ioutil.Discard)
+			assert.EqualError(t, err, tt.err, "DumpWiring")
+
+			err = AsLocator(c).Get(new(string))
+			assert.EqualError(t, err, tt.err, "Locator.Get")
+
+			err = c.Scope("child").Invoke(func() {})
+			assert.EqualError(t, err, tt.err, "Scope")
+
+			assert.Empty(t, Graph(c).Ctors, "Graph")
+			assert.Empty(t, c.KnownKeys(), "KnownKeys")
+			assert.Empty(t, c.NamesFor(new(string)), "NamesFor")
+			assert.Equal(t, Metrics{}, c.Metrics(), "Metrics")
+			_, ok := c.HasCycle()
+			assert.False(t, ok, "HasCycle")
+			assert.NotPanics(t, func() { _ = c.String() }, "String")
+			assert.Panics(t, func() { NewScopePool(c) }, "NewScopePool")
+		})
+	}
+}
+
+func TestProvideFailures(t *testing.T) {
+	t.Run("out returning multiple instances of the same type", func(t *testing.T) {
+		c := New()
+		type A struct{ idx int }
+		type ret struct {
+			Out
+
+			A1 A // sampe type A provided three times
+			A2 A
+			A3 A
+		}
+
+		err := c.Provide(func() ret {
+			return ret{
+				A1: A{idx: 1},
+				A2: A{idx: 2},
+				A3: A{idx: 3},
+			}
+		})
+		require.Error(t, err, "provide must return error")
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestProvideFailures\S+ \(\S+:\d+\) cannot be provided:`,
+			`cannot provide dig.A from \[0\].A2:`,
+			`already provided by \[0\].A1`,
+		)
+	})
+
+	t.Run("provide multiple instances with the same name", func(t *testing.T) {
+		c := New()
+		type A struct{}
+		type ret1 struct {
+			Out
+			*A `name:"foo"`
+		}
+		type ret2 struct {
+			Out
+			*A `name:"foo"`
+		}
+		require.NoError(t, c.Provide(func() ret1 {
+			return ret1{A: &A{}}
+		}))
+		err := c.Provide(func() ret2 {
+			return ret2{A: &A{}}
+		})
+		require.Error(t, err, "expected error on the second provide")
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestProvideFailures\S+ \(\S+:\d+\) cannot be provided:`,
+			`cannot provide \*dig.A\[name="foo"\] from \[0\].A:`,
+			`already provided by "go.uber.org/dig".TestProvideFailures\S+`,
+		)
+	})
+
+	t.Run("out with unexported field should error", func(t *testing.T) {
+		c := New()
+
+		type A struct{ idx int }
+		type out1 struct {
+			Out
+
+			A1 A // should be ok
+			a2 A // oops, unexported field. should generate an error
+		}
+		err := c.Provide(func() out1 { return out1{a2: A{77}} })
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestProvideFailures\S+ \(\S+:\d+\) cannot be provided:`,
+			"bad result 1:",
+			`bad field "a2" of dig.out1:`,
+			`unexported fields not allowed in dig.Out, did you mean to export "a2" \(dig.A\)\?`,
+		)
+	})
+
+	t.Run("providing pointer to out should fail", func(t *testing.T) {
+		c := New()
+		type out struct {
+			Out
 
 			String string
 		}
@@ -1819,6 +3192,72 @@ func TestProvideFailures(t *testing.T) {
 			`dig.out embeds \*dig.Out`,
 		)
 	})
+
+	t.Run("error types", func(t *testing.T) {
+		type in struct {
+			In
+
+			Err error
+		}
+		type groupIn struct {
+			In
+
+			Errs []error `group:"errs"`
+		}
+		type out struct {
+			Out
+
+			Err error `group:"errs"`
+		}
+
+		tests := []struct {
+			desc        string
+			constructor interface{}
+			opts        []ProvideOption
+			wantErr     string
+		}{
+			{
+				desc:        "parameter",
+				constructor: func(error) int { return 0 },
+				wantErr:     "cannot depend on error: errors cannot be consumed from the container",
+			},
+			{
+				desc:        "parameter object field",
+				constructor: func(in) int { return 0 },
+				wantErr:     `bad field "Err" of dig.in: cannot depend on error`,
+			},
+			{
+				desc:        "value group element",
+				constructor: func(groupIn) int { return 0 },
+				wantErr:     `bad field "Errs" of dig.groupIn: cannot depend on error`,
+			},
+			{
+				desc:        "result before the last",
+				constructor: func() (error, int) { return nil, 0 },
+				wantErr:     "bad result 1: cannot return an error here, return it from the constructor instead",
+			},
+			{
+				desc:        "value group result field",
+				constructor: func() out { return out{} },
+				wantErr:     `bad field "Err" of dig.out: cannot return an error here`,
+			},
+			{
+				desc:        "value group target",
+				constructor: func() *bytes.Buffer { return nil },
+				opts:        []ProvideOption{AsGroup("errs", new(error))},
+				wantErr:     "cannot return an error here",
+			},
+		}
+
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.desc, func(t *testing.T) {
+				err := New().Provide(tt.constructor, tt.opts...)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			})
+		}
+	})
 }
 
 func TestInvokeFailures(t *testing.T) {
@@ -1838,6 +3277,16 @@ func TestInvokeFailures(t *testing.T) {
 		assertErrorMatches(t, err, `can't invoke an untyped nil`)
 	})
 
+	t.Run("error parameter", func(t *testing.T) {
+		c := New()
+		err := c.Invoke(func(error) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`bad argument 1:`,
+			`cannot depend on error: errors cannot be consumed from the container`,
+		)
+	})
+
 	t.Run("unmet dependency", func(t *testing.T) {
 		c := New()
 
@@ -1946,8 +3395,8 @@ func TestInvokeFailures(t *testing.T) {
 			`failed to build dig.type3:`,
 			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+\):`,
 			`the following types are not in the container:`,
-			"dig.type1;",
-			`\*dig.type2 \(did you mean dig.type2\?\)`,
+			`\*dig.type2 \(argument 2, did you mean dig.type2\?\); `,
+			`dig.type1 \(argument 1\)`,
 		)
 	})
 
@@ -2103,7 +3552,21 @@ func TestInvokeFailures(t *testing.T) {
 		require.Error(t, err, "provide should return error since cases don't match")
 		assertErrorMatches(t, err,
 			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures\S+ \(\S+:\d+\):`,
-			`type dig.A\[name="camelcase"\] is not in the container, did you mean to Provide it`)
+			`type dig.A\[name="camelcase"\] is not in the container, but it is available as name "CamelCase"$`)
+	})
+
+	t.Run("missing value with doc", func(t *testing.T) {
+		c := New()
+		type A struct{}
+		type param struct {
+			In
+			A `name:"primary" doc:"primary OLTP database"`
+		}
+		err := c.Invoke(func(param) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures\S+ \(\S+:\d+\):`,
+			`type dig.A\[name="primary"\] \("primary OLTP database"\) is not in the container, did you mean to Provide it\?$`)
 	})
 
 	t.Run("in unexported member gets an error", func(t *testing.T) {
@@ -2144,392 +3607,1088 @@ func TestInvokeFailures(t *testing.T) {
 		)
 	})
 
-	t.Run("embedded unexported member gets an error", func(t *testing.T) {
-		c := New()
-		type A struct{}
-		type Embed struct {
-			In
+	t.Run("embedded unexported member gets an error", func(t *testing.T) {
+		c := New()
+		type A struct{}
+		type Embed struct {
+			In
+
+			A1 A // all is good
+			a2 A // oops, unexported type
+		}
+		type in struct {
+			Embed
+		}
+		require.NoError(t, c.Provide(func() A { return A{} }))
+
+		err := c.Invoke(func(i in) { assert.Fail(t, "should never get in here") })
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			"bad argument 1:",
+			`bad field "Embed" of dig.in:`,
+			`bad field "a2" of dig.Embed:`,
+			`unexported fields not allowed in dig.In, did you mean to export "a2" \(dig.A\)\?`,
+		)
+	})
+
+	t.Run("embedded unexported member gets an error", func(t *testing.T) {
+		c := New()
+		type param struct {
+			In
+
+			string // embed an unexported std type
+		}
+		err := c.Invoke(func(p param) { assert.Fail(t, "should never get here") })
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			"bad argument 1:",
+			`bad field "string" of dig.param:`,
+			`unexported fields not allowed in dig.In, did you mean to export "string" \(string\)\?`,
+		)
+	})
+
+	t.Run("pointer in dependency is not supported", func(t *testing.T) {
+		c := New()
+		type in struct {
+			In
+
+			String string
+			Num    int
+		}
+		err := c.Invoke(func(i *in) { assert.Fail(t, "should never get here") })
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			"bad argument 1:",
+			"cannot depend on a pointer to a parameter object, use a value instead:",
+			`\*dig.in is a pointer to a struct that embeds dig.In`,
+		)
+	})
+
+	t.Run("embedding dig.In and dig.Out is not supported", func(t *testing.T) {
+		c := New()
+		type in struct {
+			In
+			Out
+
+			String string
+		}
+
+		err := c.Invoke(func(in) {
+			assert.Fail(t, "should never get here")
+		})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			"bad argument 1:",
+			"cannot depend on dig.in:",
+			"it embeds both dig.In and dig.Out, expected only dig.In",
+		)
+	})
+
+	t.Run("embedding in pointer is not supported", func(t *testing.T) {
+		c := New()
+		type in struct {
+			*In
+
+			String string
+			Num    int
+		}
+		err := c.Invoke(func(i in) { assert.Fail(t, "should never get here") })
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			"bad argument 1:",
+			`cannot build a parameter object by embedding \*dig.In, embed dig.In instead:`,
+			`dig.in embeds \*dig.In`,
+		)
+	})
+
+	t.Run("requesting a value or pointer when other is present", func(t *testing.T) {
+		type A struct{}
+		type outA struct {
+			Out
+
+			A `name:"hello"`
+		}
+		type B struct{}
+
+		cases := []struct {
+			name        string
+			provide     interface{}
+			invoke      interface{}
+			errContains string
+		}{
+			{
+				name:        "value missing, pointer present",
+				provide:     func() *A { return &A{} },
+				invoke:      func(A) {},
+				errContains: `type dig.A is not in the container, did you mean to use \*dig.A\?`,
+			},
+			{
+				name:        "pointer missing, value present",
+				provide:     func() A { return A{} },
+				invoke:      func(*A) {},
+				errContains: `type \*dig.A is not in the container, did you mean to use dig.A?`,
+			},
+			{
+				name:    "named pointer missing, value present",
+				provide: func() outA { return outA{A: A{}} },
+				invoke: func(struct {
+					In
+
+					*A `name:"hello"`
+				}) {
+				},
+				errContains: `type \*dig.A\[name="hello"\] is not in the container, did you mean to use dig.A\[name="hello"\]?`,
+			},
+		}
+
+		for _, tc := range cases {
+			c := New()
+			t.Run(tc.name, func(t *testing.T) {
+				require.NoError(t, c.Provide(tc.provide))
+
+				err := c.Invoke(tc.invoke)
+				require.Error(t, err)
+				assertErrorMatches(t, err,
+					`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+\):`,
+					tc.errContains,
+				)
+			})
+		}
+	})
+
+	t.Run("requesting an interface when an implementation is available", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(bytes.NewReader))
+		err := c.Invoke(func(io.Reader) {
+			t.Fatalf("this function should not be called")
+		})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+\):`,
+			`type io.Reader is not in the container, did you mean to use \*bytes.Reader\?`,
+		)
+	})
+
+	t.Run("requesting an interface when multiple implementations are available", func(t *testing.T) {
+		c := New()
+
+		require.NoError(t, c.Provide(bytes.NewReader))
+		require.NoError(t, c.Provide(bytes.NewBufferString))
+
+		err := c.Invoke(func(io.Reader) {
+			t.Fatalf("this function should not be called")
+		})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+\):`,
+			`type io.Reader is not in the container, did you mean to use one of \*bytes.Buffer, or \*bytes.Reader\?`,
+		)
+	})
+
+	t.Run("requesting multiple interfaces when multiple implementations are available", func(t *testing.T) {
+		c := New()
+
+		require.NoError(t, c.Provide(bytes.NewReader))
+		require.NoError(t, c.Provide(bytes.NewBufferString))
+
+		err := c.Invoke(func(io.Reader, io.Writer) {
+			t.Fatalf("this function should not be called")
+		})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+\):`,
+			`the following types are not in the container:`,
+			`io.Reader \(argument 1, did you mean \*bytes.Buffer, or \*bytes.Reader\?\);`,
+			`io.Writer \(argument 2, did you mean \*bytes.Buffer\?\)`,
+		)
+	})
+
+	t.Run("requesting a type when an interface is available", func(t *testing.T) {
+		c := New()
+
+		require.NoError(t, c.Provide(func() io.Writer { return nil }))
+		err := c.Invoke(func(*bytes.Buffer) {
+			t.Fatalf("this function should not be called")
+		})
+
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+\):`,
+			`type \*bytes.Buffer is not in the container, did you mean to use io.Writer\?`,
+		)
+	})
+
+	t.Run("requesting a type when multiple interfaces are available", func(t *testing.T) {
+		c := New()
+
+		require.NoError(t, c.Provide(func() io.Writer { return nil }))
+		require.NoError(t, c.Provide(func() io.Reader { return nil }))
+
+		err := c.Invoke(func(*bytes.Buffer) {
+			t.Fatalf("this function should not be called")
+		})
+
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+\):`,
+			`type \*bytes.Buffer is not in the container, did you mean to use one of io.Reader, or io.Writer\?`,
+		)
+	})
+
+	t.Run("direct dependency error", func(t *testing.T) {
+		type A struct{}
+
+		c := New()
+
+		require.NoError(t, c.Provide(func() (A, error) {
+			return A{}, errors.New("great sadness")
+		}), "Provide failed")
+
+		err := c.Invoke(func(A) { panic("impossible") })
+
+		require.Error(t, err, "expected Invoke error")
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestInvokeFailures.func\S+ \(\S+\) returned a non-nil error:`,
+			"great sadness",
+		)
+		assert.Equal(t, errors.New("great sadness"), RootCause(err))
+	})
+
+	t.Run("transitive dependency error", func(t *testing.T) {
+		type A struct{}
+		type B struct{}
+
+		c := New()
+
+		require.NoError(t, c.Provide(func() (A, error) {
+			return A{}, errors.New("great sadness")
+		}), "Provide failed")
+
+		require.NoError(t, c.Provide(func(A) (B, error) {
+			return B{}, nil
+		}), "Provide failed")
+
+		err := c.Invoke(func(B) { panic("impossible") })
+
+		require.Error(t, err, "expected Invoke error")
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestInvokeFailures\S+`,
+			"failed to build dig.B",
+			`could not build arguments for function "go.uber.org/dig".TestInvokeFailures\S+`,
+			"failed to build dig.A",
+			`function "go.uber.org/dig".TestInvokeFailures.func\S+ \(\S+\) returned a non-nil error:`,
+			"great sadness",
+		)
+		assert.Equal(t, errors.New("great sadness"), RootCause(err))
+	})
+
+	t.Run("direct parameter object error", func(t *testing.T) {
+		type A struct{}
+
+		c := New()
+
+		require.NoError(t, c.Provide(func() (A, error) {
+			return A{}, errors.New("great sadness")
+		}), "Provide failed")
+
+		type params struct {
+			In
+
+			A A
+		}
+
+		err := c.Invoke(func(params) { panic("impossible") })
+
+		require.Error(t, err, "expected Invoke error")
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestInvokeFailures.func\S+`,
+			"failed to build dig.A:",
+			`function "go.uber.org/dig".TestInvokeFailures.func\S+ \(\S+\) returned a non-nil error:`,
+			"great sadness",
+		)
+		assert.Equal(t, errors.New("great sadness"), RootCause(err))
+	})
+
+	t.Run("transitive parameter object error", func(t *testing.T) {
+		type A struct{}
+		type B struct{}
+
+		c := New()
+
+		require.NoError(t, c.Provide(func() (A, error) {
+			return A{}, errors.New("great sadness")
+		}), "Provide failed")
+
+		type params struct {
+			In
+
+			A A
+		}
+
+		require.NoError(t, c.Provide(func(params) (B, error) {
+			return B{}, nil
+		}), "Provide failed")
+
+		err := c.Invoke(func(B) { panic("impossible") })
+
+		require.Error(t, err, "expected Invoke error")
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestInvokeFailures.func\S+ \(\S+:\d+\):`,
+			"failed to build dig.B:",
+			`could not build arguments for function "go.uber.org/dig".TestInvokeFailures.func\S+`,
+			"failed to build dig.A:",
+			`function "go.uber.org/dig".TestInvokeFailures.func\S+ \(\S+\) returned a non-nil error:`,
+			"great sadness",
+		)
+		assert.Equal(t, errors.New("great sadness"), RootCause(err))
+	})
+
+	t.Run("unmet dependency of a group value", func(t *testing.T) {
+		c := New()
+
+		type A struct{}
+		type B struct{}
+
+		type out struct {
+			Out
+
+			B B `group:"b"`
+		}
+
+		require.NoError(t, c.Provide(func(A) out {
+			require.FailNow(t, "must not be called")
+			return out{}
+		}))
+
+		type in struct {
+			In
+
+			Bs []B `group:"b"`
+		}
+
+		err := c.Invoke(func(in) {
+			require.FailNow(t, "must not be called")
+		})
+		require.Error(t, err, "expected failure")
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+:\d+\):`,
+			`could not build value group dig.B\[group="b"\]:`,
+			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+:\d+\):`,
+			"type dig.A is not in the container, did you mean to Provide it?",
+		)
+	})
+}
+
+func TestNodeAlreadyCalled(t *testing.T) {
+	type type1 struct{}
+	f := func() type1 { return type1{} }
+
+	n, err := newNode(f, nodeOptions{})
+	require.NoError(t, err, "failed to build node")
+	require.False(t, n.called, "node must not have been called")
+
+	c := New()
+	require.NoError(t, n.Call(c), "invoke failed")
+	require.True(t, n.called, "node must be called")
+	require.NoError(t, n.Call(c), "calling again should be okay")
+}
+
+func TestStoreRejectsInvalidValues(t *testing.T) {
+	writerType := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	writers := []struct {
+		desc string
+		give func() containerWriter
+	}{
+		{desc: "container", give: func() containerWriter { return New() }},
+		{desc: "staging", give: func() containerWriter { return newStagingContainerWriter() }},
+	}
+
+	for _, w := range writers {
+		t.Run(w.desc, func(t *testing.T) {
+			cw := w.give()
+
+			err := cw.setValue("", writerType, reflect.Value{})
+			require.Error(t, err)
+			assert.Equal(t, "cannot store an invalid reflect.Value as io.Writer", err.Error())
+
+			err = cw.setValue("foo", writerType, reflect.ValueOf("bar"))
+			require.Error(t, err)
+			assert.Equal(t, `cannot store a value of type string as io.Writer[name="foo"]`, err.Error())
+
+			err = cw.submitGroupedValue("g", writerType, reflect.Value{})
+			require.Error(t, err)
+			assert.Equal(t, `cannot store an invalid reflect.Value as io.Writer[group="g"]`, err.Error())
+
+			err = cw.submitGroupedValue("g", writerType, reflect.ValueOf(42))
+			require.Error(t, err)
+			assert.Equal(t, `cannot store a value of type int as io.Writer[group="g"]`, err.Error())
+
+			assert.NoError(t, cw.setValue("", writerType, reflect.ValueOf(new(bytes.Buffer))))
+			assert.NoError(t, cw.submitGroupedValue("g", writerType, reflect.ValueOf(new(bytes.Buffer))))
+		})
+	}
+
+	t.Run("rejected values are not stored", func(t *testing.T) {
+		c := New()
+		require.Error(t, c.setValue("", writerType, reflect.Value{}))
+		require.Error(t, c.submitGroupedValue("g", writerType, reflect.ValueOf(42)))
+
+		_, ok := c.getValue("", writerType)
+		assert.False(t, ok)
+		assert.Empty(t, c.getValueGroup("g", writerType))
+	})
+
+	t.Run("getValue never reports invalid values", func(t *testing.T) {
+		c := New()
+		c.values[key{t: writerType}] = reflect.Value{}
+
+		_, ok := c.getValue("", writerType)
+		assert.False(t, ok)
+	})
+
+	t.Run("commit reports errors of the writer", func(t *testing.T) {
+		sr := newStagingContainerWriter()
+		require.NoError(t, sr.setValue("", writerType, reflect.ValueOf(new(bytes.Buffer))))
+
+		err := sr.Commit(failingContainerWriter{err: errors.New("great sadness")})
+		require.Error(t, err)
+		assert.Equal(t, "great sadness", err.Error())
+	})
+}
+
+func TestDistinctNamedValues(t *testing.T) {
+	type DB struct{ name string }
+
+	type in struct {
+		In
+
+		Primary *DB
+		Replica *DB `name:"replica"`
+	}
+
+	newContainer := func(t *testing.T, opts ...Option) *Container {
+		c := New(opts...)
+		shared := &DB{name: "shared"}
+		require.NoError(t, c.Provide(func() *DB { return shared }))
+		require.NoError(t, c.Provide(func() *DB { return shared }, Name("replica")))
+		return c
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		c := newContainer(t)
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.True(t, i.Primary == i.Replica)
+		}))
+	})
+
+	t.Run("shared values fail", func(t *testing.T) {
+		c := newContainer(t, DistinctNamedValues())
+		err := c.Invoke(func(in) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestDistinctNamedValues\S+`,
+			`fields "Primary" and "Replica" of dig.in resolve to the same value: `,
+			`\*dig.DB provided by "go.uber.org/dig".TestDistinctNamedValues.func\S+ \(\S+\) and `,
+			`\*dig.DB\[name="replica"\] provided by "go.uber.org/dig".TestDistinctNamedValues.func\S+`)
+	})
+
+	t.Run("same names may share", func(t *testing.T) {
+		c := newContainer(t, DistinctNamedValues())
+
+		type sameNames struct {
+			In
+
+			A *DB `name:"replica"`
+			B *DB `name:"replica"`
+		}
+
+		require.NoError(t, c.Invoke(func(i sameNames) {
+			assert.True(t, i.A == i.B)
+		}))
+	})
+
+	t.Run("distinct values", func(t *testing.T) {
+		c := New(DistinctNamedValues())
+		require.NoError(t, c.Provide(func() *DB { return &DB{name: "primary"} }))
+		require.NoError(t, c.Provide(func() *DB { return &DB{name: "replica"} }, Name("replica")))
+		require.NoError(t, c.Provide(func() string { return "foo" }))
+		require.NoError(t, c.Provide(func() string { return "foo" }, Name("bar")))
+
+		type params struct {
+			In
+
+			Primary *DB
+			Replica *DB    `name:"replica"`
+			Foo     string // values are never compared
+			Bar     string `name:"bar"`
+		}
+
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.Equal(t, "primary", p.Primary.name)
+			assert.Equal(t, "replica", p.Replica.name)
+		}))
+	})
+
+	t.Run("interfaces", func(t *testing.T) {
+		c := New(DistinctNamedValues())
+		var buf bytes.Buffer
+		require.NoError(t, c.Provide(func() io.Writer { return &buf }, Name("a")))
+		require.NoError(t, c.Provide(func() io.Writer { return &buf }, Name("b")))
+
+		type params struct {
+			In
+
+			A io.Writer `name:"a"`
+			B io.Writer `name:"b"`
+		}
+
+		err := c.Invoke(func(params) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `fields "A" and "B" of dig.params resolve to the same value`)
+	})
+
+	t.Run("functions and empty structs", func(t *testing.T) {
+		type handler func() string
+		type marker struct{}
+
+		newHandler := func(s string) handler {
+			return func() string { return s }
+		}
+
+		c := New(DistinctNamedValues())
+		require.NoError(t, c.Provide(func() handler { return newHandler("a") }, Name("a")))
+		require.NoError(t, c.Provide(func() handler { return newHandler("b") }, Name("b")))
+		require.NoError(t, c.Provide(func() *marker { return new(marker) }, Name("a")))
+		require.NoError(t, c.Provide(func() *marker { return new(marker) }, Name("b")))
+
+		type params struct {
+			In
+
+			HandlerA handler `name:"a"`
+			HandlerB handler `name:"b"`
+			MarkerA  *marker `name:"a"`
+			MarkerB  *marker `name:"b"`
+		}
+
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.Equal(t, "a", p.HandlerA())
+			assert.Equal(t, "b", p.HandlerB())
+		}))
+	})
+}
+
+func TestDuplicateParams(t *testing.T) {
+	type Config struct{}
+
+	type inner struct {
+		In
+
+		Config *Config `name:"primary"`
+	}
+
+	type params struct {
+		In
+
+		Inner   inner
+		Config  *Config   `name:"secondary"`
+		Configs []*Config `group:"configs"`
+	}
+
+	tests := []struct {
+		desc string
+		ctor interface{}
+		err  string // empty if there are no duplicates
+	}{
+		{
+			desc: "distinct",
+			ctor: func(*Config, params) string { return "" },
+		},
+		{
+			desc: "positional",
+			ctor: func(*Config, int, *Config) string { return "" },
+			err:  `requests \*dig.Config more than once: as argument 1 and as argument 3`,
+		},
+		{
+			desc: "field",
+			ctor: func(params, struct {
+				In
+
+				Config *Config `name:"secondary"`
+			}) string {
+				return ""
+			},
+			err: `requests \*dig.Config\[name="secondary"\] more than once: ` +
+				`as field Config of argument 1 and as field Config of argument 2`,
+		},
+		{
+			desc: "nested field",
+			ctor: func(params, struct {
+				In
+
+				Other *Config `name:"primary"`
+			}) string {
+				return ""
+			},
+			err: `requests \*dig.Config\[name="primary"\] more than once: ` +
+				`as field Inner.Config of argument 1 and as field Other of argument 2`,
+		},
+		{
+			desc: "group",
+			ctor: func(struct {
+				In
+
+				A []*Config `group:"configs"`
+				B []*Config `group:"configs"`
+			}) string {
+				return ""
+			},
+			err: `requests \*dig.Config\[group="configs"\] more than once: ` +
+				`as field A of argument 1 and as field B of argument 1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Run("error", func(t *testing.T) {
+				err := New().Provide(tt.ctor)
+				if tt.err == "" {
+					require.NoError(t, err)
+					return
+				}
+				require.Error(t, err)
+				assertErrorMatches(t, err, `cannot be provided`, tt.err)
+			})
+
+			t.Run("warning", func(t *testing.T) {
+				var warnings []error
+				c := New(WarnDuplicateParams(func(err error) {
+					warnings = append(warnings, err)
+				})).Scope("child")
+				require.NoError(t, c.Provide(tt.ctor))
+				if tt.err == "" {
+					assert.Empty(t, warnings)
+					return
+				}
+				require.Len(t, warnings, 1)
+				assertErrorMatches(t, warnings[0], tt.err)
+			})
+		})
+	}
+}
+
+func TestParamPathsInErrors(t *testing.T) {
+	type A struct{}
+	type B struct{}
+	type C struct{}
+
+	type inner struct {
+		In
+
+		C *C `name:"c"`
+	}
 
-			A1 A // all is good
-			a2 A // oops, unexported type
-		}
-		type in struct {
-			Embed
-		}
-		require.NoError(t, c.Provide(func() A { return A{} }))
+	type params struct {
+		In
 
-		err := c.Invoke(func(i in) { assert.Fail(t, "should never get in here") })
+		B     *B
+		Inner inner
+	}
+
+	t.Run("missing arguments", func(t *testing.T) {
+		c := New()
+		err := c.Invoke(func(*A, int, *B) {})
 		require.Error(t, err)
 		assertErrorMatches(t, err,
-			"bad argument 1:",
-			`bad field "Embed" of dig.in:`,
-			`bad field "a2" of dig.Embed:`,
-			`unexported fields not allowed in dig.In, did you mean to export "a2" \(dig.A\)\?`,
-		)
+			`missing dependencies for function "go.uber.org/dig".TestParamPathsInErrors\S+ \(\S+\): `,
+			`the following types are not in the container: `,
+			`\*dig.A \(argument 1\); \*dig.B \(argument 3\); int \(argument 2\)$`)
+
+		err = c.Invoke(func(*A) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`argument 1: type \*dig.A is not in the container, did you mean to Provide it\?`)
 	})
 
-	t.Run("embedded unexported member gets an error", func(t *testing.T) {
+	t.Run("missing fields", func(t *testing.T) {
 		c := New()
-		type param struct {
-			In
+		require.NoError(t, c.Provide(func() *B { return &B{} }))
+		err := c.Provide(func(*A, params) string { return "" })
+		require.NoError(t, err)
 
-			string // embed an unexported std type
-		}
-		err := c.Invoke(func(p param) { assert.Fail(t, "should never get here") })
+		err = c.Invoke(func(string) {})
 		require.Error(t, err)
 		assertErrorMatches(t, err,
-			"bad argument 1:",
-			`bad field "string" of dig.param:`,
-			`unexported fields not allowed in dig.In, did you mean to export "string" \(string\)\?`,
-		)
+			`missing dependencies for function "go.uber.org/dig".TestParamPathsInErrors\S+ \(\S+\): `,
+			`the following types are not in the container: `,
+			`\*dig.A \(argument 1\); \*dig.C\[name="c"\] \(field Inner.C of argument 2\)$`)
 	})
 
-	t.Run("pointer in dependency is not supported", func(t *testing.T) {
+	t.Run("failed arguments", func(t *testing.T) {
 		c := New()
-		type in struct {
-			In
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Provide(func() (*B, error) { return nil, errors.New("great sadness") }))
 
-			String string
-			Num    int
-		}
-		err := c.Invoke(func(i *in) { assert.Fail(t, "should never get here") })
+		err := c.Invoke(func(*A, *B) {})
 		require.Error(t, err)
 		assertErrorMatches(t, err,
-			"bad argument 1:",
-			"cannot depend on a pointer to a parameter object, use a value instead:",
-			`\*dig.in is a pointer to a struct that embeds dig.In`,
-		)
+			`could not build arguments for function "go.uber.org/dig".TestParamPathsInErrors\S+ \(\S+\): `,
+			`argument 2: failed to build \*dig.B: `,
+			`function "go.uber.org/dig".TestParamPathsInErrors\S+ \(\S+\) returned a non-nil error: great sadness`)
+		assert.Equal(t, "great sadness", RootCause(err).Error())
 	})
 
-	t.Run("embedding dig.In and dig.Out is not supported", func(t *testing.T) {
+	t.Run("failed fields", func(t *testing.T) {
 		c := New()
-		type in struct {
-			In
-			Out
+		require.NoError(t, c.Provide(func() *B { return &B{} }))
+		require.NoError(t, c.Provide(func() (*C, error) { return nil, errors.New("great sadness") }, Name("c")))
 
-			String string
-		}
-
-		err := c.Invoke(func(in) {
-			assert.Fail(t, "should never get here")
-		})
+		err := c.Invoke(func(p params) {})
 		require.Error(t, err)
 		assertErrorMatches(t, err,
-			"bad argument 1:",
-			"cannot depend on result objects:",
-			"dig.in embeds a dig.Out",
-		)
+			`could not build arguments for function "go.uber.org/dig".TestParamPathsInErrors\S+ \(\S+\): `,
+			`field Inner.C of argument 1: failed to build \*dig.C\[name="c"\]: `,
+			`function "go.uber.org/dig".TestParamPathsInErrors\S+ \(\S+\) returned a non-nil error: great sadness`)
+		assert.Equal(t, "great sadness", RootCause(err).Error())
 	})
+}
 
-	t.Run("embedding in pointer is not supported", func(t *testing.T) {
+func TestResolveByImplementation(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
 		c := New()
-		type in struct {
-			*In
-
-			String string
-			Num    int
-		}
-		err := c.Invoke(func(i in) { assert.Fail(t, "should never get here") })
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }))
+		err := c.Invoke(func(io.Writer) {})
 		require.Error(t, err)
-		assertErrorMatches(t, err,
-			"bad argument 1:",
-			`cannot build a parameter object by embedding \*dig.In, embed dig.In instead:`,
-			`dig.in embeds \*dig.In`,
-		)
+		assert.Contains(t, err.Error(), "type io.Writer is not in the container")
 	})
 
-	t.Run("requesting a value or pointer when other is present", func(t *testing.T) {
-		type A struct{}
-		type outA struct {
-			Out
+	t.Run("single implementation", func(t *testing.T) {
+		c := New(ResolveByImplementation())
+		buf := new(bytes.Buffer)
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return buf }))
 
-			A `name:"hello"`
+		type in struct {
+			In
+
+			W io.Writer
 		}
-		type B struct{}
 
-		cases := []struct {
-			name        string
-			provide     interface{}
-			invoke      interface{}
-			errContains string
-		}{
-			{
-				name:        "value missing, pointer present",
-				provide:     func() *A { return &A{} },
-				invoke:      func(A) {},
-				errContains: `type dig.A is not in the container, did you mean to use \*dig.A\?`,
-			},
-			{
-				name:        "pointer missing, value present",
-				provide:     func() A { return A{} },
-				invoke:      func(*A) {},
-				errContains: `type \*dig.A is not in the container, did you mean to use dig.A?`,
-			},
-			{
-				name:    "named pointer missing, value present",
-				provide: func() outA { return outA{A: A{}} },
-				invoke: func(struct {
-					In
+		require.NoError(t, c.Invoke(func(w io.Writer, i in) {
+			assert.True(t, w == buf, "expected the provided buffer")
+			assert.True(t, i.W == buf, "expected the provided buffer")
+		}))
+	})
 
-					*A `name:"hello"`
-				}) {
-				},
-				errContains: `type \*dig.A\[name="hello"\] is not in the container, did you mean to use dig.A\[name="hello"\]?`,
-			},
-		}
+	t.Run("named implementation", func(t *testing.T) {
+		c := New(ResolveByImplementation())
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }, Name("out")))
+		require.NoError(t, c.Provide(func() *bytes.Reader { return new(bytes.Reader) }))
 
-		for _, tc := range cases {
-			c := New()
-			t.Run(tc.name, func(t *testing.T) {
-				require.NoError(t, c.Provide(tc.provide))
+		type in struct {
+			In
 
-				err := c.Invoke(tc.invoke)
-				require.Error(t, err)
-				assertErrorMatches(t, err,
-					`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+\):`,
-					tc.errContains,
-				)
-			})
+			W io.Writer `name:"out"`
+			R io.Reader `optional:"true"`
 		}
-	})
 
-	t.Run("requesting an interface when an implementation is available", func(t *testing.T) {
-		c := New()
-		require.NoError(t, c.Provide(bytes.NewReader))
-		err := c.Invoke(func(io.Reader) {
-			t.Fatalf("this function should not be called")
-		})
-		require.Error(t, err)
-		assertErrorMatches(t, err,
-			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+\):`,
-			`type io.Reader is not in the container, did you mean to use \*bytes.Reader\?`,
-		)
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.NotNil(t, i.W)
+			assert.NotNil(t, i.R)
+		}))
+		require.Error(t, c.Invoke(func(io.Writer) {}), "unnamed io.Writer must not resolve")
 	})
 
-	t.Run("requesting an interface when multiple implementations are available", func(t *testing.T) {
-		c := New()
+	t.Run("explicit providers win", func(t *testing.T) {
+		c := New(ResolveByImplementation())
+		var stdout bytes.Buffer
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }))
+		require.NoError(t, c.Provide(func() io.Writer { return &stdout }))
 
-		require.NoError(t, c.Provide(bytes.NewReader))
-		require.NoError(t, c.Provide(bytes.NewBufferString))
+		require.NoError(t, c.Invoke(func(w io.Writer) {
+			assert.True(t, w == &stdout, "expected the explicitly provided io.Writer")
+		}))
+	})
 
-		err := c.Invoke(func(io.Reader) {
-			t.Fatalf("this function should not be called")
-		})
+	t.Run("multiple implementations", func(t *testing.T) {
+		c := New(ResolveByImplementation())
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }))
+		require.NoError(t, c.Provide(func() *os.File { return nil }))
+
+		err := c.Invoke(func(io.Writer) {})
 		require.Error(t, err)
 		assertErrorMatches(t, err,
-			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+\):`,
-			`type io.Reader is not in the container, did you mean to use one of \*bytes.Buffer, or \*bytes.Reader\?`,
-		)
+			`could not build arguments for function "go.uber.org/dig".TestResolveByImplementation\S+`,
+			`type io.Writer is implemented by multiple provided types: \*bytes.Buffer, \*os.File; `,
+			`provide io.Writer explicitly to choose one`)
 	})
 
-	t.Run("requesting multiple interfaces when multiple implementations are available", func(t *testing.T) {
-		c := New()
+	t.Run("cache is invalidated by Provide", func(t *testing.T) {
+		c := New(ResolveByImplementation())
+		require.Error(t, c.Invoke(func(io.Writer) {}))
 
-		require.NoError(t, c.Provide(bytes.NewReader))
-		require.NoError(t, c.Provide(bytes.NewBufferString))
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }))
+		require.NoError(t, c.Invoke(func(io.Writer) {}))
 
-		err := c.Invoke(func(io.Reader, io.Writer) {
-			t.Fatalf("this function should not be called")
-		})
-		require.Error(t, err)
-		assertErrorMatches(t, err,
-			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+\):`,
-			`the following types are not in the container:`,
-			`io.Reader \(did you mean \*bytes.Buffer, or \*bytes.Reader\?\);`,
-			`io.Writer \(did you mean \*bytes.Buffer\?\)`,
-		)
+		s := c.Scope("child")
+		require.NoError(t, s.Invoke(func(io.Writer) {}))
+		require.NoError(t, c.Provide(func() *os.File { return nil }))
+		require.Error(t, s.Invoke(func(io.Writer) {}), "expected ambiguity after the parent changed")
 	})
 
-	t.Run("requesting a type when an interface is available", func(t *testing.T) {
-		c := New()
-
-		require.NoError(t, c.Provide(func() io.Writer { return nil }))
-		err := c.Invoke(func(*bytes.Buffer) {
-			t.Fatalf("this function should not be called")
-		})
+	t.Run("cycles through implementations", func(t *testing.T) {
+		type A struct{}
+		c := New(ResolveByImplementation())
+		require.NoError(t, c.Provide(func(io.Writer) *A { return &A{} }))
 
+		err := c.Provide(func(*A) *bytes.Buffer { return nil })
 		require.Error(t, err)
-		assertErrorMatches(t, err,
-			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+\):`,
-			`type \*bytes.Buffer is not in the container, did you mean to use io.Writer\?`,
-		)
+		assert.True(t, IsCycleDetected(err), "expected a cycle, got %v", err)
 	})
 
-	t.Run("requesting a type when multiple interfaces are available", func(t *testing.T) {
-		c := New()
+	t.Run("preflight", func(t *testing.T) {
+		c := New(ResolveByImplementation())
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }))
 
-		require.NoError(t, c.Provide(func() io.Writer { return nil }))
-		require.NoError(t, c.Provide(func() io.Reader { return nil }))
+		var report PreflightReport
+		require.NoError(t, c.Invoke(func(io.Writer) {}, Preflight(&report)))
+		assert.Equal(t, []Key{{Type: reflect.TypeOf(&bytes.Buffer{})}}, report.Constructed)
+		assert.Empty(t, report.Missing)
+	})
+}
 
-		err := c.Invoke(func(*bytes.Buffer) {
-			t.Fatalf("this function should not be called")
-		})
+func TestAllowAssignableTypes(t *testing.T) {
+	type Handler func()
+	type Handlers []Handler
+	type Chain []Handler
+	type Port int
 
+	t.Run("disabled by default", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() Handlers { return Handlers{} }))
+		err := c.Invoke(func([]Handler) {})
 		require.Error(t, err)
-		assertErrorMatches(t, err,
-			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+\):`,
-			`type \*bytes.Buffer is not in the container, did you mean to use one of io.Reader, or io.Writer\?`,
-		)
+		assert.Contains(t, err.Error(), "type []dig.Handler is not in the container")
 	})
 
-	t.Run("direct dependency error", func(t *testing.T) {
-		type A struct{}
+	t.Run("defined type to unnamed type", func(t *testing.T) {
+		c := New(AllowAssignableTypes())
+		require.NoError(t, c.Provide(func() Handlers { return Handlers{func() {}} }))
 
-		c := New()
+		require.NoError(t, c.Invoke(func(hs []Handler) {
+			assert.Len(t, hs, 1)
+		}))
+	})
 
-		require.NoError(t, c.Provide(func() (A, error) {
-			return A{}, errors.New("great sadness")
-		}), "Provide failed")
+	t.Run("unnamed type to defined type", func(t *testing.T) {
+		c := New(AllowAssignableTypes())
+		require.NoError(t, c.Provide(func() []Handler { return []Handler{func() {}} }, Name("chain")))
 
-		err := c.Invoke(func(A) { panic("impossible") })
+		type in struct {
+			In
 
-		require.Error(t, err, "expected Invoke error")
-		assertErrorMatches(t, err,
-			`function "go.uber.org/dig".TestInvokeFailures.func\S+ \(\S+\) returned a non-nil error:`,
-			"great sadness",
-		)
-		assert.Equal(t, errors.New("great sadness"), RootCause(err))
+			Handlers Handlers `name:"chain"`
+		}
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Len(t, i.Handlers, 1)
+		}))
 	})
 
-	t.Run("transitive dependency error", func(t *testing.T) {
-		type A struct{}
-		type B struct{}
+	t.Run("convertible types are not assignable", func(t *testing.T) {
+		c := New(AllowAssignableTypes())
+		require.NoError(t, c.Provide(func() Port { return 8080 }))
+		require.Error(t, c.Invoke(func(int) {}))
+	})
 
-		c := New()
+	t.Run("interfaces require ResolveByImplementation", func(t *testing.T) {
+		c := New(AllowAssignableTypes())
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }))
+		require.Error(t, c.Invoke(func(io.Writer) {}))
+	})
 
-		require.NoError(t, c.Provide(func() (A, error) {
-			return A{}, errors.New("great sadness")
-		}), "Provide failed")
+	t.Run("exact matches win", func(t *testing.T) {
+		c := New(AllowAssignableTypes())
+		require.NoError(t, c.Provide(func() Handlers { return Handlers{func() {}} }))
+		require.NoError(t, c.Provide(func() []Handler { return nil }))
 
-		require.NoError(t, c.Provide(func(A) (B, error) {
-			return B{}, nil
-		}), "Provide failed")
+		require.NoError(t, c.Invoke(func(hs []Handler) {
+			assert.Nil(t, hs)
+		}))
+	})
 
-		err := c.Invoke(func(B) { panic("impossible") })
+	t.Run("multiple assignable types", func(t *testing.T) {
+		c := New(AllowAssignableTypes())
+		require.NoError(t, c.Provide(func() Handlers { return nil }))
+		require.NoError(t, c.Provide(func() Chain { return nil }))
 
-		require.Error(t, err, "expected Invoke error")
+		err := c.Invoke(func([]Handler) {})
+		require.Error(t, err)
 		assertErrorMatches(t, err,
-			`could not build arguments for function "go.uber.org/dig".TestInvokeFailures\S+`,
-			"failed to build dig.B",
-			`could not build arguments for function "go.uber.org/dig".TestInvokeFailures\S+`,
-			"failed to build dig.A",
-			`function "go.uber.org/dig".TestInvokeFailures.func\S+ \(\S+\) returned a non-nil error:`,
-			"great sadness",
-		)
-		assert.Equal(t, errors.New("great sadness"), RootCause(err))
+			`could not build arguments for function "go.uber.org/dig".TestAllowAssignableTypes\S+`,
+			`type \[\]dig.Handler is assignable from multiple provided types: dig.(Handlers|Chain), dig.(Handlers|Chain); `,
+			`provide \[\]dig.Handler explicitly to choose one`)
 	})
 
-	t.Run("direct parameter object error", func(t *testing.T) {
-		type A struct{}
+	t.Run("constructor failures name the coercion", func(t *testing.T) {
+		c := New(AllowAssignableTypes())
+		require.NoError(t, c.Provide(func() (Handlers, error) { return nil, errors.New("great sadness") }))
 
-		c := New()
+		err := c.Invoke(func([]Handler) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestAllowAssignableTypes\S+`,
+			`failed to build dig.Handlers as \[\]dig.Handler:`,
+			`great sadness`)
+	})
 
-		require.NoError(t, c.Provide(func() (A, error) {
-			return A{}, errors.New("great sadness")
-		}), "Provide failed")
+	t.Run("inherited by scopes", func(t *testing.T) {
+		c := New(AllowAssignableTypes())
+		require.NoError(t, c.Provide(func() Handlers { return Handlers{} }))
+		require.NoError(t, c.Scope("child").Invoke(func([]Handler) {}))
+	})
+
+	t.Run("preflight", func(t *testing.T) {
+		c := New(AllowAssignableTypes())
+		require.NoError(t, c.Provide(func() Handlers { return Handlers{} }))
+
+		var report PreflightReport
+		require.NoError(t, c.Invoke(func([]Handler) {}, Preflight(&report)))
+		assert.Equal(t, []Key{{Type: reflect.TypeOf(Handlers{})}}, report.Constructed)
+		assert.Equal(t, map[Key]Key{
+			{Type: reflect.TypeOf([]Handler{})}: {Type: reflect.TypeOf(Handlers{})},
+		}, report.Assigned)
+	})
+}
+
+func TestNamePrefix(t *testing.T) {
+	type DB struct{ name string }
+
+	type dbOutputs struct {
+		Out
 
-		type params struct {
-			In
+		Primary *DB `name:"primary"`
+	}
 
-			A A
-		}
+	type dbInputs struct {
+		In
 
-		err := c.Invoke(func(params) { panic("impossible") })
+		Primary *DB `name:"primary"`
+	}
 
-		require.Error(t, err, "expected Invoke error")
-		assertErrorMatches(t, err,
-			`could not build arguments for function "go.uber.org/dig".TestInvokeFailures.func\S+`,
-			"failed to build dig.A:",
-			`function "go.uber.org/dig".TestInvokeFailures.func\S+ \(\S+\) returned a non-nil error:`,
-			"great sadness",
-		)
-		assert.Equal(t, errors.New("great sadness"), RootCause(err))
-	})
+	type appOutputs struct {
+		Out
 
-	t.Run("transitive parameter object error", func(t *testing.T) {
-		type A struct{}
-		type B struct{}
+		Storage dbOutputs `nameprefix:"storage."`
+		Cache   dbOutputs `nameprefix:"cache."`
+	}
 
+	t.Run("produce and consume", func(t *testing.T) {
 		c := New()
+		require.NoError(t, c.Provide(func() appOutputs {
+			return appOutputs{
+				Storage: dbOutputs{Primary: &DB{name: "storage"}},
+				Cache:   dbOutputs{Primary: &DB{name: "cache"}},
+			}
+		}))
 
-		require.NoError(t, c.Provide(func() (A, error) {
-			return A{}, errors.New("great sadness")
-		}), "Provide failed")
-
-		type params struct {
+		type in struct {
 			In
 
-			A A
+			Storage *DB      `name:"storage.primary"`
+			Cache   dbInputs `nameprefix:"cache."`
 		}
 
-		require.NoError(t, c.Provide(func(params) (B, error) {
-			return B{}, nil
-		}), "Provide failed")
-
-		err := c.Invoke(func(B) { panic("impossible") })
-
-		require.Error(t, err, "expected Invoke error")
-		assertErrorMatches(t, err,
-			`could not build arguments for function "go.uber.org/dig".TestInvokeFailures.func\S+ \(\S+:\d+\):`,
-			"failed to build dig.B:",
-			`could not build arguments for function "go.uber.org/dig".TestInvokeFailures.func\S+`,
-			"failed to build dig.A:",
-			`function "go.uber.org/dig".TestInvokeFailures.func\S+ \(\S+\) returned a non-nil error:`,
-			"great sadness",
-		)
-		assert.Equal(t, errors.New("great sadness"), RootCause(err))
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Equal(t, "storage", i.Storage.name)
+			assert.Equal(t, "cache", i.Cache.Primary.name)
+		}))
 	})
 
-	t.Run("unmet dependency of a group value", func(t *testing.T) {
+	t.Run("conflicts after prefixing", func(t *testing.T) {
 		c := New()
+		require.NoError(t, c.Provide(func() *DB { return nil }, Name("storage.primary")))
 
-		type A struct{}
-		type B struct{}
+		err := c.Provide(func() appOutputs { return appOutputs{} })
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestNamePrefix\S+ \(\S+\) cannot be provided:`,
+			`cannot provide \*dig.DB\[name="storage.primary"\] from \[0\].Storage.Primary:`,
+			`already provided by "go.uber.org/dig".TestNamePrefix\S+`)
+	})
 
+	t.Run("conflicts within a result", func(t *testing.T) {
 		type out struct {
 			Out
 
-			B B `group:"b"`
+			Primary *DB       `name:"storage.primary"`
+			Storage dbOutputs `nameprefix:"storage."`
 		}
 
-		require.NoError(t, c.Provide(func(A) out {
-			require.FailNow(t, "must not be called")
-			return out{}
+		c := New()
+		err := c.Provide(func() out { return out{} })
+		require.Error(t, err)
+		assert.Contains(t, err.Error(),
+			`cannot provide *dig.DB[name="storage.primary"] from [0].Storage.Primary: already provided by [0].Primary`)
+	})
+}
+
+func TestAmbiguousProviders(t *testing.T) {
+	type A struct{ v int }
+
+	// newAmbiguous returns a scope that knows two providers for *A: its own
+	// and one that was added to its parent after the scope provided *A.
+	newAmbiguous := func(t *testing.T, opts ...Option) *Container {
+		c := New(opts...)
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func() *A { return &A{v: 1} }))
+		require.NoError(t, c.Provide(func() *A { return &A{v: 2} }))
+		return s
+	}
+
+	t.Run("fails by default", func(t *testing.T) {
+		s := newAmbiguous(t)
+		err := s.Invoke(func(*A) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestAmbiguousProviders\S+`,
+			`failed to build \*dig.A:`,
+			`type \*dig.A is provided by multiple constructors: `,
+			`"go.uber.org/dig".TestAmbiguousProviders.func1.1 \(\S+\); `,
+			`"go.uber.org/dig".TestAmbiguousProviders.func1.2 \(\S+\); `,
+			`use dig.Name to provide them under different names`)
+
+		ambiguous, ok := RootCause(err).(AmbiguousProviderError)
+		require.True(t, ok, "expected an AmbiguousProviderError, got %T", RootCause(err))
+		assert.Equal(t, Key{Type: reflect.TypeOf(&A{})}, ambiguous.Key)
+		assert.Len(t, ambiguous.Candidates, 2)
+	})
+
+	t.Run("AllowLastProviderWins", func(t *testing.T) {
+		s := newAmbiguous(t, AllowLastProviderWins())
+		require.NoError(t, s.Invoke(func(a *A) {
+			assert.NotNil(t, a)
 		}))
+	})
+
+	t.Run("groups and singles of the same type", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{v: 1} }))
+		require.NoError(t, c.Provide(func() *A { return &A{v: 2} }, Group("as")))
+		require.NoError(t, c.Provide(func() *A { return &A{v: 3} }, Group("as")))
 
 		type in struct {
 			In
 
-			Bs []B `group:"b"`
+			A  *A
+			As []*A `group:"as"`
 		}
 
-		err := c.Invoke(func(in) {
-			require.FailNow(t, "must not be called")
-		})
-		require.Error(t, err, "expected failure")
-		assertErrorMatches(t, err,
-			`could not build arguments for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+:\d+\):`,
-			`could not build value group dig.B\[group="b"\]:`,
-			`missing dependencies for function "go.uber.org/dig".TestInvokeFailures.\S+ \(\S+:\d+\):`,
-			"type dig.A is not in the container, did you mean to Provide it?",
-		)
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Equal(t, 1, i.A.v)
+			assert.Len(t, i.As, 2)
+		}))
 	})
 }
 
-func TestNodeAlreadyCalled(t *testing.T) {
-	type type1 struct{}
-	f := func() type1 { return type1{} }
-
-	n, err := newNode(f, nodeOptions{})
-	require.NoError(t, err, "failed to build node")
-	require.False(t, n.called, "node must not have been called")
-
-	c := New()
-	require.NoError(t, n.Call(c), "invoke failed")
-	require.True(t, n.called, "node must be called")
-	require.NoError(t, n.Call(c), "calling again should be okay")
-}
-
 func TestFailingFunctionDoesNotCreateInvalidState(t *testing.T) {
 	type type1 struct{}
 
@@ -2548,9 +4707,24 @@ func TestFailingFunctionDoesNotCreateInvalidState(t *testing.T) {
 }
 
 func assertCtorEqual(t *testing.T, expected *dot.Ctor, ctor *dot.Ctor) {
-	assert.Equal(t, expected.Params, ctor.Params)
-	assert.Equal(t, expected.Results, ctor.Results)
 	assert.NotZero(t, ctor.Line)
+
+	// Paths and IDs are verified separately in TestDotGraph.
+	var params []*dot.Param
+	for _, p := range ctor.Params {
+		p := *p
+		p.Path = ""
+		params = append(params, &p)
+	}
+	assert.Equal(t, expected.Params, params)
+
+	results := make([]*dot.Result, len(ctor.Results))
+	for i, r := range ctor.Results {
+		r := *r
+		r.Path, r.ID = "", ""
+		results[i] = &r
+	}
+	assert.Equal(t, expected.Results, results)
 }
 
 func assertCtorsEqual(t *testing.T, expected []*dot.Ctor, ctors []*dot.Ctor) {
@@ -2604,6 +4778,95 @@ func TestDotGraph(t *testing.T) {
 
 	t.Parallel()
 
+	t.Run("result IDs", func(t *testing.T) {
+		type out struct {
+			Out
+
+			A t1
+			B t2 `group:"g"`
+		}
+
+		type inner struct {
+			Out
+
+			A t1 `name:"n"`
+			B t2 `group:"g"`
+		}
+
+		type nested struct {
+			Out
+
+			Inner inner
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() (t3, out) { return t3{}, out{} }))
+		require.NoError(t, c.Provide(func() nested { return nested{} }))
+		require.NoError(t, c.Provide(func(t1) t4 { return t4{} }, Group("x"), Group("y")))
+
+		dg := c.createGraph()
+		require.Len(t, dg.Ctors, 3)
+
+		var ids [][]string
+		for _, ctor := range dg.Ctors {
+			var ctorIDs []string
+			for _, r := range ctor.Results {
+				ctorIDs = append(ctorIDs, r.ID)
+			}
+			ids = append(ids, ctorIDs)
+		}
+		assert.Equal(t, [][]string{
+			{"ctor0/0", "ctor0/1/A", "ctor0/1/B@g"},
+			{"ctor1/0/Inner.A", "ctor1/0/Inner.B@g"},
+			{"ctor2/0@x", "ctor2/0@y"},
+		}, ids)
+
+		assert.Equal(t, []string{"ctor0/1/A"}, dg.ParamIDs(dg.Ctors[2].Params[0]))
+		assert.Equal(t, []string{"missing:dig.t2[name=foo]"},
+			dg.ParamIDs(tparam(type2, "foo", "", false)),
+			"missing values must have an ID")
+	})
+
+	t.Run("param paths", func(t *testing.T) {
+		type Inner struct {
+			In
+
+			B t2 `name:"b"`
+		}
+
+		type Params struct {
+			In
+
+			A     t1
+			Inner Inner
+			G     []t3 `group:"g"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func(t4, Params) int { return 0 }))
+		require.NoError(t, c.Provide(func(struct {
+			In
+
+			A t1
+		}) string {
+			return ""
+		}))
+
+		dg := c.createGraph()
+		require.Len(t, dg.Ctors, 2)
+
+		var paths []string
+		for _, p := range dg.Ctors[0].Params {
+			paths = append(paths, p.Path)
+		}
+		assert.Equal(t, []string{"0", "Params.A", "Params.Inner.B"}, paths)
+		assert.Equal(t, []string{"Params.G"}, dg.Ctors[0].GroupParamPaths)
+
+		require.Len(t, dg.Ctors[1].Params, 1)
+		assert.Equal(t, "0/A", dg.Ctors[1].Params[0].Path,
+			"anonymous parameter objects are identified by position")
+	})
+
 	t.Run("create graph with one constructor", func(t *testing.T) {
 		expected := []*dot.Ctor{
 			{
@@ -3035,6 +5298,148 @@ func TestVisualize(t *testing.T) {
 
 		VerifyVisualization(t, "missingDep", c, VisualizeError(err))
 	})
+
+	t.Run("scope", func(t *testing.T) {
+		c := New()
+		c.Provide(func() t1 { return t1{} })
+		c.Provide(func(t1) t2 { return t2{} })
+
+		s := c.Scope("child")
+		s.Provide(func(t2) t3 { return t3{} })
+
+		VerifyVisualization(t, "scope", s)
+		VerifyVisualization(t, "scopeCollapsed", s, CollapseParentScopes())
+	})
+
+	t.Run("scope fails with an error", func(t *testing.T) {
+		c := New()
+		c.Provide(func() (t1, error) { return t1{}, errors.New("great sadness") })
+		c.Provide(func(t1) t2 { return t2{} })
+
+		s := c.Scope("child")
+		s.Provide(func(t2) t3 { return t3{} })
+		err := s.Invoke(func(t3) {})
+		require.Error(t, err)
+
+		VerifyVisualization(t, "scopeError", s, VisualizeError(err))
+		VerifyVisualization(t, "scopeErrorCollapsed", s, VisualizeError(err), CollapseParentScopes())
+	})
+
+	t.Run("param labels", func(t *testing.T) {
+		type DBParams struct {
+			In
+
+			ReadDB  t1   `name:"read"`
+			WriteDB t1   `name:"write"`
+			Workers []t2 `group:"workers"`
+		}
+
+		c := New()
+		c.Provide(func() t1 { return t1{} }, Name("read"))
+		c.Provide(func() t1 { return t1{} }, Name("write"))
+		c.Provide(func() t2 { return t2{} }, Group("workers"))
+		c.Provide(func(DBParams, t2) t3 { return t3{} })
+
+		VerifyVisualization(t, "paramLabels", c, LabelParamEdges())
+	})
+
+	t.Run("indexed group", func(t *testing.T) {
+		type out1 struct {
+			Out
+
+			T t1 `group:"stages" index:"0"`
+		}
+		type out2 struct {
+			Out
+
+			T t1 `group:"stages" index:"1"`
+		}
+		type in struct {
+			In
+
+			Stages []t1 `group:"stages"`
+		}
+
+		c := New()
+		c.Provide(func() out1 { return out1{} })
+		c.Provide(func() out2 { return out2{} })
+		c.Provide(func(in) t2 { return t2{} })
+
+		VerifyVisualization(t, "indexedGroup", c)
+	})
+
+	t.Run("doc tooltips", func(t *testing.T) {
+		type out struct {
+			Out
+
+			T t1 `name:"primary" doc:"primary OLTP database"`
+		}
+		type in struct {
+			In
+
+			T t1 `name:"primary" doc:"where orders are stored"`
+		}
+
+		c := New()
+		c.Provide(func() out { return out{} }, Doc("opens the databases"))
+		c.Provide(func(in) t2 { return t2{} })
+
+		VerifyVisualization(t, "docTooltips", c)
+	})
+
+	t.Run("bridges", func(t *testing.T) {
+		type out struct {
+			Out
+
+			T t1 `group:"g"`
+		}
+
+		c := New()
+		c.Provide(func() out { return out{} })
+		c.Provide(func() t2 { return t2{} }, Name("second"))
+		c.BridgeGroupToNames("g", new(t1), func(interface{}) string { return "first" })
+		c.BridgeNamesToGroup([]string{"second"}, new(t2), "g2")
+		c.Invoke(func(struct {
+			In
+
+			T1 t1   `name:"first"`
+			T2 []t2 `group:"g2"`
+		}) {
+		})
+
+		VerifyVisualization(t, "bridges", c)
+	})
+
+	t.Run("ambient values", func(t *testing.T) {
+		c := New(WithAmbientValues())
+		c.Provide(func(ContainerName) t1 { return t1{} })
+		c.Invoke(func(t1) {})
+
+		VerifyVisualization(t, "ambient", c)
+	})
+
+	t.Run("disabled constructor", func(t *testing.T) {
+		c := New()
+		c.Provide(func() t1 { return t1{} }, When(func() bool { return false }))
+		c.Provide(func() t1 { return t1{} })
+		c.Provide(func(t1) t2 { return t2{} })
+
+		VerifyVisualization(t, "disabled", c)
+	})
+
+	t.Run("environment names", func(t *testing.T) {
+		type in struct {
+			In
+
+			A t1 `name:"local" env:"prod=primary;staging=replica"`
+		}
+
+		c := New(EnvironmentName("prod"))
+		c.Provide(func() t1 { return t1{} }, Name("primary"))
+		c.Provide(func(in) t2 { return t2{} })
+
+		VerifyVisualization(t, "environment", c)
+	})
 }
 
 type visualizableErr struct{}
@@ -3084,6 +5489,59 @@ func TestCanVisualizeError(t *testing.T) {
 	}
 }
 
+func BenchmarkInvoke(b *testing.B) {
+	type A struct{}
+	type B struct{}
+	type params struct {
+		In
+
+		A *A
+		B *B     `name:"b"`
+		C string `optional:"true"`
+	}
+
+	c := New()
+	require.NoError(b, c.Provide(func() *A { return &A{} }))
+	require.NoError(b, c.Provide(func() *B { return &B{} }, Name("b")))
+	invoke := func(params, *A) {}
+	require.NoError(b, c.Invoke(invoke), "values must be built before the benchmark")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := c.Invoke(invoke); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDeferredCycleDetection(b *testing.B) {
+	type in struct {
+		In
+
+		Values []int `group:"values"`
+	}
+	type out struct {
+		Out
+
+		Value int `group:"values"`
+	}
+
+	for i := 0; i < b.N; i++ {
+		c := New(DeferAcyclicVerification())
+		c.Provide(func() string { return "" })
+		for j := 0; j < 10000; j++ {
+			c.Provide(func(string) out { return out{} })
+		}
+		c.Provide(func(in) *bytes.Buffer { return nil })
+		c.Provide(func(in, *bytes.Buffer) *bytes.Reader { return nil })
+
+		if _, ok := c.HasCycle(); ok {
+			b.Fatal("unexpected cycle")
+		}
+	}
+}
+
 func BenchmarkProvideCycleDetection(b *testing.B) {
 	// func TestBenchmarkProvideCycleDetection(b *testing.T) {
 	type A struct{}
@@ -0,0 +1,331 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package digdebug serves a read-only view of a dig.Container over HTTP,
+// for use on internal debug ports.
+//
+//   mux.Handle("/debug/dig/", http.StripPrefix("/debug/dig", digdebug.Handler(c)))
+//
+// The handler serves an HTML page that lists the constructors of the
+// container, a searchable index of the values and value groups they provide,
+// the dependency graph, and the operational metrics of the container. The
+// same data is available as JSON from the following endpoints:
+//
+//   /providers  constructors, as a list of dig.GraphCtor
+//   /graph      dependency graph, as a dig.GraphView, or in DOT format with
+//               ?format=dot
+//   /keys       values and value groups, as a list of Key
+//   /timings    operational metrics, as a dig.Metrics
+//
+// Serving a request never calls constructors or invoked functions: the view
+// is built with dig.Graph and Container.Metrics, which may be used while
+// Invoke runs on another goroutine.
+package digdebug
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+
+	"go.uber.org/dig"
+)
+
+// An Option customizes Handler.
+type Option interface {
+	applyOption(*options)
+}
+
+type options struct {
+	// Returns the link to the source of a constructor, or "" for none.
+	SourceURL func(file string, line int) string
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) applyOption(opts *options) { f(opts) }
+
+// SourceURL is an Option that links every constructor on the HTML page to
+// the URL returned by f for the file and line at which it was defined, such
+// as a page of a code browser.
+//
+//   digdebug.SourceURL(func(file string, line int) string {
+//     return fmt.Sprintf("https://code.example.com/%s#L%d", file, line)
+//   })
+//
+// Constructors are not linked if f returns an empty string.
+func SourceURL(f func(file string, line int) string) Option {
+	return optionFunc(func(opts *options) {
+		opts.SourceURL = f
+	})
+}
+
+// Key is a value or a value group in the index served at /keys.
+type Key struct {
+	// Type of the value as printed by reflect.Type.String. For value
+	// groups, this is the type of the values in the group.
+	Type string
+
+	// Only one of Name or Group is set.
+	Name  string `json:",omitempty"`
+	Group string `json:",omitempty"`
+
+	// Constructors that provide the value or contribute to the value group,
+	// formatted as their package path and name.
+	Providers []string
+
+	// Number of times the value was constructed by the container and its
+	// scopes, or zero if it wasn't built yet. For value groups, this is the
+	// number of constructor calls that contributed to the group. See
+	// Container.ConstructionCounts.
+	Constructions int
+}
+
+// String formats the key like dig.Key.String.
+func (k Key) String() string {
+	switch {
+	case k.Name != "":
+		return fmt.Sprintf("%v[name=%q]", k.Type, k.Name)
+	case k.Group != "":
+		return fmt.Sprintf("%v[group=%q]", k.Type, k.Group)
+	default:
+		return k.Type
+	}
+}
+
+// Handler returns an http.Handler that serves a read-only view of the given
+// container. See the package documentation for the endpoints it serves.
+//
+// The handler only accepts GET and HEAD requests.
+func Handler(c *dig.Container, opts ...Option) http.Handler {
+	var options options
+	for _, o := range opts {
+		o.applyOption(&options)
+	}
+
+	h := handler{c: c, opts: options}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.serveIndex)
+	mux.HandleFunc("/providers", h.serveProviders)
+	mux.HandleFunc("/graph", h.serveGraph)
+	mux.HandleFunc("/keys", h.serveKeys)
+	mux.HandleFunc("/timings", h.serveTimings)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+type handler struct {
+	c    *dig.Container
+	opts options
+}
+
+func (h handler) serveProviders(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, dig.Graph(h.c).Ctors)
+}
+
+func (h handler) serveGraph(w http.ResponseWriter, r *http.Request) {
+	gv := dig.Graph(h.c)
+	switch f := r.URL.Query().Get("format"); f {
+	case "", "json":
+		writeJSON(w, gv)
+	case "dot":
+		var buf bytes.Buffer
+		if err := dig.VisualizeGraph(gv, &buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		w.Write(buf.Bytes())
+	default:
+		http.Error(w, fmt.Sprintf("unsupported graph format %q: must be json or dot", f), http.StatusBadRequest)
+	}
+}
+
+func (h handler) serveKeys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, indexKeys(dig.Graph(h.c), h.c.Metrics()))
+}
+
+func (h handler) serveTimings(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.c.Metrics())
+}
+
+func (h handler) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	gv := dig.Graph(h.c)
+	metrics := h.c.Metrics()
+	var dot bytes.Buffer
+	if err := dig.VisualizeGraph(gv, &dot); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page := indexPage{
+		Metrics: metrics,
+		Keys:    indexKeys(gv, metrics),
+		Graph:   dot.String(),
+	}
+	for _, ctor := range gv.Ctors {
+		p := indexProvider{GraphCtor: ctor}
+		if h.opts.SourceURL != nil {
+			p.URL = h.opts.SourceURL(ctor.File, ctor.Line)
+		}
+		page.Providers = append(page.Providers, p)
+	}
+
+	var buf bytes.Buffer
+	if err := _indexTmpl.Execute(&buf, page); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// indexKeys lists the values and value groups produced by the constructors
+// in the given graph, sorted by type, then by name and group.
+func indexKeys(gv *dig.GraphView, metrics dig.Metrics) []Key {
+	type keyID struct{ t, name, group string }
+	byID := make(map[keyID]*Key)
+	var keys []*Key
+	for _, ctor := range gv.Ctors {
+		provider := ctor.Package + "." + ctor.Name
+		for _, r := range ctor.Results {
+			id := keyID{r.Type, r.Name, r.Group}
+			k, ok := byID[id]
+			if !ok {
+				k = &Key{Type: r.Type, Name: r.Name, Group: r.Group}
+				byID[id] = k
+				keys = append(keys, k)
+			}
+			// Constructors may submit several values to the same group.
+			if n := len(k.Providers); n == 0 || k.Providers[n-1] != provider {
+				k.Providers = append(k.Providers, provider)
+			}
+		}
+	}
+
+	index := make([]Key, len(keys))
+	for i, k := range keys {
+		k.Constructions = metrics.Constructions[k.String()]
+		index[i] = *k
+	}
+	sort.Slice(index, func(i, j int) bool {
+		ki, kj := index[i], index[j]
+		if ki.Type != kj.Type {
+			return ki.Type < kj.Type
+		}
+		if ki.Name != kj.Name {
+			return ki.Name < kj.Name
+		}
+		return ki.Group < kj.Group
+	})
+	return index
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(append(b, '\n'))
+}
+
+type indexPage struct {
+	Providers []indexProvider
+	Keys      []Key
+	Metrics   dig.Metrics
+	Graph     string
+}
+
+type indexProvider struct {
+	dig.GraphCtor
+
+	// Link to the source of the constructor, if any. See SourceURL.
+	URL string
+}
+
+var _indexTmpl = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>dig</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; vertical-align: top; }
+code, pre { font-size: 0.9em; }
+.unbuilt { color: gray; }
+</style>
+</head>
+<body>
+<h1>dig</h1>
+<p>JSON: <a href="providers">providers</a>, <a href="graph">graph</a> (<a href="graph?format=dot">DOT</a>), <a href="keys">keys</a>, <a href="timings">timings</a></p>
+
+<h2>Timings</h2>
+<table>
+<tr><th>Providers</th><td>{{.Metrics.Providers}}</td></tr>
+<tr><th>Values constructed</th><td>{{.Metrics.ValuesConstructed}}</td></tr>
+<tr><th>Constructor failures</th><td>{{.Metrics.ConstructorFailures}}</td></tr>
+<tr><th>Time in constructors</th><td>{{.Metrics.ConstructorTime}}</td></tr>
+<tr><th>Invokes</th><td>{{.Metrics.Invokes}} ({{.Metrics.InvokeFailures}} failed)</td></tr>
+</table>
+
+<h2>Keys</h2>
+<p><input id="search" type="search" placeholder="Filter keys" oninput="filterKeys(this.value)"></p>
+<table id="keys">
+<tr><th>Key</th><th>Providers</th><th>Constructions</th></tr>
+{{range .Keys}}<tr{{if not .Constructions}} class="unbuilt"{{end}}><td><code>{{.}}</code></td><td>{{range .Providers}}<code>{{.}}</code><br>{{end}}</td><td>{{.Constructions}}</td></tr>
+{{end}}</table>
+
+<h2>Providers</h2>
+<table>
+<tr><th>Constructor</th><th>Location</th><th>Scope</th><th>Results</th></tr>
+{{range .Providers}}<tr{{if .Disabled}} class="unbuilt"{{end}}><td><code>{{.Package}}.{{.Name}}</code></td><td>{{if .URL}}<a href="{{.URL}}">{{.File}}:{{.Line}}</a>{{else}}{{.File}}:{{.Line}}{{end}}</td><td>{{.Scope}}</td><td>{{range .Results}}<code>{{.Type}}{{with .Name}}[name={{.}}]{{end}}{{with .Group}}[group={{.}}]{{end}}</code><br>{{end}}</td></tr>
+{{end}}</table>
+
+<h2>Graph</h2>
+<pre id="graph">{{.Graph}}</pre>
+
+<script>
+function filterKeys(q) {
+  q = q.toLowerCase();
+  var rows = document.getElementById("keys").rows;
+  for (var i = 1; i < rows.length; i++) {
+    rows[i].style.display = rows[i].textContent.toLowerCase().indexOf(q) < 0 ? "none" : "";
+  }
+}
+</script>
+</body>
+</html>
+`))
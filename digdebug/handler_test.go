@@ -0,0 +1,202 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package digdebug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.uber.org/dig"
+)
+
+type config struct{ addr string }
+
+type server struct{ cfg *config }
+
+type route string
+
+type routes struct {
+	dig.In
+
+	Routes []route `group:"routes"`
+}
+
+func newContainer(t *testing.T) *dig.Container {
+	c := dig.New()
+	require.NoError(t, c.Provide(func() *config { return &config{addr: ":8080"} }))
+	require.NoError(t, c.Provide(func(cfg *config) *server { return &server{cfg: cfg} }))
+	require.NoError(t, c.Provide(func() route { return "/a" }, dig.Group("routes")))
+	require.NoError(t, c.Provide(func() route { return "/b" }, dig.Group("routes")))
+	require.NoError(t, c.Provide(func() string { return "replica" }, dig.Name("db")))
+	return c
+}
+
+func get(t *testing.T, h http.Handler, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("keys", func(t *testing.T) {
+		c := newContainer(t)
+		require.NoError(t, c.Invoke(func(*server) {}))
+
+		rec := get(t, Handler(c), "/keys")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+		var keys []Key
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &keys))
+		require.Len(t, keys, 4)
+
+		assert.Equal(t, "*digdebug.config", keys[0].Type)
+		assert.Equal(t, 1, keys[0].Constructions, "config must be reported as built")
+		assert.Equal(t, "*digdebug.server", keys[1].Type)
+		assert.Equal(t, 1, keys[1].Constructions)
+
+		assert.Equal(t, `digdebug.route[group="routes"]`, keys[2].String())
+		assert.Len(t, keys[2].Providers, 2, "both members of the group must be listed")
+		assert.Equal(t, 0, keys[2].Constructions, "routes were not built")
+
+		assert.Equal(t, `string[name="db"]`, keys[3].String())
+		assert.Equal(t, []string{"go.uber.org/dig/digdebug.newContainer.func5"}, keys[3].Providers)
+	})
+
+	t.Run("providers", func(t *testing.T) {
+		rec := get(t, Handler(newContainer(t)), "/providers")
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var ctors []dig.GraphCtor
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ctors))
+		require.Len(t, ctors, 5)
+		assert.Equal(t, "newContainer.func2", ctors[1].Name)
+		assert.Contains(t, ctors[1].File, "handler_test.go")
+		require.Len(t, ctors[1].Params, 1)
+		assert.Equal(t, "*digdebug.config", ctors[1].Params[0].Type)
+	})
+
+	t.Run("graph", func(t *testing.T) {
+		h := Handler(newContainer(t))
+
+		rec := get(t, h, "/graph")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var gv dig.GraphView
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &gv))
+		assert.Len(t, gv.Ctors, 5)
+		require.Len(t, gv.Groups, 1)
+		assert.Len(t, gv.Groups[0].Results, 2)
+
+		rec = get(t, h, "/graph?format=dot")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, strings.HasPrefix(rec.Body.String(), "digraph {"), "got %q", rec.Body.String())
+
+		rec = get(t, h, "/graph?format=svg")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `unsupported graph format "svg"`)
+	})
+
+	t.Run("timings", func(t *testing.T) {
+		c := newContainer(t)
+		require.NoError(t, c.Invoke(func(*server) {}))
+
+		rec := get(t, Handler(c), "/timings")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var m dig.Metrics
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &m))
+		assert.Equal(t, 5, m.Providers)
+		assert.Equal(t, 2, m.ValuesConstructed)
+		assert.Equal(t, 1, m.Invokes)
+	})
+
+	t.Run("index", func(t *testing.T) {
+		c := newContainer(t)
+		h := Handler(c, SourceURL(func(file string, line int) string {
+			return fmt.Sprintf("https://code.example.com%s#L%d", file, line)
+		}))
+
+		rec := get(t, h, "/")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+		body := rec.Body.String()
+		assert.Contains(t, body, "go.uber.org/dig/digdebug.newContainer.func2")
+		assert.Contains(t, body, `<a href="https://code.example.com`)
+		assert.Contains(t, body, `digdebug.route[group=&#34;routes&#34;]`)
+		assert.Contains(t, body, "digraph {")
+	})
+
+	t.Run("unknown path", func(t *testing.T) {
+		rec := get(t, Handler(dig.New()), "/nope")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		Handler(dig.New()).ServeHTTP(rec, httptest.NewRequest("POST", "/keys", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+	})
+
+	t.Run("mounted under a prefix", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/dig/", http.StripPrefix("/debug/dig", Handler(newContainer(t))))
+
+		rec := get(t, mux, "/debug/dig/keys")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("serving does not build values", func(t *testing.T) {
+		c := newContainer(t)
+		h := Handler(c)
+		for _, target := range []string{"/", "/providers", "/graph", "/graph?format=dot", "/keys", "/timings"} {
+			require.Equal(t, http.StatusOK, get(t, h, target).Code, target)
+		}
+		assert.Empty(t, c.ConstructionCounts())
+		assert.Equal(t, 0, c.Metrics().Invokes)
+	})
+
+	t.Run("concurrent with Invoke", func(t *testing.T) {
+		c := newContainer(t)
+		require.NoError(t, c.Provide(func() chan int { return make(chan int) }, dig.When(func() bool { return true })))
+		h := Handler(c)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				assert.NoError(t, c.Invoke(func(*server, chan int, routes) {}))
+			}
+		}()
+		for i := 0; i < 20; i++ {
+			assert.Equal(t, http.StatusOK, get(t, h, "/").Code)
+		}
+		wg.Wait()
+	})
+}
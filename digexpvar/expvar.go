@@ -0,0 +1,50 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package digexpvar publishes the operational metrics of a dig.Container
+// through the standard library's expvar package.
+//
+//   digexpvar.Publish("dig", c)
+//
+// The published variable is evaluated lazily, so every read of /debug/vars
+// reports the container's counters at that time.
+package digexpvar
+
+import (
+	"expvar"
+
+	"go.uber.org/dig"
+)
+
+// Publish publishes the Metrics of the given container under the provided
+// name.
+//
+// Like expvar.Publish, this panics if the name is already in use.
+func Publish(name string, c *dig.Container) {
+	expvar.Publish(name, Func(c))
+}
+
+// Func returns an expvar.Func that reports the Metrics of the given
+// container. Use this to embed the metrics inside an existing expvar.Map.
+func Func(c *dig.Container) expvar.Func {
+	return expvar.Func(func() interface{} {
+		return c.Metrics()
+	})
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package dighttp builds a scope of a dig.Container for every HTTP request
+// so that handlers can depend on values specific to the request.
+//
+//   handler = dighttp.Middleware(c)(handler)
+//
+// Constructors that depend on the request are provided to each scope with
+// the Provide option, and handlers resolve their dependencies from the scope
+// of the request with Invoke.
+//
+//   handler = dighttp.Middleware(c, dighttp.Provide(NewSession))(handler)
+//
+//   func serve(w http.ResponseWriter, r *http.Request) {
+//     err := dighttp.Invoke(r, func(s *Session) {
+//       // ...
+//     })
+//   }
+package dighttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.uber.org/dig"
+)
+
+type scopeKey struct{}
+
+// An Option customizes Middleware.
+type Option interface {
+	applyOption(*options)
+}
+
+type options struct {
+	// Provided to the scope of every request.
+	Constructors []constructor
+
+	// Called if the scope of a request cannot be set up.
+	OnError func(http.ResponseWriter, *http.Request, error)
+
+	// Called if the OnClose functions of the scope of a request fail.
+	OnCleanupError func(*http.Request, error)
+}
+
+type constructor struct {
+	Func interface{}
+	Opts []dig.ProvideOption
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) applyOption(opts *options) { f(opts) }
+
+// Provide is an Option that provides the given constructor to the scope of
+// every request, so that it may depend on the request, the response writer,
+// or on other values specific to the request.
+//
+//   dighttp.Provide(NewSession)
+//
+// Constructors provided to the container itself resolve their dependencies
+// from the container and can't depend on these values.
+func Provide(ctor interface{}, opts ...dig.ProvideOption) Option {
+	return optionFunc(func(o *options) {
+		o.Constructors = append(o.Constructors, constructor{Func: ctor, Opts: opts})
+	})
+}
+
+// OnError is an Option that handles requests for which a scope cannot be
+// set up. By default, such requests fail with a 500 Internal Server Error.
+func OnError(f func(w http.ResponseWriter, r *http.Request, err error)) Option {
+	return optionFunc(func(opts *options) {
+		opts.OnError = f
+	})
+}
+
+// OnCleanupError is an Option that reports the errors returned by the
+// functions registered with OnClose on the scope of a request. The response
+// was already written when f is called. By default, these errors are
+// ignored.
+func OnCleanupError(f func(r *http.Request, err error)) Option {
+	return optionFunc(func(opts *options) {
+		opts.OnCleanupError = f
+	})
+}
+
+// Middleware returns a middleware that serves every request with a scope of
+// the given container, obtained from a dig.ScopePool. The request, the
+// response writer, and the context of the request are provided to the scope
+// as *http.Request, http.ResponseWriter, and context.Context, and the scope
+// is stored in the context of the request.
+//
+// Once the wrapped handler returns, the functions registered with OnClose on
+// the scope are called in the reverse order in which they were registered,
+// and the scope is returned to its pool. Handlers must not use the scope
+// afterwards.
+func Middleware(c *dig.Container, opts ...Option) func(http.Handler) http.Handler {
+	options := options{
+		OnError: func(w http.ResponseWriter, _ *http.Request, _ error) {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		},
+		OnCleanupError: func(*http.Request, error) {},
+	}
+	for _, o := range opts {
+		o.applyOption(&options)
+	}
+
+	pool := dig.NewScopePool(c)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s := pool.Get()
+			defer func() {
+				if err := pool.Release(s); err != nil {
+					options.OnCleanupError(r, err)
+				}
+			}()
+
+			ctx := context.WithValue(r.Context(), scopeKey{}, s)
+			r = r.WithContext(ctx)
+			if err := provideRequest(s, w, r, options.Constructors); err != nil {
+				options.OnError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// provideRequest provides the request, the response writer, the context of
+// the request, and the given constructors to the given scope.
+func provideRequest(s *dig.Container, w http.ResponseWriter, r *http.Request, ctors []constructor) error {
+	ctx := r.Context()
+	for _, ctor := range []interface{}{
+		func() *http.Request { return r },
+		func() http.ResponseWriter { return w },
+		func() context.Context { return ctx },
+	} {
+		if err := s.Provide(ctor); err != nil {
+			return err
+		}
+	}
+	for _, ctor := range ctors {
+		if err := s.Provide(ctor.Func, ctor.Opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scope returns the scope stored in the given context by Middleware.
+func Scope(ctx context.Context) (*dig.Container, bool) {
+	s, ok := ctx.Value(scopeKey{}).(*dig.Container)
+	return s, ok
+}
+
+// Invoke runs the given function with dependencies resolved from the scope
+// of the request. See dig.Container.Invoke.
+//
+// Invoke fails if the request was not served through Middleware.
+func Invoke(r *http.Request, function interface{}, opts ...dig.InvokeOption) error {
+	s, ok := Scope(r.Context())
+	if !ok {
+		return errors.New("cannot invoke a function for a request that was not served by dighttp.Middleware")
+	}
+	return s.Invoke(function, opts...)
+}
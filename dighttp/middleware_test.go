@@ -0,0 +1,122 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dighttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.uber.org/dig"
+)
+
+type session struct {
+	user string
+}
+
+func newSession(r *http.Request) *session {
+	return &session{user: r.Header.Get("User")}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("request scope", func(t *testing.T) {
+		c := dig.New()
+
+		var cleanups []string
+		handler := Middleware(c, Provide(newSession))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, ok := Scope(r.Context())
+			require.True(t, ok)
+			require.NoError(t, s.OnClose(func() error {
+				cleanups = append(cleanups, "first "+r.Header.Get("User"))
+				return nil
+			}))
+			require.NoError(t, s.OnClose(func() error {
+				cleanups = append(cleanups, "second "+r.Header.Get("User"))
+				return nil
+			}))
+
+			require.NoError(t, Invoke(r, func(s *session, rw http.ResponseWriter, ctx context.Context) {
+				assert.Equal(t, r.Context(), ctx)
+				rw.Write([]byte("hello " + s.user))
+			}))
+		}))
+
+		for _, user := range []string{"alice", "bob"} {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("User", user)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, "hello "+user, rec.Body.String(), "values must not leak between requests")
+		}
+		assert.Equal(t, []string{"second alice", "first alice", "second bob", "first bob"}, cleanups)
+
+		require.Error(t, c.Invoke(func(*session) {}), "the request must not be provided to the container")
+	})
+
+	t.Run("setup failure", func(t *testing.T) {
+		c := dig.New()
+		require.NoError(t, c.Provide(func() *http.Request { return nil }))
+
+		var called bool
+		handler := Middleware(c)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			called = true
+		}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		assert.False(t, called)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+		var gotErr error
+		handler = Middleware(c, OnError(func(w http.ResponseWriter, _ *http.Request, err error) {
+			gotErr = err
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))(http.NotFoundHandler())
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		require.Error(t, gotErr)
+		assert.Contains(t, gotErr.Error(), "already provided")
+	})
+
+	t.Run("cleanup failure", func(t *testing.T) {
+		var gotErr error
+		handler := Middleware(dig.New(), OnCleanupError(func(_ *http.Request, err error) {
+			gotErr = err
+		}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, _ := Scope(r.Context())
+			require.NoError(t, s.OnClose(func() error { return errors.New("great sadness") }))
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		require.Error(t, gotErr)
+		assert.Contains(t, gotErr.Error(), "great sadness")
+	})
+}
+
+func TestInvokeWithoutMiddleware(t *testing.T) {
+	err := Invoke(httptest.NewRequest("GET", "/", nil), func() {})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot invoke a function for a request that was not served by dighttp.Middleware")
+}
@@ -185,6 +185,24 @@
 //    // ...
 //  }
 //
+// Errors returned by the constructor cause all of its results to fail. For
+// result objects that hold independent values, an error field tagged with
+// `errorfor:".."` may instead report the failure of a single sibling field.
+// Other fields are added to the container as usual, while consumers of the
+// failed field receive the reported error.
+//
+//  type Tenants struct {
+//    dig.Out
+//
+//    Foo    *Tenant `name:"foo"`
+//    FooErr error   `errorfor:"Foo"`
+//    Bar    *Tenant `name:"bar"`
+//    BarErr error   `errorfor:"Bar"`
+//  }
+//
+// If every value of a constructor fails this way, the constructor fails with
+// a NoResultsCommittedError unless it was provided with dig.AllowNoResults.
+//
 // Optional Dependencies
 //
 // Constructors often don't have a hard dependency on some types and
@@ -218,6 +236,15 @@
 // The optional tag also allows adding new dependencies without breaking
 // existing consumers of the constructor.
 //
+// A field of type dig.UnresolvedKeys in the same dig.In struct lists the
+// optional fields that were absent.
+//
+// Optional dependencies may form loops, such as a constructor of *Node that
+// optionally depends on a *Node. Such loops are not reported as cycles: the
+// optional field that leads back to a constructor still building its
+// arguments is left absent. Only loops made of required dependencies are
+// cycles.
+//
 // Named Values
 //
 // Some use cases call for multiple values of the same type. Dig allows adding
@@ -284,6 +311,39 @@
 //     // ...
 //   }
 //
+// Result objects embedded in other result objects may be tagged with
+// `nameprefix:".."` to prepend a prefix to the names of all Named Values they
+// produce. This avoids collisions when result objects from different packages
+// use the same names.
+//
+//   type AppResult struct {
+//     dig.Out
+//
+//     Storage storage.Outputs `nameprefix:"storage."` // produces "storage.primary"
+//     Cache   cache.Outputs   `nameprefix:"cache."`   // produces "cache.primary"
+//   }
+//
+// Parameter objects accept the same tag to prepend a prefix to the names of
+// all Named Values they consume.
+//
+//   type AppParams struct {
+//     dig.In
+//
+//     Storage storage.Inputs `nameprefix:"storage."`
+//   }
+//
+// Several Named Values of the same type may be consumed as a slice by
+// listing their names in a `names:".."` tag. The slice holds the values in
+// the order in which their names are listed. If the field is also tagged
+// with `optional:"true"`, values that aren't available are left out of the
+// slice instead of failing the constructor.
+//
+//   type ResolverParams struct {
+//     dig.In
+//
+//     Resolvers []Resolver `names:"primary,secondary,tertiary"`
+//   }
+//
 // Value Groups
 //
 // Added in Dig 1.2.
@@ -310,10 +370,18 @@
 //     ..
 //   }
 //
+// Alternatively, constructors that return plain values may be provided with
+// the dig.Group option. The option may be repeated to send the same value to
+// multiple value groups.
+//
+//   c.Provide(NewHelloHandler, dig.Group("server"), dig.Group("admin"))
+//
 // Any number of constructors may provide values to this named collection.
 // Other constructors can request all values for this collection by requesting
 // a slice tagged with `group:".."`. This will execute all constructors that
-// provide a value to that group in an unspecified order.
+// provide a value to that group in the order in which they were provided. If
+// any of them fail, the values produced by the others are discarded and the
+// failures of all of them are reported together.
 //
 //   type ServerParams struct {
 //     dig.In
@@ -331,4 +399,72 @@
 //
 // Note that values in a value group are unordered. Dig makes no guarantees
 // about the order in which these values will be produced.
+//
+// Large value groups may be consumed lazily by requesting an iterator instead
+// of a slice. Constructors of the group are only called once the iteration
+// reaches their values, and at most once no matter how often the group is
+// iterated.
+//
+//   type RouterParams struct {
+//     dig.In
+//
+//     Handlers func(yield func(Handler) bool) error `group:"server"`
+//   }
+//
+// Iterators of type func(yield func(T) bool), or dig.GroupIter[T], are also
+// supported. These panic if a constructor of the group fails.
+//
+// Value groups may also be consumed as receive-only channels. The channel
+// delivers the values of the group and is closed after the last one. All
+// constructors of the group are called before the consumer, and failures are
+// reported by Invoke like for slices. The channel holds all values, so
+// consumers may stop reading at any time.
+//
+//   type PipelineParams struct {
+//     dig.In
+//
+//     Stages <-chan Stage `group:"stages"`
+//   }
+//
+// Indexed Value Groups
+//
+// Values of a value group may declare their position in the group with an
+// `index:".."` tag. Consumers then receive the values ordered by their
+// indexes.
+//
+//   type StageResult struct {
+//     dig.Out
+//
+//     Stage Stage `group:"stages" index:"3"`
+//   }
+//
+// Either all values of a group have an index or none of them do, and no two
+// values may have the same index. Providing a constructor that breaks either
+// rule fails. By default, consuming a group fails if an index below the
+// highest one has no value. Consumers tagged with `compact:"true"` receive
+// the values without the gaps instead.
+//
+//   type PipelineParams struct {
+//     dig.In
+//
+//     Stages []Stage `group:"stages" compact:"true"`
+//   }
+//
+// Documentation Tags
+//
+// Fields of dig.In and dig.Out structs that consume or produce a single value
+// may describe that value with a `doc:".."` tag.
+//
+//   type Params struct {
+//     dig.In
+//
+//     DB *sql.DB `name:"primary" doc:"primary OLTP database"`
+//   }
+//
+// The description is included in the error reported when the value is
+// missing, shown as a tooltip by Visualize, and reported by Graph.
+//
+//   type *sql.DB[name="primary"] ("primary OLTP database") is not in the container
+//
+// Whole constructors are described with the dig.Doc option.
 package dig // import "go.uber.org/dig"
@@ -0,0 +1,231 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package dig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// EnvironmentName is an Option that sets the environment the container runs
+// in, such as "prod" or "staging". Fields of parameter objects tagged with
+// `env:".."` then consume the value named for this environment.
+//
+//   type DBParams struct {
+//     dig.In
+//
+//     DB *sql.DB `name:"local" env:"prod=primary;staging=staging-db"`
+//   }
+//
+//   c := dig.New(dig.EnvironmentName("prod"))
+//
+// The env tag lists the name of the value for each environment as
+// environment=name pairs separated by semicolons. An empty name stands for
+// the unnamed value. Fields consume the value named by their name tag in
+// environments that aren't listed, unless the container was created with
+// StrictEnvironment.
+//
+// The names are chosen when constructors are provided and functions are
+// invoked, and Visualize shows the names chosen for the environment of the
+// container. Scopes inherit the environment of their parents.
+func EnvironmentName(env string) Option {
+	return optionFunc(func(c *Container) {
+		c.env = env
+	})
+}
+
+// StrictEnvironment is an Option that makes constructors and functions with
+// fields tagged with `env:".."` fail to be provided or invoked if the tag
+// doesn't list the environment of the container. See EnvironmentName.
+func StrictEnvironment() Option {
+	return optionFunc(func(c *Container) {
+		c.strictEnv = true
+	})
+}
+
+// envName is an entry of an `env:".."` tag.
+type envName struct {
+	Env  string
+	Name string
+}
+
+// parseEnvTag parses the value of an `env:".."` tag.
+func parseEnvTag(tag string) ([]envName, error) {
+	var names []envName
+	seen := make(map[string]string)
+	for _, entry := range strings.Split(tag, ";") {
+		entry = strings.TrimSpace(entry)
+		i := strings.Index(entry, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid value %q for %q tag: entry %q must be of the form environment=name",
+				tag, _envTag, entry)
+		}
+
+		env, name := strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+		if env == "" {
+			return nil, fmt.Errorf("invalid value %q for %q tag: environments cannot be empty", tag, _envTag)
+		}
+		if prev, ok := seen[env]; ok {
+			return nil, fmt.Errorf("invalid value %q for %q tag: environment %q is listed more than once, with names %q and %q",
+				tag, _envTag, env, prev, name)
+		}
+		seen[env] = name
+		names = append(names, envName{Env: env, Name: name})
+	}
+	return names, nil
+}
+
+// formatEnvTag formats the given entries as the value of an `env:".."` tag.
+func formatEnvTag(names []envName) string {
+	entries := make([]string, len(names))
+	for i, n := range names {
+		entries[i] = n.Env + "=" + n.Name
+	}
+	return strings.Join(entries, ";")
+}
+
+// prefixEnvTag prepends the given prefix to the non-empty names listed in the
+// given `env:".."` tag, which must be valid. See withNamePrefix.
+func prefixEnvTag(tag, prefix string) string {
+	names, _ := parseEnvTag(tag)
+	for i, n := range names {
+		if n.Name != "" {
+			names[i].Name = prefix + n.Name
+		}
+	}
+	return formatEnvTag(names)
+}
+
+// invokeParamList builds the params of a function invoked on this container
+// for the environment of the container. The params of each function type
+// are only built once for the container and its scopes.
+func (c *Container) invokeParamList(ftype reflect.Type) (paramList, error) {
+	if pl, ok := c.invokeParams.Load(ftype); ok {
+		return pl.(paramList), nil
+	}
+
+	pl, err := newParamList(ftype)
+	if err != nil {
+		return pl, err
+	}
+	if pl, err = pl.withEnvironment(c.env, c.strictEnv); err != nil {
+		return pl, err
+	}
+	c.invokeParams.Store(ftype, pl)
+	return pl, nil
+}
+
+// withEnvironment returns a copy of these params in which fields tagged with
+// `env:".."` consume the value named for the given environment.
+func (pl paramList) withEnvironment(env string, strict bool) (paramList, error) {
+	if !pl.usesEnvironment() {
+		return pl, nil
+	}
+
+	params := make([]param, len(pl.Params))
+	for i, p := range pl.Params {
+		if po, ok := p.(paramObject); ok {
+			var err error
+			if p, err = po.withEnvironment(env, strict); err != nil {
+				return pl, errWrapf(err, "bad argument %d", i+1)
+			}
+		}
+		params[i] = p
+	}
+	pl.Params = params
+
+	if len(pl.Populated) > 0 {
+		populated := make([]populatedResult, len(pl.Populated))
+		for i, pr := range pl.Populated {
+			var err error
+			if pr.Object, err = pr.Object.withEnvironment(env, strict); err != nil {
+				return pl, errWrapf(err, "bad result %d", pr.Index+1)
+			}
+			populated[i] = pr
+		}
+		pl.Populated = populated
+	}
+	return pl, nil
+}
+
+// usesEnvironment reports whether any of these params is tagged with
+// `env:".."`.
+func (pl paramList) usesEnvironment() bool {
+	var uses bool
+	check := func(p param, _ paramPath) {
+		if ps, ok := p.(paramSingle); ok && ps.Env != "" {
+			uses = true
+		}
+	}
+	for _, p := range pl.Params {
+		walkParamPaths(p, paramPath{}, check)
+	}
+	for _, pr := range pl.Populated {
+		walkParamPaths(pr.Object, paramPath{}, check)
+	}
+	return uses
+}
+
+func (po paramObject) withEnvironment(env string, strict bool) (paramObject, error) {
+	fields := make([]paramObjectField, len(po.Fields))
+	for i, f := range po.Fields {
+		switch p := f.Param.(type) {
+		case paramSingle:
+			if p.Env != "" {
+				name, err := p.envName(env, strict)
+				if err != nil {
+					return po, errWrapf(err, "bad field %q of %v", f.FieldName, po.Type)
+				}
+				p.Name = name
+				f.Param = p
+			}
+		case paramObject:
+			nested, err := p.withEnvironment(env, strict)
+			if err != nil {
+				return po, errWrapf(err, "bad field %q of %v", f.FieldName, po.Type)
+			}
+			f.Param = nested
+		}
+		fields[i] = f
+	}
+	po.Fields = fields
+	po.plan = newParamPlan(po)
+	return po, nil
+}
+
+// envName returns the name of the value this param consumes in the given
+// environment.
+func (ps paramSingle) envName(env string, strict bool) (string, error) {
+	names, err := parseEnvTag(ps.Env)
+	if err != nil {
+		return "", err
+	}
+	for _, n := range names {
+		if n.Env == env {
+			return n.Name, nil
+		}
+	}
+	if strict {
+		return "", fmt.Errorf("%v:%q does not list environment %q, and dig.StrictEnvironment is set",
+			_envTag, ps.Env, env)
+	}
+	return ps.Name, nil
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package dig
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironmentName(t *testing.T) {
+	type DB struct{ Name string }
+	type dbParams struct {
+		In
+
+		DB *DB `name:"local" env:"prod=primary; staging=staging-db"`
+	}
+
+	provideDBs := func(t *testing.T, c *Container) {
+		for _, name := range []string{"local", "primary", "staging-db"} {
+			name := name
+			require.NoError(t, c.Provide(func() *DB { return &DB{Name: name} }, Name(name)))
+		}
+	}
+
+	dbName := func(t *testing.T, c *Container) string {
+		var got string
+		require.NoError(t, c.Invoke(func(p dbParams) { got = p.DB.Name }))
+		return got
+	}
+
+	t.Run("names per environment", func(t *testing.T) {
+		for env, want := range map[string]string{
+			"prod":    "primary",
+			"staging": "staging-db",
+			"dev":     "local",
+			"":        "local",
+		} {
+			c := New(EnvironmentName(env))
+			provideDBs(t, c)
+			assert.Equal(t, want, dbName(t, c), "environment %q", env)
+		}
+	})
+
+	t.Run("constructors", func(t *testing.T) {
+		type Server struct{ DB *DB }
+
+		c := New(EnvironmentName("staging"))
+		provideDBs(t, c)
+		require.NoError(t, c.Provide(func(p dbParams) *Server { return &Server{DB: p.DB} }))
+		require.NoError(t, c.Invoke(func(s *Server) {
+			assert.Equal(t, "staging-db", s.DB.Name)
+		}))
+	})
+
+	t.Run("scopes inherit the environment", func(t *testing.T) {
+		c := New(EnvironmentName("prod"))
+		provideDBs(t, c)
+		assert.Equal(t, "primary", dbName(t, c.Scope("child")))
+	})
+
+	t.Run("unnamed values", func(t *testing.T) {
+		c := New(EnvironmentName("test"))
+		require.NoError(t, c.Provide(func() *DB { return &DB{Name: "unnamed"} }))
+		require.NoError(t, c.Invoke(func(p struct {
+			In
+
+			DB *DB `name:"local" env:"test="`
+		}) {
+			assert.Equal(t, "unnamed", p.DB.Name)
+		}))
+	})
+
+	t.Run("name prefixes", func(t *testing.T) {
+		c := New(EnvironmentName("prod"))
+		require.NoError(t, c.Provide(func() *DB { return &DB{Name: "eu.primary"} }, Name("eu.primary")))
+		require.NoError(t, c.Invoke(func(p struct {
+			In
+
+			EU dbParams `nameprefix:"eu."`
+		}) {
+			assert.Equal(t, "eu.primary", p.EU.DB.Name)
+		}))
+	})
+
+	t.Run("populated fields", func(t *testing.T) {
+		type Server struct {
+			DB *DB `env:"prod=primary"`
+		}
+
+		c := New(EnvironmentName("prod"))
+		provideDBs(t, c)
+		require.NoError(t, c.Provide(func() *Server { return &Server{} }, PopulateFields()))
+		require.NoError(t, c.Invoke(func(s *Server) {
+			assert.Equal(t, "primary", s.DB.Name)
+		}))
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		c := New(EnvironmentName("dev"), StrictEnvironment())
+		provideDBs(t, c)
+
+		err := c.Invoke(func(dbParams) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestEnvironmentName\S+ \(\S+\) cannot be invoked:`,
+			"bad argument 1:",
+			`bad field "DB" of dig.dbParams:`,
+			`env:"prod=primary; staging=staging-db" does not list environment "dev", and dig.StrictEnvironment is set`)
+
+		err = c.Provide(func(dbParams) string { return "" })
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `does not list environment "dev"`)
+	})
+
+	t.Run("invalid tags", func(t *testing.T) {
+		tests := []struct {
+			desc    string
+			shape   interface{}
+			wantErr string
+		}{
+			{
+				desc: "missing name",
+				shape: struct {
+					In
+
+					DB *DB `env:"prod"`
+				}{},
+				wantErr: `invalid value "prod" for "env" tag: entry "prod" must be of the form environment=name`,
+			},
+			{
+				desc: "empty environment",
+				shape: struct {
+					In
+
+					DB *DB `env:"=primary"`
+				}{},
+				wantErr: `invalid value "=primary" for "env" tag: environments cannot be empty`,
+			},
+			{
+				desc: "conflicting entries",
+				shape: struct {
+					In
+
+					DB *DB `env:"prod=primary;prod=replica"`
+				}{},
+				wantErr: `invalid value "prod=primary;prod=replica" for "env" tag: ` +
+					`environment "prod" is listed more than once, with names "primary" and "replica"`,
+			},
+			{
+				desc: "value groups",
+				shape: struct {
+					In
+
+					DBs []*DB `group:"dbs" env:"prod=primary"`
+				}{},
+				wantErr: `env:"prod=primary" can only be used on fields that consume a single value`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				_, err := newParamObject(reflect.TypeOf(tt.shape))
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			})
+		}
+	})
+}
@@ -23,8 +23,11 @@ package dig
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"reflect"
 	"sort"
+	"strconv"
+	"strings"
 
 	"go.uber.org/dig/internal/digreflect"
 	"go.uber.org/dig/internal/dot"
@@ -78,12 +81,31 @@ type wrappedError struct {
 	msg string
 }
 
-func (e wrappedError) cause() error { return e.err }
+func (e wrappedError) cause() error  { return e.err }
+func (e wrappedError) Unwrap() error { return e.err }
 
 func (e wrappedError) Error() string {
 	return fmt.Sprintf("%v: %v", e.msg, e.err)
 }
 
+func (e wrappedError) Format(w fmt.State, c rune) { formatCause(e, e.err, w, c) }
+
+// formatCause implements fmt.Formatter for errors whose message ends with the
+// message of the error they wrap. The %+v verb formats the wrapped error with
+// %+v as well, so that the details of the errors it wraps, such as the
+// arguments captured by CaptureArgsOnError, are included.
+func formatCause(err, reason error, w fmt.State, c rune) {
+	msg := err.Error()
+	if c == 'v' && w.Flag('+') && reason != nil {
+		if rmsg := reason.Error(); strings.HasSuffix(msg, rmsg) {
+			io.WriteString(w, strings.TrimSuffix(msg, rmsg))
+			fmt.Fprintf(w, "%+v", reason)
+			return
+		}
+	}
+	io.WriteString(w, msg)
+}
+
 // errProvide is returned when a constructor could not be Provided into the
 // container.
 type errProvide struct {
@@ -91,12 +113,15 @@ type errProvide struct {
 	Reason error
 }
 
-func (e errProvide) cause() error { return e.Reason }
+func (e errProvide) cause() error  { return e.Reason }
+func (e errProvide) Unwrap() error { return e.Reason }
 
 func (e errProvide) Error() string {
 	return fmt.Sprintf("function %v cannot be provided: %v", e.Func, e.Reason)
 }
 
+func (e errProvide) Format(w fmt.State, c rune) { formatCause(e, e.Reason, w, c) }
+
 // errConstructorFailed is returned when a user-provided constructor failed
 // with a non-nil error.
 type errConstructorFailed struct {
@@ -104,12 +129,15 @@ type errConstructorFailed struct {
 	Reason error
 }
 
-func (e errConstructorFailed) cause() error { return e.Reason }
+func (e errConstructorFailed) cause() error  { return e.Reason }
+func (e errConstructorFailed) Unwrap() error { return e.Reason }
 
 func (e errConstructorFailed) Error() string {
 	return fmt.Sprintf("function %v returned a non-nil error: %v", e.Func, e.Reason)
 }
 
+func (e errConstructorFailed) Format(w fmt.State, c rune) { formatCause(e, e.Reason, w, c) }
+
 // errArgumentsFailed is returned when a function could not be run because one
 // of its dependencies failed to build for any reason.
 type errArgumentsFailed struct {
@@ -117,12 +145,15 @@ type errArgumentsFailed struct {
 	Reason error
 }
 
-func (e errArgumentsFailed) cause() error { return e.Reason }
+func (e errArgumentsFailed) cause() error  { return e.Reason }
+func (e errArgumentsFailed) Unwrap() error { return e.Reason }
 
 func (e errArgumentsFailed) Error() string {
 	return fmt.Sprintf("could not build arguments for function %v: %v", e.Func, e.Reason)
 }
 
+func (e errArgumentsFailed) Format(w fmt.State, c rune) { formatCause(e, e.Reason, w, c) }
+
 // errMissingDependencies is returned when the dependencies of a function are
 // not available in the container.
 type errMissingDependencies struct {
@@ -130,12 +161,51 @@ type errMissingDependencies struct {
 	Reason error
 }
 
-func (e errMissingDependencies) cause() error { return e.Reason }
+func (e errMissingDependencies) cause() error  { return e.Reason }
+func (e errMissingDependencies) Unwrap() error { return e.Reason }
 
 func (e errMissingDependencies) Error() string {
 	return fmt.Sprintf("missing dependencies for function %v: %v", e.Func, e.Reason)
 }
 
+func (e errMissingDependencies) Format(w fmt.State, c rune) { formatCause(e, e.Reason, w, c) }
+
+// errParamFailed is returned when a parameter of a function could not be
+// built. It locates the parameter among the arguments of the function.
+type errParamFailed struct {
+	Path   paramPath
+	Reason error
+}
+
+func (e errParamFailed) cause() error  { return e.Reason }
+func (e errParamFailed) Unwrap() error { return e.Reason }
+
+func (e errParamFailed) Error() string {
+	return fmt.Sprintf("%v: %v", e.Path, e.Reason)
+}
+
+func (e errParamFailed) Format(w fmt.State, c rune) { formatCause(e, e.Reason, w, c) }
+
+// errParamFailedAt locates the failure to build a parameter at the given
+// argument position.
+func errParamFailedAt(err error, arg int) error {
+	if e, ok := err.(errParamFailed); ok {
+		e.Path.Arg = arg
+		return e
+	}
+	return errParamFailed{Path: paramPath{Arg: arg}, Reason: err}
+}
+
+// errParamFailedInField locates the failure to build a parameter in the
+// field with the given name of a parameter object.
+func errParamFailedInField(err error, field string) error {
+	if e, ok := err.(errParamFailed); ok {
+		e.Path.Fields = append([]string{field}, e.Path.Fields...)
+		return e
+	}
+	return errParamFailed{Path: paramPath{Fields: []string{field}}, Reason: err}
+}
+
 // errParamSingleFailed is returned when a paramSingle could not be built.
 type errParamSingleFailed struct {
 	Key    key
@@ -143,12 +213,15 @@ type errParamSingleFailed struct {
 	CtorID dot.CtorID
 }
 
-func (e errParamSingleFailed) cause() error { return e.Reason }
+func (e errParamSingleFailed) cause() error  { return e.Reason }
+func (e errParamSingleFailed) Unwrap() error { return e.Reason }
 
 func (e errParamSingleFailed) Error() string {
 	return fmt.Sprintf("failed to build %v: %v", e.Key, e.Reason)
 }
 
+func (e errParamSingleFailed) Format(w fmt.State, c rune) { formatCause(e, e.Reason, w, c) }
+
 func (e errParamSingleFailed) updateGraph(g *dot.Graph) {
 	failed := &dot.Result{
 		Node: &dot.Node{
@@ -168,24 +241,94 @@ type errParamGroupFailed struct {
 	CtorID dot.CtorID
 }
 
-func (e errParamGroupFailed) cause() error { return e.Reason }
+func (e errParamGroupFailed) cause() error  { return e.Reason }
+func (e errParamGroupFailed) Unwrap() error { return e.Reason }
 
 func (e errParamGroupFailed) Error() string {
 	return fmt.Sprintf("could not build value group %v: %v", e.Key, e.Reason)
 }
 
+func (e errParamGroupFailed) Format(w fmt.State, c rune) { formatCause(e, e.Reason, w, c) }
+
 func (e errParamGroupFailed) updateGraph(g *dot.Graph) {
 	g.FailGroupNodes(e.Key.group, e.Key.t, e.CtorID)
 }
 
+// errParamGroupFailures is returned when a value group cannot be built
+// because multiple values in the group failed to build.
+type errParamGroupFailures struct {
+	Key key
+
+	// Failures in the order in which the failed constructors were provided.
+	// The length must be at least two.
+	Failures []errParamGroupFailed
+}
+
+// The first failure is treated as the cause so that RootCause is stable.
+func (e errParamGroupFailures) cause() error { return e.Failures[0].Reason }
+
+func (e errParamGroupFailures) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f
+	}
+	return errs
+}
+
+func (e errParamGroupFailures) Error() string {
+	return fmt.Sprint(e)
+}
+
+// Format implements fmt.Formatter. The %+v verb formats the failures with
+// %+v as well.
+func (e errParamGroupFailures) Format(w fmt.State, c rune) {
+	verb := "%v"
+	if c == 'v' && w.Flag('+') {
+		verb = "%+v"
+	}
+	fmt.Fprintf(w, "could not build value group %v: %d constructors failed: ", e.Key, len(e.Failures))
+	for i, f := range e.Failures {
+		if i > 0 {
+			io.WriteString(w, "; ")
+		}
+		fmt.Fprintf(w, "[%d] "+verb, i, f.Reason)
+	}
+}
+
+func (e errParamGroupFailures) updateGraph(g *dot.Graph) {
+	for _, f := range e.Failures {
+		f.updateGraph(g)
+	}
+}
+
 // errMissingType is returned when a single value that was expected in the
 // container was not available.
 type errMissingType struct {
 	Key key
 
+	// Locations of the parameters that requested the type, if known.
+	Paths []paramPath
+
 	// If non-empty, we will include suggestions for what the user may have
 	// meant.
 	suggestions []key
+
+	// Other names under which the type is provided, if any. The empty string
+	// stands for the unnamed value.
+	names []string
+
+	// Description of the value from the `doc:".."` tag of the parameter
+	// that requested it, if any.
+	Doc string
+
+	// Constructors of the type that are disabled by their conditions. See
+	// When.
+	disabled []*digreflect.Func
+
+	// Whether the fallback resolver was asked for the value, and how it
+	// failed, if it did. See WithFallbackResolver.
+	fallback    bool
+	fallbackErr error
 }
 
 func newErrMissingType(c containerStore, k key) errMissingType {
@@ -198,22 +341,31 @@ func newErrMissingType(c containerStore, k key) errMissingType {
 		suggestions = append(suggestions, k.t.Elem())
 	}
 
-	knownTypes := c.knownTypes()
-	if k.t.Kind() == reflect.Interface {
-		// Maybe we have an implementation of the interface.
-		for _, t := range knownTypes {
+	err := errMissingType{Key: k}
+	for _, p := range c.getDisabledProviders(k) {
+		err.disabled = append(err.disabled, p.Location())
+	}
+	seen := make(map[reflect.Type]struct{})
+	for _, kk := range c.KnownKeys() {
+		t := kk.Type
+		if t == k.t && kk.Group == "" && kk.Name != k.name {
+			// Maybe we have the type under a different name.
+			err.names = append(err.names, kk.Name)
+		}
+
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+
+		if k.t.Kind() == reflect.Interface {
+			// Maybe we have an implementation of the interface.
 			if t.Implements(k.t) {
 				suggestions = append(suggestions, t)
 			}
-		}
-	} else {
-		// Maybe we have an interface that this type implements.
-		for _, t := range knownTypes {
-			if t.Kind() == reflect.Interface {
-				if k.t.Implements(t) {
-					suggestions = append(suggestions, t)
-				}
-			}
+		} else if t.Kind() == reflect.Interface && k.t.Implements(t) {
+			// Maybe we have an interface that this type implements.
+			suggestions = append(suggestions, t)
 		}
 	}
 
@@ -221,7 +373,6 @@ func newErrMissingType(c containerStore, k key) errMissingType {
 	// suggestions.
 	sort.Sort(byTypeName(suggestions))
 
-	err := errMissingType{Key: k}
 	for _, t := range suggestions {
 		if len(c.getValueProviders(k.name, t)) > 0 {
 			k.t = t
@@ -239,17 +390,50 @@ func (e errMissingType) Error() string {
 	//   type io.Reader is not in the container, did you mean to use one of *bytes.Buffer, *MyBuffer
 	//   type bytes.Buffer is not in the container, did you mean to use *bytes.Buffer?
 	//   type *foo[name="bar"] is not in the container, did you mean to use foo[name="bar"]?
+	//   type *sql.DB is not in the container, but it is available as name "replica"
+	//   type *sql.DB[name="primary"] ("primary OLTP database") is not in the container, did you mean to Provide it?
+	//   type *Cache is not in the container: it is provided by "pkg".NewCache (cache.go:12), disabled by dig.When
+	//   type *Config is not in the container, and the fallback resolver doesn't have it
+	//   type *Config is not in the container, and the fallback resolver failed: connection refused
 
 	b := new(bytes.Buffer)
 
-	fmt.Fprintf(b, "type %v is not in the container", e.Key)
+	if len(e.Paths) > 0 {
+		fmt.Fprintf(b, "%v: ", e.pathString())
+	}
+	fmt.Fprintf(b, "type %v ", e.Key)
+	if e.Doc != "" {
+		fmt.Fprintf(b, "(%q) ", e.Doc)
+	}
+	b.WriteString("is not in the container")
+	if len(e.disabled) > 0 {
+		fns := make([]string, len(e.disabled))
+		for i, fn := range e.disabled {
+			fns[i] = fn.String()
+		}
+		fmt.Fprintf(b, ": it is provided by %v, disabled by dig.When", strings.Join(fns, "; "))
+		return b.String()
+	}
+	if e.fallback {
+		b.WriteString(", and the fallback resolver ")
+		b.WriteString(e.fallbackString())
+		return b.String()
+	}
+	sep := ", "
+	if len(e.names) > 0 {
+		fmt.Fprintf(b, ", but it is %v", e.availableAs())
+		if len(e.suggestions) == 0 {
+			return b.String()
+		}
+		sep = "; "
+	}
 	switch len(e.suggestions) {
 	case 0:
 		b.WriteString(", did you mean to Provide it?")
 	case 1:
-		fmt.Fprintf(b, ", did you mean to use %v?", e.suggestions[0])
+		fmt.Fprintf(b, "%vdid you mean to use %v?", sep, e.suggestions[0])
 	default:
-		b.WriteString(", did you mean to use one of ")
+		fmt.Fprintf(b, "%vdid you mean to use one of ", sep)
 		for i, k := range e.suggestions {
 			if i > 0 {
 				b.WriteString(", ")
@@ -265,58 +449,205 @@ func (e errMissingType) Error() string {
 	return b.String()
 }
 
-// errMissingManyTypes combines multiple errMissingType errors.
-type errMissingManyTypes []errMissingType // length must be non-zero
+// fallbackString describes how the fallback resolver failed to provide the
+// type.
+func (e errMissingType) fallbackString() string {
+	if e.fallbackErr != nil {
+		return fmt.Sprintf("failed: %v", e.fallbackErr)
+	}
+	return "doesn't have it"
+}
+
+// Unwrap returns the error of the fallback resolver, if any.
+func (e errMissingType) Unwrap() error { return e.fallbackErr }
 
-func (e errMissingManyTypes) Error() string {
-	if len(e) == 1 {
-		return e[0].Error()
+// availableAs describes the other names under which the type is provided.
+func (e errMissingType) availableAs() string {
+	var parts, names []string
+	for _, name := range e.names {
+		if name == "" {
+			parts = append(parts, "without a name")
+		} else {
+			names = append(names, strconv.Quote(name))
+		}
 	}
+	switch len(names) {
+	case 0:
+		// do nothing
+	case 1:
+		parts = append(parts, "as name "+names[0])
+	default:
+		parts = append(parts, "as names "+strings.Join(names, ", "))
+	}
+	return "available " + strings.Join(parts, " and ")
+}
 
-	b := new(bytes.Buffer)
+// AmbiguousProviderError is returned when a value is requested from a
+// container that has more than one provider for it. This may happen when a
+// scope provides a type that one of its parents starts providing later.
+//
+// Use RootCause to retrieve this error from errors returned by Invoke.
+type AmbiguousProviderError struct {
+	// Key of the requested value.
+	Key Key
+
+	// Locations of all constructors that provide the value.
+	Candidates []string
+}
+
+func newAmbiguousProviderError(k key, providers []provider) AmbiguousProviderError {
+	err := AmbiguousProviderError{Key: k.exported()}
+	for _, p := range providers {
+		err.Candidates = append(err.Candidates, fmt.Sprint(p.Location()))
+	}
+	return err
+}
+
+func (e AmbiguousProviderError) Error() string {
+	return fmt.Sprintf(
+		"type %v is provided by multiple constructors: %v; "+
+			"use dig.Name to provide them under different names",
+		e.Key, strings.Join(e.Candidates, "; "))
+}
 
-	b.WriteString("the following types are not in the container: ")
-	for i, err := range e {
+// errAmbiguousImplementation is returned when an interface is resolved by
+// implementation but more than one provided type implements it, or when a
+// type is resolved by assignability but more than one provided type is
+// assignable to it.
+type errAmbiguousImplementation struct {
+	Key        key
+	Candidates []key // length must be at least two
+}
+
+func (e errAmbiguousImplementation) Error() string {
+	cands := make([]string, len(e.Candidates))
+	for i, k := range e.Candidates {
+		cands[i] = k.String()
+	}
+	verb := "is implemented by"
+	if e.Key.t.Kind() != reflect.Interface {
+		verb = "is assignable from"
+	}
+	return fmt.Sprintf(
+		"type %v %v multiple provided types: %v; "+
+			"provide %v explicitly to choose one",
+		e.Key, verb, strings.Join(cands, ", "), e.Key)
+}
+
+// errSharedNamedValue is returned when two fields of a parameter object
+// request the same type under different names but resolve to the same value.
+// See DistinctNamedValues.
+type errSharedNamedValue struct {
+	Type   reflect.Type
+	Fields [2]string
+	Keys   [2]key
+
+	// Locations of the constructors for each key, if known.
+	Locations [2]string
+}
+
+func newErrSharedNamedValue(c containerStore, t reflect.Type, fields [2]string, keys [2]key) errSharedNamedValue {
+	err := errSharedNamedValue{Type: t, Fields: fields, Keys: keys}
+	for i, k := range keys {
+		if ps := c.getValueProviders(k.name, k.t); len(ps) > 0 {
+			err.Locations[i] = fmt.Sprint(ps[0].Location())
+		}
+	}
+	return err
+}
+
+func (e errSharedNamedValue) Error() string {
+	b := new(bytes.Buffer)
+	fmt.Fprintf(b, "fields %q and %q of %v resolve to the same value: ", e.Fields[0], e.Fields[1], e.Type)
+	for i, k := range e.Keys {
 		if i > 0 {
-			b.WriteString("; ")
+			b.WriteString(" and ")
 		}
-		fmt.Fprintf(b, "%v", err.Key)
-		switch len(err.suggestions) {
-		case 0:
-			// do nothing
-		case 1:
-			fmt.Fprintf(b, " (did you mean %v?)", err.suggestions[0])
-		default:
-			b.WriteString(" (did you mean ")
-			for i, k := range err.suggestions {
-				if i > 0 {
-					b.WriteString(", ")
-					if i == len(err.suggestions)-1 {
-						b.WriteString("or ")
-					}
+		fmt.Fprint(b, k)
+		if loc := e.Locations[i]; loc != "" {
+			fmt.Fprintf(b, " provided by %v", loc)
+		}
+	}
+	return b.String()
+}
+
+// newErrMissingTypes combines errMissingType errors into a MultiError. The
+// length of errs must be non-zero.
+func newErrMissingTypes(errs []error) MultiError {
+	e := newMultiError(errs)
+	e.header = "the following types are not in the container: "
+	e.sep = "; "
+	e.unwrapSingle = true
+	return e
+}
+
+func (e errMissingType) errorKey() key { return e.Key }
+
+func (e errMissingType) merge(other keyedError) keyedError {
+	e.Paths = append(e.Paths[:len(e.Paths):len(e.Paths)], other.(errMissingType).Paths...)
+	return e
+}
+
+// shortError describes this error in the list of a MultiError, which
+// already states that the types are not in the container.
+func (e errMissingType) shortError() string {
+	var hints []string
+	if e.Doc != "" {
+		hints = append(hints, strconv.Quote(e.Doc))
+	}
+	if len(e.Paths) > 0 {
+		hints = append(hints, e.pathString())
+	}
+	if len(e.names) > 0 {
+		hints = append(hints, e.availableAs())
+	}
+	if e.fallback {
+		hints = append(hints, "the fallback resolver "+e.fallbackString())
+	}
+	switch len(e.suggestions) {
+	case 0:
+		// do nothing
+	case 1:
+		hints = append(hints, fmt.Sprintf("did you mean %v?", e.suggestions[0]))
+	default:
+		b := new(bytes.Buffer)
+		b.WriteString("did you mean ")
+		for i, k := range e.suggestions {
+			if i > 0 {
+				b.WriteString(", ")
+				if i == len(e.suggestions)-1 {
+					b.WriteString("or ")
 				}
-				fmt.Fprint(b, k)
 			}
-			b.WriteString("?)")
+			fmt.Fprint(b, k)
 		}
+		b.WriteString("?")
+		hints = append(hints, b.String())
 	}
 
-	return b.String()
+	if len(hints) == 0 {
+		return e.Key.String()
+	}
+	return fmt.Sprintf("%v (%v)", e.Key, strings.Join(hints, ", "))
 }
 
-func (e errMissingManyTypes) updateGraph(g *dot.Graph) {
-	missing := make([]*dot.Result, len(e))
+// pathString describes the parameters that requested the type.
+func (e errMissingType) pathString() string {
+	paths := make([]string, len(e.Paths))
+	for i, p := range e.Paths {
+		paths[i] = p.String()
+	}
+	return strings.Join(paths, " and ")
+}
 
-	for i, err := range e {
-		missing[i] = &dot.Result{
-			Node: &dot.Node{
-				Name:  err.Key.name,
-				Group: err.Key.group,
-				Type:  err.Key.t,
-			},
-		}
+func (e errMissingType) dotResult() *dot.Result {
+	return &dot.Result{
+		Node: &dot.Node{
+			Name:  e.Key.name,
+			Group: e.Key.group,
+			Type:  e.Key.t,
+		},
 	}
-	g.AddMissingNodes(missing)
 }
 
 type errVisualizer interface {
@@ -0,0 +1,293 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// Size of the channel returned by Subscribe unless EventBuffer is specified.
+const _defaultEventBuffer = 128
+
+// EventKind identifies what happened in a container. See Subscribe.
+type EventKind int
+
+const (
+	// EventProvided is sent when a constructor is provided to the container.
+	EventProvided EventKind = iota + 1
+
+	// EventInvoking is sent when Invoke starts building the arguments of a
+	// function.
+	EventInvoking
+
+	// EventConstructing is sent right before a constructor is called.
+	EventConstructing
+
+	// EventConstructed is sent when a constructor returns successfully.
+	EventConstructed
+
+	// EventFailed is sent when a constructor fails.
+	EventFailed
+
+	// EventValueCommitted is sent for every value added to the container by
+	// a constructor, as with OnValueCommitted.
+	EventValueCommitted
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventProvided:
+		return "provided"
+	case EventInvoking:
+		return "invoking"
+	case EventConstructing:
+		return "constructing"
+	case EventConstructed:
+		return "constructed"
+	case EventFailed:
+		return "failed"
+	case EventValueCommitted:
+		return "value committed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes something that happened in a container or one of its
+// scopes. See Subscribe.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+
+	// Key of the committed value. This is only set for EventValueCommitted.
+	Key Key
+
+	// Name, package, and location of the constructor or of the invoked
+	// function. These are not set for EventValueCommitted.
+	Name    string
+	Package string
+	File    string
+	Line    int
+
+	// Time spent in the constructor, and the error it failed with. These are
+	// only set for EventConstructed and EventFailed.
+	Duration time.Duration
+	Err      error
+}
+
+// A SubscribeOption modifies the default behavior of Subscribe.
+type SubscribeOption interface {
+	applySubscribeOption(*subscribeOptions)
+}
+
+type subscribeOptions struct {
+	// Size of the channel.
+	Buffer int
+
+	// Wait for the subscriber instead of dropping events.
+	Block bool
+}
+
+type subscribeOptionFunc func(*subscribeOptions)
+
+func (f subscribeOptionFunc) applySubscribeOption(opts *subscribeOptions) { f(opts) }
+
+// EventBuffer is a SubscribeOption that sets the number of events buffered
+// by the channel returned by Subscribe. Sizes below one are ignored.
+func EventBuffer(n int) SubscribeOption {
+	return subscribeOptionFunc(func(opts *subscribeOptions) {
+		if n > 0 {
+			opts.Buffer = n
+		}
+	})
+}
+
+// BlockOnFullBuffer is a SubscribeOption that makes the container wait for
+// the subscriber to receive events when its buffer is full, instead of
+// dropping the oldest buffered event. Use this only if the subscriber keeps
+// up with the container: construction stalls until it receives the events.
+func BlockOnFullBuffer() SubscribeOption {
+	return subscribeOptionFunc(func(opts *subscribeOptions) {
+		opts.Block = true
+	})
+}
+
+// Subscribe returns a channel that receives the events of the container and
+// its scopes, and a function that stops sending events and closes the
+// channel.
+//
+//   events, cancel := c.Subscribe()
+//   defer cancel()
+//   go func() {
+//     for e := range events {
+//       log.Printf("%v %v.%v", e.Kind, e.Package, e.Name)
+//     }
+//   }()
+//
+// The channel is buffered. When the buffer is full, the oldest buffered
+// event is dropped so that slow subscribers never stall the container. Use
+// BlockOnFullBuffer to wait for the subscriber instead.
+//
+// Both Subscribe and the returned function may be called concurrently with
+// other methods of the container. Containers without subscribers don't pay
+// for events.
+func (c *Container) Subscribe(opts ...SubscribeOption) (<-chan Event, func()) {
+	options := subscribeOptions{Buffer: _defaultEventBuffer}
+	for _, o := range opts {
+		o.applySubscribeOption(&options)
+	}
+
+	sub := &subscriber{
+		ch:    make(chan Event, options.Buffer),
+		done:  make(chan struct{}),
+		block: options.Block,
+	}
+	if c.checkInitialized() != nil {
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+	c.events.add(sub)
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			// Unblock the sends in progress before waiting for them.
+			close(sub.done)
+			c.events.remove(sub)
+			close(sub.ch)
+		})
+	}
+}
+
+// eventStream sends the events of a container and its scopes to their
+// subscribers.
+type eventStream struct {
+	count int32 // accessed atomically
+
+	mu   sync.RWMutex
+	subs []*subscriber
+}
+
+type subscriber struct {
+	ch    chan Event
+	done  chan struct{}
+	block bool
+
+	// Serializes dropping the oldest event with sending.
+	mu sync.Mutex
+}
+
+func (s *eventStream) add(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subs = append(s.subs, sub)
+	atomic.StoreInt32(&s.count, int32(len(s.subs)))
+}
+
+func (s *eventStream) remove(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs := s.subs[:0]
+	for _, other := range s.subs {
+		if other != sub {
+			subs = append(subs, other)
+		}
+	}
+	for i := len(subs); i < len(s.subs); i++ {
+		s.subs[i] = nil
+	}
+	s.subs = subs
+	atomic.StoreInt32(&s.count, int32(len(s.subs)))
+}
+
+// active reports whether anyone subscribed to the events.
+func (s *eventStream) active() bool {
+	return s != nil && atomic.LoadInt32(&s.count) > 0
+}
+
+func (s *eventStream) send(e Event) {
+	e.Time = time.Now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, sub := range s.subs {
+		sub.send(e)
+	}
+}
+
+func (sub *subscriber) send(e Event) {
+	if sub.block {
+		select {
+		case sub.ch <- e:
+		case <-sub.done:
+		}
+		return
+	}
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	for {
+		select {
+		case sub.ch <- e:
+			return
+		default:
+		}
+
+		// Drop the oldest event to make room.
+		select {
+		case <-sub.ch:
+		default:
+		}
+	}
+}
+
+// emit sends an event about the given function to the subscribers of the
+// container, if any.
+func (c *Container) emit(kind EventKind, fn *digreflect.Func, d time.Duration, err error) {
+	if !c.events.active() {
+		return
+	}
+	e := Event{Kind: kind, Duration: d, Err: err}
+	if fn != nil {
+		e.Name, e.Package, e.File, e.Line = fn.Name, fn.Package, fn.File, fn.Line
+	}
+	c.events.send(e)
+}
+
+// subscribed reports whether anyone subscribed to the events of the
+// container.
+func (c *Container) subscribed() bool {
+	return c.events.active()
+}
+
+// emitCommitted sends an EventValueCommitted for the given key to the
+// subscribers of the container, if any.
+func (c *Container) emitCommitted(k key) {
+	if c.events.active() {
+		c.events.send(Event{Kind: EventValueCommitted, Key: k.exported()})
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	type A struct{}
+	type B struct{}
+
+	// drain cancels the subscription and returns the events it received.
+	drain := func(events <-chan Event, cancel func()) []Event {
+		cancel()
+		var got []Event
+		for e := range events {
+			got = append(got, e)
+		}
+		return got
+	}
+	kinds := func(events []Event) []EventKind {
+		ks := make([]EventKind, len(events))
+		for i, e := range events {
+			ks[i] = e.Kind
+		}
+		return ks
+	}
+
+	t.Run("events", func(t *testing.T) {
+		c := New()
+		events, cancel := c.Subscribe()
+
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Provide(func(*A) (*B, error) { return nil, errors.New("great sadness") }))
+		require.Error(t, c.Invoke(func(*B) {}))
+
+		got := drain(events, cancel)
+		assert.Equal(t, []EventKind{
+			EventProvided,
+			EventProvided,
+			EventInvoking,
+			EventConstructing, // A
+			EventConstructed,
+			EventValueCommitted,
+			EventConstructing, // B
+			EventFailed,
+		}, kinds(got))
+
+		assert.Contains(t, got[0].Name, "TestSubscribe")
+		assert.Equal(t, "go.uber.org/dig", got[0].Package)
+		assert.NotEmpty(t, got[0].File)
+		assert.False(t, got[0].Time.IsZero())
+		assert.Equal(t, Key{Type: reflect.TypeOf(&A{})}, got[5].Key)
+		assert.EqualError(t, got[7].Err, "great sadness")
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		c := New()
+		events, cancel := c.Subscribe()
+
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func() *A { return &A{} }, Group("as")))
+		require.NoError(t, s.Invoke(func(struct {
+			In
+
+			As []*A `group:"as"`
+		}) {
+		}))
+
+		got := drain(events, cancel)
+		assert.Equal(t, []EventKind{
+			EventProvided,
+			EventInvoking,
+			EventConstructing,
+			EventConstructed,
+			EventValueCommitted,
+		}, kinds(got))
+		assert.Equal(t, "as", got[4].Key.Group)
+	})
+
+	t.Run("drops the oldest events", func(t *testing.T) {
+		c := New()
+		events, cancel := c.Subscribe(EventBuffer(2))
+		for i := 0; i < 5; i++ {
+			require.NoError(t, c.Provide(func() *A { return &A{} }, Name(string(rune('a'+i)))))
+		}
+
+		got := drain(events, cancel)
+		require.Len(t, got, 2)
+		assert.True(t, got[0].Time.Before(got[1].Time) || got[0].Time.Equal(got[1].Time))
+	})
+
+	t.Run("blocks when full", func(t *testing.T) {
+		c := New()
+		events, cancel := c.Subscribe(EventBuffer(1), BlockOnFullBuffer())
+
+		var got []Event
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range events {
+				got = append(got, e)
+			}
+		}()
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, c.Provide(func() *A { return &A{} }, Name(string(rune('a'+i)))))
+		}
+		cancel()
+		wg.Wait()
+		assert.Len(t, got, 5, "no events must be dropped")
+	})
+
+	t.Run("cancel unblocks the container", func(t *testing.T) {
+		c := New()
+		_, cancel := c.Subscribe(EventBuffer(1), BlockOnFullBuffer())
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 5; i++ {
+				assert.NoError(t, c.Provide(func() *A { return &A{} }, Name(string(rune('a'+i)))))
+			}
+		}()
+
+		cancel()
+		<-done
+		cancel() // must be safe to call twice
+	})
+
+	t.Run("concurrent cancel", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		pool := NewScopePool(c)
+
+		cancels := make([]func(), 10)
+		for i := range cancels {
+			_, cancels[i] = c.Subscribe(EventBuffer(1))
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				s := pool.Get()
+				assert.NoError(t, s.Provide(func() *B { return &B{} }))
+				assert.NoError(t, s.Invoke(func(*A, *B) {}))
+				pool.Put(s)
+			}
+		}()
+		for _, cancel := range cancels {
+			wg.Add(1)
+			go func(cancel func()) {
+				defer wg.Done()
+				cancel()
+			}(cancel)
+		}
+		wg.Wait()
+		assert.False(t, c.subscribed())
+	})
+
+	t.Run("String", func(t *testing.T) {
+		assert.Equal(t, "value committed", EventValueCommitted.String())
+		assert.Equal(t, "unknown", EventKind(0).String())
+	})
+}
@@ -21,9 +21,12 @@
 package dig_test
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"go.uber.org/dig"
 )
@@ -67,3 +70,48 @@ func Example_minimal() {
 	// Output:
 	// [foo] You've been invoked
 }
+
+func ExampleContainer_Subscribe() {
+	type Config struct{}
+	type DB struct{}
+	type Server struct{}
+
+	c := dig.New()
+	for _, ctor := range []interface{}{
+		func() *Config { return &Config{} },
+		func(*Config) *DB { return &DB{} },
+		func(*Config, *DB) *Server { return &Server{} },
+	} {
+		if err := c.Provide(ctor); err != nil {
+			panic(err)
+		}
+	}
+	total := len(dig.Graph(c).Ctors)
+
+	// Print the progress of WarmUp as constructors return. A terminal UI
+	// would redraw the same line instead.
+	events, cancel := c.Subscribe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		var built int
+		for e := range events {
+			if e.Kind == dig.EventConstructed {
+				built++
+				fmt.Printf("[%-*s] %d/%d\n", total, strings.Repeat("#", built), built, total)
+			}
+		}
+	}()
+
+	if err := c.WarmUp(context.Background()); err != nil {
+		panic(err)
+	}
+	cancel()
+	<-done
+
+	// Output:
+	// [#  ] 1/3
+	// [## ] 2/3
+	// [###] 3/3
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+// IfNotPresent is a ProvideOption that provides the constructor as a
+// fallback: it's only used for the values that no other constructor
+// provides. Libraries use it to ship defaults that applications may
+// override.
+//
+//   c.Provide(NewNoopMetrics, dig.IfNotPresent())
+//   c.Provide(NewPrometheusMetrics) // supersedes NewNoopMetrics
+//
+// Other constructors may provide the same values before or after the
+// fallback without failing with an "already provided" error. A fallback
+// can't be superseded once it was called, and two fallbacks for the same
+// value conflict like any other constructors.
+//
+// Fallbacks provided to a scope are also superseded by the constructors of
+// its parents, but a scope may not supersede the fallbacks of its parents.
+//
+// This option only applies to values, and cannot be combined with Group or
+// AsGroup.
+func IfNotPresent() ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.IfNotPresent = true
+	})
+}
+
+// Fallback reports whether this constructor was provided with IfNotPresent.
+func (n *node) Fallback() bool { return n.fallback }
+
+// withoutFallbacks returns the given providers of a value without the
+// fallbacks, unless only fallbacks provide the value.
+func withoutFallbacks(providers []provider) []provider {
+	var fallbacks int
+	for _, p := range providers {
+		if p.Fallback() {
+			fallbacks++
+		}
+	}
+	if fallbacks == 0 || fallbacks == len(providers) {
+		return providers
+	}
+
+	filtered := make([]provider, 0, len(providers)-fallbacks)
+	for _, p := range providers {
+		if !p.Fallback() {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// supersedes reports whether a new constructor n providing a value doesn't
+// conflict with the existing provider p of the same value in the container
+// c, because one of them is a superseded fallback.
+func supersedes(c *Container, n *node, p provider) bool {
+	if n.fallback {
+		// New fallbacks are superseded by all other constructors.
+		return !p.Fallback()
+	}
+	// New constructors supersede the fallbacks of their own container.
+	return p.Fallback() && p.OrigScope() == c
+}
+
+// supersedeFallbacks stops the superseded fallbacks for the given value,
+// which was just provided by n, from producing it.
+func (c *Container) supersedeFallbacks(n *node, k key) {
+	for _, p := range c.getProviders(k) {
+		if p == provider(n) || !supersedes(c, n, p) {
+			continue
+		}
+
+		fallback := n
+		if !n.fallback {
+			fallback = p.(*node)
+		}
+		if fallback.removedKeys == nil {
+			fallback.removedKeys = make(map[key]struct{})
+		}
+		fallback.removedKeys[k] = struct{}{}
+		if fallback.ambient && fallback.called {
+			// Ambient values are superseded even once they were used.
+			c.invalidate(k)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIfNotPresent(t *testing.T) {
+	type Metrics struct{ name string }
+	type Logger struct{ name string }
+
+	newNoop := func() *Metrics { return &Metrics{name: "noop"} }
+	newReal := func() *Metrics { return &Metrics{name: "real"} }
+
+	assertMetrics := func(t *testing.T, c *Container, want string) {
+		require.NoError(t, c.Invoke(func(m *Metrics) {
+			assert.Equal(t, want, m.name)
+		}))
+	}
+
+	t.Run("fallback only", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newNoop, IfNotPresent()))
+		assertMetrics(t, c, "noop")
+	})
+
+	t.Run("fallback first", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newNoop, IfNotPresent()))
+		require.NoError(t, c.Provide(newReal))
+		assertMetrics(t, c, "real")
+	})
+
+	t.Run("real first", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newReal))
+		require.NoError(t, c.Provide(newNoop, IfNotPresent()))
+		assertMetrics(t, c, "real")
+	})
+
+	t.Run("two fallbacks", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newNoop, IfNotPresent()))
+		err := c.Provide(newNoop, IfNotPresent())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already provided by")
+	})
+
+	t.Run("two real providers", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newNoop, IfNotPresent()))
+		require.NoError(t, c.Provide(newReal))
+		err := c.Provide(newReal)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already provided by")
+	})
+
+	t.Run("fallback already called", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newNoop, IfNotPresent()))
+		assertMetrics(t, c, "noop")
+
+		err := c.Provide(newReal)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "was already called")
+	})
+
+	t.Run("partially superseded fallback", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() (*Metrics, *Logger) {
+			return &Metrics{name: "noop"}, &Logger{name: "noop"}
+		}, IfNotPresent()))
+		require.NoError(t, c.Provide(func() *Logger { return &Logger{name: "real"} }))
+
+		require.NoError(t, c.Invoke(func(m *Metrics, l *Logger) {
+			assert.Equal(t, "noop", m.name)
+			assert.Equal(t, "real", l.name)
+		}))
+	})
+
+	t.Run("scope fallback superseded by parent", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newReal))
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(newNoop, IfNotPresent()))
+		assertMetrics(t, s, "real")
+	})
+
+	t.Run("scope cannot supersede parent fallback", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newNoop, IfNotPresent()))
+		s := c.Scope("child")
+		err := s.Provide(newReal)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already provided by")
+	})
+
+	t.Run("value groups", func(t *testing.T) {
+		c := New()
+		err := c.Provide(newNoop, IfNotPresent(), Group("metrics"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot use dig.IfNotPresent with value groups")
+	})
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package dig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// WithFallbackResolver is an Option that asks the given function for values
+// that nothing in the container provides, such as configuration values
+// held by an external service, instead of failing.
+//
+//   c := dig.New(dig.WithFallbackResolver(func(k dig.Key) (interface{}, bool, error) {
+//     return configService.Lookup(k.Type.String(), k.Name)
+//   }))
+//
+// The function is only called for values that are not optional, that no
+// constructor provides, and that no other resolver such as ProvideGeneric
+// supports. It's never called for value groups. It reports whether it has
+// the value, and the value it returns must be assignable to the requested
+// type. If it doesn't have the value or fails, the value is reported as
+// missing along with the failure.
+//
+// By default, the function is called every time one of its values is
+// needed. Use CacheFallbackValues to keep the values it returns in the
+// container instead. Scopes inherit the resolver of their parents.
+func WithFallbackResolver(resolve func(Key) (interface{}, bool, error), opts ...FallbackResolverOption) Option {
+	fr := &fallbackResolver{resolve: resolve}
+	for _, o := range opts {
+		o.applyFallbackResolverOption(fr)
+	}
+	return optionFunc(func(c *Container) {
+		if resolve == nil {
+			c.fallback = nil
+			return
+		}
+		c.fallback = fr
+	})
+}
+
+// A FallbackResolverOption modifies the default behavior of
+// WithFallbackResolver.
+type FallbackResolverOption interface {
+	applyFallbackResolverOption(*fallbackResolver)
+}
+
+type fallbackResolverOptionFunc func(*fallbackResolver)
+
+func (f fallbackResolverOptionFunc) applyFallbackResolverOption(fr *fallbackResolver) { f(fr) }
+
+// CacheFallbackValues is a FallbackResolverOption that keeps the values
+// returned by the resolver in the container that requested them, as if they
+// had been provided, so that the resolver is asked for each value only once.
+func CacheFallbackValues() FallbackResolverOption {
+	return fallbackResolverOptionFunc(func(fr *fallbackResolver) {
+		fr.cache = true
+	})
+}
+
+// fallbackResolver asks an external function for values that nothing
+// provides. See WithFallbackResolver.
+type fallbackResolver struct {
+	resolve func(Key) (interface{}, bool, error)
+	cache   bool
+}
+
+// Resolve asks the resolver for the value with the given key, and reports
+// whether it has the value.
+func (fr *fallbackResolver) Resolve(c containerStore, k key) (reflect.Value, bool, error) {
+	x, ok, err := fr.resolve(k.exported())
+	if err != nil || !ok {
+		return _noValue, false, err
+	}
+
+	v := reflect.ValueOf(x)
+	if !v.IsValid() || !v.Type().AssignableTo(k.t) {
+		return _noValue, false, fmt.Errorf("it returned %v (type %T), which is not assignable to %v", x, x, k.t)
+	}
+	if fr.cache {
+		if err := c.setValue(k.name, k.t, v); err != nil {
+			return _noValue, false, err
+		}
+	}
+	return v, true, nil
+}
+
+func (c *Container) fallbackResolver() *fallbackResolver {
+	return c.fallback
+}
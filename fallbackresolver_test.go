@@ -0,0 +1,140 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package dig
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithFallbackResolver(t *testing.T) {
+	type DBConfig struct{ Host string }
+	type Server struct{ DB *DBConfig }
+
+	configs := map[Key]interface{}{
+		{Type: reflect.TypeOf(&DBConfig{})}:                  &DBConfig{Host: "db"},
+		{Type: reflect.TypeOf(&DBConfig{}), Name: "replica"}: &DBConfig{Host: "replica"},
+	}
+	newResolver := func(calls *int) func(Key) (interface{}, bool, error) {
+		return func(k Key) (interface{}, bool, error) {
+			*calls++
+			v, ok := configs[k]
+			return v, ok, nil
+		}
+	}
+
+	t.Run("resolves missing values", func(t *testing.T) {
+		var calls int
+		c := New(WithFallbackResolver(newResolver(&calls)))
+		require.NoError(t, c.Provide(func(cfg *DBConfig) *Server { return &Server{DB: cfg} }))
+		require.NoError(t, c.Invoke(func(s *Server, p struct {
+			In
+
+			Replica *DBConfig `name:"replica"`
+		}) {
+			assert.Equal(t, "db", s.DB.Host)
+			assert.Equal(t, "replica", p.Replica.Host)
+		}))
+		assert.Equal(t, 2, calls)
+
+		require.NoError(t, c.Invoke(func(*DBConfig) {}))
+		assert.Equal(t, 3, calls, "values must not be cached by default")
+	})
+
+	t.Run("cached values", func(t *testing.T) {
+		var calls int
+		c := New(WithFallbackResolver(newResolver(&calls), CacheFallbackValues()))
+		var first, second *DBConfig
+		require.NoError(t, c.Invoke(func(cfg *DBConfig) { first = cfg }))
+		require.NoError(t, c.Invoke(func(cfg *DBConfig) { second = cfg }))
+		assert.Equal(t, 1, calls)
+		assert.True(t, first == second, "cached values must be reused")
+	})
+
+	t.Run("provided values take precedence", func(t *testing.T) {
+		var calls int
+		c := New(WithFallbackResolver(newResolver(&calls)))
+		require.NoError(t, c.Provide(func() *DBConfig { return &DBConfig{Host: "provided"} }))
+		require.NoError(t, c.Invoke(func(cfg *DBConfig) {
+			assert.Equal(t, "provided", cfg.Host)
+		}))
+		assert.Zero(t, calls)
+	})
+
+	t.Run("optional values and groups are not resolved", func(t *testing.T) {
+		var calls int
+		c := New(WithFallbackResolver(newResolver(&calls)))
+		require.NoError(t, c.Invoke(func(p struct {
+			In
+
+			DB      *DBConfig   `optional:"true"`
+			Configs []*DBConfig `group:"configs"`
+		}) {
+			assert.Nil(t, p.DB)
+			assert.Empty(t, p.Configs)
+		}))
+		assert.Zero(t, calls)
+	})
+
+	t.Run("scopes inherit the resolver", func(t *testing.T) {
+		var calls int
+		c := New(WithFallbackResolver(newResolver(&calls)))
+		require.NoError(t, c.Scope("child").Invoke(func(*DBConfig) {}))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("missing values", func(t *testing.T) {
+		var calls int
+		c := New(WithFallbackResolver(newResolver(&calls)))
+		err := c.Invoke(func(string) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestWithFallbackResolver\S+`,
+			`type string is not in the container, and the fallback resolver doesn't have it`)
+	})
+
+	t.Run("resolver errors", func(t *testing.T) {
+		c := New(WithFallbackResolver(func(Key) (interface{}, bool, error) {
+			return nil, false, errors.New("connection refused")
+		}))
+		require.NoError(t, c.Provide(func(cfg *DBConfig) *Server { return &Server{DB: cfg} }))
+		err := c.Invoke(func(*Server) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestWithFallbackResolver\S+`,
+			`could not build arguments for function "go.uber.org/dig".TestWithFallbackResolver\S+`,
+			`type \*dig.DBConfig is not in the container, and the fallback resolver failed: connection refused`)
+		assert.Equal(t, "connection refused", errors.Unwrap(RootCause(err)).Error())
+	})
+
+	t.Run("values of the wrong type", func(t *testing.T) {
+		c := New(WithFallbackResolver(func(Key) (interface{}, bool, error) {
+			return "db", true, nil
+		}))
+		err := c.Invoke(func(*DBConfig) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(),
+			"the fallback resolver failed: it returned db (type string), which is not assignable to *dig.DBConfig")
+	})
+}
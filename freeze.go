@@ -0,0 +1,116 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// FreezeAfterFirstInvoke is an Option that freezes the container when Invoke
+// is first called on it, as if by Freeze.
+//
+//   c := dig.New(dig.FreezeAfterFirstInvoke())
+//
+// Scopes inherit this option and are frozen by their own first Invoke.
+func FreezeAfterFirstInvoke() Option {
+	return optionFunc(func(c *Container) {
+		c.freezeOnInvoke = true
+	})
+}
+
+// FreezeScopes is an Option that extends the effects of Freeze to the scopes
+// of the container: once the container is frozen, they may not change their
+// constructors either.
+func FreezeScopes() Option {
+	return optionFunc(func(c *Container) {
+		c.freezeScopes = true
+	})
+}
+
+// FrozenContainerError is returned by the methods that change the
+// constructors of a container, such as Provide and RemoveNamed, after the
+// container was frozen.
+type FrozenContainerError struct {
+	// Name of the frozen scope. This is empty if a root container was
+	// frozen.
+	Scope string
+
+	// Location of the call to Freeze or Invoke that froze the container.
+	File string
+	Line int
+}
+
+func (e FrozenContainerError) Error() string {
+	if e.Scope == "" {
+		return fmt.Sprintf("cannot change dig.Container after it was frozen at %v:%v", e.File, e.Line)
+	}
+	return fmt.Sprintf("cannot change scope %q after it was frozen at %v:%v", e.Scope, e.File, e.Line)
+}
+
+// Freeze prevents further changes to the constructors of the container, so
+// that the values it builds can't depend on what is provided after startup.
+//
+//   if err := c.Freeze(); err != nil {
+//     return err
+//   }
+//
+// Once frozen, Provide, ProvideGeneric, RemoveNamed, RemoveAll, and Mount
+// fail with a FrozenContainerError, while Invoke and the methods that
+// inspect the container keep working. Scopes of a frozen container may still
+// provide their own constructors unless the container was built with
+// FreezeScopes.
+//
+// Freeze verifies that the container has no dependency cycles if this was
+// deferred with DeferAcyclicVerification, and leaves the container unfrozen
+// if it finds one. Freezing a container again has no effect.
+func (c *Container) Freeze() error {
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+	return c.freeze(digreflect.InspectCaller(1))
+}
+
+func (c *Container) freeze(caller *digreflect.Func) error {
+	if c.frozenAt != nil {
+		return nil
+	}
+	if err := c.verifyChainAcyclic(); err != nil {
+		return err
+	}
+	c.frozenAt = caller
+	return nil
+}
+
+// checkMutable returns an error if the constructors of this container may
+// no longer be changed.
+func (c *Container) checkMutable() error {
+	for s := c; s != nil; s = s.parent {
+		if s.frozenAt != nil {
+			return FrozenContainerError{Scope: s.name, File: s.frozenAt.File, Line: s.frozenAt.Line}
+		}
+		if !s.freezeScopes {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeze(t *testing.T) {
+	type A struct{}
+	type B struct{}
+
+	assertFrozen := func(t *testing.T, err error, msg string) {
+		require.Error(t, err)
+		var ferr FrozenContainerError
+		require.True(t, errors.As(err, &ferr), "expected a FrozenContainerError, got %v", err)
+		assert.Contains(t, ferr.File, "freeze_test.go")
+		assert.Contains(t, err.Error(), msg)
+	}
+
+	t.Run("explicit", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Provide(func() *B { return &B{} }, Name("b")))
+		require.NoError(t, c.Freeze())
+		require.NoError(t, c.Freeze(), "freezing again must succeed")
+
+		assertFrozen(t, c.Provide(func() *B { return &B{} }), "cannot change dig.Container after it was frozen at ")
+		assertFrozen(t, c.ProvideGeneric(func(reflect.Type) (interface{}, bool) { return nil, false }), "frozen")
+		assertFrozen(t, c.RemoveNamed((**B)(nil), "b"), "frozen")
+		_, err := c.RemoveAll((**A)(nil))
+		assertFrozen(t, err, "frozen")
+		assertFrozen(t, c.Mount("m", New()), "frozen")
+
+		require.NoError(t, c.Invoke(func(*A) {}), "Invoke must keep working")
+		assert.Len(t, Graph(c).Ctors, 2)
+	})
+
+	t.Run("after first invoke", func(t *testing.T) {
+		c := New(FreezeAfterFirstInvoke())
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Provide(func() *B { return &B{} }))
+		require.NoError(t, c.Invoke(func(*A) {}))
+
+		err := c.Provide(func() string { return "" })
+		assertFrozen(t, err, "cannot change dig.Container after it was frozen at ")
+
+		var ferr FrozenContainerError
+		require.True(t, errors.As(err, &ferr))
+		require.NoError(t, c.Invoke(func(*B) {}))
+		assert.Equal(t, ferr, c.Provide(func() string { return "" }), "the first Invoke must be reported")
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		c := New()
+		s := c.Scope("child")
+		require.NoError(t, c.Freeze())
+		require.NoError(t, s.Provide(func() *A { return &A{} }), "scopes may still provide")
+
+		require.NoError(t, s.Freeze())
+		assertFrozen(t, s.Provide(func() *B { return &B{} }), `cannot change scope "child" after it was frozen at `)
+	})
+
+	t.Run("FreezeScopes", func(t *testing.T) {
+		c := New(FreezeScopes())
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func() *A { return &A{} }), "scopes of unfrozen containers may provide")
+		require.NoError(t, c.Freeze())
+		assertFrozen(t, s.Provide(func() *B { return &B{} }), "cannot change dig.Container after it was frozen at ")
+		assertFrozen(t, c.Scope("other").Provide(func() *B { return &B{} }), "frozen")
+	})
+
+	t.Run("verifies cycles", func(t *testing.T) {
+		c := New(DeferAcyclicVerification())
+		require.NoError(t, c.Provide(func(*B) *A { return &A{} }))
+		require.NoError(t, c.Provide(func(*A) *B { return &B{} }))
+
+		err := c.Freeze()
+		require.Error(t, err)
+		assert.True(t, IsCycleDetected(err))
+		require.NoError(t, c.Provide(func() string { return "" }), "container must not be frozen")
+	})
+
+	t.Run("pooled scopes", func(t *testing.T) {
+		pool := NewScopePool(New(FreezeAfterFirstInvoke()))
+		s := pool.Get()
+		require.NoError(t, s.Invoke(func() {}))
+		assertFrozen(t, s.Provide(func() *A { return &A{} }), `cannot change scope "pooled"`)
+		pool.Put(s)
+
+		s = pool.Get()
+		require.NoError(t, s.Provide(func() *A { return &A{} }), "Put must unfreeze the scope")
+		pool.Put(s)
+	})
+}
@@ -0,0 +1,424 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"io"
+
+	"go.uber.org/dig/internal/dot"
+)
+
+// GraphView is a read-only, structured view of the dependency graph of a
+// Container. It holds the same information that Visualize renders in the DOT
+// format.
+//
+// Constructors are listed in the order in which they were provided to the
+// container, and their parameters and results are listed in the order in
+// which they are declared. Value groups are listed in the order in which they
+// were first referenced by a constructor.
+type GraphView struct {
+	// Constructors provided to the container.
+	Ctors []GraphCtor
+
+	// Value groups produced or consumed by constructors in the container.
+	Groups []GraphGroup
+
+	// Nodes that failed to build. These are empty unless the view was built
+	// with VisualizeError or retrieved with GraphFromError.
+	RootCauses         []GraphNode
+	TransitiveFailures []GraphNode
+
+	// Snapshot this view was built from and the options it was built with.
+	// These are used to render the view with VisualizeGraph.
+	snapshot graphSnapshot
+	opts     []VisualizeOption
+}
+
+// GraphFailure specifies whether and how a constructor or value group failed
+// to build.
+type GraphFailure int
+
+const (
+	// NoFailure indicates that no failure was recorded.
+	NoFailure GraphFailure = iota
+
+	// RootCauseFailure indicates that the failure was the root cause of the
+	// error.
+	RootCauseFailure
+
+	// TransitiveFailure indicates that the failure was caused by the failure
+	// of one of its dependencies.
+	TransitiveFailure
+)
+
+// GraphCtor is a constructor in a GraphView.
+type GraphCtor struct {
+	// ID uniquely identifies this constructor inside the container.
+	ID uintptr
+
+	// Name, package, and location at which the constructor was defined.
+	Name    string
+	Package string
+	File    string
+	Line    int
+
+	// Name of the scope to which the constructor was provided. This is
+	// empty for constructors provided to the root container.
+	Scope string
+
+	// Description of the bridge between a value group and named values
+	// the constructor belongs to, if it was provided by
+	// Container.BridgeGroupToNames or Container.BridgeNamesToGroup.
+	Bridge string `json:",omitempty"`
+
+	// Whether the constructor provides an ambient value. See
+	// WithAmbientValues.
+	Ambient bool `json:",omitempty"`
+
+	// Whether the constructor was provided with When and is disabled by
+	// its condition.
+	Disabled bool `json:",omitempty"`
+
+	// Prefix of the container mounted with Container.Mount in which the
+	// constructor builds its values. This is empty for other constructors.
+	Mount string
+
+	// Whether the constructor was provided to a parent of the scope the
+	// view was built for.
+	Inherited bool
+
+	// Number of positional arguments accepted by this constructor. Values
+	// consumed through dig.In structs are listed in Params, not counted here.
+	NumArgs int
+
+	// Values consumed by this constructor.
+	Params []GraphParam
+
+	// Value groups consumed by this constructor.
+	GroupParams []GraphGroupRef
+
+	// Values produced by this constructor.
+	Results []GraphNode
+
+	// Description of the constructor provided with Doc.
+	Doc string `json:",omitempty"`
+
+	Failure GraphFailure
+}
+
+// GraphNode is a value in a GraphView. Only one of Name and Group is set.
+type GraphNode struct {
+	// Type of the value as printed by reflect.Type.String.
+	Type string
+
+	Name  string
+	Group string
+
+	// Position of this value among all values of its group. This is always
+	// zero for values that aren't part of a group.
+	GroupIndex int
+
+	// Whether the value is an unbuffered channel that its constructor
+	// wasn't provided with AllowUnbuffered for. This is only known once the
+	// constructor was called.
+	Unbuffered bool `json:",omitempty"`
+
+	// Description of the value from the `doc:".."` tag of the field that
+	// consumes or produces it.
+	Doc string `json:",omitempty"`
+}
+
+// GraphParam is a value consumed by a constructor.
+type GraphParam struct {
+	GraphNode
+
+	Optional bool
+}
+
+// GraphGroupRef refers to a value group consumed by a constructor.
+type GraphGroupRef struct {
+	// Type of the values in the group.
+	Type string
+
+	Name string
+}
+
+// GraphGroup is a value group in a GraphView.
+type GraphGroup struct {
+	GraphGroupRef
+
+	// Values submitted into this group.
+	Results []GraphNode
+
+	Failure GraphFailure
+}
+
+// Graph returns a structured view of the dependency graph of the container.
+//
+// Failures may be included in the view with the VisualizeError option.
+// Like Visualize, Graph may be called while constructors are being provided
+// to c from another goroutine.
+//
+//   if err := c.Invoke(...); err != nil {
+//     g := dig.Graph(c, dig.VisualizeError(err))
+//     // ...
+//   }
+func Graph(c *Container, opts ...VisualizeOption) *GraphView {
+	return newGraphView(c.snapshotGraph(), opts)
+}
+
+// AttachGraphToErrors is an Option that attaches a snapshot of the
+// dependency graph to errors returned by Invoke. Use GraphFromError to
+// retrieve it after the Container is no longer at hand.
+//
+//   c := dig.New(dig.AttachGraphToErrors())
+//   // ...
+//   if gv, ok := dig.GraphFromError(err); ok {
+//     dig.VisualizeGraph(gv, w)
+//   }
+//
+// The snapshot only describes the constructors of the container. It does not
+// reference the container or the values it built, and is only taken when
+// Invoke fails.
+func AttachGraphToErrors() Option {
+	return optionFunc(func(c *Container) {
+		c.attachGraphToErrors = true
+	})
+}
+
+// GraphFromError returns the graph attached to the given error with the
+// failure described by the error included in it. It returns false if the
+// error was not returned by Invoke on a Container built with
+// AttachGraphToErrors.
+func GraphFromError(err error) (*GraphView, bool) {
+	for e := err; e != nil; {
+		if eg, ok := e.(errWithGraph); ok {
+			return newGraphView(eg.snapshot, []VisualizeOption{VisualizeError(err)}), true
+		}
+		c, ok := e.(causer)
+		if !ok {
+			break
+		}
+		e = c.cause()
+	}
+	return nil, false
+}
+
+// VisualizeGraph writes the given view in DOT format to w, like Visualize.
+// The view must have been returned by Graph or GraphFromError.
+//
+// Failures included in the view are rendered unless they are replaced with
+// the VisualizeError option.
+func VisualizeGraph(gv *GraphView, w io.Writer, opts ...VisualizeOption) error {
+	opts = append(gv.opts[:len(gv.opts):len(gv.opts)], opts...)
+	return renderGraph(w, gv.snapshot, opts)
+}
+
+// graphSnapshot is a copy of the constructors of a container from which its
+// dependency graph can be built. It only holds the description of the
+// constructors, so it does not keep the container or the values it built
+// reachable.
+type graphSnapshot struct {
+	ctors []ctorSnapshot
+
+	// Number of leading constructors that were provided to parents of the
+	// container rather than the container itself.
+	inherited int
+}
+
+// ctorSnapshot describes a constructor in a graphSnapshot.
+type ctorSnapshot struct {
+	ctor       dot.Ctor
+	params     []dot.Param
+	results    []dot.Result
+	numArgs    int
+	unbuffered map[key]struct{}
+}
+
+func newCtorSnapshot(n *node) ctorSnapshot {
+	cs := ctorSnapshot{
+		ctor:    *newDotCtor(n),
+		numArgs: len(n.paramList.Params),
+	}
+	for _, p := range n.paramList.DotParam() {
+		cs.params = append(cs.params, *p)
+	}
+	for _, r := range n.resultList.DotResult() {
+		cs.results = append(cs.results, *r)
+		if r.Group == "" && n.isUnbuffered(key{name: r.Name, t: r.Type}) {
+			if cs.unbuffered == nil {
+				cs.unbuffered = make(map[key]struct{})
+			}
+			cs.unbuffered[key{name: r.Name, t: r.Type}] = struct{}{}
+		}
+	}
+	return cs
+}
+
+// snapshotGraph takes a snapshot of the constructors of this container and
+// its parents. It may be called while constructors are provided from other
+// goroutines.
+func (c *Container) snapshotGraph() graphSnapshot {
+	var gs graphSnapshot
+	for _, s := range c.scopeChain() {
+		gs.inherited = len(gs.ctors)
+		s.nodesMu.RLock()
+		nodes := s.nodes
+		s.nodesMu.RUnlock()
+		for _, n := range nodes {
+			gs.ctors = append(gs.ctors, newCtorSnapshot(n))
+		}
+	}
+	return gs
+}
+
+func (gs graphSnapshot) build() *dot.Graph {
+	dg := dot.NewGraph()
+	for i, cs := range gs.ctors {
+		// The graph modifies the constructors, params, and results added
+		// to it, so each build gets its own copies.
+		ctor := cs.ctor
+		ctor.Inherited = i < gs.inherited
+		params := make([]*dot.Param, len(cs.params))
+		for j := range cs.params {
+			p := cs.params[j]
+			params[j] = &p
+		}
+		results := make([]*dot.Result, len(cs.results))
+		for j := range cs.results {
+			r := cs.results[j]
+			results[j] = &r
+		}
+		dg.AddCtor(&ctor, params, results)
+	}
+	return dg
+}
+
+// errWithGraph is an error returned by Invoke with a snapshot of the graph
+// attached to it. See AttachGraphToErrors.
+type errWithGraph struct {
+	err      error
+	snapshot graphSnapshot
+}
+
+func (e errWithGraph) cause() error  { return e.err }
+func (e errWithGraph) Unwrap() error { return e.err }
+
+func (e errWithGraph) Error() string { return e.err.Error() }
+
+func (e errWithGraph) Format(w fmt.State, c rune) { formatCause(e, e.err, w, c) }
+
+func newGraphView(gs graphSnapshot, opts []VisualizeOption) *GraphView {
+	dg := newVisualizedGraph(gs, opts)
+
+	gv := &GraphView{
+		Ctors:              make([]GraphCtor, len(dg.Ctors)),
+		Groups:             make([]GraphGroup, len(dg.Groups)),
+		RootCauses:         newGraphNodes(dg.Failed.RootCauses),
+		TransitiveFailures: newGraphNodes(dg.Failed.TransitiveFailures),
+		snapshot:           gs,
+		opts:               opts,
+	}
+
+	for i, ctor := range dg.Ctors {
+		// Constructors are added to the graph in the order of the snapshot.
+		gc := GraphCtor{
+			ID:        uintptr(ctor.ID),
+			Name:      ctor.Name,
+			Package:   ctor.Package,
+			File:      ctor.File,
+			Line:      ctor.Line,
+			Scope:     ctor.Scope,
+			Mount:     ctor.Mount,
+			Bridge:    ctor.Bridge,
+			Ambient:   ctor.Ambient,
+			Disabled:  ctor.Disabled,
+			Inherited: ctor.Inherited,
+			Doc:       ctor.Doc,
+			NumArgs:   gs.ctors[i].numArgs,
+			Params:    make([]GraphParam, len(ctor.Params)),
+			Results:   newGraphNodes(ctor.Results),
+			Failure:   newGraphFailure(ctor.ErrorType),
+		}
+		for j, p := range ctor.Params {
+			gc.Params[j] = GraphParam{
+				GraphNode: newGraphNode(p.Node, 0),
+				Optional:  p.Optional,
+			}
+			gc.Params[j].Doc = p.Doc
+		}
+		for _, g := range ctor.GroupParams {
+			gc.GroupParams = append(gc.GroupParams, newGraphGroupRef(g))
+		}
+		for j, r := range ctor.Results {
+			if r.Group != "" {
+				continue
+			}
+			_, gc.Results[j].Unbuffered = gs.ctors[i].unbuffered[key{name: r.Name, t: r.Type}]
+		}
+		gv.Ctors[i] = gc
+	}
+
+	for i, g := range dg.Groups {
+		gv.Groups[i] = GraphGroup{
+			GraphGroupRef: newGraphGroupRef(g),
+			Results:       newGraphNodes(g.Results),
+			Failure:       newGraphFailure(g.ErrorType),
+		}
+	}
+
+	return gv
+}
+
+func newGraphNode(n *dot.Node, groupIndex int) GraphNode {
+	return GraphNode{
+		Type:       n.Type.String(),
+		Name:       n.Name,
+		Group:      n.Group,
+		GroupIndex: groupIndex,
+	}
+}
+
+func newGraphNodes(results []*dot.Result) []GraphNode {
+	var nodes []GraphNode
+	for _, r := range results {
+		n := newGraphNode(r.Node, r.GroupIndex)
+		n.Doc = r.Doc
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+func newGraphGroupRef(g *dot.Group) GraphGroupRef {
+	return GraphGroupRef{Type: g.Type.String(), Name: g.Name}
+}
+
+func newGraphFailure(t dot.ErrorType) GraphFailure {
+	switch {
+	case t.IsRootCause():
+		return RootCauseFailure
+	case t.IsTransitiveFailure():
+		return TransitiveFailure
+	default:
+		return NoFailure
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraph(t *testing.T) {
+	type t1 struct{}
+	type t2 struct{}
+	type t3 struct{}
+
+	t.Run("empty container", func(t *testing.T) {
+		g := Graph(New())
+		assert.Empty(t, g.Ctors)
+		assert.Empty(t, g.Groups)
+		assert.Empty(t, g.RootCauses)
+		assert.Empty(t, g.TransitiveFailures)
+	})
+
+	t.Run("constructors, params and results", func(t *testing.T) {
+		type in struct {
+			In
+
+			A t1   `name:"foo"`
+			B t2   `optional:"true"`
+			C []t3 `group:"bar"`
+		}
+
+		type out struct {
+			Out
+
+			C t3 `group:"bar"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() (t1, t2) { return t1{}, t2{} }, Name("foo"), NameAllResults()))
+		require.NoError(t, c.Provide(func() out { return out{} }))
+		require.NoError(t, c.Provide(func(in) string { return "" }))
+
+		g := Graph(c)
+		require.Len(t, g.Ctors, 3)
+
+		first := g.Ctors[0]
+		assert.Equal(t, "TestGraph.func2.1", first.Name)
+		assert.Equal(t, "go.uber.org/dig", first.Package)
+		assert.Contains(t, first.File, "graph_test.go")
+		assert.NotZero(t, first.Line)
+		assert.NotZero(t, first.ID)
+		assert.Empty(t, first.Params)
+		assert.Equal(t, []GraphNode{
+			{Type: "dig.t1", Name: "foo"},
+			{Type: "dig.t2", Name: "foo"},
+		}, first.Results)
+		assert.Equal(t, NoFailure, first.Failure)
+
+		assert.Equal(t, []GraphNode{
+			{Type: "dig.t3", Group: "bar"},
+		}, g.Ctors[1].Results)
+
+		last := g.Ctors[2]
+		assert.Equal(t, []GraphParam{
+			{GraphNode: GraphNode{Type: "dig.t1", Name: "foo"}},
+			{GraphNode: GraphNode{Type: "dig.t2"}, Optional: true},
+		}, last.Params)
+		assert.Equal(t, []GraphGroupRef{{Type: "dig.t3", Name: "bar"}}, last.GroupParams)
+		assert.Equal(t, []GraphNode{{Type: "string"}}, last.Results)
+
+		assert.Equal(t, []GraphGroup{
+			{
+				GraphGroupRef: GraphGroupRef{Type: "dig.t3", Name: "bar"},
+				Results:       []GraphNode{{Type: "dig.t3", Group: "bar"}},
+			},
+		}, g.Groups)
+	})
+
+	t.Run("docs", func(t *testing.T) {
+		type out struct {
+			Out
+
+			A t1 `name:"primary" doc:"primary OLTP database"`
+		}
+		type in struct {
+			In
+
+			A t1 `name:"primary" doc:"where orders are stored"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() out { return out{} }, Doc("opens the databases")))
+		require.NoError(t, c.Provide(func(in) t2 { return t2{} }))
+
+		g := Graph(c)
+		require.Len(t, g.Ctors, 2)
+		assert.Equal(t, "opens the databases", g.Ctors[0].Doc)
+		assert.Equal(t, []GraphNode{
+			{Type: "dig.t1", Name: "primary", Doc: "primary OLTP database"},
+		}, g.Ctors[0].Results)
+		assert.Empty(t, g.Ctors[1].Doc)
+		assert.Equal(t, []GraphParam{
+			{GraphNode: GraphNode{Type: "dig.t1", Name: "primary", Doc: "where orders are stored"}},
+		}, g.Ctors[1].Params)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() (t1, error) { return t1{}, errors.New("great sadness") }))
+		require.NoError(t, c.Provide(func(t1) t2 { return t2{} }))
+
+		err := c.Invoke(func(t2) {})
+		require.Error(t, err, "invoke must fail")
+
+		assert.Empty(t, Graph(c).RootCauses, "failures must not be included without VisualizeError")
+
+		g := Graph(c, VisualizeError(err))
+		assert.Equal(t, []GraphNode{{Type: "dig.t1"}}, g.RootCauses)
+		assert.Equal(t, []GraphNode{{Type: "dig.t2"}}, g.TransitiveFailures)
+		assert.Equal(t, RootCauseFailure, g.Ctors[0].Failure)
+		assert.Equal(t, TransitiveFailure, g.Ctors[1].Failure)
+	})
+}
+
+func TestAttachGraphToErrors(t *testing.T) {
+	type t1 struct{}
+	type t2 struct{}
+
+	newContainer := func(t *testing.T, opts ...Option) *Container {
+		c := New(opts...)
+		require.NoError(t, c.Provide(func() (t1, error) {
+			return t1{}, errors.New("great sadness")
+		}))
+		require.NoError(t, c.Provide(func(t1) t2 { return t2{} }))
+		return c
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		c := newContainer(t)
+		err := c.Invoke(func(t2) {})
+		require.Error(t, err)
+
+		_, ok := GraphFromError(err)
+		assert.False(t, ok)
+	})
+
+	t.Run("graph from error", func(t *testing.T) {
+		c := newContainer(t, AttachGraphToErrors())
+		err := c.Invoke(func(t2) {})
+		require.Error(t, err)
+		assert.Equal(t, "great sadness", RootCause(err).Error())
+		assert.True(t, CanVisualizeError(err))
+
+		gv, ok := GraphFromError(errWrapf(err, "failed to start"))
+		require.True(t, ok)
+		require.Len(t, gv.Ctors, 2)
+		assert.Equal(t, RootCauseFailure, gv.Ctors[0].Failure)
+		assert.Equal(t, TransitiveFailure, gv.Ctors[1].Failure)
+		assert.NotEmpty(t, gv.RootCauses)
+
+		// Constructors provided after the failure are not in the snapshot.
+		require.NoError(t, c.Provide(func() string { return "" }))
+		gv, ok = GraphFromError(err)
+		require.True(t, ok)
+		assert.Len(t, gv.Ctors, 2)
+
+		var got, want bytes.Buffer
+		require.NoError(t, VisualizeGraph(gv, &got))
+		require.NoError(t, Visualize(newContainer(t), &want, VisualizeError(err)))
+		assert.Equal(t, want.String(), got.String())
+	})
+
+	t.Run("successful invokes", func(t *testing.T) {
+		c := New(AttachGraphToErrors())
+		require.NoError(t, c.Invoke(func() {}))
+	})
+
+	t.Run("values become collectable", func(t *testing.T) {
+		type value struct{ data [1 << 20]byte }
+
+		collected := make(chan struct{}, 1)
+		newError := func() error {
+			c := New(AttachGraphToErrors())
+			require.NoError(t, c.Provide(func() *value {
+				v := &value{}
+				runtime.SetFinalizer(v, func(*value) { collected <- struct{}{} })
+				return v
+			}))
+			require.NoError(t, c.Provide(func(*value) (t1, error) {
+				return t1{}, errors.New("great sadness")
+			}))
+			return c.Invoke(func(t1) {})
+		}
+
+		err := newError()
+		require.Error(t, err)
+
+		timeout := time.After(5 * time.Second)
+	collect:
+		for {
+			runtime.GC()
+			select {
+			case <-collected:
+				break collect
+			case <-time.After(10 * time.Millisecond):
+			case <-timeout:
+				t.Fatal("the error kept the values of the container reachable")
+			}
+		}
+
+		gv, ok := GraphFromError(err)
+		require.True(t, ok)
+		assert.Len(t, gv.Ctors, 2)
+	})
+}
+
+func TestVisualizeGraph(t *testing.T) {
+	type t1 struct{}
+
+	c := New()
+	require.NoError(t, c.Provide(func() t1 { return t1{} }))
+	require.NoError(t, c.Provide(func(t1) (string, error) {
+		return "", errors.New("great sadness")
+	}))
+	err := c.Invoke(func(string) {})
+	require.Error(t, err)
+
+	t.Run("matches Visualize", func(t *testing.T) {
+		var got, want bytes.Buffer
+		require.NoError(t, VisualizeGraph(Graph(c), &got))
+		require.NoError(t, Visualize(c, &want))
+		assert.Equal(t, want.String(), got.String())
+	})
+
+	t.Run("VisualizeError", func(t *testing.T) {
+		var got, want bytes.Buffer
+		require.NoError(t, VisualizeGraph(Graph(c), &got, VisualizeError(err)))
+		require.NoError(t, Visualize(c, &want, VisualizeError(err)))
+		assert.Equal(t, want.String(), got.String())
+		assert.Contains(t, got.String(), "color=red")
+	})
+}
+
+func TestVisualizeConcurrentProvide(t *testing.T) {
+	for _, opts := range [][]Option{nil, {DeferAcyclicVerification()}} {
+		c := New(opts...)
+		s := c.Scope("child")
+
+		done := make(chan struct{})
+		errs := make(chan error, 1)
+		go func() {
+			defer close(errs)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				var buf bytes.Buffer
+				if err := Visualize(s, &buf); err != nil {
+					errs <- err
+					return
+				}
+				Graph(s)
+			}
+		}()
+
+		// The scope's graph includes the constructors of its parent.
+		provideChain(t, c, 200)
+		close(done)
+		require.NoError(t, <-errs)
+	}
+}
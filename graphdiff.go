@@ -0,0 +1,246 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GraphDiffKind specifies how a constructor differs between two containers.
+type GraphDiffKind int
+
+const (
+	// ConstructorAdded indicates that the constructor was only provided to
+	// the second container.
+	ConstructorAdded GraphDiffKind = iota + 1
+
+	// ConstructorRemoved indicates that the constructor was only provided
+	// to the first container.
+	ConstructorRemoved
+
+	// ConstructorChanged indicates that the constructor was provided to
+	// both containers, but consumes or produces different values, or was
+	// provided with different options.
+	ConstructorChanged
+)
+
+func (k GraphDiffKind) String() string {
+	switch k {
+	case ConstructorAdded:
+		return "added"
+	case ConstructorRemoved:
+		return "removed"
+	case ConstructorChanged:
+		return "changed"
+	default:
+		return fmt.Sprintf("GraphDiffKind(%d)", int(k))
+	}
+}
+
+// GraphDiffEntry is a single difference between the graphs of two
+// containers. See Equal.
+type GraphDiffEntry struct {
+	Kind GraphDiffKind
+
+	// Package and name of the constructor.
+	Package string
+	Name    string
+
+	// Description of the change for ConstructorChanged entries, such as
+	// `result *sql.DB: [name="primary"] changed to [name="main"]`.
+	Detail string
+}
+
+func (e GraphDiffEntry) String() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%v %q.%v", e.Kind, e.Package, e.Name)
+	}
+	return fmt.Sprintf("%v %q.%v: %v", e.Kind, e.Package, e.Name, e.Detail)
+}
+
+// Equal reports whether the two containers have the same dependency graph:
+// the same constructors, identified by their package and function name,
+// consuming and producing the same values and value groups with the same
+// names and optionality. Values that were already built and the locations
+// of the constructors are not compared.
+//
+//   if ok, diff := dig.Equal(current, next); !ok {
+//     for _, d := range diff {
+//       log.Print(d)
+//     }
+//   }
+//
+// The returned entries list the constructors that were removed from a or
+// changed in b in the order in which they were provided to a, followed by
+// the constructors added to b in the order in which they were provided to b.
+// Changed constructors have one entry per change.
+func Equal(a, b *Container) (bool, []GraphDiffEntry) {
+	diff := diffGraphs(Graph(a).Ctors, Graph(b).Ctors)
+	return len(diff) == 0, diff
+}
+
+func diffGraphs(a, b []GraphCtor) []GraphDiffEntry {
+	// Constructors provided more than once are matched in order.
+	type ctorID struct{ pkg, name string }
+	unmatched := make(map[ctorID][]int) // indexes into b
+	for i, ctor := range b {
+		id := ctorID{ctor.Package, ctor.Name}
+		unmatched[id] = append(unmatched[id], i)
+	}
+
+	var diff []GraphDiffEntry
+	matched := make([]bool, len(b))
+	for _, ctor := range a {
+		id := ctorID{ctor.Package, ctor.Name}
+		idxs := unmatched[id]
+		if len(idxs) == 0 {
+			diff = append(diff, GraphDiffEntry{Kind: ConstructorRemoved, Package: ctor.Package, Name: ctor.Name})
+			continue
+		}
+		unmatched[id] = idxs[1:]
+		matched[idxs[0]] = true
+
+		for _, detail := range diffCtor(ctor, b[idxs[0]]) {
+			diff = append(diff, GraphDiffEntry{
+				Kind:    ConstructorChanged,
+				Package: ctor.Package,
+				Name:    ctor.Name,
+				Detail:  detail,
+			})
+		}
+	}
+	for i, ctor := range b {
+		if !matched[i] {
+			diff = append(diff, GraphDiffEntry{Kind: ConstructorAdded, Package: ctor.Package, Name: ctor.Name})
+		}
+	}
+	return diff
+}
+
+// diffCtor describes the differences between two versions of the same
+// constructor.
+func diffCtor(a, b GraphCtor) []string {
+	var details []string
+	if a.Scope != b.Scope {
+		details = append(details, fmt.Sprintf("scope %q changed to %q", a.Scope, b.Scope))
+	}
+	if a.Mount != b.Mount {
+		details = append(details, fmt.Sprintf("mount %q changed to %q", a.Mount, b.Mount))
+	}
+
+	details = append(details, diffNodes("param", paramDiffNodes(a), paramDiffNodes(b))...)
+	details = append(details, diffNodes("result", resultDiffNodes(a), resultDiffNodes(b))...)
+	return details
+}
+
+// diffNode is a value or value group consumed or produced by a constructor,
+// split into its type and its annotations.
+type diffNode struct {
+	Type        string
+	Annotations string
+}
+
+func (n diffNode) String() string {
+	if n.Annotations == "" {
+		return n.Type
+	}
+	return fmt.Sprintf("%v[%v]", n.Type, n.Annotations)
+}
+
+func paramDiffNodes(ctor GraphCtor) []diffNode {
+	var nodes []diffNode
+	for _, p := range ctor.Params {
+		var annotations []string
+		if p.Name != "" {
+			annotations = append(annotations, fmt.Sprintf("name=%q", p.Name))
+		}
+		if p.Optional {
+			annotations = append(annotations, "optional")
+		}
+		nodes = append(nodes, diffNode{Type: p.Type, Annotations: strings.Join(annotations, ", ")})
+	}
+	for _, g := range ctor.GroupParams {
+		nodes = append(nodes, diffNode{Type: g.Type, Annotations: fmt.Sprintf("group=%q", g.Name)})
+	}
+	return nodes
+}
+
+func resultDiffNodes(ctor GraphCtor) []diffNode {
+	var nodes []diffNode
+	for _, r := range ctor.Results {
+		n := diffNode{Type: r.Type}
+		switch {
+		case r.Name != "":
+			n.Annotations = fmt.Sprintf("name=%q", r.Name)
+		case r.Group != "":
+			n.Annotations = fmt.Sprintf("group=%q", r.Group)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// diffNodes describes the differences between the values consumed or
+// produced by two versions of a constructor. Values of the same type whose
+// annotations differ are reported as changed rather than added and removed.
+func diffNodes(what string, a, b []diffNode) []string {
+	remaining := make(map[diffNode]int)
+	for _, n := range b {
+		remaining[n]++
+	}
+	var removed []diffNode
+	for _, n := range a {
+		if remaining[n] > 0 {
+			remaining[n]--
+			continue
+		}
+		removed = append(removed, n)
+	}
+	var added []diffNode
+	for _, n := range b {
+		if remaining[n] > 0 {
+			remaining[n]--
+			added = append(added, n)
+		}
+	}
+
+	var details []string
+	for _, r := range removed {
+		changed := false
+		for i, n := range added {
+			if n.Type == r.Type {
+				details = append(details, fmt.Sprintf("%v %v: [%v] changed to [%v]",
+					what, r.Type, r.Annotations, n.Annotations))
+				added = append(added[:i], added[i+1:]...)
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			details = append(details, fmt.Sprintf("%v %v removed", what, r))
+		}
+	}
+	for _, n := range added {
+		details = append(details, fmt.Sprintf("%v %v added", what, n))
+	}
+	return details
+}
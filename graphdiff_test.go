@@ -0,0 +1,153 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type graphDiffA struct{}
+
+func newGraphDiffA() *graphDiffA { return &graphDiffA{} }
+
+func newGraphDiffString(*graphDiffA) string { return "" }
+
+func newGraphDiffInt() int { return 0 }
+
+func TestEqual(t *testing.T) {
+	t.Run("equal", func(t *testing.T) {
+		build := func() *Container {
+			c := New()
+			c.Provide(newGraphDiffA)
+			c.Provide(newGraphDiffString, Name("s"))
+			return c
+		}
+		a, b := build(), build()
+		assert.NoError(t, b.Invoke(func(*graphDiffA) {}))
+
+		ok, diff := Equal(a, b)
+		assert.True(t, ok, "built values must not be compared")
+		assert.Empty(t, diff)
+	})
+
+	t.Run("added and removed", func(t *testing.T) {
+		a, b := New(), New()
+		a.Provide(newGraphDiffA)
+		a.Provide(newGraphDiffInt)
+		b.Provide(newGraphDiffA)
+		b.Provide(newGraphDiffString)
+
+		ok, diff := Equal(a, b)
+		assert.False(t, ok)
+		assert.Equal(t, []GraphDiffEntry{
+			{Kind: ConstructorRemoved, Package: "go.uber.org/dig", Name: "newGraphDiffInt"},
+			{Kind: ConstructorAdded, Package: "go.uber.org/dig", Name: "newGraphDiffString"},
+		}, diff)
+		assert.Equal(t, `removed "go.uber.org/dig".newGraphDiffInt`, diff[0].String())
+	})
+
+	t.Run("renamed value", func(t *testing.T) {
+		a, b := New(), New()
+		a.Provide(newGraphDiffString, Name("primary"))
+		b.Provide(newGraphDiffString, Name("main"))
+
+		ok, diff := Equal(a, b)
+		assert.False(t, ok)
+		assert.Equal(t, []GraphDiffEntry{{
+			Kind:    ConstructorChanged,
+			Package: "go.uber.org/dig",
+			Name:    "newGraphDiffString",
+			Detail:  `result string: [name="primary"] changed to [name="main"]`,
+		}}, diff)
+		assert.Equal(t,
+			`changed "go.uber.org/dig".newGraphDiffString: result string: [name="primary"] changed to [name="main"]`,
+			diff[0].String())
+	})
+
+	t.Run("groups and options", func(t *testing.T) {
+		a, b := New(), New()
+		a.Provide(newGraphDiffInt)
+		b.Provide(newGraphDiffInt, Group("ints"))
+
+		_, diff := Equal(a, b)
+		var details []string
+		for _, d := range diff {
+			details = append(details, d.Detail)
+		}
+		assert.Equal(t, []string{
+			`result int: [] changed to [group="ints"]`,
+		}, details)
+	})
+
+	t.Run("constructor provided twice", func(t *testing.T) {
+		a, b := New(), New()
+		a.Provide(newGraphDiffString, Name("x"))
+		a.Provide(newGraphDiffString, Name("y"))
+		b.Provide(newGraphDiffString, Name("x"))
+
+		_, diff := Equal(a, b)
+		assert.Equal(t, []GraphDiffEntry{
+			{Kind: ConstructorRemoved, Package: "go.uber.org/dig", Name: "newGraphDiffString"},
+		}, diff)
+	})
+}
+
+func TestDiffGraphParams(t *testing.T) {
+	param := func(typ, name string, optional bool) GraphParam {
+		return GraphParam{GraphNode: GraphNode{Type: typ, Name: name}, Optional: optional}
+	}
+
+	a := []GraphCtor{{
+		Package: "foo",
+		Name:    "New",
+		Scope:   "request",
+		Params: []GraphParam{
+			param("*sql.DB", "read", false),
+			param("*log.Logger", "", false),
+			param("string", "", false),
+		},
+		GroupParams: []GraphGroupRef{{Type: "http.Handler", Name: "routes"}},
+	}}
+	b := []GraphCtor{{
+		Package: "foo",
+		Name:    "New",
+		Params: []GraphParam{
+			param("*sql.DB", "read", true),
+			param("string", "", false),
+			param("*zap.Logger", "", false),
+		},
+		GroupParams: []GraphGroupRef{{Type: "http.Handler", Name: "routes"}},
+	}}
+
+	var details []string
+	for _, d := range diffGraphs(a, b) {
+		assert.Equal(t, ConstructorChanged, d.Kind)
+		details = append(details, d.Detail)
+	}
+	assert.Equal(t, []string{
+		`scope "request" changed to ""`,
+		`param *sql.DB: [name="read"] changed to [name="read", optional]`,
+		"param *log.Logger removed",
+		"param *zap.Logger added",
+	}, details)
+}
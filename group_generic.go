@@ -0,0 +1,134 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+//go:build go1.18
+// +build go1.18
+
+package dig
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// ProvideIntoGroup provides each of the given constructors to the container
+// and adds the value produced by each one to the value group with the given
+// name as a T.
+//
+//   err := dig.ProvideIntoGroup[http.Handler](c, "routes", NewUserHandler, NewAdminHandler)
+//
+// is the same as providing each constructor with
+// dig.AsGroup("routes", new(http.Handler)). Consumers request []T from the
+// group.
+//
+// Each constructor must return a single value assignable to T, optionally
+// followed by an error. If T is not an interface, values of other types
+// assignable to it are converted to T. Like AsGroup, the values are only
+// added to the group and are not available as their own types.
+//
+// Constructors are provided in order, and those before a constructor that
+// fails to be provided remain in the container.
+func ProvideIntoGroup[T any](c *Container, group string, ctors ...interface{}) error {
+	target := reflect.TypeOf((*T)(nil)).Elem()
+	for _, ctor := range ctors {
+		if err := provideIntoGroup(c, group, target, ctor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func provideIntoGroup(c *Container, group string, target reflect.Type, ctor interface{}) error {
+	ctype := reflect.TypeOf(ctor)
+	if ctype == nil || ctype.Kind() != reflect.Func {
+		return fmt.Errorf("must provide constructor function, got %v (type %v)", ctor, ctype)
+	}
+
+	fn := digreflect.InspectFunc(ctor)
+	rtype, err := groupResultType(ctype)
+	if err == nil && !rtype.AssignableTo(target) {
+		err = fmt.Errorf("cannot add %v to value group %q as %v: %v is not assignable to %v",
+			rtype, group, target, rtype, target)
+	}
+	if err != nil {
+		return errProvide{Func: fn, Reason: err}
+	}
+
+	switch {
+	case rtype == target:
+		return c.Provide(ctor, Group(group))
+	case target.Kind() == reflect.Interface:
+		return c.Provide(ctor, AsGroup(group, reflect.New(target).Interface()))
+	default:
+		return c.Provide(convertResult(ctor, target), Group(group),
+			provideOptionFunc(func(opts *provideOptions) {
+				opts.Location = fn
+			}))
+	}
+}
+
+// groupResultType returns the type of the value produced by a constructor
+// given to ProvideIntoGroup.
+func groupResultType(ctype reflect.Type) (reflect.Type, error) {
+	n := ctype.NumOut()
+	if n > 0 && isError(ctype.Out(n-1)) {
+		n--
+	}
+	if n != 1 {
+		return nil, fmt.Errorf("%v must return a single value, optionally followed by an error", ctype)
+	}
+
+	rtype := ctype.Out(0)
+	if IsOut(rtype) {
+		return nil, fmt.Errorf("cannot add a result object to a value group: %v embeds dig.Out", rtype)
+	}
+	return rtype, nil
+}
+
+// convertResult returns a function that calls the constructor and converts
+// the value it produces to the given type.
+func convertResult(ctor interface{}, target reflect.Type) interface{} {
+	cval := reflect.ValueOf(ctor)
+	ctype := cval.Type()
+
+	in := make([]reflect.Type, ctype.NumIn())
+	for i := range in {
+		in[i] = ctype.In(i)
+	}
+	out := make([]reflect.Type, ctype.NumOut())
+	for i := range out {
+		out[i] = ctype.Out(i)
+	}
+	out[0] = target
+
+	ftype := reflect.FuncOf(in, out, ctype.IsVariadic())
+	return reflect.MakeFunc(ftype, func(args []reflect.Value) []reflect.Value {
+		var results []reflect.Value
+		if ctype.IsVariadic() {
+			results = cval.CallSlice(args)
+		} else {
+			results = cval.Call(args)
+		}
+		results[0] = results[0].Convert(target)
+		return results
+	}).Interface()
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+//go:build go1.18
+// +build go1.18
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvideIntoGroup(t *testing.T) {
+	type in struct {
+		In
+
+		Writers []io.Writer `group:"writers"`
+	}
+
+	t.Run("interface", func(t *testing.T) {
+		c := New()
+		require.NoError(t, ProvideIntoGroup[io.Writer](c, "writers",
+			func() *bytes.Buffer { return new(bytes.Buffer) },
+			func() (io.Writer, error) { return io.Discard, nil },
+		))
+
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Len(t, i.Writers, 2)
+		}))
+		require.Error(t, c.Invoke(func(*bytes.Buffer) {}), "*bytes.Buffer must only be in the group")
+	})
+
+	t.Run("assignable type", func(t *testing.T) {
+		type Names []string
+		type in struct {
+			In
+
+			Names [][]string `group:"names"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() int { return 2 }))
+		require.NoError(t, ProvideIntoGroup[[]string](c, "names",
+			func(n int) Names { return make(Names, n) },
+			func() []string { return []string{"a"} },
+		))
+
+		require.NoError(t, c.Invoke(func(i in) {
+			lens := []int{len(i.Names[0]), len(i.Names[1])}
+			sort.Ints(lens)
+			assert.Equal(t, []int{1, 2}, lens)
+		}))
+	})
+
+	t.Run("constructor errors", func(t *testing.T) {
+		type Names []string
+
+		c := New()
+		require.NoError(t, ProvideIntoGroup[[]string](c, "names", func() (Names, error) {
+			return nil, errors.New("great sadness")
+		}))
+
+		err := c.Invoke(func(struct {
+			In
+
+			Names [][]string `group:"names"`
+		}) {
+		})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not build value group \[\]string\[group="names"\]:`,
+			`function "go.uber.org/dig".TestProvideIntoGroup\S+ \(\S+/group_generic_test.go:\d+\) returned a non-nil error:`,
+			`great sadness`)
+	})
+
+	t.Run("not assignable", func(t *testing.T) {
+		c := New()
+		err := ProvideIntoGroup[io.Writer](c, "writers",
+			func() *bytes.Buffer { return new(bytes.Buffer) },
+			func() *bytes.Reader { return nil },
+		)
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestProvideIntoGroup\S+ \(\S+:\d+\) cannot be provided:`,
+			`cannot add \*bytes.Reader to value group "writers" as io.Writer: `,
+			`\*bytes.Reader is not assignable to io.Writer`)
+
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Len(t, i.Writers, 1, "earlier constructors must remain provided")
+		}))
+	})
+
+	t.Run("invalid constructors", func(t *testing.T) {
+		type out struct {
+			Out
+
+			W io.Writer `group:"writers"`
+		}
+
+		tests := []struct {
+			desc string
+			ctor interface{}
+			err  string
+		}{
+			{
+				desc: "not a function",
+				ctor: 42,
+				err:  `must provide constructor function, got 42 \(type int\)`,
+			},
+			{
+				desc: "no results",
+				ctor: func() error { return nil },
+				err:  `func\(\) error must return a single value, optionally followed by an error`,
+			},
+			{
+				desc: "multiple results",
+				ctor: func() (io.Writer, io.Writer) { return nil, nil },
+				err:  `must return a single value, optionally followed by an error`,
+			},
+			{
+				desc: "result object",
+				ctor: func() out { return out{} },
+				err:  `cannot add a result object to a value group: dig.out embeds dig.Out`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				err := ProvideIntoGroup[io.Writer](New(), "writers", tt.ctor)
+				require.Error(t, err)
+				assertErrorMatches(t, err, tt.err)
+			})
+		}
+	})
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"sort"
+)
+
+// groupIndexEntry locates a value of an indexed value group: the value is
+// the Pos-th of those that Provider submits to the group.
+//
+// The values of a value group are indexed if they're produced by dig.Out
+// fields with an `index:".."` tag. Consumers receive them ordered by their
+// indexes rather than in random order.
+type groupIndexEntry struct {
+	Index    int
+	Provider provider
+	Pos      int
+}
+
+// indexedGroup returns the values of the value group k ordered by their
+// indexes, or false if the values of the group don't have indexes.
+//
+// It fails if two values have the same index, and, unless compact is set, if
+// an index below the highest one has no value.
+func indexedGroup(c containerStore, k key, compact bool) ([]groupIndexEntry, bool, error) {
+	var entries []groupIndexEntry
+	for _, p := range c.getGroupContributors(k.group, k.t) {
+		indexes, _ := groupIndexes(p, k)
+		for pos, i := range indexes {
+			entries = append(entries, groupIndexEntry{Index: i, Provider: p, Pos: pos})
+		}
+	}
+	if len(entries) == 0 {
+		return nil, false, nil
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Index < entries[j].Index
+	})
+	for i, e := range entries {
+		if i > 0 && entries[i-1].Index == e.Index {
+			return nil, true, fmt.Errorf("value group %v has more than one value at index %d: provided by %v and %v",
+				k, e.Index, entries[i-1].Provider.Location(), e.Provider.Location())
+		}
+		if !compact && e.Index != i {
+			return nil, true, fmt.Errorf("value group %v has no value at index %d", k, i)
+		}
+	}
+	return entries, true, nil
+}
+
+// groupIndexes returns the indexes declared by the results of the given
+// provider for the values they submit to the value group k, in the order in
+// which the values are submitted, and the number of values submitted to the
+// group without an index.
+func groupIndexes(p provider, k key) (indexes []int, unindexed int) {
+	walkResult(p.ResultList(), groupIndexCollector{
+		key:       k,
+		indexes:   &indexes,
+		unindexed: &unindexed,
+	})
+	return indexes, unindexed
+}
+
+// groupIndexCollector is a resultVisitor that collects the indexes of the
+// results submitted to a value group.
+type groupIndexCollector struct {
+	key       key
+	indexes   *[]int
+	unindexed *int
+}
+
+func (gc groupIndexCollector) Visit(res result) resultVisitor {
+	r, ok := res.(resultGrouped)
+	if !ok {
+		return gc
+	}
+	for i, g := range r.Groups {
+		if g != gc.key.group || r.Type != gc.key.t {
+			continue
+		}
+		if i == 0 && r.Indexed {
+			*gc.indexes = append(*gc.indexes, r.Index)
+		} else {
+			*gc.unindexed++
+		}
+	}
+	for _, as := range r.As {
+		if as.Group == gc.key.group && as.Type == gc.key.t {
+			*gc.unindexed++
+		}
+	}
+	return gc
+}
+
+func (gc groupIndexCollector) AnnotateWithField(resultObjectField) resultVisitor { return gc }
+func (gc groupIndexCollector) AnnotateWithPosition(int) resultVisitor            { return gc }
+
+// checkGroupIndexes verifies that the values n submits to the value group k
+// can be added to those of the constructors already provided: either all of
+// them have an index or none of them do, and no two of them have the same
+// index.
+func (c *Container) checkGroupIndexes(n *node, k key) error {
+	own, unindexed := groupIndexes(n, k)
+	providers := c.getGroupProviders(k.group, k.t)
+	if len(own) == 0 {
+		for _, p := range providers {
+			if indexes, _ := groupIndexes(p, k); len(indexes) > 0 {
+				return fmt.Errorf("cannot provide %v from %v without an index: %v provides values of the group with indexes",
+					k, n.Location(), p.Location())
+			}
+			// The other providers agree with the first one.
+			break
+		}
+		return nil
+	}
+	if unindexed > 0 {
+		return fmt.Errorf("cannot provide %v from %v: either all values of the group must have an index or none of them",
+			k, n.Location())
+	}
+
+	seen := make(map[int]struct{}, len(own))
+	for _, i := range own {
+		if _, ok := seen[i]; ok {
+			return fmt.Errorf("cannot provide %v at index %d from %v: already provided at that index by %v",
+				k, i, n.Location(), n.Location())
+		}
+		seen[i] = struct{}{}
+	}
+	for _, p := range providers {
+		indexes, unindexed := groupIndexes(p, k)
+		if unindexed > 0 {
+			return fmt.Errorf("cannot provide %v at index %d from %v: %v provides values of the group without an index",
+				k, own[0], n.Location(), p.Location())
+		}
+		for _, i := range indexes {
+			if _, ok := seen[i]; ok {
+				return fmt.Errorf("cannot provide %v at index %d from %v: already provided at that index by %v",
+					k, i, n.Location(), p.Location())
+			}
+		}
+	}
+	return nil
+}
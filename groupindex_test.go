@@ -0,0 +1,290 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexedGroup(t *testing.T) {
+	type Stage struct{ name string }
+
+	type stage0 struct {
+		Out
+
+		Stage Stage `group:"stages" index:"0"`
+	}
+	type stage1 struct {
+		Out
+
+		Stage Stage `group:"stages" index:"1"`
+	}
+	type stage3 struct {
+		Out
+
+		Stage Stage `group:"stages" index:"3"`
+	}
+
+	// newStage returns a constructor of the stage with the given name at
+	// the given index.
+	newStage := func(name string, index int) interface{} {
+		switch index {
+		case 0:
+			return func() stage0 { return stage0{Stage: Stage{name}} }
+		case 1:
+			return func() stage1 { return stage1{Stage: Stage{name}} }
+		default:
+			return func() stage3 { return stage3{Stage: Stage{name}} }
+		}
+	}
+
+	names := func(stages []Stage) []string {
+		var names []string
+		for _, s := range stages {
+			names = append(names, s.name)
+		}
+		return names
+	}
+
+	t.Run("ordered by index", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newStage("second", 1)))
+		require.NoError(t, c.Provide(newStage("first", 0)))
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, c.Invoke(func(p struct {
+				In
+
+				Stages []Stage `group:"stages"`
+			}) {
+				assert.Equal(t, []string{"first", "second"}, names(p.Stages))
+			}))
+		}
+	})
+
+	t.Run("several indexes from one constructor", func(t *testing.T) {
+		type out struct {
+			Out
+
+			Last  Stage `group:"stages" index:"1"`
+			First Stage `group:"stages" index:"0"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() out {
+			return out{Last: Stage{"last"}, First: Stage{"first"}}
+		}))
+		require.NoError(t, c.Invoke(func(p struct {
+			In
+
+			Stages []Stage `group:"stages"`
+		}) {
+			assert.Equal(t, []string{"first", "last"}, names(p.Stages))
+		}))
+	})
+
+	t.Run("gap", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newStage("first", 0)))
+		require.NoError(t, c.Provide(newStage("last", 3)))
+
+		err := c.Invoke(func(p struct {
+			In
+
+			Stages []Stage `group:"stages"`
+		}) {
+			t.Fatal("must not be called")
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "has no value at index 1")
+	})
+
+	t.Run("compact", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newStage("last", 3)))
+		require.NoError(t, c.Provide(newStage("first", 0)))
+
+		require.NoError(t, c.Invoke(func(p struct {
+			In
+
+			Stages []Stage `group:"stages" compact:"true"`
+		}) {
+			assert.Equal(t, []string{"first", "last"}, names(p.Stages))
+		}))
+	})
+
+	t.Run("iterator", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newStage("last", 3)))
+		require.NoError(t, c.Provide(newStage("first", 0)))
+
+		require.NoError(t, c.Invoke(func(p struct {
+			In
+
+			Stages func(yield func(Stage) bool) error `group:"stages" compact:"true"`
+		}) {
+			var got []Stage
+			require.NoError(t, p.Stages(func(s Stage) bool {
+				got = append(got, s)
+				return true
+			}))
+			assert.Equal(t, []string{"first", "last"}, names(got))
+		}))
+	})
+
+	t.Run("duplicate index", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newStage("first", 0)))
+
+		err := c.Provide(newStage("other", 0))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "at index 0 from")
+		assert.Contains(t, err.Error(), "already provided at that index by")
+		assert.Contains(t, err.Error(), "groupindex_test.go")
+	})
+
+	t.Run("duplicate index in one constructor", func(t *testing.T) {
+		type out struct {
+			Out
+
+			A Stage `group:"stages" index:"0"`
+			B Stage `group:"stages" index:"0"`
+		}
+
+		c := New()
+		err := c.Provide(func() out { return out{} })
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already provided at that index by")
+	})
+
+	t.Run("duplicate index across scopes", func(t *testing.T) {
+		c := New()
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(newStage("child", 0)))
+		require.NoError(t, c.Provide(newStage("parent", 0)))
+
+		err := s.Invoke(func(p struct {
+			In
+
+			Stages []Stage `group:"stages"`
+		}) {
+			t.Fatal("must not be called")
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "has more than one value at index 0")
+	})
+
+	t.Run("mixed with unindexed values", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newStage("first", 0)))
+
+		err := c.Provide(func() Stage { return Stage{} }, Group("stages"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "without an index")
+
+		c = New()
+		require.NoError(t, c.Provide(func() Stage { return Stage{} }, Group("stages")))
+
+		err = c.Provide(newStage("first", 0))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "provides values of the group without an index")
+	})
+
+	t.Run("constructor fails", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() (stage0, error) {
+			return stage0{}, errors.New("great sadness")
+		}))
+
+		err := c.Invoke(func(p struct {
+			In
+
+			Stages []Stage `group:"stages"`
+		}) {
+			t.Fatal("must not be called")
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "great sadness")
+	})
+}
+
+func TestIndexedGroupErrors(t *testing.T) {
+	type Stage struct{}
+
+	tests := []struct {
+		desc string
+		ctor interface{}
+		want string
+	}{
+		{
+			desc: "negative index",
+			ctor: func() struct {
+				Out
+				Stage Stage `group:"stages" index:"-1"`
+			} {
+				panic("must not be called")
+			},
+			want: `invalid value "-1" for "index" tag`,
+		},
+		{
+			desc: "not a number",
+			ctor: func() struct {
+				Out
+				Stage Stage `group:"stages" index:"first"`
+			} {
+				panic("must not be called")
+			},
+			want: `invalid value "first" for "index" tag`,
+		},
+		{
+			desc: "without a group",
+			ctor: func() struct {
+				Out
+				Stage Stage `index:"0"`
+			} {
+				panic("must not be called")
+			},
+			want: `index:"0" can only be used on fields of value groups`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			err := New().Provide(tt.ctor)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+
+	t.Run("invalid compact tag", func(t *testing.T) {
+		err := New().Invoke(func(struct {
+			In
+			Stages []Stage `group:"stages" compact:"sometimes"`
+		}) {
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid value "sometimes" for "compact" tag`)
+	})
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// Number of constructors listed in errGroupSizeLimit.
+const _groupLimitContributors = 3
+
+// GroupSizeLimit is an Option that limits the number of values in each value
+// group of the container to n. A constructor whose values would make a group
+// grow past the limit fails, and its values are not added to the container.
+//
+//   c := dig.New(dig.GroupSizeLimit(1000))
+//
+// Values in the groups of parent scopes count towards the limit of a scope.
+// Groups are unlimited by default, or if n is not positive. Scopes inherit
+// the limit of their parents.
+func GroupSizeLimit(n int) Option {
+	return optionFunc(func(c *Container) {
+		c.groupSizeLimit = n
+	})
+}
+
+// errGroupSizeLimit is returned when a constructor submits values to a value
+// group that would exceed the limit set with GroupSizeLimit.
+type errGroupSizeLimit struct {
+	Func *digreflect.Func
+	Key  key
+
+	// Number of values the constructor submitted, values already in the
+	// group, and the limit of the group.
+	Submitted int
+	Size      int
+	Limit     int
+
+	// Constructors that contributed the most values to the group, including
+	// the failing one.
+	Contributors []groupContributor
+}
+
+type groupContributor struct {
+	Func   *digreflect.Func
+	Values int
+}
+
+func (e errGroupSizeLimit) Error() string {
+	b := new(bytes.Buffer)
+	fmt.Fprintf(b, "function %v cannot submit %d values to value group %v: "+
+		"the group already has %d values and is limited to %d; largest contributors: ",
+		e.Func, e.Submitted, e.Key, e.Size, e.Limit)
+	for i, c := range e.Contributors {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "%v (%d values)", c.Func, c.Values)
+	}
+	return b.String()
+}
+
+// checkGroupSizes returns an error if the values that the given provider
+// staged would make one of the value groups of this container exceed the
+// limit set with GroupSizeLimit.
+func (c *Container) checkGroupSizes(p provider, groups map[key][]reflect.Value) error {
+	if c.groupSizeLimit <= 0 {
+		return nil
+	}
+
+	keys := make([]key, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+
+	for _, k := range keys {
+		vs := groups[k]
+		var size int
+		for s := c; s != nil; s = s.parent {
+			s.valuesMu.RLock()
+			size += len(s.groups[k])
+			s.valuesMu.RUnlock()
+		}
+		if size+len(vs) <= c.groupSizeLimit {
+			continue
+		}
+
+		contributors := []groupContributor{{Func: p.Location(), Values: len(vs)}}
+		for _, gp := range c.getGroupProviders(k.group, k.t) {
+			if n := len(gp.GroupValues(k.group, k.t)); n > 0 {
+				contributors = append(contributors, groupContributor{Func: gp.Location(), Values: n})
+			}
+		}
+		sort.SliceStable(contributors, func(i, j int) bool {
+			return contributors[i].Values > contributors[j].Values
+		})
+		if len(contributors) > _groupLimitContributors {
+			contributors = contributors[:_groupLimitContributors]
+		}
+
+		return errGroupSizeLimit{
+			Func:         p.Location(),
+			Key:          k,
+			Submitted:    len(vs),
+			Size:         size,
+			Limit:        c.groupSizeLimit,
+			Contributors: contributors,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupSizeLimit(t *testing.T) {
+	type in struct {
+		In
+
+		Values []int `group:"values"`
+	}
+	type out struct {
+		Out
+
+		A int `group:"values"`
+		B int `group:"values"`
+	}
+
+	t.Run("within the limit", func(t *testing.T) {
+		c := New(GroupSizeLimit(3))
+		require.NoError(t, c.Provide(func() out { return out{A: 1, B: 2} }))
+		require.NoError(t, c.Provide(func() int { return 3 }, Group("values")))
+
+		require.NoError(t, c.Invoke(func(in in) {
+			assert.ElementsMatch(t, []int{1, 2, 3}, in.Values)
+		}))
+	})
+
+	t.Run("exceeding the limit", func(t *testing.T) {
+		c := New(GroupSizeLimit(3))
+		newOut := func() out { return out{A: 1, B: 2} }
+		require.NoError(t, c.Provide(newOut))
+		require.NoError(t, c.Provide(func() int { return 3 }, Group("values")))
+		require.NoError(t, c.Provide(newOut))
+
+		err := c.Invoke(func(in) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not build value group int\[group="values"\]:`,
+			`function "go.uber.org/dig".TestGroupSizeLimit\S+ \(\S+:\d+\) cannot submit 2 values to value group int\[group="values"\]:`,
+			`the group already has 3 values and is limited to 3;`,
+			`largest contributors: "go.uber.org/dig".TestGroupSizeLimit\S+ \(\S+:\d+\) \(2 values\), `+
+				`"go.uber.org/dig".TestGroupSizeLimit\S+ \(\S+:\d+\) \(2 values\), `+
+				`"go.uber.org/dig".TestGroupSizeLimit\S+ \(\S+:\d+\) \(1 values\)$`,
+		)
+
+		// Values of the failing constructor were not added to the group.
+		assert.Equal(t, 3, c.Metrics().GroupValues[`int[group="values"]`])
+	})
+
+	t.Run("parents count towards the limit", func(t *testing.T) {
+		c := New(GroupSizeLimit(2))
+		require.NoError(t, c.Provide(func() out { return out{A: 1, B: 2} }))
+		require.NoError(t, c.Invoke(func(in) {}))
+
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func() int { return 3 }, Group("values")))
+		err := s.Invoke(func(in) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the group already has 2 values and is limited to 2")
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		c := New(GroupSizeLimit(0))
+		for i := 0; i < 10; i++ {
+			require.NoError(t, c.Provide(func() out { return out{} }))
+		}
+		require.NoError(t, c.Invoke(func(in in) {
+			assert.Len(t, in.Values, 20)
+		}))
+	})
+}
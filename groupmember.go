@@ -0,0 +1,116 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// GroupMember describes a value of a value group passed to the function
+// given to ForEachGroupMember.
+type GroupMember struct {
+	// Name, package, and location of the constructor that submitted the
+	// value.
+	Name    string
+	Package string
+	File    string
+	Line    int
+
+	// Position of the value among the values of the group submitted by the
+	// same constructor.
+	Index int
+}
+
+// ForEachGroupMember calls fn with each value of the value group with the
+// given name and the type pointed to by of, one constructor at a time.
+//
+//   err := c.ForEachGroupMember("startup-tasks", (*Task)(nil),
+//     func(v interface{}, m dig.GroupMember) error {
+//       return v.(Task)(ctx)
+//     })
+//
+// Unlike consuming the group as a slice, a failed constructor doesn't
+// prevent the other values from being visited. ForEachGroupMember continues
+// past constructors that fail and calls to fn that return an error, and
+// returns a MultiError describing all of them.
+//
+// Values are visited in the order described by NoGroupShuffle if the
+// container was created with it, and in the order in which their
+// constructors were provided otherwise.
+func (c *Container) ForEachGroupMember(group string, of interface{}, fn func(v interface{}, m GroupMember) error) error {
+	if err := c.checkInitialized(); err != nil {
+		return err
+	}
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+
+	t := pointedType(of)
+	switch {
+	case group == "":
+		return errors.New("cannot visit a value group without a name")
+	case t == nil:
+		return fmt.Errorf("cannot visit value group %q of %v (type %T): must be a pointer", group, of, of)
+	}
+	if err := c.verifyChainAcyclic(); err != nil {
+		return err
+	}
+
+	k := key{group: group, t: t}
+	providers := c.getGroupProviders(group, t)
+	if c.noGroupShuffle {
+		sort.SliceStable(providers, func(i, j int) bool {
+			return providerLess(providers[i], providers[j])
+		})
+	}
+
+	var errs []error
+	for _, p := range providers {
+		if err := callProvider(c, p); err != nil {
+			errs = append(errs, errParamGroupFailed{CtorID: p.ID(), Key: k, Reason: err})
+			continue
+		}
+
+		loc := p.Location()
+		for i, v := range p.GroupValues(group, t) {
+			m := GroupMember{
+				Name:    loc.Name,
+				Package: loc.Package,
+				File:    loc.File,
+				Line:    loc.Line,
+				Index:   i,
+			}
+			if err := fn(v.Interface(), m); err != nil {
+				errs = append(errs, errWrapf(err, "value %d of %v from function %v failed", i, k, loc))
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	err := newMultiError(errs)
+	err.header = fmt.Sprintf("failed to visit value group %v:", k)
+	err.prefix = "\n\t"
+	return err
+}
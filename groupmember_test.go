@@ -0,0 +1,141 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEachGroupMember(t *testing.T) {
+	type Task func() error
+
+	t.Run("visits every member", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() Task { return func() error { return nil } }, Group("tasks")))
+		require.NoError(t, c.Provide(func() (out struct {
+			Out
+
+			A Task `group:"tasks"`
+			B Task `group:"tasks"`
+		}) {
+			out.A = func() error { return nil }
+			out.B = func() error { return nil }
+			return out
+		}))
+
+		var members []GroupMember
+		require.NoError(t, c.ForEachGroupMember("tasks", (*Task)(nil), func(v interface{}, m GroupMember) error {
+			members = append(members, m)
+			return v.(Task)()
+		}))
+
+		require.Len(t, members, 3)
+		assert.Equal(t, 0, members[0].Index)
+		assert.Equal(t, 0, members[1].Index)
+		assert.Equal(t, 1, members[2].Index)
+		assert.Equal(t, members[1].Line, members[2].Line)
+		assert.Equal(t, "go.uber.org/dig", members[0].Package)
+		assert.Contains(t, members[0].File, "groupmember_test.go")
+	})
+
+	t.Run("isolates failures", func(t *testing.T) {
+		c := New()
+		var ran []string
+		require.NoError(t, c.Provide(func() (Task, error) {
+			return nil, errors.New("great sadness")
+		}, Group("tasks")))
+		require.NoError(t, c.Provide(func() Task {
+			return func() error {
+				ran = append(ran, "fails")
+				return errors.New("task failed")
+			}
+		}, Group("tasks")))
+		require.NoError(t, c.Provide(func() Task {
+			return func() error {
+				ran = append(ran, "succeeds")
+				return nil
+			}
+		}, Group("tasks")))
+
+		err := c.ForEachGroupMember("tasks", (*Task)(nil), func(v interface{}, _ GroupMember) error {
+			return v.(Task)()
+		})
+		require.Error(t, err)
+		assert.Equal(t, []string{"fails", "succeeds"}, ran)
+		assertErrorMatches(t, err,
+			`failed to visit value group dig.Task\[group="tasks"\]:`,
+			`\n\tcould not build value group dig.Task\[group="tasks"\]: `,
+			`function "go.uber.org/dig".TestForEachGroupMember\S+ \(\S+\) returned a non-nil error: great sadness`,
+			`\n\tvalue 0 of dig.Task\[group="tasks"\] from function "go.uber.org/dig".TestForEachGroupMember\S+ \(\S+\) failed: `,
+			`task failed`)
+
+		var merr MultiError
+		require.True(t, errors.As(err, &merr))
+		assert.Len(t, merr.Unwrap(), 2)
+	})
+
+	t.Run("deterministic order", func(t *testing.T) {
+		c := New(NoGroupShuffle())
+		require.NoError(t, c.Provide(groupOrderC, Group("names")))
+		require.NoError(t, c.Provide(groupOrderB, Group("names")))
+		require.NoError(t, c.Provide(groupOrderA))
+
+		var names []string
+		require.NoError(t, c.ForEachGroupMember("names", (*string)(nil), func(v interface{}, _ GroupMember) error {
+			names = append(names, v.(string))
+			return nil
+		}))
+		assert.Equal(t, []string{"a1", "a2", "b", "c"}, names)
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(groupOrderC, Group("names")))
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(groupOrderB, Group("names")))
+
+		var names []string
+		require.NoError(t, s.ForEachGroupMember("names", (*string)(nil), func(v interface{}, _ GroupMember) error {
+			names = append(names, v.(string))
+			return nil
+		}))
+		assert.Equal(t, []string{"b", "c"}, names)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		c := New()
+		visit := func(interface{}, GroupMember) error { return nil }
+
+		err := c.ForEachGroupMember("", (*Task)(nil), visit)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot visit a value group without a name")
+
+		err = c.ForEachGroupMember("tasks", Task(nil), visit)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `cannot visit value group "tasks" of <nil> (type dig.Task): must be a pointer`)
+
+		assert.NoError(t, c.ForEachGroupMember("tasks", (*Task)(nil), visit), "empty groups have nothing to visit")
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"reflect"
+	"sort"
+)
+
+// NoGroupShuffle is an Option that makes value groups deterministic. Instead
+// of being shuffled, the values of a group are ordered by the package and
+// name of the constructors that contributed them, and then by their position
+// in the results of each constructor.
+//
+//   c := dig.New(dig.NoGroupShuffle())
+//
+// Unlike the order in which constructors were provided, this order doesn't
+// change when modules are registered in a different order. Constructors with
+// the same package and name are ordered by the file and line at which they
+// were defined, and then in the order in which they were provided.
+func NoGroupShuffle() Option {
+	return optionFunc(func(c *Container) {
+		c.noGroupShuffle = true
+	})
+}
+
+// canonicalValueGroup returns the values of the given value group consumed by
+// the Invoke with the given log, in the order described by NoGroupShuffle.
+func (c *Container) canonicalValueGroup(l *callLog, name string, t reflect.Type) []reflect.Value {
+	providers := c.groupContributors(l, name, t)
+	sort.SliceStable(providers, func(i, j int) bool {
+		return providerLess(providers[i], providers[j])
+	})
+
+	var items []reflect.Value
+	for _, p := range providers {
+		items = append(items, p.GroupValues(name, t)...)
+	}
+	return items
+}
+
+// providerLess orders providers by the package, name, file, and line of their
+// constructors.
+func providerLess(a, b provider) bool {
+	la, lb := a.Location(), b.Location()
+	switch {
+	case la.Package != lb.Package:
+		return la.Package < lb.Package
+	case la.Name != lb.Name:
+		return la.Name < lb.Name
+	case la.File != lb.File:
+		return la.File < lb.File
+	default:
+		return la.Line < lb.Line
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type groupOrderOut struct {
+	Out
+
+	First  string `group:"names"`
+	Second string `group:"names"`
+}
+
+func groupOrderA() groupOrderOut { return groupOrderOut{First: "a1", Second: "a2"} }
+func groupOrderB() string        { return "b" }
+func groupOrderC() string        { return "c" }
+
+func TestNoGroupShuffle(t *testing.T) {
+	type in struct {
+		In
+
+		Names []string `group:"names"`
+	}
+
+	build := func(t *testing.T, opts []Option, ctors ...interface{}) []string {
+		c := New(opts...)
+		for _, ctor := range ctors {
+			if _, ok := ctor.(func() string); ok {
+				require.NoError(t, c.Provide(ctor, Group("names")))
+			} else {
+				require.NoError(t, c.Provide(ctor))
+			}
+		}
+
+		var names []string
+		require.NoError(t, c.Invoke(func(i in) { names = i.Names }))
+		return names
+	}
+
+	want := []string{"a1", "a2", "b", "c"}
+
+	t.Run("independent of provide order", func(t *testing.T) {
+		opts := []Option{NoGroupShuffle()}
+		assert.Equal(t, want, build(t, opts, groupOrderA, groupOrderB, groupOrderC))
+		assert.Equal(t, want, build(t, opts, groupOrderC, groupOrderB, groupOrderA))
+		assert.Equal(t, want, build(t, opts, groupOrderB, groupOrderA, groupOrderC))
+	})
+
+	t.Run("independent of the source of randomness", func(t *testing.T) {
+		for seed := int64(0); seed < 10; seed++ {
+			opts := []Option{NoGroupShuffle(), setRand(rand.New(rand.NewSource(seed)))}
+			assert.Equal(t, want, build(t, opts, groupOrderC, groupOrderA, groupOrderB))
+		}
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		c := New(NoGroupShuffle())
+		require.NoError(t, c.Provide(groupOrderC, Group("names")))
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(groupOrderB, Group("names")))
+		require.NoError(t, s.Provide(groupOrderA))
+
+		require.NoError(t, s.Invoke(func(i in) {
+			assert.Equal(t, want, i.Names)
+		}))
+	})
+}
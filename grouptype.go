@@ -0,0 +1,149 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// groupTypeUse is a constructor that submits values of type t to a value
+// group, or consumes the group as values of type t.
+type groupTypeUse struct {
+	t        reflect.Type
+	n        *node
+	consumes bool
+}
+
+func (u groupTypeUse) String() string {
+	if u.consumes {
+		return fmt.Sprintf("%v consumes the group as %v", u.n.location, u.t)
+	}
+	return fmt.Sprintf("%v submits %v to it", u.n.location, u.t)
+}
+
+// groupTypeUses returns how the given constructor, which produces the given
+// keys, uses value groups, keyed by group name.
+func groupTypeUses(n *node, keys map[key]struct{}) map[string][]groupTypeUse {
+	uses := make(map[string][]groupTypeUse)
+	for k := range keys {
+		if k.group != "" {
+			uses[k.group] = append(uses[k.group], groupTypeUse{t: k.t, n: n})
+		}
+	}
+	walkParam(n.paramList, paramVisitorFunc(func(p param) bool {
+		if pg, ok := p.(paramGroupedSlice); ok {
+			uses[pg.Group] = append(uses[pg.Group], groupTypeUse{t: pg.Type.Elem(), n: n, consumes: true})
+		}
+		return true
+	}))
+	return uses
+}
+
+// checkGroupTypes returns an error if the given uses of value groups by a
+// constructor disagree with those of the constructors provided so far to
+// this container and its parents about the types of the values of a group,
+// for example if one submits a concrete type to a group that another
+// consumes as an interface. Such values would be silently missing from the
+// group.
+func (c *Container) checkGroupTypes(uses map[string][]groupTypeUse) error {
+	for _, s := range c.scopeChain() {
+		for group, us := range uses {
+			s.providersMu.RLock()
+			prevs := s.groupTypes[group]
+			s.providersMu.RUnlock()
+			for _, prev := range prevs {
+				if prev.n.providesNothing() {
+					continue
+				}
+				for _, u := range us {
+					if u.consumes && prev.consumes || !relatedTypes(u.t, prev.t) {
+						continue
+					}
+					return errGroupTypeMismatch{Group: group, Use: u, Prev: prev}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// recordGroupTypes records how a constructor provided to this container
+// uses value groups. See checkGroupTypes.
+func (c *Container) recordGroupTypes(uses map[string][]groupTypeUse) {
+	if len(uses) == 0 {
+		return
+	}
+	c.providersMu.Lock()
+	defer c.providersMu.Unlock()
+	if c.groupTypes == nil {
+		c.groupTypes = make(map[string][]groupTypeUse)
+	}
+	for group, us := range uses {
+		c.groupTypes[group] = append(c.groupTypes[group], us...)
+	}
+}
+
+// relatedTypes reports whether a and b are different types whose values are
+// easily mistaken for one another: an interface and a type implementing it,
+// or a type and a pointer to it.
+func relatedTypes(a, b reflect.Type) bool {
+	switch {
+	case a == b:
+		return false
+	case a.Kind() == reflect.Interface && b.Implements(a),
+		b.Kind() == reflect.Interface && a.Implements(b):
+		return true
+	default:
+		return a == reflect.PtrTo(b) || b == reflect.PtrTo(a)
+	}
+}
+
+// errGroupTypeMismatch is returned by Provide when a constructor submits
+// values of a type to a value group that another constructor consumes or
+// submits to as a related type.
+type errGroupTypeMismatch struct {
+	Group string
+	Use   groupTypeUse
+	Prev  groupTypeUse
+}
+
+func (e errGroupTypeMismatch) Error() string {
+	verb := "submits " + e.Use.t.String() + " to"
+	if e.Use.consumes {
+		verb = "consumes"
+	}
+	msg := fmt.Sprintf("it %v value group %q, but %v", verb, e.Group, e.Prev)
+	if e.Use.consumes {
+		msg += fmt.Sprintf(" instead of %v", e.Use.t)
+	}
+
+	// Suggest submitting concrete values as the interface.
+	from, to := e.Use, e.Prev
+	if from.consumes {
+		from, to = to, from
+	}
+	if to.t.Kind() == reflect.Interface && from.t.Implements(to.t) {
+		return fmt.Sprintf("%v: provide %v with dig.AsGroup(%q, new(%v)) to submit its values as %v",
+			msg, from.n.location, e.Group, to.t, to.t)
+	}
+	return msg + ": values of a value group must all have the same type"
+}
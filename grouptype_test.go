@@ -0,0 +1,105 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type groupTypeHandler interface{ Handle() }
+
+type groupTypeConcrete struct{}
+
+func (*groupTypeConcrete) Handle() {}
+
+func TestGroupTypeMismatch(t *testing.T) {
+	type handlers struct {
+		In
+
+		Handlers []groupTypeHandler `group:"handlers"`
+	}
+	newMux := func(handlers) int { return 0 }
+	newConcrete := func() *groupTypeConcrete { return &groupTypeConcrete{} }
+
+	t.Run("contributor after consumer", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newMux))
+		err := c.Provide(newConcrete, Group("handlers"))
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestGroupTypeMismatch\S+ \(\S+/grouptype_test.go:\d+\) cannot be provided: `+
+				`it submits \*dig.groupTypeConcrete to value group "handlers", `+
+				`but "go.uber.org/dig".TestGroupTypeMismatch\S+ \(\S+/grouptype_test.go:\d+\) consumes the group as dig.groupTypeHandler: `+
+				`provide "go.uber.org/dig".TestGroupTypeMismatch\S+ \(\S+/grouptype_test.go:\d+\) `+
+				`with dig.AsGroup\("handlers", new\(dig.groupTypeHandler\)\) to submit its values as dig.groupTypeHandler`)
+	})
+
+	t.Run("consumer after contributor", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newConcrete, Group("handlers")))
+		err := c.Provide(newMux)
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`it consumes value group "handlers", `+
+				`but "go.uber.org/dig".TestGroupTypeMismatch\S+ \(\S+/grouptype_test.go:\d+\) submits \*dig.groupTypeConcrete to it instead of dig.groupTypeHandler: `+
+				`provide "go.uber.org/dig".TestGroupTypeMismatch\S+ \(\S+/grouptype_test.go:\d+\) with dig.AsGroup`)
+	})
+
+	t.Run("pointer and value", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newConcrete, Group("handlers")))
+		err := c.Provide(func() groupTypeConcrete { return groupTypeConcrete{} }, Group("handlers"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `it submits dig.groupTypeConcrete to value group "handlers", but `)
+		assert.Contains(t, err.Error(), `submits *dig.groupTypeConcrete to it: values of a value group must all have the same type`)
+	})
+
+	t.Run("parent scope", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newMux))
+		err := c.Scope("child").Provide(newConcrete, Group("handlers"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "consumes the group as dig.groupTypeHandler")
+	})
+
+	t.Run("AsGroup", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newMux))
+		require.NoError(t, c.Provide(newConcrete, AsGroup("handlers", new(groupTypeHandler))))
+	})
+
+	t.Run("unrelated types", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newMux))
+		require.NoError(t, c.Provide(func() string { return "" }, Group("handlers")))
+	})
+
+	t.Run("removed constructor", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newConcrete, Group("handlers")))
+		_, err := c.RemoveAll(new(*groupTypeConcrete))
+		require.NoError(t, err)
+		require.NoError(t, c.Provide(newMux))
+	})
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Value groups may gain contributors while they are consumed: constructors
+// may be provided from another goroutine while Invoke runs, or from inside
+// the constructors called by Invoke. To give each Invoke a consistent view
+// of its value groups, constructors are numbered in the order in which they
+// were provided, and Invoke records the number of the last one when it
+// starts building its arguments. Value groups consumed by that Invoke only
+// include the values of the constructors provided before it, so a value is
+// either part of the group for the whole Invoke or not at all. Constructors
+// provided afterwards contribute to later Invokes.
+
+// _unpublished is the version of constructors that are being provided. It
+// hides them from the value groups consumed by all Invokes.
+const _unpublished = math.MaxUint64
+
+// groupVersions numbers the constructors provided to a container and its
+// scopes, which share it.
+type groupVersions struct {
+	mu   sync.RWMutex
+	last uint64
+}
+
+// publish gives n the next version, adding it to the value groups consumed
+// by the Invokes that start afterwards.
+func (v *groupVersions) publish(n *node) {
+	v.mu.Lock()
+	v.last++
+	atomic.StoreUint64(&n.version, v.last)
+	v.mu.Unlock()
+}
+
+// current returns the version of the last constructor published.
+func (v *groupVersions) current() uint64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.last
+}
+
+// groupValue is a value of a value group stored in a container.
+type groupValue struct {
+	Value reflect.Value
+
+	// Constructor that produced the value, if any.
+	Provider provider
+}
+
+// visibleAt reports whether the value is consumed by Invokes that started
+// when the given version was the last one published.
+func (gv groupValue) visibleAt(version uint64) bool {
+	return gv.Provider == nil || gv.Provider.Version() <= version
+}
+
+// providerWriter submits values to value groups of a containerStore along
+// with the provider that produced them.
+type providerWriter struct {
+	containerStore
+
+	p provider
+}
+
+func (w providerWriter) submitGroupedValue(name string, t reflect.Type, v reflect.Value) error {
+	return w.submitProvidedValue(w.p, name, t, v)
+}
+
+// getGroupContributors is like getGroupProviders, but leaves out the
+// constructors provided after the running Invoke started, if any.
+func (c *Container) getGroupContributors(name string, t reflect.Type) []provider {
+	return c.groupContributors(c.calls, name, t)
+}
+
+// groupContributors is getGroupContributors for the Invoke with the given
+// log.
+func (c *Container) groupContributors(l *callLog, name string, t reflect.Type) []provider {
+	providers := c.getGroupProviders(name, t)
+	version, ok := l.groupVersion()
+	if !ok {
+		return providers
+	}
+
+	contributors := providers[:0]
+	for _, p := range providers {
+		if p.Version() <= version {
+			contributors = append(contributors, p)
+		}
+	}
+	return contributors
+}
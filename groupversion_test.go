@@ -0,0 +1,169 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupVersions(t *testing.T) {
+	type in struct {
+		In
+
+		Values []int `group:"values"`
+	}
+
+	t.Run("provided during an Invoke", func(t *testing.T) {
+		type plugins struct{}
+		type params struct {
+			In
+
+			Plugins plugins
+			Values  []int `group:"values"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() int { return 1 }, Group("values")))
+		require.NoError(t, c.Provide(func() (plugins, error) {
+			return plugins{}, c.Provide(func() int { return 2 }, Group("values"))
+		}))
+
+		require.NoError(t, c.Invoke(func(p params) {
+			assert.Equal(t, []int{1}, p.Values, "must not see values provided after the Invoke started")
+		}))
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.ElementsMatch(t, []int{1, 2}, i.Values)
+		}))
+	})
+
+	t.Run("nested Invoke", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() int { return 1 }, Group("values")))
+
+		require.NoError(t, c.Invoke(func(outer in) {
+			assert.Equal(t, []int{1}, outer.Values)
+
+			require.NoError(t, c.Provide(func() int { return 2 }, Group("values")))
+			require.NoError(t, c.Invoke(func(inner in) {
+				assert.ElementsMatch(t, []int{1, 2}, inner.Values, "nested Invokes start a new snapshot")
+			}))
+		}))
+	})
+
+	t.Run("values built by later Invokes", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() int { return 1 }, Group("values")))
+
+		require.NoError(t, c.Invoke(func(in) {
+			require.NoError(t, c.Provide(func() int { return 2 }, Group("values")))
+			// Builds the new value while the outer Invoke is running.
+			require.NoError(t, c.Invoke(func(in) {}))
+
+			values := c.getValueGroup("values", reflect.TypeOf(0))
+			require.Len(t, values, 1)
+			assert.Equal(t, 1, values[0].Interface())
+		}))
+	})
+
+	t.Run("concurrent Provide", func(t *testing.T) {
+		const contributors = 100
+
+		type total int
+
+		c := New()
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < contributors; i++ {
+				i := i
+				assert.NoError(t, c.Provide(func() int { return i }, Group("values")))
+			}
+		}()
+
+		for i := 0; i < contributors; i++ {
+			s := c.Scope("request")
+			require.NoError(t, s.Provide(func(i in) total {
+				var sum int
+				for _, v := range i.Values {
+					sum += v
+				}
+				return total(sum)
+			}))
+			require.NoError(t, s.Invoke(func(sum total, i in) {
+				seen := make(map[int]struct{}, len(i.Values))
+				for _, v := range i.Values {
+					seen[v] = struct{}{}
+				}
+				for v := 0; v < len(i.Values); v++ {
+					assert.Contains(t, seen, v, "values must be provided in order")
+				}
+
+				want := len(i.Values) * (len(i.Values) - 1) / 2
+				assert.Equal(t, total(want), sum, "the group must not change during the Invoke")
+			}))
+		}
+		wg.Wait()
+	})
+
+	t.Run("concurrent scopes", func(t *testing.T) {
+		const (
+			contributors = 50
+			consumers    = 4
+		)
+
+		c := New()
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < contributors; i++ {
+				i := i
+				assert.NoError(t, c.Provide(func() int { return i }, Group("values")))
+			}
+		}()
+
+		for g := 0; g < consumers; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < contributors; i++ {
+					assert.NoError(t, c.Scope("request").Invoke(func(i in) {
+						seen := make(map[int]struct{}, len(i.Values))
+						for _, v := range i.Values {
+							seen[v] = struct{}{}
+						}
+						assert.Len(t, seen, len(i.Values), "values must not be duplicated")
+						for v := 0; v < len(i.Values); v++ {
+							assert.Contains(t, seen, v, "values must be provided in order")
+						}
+					}))
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// InvokeInfo describes a function about to be invoked. See
+// WithInvokeInterceptor.
+type InvokeInfo struct {
+	// Name, package, and location at which the function was defined.
+	Name    string
+	Package string
+	File    string
+	Line    int
+
+	// Values and value groups the function depends on, in the order in
+	// which they appear in its parameters.
+	Params []Key
+}
+
+// WithInvokeInterceptor is an Option that calls the given function before
+// every Invoke, after the dependencies of the invoked function were found to
+// be available but before any constructors are called. If the interceptor
+// returns an error, the Invoke fails with an InterceptedError.
+//
+//   c := dig.New(dig.WithInvokeInterceptor(func(info dig.InvokeInfo) error {
+//     if started {
+//       return errors.New("the server is already running")
+//     }
+//     return nil
+//   }))
+//
+// This option may be specified multiple times. Interceptors run in the order
+// in which they were specified, and all of them must pass for the Invoke to
+// proceed. Scopes inherit the interceptors of their parents.
+func WithInvokeInterceptor(f func(InvokeInfo) error) Option {
+	return optionFunc(func(c *Container) {
+		c.invokeInterceptors = append(c.invokeInterceptors, f)
+	})
+}
+
+// InterceptedError is returned by Invoke when an interceptor specified with
+// WithInvokeInterceptor rejected the invoked function.
+//
+// Use RootCause to retrieve the error returned by the interceptor.
+type InterceptedError struct {
+	// Function that was rejected.
+	Info InvokeInfo
+
+	// Error returned by the interceptor.
+	Reason error
+}
+
+func (e InterceptedError) cause() error { return e.Reason }
+
+// Unwrap returns the error returned by the interceptor.
+func (e InterceptedError) Unwrap() error { return e.Reason }
+
+func (e InterceptedError) Error() string {
+	return fmt.Sprintf("function %q.%v (%v:%v) was rejected by an invoke interceptor: %v",
+		e.Info.Package, e.Info.Name, e.Info.File, e.Info.Line, e.Reason)
+}
+
+func newInvokeInfo(f *digreflect.Func, pl paramList) InvokeInfo {
+	info := InvokeInfo{
+		Name:    f.Name,
+		Package: f.Package,
+		File:    f.File,
+		Line:    f.Line,
+	}
+
+	walkParam(pl, paramVisitorFunc(func(p param) bool {
+		switch p := p.(type) {
+		case paramSingle:
+			info.Params = append(info.Params, Key{Type: p.Type, Name: p.Name})
+		case paramGroupedSlice:
+			info.Params = append(info.Params, Key{Type: p.Type.Elem(), Group: p.Group})
+		}
+		return true
+	}))
+	return info
+}
+
+// intercept runs all invoke interceptors of this container on the given
+// function.
+func (c *Container) intercept(f *digreflect.Func, pl paramList) error {
+	if len(c.invokeInterceptors) == 0 {
+		return nil
+	}
+
+	info := newInvokeInfo(f, pl)
+	for _, intercept := range c.invokeInterceptors {
+		if err := intercept(info); err != nil {
+			return InterceptedError{Info: info, Reason: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvokeInterceptor(t *testing.T) {
+	type in struct {
+		In
+
+		W io.Writer   `name:"out"`
+		R []io.Reader `group:"readers"`
+	}
+
+	newContainer := func(t *testing.T, opts ...Option) *Container {
+		c := New(opts...)
+		require.NoError(t, c.Provide(func() io.Writer { return new(bytes.Buffer) }, Name("out")))
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }))
+		return c
+	}
+
+	t.Run("receives info", func(t *testing.T) {
+		var infos []InvokeInfo
+		c := newContainer(t, WithInvokeInterceptor(func(info InvokeInfo) error {
+			infos = append(infos, info)
+			return nil
+		}))
+
+		require.NoError(t, c.Invoke(func(*bytes.Buffer, in) {}))
+		require.Len(t, infos, 1)
+
+		info := infos[0]
+		assert.Equal(t, "go.uber.org/dig", info.Package)
+		assert.Contains(t, info.Name, "TestInvokeInterceptor")
+		assert.Contains(t, info.File, "intercept_test.go")
+		assert.NotZero(t, info.Line)
+		assert.Equal(t, []Key{
+			{Type: reflect.TypeOf(&bytes.Buffer{})},
+			{Type: reflect.TypeOf((*io.Writer)(nil)).Elem(), Name: "out"},
+			{Type: reflect.TypeOf((*io.Reader)(nil)).Elem(), Group: "readers"},
+		}, info.Params)
+	})
+
+	t.Run("veto", func(t *testing.T) {
+		var calls []string
+		c := newContainer(t,
+			WithInvokeInterceptor(func(InvokeInfo) error {
+				calls = append(calls, "first")
+				return errors.New("great sadness")
+			}),
+			WithInvokeInterceptor(func(InvokeInfo) error {
+				calls = append(calls, "second")
+				return nil
+			}),
+		)
+
+		called := false
+		err := c.Invoke(func(*bytes.Buffer) { called = true })
+		require.Error(t, err)
+		assert.False(t, called, "function must not be called")
+		assert.Equal(t, []string{"first"}, calls, "interceptors after a failure must not run")
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestInvokeInterceptor\S+ \(\S+intercept_test.go:\d+\) `,
+			`was rejected by an invoke interceptor: great sadness`)
+
+		_, ok := err.(InterceptedError)
+		assert.True(t, ok, "expected an InterceptedError, got %T", err)
+		assert.Equal(t, "great sadness", RootCause(err).Error())
+
+		require.NoError(t, c.Provide(func() *bytes.Reader {
+			t.Fatal("constructor must not be called")
+			return nil
+		}))
+		require.Error(t, c.Invoke(func(*bytes.Reader) {}))
+	})
+
+	t.Run("run in order", func(t *testing.T) {
+		var calls []string
+		c := newContainer(t,
+			WithInvokeInterceptor(func(InvokeInfo) error {
+				calls = append(calls, "first")
+				return nil
+			}),
+			WithInvokeInterceptor(func(InvokeInfo) error {
+				calls = append(calls, "second")
+				return nil
+			}),
+		)
+
+		require.NoError(t, c.Invoke(func() {}))
+		require.NoError(t, c.Scope("child").Invoke(func() {}))
+		assert.Equal(t, []string{"first", "second", "first", "second"}, calls)
+	})
+
+	t.Run("missing dependencies are reported first", func(t *testing.T) {
+		c := New(WithInvokeInterceptor(func(InvokeInfo) error {
+			t.Fatal("interceptor must not be called")
+			return nil
+		}))
+		err := c.Invoke(func(*bytes.Buffer) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing dependencies")
+	})
+}
@@ -49,6 +49,14 @@ func (f *Func) String() string {
 	return fmt.Sprintf("%q.%v (%v:%v)", f.Package, f.Name, f.File, f.Line)
 }
 
+// IsMethodValue reports whether the function is a method bound to its
+// receiver, like (&Module{}).NewThing.
+func (f *Func) IsMethodValue() bool {
+	// The compiler generates a wrapper named "T.Method-fm" or
+	// "(*T).Method-fm" for method values.
+	return strings.HasSuffix(f.Name, "-fm")
+}
+
 // InspectFunc inspects and returns runtime information about the given
 // function.
 func InspectFunc(function interface{}) *Func {
@@ -64,6 +72,30 @@ func InspectFunc(function interface{}) *Func {
 	}
 }
 
+// InspectCaller inspects and returns runtime information about a call site
+// on the current goroutine's stack. The argument skip is the number of stack
+// frames to ascend, with 0 identifying the caller of InspectCaller.
+//
+// The returned Func names the function containing the call site, while File
+// and Line point at the call site itself.
+func InspectCaller(skip int) *Func {
+	pc, fileName, lineNum, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return &Func{}
+	}
+
+	var pkgName, funcName string
+	if f := runtime.FuncForPC(pc); f != nil {
+		pkgName, funcName = splitFuncName(f.Name())
+	}
+	return &Func{
+		Name:    funcName,
+		Package: pkgName,
+		File:    fileName,
+		Line:    lineNum,
+	}
+}
+
 const _vendor = "/vendor/"
 
 func splitFuncName(function string) (pname string, fname string) {
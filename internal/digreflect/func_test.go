@@ -128,6 +128,46 @@ func TestInspectFunc(t *testing.T) {
 	}
 }
 
+type module struct{}
+
+func (*module) method() {}
+
+func TestIsMethodValue(t *testing.T) {
+	nested, _, _ := nestedFunctions()
+	var m *module
+
+	assert.True(t, InspectFunc(m.method).IsMethodValue(), "method value")
+	assert.False(t, InspectFunc((*module).method).IsMethodValue(), "method expression")
+	assert.False(t, InspectFunc(SomeExportedFunction).IsMethodValue(), "function")
+	assert.False(t, InspectFunc(nested).IsMethodValue(), "nested function")
+	assert.False(t, InspectCaller(0).IsMethodValue(), "caller")
+}
+
+func callerOfHelper() *Func {
+	return InspectCaller(1)
+}
+
+func TestInspectCaller(t *testing.T) {
+	t.Run("direct caller", func(t *testing.T) {
+		f := InspectCaller(0)
+		assert.Equal(t, "TestInspectCaller.func1", f.Name, "function name did not match")
+		assert.Equal(t, "go.uber.org/dig/internal/digreflect", f.Package, "package name did not match")
+		assert.True(t, strings.HasSuffix(f.File, "src/go.uber.org/dig/internal/digreflect/func_test.go"),
+			"unexpected file path %q", f.File)
+	})
+
+	t.Run("skip frames", func(t *testing.T) {
+		here := InspectCaller(0)
+		f := callerOfHelper()
+		assert.Equal(t, "TestInspectCaller.func2", f.Name, "function name did not match")
+		assert.Equal(t, here.Line+1, f.Line, "line number did not match")
+	})
+
+	t.Run("beyond the stack", func(t *testing.T) {
+		assert.Equal(t, &Func{}, InspectCaller(1000))
+	})
+}
+
 func TestSplitFuncEmptyString(t *testing.T) {
 	pname, fname := splitFuncName("")
 	assert.Empty(t, pname, "package name must be empty")
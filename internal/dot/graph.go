@@ -22,7 +22,10 @@ package dot
 
 import (
 	"fmt"
+	"html"
 	"reflect"
+	"regexp"
+	"strconv"
 )
 
 // ErrorType of a constructor or group is updated when they fail to build.
@@ -48,6 +51,35 @@ type Ctor struct {
 	GroupParams []*Group
 	Results     []*Result
 	ErrorType   ErrorType
+
+	// GroupParamPaths holds the Path of the param through which the
+	// constructor consumes each of its GroupParams.
+	GroupParamPaths []string
+
+	// Scope is the name of the scope to which the constructor was provided.
+	Scope string
+
+	// Bridge describes the bridge between a value group and named values
+	// the constructor belongs to, if any.
+	Bridge string
+
+	// Ambient is true if the constructor provides an ambient value.
+	Ambient bool
+
+	// Disabled is true if the constructor is disabled by its condition.
+	Disabled bool
+
+	// Mount is the prefix of the mounted container in which the constructor
+	// builds its values, if any.
+	Mount string
+
+	// Inherited is true if the constructor was provided to a parent of the
+	// scope that is being visualized.
+	Inherited bool
+
+	// Doc describes the constructor. It is shown as the tooltip of its
+	// label.
+	Doc string
 }
 
 // Node is a single node in a graph and is embedded into Params and Results.
@@ -62,6 +94,15 @@ type Param struct {
 	*Node
 
 	Optional bool
+
+	// Path locates the param among the arguments of its constructor: the
+	// position of the argument, or the name of the dig.In struct, followed
+	// by the names of the fields leading to the param separated by dots if
+	// any. For example, "0" or "Params.ReadDB".
+	Path string
+
+	// Doc describes the param. It is shown as the tooltip of its edge.
+	Doc string
 }
 
 // Result is a result node in the graph.
@@ -73,6 +114,27 @@ type Result struct {
 	// representations are the same so we need indices to uniquely identify
 	// the values.
 	GroupIndex int
+
+	// Indexed is set for grouped values that declare their position in the
+	// group with the `index:".."` tag. Index is that position.
+	Indexed bool
+	Index   int
+
+	// Path locates the result among the results of its constructor: the
+	// position of the result, followed by the names of the dig.Out fields
+	// leading to it separated by dots if any. For example, "1/Conn.Primary".
+	Path string
+
+	// ID identifies the result in the DOT graph. It is set by AddCtor for
+	// results of constructors and derived from the type of the result for
+	// values that aren't provided by any constructor.
+	//
+	// Unlike String, IDs don't depend on the names of types, so they are
+	// stable across renames and unique for identical anonymous types.
+	ID string
+
+	// Doc describes the result. It is shown as the tooltip of its node.
+	Doc string
 }
 
 // Group is a group node in the graph.
@@ -92,9 +154,29 @@ type Graph struct {
 	Groups   []*Group
 	groupMap map[groupKey]*Group
 
+	// Results of constructors that aren't part of a value group.
+	resultMap map[valueKey][]*Result
+
 	Failed *FailedNodes
+
+	// Inherited stands in for the constructors of parent scopes once they
+	// were collapsed with CollapseInherited. It is nil otherwise.
+	Inherited *InheritedCtors
 }
 
+// InheritedCtors is a single node in the graph that replaces the
+// constructors provided to parents of the visualized scope.
+type InheritedCtors struct {
+	// Number of constructors the node replaces.
+	Count int
+
+	// ErrorType is the most severe failure of the replaced constructors.
+	ErrorType ErrorType
+}
+
+// InheritedID is the ID of the InheritedCtors node in the DOT graph.
+const InheritedID = "inherited"
+
 // FailedNodes is the nodes that failed in the graph.
 type FailedNodes struct {
 	// RootCauses is a list of the point of failures. They are the root causes
@@ -112,12 +194,18 @@ type groupKey struct {
 	group string
 }
 
+type valueKey struct {
+	t    reflect.Type
+	name string
+}
+
 // NewGraph creates an empty graph.
 func NewGraph() *Graph {
 	return &Graph{
-		ctorMap:  make(map[CtorID]*Ctor),
-		groupMap: make(map[groupKey]*Group),
-		Failed:   &FailedNodes{},
+		ctorMap:   make(map[CtorID]*Ctor),
+		groupMap:  make(map[groupKey]*Group),
+		resultMap: make(map[valueKey][]*Result),
+		Failed:    &FailedNodes{},
 	}
 }
 
@@ -132,8 +220,9 @@ func NewGroup(k groupKey) *Group {
 // AddCtor adds the constructor with paramList and resultList into the graph.
 func (dg *Graph) AddCtor(c *Ctor, paramList []*Param, resultList []*Result) {
 	var (
-		params      []*Param
-		groupParams []*Group
+		params          []*Param
+		groupParams     []*Group
+		groupParamPaths []string
 	)
 
 	// Loop through the paramList to separate them into regular params and
@@ -149,18 +238,31 @@ func (dg *Graph) AddCtor(c *Ctor, paramList []*Param, resultList []*Result) {
 		k := groupKey{t: param.Type.Elem(), group: param.Group}
 		group := dg.getGroup(k)
 		groupParams = append(groupParams, group)
+		groupParamPaths = append(groupParamPaths, param.Path)
 	}
 
 	for _, result := range resultList {
+		// Constructors are identified by their position rather than their
+		// CtorID, which is the address of the function and changes with
+		// every build.
+		result.ID = fmt.Sprintf("ctor%d/%v", len(dg.Ctors), result.Path)
+
 		// If the result is a grouped value, we want to update its GroupIndex
 		// and add it to the Group.
 		if result.Group != "" {
+			// The same value may be submitted to multiple groups.
+			result.ID += "@" + result.Group
 			dg.addToGroup(result, c.ID)
+			continue
 		}
+
+		k := valueKey{t: result.Type, name: result.Name}
+		dg.resultMap[k] = append(dg.resultMap[k], result)
 	}
 
 	c.Params = params
 	c.GroupParams = groupParams
+	c.GroupParamPaths = groupParamPaths
 	c.Results = resultList
 
 	dg.Ctors = append(dg.Ctors, c)
@@ -181,21 +283,136 @@ func (dg *Graph) AddMissingNodes(results []*Result) {
 	isRootCause := len(dg.Failed.RootCauses) == 0
 
 	for _, r := range results {
+		if r.ID == "" {
+			r.ID = missingID(r.Node)
+		}
 		dg.failNode(r, isRootCause)
 	}
 }
 
+// ParamIDs returns the IDs of the results that may provide the value for the
+// given param, or the ID of a missing value if there are none.
+func (dg *Graph) ParamIDs(p *Param) []string {
+	results := dg.resultMap[valueKey{t: p.Type, name: p.Name}]
+	if len(results) == 0 {
+		return []string{missingID(p.Node)}
+	}
+
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		// Results of collapsed constructors share the same ID.
+		if len(ids) > 0 && ids[len(ids)-1] == r.ID {
+			continue
+		}
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+// CollapseInherited replaces all inherited constructors in the graph with a
+// single InheritedCtors node. Values produced by these constructors,
+// including failed ones, are replaced with that node too.
+//
+// This must be called after all failures were added to the graph.
+func (dg *Graph) CollapseInherited() {
+	inherited := &InheritedCtors{}
+
+	var ctors []*Ctor
+	for _, c := range dg.Ctors {
+		if !c.Inherited {
+			ctors = append(ctors, c)
+			continue
+		}
+
+		inherited.Count++
+		inherited.fail(c.ErrorType)
+		for _, r := range c.Results {
+			r.ID = InheritedID
+		}
+	}
+	if inherited.Count == 0 {
+		return
+	}
+	dg.Ctors = ctors
+	dg.Inherited = inherited
+
+	for _, g := range dg.Groups {
+		var (
+			results   []*Result
+			collapsed bool
+		)
+		for _, r := range g.Results {
+			switch {
+			case r.ID != InheritedID:
+				results = append(results, r)
+			case !collapsed:
+				results = append(results, &Result{ID: InheritedID})
+				collapsed = true
+			}
+		}
+		g.Results = results
+	}
+
+	dg.Failed.RootCauses = inherited.filter(dg.Failed.RootCauses, rootCause)
+	dg.Failed.TransitiveFailures = inherited.filter(dg.Failed.TransitiveFailures, transitiveFailure)
+}
+
+// fail records a failure of one of the replaced constructors. Root causes
+// take precedence over transitive failures.
+func (ic *InheritedCtors) fail(t ErrorType) {
+	if t == rootCause || ic.ErrorType == noError {
+		ic.ErrorType = t
+	}
+}
+
+// filter returns the given failed results without the ones replaced by this
+// node, recording their failure on the node instead.
+func (ic *InheritedCtors) filter(results []*Result, t ErrorType) []*Result {
+	var kept []*Result
+	for _, r := range results {
+		if r.ID == InheritedID {
+			ic.fail(t)
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// Attributes composes and returns a string of the InheritedCtors node's
+// attributes.
+func (ic *InheritedCtors) Attributes() string {
+	color := "gray"
+	if ic.ErrorType != noError {
+		color = ic.ErrorType.Color()
+	}
+	return fmt.Sprintf(`shape=box style=dashed label=<inherited<BR /><FONT POINT-SIZE="10">%d constructors</FONT>> color=%v`, ic.Count, color)
+}
+
+// missingID returns the ID of a value that isn't provided by any
+// constructor.
+func missingID(n *Node) string {
+	if n.Name != "" {
+		return fmt.Sprintf("missing:%v[name=%v]", n.Type, n.Name)
+	}
+	return fmt.Sprintf("missing:%v", n.Type)
+}
+
 // FailNodes adds results to the list of failed Results in the graph, and
 // updates the state of the constructor with the given id accordingly.
 func (dg *Graph) FailNodes(results []*Result, id CtorID) {
 	// This failure is the root cause if there are no other failures.
 	isRootCause := len(dg.Failed.RootCauses) == 0
 
+	c, ok := dg.ctorMap[id]
 	for _, r := range results {
+		if ok {
+			r = c.result(r)
+		}
 		dg.failNode(r, isRootCause)
 	}
 
-	if c, ok := dg.ctorMap[id]; ok {
+	if ok {
 		if isRootCause {
 			c.ErrorType = rootCause
 		} else {
@@ -226,11 +443,28 @@ func (dg *Graph) FailGroupNodes(name string, t reflect.Type, id CtorID) {
 			c.ErrorType = rootCause
 		} else {
 			group.ErrorType = transitiveFailure
-			c.ErrorType = transitiveFailure
+			// Constructors that are the root cause stay marked as such.
+			if c.ErrorType != rootCause {
+				c.ErrorType = transitiveFailure
+			}
 		}
 	}
 }
 
+// result returns the result of this constructor for the same value as r, or
+// r with the ID of a missing value if there is none.
+func (c *Ctor) result(r *Result) *Result {
+	for _, cr := range c.Results {
+		if cr.Type == r.Type && cr.Name == r.Name && cr.Group == r.Group {
+			return cr
+		}
+	}
+	if r.ID == "" {
+		r.ID = missingID(r.Node)
+	}
+	return r
+}
+
 // getGroup finds the group by groupKey from the graph. If it is not available,
 // a new group is created and returned.
 func (dg *Graph) getGroup(k groupKey) *Group {
@@ -277,27 +511,63 @@ func (g *Group) String() string {
 	return fmt.Sprintf("[type=%v group=%v]", g.Type.String(), g.Name)
 }
 
+// ID identifies the group in the DOT graph.
+func (g *Group) ID() string {
+	return fmt.Sprintf("group:%v/%v", g.Name, g.Type)
+}
+
 // Attributes composes and returns a string of the Result node's attributes.
 func (r *Result) Attributes() string {
+	var attr string
 	switch {
 	case r.Name != "":
-		return fmt.Sprintf(`label=<%v<BR /><FONT POINT-SIZE="10">Name: %v</FONT>>`, r.Type, r.Name)
+		attr = fmt.Sprintf(`label=<%v<BR /><FONT POINT-SIZE="10">Name: %v</FONT>>`,
+			typeLabel(r.Type), html.EscapeString(r.Name))
 	case r.Group != "":
-		return fmt.Sprintf(`label=<%v<BR /><FONT POINT-SIZE="10">Group: %v</FONT>>`, r.Type, r.Group)
+		attr = fmt.Sprintf(`label=<%v<BR /><FONT POINT-SIZE="10">Group: %v</FONT>>`,
+			typeLabel(r.Type), html.EscapeString(r.Group))
 	default:
-		return fmt.Sprintf(`label=<%v>`, r.Type)
+		attr = fmt.Sprintf(`label=<%v>`, typeLabel(r.Type))
+	}
+	if r.Doc != "" {
+		attr += " tooltip=" + strconv.Quote(r.Doc)
 	}
+	return attr
 }
 
 // Attributes composes and returns a string of the Group node's attributes.
 func (g *Group) Attributes() string {
-	attr := fmt.Sprintf(`shape=diamond label=<%v<BR /><FONT POINT-SIZE="10">Group: %v</FONT>>`, g.Type, g.Name)
+	attr := fmt.Sprintf(`shape=diamond label=<%v<BR /><FONT POINT-SIZE="10">Group: %v</FONT>>`,
+		typeLabel(g.Type), html.EscapeString(g.Name))
 	if g.ErrorType != noError {
 		attr += " color=" + g.ErrorType.Color()
 	}
 	return attr
 }
 
+// _packageQualifier matches the package names that qualify the types in the
+// string representation of a type.
+var _packageQualifier = regexp.MustCompile(`\w+\.`)
+
+// typeLabel returns the label of the given type for HTML-like labels. The
+// signatures of functions are shortened by dropping the packages of the types
+// they refer to, for example func(*http.Request) time.Time becomes
+// func(*Request) Time.
+func typeLabel(t reflect.Type) string {
+	s := t.String()
+	if t.Kind() == reflect.Func {
+		s = _packageQualifier.ReplaceAllString(s, "")
+	}
+	return html.EscapeString(s)
+}
+
+// IsRootCause returns true if the failure was a root cause of the error.
+func (s ErrorType) IsRootCause() bool { return s == rootCause }
+
+// IsTransitiveFailure returns true if the failure was caused by a failed
+// dependency.
+func (s ErrorType) IsTransitiveFailure() bool { return s == transitiveFailure }
+
 // Color returns the color representation of each ErrorType.
 func (s ErrorType) Color() string {
 	switch s {
@@ -25,6 +25,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type t1 struct{}
@@ -205,6 +206,90 @@ func TestFailNodes(t *testing.T) {
 	})
 }
 
+func TestIDs(t *testing.T) {
+	type1 := reflect.TypeOf(t1{})
+	type2 := reflect.TypeOf(t2{})
+
+	t.Run("results", func(t *testing.T) {
+		dg := NewGraph()
+		r0 := &Result{Node: &Node{Type: type1}, Path: "0"}
+		r1 := &Result{Node: &Node{Type: type1}, Path: "0"}
+		r2 := &Result{Node: &Node{Type: type2, Group: "foo"}, Path: "1/A.B"}
+
+		dg.AddCtor(&Ctor{ID: 123}, nil, []*Result{r0})
+		dg.AddCtor(&Ctor{ID: 456}, nil, []*Result{r1, r2})
+
+		assert.Equal(t, "ctor0/0", r0.ID)
+		assert.Equal(t, "ctor1/0", r1.ID)
+		assert.Equal(t, "ctor1/1/A.B@foo", r2.ID)
+		assert.Equal(t, "group:foo/dot.t2", dg.Groups[0].ID())
+	})
+
+	t.Run("params", func(t *testing.T) {
+		dg := NewGraph()
+		dg.AddCtor(&Ctor{ID: 123}, nil, []*Result{{Node: &Node{Type: type1}, Path: "0"}})
+		dg.AddCtor(&Ctor{ID: 456}, nil, []*Result{{Node: &Node{Type: type1}, Path: "0"}})
+
+		assert.Equal(t, []string{"ctor0/0", "ctor1/0"},
+			dg.ParamIDs(&Param{Node: &Node{Type: type1}}))
+		assert.Equal(t, []string{"missing:dot.t1[name=foo]"},
+			dg.ParamIDs(&Param{Node: &Node{Type: type1, Name: "foo"}}))
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		dg := NewGraph()
+		r := &Result{Node: &Node{Type: type1}, Path: "0"}
+		dg.AddCtor(&Ctor{ID: 123}, nil, []*Result{r})
+
+		// Failures reported for the results of a constructor use the IDs of
+		// these results.
+		dg.FailNodes([]*Result{{Node: &Node{Type: type1}}}, 123)
+		assert.Equal(t, []*Result{r}, dg.Failed.RootCauses)
+
+		missing := &Result{Node: &Node{Type: type2}}
+		dg.AddMissingNodes([]*Result{missing})
+		assert.Equal(t, "missing:dot.t2", missing.ID)
+	})
+
+	t.Run("collapse inherited", func(t *testing.T) {
+		dg := NewGraph()
+		dg.AddCtor(&Ctor{ID: 1, Inherited: true}, nil, []*Result{
+			{Node: &Node{Type: type1}, Path: "0"},
+			{Node: &Node{Type: type2, Group: "g"}, Path: "1"},
+		})
+		dg.AddCtor(&Ctor{ID: 2, Inherited: true}, nil, []*Result{
+			{Node: &Node{Type: type1}, Path: "0"},
+			{Node: &Node{Type: type2, Group: "g"}, Path: "1"},
+		})
+		local := &Result{Node: &Node{Type: type2, Group: "g"}, Path: "0"}
+		dg.AddCtor(&Ctor{ID: 3}, nil, []*Result{local})
+
+		dg.FailNodes([]*Result{{Node: &Node{Type: type1}}}, 1)
+		dg.FailNodes([]*Result{local}, 3)
+		dg.CollapseInherited()
+
+		require.Len(t, dg.Ctors, 1)
+		assert.Equal(t, CtorID(3), dg.Ctors[0].ID)
+		assert.Equal(t, &InheritedCtors{Count: 2, ErrorType: rootCause}, dg.Inherited)
+		assert.Equal(t, []string{InheritedID},
+			dg.ParamIDs(&Param{Node: &Node{Type: type1}}))
+
+		require.Len(t, dg.Groups, 1)
+		assert.Equal(t, []*Result{{ID: InheritedID}, local}, dg.Groups[0].Results)
+		assert.Empty(t, dg.Failed.RootCauses)
+		assert.Equal(t, []*Result{local}, dg.Failed.TransitiveFailures)
+	})
+
+	t.Run("collapse without inherited constructors", func(t *testing.T) {
+		dg := NewGraph()
+		dg.AddCtor(&Ctor{ID: 1}, nil, []*Result{{Node: &Node{Type: type1}, Path: "0"}})
+		dg.CollapseInherited()
+
+		assert.Len(t, dg.Ctors, 1)
+		assert.Nil(t, dg.Inherited)
+	})
+}
+
 func TestGetGroup(t *testing.T) {
 	type1 := reflect.TypeOf(t1{})
 	type2 := reflect.TypeOf(t2{})
@@ -272,6 +357,14 @@ func TestStringerAndAttribute(t *testing.T) {
 		assert.Equal(t, `label=<dot.t3<BR /><FONT POINT-SIZE="10">Group: foo</FONT>>`, r3.Attributes())
 	})
 
+	t.Run("reference type attributes", func(t *testing.T) {
+		fn := &Result{Node: &Node{Type: reflect.TypeOf(func(*Node, ...string) (map[string]Param, error) { return nil, nil })}}
+		assert.Equal(t, `label=<func(*Node, ...string) (map[string]Param, error)>`, fn.Attributes())
+
+		ch := &Result{Node: &Node{Type: reflect.TypeOf(make(chan<- t1)), Name: "a&b"}}
+		assert.Equal(t, `label=<chan&lt;- dot.t1<BR /><FONT POINT-SIZE="10">Name: a&amp;b</FONT>>`, ch.Attributes())
+	})
+
 	t.Run("group attributes", func(t *testing.T) {
 		assert.Equal(t, `shape=diamond label=<dot.t1<BR /><FONT POINT-SIZE="10">Group: group1</FONT>>`, g1.Attributes())
 		assert.Equal(t, `shape=diamond label=<dot.t2<BR /><FONT POINT-SIZE="10">Group: group2</FONT>> color=red`, g2.Attributes())
@@ -284,3 +377,12 @@ func TestColor(t *testing.T) {
 	assert.Equal(t, "red", rootCause.Color())
 	assert.Equal(t, "orange", transitiveFailure.Color())
 }
+
+func TestErrorTypePredicates(t *testing.T) {
+	assert.False(t, noError.IsRootCause())
+	assert.False(t, noError.IsTransitiveFailure())
+	assert.True(t, rootCause.IsRootCause())
+	assert.False(t, rootCause.IsTransitiveFailure())
+	assert.False(t, transitiveFailure.IsRootCause())
+	assert.True(t, transitiveFailure.IsTransitiveFailure())
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// invocationStore is the containerStore with which an Invoke of a scope calls
+// the constructors provided to one of the scope's parents. Values are read
+// from and stored into the parent, as if the parent had been invoked, but the
+// calls are recorded in the log of the scope, so that scopes sharing a parent
+// don't share the state of their Invokes.
+type invocationStore struct {
+	*Container
+
+	log *callLog
+}
+
+var _ containerStore = invocationStore{}
+
+// providerStore returns the store with which the constructor of p is called
+// to build values for c: the container p was provided to, with the log of the
+// Invoke that c is running.
+func providerStore(c containerStore, p provider) containerStore {
+	orig := p.OrigScope().container()
+	if orig == c.container() {
+		return c
+	}
+	return invocationStore{Container: orig, log: c.invocation()}
+}
+
+// callProvider calls the constructor of p for the Invoke that c is running,
+// unless it was already called. Constructors of parents are called while the
+// Invoke holds their parentCalls.
+func callProvider(c containerStore, p provider) error {
+	s := providerStore(c, p)
+	if is, ok := s.(invocationStore); ok {
+		is.parentCalls.lock(is.log)
+		defer is.parentCalls.unlock()
+	}
+	return p.Call(s)
+}
+
+// stageProvider stages the values of the constructor of p for the Invoke
+// that c is running, like provider.Stage. Constructors of parents are staged
+// and committed while the Invoke holds their parentCalls.
+func stageProvider(c containerStore, p provider) (commit func() error, err error) {
+	s := providerStore(c, p)
+	is, ok := s.(invocationStore)
+	if !ok {
+		return p.Stage(s)
+	}
+
+	is.parentCalls.lock(is.log)
+	staged, err := p.Stage(s)
+	is.parentCalls.unlock()
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		is.parentCalls.lock(is.log)
+		defer is.parentCalls.unlock()
+		return staged()
+	}, nil
+}
+
+// parentCalls serializes the Invokes of scopes that call the constructors of
+// their parents, so that each of those constructors is called at most once
+// even if the scopes are invoked from multiple goroutines. It's held by one
+// Invoke at a time, identified by its log, which may acquire it again while
+// it holds it.
+type parentCalls struct {
+	mu    sync.Mutex
+	freed *sync.Cond
+	owner *callLog
+	depth int
+}
+
+func newParentCalls() *parentCalls {
+	pc := new(parentCalls)
+	pc.freed = sync.NewCond(&pc.mu)
+	return pc
+}
+
+// lock acquires pc for the Invoke with the given log, waiting for other
+// Invokes to release it.
+func (pc *parentCalls) lock(l *callLog) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for pc.owner != nil && pc.owner != l {
+		pc.freed.Wait()
+	}
+	pc.owner = l
+	pc.depth++
+}
+
+// unlock releases one acquisition of pc.
+func (pc *parentCalls) unlock() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.depth--
+	if pc.depth == 0 {
+		pc.owner = nil
+		pc.freed.Broadcast()
+	}
+}
+
+func (s invocationStore) invocation() *callLog { return s.log }
+
+func (s invocationStore) getValueGroup(name string, t reflect.Type) []reflect.Value {
+	return s.valueGroup(s.log, name, t)
+}
+
+func (s invocationStore) getGroupContributors(name string, t reflect.Type) []provider {
+	return s.groupContributors(s.log, name, t)
+}
+
+func (s invocationStore) recordConstructor(fn *digreflect.Func, d time.Duration, values int, err error) {
+	s.recordCall(s.log, fn, d, values, err)
+}
+
+func (s invocationStore) currentInvoke() *InvokeInfo {
+	if !s.trackProvenance {
+		return nil
+	}
+	return s.log.invoke
+}
+
+func (s invocationStore) audit() *InvokeAudit {
+	return s.log.audit
+}
+
+func (s invocationStore) enterConstructor(fn *digreflect.Func, k key) error {
+	return s.log.constructing.enter(s.maxDepth, fn, k)
+}
+
+func (s invocationStore) exitConstructor() {
+	s.log.constructing.exit()
+}
+
+func (s invocationStore) requestValue(k key) (restore func()) {
+	return s.log.constructing.request(k)
+}
+
+func (s invocationStore) constructionInfo() ConstructionInfo {
+	return s.log.constructing.info()
+}
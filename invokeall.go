@@ -0,0 +1,220 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// InvokeAll invokes each of the given functions like Invoke, in an order
+// that respects the dependencies between them.
+//
+// A function that depends directly on a value runs before the functions
+// that only depend on that value through constructors. This lets functions
+// configure values before other functions use what was built from them.
+//
+//   err := c.InvokeAll(
+//     func(s *http.Server) error { return s.ListenAndServe() },
+//     func(mux *http.ServeMux) { mux.Handle("/users", users) },
+//   )
+//
+// If the *http.Server is built from the *http.ServeMux, the handler is
+// registered before the server starts. Functions without such a
+// relationship, or that depend on each other both ways, run in the given
+// order. Constructors are called at most once, as usual.
+//
+// InvokeAll stops at the first function that fails. The error names the
+// function and the functions that completed before it.
+func (c *Container) InvokeAll(functions ...interface{}) error {
+	if err := c.checkInitialized(); err != nil {
+		return err
+	}
+
+	plan := make([]invokeAllEntry, len(functions))
+	for i, function := range functions {
+		e, err := c.newInvokeAllEntry(function)
+		if err != nil {
+			return err
+		}
+		plan[i] = e
+	}
+
+	var completed []*digreflect.Func
+	for _, i := range invokeAllOrder(plan) {
+		e := plan[i]
+		if err := c.Invoke(e.Function); err != nil {
+			return errInvokeAll{Func: e.Func, Completed: completed, Reason: err}
+		}
+		completed = append(completed, e.Func)
+	}
+	return nil
+}
+
+// invokeAllEntry is a function passed to InvokeAll with the keys it depends
+// on.
+type invokeAllEntry struct {
+	Function interface{}
+	Func     *digreflect.Func
+
+	// Keys of the parameters of the function.
+	Direct map[key]struct{}
+
+	// Keys that the constructors of the parameters of the function depend
+	// on, directly or not.
+	Indirect map[key]struct{}
+}
+
+func (c *Container) newInvokeAllEntry(function interface{}) (invokeAllEntry, error) {
+	ftype := reflect.TypeOf(function)
+	if ftype == nil {
+		return invokeAllEntry{}, errors.New("can't invoke an untyped nil")
+	}
+	if ftype.Kind() != reflect.Func {
+		return invokeAllEntry{}, fmt.Errorf("can't invoke non-function %v (type %v)", function, ftype)
+	}
+
+	fn := digreflect.InspectFunc(function)
+	pl, err := c.invokeParamList(ftype)
+	if err != nil {
+		return invokeAllEntry{}, errWrapf(err, "function %v cannot be invoked", fn)
+	}
+
+	e := invokeAllEntry{
+		Function: function,
+		Func:     fn,
+		Direct:   make(map[key]struct{}),
+		Indirect: make(map[key]struct{}),
+	}
+
+	var providers []provider
+	walkParam(pl, paramVisitorFunc(func(p param) bool {
+		k, ps, ok := dependencyEdge(c, p)
+		if !ok {
+			return true
+		}
+		e.Direct[k] = struct{}{}
+		providers = append(providers, ps...)
+		return false
+	}))
+
+	seen := make(map[provider]struct{})
+	for len(providers) > 0 {
+		n := providers[len(providers)-1]
+		providers = providers[:len(providers)-1]
+		if _, ok := seen[n]; ok {
+			continue
+		}
+		seen[n] = struct{}{}
+
+		walkParam(n.ParamList(), paramVisitorFunc(func(p param) bool {
+			k, ps, ok := dependencyEdge(c, p)
+			if !ok {
+				return true
+			}
+			e.Indirect[k] = struct{}{}
+			providers = append(providers, ps...)
+			return false
+		}))
+	}
+	return e, nil
+}
+
+// runsBefore reports whether e must run before other: whether e depends
+// directly on a value that other only depends on through constructors.
+func (e invokeAllEntry) runsBefore(other invokeAllEntry) bool {
+	for k := range e.Direct {
+		if _, ok := other.Indirect[k]; !ok {
+			continue
+		}
+		if _, ok := other.Direct[k]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// invokeAllOrder returns the order in which to run the functions of plan.
+// Each step runs the first function in the given order that no remaining
+// function must run before, or the first remaining function if they all
+// depend on each other.
+func invokeAllOrder(plan []invokeAllEntry) []int {
+	done := make([]bool, len(plan))
+	order := make([]int, 0, len(plan))
+	for len(order) < len(plan) {
+		next := -1
+		for i := range plan {
+			if done[i] {
+				continue
+			}
+			if next < 0 {
+				next = i
+			}
+			if !blocked(plan, done, i) {
+				next = i
+				break
+			}
+		}
+		done[next] = true
+		order = append(order, next)
+	}
+	return order
+}
+
+// blocked reports whether a function that hasn't run yet must run before
+// plan[i].
+func blocked(plan []invokeAllEntry, done []bool, i int) bool {
+	for j := range plan {
+		if j != i && !done[j] && plan[j].runsBefore(plan[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// errInvokeAll is returned by InvokeAll when one of its functions fails.
+type errInvokeAll struct {
+	Func      *digreflect.Func
+	Completed []*digreflect.Func
+	Reason    error
+}
+
+func (e errInvokeAll) cause() error  { return e.Reason }
+func (e errInvokeAll) Unwrap() error { return e.Reason }
+
+func (e errInvokeAll) Error() string {
+	if len(e.Completed) == 0 {
+		return fmt.Sprintf("function %v failed before any other function completed: %v", e.Func, e.Reason)
+	}
+
+	fns := make([]string, len(e.Completed))
+	for i, fn := range e.Completed {
+		fns[i] = fn.String()
+	}
+	return fmt.Sprintf("function %v failed after %d other functions completed (%v): %v",
+		e.Func, len(fns), strings.Join(fns, ", "), e.Reason)
+}
+
+func (e errInvokeAll) Format(w fmt.State, c rune) { formatCause(e, e.Reason, w, c) }
@@ -0,0 +1,176 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvokeAll(t *testing.T) {
+	type Router struct{ routes []string }
+	type Server struct{ routes []string }
+	type Cache struct{}
+
+	newContainer := func(t *testing.T, calls *int) *Container {
+		c := New()
+		require.NoError(t, c.Provide(func() *Router {
+			*calls++
+			return &Router{}
+		}))
+		require.NoError(t, c.Provide(func(r *Router) *Server {
+			*calls++
+			return &Server{routes: r.routes}
+		}))
+		require.NoError(t, c.Provide(func() *Cache {
+			*calls++
+			return &Cache{}
+		}))
+		return c
+	}
+
+	t.Run("dependency order", func(t *testing.T) {
+		var calls int
+		c := newContainer(t, &calls)
+
+		var ran []string
+		require.NoError(t, c.InvokeAll(
+			func(s *Server) {
+				assert.Equal(t, []string{"/users"}, s.routes)
+				ran = append(ran, "serve")
+			},
+			func(*Cache) { ran = append(ran, "warm") },
+			func(r *Router) {
+				r.routes = append(r.routes, "/users")
+				ran = append(ran, "route")
+			},
+		))
+		assert.Equal(t, []string{"warm", "route", "serve"}, ran)
+		assert.Equal(t, 3, calls, "constructors must be called once")
+	})
+
+	t.Run("given order when independent", func(t *testing.T) {
+		var calls int
+		c := newContainer(t, &calls)
+
+		var ran []string
+		require.NoError(t, c.InvokeAll(
+			func(*Cache) { ran = append(ran, "a") },
+			func(*Router) { ran = append(ran, "b") },
+			func(*Router, *Cache) { ran = append(ran, "c") },
+			func() { ran = append(ran, "d") },
+		))
+		assert.Equal(t, []string{"a", "b", "c", "d"}, ran)
+	})
+
+	t.Run("parameter objects", func(t *testing.T) {
+		type in struct {
+			In
+
+			Server *Server
+		}
+
+		var calls int
+		c := newContainer(t, &calls)
+
+		var ran []string
+		require.NoError(t, c.InvokeAll(
+			func(in) { ran = append(ran, "serve") },
+			func(*Router) { ran = append(ran, "route") },
+		))
+		assert.Equal(t, []string{"route", "serve"}, ran)
+	})
+
+	t.Run("mutual dependencies", func(t *testing.T) {
+		type A struct{}
+		type B struct{}
+
+		c := New()
+		require.NoError(t, c.Provide(func(*B) *A { return &A{} }, Name("a")))
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Provide(func(*A) *B { return &B{} }, Name("b")))
+		require.NoError(t, c.Provide(func() *B { return &B{} }))
+
+		// Each function depends directly on a value that the other depends
+		// on through a constructor.
+		type needsA struct {
+			In
+
+			Named *A `name:"a"`
+			A     *A
+		}
+		type needsB struct {
+			In
+
+			Named *B `name:"b"`
+			B     *B
+		}
+
+		var ran []string
+		require.NoError(t, c.InvokeAll(
+			func(needsB) { ran = append(ran, "b") },
+			func(needsA) { ran = append(ran, "a") },
+		))
+		assert.Equal(t, []string{"b", "a"}, ran)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		var calls int
+		c := newContainer(t, &calls)
+
+		var ran []string
+		err := c.InvokeAll(
+			func(*Cache) { ran = append(ran, "warm") },
+			func(*Server) error { return errors.New("great sadness") },
+			func(*Router) { ran = append(ran, "route") },
+		)
+		require.Error(t, err)
+		assert.Equal(t, []string{"warm", "route"}, ran)
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestInvokeAll\S+ \(\S+invokeall_test.go:\d+\) failed after 2 other functions completed `,
+			`\("go.uber.org/dig".TestInvokeAll\S+ \(\S+\), "go.uber.org/dig".TestInvokeAll\S+ \(\S+\)\): `,
+			`great sadness`)
+		assert.Equal(t, "great sadness", RootCause(err).Error())
+	})
+
+	t.Run("first function fails", func(t *testing.T) {
+		c := New()
+		err := c.InvokeAll(func(*Cache) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestInvokeAll\S+ \(\S+\) failed before any other function completed: `,
+			`missing dependencies for function`)
+	})
+
+	t.Run("invalid functions are rejected before running any", func(t *testing.T) {
+		c := New()
+		var ran bool
+		err := c.InvokeAll(func() { ran = true }, 42)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "can't invoke non-function 42 (type int)")
+		assert.False(t, ran)
+
+		assert.Error(t, c.InvokeAll(func() {}, nil))
+	})
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"reflect"
+	"sort"
+)
+
+// KnownKey is a value or a value group that the container knows how to
+// build. See Container.KnownKeys.
+type KnownKey struct {
+	Key
+
+	// Whether the value was already built. For value groups, this reports
+	// whether all constructors that contribute to the group were called.
+	Built bool
+}
+
+// KnownKeys returns the keys of all values and value groups provided to the
+// container or its parents. Unlike types alone, keys distinguish values of
+// the same type provided under different names.
+//
+//   for _, k := range c.KnownKeys() {
+//     fmt.Println(k, k.Built)
+//   }
+//
+// Keys are sorted by type, then by name and group.
+func (c *Container) KnownKeys() []KnownKey {
+	if c.checkInitialized() != nil {
+		return nil
+	}
+
+	seen := make(map[key]struct{})
+	var provided []key
+	for s := c; s != nil; s = s.parent {
+		s.providersMu.RLock()
+		for k := range s.providers {
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			provided = append(provided, k)
+		}
+		s.providersMu.RUnlock()
+	}
+
+	keys := make([]KnownKey, len(provided))
+	for i, k := range provided {
+		keys[i] = KnownKey{Key: k.exported(), Built: c.isBuilt(k)}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		ki, kj := keys[i], keys[j]
+		if ti, tj := ki.Type.String(), kj.Type.String(); ti != tj {
+			return ti < tj
+		}
+		if ki.Name != kj.Name {
+			return ki.Name < kj.Name
+		}
+		return ki.Group < kj.Group
+	})
+	return keys
+}
+
+// isBuilt reports whether the value or value group with the given key was
+// built. See KnownKey.Built.
+func (c *Container) isBuilt(k key) bool {
+	if k.group == "" {
+		_, ok := c.getValue(k.name, k.t)
+		return ok
+	}
+
+	for _, p := range c.getGroupProviders(k.group, k.t) {
+		if !p.Called() {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Container) knownTypes() []reflect.Type {
+	seen := make(map[reflect.Type]struct{})
+	var types []reflect.Type
+	for _, k := range c.KnownKeys() {
+		if _, ok := seen[k.Type]; !ok {
+			seen[k.Type] = struct{}{}
+			types = append(types, k.Type)
+		}
+	}
+	return types
+}
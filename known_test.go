@@ -0,0 +1,133 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKnownKeys(t *testing.T) {
+	type A struct{}
+	type B struct{}
+
+	c := New()
+	require.NoError(t, c.Provide(func() *A { return &A{} }, Name("replica")))
+	require.NoError(t, c.Provide(func() *A { return &A{} }, Name("primary")))
+	require.NoError(t, c.Provide(func() *A { return &A{} }))
+	require.NoError(t, c.Provide(func() B { return B{} }, Group("bs")))
+
+	s := c.Scope("child")
+	require.NoError(t, s.Provide(func() B { return B{} }, Group("bs")))
+	require.NoError(t, s.Provide(func() string { return "" }))
+
+	var (
+		aType = reflect.TypeOf(&A{})
+		bType = reflect.TypeOf(B{})
+	)
+	assert.Equal(t, []KnownKey{
+		{Key: Key{Type: aType}},
+		{Key: Key{Type: aType, Name: "primary"}},
+		{Key: Key{Type: aType, Name: "replica"}},
+		{Key: Key{Type: bType, Group: "bs"}},
+	}, c.KnownKeys())
+
+	type params struct {
+		In
+
+		A *A `name:"replica"`
+	}
+	require.NoError(t, c.Invoke(func(params) {}))
+	require.NoError(t, c.Invoke(func(struct {
+		In
+
+		Bs []B `group:"bs"`
+	}) {
+	}))
+
+	assert.Equal(t, []KnownKey{
+		{Key: Key{Type: aType}},
+		{Key: Key{Type: aType, Name: "primary"}},
+		{Key: Key{Type: aType, Name: "replica"}, Built: true},
+		{Key: Key{Type: bType, Group: "bs"}, Built: false},
+		{Key: Key{Type: reflect.TypeOf("")}},
+	}, s.KnownKeys(), "the child's constructor of the group was not called")
+
+	assert.Equal(t, []reflect.Type{aType, bType, reflect.TypeOf("")}, s.knownTypes())
+}
+
+func TestMissingTypeNames(t *testing.T) {
+	type A struct{}
+
+	c := New()
+	require.NoError(t, c.Provide(func() *A { return &A{} }, Name("replica")))
+
+	t.Run("one name", func(t *testing.T) {
+		err := c.Invoke(func(*A) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`type \*dig.A is not in the container, but it is available as name "replica"$`)
+	})
+
+	t.Run("several names", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }, Name("replica")))
+		require.NoError(t, c.Provide(func() *A { return &A{} }, Name("primary")))
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+
+		err := c.Invoke(func(struct {
+			In
+
+			A *A `name:"backup"`
+		}) {
+		})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`type \*dig.A\[name="backup"\] is not in the container, `,
+			`but it is available without a name and as names "primary", "replica"$`)
+	})
+
+	t.Run("with suggestions", func(t *testing.T) {
+		err := c.Invoke(func(A, string) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`the following types are not in the container: `,
+			`dig.A \(argument 1\); string \(argument 2\)$`)
+
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Provide(func() A { return A{} }, Name("a")))
+
+		err = c.Invoke(func(A, string) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`dig.A \(argument 1, available as name "a", did you mean \*dig.A\?\); string \(argument 2\)$`)
+
+		err = c.Invoke(func(A) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`type dig.A is not in the container, but it is available as name "a"; `,
+			`did you mean to use \*dig.A\?$`)
+	})
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// Locator retrieves values from a Container one at a time. It eases the
+// migration of code written for service locators to dig, and should not be
+// used by new code.
+//
+// A Locator can't provide new constructors to the container, so it may be
+// handed to code that must only consume values.
+type Locator interface {
+	// Get fills the value pointed to by ptr with the value of that type
+	// from the container, calling constructors as needed.
+	//
+	//   var db *sql.DB
+	//   if err := l.Get(&db); err != nil {
+	//     return err
+	//   }
+	Get(ptr interface{}, opts ...ResolveOption) error
+
+	// MustGet is like Get but panics if the value can't be retrieved.
+	MustGet(ptr interface{}, opts ...ResolveOption)
+}
+
+// A LocatorOption modifies the default behavior of a Locator.
+type LocatorOption interface {
+	applyLocatorOption(*locator)
+}
+
+type locatorOptionFunc func(*locator)
+
+func (f locatorOptionFunc) applyLocatorOption(l *locator) { f(l) }
+
+// LocatorUse describes a call to Get or MustGet on a Locator. See
+// LogLocatorUse.
+type LocatorUse struct {
+	// Value that was requested.
+	Key Key
+
+	// Location of the call.
+	File string
+	Line int
+
+	// Error returned by Get, if any.
+	Err error
+}
+
+// LogLocatorUse is a LocatorOption that calls the given function after every
+// use of the Locator. Use it to track down the code that still relies on the
+// Locator.
+func LogLocatorUse(f func(LocatorUse)) LocatorOption {
+	return locatorOptionFunc(func(l *locator) {
+		l.log = f
+	})
+}
+
+// A ResolveOption modifies the default behavior of Locator.Get and
+// Container.Construct.
+type ResolveOption interface {
+	applyResolveOption(*resolveOptions)
+}
+
+type resolveOptions struct {
+	Name  string
+	Group string
+}
+
+type resolveOptionFunc func(*resolveOptions)
+
+func (f resolveOptionFunc) applyResolveOption(opts *resolveOptions) { f(opts) }
+
+// ResolveName is a ResolveOption that retrieves the value with the given
+// name instead of the unnamed value.
+//
+//   var ro *sql.DB
+//   err := l.Get(&ro, dig.ResolveName("ro"))
+func ResolveName(name string) ResolveOption {
+	return resolveOptionFunc(func(opts *resolveOptions) {
+		opts.Name = name
+	})
+}
+
+// ResolveGroup is a ResolveOption that retrieves the values of the value
+// group with the given name. The requested type must be a slice of the type
+// of the values in the group.
+//
+//   var handlers []http.Handler
+//   err := l.Get(&handlers, dig.ResolveGroup("routes"))
+//
+// This option cannot be combined with ResolveName.
+func ResolveGroup(group string) ResolveOption {
+	return resolveOptionFunc(func(opts *resolveOptions) {
+		opts.Group = group
+	})
+}
+
+func newResolveOptions(opts []ResolveOption) resolveOptions {
+	var options resolveOptions
+	for _, o := range opts {
+		o.applyResolveOption(&options)
+	}
+	return options
+}
+
+// key returns the key of the value of type t described by these options.
+func (o resolveOptions) key(t reflect.Type) Key {
+	if o.Group != "" && t.Kind() == reflect.Slice {
+		return Key{Type: t.Elem(), Group: o.Group}
+	}
+	return Key{Type: t, Name: o.Name}
+}
+
+// newResolveFunc returns a function that accepts only the value of type t
+// described by opts, wrapped in a parameter object if it's named or grouped,
+// and passes it to f.
+func newResolveFunc(t reflect.Type, opts resolveOptions, f func(reflect.Value)) (interface{}, error) {
+	var tag reflect.StructTag
+	switch {
+	case opts.Name != "" && opts.Group != "":
+		return nil, fmt.Errorf("cannot resolve %v with both dig.ResolveName(%q) and dig.ResolveGroup(%q)",
+			t, opts.Name, opts.Group)
+	case opts.Group != "" && t.Kind() != reflect.Slice:
+		return nil, fmt.Errorf("cannot resolve value group %q as %v: must be a slice", opts.Group, t)
+	case opts.Group != "":
+		tag = reflect.StructTag(_groupTag + ":" + strconv.Quote(opts.Group))
+	case opts.Name != "":
+		tag = reflect.StructTag(_nameTag + ":" + strconv.Quote(opts.Name))
+	}
+
+	param := t
+	if tag != "" {
+		param = reflect.StructOf([]reflect.StructField{
+			{Name: "In", Type: _inType, Anonymous: true},
+			{Name: "Value", Type: t, Tag: tag},
+		})
+	}
+	ftype := reflect.FuncOf([]reflect.Type{param}, nil /* out */, false /* variadic */)
+	return reflect.MakeFunc(ftype, func(args []reflect.Value) []reflect.Value {
+		arg := args[0]
+		if tag != "" {
+			arg = arg.Field(1)
+		}
+		f(arg)
+		return nil
+	}).Interface(), nil
+}
+
+// AsLocator returns a Locator that retrieves values from the given
+// container.
+func AsLocator(c *Container, opts ...LocatorOption) Locator {
+	l := &locator{c: c}
+	for _, o := range opts {
+		o.applyLocatorOption(l)
+	}
+	return l
+}
+
+type locator struct {
+	c   *Container
+	log func(LocatorUse)
+}
+
+var _ Locator = (*locator)(nil)
+
+func (l *locator) Get(ptr interface{}, opts ...ResolveOption) error {
+	return l.get(ptr, opts)
+}
+
+func (l *locator) MustGet(ptr interface{}, opts ...ResolveOption) {
+	if err := l.get(ptr, opts); err != nil {
+		panic(err)
+	}
+}
+
+// get must be called directly by Get and MustGet so that LogLocatorUse
+// reports their callers.
+func (l *locator) get(ptr interface{}, opts []ResolveOption) error {
+	options := newResolveOptions(opts)
+	caller := digreflect.InspectCaller(2)
+	var k Key
+	err := l.resolve(ptr, options, caller, &k)
+	if l.log != nil {
+		l.log(LocatorUse{Key: k, File: caller.File, Line: caller.Line, Err: err})
+	}
+	return err
+}
+
+func (l *locator) resolve(ptr interface{}, opts resolveOptions, caller *digreflect.Func, k *Key) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("can't resolve into %v (type %T): must be a non-nil pointer", ptr, ptr)
+	}
+
+	t := v.Type().Elem()
+	*k = opts.key(t)
+
+	f, err := newResolveFunc(t, opts, func(arg reflect.Value) { v.Elem().Set(arg) })
+	if err != nil {
+		return err
+	}
+	return l.c.Invoke(f, invokeOptionFunc(func(opts *invokeOptions) {
+		opts.caller = caller
+	}))
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocator(t *testing.T) {
+	newContainer := func(t *testing.T) *Container {
+		c := New()
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return bytes.NewBufferString("unnamed") }))
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return bytes.NewBufferString("named") }, Name(`a "quoted" name`)))
+		require.NoError(t, c.Provide(func() (io.Reader, error) { return nil, errors.New("great sadness") }))
+		return c
+	}
+
+	t.Run("get", func(t *testing.T) {
+		l := AsLocator(newContainer(t))
+
+		var buf *bytes.Buffer
+		require.NoError(t, l.Get(&buf))
+		assert.Equal(t, "unnamed", buf.String())
+
+		require.NoError(t, l.Get(&buf, ResolveName(`a "quoted" name`)))
+		assert.Equal(t, "named", buf.String())
+	})
+
+	t.Run("get group", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() string { return "a" }, Group("names")))
+		require.NoError(t, c.Provide(func() string { return "b" }, Group("names")))
+
+		var uses []LocatorUse
+		l := AsLocator(c, LogLocatorUse(func(u LocatorUse) {
+			uses = append(uses, u)
+		}))
+
+		var names []string
+		require.NoError(t, l.Get(&names, ResolveGroup("names")))
+		assert.ElementsMatch(t, []string{"a", "b"}, names)
+		require.Len(t, uses, 1)
+		assert.Equal(t, Key{Type: reflect.TypeOf(""), Group: "names"}, uses[0].Key)
+
+		var name string
+		err := l.Get(&name, ResolveGroup("names"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `cannot resolve value group "names" as string: must be a slice`)
+
+		err = l.Get(&names, ResolveGroup("names"), ResolveName("name"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(),
+			`cannot resolve []string with both dig.ResolveName("name") and dig.ResolveGroup("names")`)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		l := AsLocator(newContainer(t))
+
+		var r io.Reader
+		err := l.Get(&r)
+		require.Error(t, err)
+		assert.Equal(t, "great sadness", RootCause(err).Error())
+
+		var w io.Writer
+		err = l.Get(&w)
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`missing dependencies for function "go.uber.org/dig".TestLocator.\S+ \(\S+locator_test.go:\d+\):`,
+			`type io.Writer is not in the container`)
+
+		var buf *bytes.Buffer
+		assert.Error(t, l.Get(&buf, ResolveName("unknown")))
+
+		err = l.Get(buf)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must be a non-nil pointer")
+		assert.Error(t, l.Get(nil))
+		assert.Error(t, l.Get((**bytes.Buffer)(nil)))
+	})
+
+	t.Run("must get", func(t *testing.T) {
+		l := AsLocator(newContainer(t))
+
+		var buf *bytes.Buffer
+		assert.NotPanics(t, func() { l.MustGet(&buf) })
+		assert.Equal(t, "unnamed", buf.String())
+
+		var r io.Reader
+		assert.Panics(t, func() { l.MustGet(&r) })
+	})
+
+	t.Run("log use", func(t *testing.T) {
+		var uses []LocatorUse
+		l := AsLocator(newContainer(t), LogLocatorUse(func(u LocatorUse) {
+			uses = append(uses, u)
+		}))
+
+		var buf *bytes.Buffer
+		require.NoError(t, l.Get(&buf, ResolveName(`a "quoted" name`)))
+		var r io.Reader
+		assert.Panics(t, func() { l.MustGet(&r) })
+
+		require.Len(t, uses, 2)
+		assert.Equal(t, Key{Type: reflect.TypeOf(buf), Name: `a "quoted" name`}, uses[0].Key)
+		assert.Contains(t, uses[0].File, "locator_test.go")
+		assert.NotZero(t, uses[0].Line)
+		assert.NoError(t, uses[0].Err)
+
+		assert.Equal(t, Key{Type: reflect.TypeOf((*io.Reader)(nil)).Elem()}, uses[1].Key)
+		assert.Contains(t, uses[1].File, "locator_test.go")
+		assert.Equal(t, uses[0].Line+2, uses[1].Line)
+		assert.Error(t, uses[1].Err)
+	})
+
+	t.Run("reported at the call site", func(t *testing.T) {
+		c := newContainer(t)
+		var buf *bytes.Buffer
+		require.NoError(t, AsLocator(c).Get(&buf))
+		assert.Empty(t, c.invokes)
+
+		var w io.Writer
+		c = New(WithInvokeInterceptor(func(info InvokeInfo) error {
+			assert.Contains(t, info.Name, "TestLocator")
+			assert.Contains(t, info.File, "locator_test.go")
+			return nil
+		}))
+		require.NoError(t, c.Provide(func() io.Writer { return new(bytes.Buffer) }))
+		require.NoError(t, AsLocator(c).Get(&w))
+	})
+
+	t.Run("constructors are called once", func(t *testing.T) {
+		var calls int
+		c := New()
+		require.NoError(t, c.Provide(func() *bytes.Buffer {
+			calls++
+			return new(bytes.Buffer)
+		}))
+
+		l := AsLocator(c)
+		var a, b *bytes.Buffer
+		require.NoError(t, l.Get(&a))
+		require.NoError(t, l.Get(&b))
+		assert.True(t, a == b, "values must be shared")
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MemoizeByArgs is a ProvideOption that caches the results of a constructor
+// by the values of its arguments instead of only calling it once. When the
+// constructor has to be called again, for example because a value it
+// depends on was removed with RemoveNamed and provided again, it's only
+// called if its arguments differ from those of a previous call. Otherwise,
+// the results of that call are reused.
+//
+//   c.Provide(NewQueue, dig.MemoizeByArgs())
+//
+// All arguments of the constructor must be comparable, and the constructor
+// may not produce values for value groups. Arguments holding values that
+// turn out not to be comparable at runtime, such as interfaces holding
+// slices, are not cached.
+func MemoizeByArgs() ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.MemoizeByArgs = true
+	})
+}
+
+// checkMemoizable returns an error if a constructor with the given
+// parameters and results can't be memoized by its arguments.
+func checkMemoizable(pl paramList, rl resultList) error {
+	// Value groups are consumed as slices or functions, so parameter
+	// objects consuming them are not comparable either.
+	for i := range pl.Params {
+		if t := pl.ctype.In(i); !t.Comparable() {
+			return fmt.Errorf("cannot use dig.MemoizeByArgs with %v: argument %d of type %v is not comparable", pl.ctype, i+1, t)
+		}
+	}
+	if len(pl.Populated) > 0 {
+		return fmt.Errorf("cannot use dig.MemoizeByArgs with %v: it is provided with dig.PopulateFields", pl.ctype)
+	}
+	if hasGroupedResults(rl) {
+		return fmt.Errorf("cannot use dig.MemoizeByArgs with %v: it produces values for value groups", rl.ctype)
+	}
+	return nil
+}
+
+func hasGroupedResults(r result) bool {
+	switch r := r.(type) {
+	case resultGrouped:
+		return true
+	case resultObject:
+		for _, f := range r.Fields {
+			if hasGroupedResults(f.Result) {
+				return true
+			}
+		}
+	case resultList:
+		for _, r := range r.Results {
+			if hasGroupedResults(r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newMemoKey returns a comparable value that identifies the given arguments,
+// or nil if they can't be compared.
+func newMemoKey(args []reflect.Value) interface{} {
+	k := reflect.New(reflect.ArrayOf(len(args), _interfaceType)).Elem()
+	for i, arg := range args {
+		if !arg.Comparable() {
+			return nil
+		}
+		k.Index(i).Set(arg)
+	}
+	return k.Interface()
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoizeByArgs(t *testing.T) {
+	type QueueName string
+	type Queue struct{ Name QueueName }
+
+	type queueParams struct {
+		In
+
+		Name QueueName `name:"queue"`
+	}
+
+	t.Run("cached by arguments", func(t *testing.T) {
+		var calls int
+		c := New()
+		require.NoError(t, c.Provide(func(p queueParams) *Queue {
+			calls++
+			return &Queue{Name: p.Name}
+		}, MemoizeByArgs()))
+
+		useName := func(name QueueName) *Queue {
+			// Replace the name so that the queue is built again.
+			if len(c.NamesFor((*QueueName)(nil))) > 0 {
+				require.NoError(t, c.RemoveNamed((*QueueName)(nil), "queue"))
+			}
+			require.NoError(t, c.Provide(func() QueueName { return name }, Name("queue")))
+
+			var q *Queue
+			require.NoError(t, c.Invoke(func(got *Queue) { q = got }))
+			assert.Equal(t, name, q.Name)
+			return q
+		}
+
+		a := useName("a")
+		b := useName("b")
+		assert.False(t, a == b, "different arguments must produce different values")
+		assert.Equal(t, 2, calls)
+
+		assert.True(t, useName("a") == a, "same arguments must reuse the value")
+		assert.True(t, useName("b") == b, "same arguments must reuse the value")
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("failures are not cached", func(t *testing.T) {
+		var calls int
+		c := New()
+		require.NoError(t, c.Provide(func() QueueName { return "a" }))
+		require.NoError(t, c.Provide(func(QueueName) (*Queue, error) {
+			calls++
+			if calls == 1 {
+				return nil, assert.AnError
+			}
+			return new(Queue), nil
+		}, MemoizeByArgs()))
+
+		assert.Error(t, c.Invoke(func(*Queue) {}))
+		assert.NoError(t, c.Invoke(func(*Queue) {}))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("non-comparable values are not cached", func(t *testing.T) {
+		var calls int
+		c := New()
+		require.NoError(t, c.Provide(func() interface{} { return []string{"a"} }, Name("v")))
+		require.NoError(t, c.Provide(func(p struct {
+			In
+
+			V interface{} `name:"v"`
+		}) *Queue {
+			calls++
+			return new(Queue)
+		}, MemoizeByArgs()))
+
+		require.NoError(t, c.Invoke(func(*Queue) {}))
+		require.NoError(t, c.RemoveNamed((*interface{})(nil), "v"))
+		require.NoError(t, c.Provide(func() interface{} { return []string{"a"} }, Name("v")))
+		require.NoError(t, c.Invoke(func(*Queue) {}))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("invalid constructors", func(t *testing.T) {
+		type groupOut struct {
+			Out
+
+			Queue *Queue `group:"queues"`
+		}
+		type groupIn struct {
+			In
+
+			Queues []*Queue `group:"queues"`
+		}
+
+		tests := []struct {
+			desc string
+			ctor interface{}
+			opts []ProvideOption
+			err  string
+		}{
+			{
+				desc: "non-comparable argument",
+				ctor: func([]string) *Queue { return nil },
+				err:  "argument 1 of type []string is not comparable",
+			},
+			{
+				desc: "non-comparable field",
+				ctor: func(struct {
+					In
+
+					Names []string
+				}) *Queue {
+					return nil
+				},
+				err: "is not comparable",
+			},
+			{
+				desc: "group argument",
+				ctor: func(groupIn) QueueName { return "" },
+				err:  "is not comparable",
+			},
+			{
+				desc: "group result",
+				ctor: func() groupOut { return groupOut{} },
+				err:  "it produces values for value groups",
+			},
+			{
+				desc: "group option",
+				ctor: func() *Queue { return nil },
+				opts: []ProvideOption{Group("queues")},
+				err:  "it produces values for value groups",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				err := New().Provide(tt.ctor, append(tt.opts, MemoizeByArgs())...)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "cannot use dig.MemoizeByArgs")
+				assert.Contains(t, err.Error(), tt.err)
+			})
+		}
+	})
+}
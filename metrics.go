@@ -0,0 +1,214 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is a snapshot of the operational counters of a Container.
+//
+// Counters are cumulative over the lifetime of the Container.
+type Metrics struct {
+	// Number of constructors successfully provided to the container.
+	Providers int
+
+	// Number of values committed into the container by constructors. Every
+	// member of a value group counts as a separate value.
+	ValuesConstructed int
+
+	// Number of constructor calls that returned an error.
+	ConstructorFailures int
+
+	// Total time spent inside constructors, successful or not.
+	ConstructorTime time.Duration
+
+	// Number of calls to Invoke.
+	Invokes int
+
+	// Number of calls to Invoke that returned an error.
+	InvokeFailures int
+
+	// Number of values submitted to each value group, keyed by the group as
+	// formatted by Key.String. Values submitted to the same group in
+	// different scopes are counted together.
+	GroupValues map[string]int
+
+	// Number of times each value was constructed, keyed by the value as
+	// formatted by Key.String. See Container.ConstructionCounts.
+	Constructions map[string]int
+}
+
+// containerMetrics holds the live counters backing Metrics. All fields are
+// accessed atomically.
+type containerMetrics struct {
+	providers           int64
+	valuesConstructed   int64
+	constructorFailures int64
+	constructorTime     int64 // nanoseconds
+	invokes             int64
+	invokeFailures      int64
+
+	mu            sync.Mutex
+	groupValues   map[key]int
+	constructions map[key]int
+}
+
+func (m *containerMetrics) recordProvide() {
+	atomic.AddInt64(&m.providers, 1)
+}
+
+func (m *containerMetrics) recordConstructor(d time.Duration, values int, err error) {
+	atomic.AddInt64(&m.constructorTime, int64(d))
+	if err != nil {
+		atomic.AddInt64(&m.constructorFailures, 1)
+		return
+	}
+	atomic.AddInt64(&m.valuesConstructed, int64(values))
+}
+
+func (m *containerMetrics) recordGroupValue(k key) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.groupValues == nil {
+		m.groupValues = make(map[key]int)
+	}
+	m.groupValues[k]++
+}
+
+// recordConstructions counts the values staged by a call to the constructor
+// of the given node.
+func (m *containerMetrics) recordConstructions(n *node, sr *stagingContainerWriter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.constructions == nil {
+		m.constructions = make(map[key]int)
+	}
+	for _, k := range sr.sortedKeys() {
+		if _, ok := n.removedKeys[k]; !ok {
+			m.constructions[k]++
+		}
+	}
+}
+
+func (m *containerMetrics) recordInvoke(err error) {
+	atomic.AddInt64(&m.invokes, 1)
+	if err != nil {
+		atomic.AddInt64(&m.invokeFailures, 1)
+	}
+}
+
+func (m *containerMetrics) snapshot() Metrics {
+	var groupValues, constructions map[string]int
+	m.mu.Lock()
+	if len(m.groupValues) > 0 {
+		groupValues = make(map[string]int, len(m.groupValues))
+		for k, n := range m.groupValues {
+			groupValues[k.String()] += n
+		}
+	}
+	if len(m.constructions) > 0 {
+		constructions = make(map[string]int, len(m.constructions))
+		for k, n := range m.constructions {
+			constructions[k.String()] += n
+		}
+	}
+	m.mu.Unlock()
+
+	return Metrics{
+		Providers:           int(atomic.LoadInt64(&m.providers)),
+		ValuesConstructed:   int(atomic.LoadInt64(&m.valuesConstructed)),
+		ConstructorFailures: int(atomic.LoadInt64(&m.constructorFailures)),
+		ConstructorTime:     time.Duration(atomic.LoadInt64(&m.constructorTime)),
+		Invokes:             int(atomic.LoadInt64(&m.invokes)),
+		InvokeFailures:      int(atomic.LoadInt64(&m.invokeFailures)),
+		GroupValues:         groupValues,
+		Constructions:       constructions,
+	}
+}
+
+// Metrics returns a snapshot of the container's operational counters.
+func (c *Container) Metrics() Metrics {
+	if c.checkInitialized() != nil {
+		return Metrics{}
+	}
+	return c.metrics.snapshot()
+}
+
+// ConstructionCounts returns the number of times each value was constructed
+// by the container and its scopes. Values constructed by different scopes
+// are counted together. Members of value groups are counted under the key
+// of their group, once per constructor call.
+//
+//   for k, n := range c.ConstructionCounts() {
+//     if n > 1 && k.Group == "" {
+//       log.Printf("%v was constructed %d times", k, n)
+//     }
+//   }
+//
+// Counts are cumulative: they include values that were since removed, and
+// values of pooled scopes that were returned to their pool. Use
+// ResetConstructionCounts to start over.
+func (c *Container) ConstructionCounts() map[Key]int {
+	if c.checkInitialized() != nil {
+		return nil
+	}
+	return c.metrics.constructionCounts()
+}
+
+// ResetConstructionCounts clears the counts returned by ConstructionCounts
+// for the container and its scopes.
+func (c *Container) ResetConstructionCounts() {
+	if c.checkInitialized() != nil {
+		return
+	}
+	c.metrics.mu.Lock()
+	defer c.metrics.mu.Unlock()
+	c.metrics.constructions = nil
+}
+
+func (m *containerMetrics) constructionCounts() map[Key]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make(map[Key]int, len(m.constructions))
+	for k, n := range m.constructions {
+		counts[k.exported()] = n
+	}
+	return counts
+}
+
+// WithMetricsSink is an Option that calls the provided function with a
+// snapshot of the container's Metrics after each call to Invoke, successful
+// or not.
+//
+//   c := dig.New(dig.WithMetricsSink(func(m dig.Metrics) {
+//     stats.Gauge("dig.invoke_failures").Update(float64(m.InvokeFailures))
+//   }))
+func WithMetricsSink(sink func(Metrics)) Option {
+	return optionFunc(func(c *Container) {
+		c.metricsSink = sink
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("empty container", func(t *testing.T) {
+		assert.Equal(t, Metrics{}, New().Metrics())
+	})
+
+	t.Run("counts providers, values and invokes", func(t *testing.T) {
+		type out struct {
+			Out
+
+			A int `group:"ints"`
+			B int `group:"ints"`
+		}
+
+		type in struct {
+			In
+
+			Ints []int `group:"ints"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }))
+		require.NoError(t, c.Provide(func() (string, out) { return "foo", out{A: 1, B: 2} }))
+		require.Error(t, c.Provide(func() {}), "provide must fail")
+
+		require.NoError(t, c.Invoke(func(*bytes.Buffer, string) {}))
+		require.NoError(t, c.Invoke(func(*bytes.Buffer, in) {}))
+
+		m := c.Metrics()
+		assert.Equal(t, 2, m.Providers)
+		assert.Equal(t, 4, m.ValuesConstructed)
+		assert.Equal(t, 0, m.ConstructorFailures)
+		assert.Equal(t, 2, m.Invokes)
+		assert.Equal(t, 0, m.InvokeFailures)
+		assert.Equal(t, map[string]int{`int[group="ints"]`: 2}, m.GroupValues)
+		assert.Equal(t, map[string]int{
+			"*bytes.Buffer":     1,
+			"string":            1,
+			`int[group="ints"]`: 1,
+		}, m.Constructions)
+	})
+
+	t.Run("counts failures", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() (*bytes.Buffer, error) {
+			return nil, errors.New("great sadness")
+		}))
+
+		require.Error(t, c.Invoke(func(*bytes.Buffer) {}))
+		require.Error(t, c.Invoke(func(string) {}))
+		require.Error(t, c.Invoke(func() error { return errors.New("oh no") }))
+
+		m := c.Metrics()
+		assert.Equal(t, 1, m.ConstructorFailures)
+		assert.Equal(t, 0, m.ValuesConstructed)
+		assert.Equal(t, 3, m.Invokes)
+		assert.Equal(t, 3, m.InvokeFailures)
+	})
+
+	t.Run("sink is called after each invoke", func(t *testing.T) {
+		var got []Metrics
+		c := New(WithMetricsSink(func(m Metrics) {
+			got = append(got, m)
+		}))
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }))
+
+		require.NoError(t, c.Invoke(func(*bytes.Buffer) {}))
+		require.Error(t, c.Invoke(func(string) {}))
+
+		require.Len(t, got, 2)
+		assert.Equal(t, 1, got[0].Invokes)
+		assert.Equal(t, 0, got[0].InvokeFailures)
+		assert.Equal(t, 1, got[0].ValuesConstructed)
+		assert.Equal(t, 2, got[1].Invokes)
+		assert.Equal(t, 1, got[1].InvokeFailures)
+	})
+
+	t.Run("construction counts", func(t *testing.T) {
+		type Session struct{}
+
+		c := New()
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }))
+		require.NoError(t, c.Provide(func() string { return "x" }, Group("names")))
+		require.NoError(t, c.Provide(func() string { return "y" }, Group("names")))
+		pool := NewScopePool(c)
+		for i := 0; i < 3; i++ {
+			s := pool.Get()
+			require.NoError(t, s.Provide(func() *Session { return &Session{} }))
+			require.NoError(t, s.Invoke(func(*bytes.Buffer, *Session, struct {
+				In
+
+				Names []string `group:"names"`
+			}) {
+			}))
+			pool.Put(s)
+		}
+
+		want := map[Key]int{
+			{Type: reflect.TypeOf(new(bytes.Buffer))}:  1,
+			{Type: reflect.TypeOf(&Session{})}:         3,
+			{Type: reflect.TypeOf(""), Group: "names"}: 2,
+		}
+		assert.Equal(t, want, c.ConstructionCounts(), "counts must survive Put")
+		assert.Equal(t, want, pool.Get().ConstructionCounts(), "counts must be shared with scopes")
+		assert.Equal(t, 3, c.Metrics().Constructions["*dig.Session"])
+
+		c.ResetConstructionCounts()
+		assert.Empty(t, c.ConstructionCounts())
+		assert.Nil(t, c.Metrics().Constructions)
+	})
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// A MountOption modifies the default behavior of Mount.
+type MountOption interface {
+	applyMountOption(*mountOptions)
+}
+
+type mountOptions struct {
+	// Types of unnamed values made available without a prefix.
+	Exports []reflect.Type
+}
+
+type mountOptionFunc func(*mountOptions)
+
+func (f mountOptionFunc) applyMountOption(opts *mountOptions) { f(opts) }
+
+// ReExport is a MountOption that makes the unnamed values of the types
+// pointed to by the given pointers available in the container as unnamed
+// values, as if the mounted container's constructors were provided to it.
+//
+//   c.Mount("payments", vendor, dig.ReExport(new(*payments.Client)))
+func ReExport(targets ...interface{}) MountOption {
+	return mountOptionFunc(func(opts *mountOptions) {
+		for _, t := range targets {
+			opts.Exports = append(opts.Exports, reflect.TypeOf(t))
+		}
+	})
+}
+
+// Mount makes the values of another container available in this one with
+// the given prefix, so that containers built independently can be composed
+// without conflicts.
+//
+//   c.Mount("payments", vendor)
+//
+// Named values of the mounted container are available with the prefix
+// followed by a dot and their name, so the "primary" *sql.DB of the vendor
+// container above is available as the "payments.primary" *sql.DB. Value
+// groups are prefixed the same way. Unnamed values are only available if
+// they are re-exported with ReExport.
+//
+// Values are built by the mounted container and shared with it: their
+// constructors are still called at most once, whichever container requests
+// them first. Only the values provided to the mounted container before the
+// call to Mount are available. A container can't be mounted into one that
+// it already resolves values from, directly or through other mounts.
+func (c *Container) Mount(prefix string, m *Container, opts ...MountOption) error {
+	if err := c.checkInitialized(); err != nil {
+		return err
+	}
+	if err := c.checkMutable(); err != nil {
+		return err
+	}
+	if err := m.checkInitialized(); err != nil {
+		return errWrapf(err, "cannot mount container as %q", prefix)
+	}
+	if err := c.mount(prefix, m, opts); err != nil {
+		return errWrapf(err, "cannot mount container as %q", prefix)
+	}
+	return nil
+}
+
+func (c *Container) mount(prefix string, m *Container, opts []MountOption) error {
+	var options mountOptions
+	for _, o := range opts {
+		o.applyMountOption(&options)
+	}
+
+	if prefix == "" {
+		return errors.New("the prefix cannot be empty")
+	}
+	if m.resolvesFrom(c) {
+		return errors.New("the container already resolves values from this container")
+	}
+
+	exports := make(map[key]struct{})
+	for _, t := range options.Exports {
+		if t == nil || t.Kind() != reflect.Ptr {
+			return fmt.Errorf("invalid dig.ReExport(%v): expected a pointer", t)
+		}
+		k := key{t: t.Elem()}
+		if len(m.getProviders(k)) == 0 {
+			return fmt.Errorf("cannot re-export %v: it is not provided by the mounted container", k)
+		}
+		exports[k] = struct{}{}
+	}
+
+	caller := digreflect.InspectCaller(2)
+	for _, kk := range m.KnownKeys() {
+		k := key{t: kk.Type, name: kk.Name, group: kk.Group}
+		var err error
+		switch {
+		case k.group != "":
+			err = c.mountGroup(prefix, m, k, caller)
+		case k.name != "":
+			err = c.mountValue(prefix, m, k, prefix+"."+k.name, caller)
+		default:
+			if _, ok := exports[k]; ok {
+				err = c.mountValue(prefix, m, k, "", caller)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	c.mounts = append(c.mounts, m)
+	return nil
+}
+
+// resolvesFrom reports whether building the values of this container may
+// build values of other, through parents or mounted containers.
+func (c *Container) resolvesFrom(other *Container) bool {
+	for _, s := range c.scopeChain() {
+		if s == other {
+			return true
+		}
+		for _, m := range s.mounts {
+			if m.resolvesFrom(other) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mountValue provides a constructor that produces the value with the given
+// key by building it in the mounted container m.
+func (c *Container) mountValue(prefix string, m *Container, k key, name string, caller *digreflect.Func) error {
+	ftype := reflect.FuncOf(nil /* in */, []reflect.Type{k.t, _errType}, false /* variadic */)
+	ctor := reflect.MakeFunc(ftype, func([]reflect.Value) []reflect.Value {
+		v, err := paramSingle{Name: k.name, Type: k.t}.Build(m)
+		if err != nil {
+			return []reflect.Value{reflect.Zero(k.t), reflect.ValueOf(&err).Elem()}
+		}
+		return []reflect.Value{v, reflect.Zero(_errType)}
+	})
+
+	return c.provide(ctor.Interface(), provideOptions{
+		Name:     name,
+		Location: caller,
+		Mount:    prefix,
+	})
+}
+
+// mountGroup provides a constructor for each constructor of the value group
+// with the given key in the mounted container m. Each one submits the values
+// of the original constructor to the prefixed value group.
+func (c *Container) mountGroup(prefix string, m *Container, k key, caller *digreflect.Func) error {
+	tag := reflect.StructTag(_groupTag + ":" + strconv.Quote(prefix+"."+k.group))
+	for _, p := range m.getGroupProviders(k.group, k.t) {
+		p := p
+
+		fields := []reflect.StructField{{Name: "Out", Type: _outType, Anonymous: true}}
+		for i := 0; i < countGroupResults(p.ResultList(), k); i++ {
+			fields = append(fields, reflect.StructField{
+				Name: fmt.Sprintf("Value%d", i),
+				Type: k.t,
+				Tag:  tag,
+			})
+		}
+		out := reflect.StructOf(fields)
+
+		ftype := reflect.FuncOf(nil /* in */, []reflect.Type{out, _errType}, false /* variadic */)
+		ctor := reflect.MakeFunc(ftype, func([]reflect.Value) []reflect.Value {
+			if err := p.Call(p.OrigScope()); err != nil {
+				err = errParamGroupFailed{CtorID: p.ID(), Key: k, Reason: err}
+				return []reflect.Value{reflect.Zero(out), reflect.ValueOf(&err).Elem()}
+			}
+
+			result := reflect.New(out).Elem()
+			for i, v := range p.GroupValues(k.group, k.t) {
+				result.Field(i + 1).Set(v)
+			}
+			return []reflect.Value{result, reflect.Zero(_errType)}
+		})
+
+		err := c.provide(ctor.Interface(), provideOptions{Location: caller, Mount: prefix})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countGroupResults returns the number of values that a constructor with
+// the given results submits to the value group with the given key.
+func countGroupResults(rl resultList, k key) int {
+	var count int
+	walkResult(rl, groupResultCounter{key: k, count: &count})
+	return count
+}
+
+// groupResultCounter is a resultVisitor that counts the results submitted
+// to a value group.
+type groupResultCounter struct {
+	key   key
+	count *int
+}
+
+func (gc groupResultCounter) Visit(res result) resultVisitor {
+	r, ok := res.(resultGrouped)
+	if !ok {
+		return gc
+	}
+	for _, g := range r.Groups {
+		if g == gc.key.group && r.Type == gc.key.t {
+			*gc.count++
+		}
+	}
+	for _, as := range r.As {
+		if as.Group == gc.key.group && as.Type == gc.key.t {
+			*gc.count++
+		}
+	}
+	return gc
+}
+
+func (gc groupResultCounter) AnnotateWithField(resultObjectField) resultVisitor { return gc }
+func (gc groupResultCounter) AnnotateWithPosition(int) resultVisitor            { return gc }
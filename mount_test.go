@@ -0,0 +1,190 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMount(t *testing.T) {
+	type DB struct{ name string }
+	type Client struct{}
+
+	newVendor := func(t *testing.T, calls *int) *Container {
+		v := New()
+		require.NoError(t, v.Provide(func() *DB {
+			*calls++
+			return &DB{name: "primary"}
+		}, Name("primary")))
+		require.NoError(t, v.Provide(func(in struct {
+			In
+
+			DB *DB `name:"primary"`
+		}) *Client {
+			*calls++
+			return &Client{}
+		}))
+		require.NoError(t, v.Provide(func() string { return "a" }, Group("names")))
+		require.NoError(t, v.Provide(func() (out struct {
+			Out
+
+			B string `group:"names"`
+			C string `group:"names"`
+		}) {
+			out.B, out.C = "b", "c"
+			return out
+		}))
+		return v
+	}
+
+	t.Run("named values", func(t *testing.T) {
+		var calls int
+		v := newVendor(t, &calls)
+		c := New()
+		require.NoError(t, c.Mount("payments", v))
+
+		type in struct {
+			In
+
+			DB *DB `name:"payments.primary"`
+		}
+		var db *DB
+		require.NoError(t, c.Invoke(func(i in) { db = i.DB }))
+		assert.Equal(t, "primary", db.name)
+
+		require.NoError(t, v.Invoke(func(i struct {
+			In
+
+			DB *DB `name:"primary"`
+		}) {
+			assert.True(t, i.DB == db, "values must be shared with the mounted container")
+		}))
+		assert.Equal(t, 1, calls, "constructors must be called once")
+	})
+
+	t.Run("value groups", func(t *testing.T) {
+		var calls int
+		c := New()
+		require.NoError(t, c.Mount("payments", newVendor(t, &calls)))
+		require.NoError(t, c.Provide(func() string { return "mine" }, Group("names")))
+
+		require.NoError(t, c.Invoke(func(i struct {
+			In
+
+			Vendor []string `group:"payments.names"`
+			Mine   []string `group:"names"`
+		}) {
+			assert.ElementsMatch(t, []string{"a", "b", "c"}, i.Vendor)
+			assert.Equal(t, []string{"mine"}, i.Mine)
+		}))
+	})
+
+	t.Run("unnamed values", func(t *testing.T) {
+		var calls int
+		c := New()
+		require.NoError(t, c.Mount("payments", newVendor(t, &calls)))
+		require.Error(t, c.Invoke(func(*Client) {}), "unnamed values must not be exported")
+
+		c = New()
+		v := newVendor(t, &calls)
+		require.NoError(t, c.Mount("payments", v, ReExport(new(*Client))))
+
+		var client *Client
+		require.NoError(t, c.Invoke(func(cl *Client) { client = cl }))
+		require.NoError(t, v.Invoke(func(cl *Client) {
+			assert.True(t, cl == client, "values must be shared with the mounted container")
+		}))
+
+		err := New().Mount("payments", v, ReExport(new(*bytes.Buffer)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(),
+			`cannot mount container as "payments": cannot re-export *bytes.Buffer: `+
+				`it is not provided by the mounted container`)
+	})
+
+	t.Run("constructor errors", func(t *testing.T) {
+		v := New()
+		require.NoError(t, v.Provide(func() (*DB, error) {
+			return nil, errors.New("great sadness")
+		}, Name("primary")))
+
+		c := New()
+		require.NoError(t, c.Mount("payments", v))
+		err := c.Invoke(func(struct {
+			In
+
+			DB *DB `name:"payments.primary"`
+		}) {
+		})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestMount\S+ \(\S+mount_test.go:\d+\) returned a non-nil error:`,
+			`function "go.uber.org/dig".TestMount\S+ \(\S+mount_test.go:\d+\) returned a non-nil error: great sadness`)
+		assert.Equal(t, "great sadness", RootCause(err).Error())
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		var calls int
+		c := New()
+		require.NoError(t, c.Provide(func() *DB { return &DB{} }, Name("payments.primary")))
+
+		err := c.Mount("payments", newVendor(t, &calls))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `cannot provide *dig.DB[name="payments.primary"]`)
+	})
+
+	t.Run("cycles", func(t *testing.T) {
+		a, b := New(), New()
+		require.NoError(t, a.Provide(func() *DB { return &DB{} }, Name("db")))
+		require.NoError(t, b.Provide(func() *DB { return &DB{} }, Name("db")))
+		require.NoError(t, a.Mount("b", b))
+
+		err := b.Mount("a", a)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(),
+			`cannot mount container as "a": the container already resolves values from this container`)
+
+		assert.Error(t, a.Mount("self", a))
+		assert.NoError(t, a.Scope("child").Mount("parent", a), "parents don't resolve from their scopes")
+		assert.Error(t, New().Mount("", a))
+	})
+
+	t.Run("visualize", func(t *testing.T) {
+		var calls int
+		c := New()
+		require.NoError(t, c.Mount("payments", newVendor(t, &calls)))
+
+		var buf bytes.Buffer
+		require.NoError(t, Visualize(c, &buf))
+		assert.Contains(t, buf.String(), `label="payments";`)
+
+		gv := Graph(c)
+		require.NotEmpty(t, gv.Ctors)
+		for _, ctor := range gv.Ctors {
+			assert.Equal(t, "payments", ctor.Mount)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"sort"
+
+	"go.uber.org/dig/internal/dot"
+)
+
+// MultiError reports several failures at once, such as all missing
+// dependencies of a function or all problems found by LintWiring.
+//
+// Use errors.As to retrieve it from errors returned by dig. Its Unwrap method
+// returns the individual failures, so errors.Is and errors.As find them too.
+type MultiError struct {
+	errs []error
+
+	// Written before the first failure and before each failure, and between
+	// failures.
+	header, prefix, sep string
+
+	// Describe a single failure with its own message only.
+	unwrapSingle bool
+}
+
+// keyedError is a failure related to a single value or value group.
+// MultiErrors sort these failures by key and combine failures for the same
+// key.
+type keyedError interface {
+	error
+
+	errorKey() key
+
+	// merge combines this failure with another failure for the same key.
+	merge(other keyedError) keyedError
+}
+
+// shortError is a failure that has a shorter description for use in a
+// MultiError, which already describes the problem as a whole.
+type shortError interface {
+	shortError() string
+}
+
+// newMultiError builds a MultiError from the given failures. If all
+// failures are keyedErrors, they are sorted by key and failures for the same
+// key are merged.
+func newMultiError(errs []error) MultiError {
+	keyed := make([]keyedError, 0, len(errs))
+	for _, err := range errs {
+		if ke, ok := err.(keyedError); ok {
+			keyed = append(keyed, ke)
+		}
+	}
+	if len(keyed) < len(errs) {
+		return MultiError{errs: errs}
+	}
+
+	sort.SliceStable(keyed, func(i, j int) bool {
+		return keyLess(keyed[i].errorKey(), keyed[j].errorKey())
+	})
+
+	merged := make([]error, 0, len(keyed))
+	for i, ke := range keyed {
+		if i > 0 && ke.errorKey() == keyed[i-1].errorKey() {
+			last := merged[len(merged)-1].(keyedError)
+			merged[len(merged)-1] = last.merge(ke)
+			continue
+		}
+		merged = append(merged, ke)
+	}
+	return MultiError{errs: merged}
+}
+
+// keyLess orders keys by type name, then by name and group.
+func keyLess(a, b key) bool {
+	if ta, tb := a.t.String(), b.t.String(); ta != tb {
+		return ta < tb
+	}
+	if a.name != b.name {
+		return a.name < b.name
+	}
+	return a.group < b.group
+}
+
+func (e MultiError) Error() string {
+	if len(e.errs) == 1 && e.unwrapSingle {
+		return e.errs[0].Error()
+	}
+
+	b := new(bytes.Buffer)
+	b.WriteString(e.header)
+	for i, err := range e.errs {
+		if i > 0 {
+			b.WriteString(e.sep)
+		}
+		b.WriteString(e.prefix)
+		if se, ok := err.(shortError); ok {
+			b.WriteString(se.shortError())
+		} else {
+			b.WriteString(err.Error())
+		}
+	}
+	return b.String()
+}
+
+// Unwrap returns the individual failures.
+func (e MultiError) Unwrap() []error {
+	return append([]error(nil), e.errs...)
+}
+
+func (e MultiError) updateGraph(g *dot.Graph) {
+	// Missing values are added at once so that they're all root causes.
+	var missing []*dot.Result
+	for _, err := range e.errs {
+		switch err := err.(type) {
+		case errMissingType:
+			missing = append(missing, err.dotResult())
+		case errVisualizer:
+			err.updateGraph(g)
+		}
+	}
+	if len(missing) > 0 {
+		g.AddMissingNodes(missing)
+	}
+}
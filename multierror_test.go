@@ -0,0 +1,102 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// verifyErrorMessage compares the message of err with the contents of
+// testdata/<testname>.txt.
+func verifyErrorMessage(t *testing.T, testname string, err error) {
+	file := filepath.Join("testdata", testname+".txt")
+	if *generate {
+		require.NoError(t, ioutil.WriteFile(file, []byte(err.Error()), 0644))
+		return
+	}
+
+	want, rerr := ioutil.ReadFile(file)
+	require.NoError(t, rerr)
+	assert.Equal(t, string(want), err.Error(),
+		"Output did not match. Make sure you updated the testdata by running 'go test -generate'")
+}
+
+func TestMultiError(t *testing.T) {
+	type A struct{}
+	type B struct{}
+	type params struct {
+		In
+
+		A  A
+		B  *B `name:"b"`
+		B2 *B `name:"b" optional:"true"`
+		C  string
+	}
+
+	newContainer := func() *Container {
+		c := New(WarnDuplicateParams(func(error) {}))
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Provide(func() *B { return &B{} }, Name("c")))
+		return c
+	}
+
+	t.Run("sorted and deduplicated", func(t *testing.T) {
+		c := newContainer()
+		require.NoError(t, c.Provide(func(*B, A, params) int { return 0 }))
+
+		err := c.Invoke(func(int) {})
+		require.Error(t, err)
+
+		var merr MultiError
+		require.True(t, errors.As(err, &merr), "must be a MultiError")
+		verifyErrorMessage(t, "multierror-missing", merr)
+		assert.Len(t, merr.Unwrap(), 4, "duplicate keys must be merged")
+	})
+
+	t.Run("single failure", func(t *testing.T) {
+		c := newContainer()
+
+		err := c.Invoke(func(A, A) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`argument 1 and argument 2: type dig.A is not in the container, did you mean to use \*dig.A\?$`)
+
+		var merr MultiError
+		require.True(t, errors.As(err, &merr), "must be a MultiError")
+		assert.Len(t, merr.Unwrap(), 1)
+	})
+
+	t.Run("unwrap", func(t *testing.T) {
+		sentinel := errors.New("great sadness")
+		merr := newMultiError([]error{errors.New("foo"), sentinel})
+		assert.True(t, errors.Is(merr, sentinel), "errors.Is must find members")
+
+		errs := merr.Unwrap()
+		errs[0] = nil
+		assert.NotNil(t, merr.Unwrap()[0], "Unwrap must return a copy")
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// Edge is a dependency of a constructor on a value or value group that it
+// doesn't declare as a parameter, because it consumes the value through an
+// Invoke made from inside the constructor. See Container.HiddenEdges.
+type Edge struct {
+	// Name, package, and location of the constructor.
+	Name    string
+	Package string
+	File    string
+	Line    int
+
+	// Value or value group consumed by the function the constructor
+	// invoked.
+	Key Key
+}
+
+func (e Edge) String() string {
+	return fmt.Sprintf("%q.%v (%v:%v) depends on %v", e.Package, e.Name, e.File, e.Line, e.Key)
+}
+
+// hiddenEdges records the Edges observed by a container. Scopes share the
+// edges of their root container.
+type hiddenEdges struct {
+	mu    sync.Mutex
+	edges []Edge
+	seen  map[Edge]struct{}
+}
+
+func (h *hiddenEdges) add(e Edge) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.seen[e]; ok {
+		return
+	}
+	if h.seen == nil {
+		h.seen = make(map[Edge]struct{})
+	}
+	h.seen[e] = struct{}{}
+	h.edges = append(h.edges, e)
+}
+
+func (h *hiddenEdges) list() []Edge {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Edge(nil), h.edges...)
+}
+
+// DisallowNestedInvokes is an Option that makes Invokes made from inside
+// constructors fail. Such Invokes hide the dependencies of the constructors
+// from Visualize and from cycle detection.
+//
+//   c := dig.New(dig.DisallowNestedInvokes())
+//
+// This includes calls to Construct and to the methods of a Locator. By
+// default, the dependencies consumed by nested Invokes are reported by
+// Container.HiddenEdges instead.
+func DisallowNestedInvokes() Option {
+	return optionFunc(func(c *Container) {
+		c.disallowNestedInvokes = true
+	})
+}
+
+// HiddenEdges returns the dependencies that constructors of the container,
+// its parents, and its scopes consumed through Invokes made from inside
+// them so far, in the order in which they were first observed. Each should
+// be declared as a parameter of its constructor instead.
+//
+//   for _, e := range c.HiddenEdges() {
+//     log.Printf("hidden dependency: %v", e)
+//   }
+func (c *Container) HiddenEdges() []Edge {
+	if c.checkInitialized() != nil {
+		return nil
+	}
+	return c.hidden.list()
+}
+
+// checkNestedInvoke records the dependencies of a function invoked from
+// inside a constructor as hidden edges of that constructor, or returns an
+// error if nested Invokes are disallowed. It does nothing for functions
+// invoked outside constructors.
+func (c *Container) checkNestedInvoke(fn *digreflect.Func, pl paramList) error {
+	fns := c.calls.constructing.fns
+	if len(fns) == 0 {
+		return nil
+	}
+	ctor := fns[len(fns)-1]
+	if c.disallowNestedInvokes {
+		return errNestedInvoke{Func: fn, Constructor: ctor}
+	}
+	for _, k := range newInvokeInfo(fn, pl).Params {
+		c.hidden.add(Edge{
+			Name:    ctor.Name,
+			Package: ctor.Package,
+			File:    ctor.File,
+			Line:    ctor.Line,
+			Key:     k,
+		})
+	}
+	return nil
+}
+
+// errNestedInvoke is returned by Invokes made from inside constructors of
+// containers created with DisallowNestedInvokes.
+type errNestedInvoke struct {
+	Func        *digreflect.Func
+	Constructor *digreflect.Func
+}
+
+func (e errNestedInvoke) Error() string {
+	return fmt.Sprintf("cannot invoke function %v from inside constructor %v: "+
+		"nested Invokes hide the dependencies of constructors; accept them as parameters of the constructor instead",
+		e.Func, e.Constructor)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHiddenEdges(t *testing.T) {
+	type dep struct{}
+	type widget struct{ dep *dep }
+
+	provideWidget := func(t *testing.T, c *Container) {
+		require.NoError(t, c.Provide(func() *dep { return &dep{} }))
+		require.NoError(t, c.Provide(func() (*widget, error) {
+			var w widget
+			err := c.Invoke(func(d *dep) { w.dep = d })
+			return &w, err
+		}))
+	}
+
+	t.Run("recorded", func(t *testing.T) {
+		c := New()
+		provideWidget(t, c)
+		assert.Empty(t, c.HiddenEdges())
+
+		require.NoError(t, c.Invoke(func(w *widget) {
+			assert.NotNil(t, w.dep)
+		}))
+
+		edges := c.HiddenEdges()
+		require.Len(t, edges, 1)
+		e := edges[0]
+		assert.Equal(t, Key{Type: reflect.TypeOf(&dep{})}, e.Key)
+		assert.Contains(t, e.Name, "TestHiddenEdges")
+		assert.Equal(t, "go.uber.org/dig", e.Package)
+		assert.Contains(t, e.File, "nested_test.go")
+		assert.Regexp(t,
+			`^"go.uber.org/dig".TestHiddenEdges\S+ \(\S+/nested_test.go:\d+\) depends on \*dig.dep$`, e.String())
+	})
+
+	t.Run("top-level invokes", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *dep { return &dep{} }))
+		require.NoError(t, c.Invoke(func(*dep) {}))
+		assert.Empty(t, c.HiddenEdges())
+	})
+
+	t.Run("shared with scopes", func(t *testing.T) {
+		c := New()
+		s := c.Scope("child")
+		provideWidget(t, s)
+		require.NoError(t, s.Invoke(func(*widget) {}))
+
+		assert.Len(t, c.HiddenEdges(), 1)
+		assert.Equal(t, c.HiddenEdges(), s.HiddenEdges())
+	})
+
+	t.Run("lint", func(t *testing.T) {
+		c := New()
+		provideWidget(t, c)
+		require.NoError(t, c.Invoke(func(*widget) {}))
+
+		var buf bytes.Buffer
+		require.NoError(t, c.DumpWiring(&buf))
+		err := LintWiring(&buf)
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestHiddenEdges\S+ \(\S+/nested_test.go:\d+\) consumes \*dig.dep `+
+				`through an Invoke made from inside it: accept it as a parameter instead`)
+	})
+
+	t.Run("disallowed", func(t *testing.T) {
+		c := New(DisallowNestedInvokes())
+		provideWidget(t, c)
+
+		err := c.Invoke(func(*widget) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`cannot invoke function "go.uber.org/dig".TestHiddenEdges\S+ \(\S+/nested_test.go:\d+\) `+
+				`from inside constructor "go.uber.org/dig".TestHiddenEdges\S+ \(\S+/nested_test.go:\d+\): `+
+				`nested Invokes hide the dependencies of constructors; accept them as parameters of the constructor instead`)
+		assert.Empty(t, c.HiddenEdges())
+	})
+}
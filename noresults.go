@@ -0,0 +1,117 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// AllowNoResults is a ProvideOption that lets a constructor succeed without
+// committing any of the values it provides, such as a constructor whose
+// dig.Out fields all failed with an `errorfor:".."` field.
+//
+//   c.Provide(RunMigrations, dig.AllowNoResults())
+//
+// Use this for constructors that are mostly called for their side effects,
+// for example with Container.Construct. Consumers of the values that were
+// not committed fail with the errors recorded for them, as usual.
+func AllowNoResults() ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.AllowNoResults = true
+	})
+}
+
+// NoResultsCommittedError is returned when a constructor returns without
+// error but none of the values it provides are available, for example
+// because every field of its dig.Out result failed with an `errorfor:".."`
+// field. Nothing is committed to the container in that case, and the
+// constructor is called again by the next Invoke that needs it.
+//
+// Value groups aren't considered: a constructor may submit no values to a
+// group. Use AllowNoResults to let a constructor commit nothing.
+type NoResultsCommittedError struct {
+	// Values provided by the constructor, none of which were produced.
+	Keys []Key
+
+	// Errors recorded for each of Keys, if any.
+	Reasons []error
+
+	fn *digreflect.Func
+}
+
+func (e NoResultsCommittedError) Error() string {
+	missing := make([]string, len(e.Keys))
+	for i, k := range e.Keys {
+		missing[i] = k.String()
+		if err := e.Reasons[i]; err != nil {
+			missing[i] += fmt.Sprintf(" (%v)", err)
+		}
+	}
+	return fmt.Sprintf("cannot commit the results of function %v: "+
+		"it returned no error but produced none of the values it provides: %v",
+		e.fn, strings.Join(missing, ", "))
+}
+
+// checkResultsCommitted returns a NoResultsCommittedError if the given
+// values, staged by a call to this node's constructor, don't include any of
+// the values the node provides outside of value groups.
+func (n *node) checkResultsCommitted(sr *stagingContainerWriter) error {
+	if n.allowNoResults {
+		return nil
+	}
+
+	var promised []key
+	seen := make(map[key]struct{})
+	for _, r := range n.resultList.DotResult() {
+		if r.Group != "" {
+			continue
+		}
+		k := key{t: r.Type, name: r.Name}
+		if _, ok := n.removedKeys[k]; ok {
+			continue
+		}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		if _, ok := sr.values[k]; ok {
+			return nil
+		}
+		promised = append(promised, k)
+	}
+	if len(promised) == 0 {
+		return nil
+	}
+
+	err := NoResultsCommittedError{
+		Keys:    make([]Key, len(promised)),
+		Reasons: make([]error, len(promised)),
+		fn:      n.location,
+	}
+	for i, k := range promised {
+		err.Keys[i] = k.exported()
+		err.Reasons[i] = sr.errors[k]
+	}
+	return err
+}
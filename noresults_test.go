@@ -0,0 +1,133 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoResultsCommitted(t *testing.T) {
+	type tenant struct{ name string }
+
+	type out struct {
+		Out
+
+		Foo    *tenant `name:"foo"`
+		FooErr error   `errorfor:"Foo"`
+		Bar    *tenant `name:"bar"`
+		BarErr error   `errorfor:"Bar"`
+	}
+
+	type in struct {
+		In
+
+		Foo *tenant `name:"foo"`
+	}
+
+	failAll := func(calls *int) func() out {
+		return func() out {
+			*calls++
+			return out{
+				FooErr: errors.New("foo is down"),
+				BarErr: errors.New("bar is down"),
+			}
+		}
+	}
+
+	t.Run("fails the constructor", func(t *testing.T) {
+		c := New()
+		var calls int
+		require.NoError(t, c.Provide(failAll(&calls)))
+
+		err := c.Invoke(func(in) {
+			require.FailNow(t, "invoke must not be called")
+		})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`cannot commit the results of function "go.uber.org/dig".TestNoResultsCommitted\S+ \(\S+:\d+\):`,
+			`it returned no error but produced none of the values it provides:`,
+			`\*dig.tenant\[name="foo"\] \(field Foo of dig.out failed: foo is down\), `+
+				`\*dig.tenant\[name="bar"\] \(field Bar of dig.out failed: bar is down\)`)
+
+		var nerr NoResultsCommittedError
+		require.True(t, errors.As(err, &nerr), "expected a NoResultsCommittedError, got %v", err)
+		require.Len(t, nerr.Keys, 2)
+		assert.Equal(t, "foo", nerr.Keys[0].Name)
+		assert.EqualError(t, nerr.Reasons[0], "field Foo of dig.out failed: foo is down")
+
+		require.Error(t, c.Invoke(func(in) {}))
+		assert.Equal(t, 2, calls, "nothing must be committed, so the constructor must be called again")
+	})
+
+	t.Run("one value is enough", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() out {
+			return out{Foo: &tenant{name: "foo"}, BarErr: errors.New("bar is down")}
+		}))
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Equal(t, "foo", i.Foo.name)
+		}))
+	})
+
+	t.Run("value groups are not considered", func(t *testing.T) {
+		type groupOut struct {
+			Out
+
+			Foo    *tenant `name:"foo"`
+			FooErr error   `errorfor:"Foo"`
+			Member string  `group:"members"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() groupOut {
+			return groupOut{FooErr: errors.New("foo is down"), Member: "x"}
+		}))
+		err := c.Invoke(func(struct {
+			In
+
+			Members []string `group:"members"`
+		}) {
+		})
+		var nerr NoResultsCommittedError
+		require.True(t, errors.As(err, &nerr), "expected a NoResultsCommittedError, got %v", err)
+	})
+
+	t.Run("AllowNoResults", func(t *testing.T) {
+		c := New()
+		var calls int
+		require.NoError(t, c.Provide(failAll(&calls), AllowNoResults()))
+
+		for i := 0; i < 2; i++ {
+			err := c.Invoke(func(in) {
+				require.FailNow(t, "invoke must not be called")
+			})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "foo is down")
+			var nerr NoResultsCommittedError
+			assert.False(t, errors.As(err, &nerr), "unexpected NoResultsCommittedError: %v", err)
+		}
+		assert.Equal(t, 1, calls, "the constructor must only be called once")
+	})
+}
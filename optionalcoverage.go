@@ -0,0 +1,193 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package dig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// OptionalDep is an optional dependency of a constructor or of a function
+// passed to Invoke. See Container.OptionalCoverage.
+type OptionalDep struct {
+	// Name, package, and location of the function that consumes the
+	// dependency.
+	Name    string
+	Package string
+	File    string
+	Line    int
+
+	// Whether the function was passed to Invoke rather than Provide.
+	Invoked bool
+
+	// Location of the dependency in the parameters of the function, like
+	// "field Logger of argument 1".
+	Path string
+
+	// Value consumed by the function.
+	Key Key
+
+	// Whether the container has the value or a constructor for it. The
+	// zero value is passed to the function otherwise.
+	Satisfied bool
+}
+
+func (d OptionalDep) String() string {
+	status := "unsatisfied"
+	if d.Satisfied {
+		status = "satisfied"
+	}
+	return fmt.Sprintf("%v of %q.%v (%v:%v) requests %v: %v", d.Path, d.Package, d.Name, d.File, d.Line, d.Key, status)
+}
+
+// OptionalCoverage returns the optional dependencies of the constructors
+// provided to the container and its parents, and of the functions invoked
+// on them so far, with whether the container satisfies each of them.
+// Constructors are listed in the order in which they were provided,
+// followed by invoked functions in the order in which they were first
+// invoked.
+//
+// Optional dependencies that aren't satisfied are silently replaced with
+// their zero values, so this is useful to check a container before a
+// release.
+//
+//   for _, d := range c.OptionalCoverage() {
+//     if !d.Satisfied {
+//       log.Printf("missing optional dependency: %v", d)
+//     }
+//   }
+//
+// WriteOptionalCoverage renders the result as a text document.
+func (c *Container) OptionalCoverage() []OptionalDep {
+	if c.checkInitialized() != nil {
+		return nil
+	}
+
+	var deps []OptionalDep
+	for _, n := range c.chainNodes() {
+		deps = c.appendOptionalDeps(deps, n.location, false, n.paramList)
+	}
+	for _, s := range c.scopeChain() {
+		for _, inv := range s.invokes {
+			deps = c.appendOptionalDeps(deps, inv.fn, true, inv.params)
+		}
+	}
+	return deps
+}
+
+// appendOptionalDeps appends the optional dependencies in the given params
+// of fn to deps.
+func (c *Container) appendOptionalDeps(deps []OptionalDep, fn *digreflect.Func, invoked bool, pl paramList) []OptionalDep {
+	add := func(p param, path paramPath) {
+		ps, ok := p.(paramSingle)
+		if !ok || !ps.Optional {
+			return
+		}
+		deps = append(deps, OptionalDep{
+			Name:      fn.Name,
+			Package:   fn.Package,
+			File:      fn.File,
+			Line:      fn.Line,
+			Invoked:   invoked,
+			Path:      path.String(),
+			Key:       Key{Type: ps.Type, Name: ps.Name},
+			Satisfied: c.satisfies(ps),
+		})
+	}
+
+	for i, p := range pl.Params {
+		walkParamPaths(p, paramPath{Arg: i + 1}, add)
+	}
+	for _, pr := range pl.Populated {
+		walkParamPaths(pr.Object, paramPath{}, add)
+	}
+	return deps
+}
+
+// satisfies reports whether the container has a value or a constructor for
+// the given param.
+func (c *Container) satisfies(ps paramSingle) bool {
+	if _, ok := c.getValue(ps.Name, ps.Type); ok {
+		return true
+	}
+	return len(c.getValueProviders(ps.Name, ps.Type)) > 0 ||
+		len(c.getImplementations(ps.Name, ps.Type)) > 0
+}
+
+// OptionalCoverageSummary counts the optional dependencies returned by
+// Container.OptionalCoverage.
+type OptionalCoverageSummary struct {
+	Total       int
+	Satisfied   int
+	Unsatisfied int
+}
+
+// SummarizeOptionalCoverage counts the given optional dependencies.
+func SummarizeOptionalCoverage(deps []OptionalDep) OptionalCoverageSummary {
+	s := OptionalCoverageSummary{Total: len(deps)}
+	for _, d := range deps {
+		if d.Satisfied {
+			s.Satisfied++
+		} else {
+			s.Unsatisfied++
+		}
+	}
+	return s
+}
+
+// WriteOptionalCoverage writes the given optional dependencies to w as a
+// text document, grouped by the package of the functions that consume
+// them.
+//
+//   dig.WriteOptionalCoverage(os.Stdout, c.OptionalCoverage())
+func WriteOptionalCoverage(w io.Writer, deps []OptionalDep) error {
+	s := SummarizeOptionalCoverage(deps)
+	b := new(bytes.Buffer)
+	fmt.Fprintf(b, "optional dependencies: %d (%d satisfied, %d unsatisfied)\n",
+		s.Total, s.Satisfied, s.Unsatisfied)
+
+	byPkg := make(map[string][]OptionalDep)
+	var pkgs []string
+	for _, d := range deps {
+		if _, ok := byPkg[d.Package]; !ok {
+			pkgs = append(pkgs, d.Package)
+		}
+		byPkg[d.Package] = append(byPkg[d.Package], d)
+	}
+	sort.Strings(pkgs)
+
+	for _, pkg := range pkgs {
+		fmt.Fprintf(b, "\n%q:\n", pkg)
+		for _, d := range byPkg[pkg] {
+			status := "missing"
+			if d.Satisfied {
+				status = "ok"
+			}
+			fmt.Fprintf(b, "\t[%v] %v (%v:%v) %v: %v\n", status, d.Name, d.File, d.Line, d.Path, d.Key)
+		}
+	}
+
+	_, err := b.WriteTo(w)
+	return err
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package dig
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionalCoverage(t *testing.T) {
+	type logger struct{}
+	type tracer struct{}
+	type server struct{}
+	type params struct {
+		In
+
+		Logger *logger `optional:"true"`
+		Tracer *tracer `name:"remote" optional:"true"`
+	}
+
+	t.Run("no optional dependencies", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *logger { return &logger{} }))
+		require.NoError(t, c.Invoke(func(*logger) {}))
+		assert.Empty(t, c.OptionalCoverage())
+	})
+
+	t.Run("lists optional dependencies", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *logger { return &logger{} }))
+		require.NoError(t, c.Provide(func(params) *server { return &server{} }))
+		require.NoError(t, c.Invoke(func(s *server, p struct {
+			In
+
+			Logger *logger `optional:"true"`
+		}) {
+		}))
+
+		deps := c.OptionalCoverage()
+		require.Len(t, deps, 3)
+
+		assert.Equal(t, Key{Type: reflect.TypeOf(&logger{})}, deps[0].Key)
+		assert.Equal(t, "field Logger of argument 1", deps[0].Path)
+		assert.True(t, deps[0].Satisfied)
+		assert.False(t, deps[0].Invoked)
+		assert.Equal(t, "go.uber.org/dig", deps[0].Package)
+		assert.Contains(t, deps[0].File, "optionalcoverage_test.go")
+
+		assert.Equal(t, Key{Type: reflect.TypeOf(&tracer{}), Name: "remote"}, deps[1].Key)
+		assert.False(t, deps[1].Satisfied)
+		assert.Regexp(t, `^field Tracer of argument 1 of "go.uber.org/dig".TestOptionalCoverage\S+ `+
+			`\(\S+/optionalcoverage_test.go:\d+\) requests \*dig.tracer\[name="remote"\]: unsatisfied$`, deps[1].String())
+
+		assert.Equal(t, "field Logger of argument 2", deps[2].Path)
+		assert.True(t, deps[2].Invoked)
+		assert.True(t, deps[2].Satisfied)
+
+		assert.Equal(t, OptionalCoverageSummary{Total: 3, Satisfied: 2, Unsatisfied: 1},
+			SummarizeOptionalCoverage(deps))
+	})
+
+	t.Run("values and implementations satisfy dependencies", func(t *testing.T) {
+		c := New(ResolveByImplementation())
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }))
+		require.NoError(t, c.Provide(func(p struct {
+			In
+
+			Writer interface{ Write([]byte) (int, error) } `optional:"true"`
+		}) *server {
+			return &server{}
+		}))
+
+		deps := c.OptionalCoverage()
+		require.Len(t, deps, 1)
+		assert.True(t, deps[0].Satisfied)
+	})
+
+	t.Run("scopes see the constructors of their parents", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func(params) *server { return &server{} }))
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func() *logger { return &logger{} }))
+
+		assert.False(t, c.OptionalCoverage()[0].Satisfied)
+		assert.True(t, s.OptionalCoverage()[0].Satisfied)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *logger { return &logger{} }))
+		require.NoError(t, c.Provide(func(params) *server { return &server{} }))
+
+		var buf bytes.Buffer
+		require.NoError(t, WriteOptionalCoverage(&buf, c.OptionalCoverage()))
+		assert.Regexp(t, `^optional dependencies: 2 \(1 satisfied, 1 unsatisfied\)
+
+"go.uber.org/dig":
+	\[ok\] TestOptionalCoverage\S+ \(\S+:\d+\) field Logger of argument 1: \*dig.logger
+	\[missing\] TestOptionalCoverage\S+ \(\S+:\d+\) field Tracer of argument 1: \*dig.tracer\[name="remote"\]
+$`, buf.String())
+	})
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// RecoverFromPanics is an Option that recovers from panics in constructors
+// and invoked functions. The panic is reported as a PanicError by Invoke
+// instead of crashing the program.
+//
+//   c := dig.New(dig.RecoverFromPanics())
+//   err := c.Invoke(run)
+//   var panicErr dig.PanicError
+//   if errors.As(err, &panicErr) {
+//     log.Printf("recovered from %v", panicErr.Panic)
+//   }
+//
+// Constructors provided as method values, like (&Module{}).NewThing, often
+// panic because their receiver was never initialized. PanicErrors for such
+// constructors point this out.
+func RecoverFromPanics() Option {
+	return optionFunc(func(c *Container) {
+		c.recoverFromPanics = true
+	})
+}
+
+// PanicError is returned by Invoke when a constructor or an invoked function
+// panics in a container created with RecoverFromPanics.
+type PanicError struct {
+	// Value passed to panic.
+	Panic interface{}
+
+	// Function that panicked.
+	fn *digreflect.Func
+}
+
+func (e PanicError) Error() string {
+	msg := fmt.Sprintf("function %v panicked: %v", e.fn, e.Panic)
+	if e.fn.IsMethodValue() {
+		msg += "; note: the method receiver may be nil, " +
+			"the function was provided as a bound method"
+	}
+	return msg
+}
+
+func (c *Container) call(fn *digreflect.Func, f interface{}, args []reflect.Value) (results []reflect.Value, err error) {
+	if c.recoverFromPanics {
+		defer func() {
+			if p := recover(); p != nil {
+				err = PanicError{Panic: p, fn: fn}
+			}
+		}()
+	}
+	return reflect.ValueOf(f).Call(args), nil
+}
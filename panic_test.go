@@ -0,0 +1,102 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type panicTestModule struct {
+	name string
+}
+
+func (m *panicTestModule) NewName() string { return m.name }
+
+func TestRecoverFromPanics(t *testing.T) {
+	type A struct{}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() A { panic("great sadness") }))
+		assert.Panics(t, func() {
+			c.Invoke(func(A) {})
+		})
+	})
+
+	t.Run("constructor", func(t *testing.T) {
+		c := New(RecoverFromPanics())
+		require.NoError(t, c.Provide(func() A { panic("great sadness") }))
+
+		err := c.Invoke(func(A) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestRecoverFromPanics\S+`,
+			`function "go.uber.org/dig".TestRecoverFromPanics\S+ \(\S+\) panicked: great sadness$`)
+
+		var panicErr PanicError
+		require.True(t, errors.As(err, &panicErr))
+		assert.Equal(t, "great sadness", panicErr.Panic)
+		assert.NotContains(t, err.Error(), "receiver")
+	})
+
+	t.Run("invoked function", func(t *testing.T) {
+		c := New(RecoverFromPanics())
+		err := c.Invoke(func() { panic("great sadness") })
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`^function "go.uber.org/dig".TestRecoverFromPanics\S+ \(\S+\) panicked: great sadness$`)
+	})
+
+	t.Run("inherited by scopes", func(t *testing.T) {
+		s := New(RecoverFromPanics()).Scope("child")
+		err := s.Invoke(func() { panic("great sadness") })
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "panicked: great sadness")
+	})
+
+	t.Run("nil receiver", func(t *testing.T) {
+		var m *panicTestModule
+
+		c := New(RecoverFromPanics())
+		require.NoError(t, c.Provide(m.NewName))
+
+		err := c.Invoke(func(string) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".\(\*panicTestModule\).NewName-fm \(\S+\) panicked: `,
+			`nil pointer dereference; `,
+			`note: the method receiver may be nil, the function was provided as a bound method$`)
+	})
+
+	t.Run("non-nil receiver", func(t *testing.T) {
+		m := &panicTestModule{name: "foo"}
+
+		c := New(RecoverFromPanics())
+		require.NoError(t, c.Provide(m.NewName))
+		require.NoError(t, c.Invoke(func(name string) {
+			assert.Equal(t, "foo", name)
+		}))
+	})
+}
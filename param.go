@@ -24,6 +24,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"go.uber.org/dig/internal/dot"
 )
@@ -39,6 +41,11 @@ import (
 //                A slice consuming a value group. This will receive all
 //                values produced with a `group:".."` tag with the same name
 //                as a slice.
+//  paramNamedSlice
+//                A slice consuming the named values listed in a
+//                `names:".."` tag, in that order.
+//  paramConstructionInfo
+//                The ConstructionInfo of the constructor being called.
 type param interface {
 	fmt.Stringer
 
@@ -57,14 +64,21 @@ var (
 	_ param = paramObject{}
 	_ param = paramList{}
 	_ param = paramGroupedSlice{}
+	_ param = paramNamedSlice{}
 )
 
 // newParam builds a param from the given type. If the provided type is a
 // dig.In struct, an paramObject will be returned.
 func newParam(t reflect.Type) (param, error) {
 	switch {
+	case embedsInAndOut(t):
+		return nil, fmt.Errorf(
+			"cannot depend on %v: it embeds both dig.In and dig.Out, expected only dig.In: "+
+				"remove the dig.Out embed to use it as a parameter object", t)
 	case IsOut(t) || (t.Kind() == reflect.Ptr && IsOut(t.Elem())) || embedsType(t, _outPtrType):
-		return nil, fmt.Errorf("cannot depend on result objects: %v embeds a dig.Out", t)
+		return nil, fmt.Errorf(
+			"cannot depend on result objects: %v embeds a dig.Out, expected a dig.In: "+
+				"embed dig.In instead of dig.Out to use it as a parameter object", t)
 	case IsIn(t):
 		return newParamObject(t)
 	case embedsType(t, _inPtrType):
@@ -75,7 +89,12 @@ func newParam(t reflect.Type) (param, error) {
 		return nil, fmt.Errorf(
 			"cannot depend on a pointer to a parameter object, use a value instead: "+
 				"%v is a pointer to a struct that embeds dig.In", t)
+	case t == _constructionInfoType:
+		return paramConstructionInfo{}, nil
 	default:
+		if err := checkErrorParam(t); err != nil {
+			return nil, err
+		}
 		return paramSingle{Type: t}, nil
 	}
 }
@@ -120,7 +139,7 @@ func walkParam(p param, v paramVisitor) {
 	}
 
 	switch par := p.(type) {
-	case paramSingle, paramGroupedSlice:
+	case paramSingle, paramGroupedSlice, paramConstructionInfo:
 		// No sub-results
 	case paramObject:
 		for _, f := range par.Fields {
@@ -130,6 +149,13 @@ func walkParam(p param, v paramVisitor) {
 		for _, p := range par.Params {
 			walkParam(p, v)
 		}
+		for _, pr := range par.Populated {
+			walkParam(pr.Object, v)
+		}
+	case paramNamedSlice:
+		for _, p := range par.Params {
+			walkParam(p, v)
+		}
 	default:
 		panic(fmt.Sprintf(
 			"It looks like you have found a bug in dig. "+
@@ -147,12 +173,29 @@ type paramList struct {
 	ctype reflect.Type // type of the constructor
 
 	Params []param
+
+	// Results of the constructor whose fields are populated after it
+	// returns. This is empty unless dig.PopulateFields was used.
+	Populated []populatedResult
 }
 
 func (pl paramList) DotParam() []*dot.Param {
 	var types []*dot.Param
-	for _, param := range pl.Params {
-		types = append(types, param.DotParam()...)
+	for i, param := range pl.Params {
+		prefix, sep := strconv.Itoa(i), "/"
+		if po, ok := param.(paramObject); ok && po.Type.Name() != "" {
+			prefix, sep = po.Type.Name(), "."
+		}
+		for _, p := range param.DotParam() {
+			p.Path = joinDotPath(prefix, sep, p.Path)
+			types = append(types, p)
+		}
+	}
+	for _, pr := range pl.Populated {
+		for _, p := range pr.Object.DotParam() {
+			p.Path = joinDotPath(pr.Object.Type.Name(), ".", p.Path)
+			types = append(types, p)
+		}
 	}
 	return types
 }
@@ -185,6 +228,77 @@ func newParamList(ctype reflect.Type) (paramList, error) {
 	return pl, nil
 }
 
+// checkDuplicates returns an error if the same value or value group is
+// requested more than once by these parameters, including the fields of
+// parameter objects.
+func (pl paramList) checkDuplicates() error {
+	seen := make(map[key]paramPath)
+	var err error
+	for i, p := range pl.Params {
+		walkParamPaths(p, paramPath{Arg: i + 1}, func(p param, path paramPath) {
+			var k key
+			switch p := p.(type) {
+			case paramSingle:
+				k = key{name: p.Name, t: p.Type}
+			case paramGroupedSlice:
+				k = key{group: p.Group, t: p.Type.Elem()}
+			}
+			if prev, ok := seen[k]; ok && err == nil {
+				err = fmt.Errorf("%v requests %v more than once: as %v and as %v", pl.ctype, k, prev, path)
+			}
+			seen[k] = path
+		})
+	}
+	return err
+}
+
+// paramPath locates a param in the arguments of a function.
+type paramPath struct {
+	// Position of the argument, starting at 1. This is zero for the fields
+	// of objects populated with dig.PopulateFields.
+	Arg int
+
+	// Names of the fields leading to the param if the argument is a
+	// parameter object.
+	Fields []string
+}
+
+func (pp paramPath) String() string {
+	switch {
+	case len(pp.Fields) == 0:
+		return fmt.Sprintf("argument %d", pp.Arg)
+	case pp.Arg == 0:
+		return fmt.Sprintf("field %v", strings.Join(pp.Fields, "."))
+	default:
+		return fmt.Sprintf("field %v of argument %d", strings.Join(pp.Fields, "."), pp.Arg)
+	}
+}
+
+// IsZero reports whether this path is unknown.
+func (pp paramPath) IsZero() bool {
+	return pp.Arg == 0 && len(pp.Fields) == 0
+}
+
+// walkParamPaths calls f with the path of every paramSingle and
+// paramGroupedSlice in p. The values of a paramNamedSlice share the path of
+// the slice.
+func walkParamPaths(p param, path paramPath, f func(param, paramPath)) {
+	switch p := p.(type) {
+	case paramSingle, paramGroupedSlice:
+		f(p, path)
+	case paramNamedSlice:
+		for _, ps := range p.Params {
+			f(ps, path)
+		}
+	case paramObject:
+		for _, field := range p.Fields {
+			fpath := paramPath{Arg: path.Arg}
+			fpath.Fields = append(append(fpath.Fields, path.Fields...), field.FieldName)
+			walkParamPaths(field.Param, fpath, f)
+		}
+	}
+}
+
 func (pl paramList) Build(containerStore) (reflect.Value, error) {
 	panic("It looks like you have found a bug in dig. " +
 		"Please file an issue at https://github.com/uber-go/dig/issues/ " +
@@ -200,7 +314,7 @@ func (pl paramList) BuildList(c containerStore) ([]reflect.Value, error) {
 		var err error
 		args[i], err = p.Build(c)
 		if err != nil {
-			return nil, err
+			return nil, errParamFailedAt(err, i+1)
 		}
 	}
 	return args, nil
@@ -214,6 +328,14 @@ type paramSingle struct {
 	Name     string
 	Optional bool
 	Type     reflect.Type
+
+	// Description of the value from the `doc:".."` tag, if any.
+	Doc string
+
+	// Names of the value in each environment from the `env:".."` tag, if
+	// any. Name is replaced with the name for the environment of the
+	// container by paramList.withEnvironment.
+	Env string
 }
 
 func (ps paramSingle) DotParam() []*dot.Param {
@@ -224,46 +346,146 @@ func (ps paramSingle) DotParam() []*dot.Param {
 				Name: ps.Name,
 			},
 			Optional: ps.Optional,
+			Doc:      ps.Doc,
 		},
 	}
 }
 
 func (ps paramSingle) Build(c containerStore) (reflect.Value, error) {
+	v, _, err := ps.build(c)
+	return v, err
+}
+
+// build is like Build, but also reports whether the zero value was returned
+// because this optional param could not be resolved.
+func (ps paramSingle) build(c containerStore) (_ reflect.Value, unresolved bool, _ error) {
+	c.tracer().requested(key{name: ps.Name, t: ps.Type})
 	if v, ok := c.getValue(ps.Name, ps.Type); ok {
-		return v, nil
+		c.tracer().cached(key{name: ps.Name, t: ps.Type})
+		if a := c.audit(); a != nil {
+			a.add(key{name: ps.Name, t: ps.Type}, AuditEntry{Kind: AuditCached})
+			a.resolve(key{name: ps.Name, t: ps.Type})
+		}
+		return ps.inject(c, v), false, nil
 	}
 
 	providers := c.getValueProviders(ps.Name, ps.Type)
 	if len(providers) == 0 {
+		if impls := c.getImplementations(ps.Name, ps.Type); len(impls) > 0 {
+			v, err := ps.buildImplementation(c, impls)
+			return v, false, err
+		}
+		resolve := c.resolveGeneric
+		if ps.Name != "" {
+			resolve = func(t reflect.Type) (bool, error) { return c.resolveBridge(ps.Name, t) }
+		}
+		resolved, resolveErr := resolve(ps.Type)
+		if resolveErr != nil {
+			return _noValue, false, resolveErr
+		}
+		if resolved {
+			return ps.build(c)
+		}
+		c.tracer().missing(key{name: ps.Name, t: ps.Type})
 		if ps.Optional {
-			return reflect.Zero(ps.Type), nil
+			if a := c.audit(); a != nil {
+				a.add(key{name: ps.Name, t: ps.Type}, AuditEntry{Kind: AuditDefaulted})
+			}
+			return reflect.Zero(ps.Type), true, nil
+		}
+		err := newErrMissingType(c, key{name: ps.Name, t: ps.Type})
+		err.Doc = ps.Doc
+		if fr := c.fallbackResolver(); fr != nil {
+			v, ok, ferr := fr.Resolve(c, key{name: ps.Name, t: ps.Type})
+			if ok {
+				return ps.inject(c, v), false, nil
+			}
+			err.fallback, err.fallbackErr = true, ferr
+		}
+		return _noValue, false, err
+	}
+
+	if len(providers) > 1 && !c.allowsLastProviderWins() {
+		k := key{t: ps.Type, name: ps.Name}
+		return _noValue, false, errParamSingleFailed{
+			CtorID: providers[0].ID(),
+			Key:    k,
+			Reason: newAmbiguousProviderError(k, providers),
 		}
-		return _noValue, newErrMissingType(c, key{name: ps.Name, t: ps.Type})
 	}
 
 	for _, n := range providers {
-		err := n.Call(c)
+		c.tracer().provider(key{name: ps.Name, t: ps.Type}, n)
+		restore := c.requestValue(key{name: ps.Name, t: ps.Type})
+		err := callProvider(c, n)
+		restore()
 		if err == nil {
 			continue
 		}
 
-		// If we're missing dependencies but the parameter itself is optional,
-		// we can just move on.
-		if _, ok := err.(errMissingDependencies); ok && ps.Optional {
-			return reflect.Zero(ps.Type), nil
+		// If we're missing dependencies, or the optional dependencies of
+		// the constructor lead back to this parameter, but the parameter
+		// itself is optional, we can just move on.
+		if _, ok := err.(errMissingDependencies); (ok || isRecursiveCall(err)) && ps.Optional {
+			if a := c.audit(); a != nil {
+				a.add(key{name: ps.Name, t: ps.Type}, AuditEntry{Kind: AuditDefaulted})
+			}
+			return reflect.Zero(ps.Type), true, nil
 		}
 
-		return _noValue, errParamSingleFailed{
+		return _noValue, false, errParamSingleFailed{
 			CtorID: n.ID(),
 			Key:    key{t: ps.Type, name: ps.Name},
 			Reason: err,
 		}
 	}
 
-	// If we get here, it's impossible for the value to be absent from the
-	// container.
-	v, _ := c.getValue(ps.Name, ps.Type)
-	return v, nil
+	// If we get here, the value is absent from the container only if the
+	// constructor reported a failure for it with an errorfor field.
+	v, ok := c.getValue(ps.Name, ps.Type)
+	if !ok {
+		n := providers[0]
+		return _noValue, false, errParamSingleFailed{
+			CtorID: n.ID(),
+			Key:    key{t: ps.Type, name: ps.Name},
+			Reason: errConstructorFailed{
+				Func:   n.Location(),
+				Reason: c.getValueError(ps.Name, ps.Type),
+			},
+		}
+	}
+	if a := c.audit(); a != nil {
+		a.resolve(key{name: ps.Name, t: ps.Type})
+	}
+	return ps.inject(c, v), false, nil
+}
+
+// inject returns the value of this param to hand to its consumer: a copy of
+// v if the value was provided with CopyOnInject, and v itself otherwise.
+func (ps paramSingle) inject(c containerStore, v reflect.Value) reflect.Value {
+	if c.copiesOnInject(key{name: ps.Name, t: ps.Type}) {
+		return shallowCopy(v)
+	}
+	return v
+}
+
+// buildImplementation builds this interface param from the only type in impls
+// that implements it.
+func (ps paramSingle) buildImplementation(c containerStore, impls []reflect.Type) (reflect.Value, error) {
+	k := key{name: ps.Name, t: ps.Type}
+	if len(impls) > 1 {
+		err := errAmbiguousImplementation{Key: k}
+		for _, t := range impls {
+			err.Candidates = append(err.Candidates, key{name: ps.Name, t: t})
+		}
+		return _noValue, err
+	}
+
+	v, err := paramSingle{Name: ps.Name, Type: impls[0]}.Build(c)
+	if err != nil {
+		return _noValue, errWrapf(err, "failed to build %v as %v", impls[0], k)
+	}
+	return v.Convert(ps.Type), nil
 }
 
 // paramObject is a dig.In struct where each field is another param.
@@ -272,6 +494,48 @@ func (ps paramSingle) Build(c containerStore) (reflect.Value, error) {
 type paramObject struct {
 	Type   reflect.Type
 	Fields []paramObjectField
+
+	// Index of the dig.UnresolvedKeys field, if HasUnresolvedKeys is set.
+	// This field is not part of Fields.
+	UnresolvedKeysIndex int
+	HasUnresolvedKeys   bool
+
+	// Steps that fill in the struct, compiled from Fields by newParamPlan
+	// so that building the struct doesn't walk the nested structs again.
+	plan []paramFill
+}
+
+// paramFill is a step of the plan that fills in a dig.In struct: it sets the
+// field at Index to the value of Param. Index goes through the fields of
+// nested dig.In structs, and Fields holds the names of the fields along it.
+type paramFill struct {
+	Index  []int
+	Fields []string
+	Param  param
+}
+
+// newParamPlan compiles the steps that fill in the fields of the given
+// paramObject. The fields of nested dig.In structs are flattened into the
+// plan, except for those of structs with a dig.UnresolvedKeys field, which
+// are filled in as a whole because their unresolved keys are collected
+// separately.
+func newParamPlan(po paramObject) []paramFill {
+	var plan []paramFill
+	var flatten func(po paramObject, index []int, fields []string)
+	flatten = func(po paramObject, index []int, fields []string) {
+		for _, f := range po.Fields {
+			// Copy the prefixes so that steps don't share backing arrays.
+			fi := append(append([]int(nil), index...), f.FieldIndex)
+			fn := append(append([]string(nil), fields...), f.FieldName)
+			if nested, ok := f.Param.(paramObject); ok && !nested.HasUnresolvedKeys {
+				flatten(nested, fi, fn)
+				continue
+			}
+			plan = append(plan, paramFill{Index: fi, Fields: fn, Param: f.Param})
+		}
+	}
+	flatten(po, nil, nil)
+	return plan
 }
 
 func (po paramObject) DotParam() []*dot.Param {
@@ -294,6 +558,21 @@ func newParamObject(t reflect.Type) (paramObject, error) {
 			continue
 		}
 
+		if f.Type == _unresolvedKeysType {
+			if po.HasUnresolvedKeys {
+				return po, fmt.Errorf(
+					"bad field %q of %v: cannot have more than one dig.UnresolvedKeys field", f.Name, t)
+			}
+			if f.PkgPath != "" {
+				return po, fmt.Errorf(
+					"bad field %q of %v: unexported fields not allowed in dig.In, did you mean to export %q (%v)?",
+					f.Name, t, f.Name, f.Type)
+			}
+			po.UnresolvedKeysIndex = i
+			po.HasUnresolvedKeys = true
+			continue
+		}
+
 		pof, err := newParamObjectField(i, f)
 		if err != nil {
 			return po, errWrapf(err, "bad field %q of %v", f.Name, t)
@@ -302,19 +581,146 @@ func newParamObject(t reflect.Type) (paramObject, error) {
 		po.Fields = append(po.Fields, pof)
 	}
 
+	po.plan = newParamPlan(po)
 	return po, nil
 }
 
 func (po paramObject) Build(c containerStore) (reflect.Value, error) {
 	dest := reflect.New(po.Type).Elem()
-	for _, f := range po.Fields {
-		v, err := f.Build(c)
+	return dest, po.buildInto(c, dest)
+}
+
+// buildInto builds the fields of this paramObject into the provided struct
+// value, which must be settable.
+func (po paramObject) buildInto(c containerStore, dest reflect.Value) error {
+	_, err := po.build(c, dest)
+	return err
+}
+
+// build is like buildInto, but also returns the keys of the optional fields
+// of this and nested paramObjects that could not be resolved.
+func (po paramObject) build(c containerStore, dest reflect.Value) (UnresolvedKeys, error) {
+	plan := po.plan
+	if plan == nil {
+		plan = newParamPlan(po)
+	}
+
+	var unresolved UnresolvedKeys
+	for _, s := range plan {
+		field := dest.Field(s.Index[0])
+		for _, i := range s.Index[1:] {
+			field = field.Field(i)
+		}
+
+		var (
+			v   reflect.Value
+			err error
+		)
+		switch p := s.Param.(type) {
+		case paramSingle:
+			var missing bool
+			v, missing, err = p.build(c)
+			if missing {
+				unresolved = append(unresolved, Key{Type: p.Type, Name: p.Name})
+			}
+		case paramObject:
+			// Nested structs are built in place.
+			var nested UnresolvedKeys
+			nested, err = p.build(c, field)
+			unresolved = append(unresolved, nested...)
+		case paramNamedSlice:
+			var missing UnresolvedKeys
+			v, missing, err = p.build(c)
+			unresolved = append(unresolved, missing...)
+		default:
+			v, err = p.Build(c)
+		}
 		if err != nil {
-			return dest, err
+			for i := len(s.Fields) - 1; i >= 0; i-- {
+				err = errParamFailedInField(err, s.Fields[i])
+			}
+			return nil, err
+		}
+		if v.IsValid() {
+			field.Set(v)
+		}
+	}
+
+	if po.HasUnresolvedKeys {
+		dest.Field(po.UnresolvedKeysIndex).Set(reflect.ValueOf(unresolved))
+	}
+
+	if c.checksDistinctNamedValues() {
+		if err := po.checkDistinctNamedValues(c, dest); err != nil {
+			return nil, err
+		}
+	}
+	return unresolved, nil
+}
+
+// checkDistinctNamedValues verifies that fields of the provided struct value
+// which request the same type under different names hold different values.
+func (po paramObject) checkDistinctNamedValues(c containerStore, dest reflect.Value) error {
+	for i, f1 := range po.Fields {
+		if nested, ok := f1.Param.(paramObject); ok && !nested.HasUnresolvedKeys {
+			// Structs filled in as a whole were checked when they were
+			// built. Others were flattened into the plan of this one.
+			if err := nested.checkDistinctNamedValues(c, dest.Field(f1.FieldIndex)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		p1, ok := f1.Param.(paramSingle)
+		if !ok {
+			continue
+		}
+		id1, ok := valueIdentity(dest.Field(f1.FieldIndex))
+		if !ok {
+			continue
+		}
+
+		for _, f2 := range po.Fields[i+1:] {
+			p2, ok := f2.Param.(paramSingle)
+			if !ok || p1.Type != p2.Type || p1.Name == p2.Name {
+				continue
+			}
+			if id2, ok := valueIdentity(dest.Field(f2.FieldIndex)); ok && id1 == id2 {
+				return newErrSharedNamedValue(c, po.Type,
+					[2]string{f1.FieldName, f2.FieldName},
+					[2]key{{name: p1.Name, t: p1.Type}, {name: p2.Name, t: p2.Type}})
+			}
 		}
-		dest.Field(f.FieldIndex).Set(v)
 	}
-	return dest, nil
+	return nil
+}
+
+// valueIdentity returns the address referenced by the given value if it's a
+// non-nil reference type or an interface holding one.
+//
+// Functions are never compared: the address of a function is that of its
+// code, which all closures of the same function literal share. Neither are
+// pointers to zero-sized values, which Go may allocate at the same address.
+func valueIdentity(v reflect.Value) (uintptr, bool) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0, false
+		}
+		return valueIdentity(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() || v.Type().Elem().Size() == 0 {
+			return 0, false
+		}
+		return v.Pointer(), true
+	case reflect.Map, reflect.Chan, reflect.UnsafePointer:
+		if v.IsNil() {
+			return 0, false
+		}
+		return v.Pointer(), true
+	default:
+		return 0, false
+	}
 }
 
 // paramObjectField is a single field of a dig.In struct.
@@ -333,7 +739,11 @@ type paramObjectField struct {
 }
 
 func (pof paramObjectField) DotParam() []*dot.Param {
-	return pof.Param.DotParam()
+	params := pof.Param.DotParam()
+	for _, p := range params {
+		p.Path = joinDotPath(pof.FieldName, ".", p.Path)
+	}
+	return params
 }
 
 func newParamObjectField(idx int, f reflect.StructField) (paramObjectField, error) {
@@ -356,6 +766,18 @@ func newParamObjectField(idx int, f reflect.StructField) (paramObjectField, erro
 			return pof, err
 		}
 
+	case f.Tag.Get(_namesTag) != "":
+		var err error
+		p, err = newParamNamedSlice(f)
+		if err != nil {
+			return pof, err
+		}
+
+	case f.Tag.Get(_prefixTag) != "" && !IsIn(f.Type):
+		return pof, fmt.Errorf(
+			"%v:%q can only be used on fields that are dig.In structs, got %v",
+			_prefixTag, f.Tag.Get(_prefixTag), f.Type)
+
 	default:
 		var err error
 		p, err = newParam(f.Type)
@@ -364,8 +786,39 @@ func newParamObjectField(idx int, f reflect.StructField) (paramObjectField, erro
 		}
 	}
 
+	if po, ok := p.(paramObject); ok {
+		p = po.withNamePrefix(f.Tag.Get(_prefixTag))
+	}
+
+	if _, ok := p.(paramConstructionInfo); ok && (f.Tag.Get(_nameTag) != "" || f.Tag.Get(_optionalTag) != "") {
+		return pof, fmt.Errorf(
+			"field %q of type %v cannot be named or optional: it's always provided by dig",
+			f.Name, f.Type)
+	}
+
+	doc := f.Tag.Get(_docTag)
+	if _, ok := p.(paramSingle); !ok && doc != "" {
+		return pof, fmt.Errorf(
+			"%v:%q can only be used on fields that consume a single value: field %q is %v",
+			_docTag, doc, f.Name, f.Type)
+	}
+
+	env := f.Tag.Get(_envTag)
+	if _, ok := p.(paramSingle); !ok && env != "" {
+		return pof, fmt.Errorf(
+			"%v:%q can only be used on fields that consume a single value: field %q is %v",
+			_envTag, env, f.Name, f.Type)
+	}
+	if env != "" {
+		if _, err := parseEnvTag(env); err != nil {
+			return pof, err
+		}
+	}
+
 	if ps, ok := p.(paramSingle); ok {
 		ps.Name = f.Tag.Get(_nameTag)
+		ps.Doc = doc
+		ps.Env = env
 
 		var err error
 		ps.Optional, err = isFieldOptional(f)
@@ -380,6 +833,43 @@ func newParamObjectField(idx int, f reflect.StructField) (paramObjectField, erro
 	return pof, nil
 }
 
+// withNamePrefix returns a copy of this paramObject with the given prefix
+// prepended to the names of all named values it depends on, including those
+// of nested parameter objects.
+func (po paramObject) withNamePrefix(prefix string) paramObject {
+	if prefix == "" {
+		return po
+	}
+
+	fields := make([]paramObjectField, len(po.Fields))
+	for i, f := range po.Fields {
+		switch p := f.Param.(type) {
+		case paramSingle:
+			if p.Name != "" {
+				p.Name = prefix + p.Name
+			}
+			if p.Env != "" {
+				p.Env = prefixEnvTag(p.Env, prefix)
+			}
+			f.Param = p
+		case paramObject:
+			f.Param = p.withNamePrefix(prefix)
+		case paramNamedSlice:
+			params := make([]paramSingle, len(p.Params))
+			for j, ps := range p.Params {
+				ps.Name = prefix + ps.Name
+				params[j] = ps
+			}
+			p.Params = params
+			f.Param = p
+		}
+		fields[i] = f
+	}
+	po.Fields = fields
+	po.plan = newParamPlan(po)
+	return po
+}
+
 func (pof paramObjectField) Build(c containerStore) (reflect.Value, error) {
 	v, err := pof.Param.Build(c)
 	if err != nil {
@@ -389,13 +879,26 @@ func (pof paramObjectField) Build(c containerStore) (reflect.Value, error) {
 }
 
 // paramGroupedSlice is a param which produces a slice of values with the same
-// group name.
+// group name, an iterator over these values, or a channel that delivers them.
 type paramGroupedSlice struct {
 	// Name of the group as specified in the `group:".."` tag.
 	Group string
 
 	// Type of the slice.
 	Type reflect.Type
+
+	// If set, the values are produced lazily by an iterator of this type
+	// instead of a slice. See groupIterElem.
+	Iter reflect.Type
+
+	// If set, the values are delivered on a receive-only channel of this
+	// type instead of a slice. The channel is closed after the last value.
+	Chan reflect.Type
+
+	// Whether indexes without a value are skipped when the values of the
+	// group have indexes, as specified with the `compact:"true"` tag.
+	// Otherwise, such gaps are an error. See indexedGroup.
+	Compact bool
 }
 
 func (pt paramGroupedSlice) DotParam() []*dot.Param {
@@ -415,13 +918,23 @@ func (pt paramGroupedSlice) DotParam() []*dot.Param {
 // The type MUST be a slice type.
 func newParamGroupedSlice(f reflect.StructField) (paramGroupedSlice, error) {
 	pg := paramGroupedSlice{Group: f.Tag.Get(_groupTag), Type: f.Type}
+	if elem, ok := groupIterElem(f.Type); ok {
+		pg.Type = reflect.SliceOf(elem)
+		pg.Iter = f.Type
+	} else if f.Type.Kind() == reflect.Chan && f.Type.ChanDir() == reflect.RecvDir {
+		pg.Type = reflect.SliceOf(f.Type.Elem())
+		pg.Chan = f.Type
+	}
 
 	name := f.Tag.Get(_nameTag)
 	optional, _ := isFieldOptional(f)
 	switch {
-	case f.Type.Kind() != reflect.Slice:
-		return pg, fmt.Errorf("value groups may be consumed as slices only: "+
-			"field %q (%v) is not a slice", f.Name, f.Type)
+	case f.Type.Kind() == reflect.Chan && pg.Chan == nil:
+		return pg, fmt.Errorf("value groups may be consumed as receive-only channels only: "+
+			"field %q (%v) must be of type <-chan %v", f.Name, f.Type, f.Type.Elem())
+	case pg.Type.Kind() != reflect.Slice:
+		return pg, fmt.Errorf("value groups may be consumed as slices or iterators only: "+
+			"field %q (%v) is not a slice or an iterator", f.Name, f.Type)
 	case name != "":
 		return pg, fmt.Errorf(
 			"cannot use named values with value groups: name:%q requested with group:%q", name, pg.Group)
@@ -430,21 +943,87 @@ func newParamGroupedSlice(f reflect.StructField) (paramGroupedSlice, error) {
 		return pg, errors.New("value groups cannot be optional")
 	}
 
+	if tag := f.Tag.Get(_compactTag); tag != "" {
+		compact, err := strconv.ParseBool(tag)
+		if err != nil {
+			return pg, fmt.Errorf("invalid value %q for %q tag on field %v: %v", tag, _compactTag, f.Name, err)
+		}
+		pg.Compact = compact
+	}
+
+	if pg.Type.Kind() == reflect.Slice {
+		if err := checkErrorParam(pg.Type.Elem()); err != nil {
+			return pg, err
+		}
+	}
 	return pg, nil
 }
 
 func (pt paramGroupedSlice) Build(c containerStore) (reflect.Value, error) {
-	for _, n := range c.getGroupProviders(pt.Group, pt.Type.Elem()) {
-		if err := n.Call(c); err != nil {
-			return _noValue, errParamGroupFailed{
-				CtorID: n.ID(),
-				Key:    key{group: pt.Group, t: pt.Type.Elem()},
-				Reason: err,
+	if a := c.audit(); a != nil {
+		pt.audit(c, a)
+	}
+
+	if pt.Iter != nil {
+		return reflect.MakeFunc(pt.Iter, func(args []reflect.Value) []reflect.Value {
+			err := pt.iterate(c, args[0])
+			if pt.Iter.NumOut() == 0 {
+				if err != nil {
+					panic(err)
+				}
+				return nil
 			}
-		}
+
+			errv := reflect.Zero(_errType)
+			if err != nil {
+				errv = reflect.ValueOf(&err).Elem()
+			}
+			return []reflect.Value{errv}
+		}), nil
+	}
+
+	if pt.Chan != nil {
+		return pt.buildChan(c)
+	}
+	return pt.buildSlice(c)
+}
+
+// buildChan builds the values of this group and returns a channel that
+// delivers them. The channel is buffered to hold all values and closed, so
+// consumers that stop reading early don't leak anything.
+func (pt paramGroupedSlice) buildChan(c containerStore) (reflect.Value, error) {
+	items, err := pt.buildSlice(c)
+	if err != nil {
+		return _noValue, err
+	}
+
+	ch := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, pt.Type.Elem()), items.Len())
+	for i := 0; i < items.Len(); i++ {
+		ch.Send(items.Index(i))
 	}
+	ch.Close()
+	return ch.Convert(pt.Chan), nil
+}
+
+func (pt paramGroupedSlice) buildSlice(c containerStore) (reflect.Value, error) {
+	k := key{group: pt.Group, t: pt.Type.Elem()}
+	entries, indexed, err := indexedGroup(c, k, pt.Compact)
+	if err != nil {
+		return _noValue, err
+	}
+
+	if err := pt.callProviders(c); err != nil {
+		return _noValue, err
+	}
+	c.tracer().group(k, c.getGroupContributors(pt.Group, pt.Type.Elem()))
 
 	items := c.getValueGroup(pt.Group, pt.Type.Elem())
+	if indexed {
+		items = items[:0:0]
+		for _, e := range entries {
+			items = append(items, e.Provider.GroupValues(k.group, k.t)[e.Pos])
+		}
+	}
 
 	result := reflect.MakeSlice(pt.Type, len(items), len(items))
 	for i, v := range items {
@@ -452,3 +1031,203 @@ func (pt paramGroupedSlice) Build(c containerStore) (reflect.Value, error) {
 	}
 	return result, nil
 }
+
+// audit records this group as cached if all of its constructors were already
+// called, or as defaulted if nothing provides it. Otherwise, it's recorded as
+// constructed when its constructors are called. Groups that something
+// provides are recorded as resolved.
+func (pt paramGroupedSlice) audit(c containerStore, a *InvokeAudit) {
+	k := key{group: pt.Group, t: pt.Type.Elem()}
+	providers := c.getGroupContributors(pt.Group, pt.Type.Elem())
+	if len(providers) == 0 {
+		a.add(k, AuditEntry{Kind: AuditDefaulted})
+		return
+	}
+	a.resolve(k)
+	for _, n := range providers {
+		if !n.Called() {
+			return
+		}
+	}
+	a.add(k, AuditEntry{Kind: AuditCached})
+}
+
+// callProviders calls all constructors of this group in the order in which
+// they were provided. The values they produce are committed only if all of
+// them succeed; otherwise the failures of all of them are reported.
+func (pt paramGroupedSlice) callProviders(c containerStore) error {
+	k := key{group: pt.Group, t: pt.Type.Elem()}
+
+	var (
+		staged   []provider
+		commits  []func() error
+		failures []errParamGroupFailed
+	)
+	for _, n := range c.getGroupContributors(pt.Group, pt.Type.Elem()) {
+		commit, err := stageProvider(c, n)
+		if err != nil {
+			failures = append(failures, errParamGroupFailed{
+				CtorID: n.ID(),
+				Key:    k,
+				Reason: err,
+			})
+			continue
+		}
+		staged = append(staged, n)
+		commits = append(commits, commit)
+	}
+
+	switch len(failures) {
+	case 0:
+		// Continue below.
+	case 1:
+		return failures[0]
+	default:
+		return errParamGroupFailures{Key: k, Failures: failures}
+	}
+
+	// Commit all values even if some of them can't be committed or
+	// OnValueCommitted functions fail for them so that the group is as
+	// complete as possible.
+	var err error
+	for i, commit := range commits {
+		if e := commit(); e != nil && err == nil {
+			err = errParamGroupFailed{
+				CtorID: staged[i].ID(),
+				Key:    k,
+				Reason: e,
+			}
+		}
+	}
+	return err
+}
+
+// iterate calls yield with the values of this group until it returns false,
+// calling the constructors of the group only as their values are reached.
+//
+// Constructors are called at most once, so iterating again yields the same
+// values without calling them again.
+func (pt paramGroupedSlice) iterate(c containerStore, yield reflect.Value) error {
+	t := pt.Type.Elem()
+	k := key{group: pt.Group, t: t}
+	entries, indexed, err := indexedGroup(c, k, pt.Compact)
+	if err != nil {
+		return err
+	}
+	if indexed {
+		for _, e := range entries {
+			if err := callProvider(c, e.Provider); err != nil {
+				return errParamGroupFailed{CtorID: e.Provider.ID(), Key: k, Reason: err}
+			}
+			v := e.Provider.GroupValues(k.group, k.t)[e.Pos]
+			if !yield.Call([]reflect.Value{v})[0].Bool() {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	for _, n := range c.getGroupContributors(pt.Group, t) {
+		if err := callProvider(c, n); err != nil {
+			return errParamGroupFailed{
+				CtorID: n.ID(),
+				Key:    key{group: pt.Group, t: t},
+				Reason: err,
+			}
+		}
+
+		for _, v := range n.GroupValues(pt.Group, t) {
+			if !yield.Call([]reflect.Value{v})[0].Bool() {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// paramNamedSlice is a param which produces a slice of named values of the
+// same type in the order in which their names were listed in the
+// `names:".."` tag.
+type paramNamedSlice struct {
+	// Type of the slice.
+	Type reflect.Type
+
+	// Values that make up the slice, in order. These are all optional if
+	// the field was tagged with `optional:"true"`.
+	Params []paramSingle
+}
+
+func (pn paramNamedSlice) DotParam() []*dot.Param {
+	types := make([]*dot.Param, 0, len(pn.Params))
+	for _, ps := range pn.Params {
+		types = append(types, ps.DotParam()...)
+	}
+	return types
+}
+
+// newParamNamedSlice builds a paramNamedSlice from the provided struct field
+// tagged with `names:".."`.
+func newParamNamedSlice(f reflect.StructField) (paramNamedSlice, error) {
+	pn := paramNamedSlice{Type: f.Type}
+	tag := f.Tag.Get(_namesTag)
+
+	switch {
+	case f.Type.Kind() != reflect.Slice:
+		return pn, fmt.Errorf("named values may be consumed together as slices only: "+
+			"field %q (%v) is not a slice", f.Name, f.Type)
+	case f.Tag.Get(_nameTag) != "":
+		return pn, fmt.Errorf("cannot use %v:%q with %v:%q: list all names in the %v tag",
+			_nameTag, f.Tag.Get(_nameTag), _namesTag, tag, _namesTag)
+	}
+	if err := checkErrorParam(f.Type.Elem()); err != nil {
+		return pn, err
+	}
+
+	optional, err := isFieldOptional(f)
+	if err != nil {
+		return pn, err
+	}
+
+	seen := make(map[string]struct{})
+	for _, name := range strings.Split(tag, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return pn, fmt.Errorf("invalid value %q for %q tag: names cannot be empty", tag, _namesTag)
+		}
+		if _, ok := seen[name]; ok {
+			return pn, fmt.Errorf("invalid value %q for %q tag: name %q is listed more than once",
+				tag, _namesTag, name)
+		}
+		seen[name] = struct{}{}
+		pn.Params = append(pn.Params, paramSingle{
+			Name:     name,
+			Optional: optional,
+			Type:     f.Type.Elem(),
+		})
+	}
+	return pn, nil
+}
+
+func (pn paramNamedSlice) Build(c containerStore) (reflect.Value, error) {
+	v, _, err := pn.build(c)
+	return v, err
+}
+
+// build is like Build, but also returns the keys of the optional values that
+// could not be resolved and were left out of the slice.
+func (pn paramNamedSlice) build(c containerStore) (reflect.Value, UnresolvedKeys, error) {
+	var unresolved UnresolvedKeys
+	result := reflect.MakeSlice(pn.Type, 0, len(pn.Params))
+	for _, ps := range pn.Params {
+		v, missing, err := ps.build(c)
+		if err != nil {
+			return _noValue, nil, err
+		}
+		if missing {
+			unresolved = append(unresolved, Key{Type: ps.Type, Name: ps.Name})
+			continue
+		}
+		result = reflect.Append(result, v)
+	}
+	return result, unresolved, nil
+}
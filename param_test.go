@@ -21,6 +21,8 @@
 package dig
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"reflect"
 	"testing"
@@ -101,6 +103,89 @@ func TestParamObjectSuccess(t *testing.T) {
 	})
 }
 
+func TestParamObjectNamePrefix(t *testing.T) {
+	type type1 struct{}
+
+	type inner struct {
+		In
+
+		Named   type1 `name:"primary"`
+		Unnamed type1
+	}
+
+	type in struct {
+		In
+
+		Inner inner `nameprefix:"storage."`
+		Outer struct {
+			In
+
+			Inner inner `nameprefix:"db."`
+		} `nameprefix:"app."`
+	}
+
+	po, err := newParamObject(reflect.TypeOf(in{}))
+	require.NoError(t, err)
+	require.Len(t, po.Fields, 2)
+
+	names := func(p param) []string {
+		var names []string
+		walkParam(p, paramVisitorFunc(func(p param) bool {
+			if ps, ok := p.(paramSingle); ok {
+				names = append(names, ps.Name)
+			}
+			return true
+		}))
+		return names
+	}
+
+	assert.Equal(t, []string{"storage.primary", ""}, names(po.Fields[0].Param))
+	assert.Equal(t, []string{"app.db.primary", ""}, names(po.Fields[1].Param))
+
+	t.Run("non-parameter object", func(t *testing.T) {
+		type in struct {
+			In
+
+			T type1 `nameprefix:"foo."`
+		}
+
+		_, err := newParamObject(reflect.TypeOf(in{}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(),
+			`nameprefix:"foo." can only be used on fields that are dig.In structs, got dig.type1`)
+	})
+}
+
+func TestParamObjectDoc(t *testing.T) {
+	type type1 struct{}
+
+	t.Run("single value", func(t *testing.T) {
+		type in struct {
+			In
+
+			T type1 `name:"primary" doc:"primary OLTP database"`
+		}
+
+		po, err := newParamObject(reflect.TypeOf(in{}))
+		require.NoError(t, err)
+		require.Len(t, po.Fields, 1)
+		assert.Equal(t, "primary OLTP database", po.Fields[0].Param.(paramSingle).Doc)
+	})
+
+	t.Run("value group", func(t *testing.T) {
+		type in struct {
+			In
+
+			Ts []type1 `group:"foo" doc:"all the things"`
+		}
+
+		_, err := newParamObject(reflect.TypeOf(in{}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(),
+			`doc:"all the things" can only be used on fields that consume a single value: field "Ts" is []dig.type1`)
+	})
+}
+
 func TestParamObjectFailure(t *testing.T) {
 	t.Run("unexported field gets an error", func(t *testing.T) {
 		type A struct{}
@@ -118,6 +203,152 @@ func TestParamObjectFailure(t *testing.T) {
 	})
 }
 
+func TestParamObjectPlan(t *testing.T) {
+	type type1 struct{ name string }
+	type type2 struct{}
+
+	type leaf struct {
+		In
+
+		Primary   *type1 `name:"primary"`
+		Secondary *type1 `name:"secondary" optional:"true"`
+	}
+
+	type withKeys struct {
+		In
+
+		Missing    *type2 `optional:"true"`
+		Unresolved UnresolvedKeys
+	}
+
+	type in struct {
+		In
+
+		First  *type1 `name:"primary"`
+		Leaf   leaf   `nameprefix:"db."`
+		Keys   withKeys
+		Group  []*type1 `group:"all"`
+		Names  []*type1 `names:"primary,db.primary"`
+		Nested struct {
+			In
+
+			Leaf leaf
+		}
+		Unresolved UnresolvedKeys
+	}
+
+	po, err := newParamObject(reflect.TypeOf(in{}))
+	require.NoError(t, err)
+
+	var paths []string
+	for _, s := range po.plan {
+		paths = append(paths, fmt.Sprint(s.Fields))
+	}
+	assert.Equal(t, []string{
+		"[First]",
+		"[Leaf Primary]",
+		"[Leaf Secondary]",
+		"[Keys]",
+		"[Group]",
+		"[Names]",
+		"[Nested Leaf Primary]",
+		"[Nested Leaf Secondary]",
+	}, paths, "nested structs must be flattened unless they collect unresolved keys")
+
+	newType1 := func(name string) func() *type1 {
+		return func() *type1 { return &type1{name: name} }
+	}
+
+	c := New()
+	require.NoError(t, c.Provide(newType1("primary"), Name("primary")))
+	require.NoError(t, c.Provide(newType1("db.primary"), Name("db.primary")))
+	require.NoError(t, c.Provide(newType1("grouped"), Group("all")))
+
+	require.NoError(t, c.Invoke(func(p in) {
+		assert.Equal(t, "primary", p.First.name)
+		assert.Equal(t, "db.primary", p.Leaf.Primary.name)
+		assert.Nil(t, p.Leaf.Secondary)
+		assert.Nil(t, p.Keys.Missing)
+		require.Len(t, p.Group, 1)
+		assert.Equal(t, "grouped", p.Group[0].name)
+		require.Len(t, p.Names, 2)
+		assert.Equal(t, "db.primary", p.Names[1].name)
+		assert.Equal(t, "primary", p.Nested.Leaf.Primary.name)
+
+		assert.Equal(t, UnresolvedKeys{{Type: reflect.TypeOf(&type2{})}}, p.Keys.Unresolved)
+		assert.Equal(t, UnresolvedKeys{
+			{Type: reflect.TypeOf(&type1{}), Name: "db.secondary"},
+			{Type: reflect.TypeOf(&type2{})},
+			{Type: reflect.TypeOf(&type1{}), Name: "secondary"},
+		}, p.Unresolved)
+	}))
+
+	t.Run("errors name the nested field", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newType1("primary"), Name("primary")))
+		require.NoError(t, c.Provide(func() (*type1, error) {
+			return nil, errors.New("great sadness")
+		}, Name("db.primary")))
+
+		err := c.Invoke(func(p struct {
+			In
+
+			Outer struct {
+				In
+
+				Leaf leaf `nameprefix:"db."`
+			}
+		}) {
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "field Outer.Leaf.Primary")
+		assert.Contains(t, err.Error(), "great sadness")
+	})
+}
+
+func BenchmarkParamObject(b *testing.B) {
+	// A dig.In struct of 30 named fields, 10 of which are in nested
+	// dig.In structs.
+	nested := []reflect.StructField{{Name: "In", Type: _inType, Anonymous: true}}
+	for i := 0; i < 10; i++ {
+		nested = append(nested, reflect.StructField{
+			Name: fmt.Sprintf("N%d", i),
+			Type: reflect.TypeOf(0),
+			Tag:  reflect.StructTag(fmt.Sprintf(`name:"n%d"`, i)),
+		})
+	}
+	fields := []reflect.StructField{{Name: "In", Type: _inType, Anonymous: true}}
+	for i := 0; i < 20; i++ {
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: reflect.TypeOf(0),
+			Tag:  reflect.StructTag(fmt.Sprintf(`name:"f%d"`, i)),
+		})
+	}
+	fields = append(fields, reflect.StructField{Name: "Nested", Type: reflect.StructOf(nested)})
+
+	c := New()
+	for i := 0; i < 20; i++ {
+		i := i
+		require.NoError(b, c.Provide(func() int { return i }, Name(fmt.Sprintf("f%d", i))))
+	}
+	for i := 0; i < 10; i++ {
+		i := i
+		require.NoError(b, c.Provide(func() int { return i }, Name(fmt.Sprintf("n%d", i))))
+	}
+
+	po, err := newParamObject(reflect.StructOf(fields))
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := po.Build(c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func TestParamGroupSliceErrors(t *testing.T) {
 	tests := []struct {
 		desc    string
@@ -131,8 +362,8 @@ func TestParamGroupSliceErrors(t *testing.T) {
 
 				Foo string `group:"foo"`
 			}{},
-			wantErr: "value groups may be consumed as slices only: " +
-				`field "Foo" (string) is not a slice`,
+			wantErr: "value groups may be consumed as slices or iterators only: " +
+				`field "Foo" (string) is not a slice or an iterator`,
 		},
 		{
 			desc: "cannot provide name for a group",
@@ -164,6 +395,160 @@ func TestParamGroupSliceErrors(t *testing.T) {
 	}
 }
 
+func TestParamNamedSliceErrors(t *testing.T) {
+	tests := []struct {
+		desc    string
+		shape   interface{}
+		wantErr string
+	}{
+		{
+			desc: "non-slice type are disallowed",
+			shape: struct {
+				In
+
+				Foo string `names:"a,b"`
+			}{},
+			wantErr: `field "Foo" (string) is not a slice`,
+		},
+		{
+			desc: "cannot combine with name",
+			shape: struct {
+				In
+
+				Foo []string `names:"a,b" name:"c"`
+			}{},
+			wantErr: `cannot use name:"c" with names:"a,b"`,
+		},
+		{
+			desc: "empty name",
+			shape: struct {
+				In
+
+				Foo []string `names:"a,,b"`
+			}{},
+			wantErr: `invalid value "a,,b" for "names" tag: names cannot be empty`,
+		},
+		{
+			desc: "duplicate name",
+			shape: struct {
+				In
+
+				Foo []string `names:"a,b,a"`
+			}{},
+			wantErr: `name "a" is listed more than once`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := newParamObject(reflect.TypeOf(tt.shape))
+			require.Error(t, err, "expected failure")
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestParamNamedSlice(t *testing.T) {
+	type resolver struct{ name string }
+
+	provide := func(t *testing.T, c *Container, names ...string) {
+		for _, name := range names {
+			name := name
+			require.NoError(t, c.Provide(func() *resolver { return &resolver{name: name} }, Name(name)))
+		}
+	}
+	resolverNames := func(rs []*resolver) []string {
+		var names []string
+		for _, r := range rs {
+			names = append(names, r.name)
+		}
+		return names
+	}
+
+	t.Run("listed order", func(t *testing.T) {
+		type in struct {
+			In
+
+			Resolvers []*resolver `names:"primary, secondary,tertiary"`
+		}
+
+		c := New()
+		provide(t, c, "tertiary", "secondary", "primary")
+		require.NoError(t, c.Invoke(func(p in) {
+			assert.Equal(t, []string{"primary", "secondary", "tertiary"}, resolverNames(p.Resolvers))
+		}))
+	})
+
+	t.Run("missing value", func(t *testing.T) {
+		type in struct {
+			In
+
+			Resolvers []*resolver `names:"primary,secondary"`
+		}
+
+		c := New()
+		provide(t, c, "primary")
+		err := c.Invoke(func(in) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `field Resolvers of argument 1: type *dig.resolver[name="secondary"] is not in the container`)
+	})
+
+	t.Run("optional skips missing values", func(t *testing.T) {
+		type in struct {
+			In
+
+			Resolvers  []*resolver `names:"primary,secondary,tertiary" optional:"true"`
+			Unresolved UnresolvedKeys
+		}
+
+		c := New()
+		provide(t, c, "tertiary", "primary")
+		require.NoError(t, c.Invoke(func(p in) {
+			assert.Equal(t, []string{"primary", "tertiary"}, resolverNames(p.Resolvers))
+			assert.Equal(t, UnresolvedKeys{
+				{Type: reflect.TypeOf(&resolver{}), Name: "secondary"},
+			}, p.Unresolved)
+		}))
+	})
+
+	t.Run("name prefix", func(t *testing.T) {
+		type inner struct {
+			In
+
+			Resolvers []*resolver `names:"primary,secondary"`
+		}
+		type in struct {
+			In
+
+			Inner inner `nameprefix:"dns."`
+		}
+
+		c := New()
+		provide(t, c, "dns.primary", "dns.secondary")
+		require.NoError(t, c.Invoke(func(p in) {
+			assert.Equal(t, []string{"dns.primary", "dns.secondary"}, resolverNames(p.Inner.Resolvers))
+		}))
+	})
+
+	t.Run("dot edges", func(t *testing.T) {
+		po, err := newParamObject(reflect.TypeOf(struct {
+			In
+
+			Resolvers []*resolver `names:"primary,secondary" optional:"true"`
+		}{}))
+		require.NoError(t, err)
+
+		params := po.DotParam()
+		require.Len(t, params, 2)
+		for i, name := range []string{"primary", "secondary"} {
+			assert.Equal(t, name, params[i].Name)
+			assert.Equal(t, reflect.TypeOf(&resolver{}), params[i].Type)
+			assert.True(t, params[i].Optional)
+		}
+		assert.Equal(t, `*dig.resolver[names="primary,secondary"]`, po.Fields[0].Param.String())
+	})
+}
+
 func TestParamVisitorChecksEverything(t *testing.T) {
 	type params struct {
 		In
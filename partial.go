@@ -0,0 +1,93 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package dig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// CommitPartialResults is a ProvideOption that keeps the values a
+// constructor returned along with an error instead of failing.
+//
+//   func NewClients(cfg *Config) (*UserClient, *BillingClient, error)
+//
+//   c.Provide(NewClients, dig.CommitPartialResults())
+//
+// If the constructor returns a non-nil error and at least one value that
+// isn't the zero value of its type, such as a non-nil pointer, the non-zero
+// values are added to the container, and the error is reported as a Warning
+// by the constructor. Consumers of the zero values fail with the error of the
+// constructor instead of receiving them. If all values are zero values, the
+// constructor fails as usual. Panics always fail the constructor.
+//
+// The constructor may only return plain values, optionally with names: it
+// may not return dig.Out structs or produce values for value groups.
+func CommitPartialResults() ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.CommitPartialResults = true
+	})
+}
+
+// checkPartialResults returns an error if the results of a constructor
+// can't be committed partially.
+func checkPartialResults(rl resultList) error {
+	for _, r := range rl.Results {
+		if _, ok := r.(resultSingle); !ok {
+			return fmt.Errorf("cannot use dig.CommitPartialResults with %v: "+
+				"it returns result objects or produces values for value groups", rl.ctype)
+		}
+	}
+	return nil
+}
+
+// ExtractPartial stores the non-zero values returned by a constructor that
+// failed with the given error into the provided containerWriter, and records
+// the error for the others. It stores nothing and reports false if all values
+// are zero values.
+//
+// The results must have been checked with checkPartialResults.
+func (rl resultList) ExtractPartial(cw containerWriter, values []reflect.Value, err error) (bool, error) {
+	var salvaged bool
+	for i, v := range values {
+		if rl.resultIndexes[i] >= 0 && !v.IsZero() {
+			salvaged = true
+		}
+	}
+	if !salvaged {
+		return false, nil
+	}
+
+	for i, v := range values {
+		resultIdx := rl.resultIndexes[i]
+		if resultIdx < 0 {
+			continue
+		}
+		rs := rl.Results[resultIdx].(resultSingle)
+		if v.IsZero() {
+			cw.setValueError(rs.Name, rs.Type, err)
+			continue
+		}
+		if err := rs.Extract(cw, v); err != nil {
+			return false, err
+		}
+	}
+	return true, cw.submitGroupedValue(_warningsGroup, _warningType, reflect.ValueOf(Warning{Err: err}))
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package dig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitPartialResults(t *testing.T) {
+	type UserClient struct{}
+	type BillingClient struct{}
+
+	newClients := func(billing bool) func() (*UserClient, *BillingClient, error) {
+		return func() (*UserClient, *BillingClient, error) {
+			if billing {
+				return &UserClient{}, &BillingClient{}, nil
+			}
+			return &UserClient{}, nil, errors.New("billing is down")
+		}
+	}
+
+	t.Run("non-zero values are committed", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newClients(false), CommitPartialResults()))
+		require.NoError(t, c.Invoke(func(u *UserClient) {
+			assert.NotNil(t, u)
+		}))
+
+		warnings := c.Warnings()
+		require.Len(t, warnings, 1)
+		assert.EqualError(t, warnings[0].Err, "billing is down")
+		assert.Contains(t, warnings[0].Name, "TestCommitPartialResults")
+	})
+
+	t.Run("consumers of zero values fail", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newClients(false), CommitPartialResults()))
+		require.NoError(t, c.Invoke(func(*UserClient) {}))
+
+		err := c.Invoke(func(*BillingClient) {
+			t.Fatal("function must not be called")
+		})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestCommitPartialResults\S+`,
+			`failed to build \*dig.BillingClient:`,
+			`function "go.uber.org/dig".TestCommitPartialResults\S+ \(\S+\) returned a non-nil error:`,
+			"billing is down")
+		assert.Equal(t, "billing is down", RootCause(err).Error())
+	})
+
+	t.Run("all zero values fail", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() (*UserClient, *BillingClient, error) {
+			return nil, nil, errors.New("everything is down")
+		}, CommitPartialResults()))
+
+		err := c.Invoke(func(*UserClient) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "everything is down")
+		assert.Empty(t, c.Warnings())
+	})
+
+	t.Run("without errors", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newClients(true), CommitPartialResults()))
+		require.NoError(t, c.Invoke(func(*UserClient, *BillingClient) {}))
+		assert.Empty(t, c.Warnings())
+	})
+
+	t.Run("without the option", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newClients(false)))
+		err := c.Invoke(func(*UserClient) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "billing is down")
+	})
+
+	t.Run("named values", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() (string, string, error) {
+			return "primary", "", errors.New("no replica")
+		}, NameResult(0, "primary"), NameResult(1, "replica"), CommitPartialResults()))
+
+		require.NoError(t, c.Invoke(func(p struct {
+			In
+
+			Primary string `name:"primary"`
+		}) {
+			assert.Equal(t, "primary", p.Primary)
+		}))
+		err := c.Invoke(func(p struct {
+			In
+
+			Replica string `name:"replica"`
+		}) {
+		})
+		require.Error(t, err)
+		assert.Equal(t, "no replica", RootCause(err).Error())
+	})
+
+	t.Run("panics fail", func(t *testing.T) {
+		c := New(RecoverFromPanics())
+		require.NoError(t, c.Provide(func() (*UserClient, error) {
+			panic("great sadness")
+		}, CommitPartialResults()))
+		require.Error(t, c.Invoke(func(*UserClient) {}))
+	})
+
+	t.Run("result objects are rejected", func(t *testing.T) {
+		type out struct {
+			Out
+
+			User *UserClient
+		}
+		err := New().Provide(func() (out, error) { return out{}, nil }, CommitPartialResults())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot use dig.CommitPartialResults with func() (dig.out, error): "+
+			"it returns result objects or produces values for value groups")
+	})
+
+	t.Run("value groups are rejected", func(t *testing.T) {
+		err := New().Provide(newClients(false), Group("clients"), CommitPartialResults())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot use dig.CommitPartialResults")
+	})
+}
@@ -0,0 +1,277 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopePool(t *testing.T) {
+	type request struct{ id int }
+
+	t.Run("scopes are empty", func(t *testing.T) {
+		c := New()
+		pool := NewScopePool(c)
+
+		s := pool.Get()
+		require.NoError(t, s.Provide(Value(&request{id: 1})))
+		require.NoError(t, s.Invoke(func(*request) {}))
+		pool.Put(s)
+
+		s = pool.Get()
+		require.Error(t, s.Invoke(func(*request) {}), "values must not survive Put")
+		require.NoError(t, s.Provide(Value(&request{id: 2})), "providers must not survive Put")
+		require.NoError(t, s.Invoke(func(r *request) {
+			assert.Equal(t, 2, r.id)
+		}))
+		pool.Put(s)
+	})
+
+	t.Run("constructors are kept", func(t *testing.T) {
+		type session struct{ req *request }
+
+		pool := NewScopePool(New())
+		var calls int
+		newSession := func(r *request) *session {
+			calls++
+			return &session{req: r}
+		}
+		for i := 0; i < 3; i++ {
+			s := pool.Get()
+			req := &request{id: i}
+			require.NoError(t, s.Provide(func() *request { return req }))
+			require.NoError(t, s.Provide(newSession))
+			require.NoError(t, s.Invoke(func(s *session) {
+				assert.Equal(t, i, s.req.id, "constructors must be called with the values of each use")
+			}))
+			pool.Put(s)
+		}
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("different constructors", func(t *testing.T) {
+		type (
+			user    struct{ name string }
+			session struct{ id int }
+		)
+
+		pool := NewScopePool(New())
+		s := pool.Get()
+		require.NoError(t, s.Provide(Value(&request{id: 1})))
+		require.NoError(t, s.Provide(func(r *request) *session { return &session{id: r.id} }))
+		pool.Put(s)
+
+		s = pool.Get()
+		require.NoError(t, s.Provide(Value(&request{id: 2})))
+		require.NoError(t, s.Provide(func() *user { return &user{name: "foo"} }))
+		require.NoError(t, s.Invoke(func(r *request, u *user) {
+			assert.Equal(t, 2, r.id)
+			assert.Equal(t, "foo", u.name)
+		}))
+		err := s.Invoke(func(*session) {})
+		require.Error(t, err, "constructors that weren't provided again must be dropped")
+		assert.Contains(t, err.Error(), "type *dig.session is not in the container")
+		pool.Put(s)
+
+		s = pool.Get()
+		require.NoError(t, s.Provide(Value(&request{id: 3})))
+		err = s.Invoke(func(*user) {})
+		require.Error(t, err, "constructors that weren't provided again must be dropped")
+		assert.Contains(t, err.Error(), "type *dig.user is not in the container")
+		pool.Put(s)
+	})
+
+	t.Run("use after put", func(t *testing.T) {
+		pool := NewScopePool(New())
+		s := pool.Get()
+		pool.Put(s)
+
+		err := s.Provide(Value(&request{}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `cannot use scope "pooled" after it was returned to its pool`)
+
+		err = s.Invoke(func() {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `cannot use scope "pooled" after it was returned to its pool`)
+
+		assert.Panics(t, func() { pool.Put(s) }, "double put must panic")
+	})
+
+	t.Run("foreign scopes", func(t *testing.T) {
+		c := New()
+		pool := NewScopePool(c)
+		assert.Panics(t, func() { pool.Put(c.Scope("foo")) })
+		assert.Panics(t, func() { NewScopePool(c).Put(pool.Get()) })
+	})
+
+	t.Run("release", func(t *testing.T) {
+		pool := NewScopePool(New())
+
+		var calls []string
+		s := pool.Get()
+		require.NoError(t, s.OnClose(func() error {
+			calls = append(calls, "first")
+			return errors.New("great sadness")
+		}))
+		require.NoError(t, s.OnClose(func() error {
+			calls = append(calls, "second")
+			return nil
+		}))
+
+		err := pool.Release(s)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to release the scope:")
+		assert.Contains(t, err.Error(), "great sadness")
+		assert.Equal(t, []string{"second", "first"}, calls)
+		assert.Panics(t, func() { pool.Put(s) }, "scope must have been returned")
+
+		s = pool.Get()
+		require.NoError(t, pool.Release(s), "OnClose functions must not survive Release")
+		assert.Len(t, calls, 2)
+
+		s = pool.Get()
+		require.NoError(t, s.OnClose(func() error {
+			calls = append(calls, "discarded")
+			return nil
+		}))
+		pool.Put(s)
+		assert.Len(t, calls, 2, "Put must not call OnClose functions")
+	})
+
+	t.Run("concurrent scopes", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return new(bytes.Buffer) }))
+		require.NoError(t, c.Invoke(func(*bytes.Buffer) {}))
+
+		pool := NewScopePool(c)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				s := pool.Get()
+				defer pool.Put(s)
+
+				assert.NoError(t, s.Provide(Value(&request{id: i})))
+				assert.NoError(t, s.Invoke(func(r *request, _ *bytes.Buffer) {
+					assert.Equal(t, i, r.id)
+				}))
+			}(i)
+		}
+		wg.Wait()
+	})
+}
+
+func TestScopePoolAllocs(t *testing.T) {
+	type (
+		request struct{ path string }
+		session struct{ req *request }
+	)
+
+	c := New()
+	newSession := func(r *request) *session { return &session{req: r} }
+	serve := func(s *Container) {
+		require.NoError(t, s.Provide(Value(&request{path: "/"})))
+		require.NoError(t, s.Provide(newSession))
+		require.NoError(t, s.Invoke(func(*session) {}))
+	}
+
+	fresh := testing.AllocsPerRun(100, func() {
+		serve(c.Scope("request"))
+	})
+	// Reset the scope like Put does: the race detector makes the sync.Pool
+	// behind ScopePool drop scopes at random.
+	s := NewScopePool(c).Get()
+	pooled := testing.AllocsPerRun(100, func() {
+		serve(s)
+		s.reset()
+	})
+	assert.True(t, pooled < fresh/2,
+		"pooled scopes must not build their constructors again: %v allocs, against %v for new scopes", pooled, fresh)
+}
+
+func BenchmarkScopePool(b *testing.B) {
+	type (
+		config   struct{ prefix string }
+		logger   struct{ cfg *config }
+		database struct{ cfg *config }
+		request  struct{ path string }
+		session  struct {
+			log *logger
+			req *request
+		}
+		handlerParams struct {
+			In
+
+			Log     *logger
+			DB      *database
+			Session *session
+		}
+	)
+
+	c := New()
+	require.NoError(b, c.Provide(func() *config { return &config{prefix: "app"} }))
+	require.NoError(b, c.Provide(func(cfg *config) *logger { return &logger{cfg: cfg} }))
+	require.NoError(b, c.Provide(func(cfg *config) *database { return &database{cfg: cfg} }))
+	require.NoError(b, c.Invoke(func(*logger, *database) {}))
+
+	newSession := func(log *logger, req *request) *session {
+		return &session{log: log, req: req}
+	}
+	handler := func(handlerParams) {}
+	req := &request{path: "/"}
+
+	serve := func(b *testing.B, s *Container) {
+		if err := s.Provide(Value(req)); err != nil {
+			b.Fatal(err)
+		}
+		if err := s.Provide(newSession); err != nil {
+			b.Fatal(err)
+		}
+		if err := s.Invoke(handler); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("new scope", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			serve(b, c.Scope("request"))
+		}
+	})
+
+	b.Run("pooled scope", func(b *testing.B) {
+		pool := NewScopePool(c)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s := pool.Get()
+			serve(b, s)
+			pool.Put(s)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+const _injectTag = "inject"
+
+// PopulateFields is a ProvideOption that specifies that the fields of values
+// produced by a constructor should be filled in from the container after the
+// constructor returns. This is useful for types that expose their
+// dependencies as exported fields rather than constructor parameters.
+//
+// Fields are populated if they are tagged with `inject:"true"`, or with
+// `name:".."` or `group:".."`. The `optional:"true"` tag is honored as with
+// dig.In structs.
+//
+//   type Handler struct {
+//     Logger *log.Logger `inject:"true"`
+//     DB     *sql.DB     `name:"ro"`
+//   }
+//
+//   c.Provide(func() *Handler { return &Handler{} }, dig.PopulateFields())
+//
+// Fields are populated before the value is made available to other
+// constructors so consumers always observe fully populated values.
+//
+// This option may only be used with constructors that return pointers to
+// structs.
+func PopulateFields() ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.PopulateFields = true
+	})
+}
+
+// populatedResult is a pointer-to-struct result of a constructor whose
+// tagged fields are populated after the constructor returns.
+type populatedResult struct {
+	// Position of the result in the constructor's return values.
+	Index int
+
+	// Fields of the struct that will be populated.
+	Object paramObject
+}
+
+// newPopulatedResults builds a populatedResult for each value returned by the
+// provided constructor type.
+func newPopulatedResults(ctype reflect.Type) ([]populatedResult, error) {
+	var prs []populatedResult
+	for i := 0; i < ctype.NumOut(); i++ {
+		t := ctype.Out(i)
+		if isError(t) {
+			continue
+		}
+
+		if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct || IsOut(t.Elem()) {
+			return nil, fmt.Errorf(
+				"bad result %d: cannot populate fields of %v: "+
+					"dig.PopulateFields requires results that are pointers to structs", i+1, t)
+		}
+
+		po, err := newPopulatedObject(t.Elem())
+		if err != nil {
+			return nil, errWrapf(err, "bad result %d", i+1)
+		}
+		prs = append(prs, populatedResult{Index: i, Object: po})
+	}
+	return prs, nil
+}
+
+// newPopulatedObject builds a paramObject for the tagged fields of the given
+// struct type.
+func newPopulatedObject(t reflect.Type) (paramObject, error) {
+	po := paramObject{Type: t}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		inject, err := isFieldInjected(f)
+		if err != nil {
+			return po, errWrapf(err, "bad field %q of %v", f.Name, t)
+		}
+		if !inject {
+			continue
+		}
+
+		pof, err := newParamObjectField(i, f)
+		if err != nil {
+			return po, errWrapf(err, "bad field %q of %v", f.Name, t)
+		}
+		po.Fields = append(po.Fields, pof)
+	}
+	po.plan = newParamPlan(po)
+	return po, nil
+}
+
+// Checks if a field of a struct should be populated by dig.PopulateFields.
+func isFieldInjected(f reflect.StructField) (bool, error) {
+	if f.Tag.Get(_nameTag) != "" || f.Tag.Get(_groupTag) != "" || f.Tag.Get(_envTag) != "" {
+		return true, nil
+	}
+
+	tag := f.Tag.Get(_injectTag)
+	if tag == "" {
+		return false, nil
+	}
+
+	inject, err := strconv.ParseBool(tag)
+	if err != nil {
+		err = errWrapf(err,
+			"invalid value %q for %q tag on field %v",
+			tag, _injectTag, f.Name)
+	}
+	return inject, err
+}
+
+// PopulateResults populates the tagged fields of the values returned by the
+// constructor.
+func (pl paramList) PopulateResults(c containerStore, results []reflect.Value) error {
+	for _, pr := range pl.Populated {
+		v := results[pr.Index]
+		if v.IsNil() {
+			return fmt.Errorf("cannot populate fields of nil %v", v.Type())
+		}
+		if err := pr.Object.buildInto(c, v.Elem()); err != nil {
+			return errWrapf(err, "could not populate fields of %v", v.Type())
+		}
+	}
+	return nil
+}
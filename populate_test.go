@@ -0,0 +1,186 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPopulateFields(t *testing.T) {
+	type handler struct {
+		Buffer   *bytes.Buffer `inject:"true"`
+		RO       string        `name:"ro"`
+		Ints     []int         `group:"ints"`
+		Missing  *int          `inject:"true" optional:"true"`
+		Untagged string
+		Skipped  *bytes.Buffer `inject:"false"`
+	}
+
+	t.Run("fields are populated before commit", func(t *testing.T) {
+		c := New()
+		buf := new(bytes.Buffer)
+		require.NoError(t, c.Provide(func() *bytes.Buffer { return buf }))
+		require.NoError(t, c.Provide(func() string { return "foo" }, Name("ro")))
+		require.NoError(t, c.Provide(func() int { return 42 }, Group("ints")))
+		require.NoError(t, c.Provide(func() *handler {
+			return &handler{Untagged: "bar"}
+		}, PopulateFields()))
+
+		require.NoError(t, c.Invoke(func(h *handler) {
+			assert.True(t, h.Buffer == buf, "buffer must be populated")
+			assert.Equal(t, "foo", h.RO)
+			assert.Equal(t, []int{42}, h.Ints)
+			assert.Nil(t, h.Missing)
+			assert.Equal(t, "bar", h.Untagged)
+			assert.Nil(t, h.Skipped)
+		}))
+	})
+
+	t.Run("missing field dependency", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *handler {
+			require.FailNow(t, "constructor must not be called")
+			return nil
+		}, PopulateFields()))
+
+		err := c.Invoke(func(*handler) {})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`missing dependencies for function "go.uber.org/dig".TestPopulateFields\S+`,
+			"the following types are not in the container:",
+			`\*bytes.Buffer`,
+			`string\[name="ro"\]`,
+		)
+	})
+
+	t.Run("nil result", func(t *testing.T) {
+		type simple struct {
+			S string `inject:"true"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() string { return "" }))
+		require.NoError(t, c.Provide(func() *simple { return nil }, PopulateFields()))
+
+		err := c.Invoke(func(*simple) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot populate fields of nil *dig.simple")
+	})
+
+	t.Run("field failure", func(t *testing.T) {
+		type simple struct {
+			S string `inject:"true"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() (string, error) { return "", errors.New("great sadness") }))
+		require.NoError(t, c.Provide(func() *simple { return &simple{} }, PopulateFields()))
+
+		err := c.Invoke(func(*simple) {
+			require.FailNow(t, "invoke must not be called")
+		})
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not populate fields of \*dig.simple:`,
+			"great sadness",
+		)
+
+		require.Error(t, c.Invoke(func(*simple) {}), "value must not have been committed")
+	})
+
+	t.Run("cycle through fields", func(t *testing.T) {
+		type a struct {
+			S string `inject:"true"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func(*a) string { return "" }))
+		err := c.Provide(func() *a { return &a{} }, PopulateFields())
+		require.Error(t, err)
+		assert.True(t, IsCycleDetected(err), "expected a cycle: %v", err)
+	})
+
+	t.Run("unsupported results", func(t *testing.T) {
+		type out struct {
+			Out
+
+			S string
+		}
+
+		type unexported struct {
+			s string `inject:"true"`
+		}
+
+		type badTag struct {
+			S string `inject:"yes"`
+		}
+
+		tests := []struct {
+			desc        string
+			constructor interface{}
+			err         string
+		}{
+			{
+				desc:        "value",
+				constructor: func() handler { return handler{} },
+				err:         "bad result 1: cannot populate fields of dig.handler",
+			},
+			{
+				desc:        "pointer to non-struct",
+				constructor: func() *int { return nil },
+				err:         "bad result 1: cannot populate fields of *int",
+			},
+			{
+				desc:        "second result",
+				constructor: func() (*handler, string, error) { return nil, "", nil },
+				err:         "bad result 2: cannot populate fields of string",
+			},
+			{
+				desc:        "result object",
+				constructor: func() *out { return nil },
+				err:         "bad result 1: cannot populate fields of *dig.out",
+			},
+			{
+				desc:        "unexported field",
+				constructor: func() *unexported { return nil },
+				err:         "unexported fields not allowed",
+			},
+			{
+				desc:        "invalid inject tag",
+				constructor: func() *badTag { return nil },
+				err:         `invalid value "yes" for "inject" tag on field S`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				err := New().Provide(tt.constructor, PopulateFields())
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+			})
+		}
+	})
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import "reflect"
+
+// PreflightReport describes the work an Invoke will do to build the
+// dependencies of its function. See Preflight.
+//
+// Keys are listed in the order in which they are first encountered while
+// walking the dependencies of the function, and each key is listed at most
+// once.
+type PreflightReport struct {
+	// Values that are already in the container and will be reused.
+	//
+	// Value groups are reported here if all of their constructors have
+	// already been called.
+	Cached []Key
+
+	// Values that will be built by calling their constructors.
+	Constructed []Key
+
+	// Optional values that nothing provides. These will be filled with zero
+	// values.
+	MissingOptional []Key
+
+	// Required values that nothing provides. The Invoke will fail if this is
+	// non-empty.
+	Missing []Key
+
+	// Values that nothing provides that will be resolved from a provided
+	// value of another type assignable to them, keyed by the requested value.
+	// See AllowAssignableTypes.
+	Assigned map[Key]Key
+}
+
+// Preflight is an InvokeOption that fills the given report with a summary of
+// which dependencies of the invoked function will be served from the
+// container's cache and which will be built fresh. The report is filled in
+// before any constructors are called, even if the Invoke fails.
+//
+//   var report dig.PreflightReport
+//   err := c.Invoke(startServer, dig.Preflight(&report))
+//   log.Printf("constructing %d values", len(report.Constructed))
+//
+// Building the report does not call any constructors.
+func Preflight(report *PreflightReport) InvokeOption {
+	return invokeOptionFunc(func(opts *invokeOptions) {
+		opts.Preflight = report
+	})
+}
+
+func newPreflightReport(c containerStore, pl paramList) PreflightReport {
+	var (
+		r     PreflightReport
+		seen  = make(map[key]struct{})
+		visit paramVisitorFunc
+	)
+
+	visit = func(p param) bool {
+		var (
+			k         key
+			providers []provider
+		)
+
+		switch p := p.(type) {
+		case paramSingle:
+			k = key{name: p.Name, t: p.Type}
+			if _, ok := seen[k]; ok {
+				return false
+			}
+			seen[k] = struct{}{}
+
+			if _, ok := c.getValue(p.Name, p.Type); ok {
+				r.Cached = append(r.Cached, k.exported())
+				return false
+			}
+
+			providers = c.getValueProviders(p.Name, p.Type)
+			if impls := c.getImplementations(p.Name, p.Type); len(providers) == 0 && len(impls) == 1 {
+				// Report the implementation or assignable type this
+				// value resolves to.
+				if p.Type.Kind() != reflect.Interface {
+					if r.Assigned == nil {
+						r.Assigned = make(map[Key]Key)
+					}
+					r.Assigned[k.exported()] = key{name: p.Name, t: impls[0]}.exported()
+				}
+				return visit(paramSingle{Name: p.Name, Type: impls[0]})
+			}
+			if len(providers) == 0 {
+				if p.Optional {
+					r.MissingOptional = append(r.MissingOptional, k.exported())
+				} else {
+					r.Missing = append(r.Missing, k.exported())
+				}
+				return false
+			}
+
+		case paramGroupedSlice:
+			k = key{group: p.Group, t: p.Type.Elem()}
+			if _, ok := seen[k]; ok {
+				return false
+			}
+			seen[k] = struct{}{}
+
+			for _, n := range c.getGroupProviders(p.Group, p.Type.Elem()) {
+				if !n.Called() {
+					providers = append(providers, n)
+				}
+			}
+			if len(providers) == 0 {
+				r.Cached = append(r.Cached, k.exported())
+				return false
+			}
+
+		default:
+			// Recurse for non-edge params.
+			return true
+		}
+
+		r.Constructed = append(r.Constructed, k.exported())
+		for _, n := range providers {
+			walkParam(n.ParamList(), visit)
+		}
+		return false
+	}
+
+	walkParam(pl, visit)
+	return r
+}
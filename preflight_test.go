@@ -0,0 +1,103 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreflight(t *testing.T) {
+	type in struct {
+		In
+
+		Buffer   *bytes.Buffer
+		Name     string `name:"foo"`
+		Ints     []int  `group:"ints"`
+		Optional *int   `optional:"true"`
+	}
+
+	var (
+		bufferKey = Key{Type: reflect.TypeOf(&bytes.Buffer{})}
+		nameKey   = Key{Type: reflect.TypeOf(""), Name: "foo"}
+		intsKey   = Key{Type: reflect.TypeOf(0), Group: "ints"}
+		optKey    = Key{Type: reflect.TypeOf((*int)(nil))}
+		floatKey  = Key{Type: reflect.TypeOf(float64(0))}
+	)
+
+	c := New()
+	calls := 0
+	require.NoError(t, c.Provide(func(float64) *bytes.Buffer {
+		calls++
+		return new(bytes.Buffer)
+	}))
+	require.NoError(t, c.Provide(func() float64 { calls++; return 0 }))
+	require.NoError(t, c.Provide(func(*bytes.Buffer) string { calls++; return "" }, Name("foo")))
+	require.NoError(t, c.Provide(func() int { calls++; return 1 }, Group("ints")))
+
+	t.Run("nothing constructed yet", func(t *testing.T) {
+		var report PreflightReport
+		require.NoError(t, c.Invoke(func(in) {
+			assert.Equal(t, 4, calls, "constructors must be called by Invoke")
+		}, Preflight(&report)))
+
+		assert.Empty(t, report.Cached)
+		assert.Equal(t, []Key{bufferKey, floatKey, nameKey, intsKey}, report.Constructed)
+		assert.Equal(t, []Key{optKey}, report.MissingOptional)
+		assert.Empty(t, report.Missing)
+	})
+
+	t.Run("everything cached", func(t *testing.T) {
+		var report PreflightReport
+		require.NoError(t, c.Invoke(func(in) {}, Preflight(&report)))
+
+		assert.Equal(t, []Key{bufferKey, nameKey, intsKey}, report.Cached)
+		assert.Empty(t, report.Constructed)
+		assert.Equal(t, []Key{optKey}, report.MissingOptional)
+		assert.Empty(t, report.Missing)
+		assert.Equal(t, 4, calls, "constructors must not be called again")
+	})
+
+	t.Run("missing dependencies", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func(float64) string {
+			require.FailNow(t, "constructor must not be called")
+			return ""
+		}))
+
+		var report PreflightReport
+		require.Error(t, c.Invoke(func(string, *bytes.Buffer) {}, Preflight(&report)))
+
+		assert.Equal(t, []Key{{Type: reflect.TypeOf("")}}, report.Constructed)
+		assert.Equal(t, []Key{floatKey, bufferKey}, report.Missing)
+	})
+}
+
+func TestKeyString(t *testing.T) {
+	typ := reflect.TypeOf("")
+	assert.Equal(t, "string", Key{Type: typ}.String())
+	assert.Equal(t, `string[name="foo"]`, Key{Type: typ, Name: "foo"}.String())
+	assert.Equal(t, `string[group="bar"]`, Key{Type: typ, Group: "bar"}.String())
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"reflect"
+	"time"
+)
+
+// TrackProvenance is an Option that makes the container remember which
+// constructor produced each of its values, so that Provenance can answer
+// where a value came from.
+//
+//   c := dig.New(dig.TrackProvenance())
+//   ...
+//   if p, ok := c.Provenance(db); ok {
+//     log.Printf("%v was built by %v.%v (%v:%v)", p.Key, p.Package, p.Name, p.File, p.Line)
+//   }
+//
+// Scopes inherit this option from their parents.
+func TrackProvenance() Option {
+	return optionFunc(func(c *Container) {
+		c.trackProvenance = true
+	})
+}
+
+// ValueProvenance describes how a value of the container was built. See
+// Provenance.
+type ValueProvenance struct {
+	// Key under which the value was added to the container. If the value
+	// was added under several keys, this is the first of them in the order
+	// of their types, names, and groups.
+	Key Key
+
+	// Name, package, and location of the constructor that produced the
+	// value.
+	Name    string
+	Package string
+	File    string
+	Line    int
+
+	// Function whose Invoke caused the constructor to be called, or nil if
+	// the value was built outside of an Invoke, for example by WarmUp.
+	Invoke *InvokeInfo
+
+	// Time at which the constructor was called, and the time it took.
+	Time     time.Time
+	Duration time.Duration
+}
+
+// provenanceKey identifies a value by its type and address.
+type provenanceKey struct {
+	t reflect.Type
+	p uintptr
+}
+
+// newProvenanceKey returns the identity of the given value. Only values that
+// refer to other memory, such as pointers and maps, have an identity.
+func newProvenanceKey(v reflect.Value) (provenanceKey, bool) {
+	for v.IsValid() && v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return provenanceKey{}, false
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.UnsafePointer:
+		if v.IsNil() {
+			return provenanceKey{}, false
+		}
+		return provenanceKey{t: v.Type(), p: v.Pointer()}, true
+	default:
+		return provenanceKey{}, false
+	}
+}
+
+// Provenance reports which constructor produced the given value, if the
+// container was created with the TrackProvenance option.
+//
+//   func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+//     if p, ok := c.Provenance(h.db); ok {
+//       fmt.Fprintf(w, "database from %v:%v\n", p.File, p.Line)
+//     }
+//   }
+//
+// Values are identified by their address, so only pointers, maps, channels,
+// and unsafe pointers are supported, including those wrapped in interfaces.
+// Provenance returns false for values of other kinds, for values that weren't
+// produced by a constructor of this container or one of its parents, and for
+// values built before provenance was tracked.
+func (c *Container) Provenance(v interface{}) (*ValueProvenance, bool) {
+	if c.checkInitialized() != nil {
+		return nil, false
+	}
+
+	pk, ok := newProvenanceKey(reflect.ValueOf(v))
+	if !ok {
+		return nil, false
+	}
+	for s := c; s != nil; s = s.parent {
+		if p, ok := s.provenance[pk]; ok {
+			return &p, true
+		}
+	}
+	return nil, false
+}
+
+// recordProvenance remembers that the values committed by the given writer
+// were produced by the constructor of the given node.
+func (c *Container) recordProvenance(n *node, sr *stagingContainerWriter) {
+	if !c.trackProvenance {
+		return
+	}
+	if c.provenance == nil {
+		c.provenance = make(map[provenanceKey]ValueProvenance)
+	}
+
+	record := func(k key, v reflect.Value) {
+		pk, ok := newProvenanceKey(v)
+		if !ok {
+			return
+		}
+		if _, ok := c.provenance[pk]; ok {
+			return
+		}
+		c.provenance[pk] = ValueProvenance{
+			Key:      k.exported(),
+			Name:     n.location.Name,
+			Package:  n.location.Package,
+			File:     n.location.File,
+			Line:     n.location.Line,
+			Invoke:   sr.invoke,
+			Time:     sr.built,
+			Duration: sr.duration,
+		}
+	}
+	for _, k := range sr.sortedKeys() {
+		if k.group == "" {
+			record(k, sr.values[k])
+			continue
+		}
+		for _, v := range sr.groups[k] {
+			record(k, v)
+		}
+	}
+}
+
+// currentInvoke returns the innermost function being invoked, or nil if
+// there is none or provenance isn't tracked.
+func (c *Container) currentInvoke() *InvokeInfo {
+	if !c.trackProvenance {
+		return nil
+	}
+	return c.calls.invoke
+}
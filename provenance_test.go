@@ -0,0 +1,152 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.uber.org/dig"
+)
+
+func TestProvenance(t *testing.T) {
+	t.Parallel()
+
+	type DB struct{ name string }
+
+	newDBs := func() (out struct {
+		dig.Out
+
+		Primary *DB `name:"primary"`
+		Replica *DB `name:"replica"`
+	}) {
+		out.Primary = &DB{name: "primary"}
+		out.Replica = &DB{name: "replica"}
+		return out
+	}
+
+	t.Run("named values", func(t *testing.T) {
+		t.Parallel()
+
+		c := dig.New(dig.TrackProvenance())
+		require.NoError(t, c.Provide(newDBs))
+
+		type params struct {
+			dig.In
+
+			Replica *DB `name:"replica"`
+		}
+		run := func(p params) {
+			prov, ok := c.Provenance(p.Replica)
+			require.True(t, ok)
+			assert.Equal(t, dig.Key{Type: reflect.TypeOf(&DB{}), Name: "replica"}, prov.Key)
+			assert.Contains(t, prov.Name, "TestProvenance")
+			assert.Contains(t, prov.File, "provenance_test.go")
+			assert.NotZero(t, prov.Line)
+			assert.False(t, prov.Time.IsZero())
+			require.NotNil(t, prov.Invoke)
+			assert.Equal(t, []dig.Key{{Type: reflect.TypeOf(&DB{}), Name: "replica"}}, prov.Invoke.Params)
+		}
+		require.NoError(t, c.Invoke(run))
+	})
+
+	t.Run("value groups", func(t *testing.T) {
+		t.Parallel()
+
+		c := dig.New(dig.TrackProvenance())
+		require.NoError(t, c.Provide(func() *DB { return &DB{name: "a"} }, dig.Group("dbs")))
+		require.NoError(t, c.Provide(func() map[string]int { return map[string]int{} }))
+
+		type params struct {
+			dig.In
+
+			DBs []*DB `group:"dbs"`
+			M   map[string]int
+		}
+		require.NoError(t, c.Invoke(func(p params) {
+			require.Len(t, p.DBs, 1)
+			prov, ok := c.Provenance(p.DBs[0])
+			require.True(t, ok)
+			assert.Equal(t, "dbs", prov.Key.Group)
+
+			_, ok = c.Provenance(p.M)
+			assert.True(t, ok, "maps have an identity")
+		}))
+	})
+
+	t.Run("scope sees parent values", func(t *testing.T) {
+		t.Parallel()
+
+		c := dig.New(dig.TrackProvenance())
+		require.NoError(t, c.Provide(func() *DB { return &DB{} }))
+		s := c.Scope("child")
+
+		require.NoError(t, s.Invoke(func(db *DB) {
+			_, ok := s.Provenance(db)
+			assert.True(t, ok)
+		}))
+	})
+
+	t.Run("built outside invoke", func(t *testing.T) {
+		t.Parallel()
+
+		c := dig.New(dig.TrackProvenance())
+		require.NoError(t, c.Provide(func() *DB { return &DB{} }))
+		require.NoError(t, c.WarmUp(context.Background()))
+
+		var db *DB
+		require.NoError(t, c.Invoke(func(d *DB) { db = d }))
+		prov, ok := c.Provenance(db)
+		require.True(t, ok)
+		assert.Nil(t, prov.Invoke)
+	})
+
+	t.Run("unsupported values", func(t *testing.T) {
+		t.Parallel()
+
+		c := dig.New(dig.TrackProvenance())
+		require.NoError(t, c.Provide(func() string { return "hello" }))
+		require.NoError(t, c.Invoke(func(s string) {
+			_, ok := c.Provenance(s)
+			assert.False(t, ok, "strings have no identity")
+		}))
+
+		_, ok := c.Provenance(&DB{})
+		assert.False(t, ok, "value not built by the container")
+		_, ok = c.Provenance(nil)
+		assert.False(t, ok)
+	})
+
+	t.Run("not tracked", func(t *testing.T) {
+		t.Parallel()
+
+		c := dig.New()
+		require.NoError(t, c.Provide(func() *DB { return &DB{} }))
+		require.NoError(t, c.Invoke(func(db *DB) {
+			_, ok := c.Provenance(db)
+			assert.False(t, ok)
+		}))
+	})
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// ReentrancyError is returned by an Invoke made from inside a constructor
+// when the invoked function depends on the results of a constructor that is
+// still running, such as the constructor that made the call.
+//
+// Constructors may call Invoke on their container or its scopes as long as
+// the invoked function only depends on values that are already built or on
+// constructors that aren't running. The constructors it needs are called at
+// most once, as usual. The values consumed this way are hidden from the
+// graph and reported by Container.HiddenEdges, unless the container was
+// created with DisallowNestedInvokes.
+type ReentrancyError struct {
+	// Constructor that is still running.
+	fn *digreflect.Func
+}
+
+func (e ReentrancyError) Error() string {
+	return fmt.Sprintf("cannot call function %v: it is still running "+
+		"and an Invoke made from inside it depends on its results", e.fn)
+}
+
+// callConstructor calls the constructor of this node with the given
+// arguments, marking it running until it returns.
+func (n *node) callConstructor(c containerStore, args []reflect.Value) ([]reflect.Value, error) {
+	n.running = true
+	defer func() { n.running = false }()
+	return c.call(n.location, n.ctor, args)
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReentrantInvoke(t *testing.T) {
+	type A struct{}
+	type B struct{}
+	type C struct{}
+
+	t.Run("independent values", func(t *testing.T) {
+		c := New()
+		var bCalls int
+		require.NoError(t, c.Provide(func() *B {
+			bCalls++
+			return &B{}
+		}))
+		require.NoError(t, c.Provide(func() (*A, error) {
+			return &A{}, c.Invoke(func(*B) {})
+		}))
+
+		require.NoError(t, c.Invoke(func(*A, *B) {}))
+		assert.Equal(t, 1, bCalls, "B must be constructed once")
+	})
+
+	t.Run("values that are already built", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *B { return &B{} }))
+		require.NoError(t, c.Provide(func(b *B) (*A, error) {
+			return &A{}, c.Invoke(func(got *B) {
+				assert.True(t, b == got, "expected the same *B")
+			})
+		}))
+		require.NoError(t, c.Invoke(func(*A) {}))
+	})
+
+	t.Run("from a scope", func(t *testing.T) {
+		c := New()
+		s := c.Scope("child")
+		require.NoError(t, c.Provide(func() *B { return &B{} }))
+		require.NoError(t, s.Provide(func() (*A, error) {
+			return &A{}, c.Invoke(func(*B) {})
+		}))
+		require.NoError(t, s.Invoke(func(*A) {}))
+	})
+
+	t.Run("self", func(t *testing.T) {
+		c := New()
+		var aCalls int
+		require.NoError(t, c.Provide(func() (*A, error) {
+			aCalls++
+			return &A{}, c.Invoke(func(*A) {})
+		}))
+
+		err := c.Invoke(func(*A) {})
+		require.Error(t, err)
+		var rerr ReentrancyError
+		require.True(t, errors.As(err, &rerr), "expected a ReentrancyError, got %v", err)
+		assertErrorMatches(t, err,
+			`cannot call function "go.uber.org/dig".TestReentrantInvoke\S+ \(\S+\): `,
+			`it is still running and an Invoke made from inside it depends on its results`)
+		assert.Equal(t, 1, aCalls, "A must not be constructed again")
+	})
+
+	t.Run("through another constructor", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func(*A) *B { return &B{} }))
+		require.NoError(t, c.Provide(func() (*A, error) {
+			return &A{}, c.Invoke(func(*B) {})
+		}))
+
+		err := c.Invoke(func(*A) {})
+		require.Error(t, err)
+		var rerr ReentrancyError
+		assert.True(t, errors.As(err, &rerr), "expected a ReentrancyError, got %v", err)
+	})
+
+	t.Run("through a value group", func(t *testing.T) {
+		type in struct {
+			In
+
+			Cs []*C `group:"cs"`
+		}
+		c := New()
+		require.NoError(t, c.Provide(func() *C { return &C{} }, Group("cs")))
+		require.NoError(t, c.Provide(func() (*C, error) {
+			return &C{}, c.Invoke(func(in) {})
+		}, Group("cs")))
+
+		err := c.Invoke(func(in) {})
+		require.Error(t, err)
+		var rerr ReentrancyError
+		assert.True(t, errors.As(err, &rerr), "expected a ReentrancyError, got %v", err)
+	})
+
+	t.Run("container is usable after a failure", func(t *testing.T) {
+		c := New()
+		fail := true
+		require.NoError(t, c.Provide(func() (*A, error) {
+			if fail {
+				fail = false
+				return nil, c.Invoke(func(*A) {})
+			}
+			return &A{}, nil
+		}))
+
+		require.Error(t, c.Invoke(func(*A) {}))
+		require.NoError(t, c.Invoke(func(*A) {}))
+	})
+}
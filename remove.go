@@ -0,0 +1,362 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Maximum number of invalidated keys that a container keeps for its scopes to
+// catch up with. See discardRemovedValues.
+const _maxInvalidated = 256
+
+// NamesFor returns the names under which values of the type pointed to by of
+// are provided to the container or its parents, in sorted order. Pass a nil
+// pointer of the type to look up.
+//
+//   names := c.NamesFor((*Plugin)(nil))
+func (c *Container) NamesFor(of interface{}) []string {
+	t := pointedType(of)
+	if t == nil {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	var names []string
+	for s := c; s != nil; s = s.parent {
+		for k := range s.providers {
+			if k.t != t || k.name == "" {
+				continue
+			}
+			if _, ok := seen[k.name]; !ok {
+				seen[k.name] = struct{}{}
+				names = append(names, k.name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RemoveNamed removes the constructor for the named value of the type pointed
+// to by of from the container, along with the value if it was already built.
+// Unnamed values and values with other names are left untouched.
+//
+//   err := c.RemoveNamed((*Plugin)(nil), "plugin-42")
+//
+// Values that were built from the removed value are discarded as well, so
+// that their constructors are called again the next time they are needed.
+//
+// Only values provided to this container may be removed, not those provided
+// to its parents. Scopes of the container discard the values they built from
+// the removed value the next time they are invoked.
+func (c *Container) RemoveNamed(of interface{}, name string) error {
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+	if err := c.checkMutable(); err != nil {
+		return err
+	}
+
+	t := pointedType(of)
+	switch {
+	case t == nil:
+		return fmt.Errorf("RemoveNamed requires a pointer to the type of the value, got %T", of)
+	case name == "":
+		return errors.New("RemoveNamed requires a name: unnamed values cannot be removed")
+	}
+
+	k := key{name: name, t: t}
+	if _, ok := c.providers[k]; !ok {
+		return fmt.Errorf("cannot remove %v: it was not provided to this container", k)
+	}
+	c.remove([]key{k})
+	return nil
+}
+
+// RemoveAll removes the constructors for all values of the type pointed to by
+// of from the container, whether they are unnamed, named, or members of value
+// groups, along with the values that were already built. It returns the keys
+// that were removed, sorted, so that callers can log them.
+//
+//   removed, err := c.RemoveAll((*Plugin)(nil))
+//
+// As with RemoveNamed, values that were built from the removed values are
+// discarded as well, and only values provided to this container may be
+// removed.
+func (c *Container) RemoveAll(of interface{}) ([]Key, error) {
+	if err := c.checkUsable(); err != nil {
+		return nil, err
+	}
+	if err := c.checkMutable(); err != nil {
+		return nil, err
+	}
+
+	t := pointedType(of)
+	if t == nil {
+		return nil, fmt.Errorf("RemoveAll requires a pointer to the type of the values, got %T", of)
+	}
+
+	var keys []key
+	for k := range c.providers {
+		if k.t == t {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("cannot remove values of type %v: none were provided to this container", t)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+	c.remove(keys)
+
+	removed := make([]Key, len(keys))
+	for i, k := range keys {
+		removed[i] = k.exported()
+	}
+	return removed, nil
+}
+
+// remove removes the constructors for the given keys, which must be provided
+// to this container, and discards the values built from them.
+func (c *Container) remove(keys []key) {
+	c.providersMu.Lock()
+	for _, k := range keys {
+		for _, n := range c.providers[k] {
+			if n.removedKeys == nil {
+				n.removedKeys = make(map[key]struct{})
+			}
+			n.removedKeys[k] = struct{}{}
+		}
+		delete(c.providers, k)
+		delete(c.copyOnInject, k)
+	}
+	c.providersVersion++
+	c.providersMu.Unlock()
+	for _, k := range keys {
+		c.invalidate(k)
+	}
+
+	// Prune constructors that don't provide anything anymore.
+	c.nodesMu.Lock()
+	defer c.nodesMu.Unlock()
+	remaining := c.nodes[:0]
+	for _, n := range c.nodes {
+		if !n.providesNothing() {
+			remaining = append(remaining, n)
+		}
+	}
+	for i := len(remaining); i < len(c.nodes); i++ {
+		c.nodes[i] = nil
+	}
+	c.nodes = remaining
+}
+
+// invalidate discards the value for the given key and all values of this
+// container that were built from it.
+func (c *Container) invalidate(removed key) {
+	c.valuesMu.Lock()
+	delete(c.values, removed)
+	delete(c.valueErrors, removed)
+	c.valuesMu.Unlock()
+	c.rebuildGroups(c.invalidateDependents(removed))
+}
+
+// invalidateDependents discards the values of this container that were
+// built, directly or not, from the value with the given key. It returns the
+// keys of the discarded values, including the given key, and records them so
+// that scopes of this container discard the values they built from them.
+func (c *Container) invalidateDependents(k key) map[key]struct{} {
+	invalid := map[key]struct{}{k: {}}
+	queue := []key{k}
+	for len(queue) > 0 {
+		k := queue[0]
+		queue = queue[1:]
+
+		for _, n := range c.nodes {
+			if !n.called || !n.dependsOn(k) {
+				continue
+			}
+
+			// Call the constructor again next time.
+			n.called = false
+			n.groupValues = nil
+			for _, r := range n.resultList.DotResult() {
+				rk := key{name: r.Name, group: r.Group, t: r.Type}
+				if _, ok := invalid[rk]; !ok {
+					invalid[rk] = struct{}{}
+					queue = append(queue, rk)
+					c.valuesMu.Lock()
+					delete(c.values, rk)
+					delete(c.valueErrors, rk)
+					c.valuesMu.Unlock()
+				}
+			}
+		}
+	}
+
+	c.providersMu.Lock()
+	for k := range invalid {
+		c.invalidated = append(c.invalidated, k)
+	}
+	if len(c.invalidated) > 2*_maxInvalidated {
+		// Scopes that didn't catch up with the dropped keys discard all
+		// values they built from those of their parents.
+		drop := len(c.invalidated) - _maxInvalidated
+		c.invalidated = append([]key(nil), c.invalidated[drop:]...)
+		c.invalidatedBase += drop
+	}
+	c.providersMu.Unlock()
+	return invalid
+}
+
+// rebuildGroups rebuilds the given value groups, which lost values, from the
+// constructors that are still called. Keys of other values are ignored.
+func (c *Container) rebuildGroups(keys map[key]struct{}) {
+	for k := range keys {
+		if k.group == "" {
+			continue
+		}
+		var items []groupValue
+		for _, n := range c.nodes {
+			if _, ok := n.removedKeys[k]; ok {
+				continue
+			}
+			for _, v := range n.GroupValues(k.group, k.t) {
+				items = append(items, groupValue{Value: v, Provider: n})
+			}
+		}
+		c.valuesMu.Lock()
+		c.groups[k] = items
+		c.valuesMu.Unlock()
+	}
+}
+
+// discardRemovedValues discards the values of this container and its parents
+// that were built from values removed from, or discarded by, one of their
+// parents since they were last invoked. Parents don't keep track of their
+// scopes, so the scopes catch up with the removals instead.
+func (c *Container) discardRemovedValues() {
+	chain := c.scopeChain()
+	for i, s := range chain[1:] {
+		fresh := len(s.replayed) == 0
+		if fresh {
+			s.replayed = make([]int, i+1)
+		}
+		for j, p := range chain[:i+1] {
+			p.providersMu.RLock()
+			start := s.replayed[j] - p.invalidatedBase
+			var keys []key
+			if start >= 0 {
+				keys = p.invalidated[start:]
+			}
+			s.replayed[j] = p.invalidatedBase + len(p.invalidated)
+			p.providersMu.RUnlock()
+
+			switch {
+			case fresh:
+				// Scopes that weren't invoked yet built no values.
+				continue
+			case start < 0:
+				s.discardInheritedValues()
+				continue
+			}
+			for _, k := range keys {
+				// The values of the scope itself were provided to it, so
+				// only those built from the parent's values are stale.
+				invalid := s.invalidateDependents(k)
+				delete(invalid, k)
+				s.rebuildGroups(invalid)
+			}
+		}
+	}
+}
+
+// discardInheritedValues discards the values of this container that were
+// built, directly or not, from values of its parents.
+func (c *Container) discardInheritedValues() {
+	c.nodesMu.RLock()
+	nodes := append([]*node(nil), c.nodes...)
+	c.nodesMu.RUnlock()
+
+	for _, n := range nodes {
+		for _, p := range n.paramList.DotParam() {
+			k := key{name: p.Name, group: p.Group, t: p.Type}
+			if p.Group != "" {
+				// Value groups are consumed as slices of the group type,
+				// and may have values from parents either way.
+				k.t = p.Type.Elem()
+			} else {
+				c.providersMu.RLock()
+				own := len(c.providers[k]) > 0
+				c.providersMu.RUnlock()
+				if own {
+					continue
+				}
+			}
+			invalid := c.invalidateDependents(k)
+			delete(invalid, k)
+			c.rebuildGroups(invalid)
+		}
+	}
+}
+
+// dependsOn reports whether this node consumes the value or value group with
+// the given key.
+func (n *node) dependsOn(k key) bool {
+	for _, p := range n.paramList.DotParam() {
+		pk := key{name: p.Name, group: p.Group, t: p.Type}
+		if p.Group != "" {
+			// Value groups are consumed as slices of the group type.
+			pk.t = p.Type.Elem()
+		}
+		if pk == k {
+			return true
+		}
+	}
+	return false
+}
+
+// providesNothing reports whether all values produced by this node were
+// removed with RemoveNamed or RemoveAll.
+func (n *node) providesNothing() bool {
+	if len(n.removedKeys) == 0 {
+		return false
+	}
+	for _, r := range n.resultList.DotResult() {
+		if _, ok := n.removedKeys[key{name: r.Name, group: r.Group, t: r.Type}]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// pointedType returns the type pointed to by the given pointer, or nil if it
+// isn't a pointer.
+func pointedType(of interface{}) reflect.Type {
+	t := reflect.TypeOf(of)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil
+	}
+	return t.Elem()
+}
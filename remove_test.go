@@ -0,0 +1,423 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamesFor(t *testing.T) {
+	type Plugin struct{ id string }
+
+	c := New()
+	assert.Empty(t, c.NamesFor((*Plugin)(nil)))
+
+	require.NoError(t, c.Provide(func() *Plugin { return nil }))
+	require.NoError(t, c.Provide(func() *Plugin { return nil }, Name("b")))
+	require.NoError(t, c.Provide(func() *Plugin { return nil }, Name("a")))
+	require.NoError(t, c.Provide(func() *Plugin { return nil }, Group("plugins")))
+
+	s := c.Scope("child")
+	require.NoError(t, s.Provide(func() *Plugin { return nil }, Name("c")))
+
+	assert.Equal(t, []string{"a", "b"}, c.NamesFor((**Plugin)(nil)))
+	assert.Equal(t, []string{"a", "b", "c"}, s.NamesFor((**Plugin)(nil)))
+	assert.Empty(t, c.NamesFor(Plugin{}), "non-pointers must not match")
+}
+
+func TestRemoveNamed(t *testing.T) {
+	type Plugin struct{ id string }
+
+	t.Run("removes only the named value", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *Plugin { return &Plugin{id: "default"} }))
+		require.NoError(t, c.Provide(func() *Plugin { return &Plugin{id: "a"} }, Name("a")))
+		require.NoError(t, c.Provide(func() *Plugin { return &Plugin{id: "b"} }, Name("b")))
+
+		type in struct {
+			In
+
+			A *Plugin `name:"a"`
+		}
+		require.NoError(t, c.Invoke(func(in) {}))
+
+		require.NoError(t, c.RemoveNamed((**Plugin)(nil), "a"))
+		assert.Equal(t, []string{"b"}, c.NamesFor((**Plugin)(nil)))
+		assert.Len(t, Graph(c).Ctors, 2, "constructor must be pruned")
+
+		err := c.Invoke(func(in) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `type *dig.Plugin[name="a"] is not in the container`)
+
+		type others struct {
+			In
+
+			Default *Plugin
+			B       *Plugin `name:"b"`
+		}
+		require.NoError(t, c.Invoke(func(o others) {
+			assert.Equal(t, "default", o.Default.id)
+			assert.Equal(t, "b", o.B.id)
+		}))
+
+		require.NoError(t, c.Provide(func() *Plugin { return &Plugin{id: "a2"} }, Name("a")),
+			"the name must be available again")
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Equal(t, "a2", i.A.id)
+		}))
+	})
+
+	t.Run("constructors with other results are kept", func(t *testing.T) {
+		type out struct {
+			Out
+
+			A *Plugin `name:"a"`
+			B *Plugin `name:"b"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() out {
+			return out{A: &Plugin{id: "a"}, B: &Plugin{id: "b"}}
+		}))
+		require.NoError(t, c.RemoveNamed((**Plugin)(nil), "a"))
+		assert.Len(t, Graph(c).Ctors, 1)
+
+		type in struct {
+			In
+
+			A *Plugin `name:"a" optional:"true"`
+			B *Plugin `name:"b"`
+		}
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Nil(t, i.A, "removed value must not be submitted")
+			assert.Equal(t, "b", i.B.id)
+		}))
+	})
+
+	t.Run("dependents are invalidated", func(t *testing.T) {
+		type Registry struct{ plugins int }
+		type in struct {
+			In
+
+			A *Plugin `name:"a" optional:"true"`
+		}
+
+		var registryCalls int
+		c := New()
+		require.NoError(t, c.Provide(func() *Plugin { return &Plugin{id: "a"} }, Name("a")))
+		require.NoError(t, c.Provide(func(i in) *Registry {
+			registryCalls++
+			if i.A == nil {
+				return &Registry{}
+			}
+			return &Registry{plugins: 1}
+		}))
+		require.NoError(t, c.Provide(func(r *Registry) string { return "handler" }, Group("handlers")))
+
+		type handlers struct {
+			In
+
+			Handlers []string `group:"handlers"`
+		}
+
+		require.NoError(t, c.Invoke(func(r *Registry, h handlers) {
+			assert.Equal(t, 1, r.plugins)
+			assert.Len(t, h.Handlers, 1)
+		}))
+
+		require.NoError(t, c.RemoveNamed((**Plugin)(nil), "a"))
+		require.NoError(t, c.Invoke(func(r *Registry, h handlers) {
+			assert.Equal(t, 0, r.plugins, "registry must be rebuilt")
+			assert.Len(t, h.Handlers, 1, "group values must not be duplicated")
+		}))
+		assert.Equal(t, 2, registryCalls)
+	})
+
+	t.Run("values of scopes are invalidated", func(t *testing.T) {
+		type Registry struct{ plugins int }
+		type Handler struct{ registry *Registry }
+		type in struct {
+			In
+
+			A *Plugin `name:"a" optional:"true"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() *Plugin { return &Plugin{id: "a"} }, Name("a")))
+		require.NoError(t, c.Provide(func() string { return "unrelated" }))
+
+		child := c.Scope("child")
+		require.NoError(t, child.Provide(func(i in) *Registry {
+			if i.A == nil {
+				return &Registry{}
+			}
+			return &Registry{plugins: 1}
+		}))
+		var handlerCalls, countCalls int
+		require.NoError(t, child.Provide(func(s string) int {
+			countCalls++
+			return len(s)
+		}))
+
+		grandchild := child.Scope("grandchild")
+		require.NoError(t, grandchild.Provide(func(r *Registry) *Handler {
+			handlerCalls++
+			return &Handler{registry: r}
+		}))
+
+		require.NoError(t, grandchild.Invoke(func(h *Handler, _ int) {
+			assert.Equal(t, 1, h.registry.plugins)
+		}))
+
+		require.NoError(t, c.RemoveNamed((**Plugin)(nil), "a"))
+		require.NoError(t, grandchild.Invoke(func(h *Handler, _ int) {
+			assert.Equal(t, 0, h.registry.plugins, "values of all scopes must be rebuilt")
+		}))
+		require.NoError(t, child.Invoke(func(r *Registry) {
+			assert.Equal(t, 0, r.plugins)
+		}))
+		assert.Equal(t, 2, handlerCalls)
+		assert.Equal(t, 1, countCalls, "unrelated values must be kept")
+	})
+
+	t.Run("scopes that fell behind", func(t *testing.T) {
+		type Registry struct{ plugins int }
+		type in struct {
+			In
+
+			A *Plugin `name:"a" optional:"true"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() *Plugin { return &Plugin{id: "a"} }, Name("a")))
+
+		child := c.Scope("child")
+		require.NoError(t, child.Provide(func(i in) *Registry {
+			if i.A == nil {
+				return &Registry{}
+			}
+			return &Registry{plugins: 1}
+		}))
+		require.NoError(t, child.Invoke(func(r *Registry) {
+			assert.Equal(t, 1, r.plugins)
+		}))
+
+		require.NoError(t, c.RemoveNamed((**Plugin)(nil), "a"))
+		for i := 0; i < 3*_maxInvalidated; i++ {
+			require.NoError(t, c.Provide(func() *Plugin { return &Plugin{id: "b"} }, Name("b")))
+			require.NoError(t, c.RemoveNamed((**Plugin)(nil), "b"))
+		}
+		assert.True(t, len(c.invalidated) <= 2*_maxInvalidated,
+			"removals must not be kept forever, got %d", len(c.invalidated))
+
+		require.NoError(t, child.Invoke(func(r *Registry) {
+			assert.Equal(t, 0, r.plugins, "values of the scope must be rebuilt")
+		}))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *Plugin { return nil }))
+
+		err := c.RemoveNamed(Plugin{}, "a")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "RemoveNamed requires a pointer to the type of the value, got dig.Plugin")
+
+		err = c.RemoveNamed((**Plugin)(nil), "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unnamed values cannot be removed")
+
+		err = c.RemoveNamed((**Plugin)(nil), "a")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `cannot remove *dig.Plugin[name="a"]: it was not provided to this container`)
+	})
+}
+
+func TestRemoveAll(t *testing.T) {
+	type Plugin struct{ id string }
+	type Registry struct{ plugins []string }
+
+	type plugins struct {
+		In
+
+		Default *Plugin   `optional:"true"`
+		A       *Plugin   `name:"a" optional:"true"`
+		Group   []*Plugin `group:"plugins"`
+	}
+
+	newRegistry := func(p plugins) *Registry {
+		var r Registry
+		for _, p := range append([]*Plugin{p.Default, p.A}, p.Group...) {
+			if p != nil {
+				r.plugins = append(r.plugins, p.id)
+			}
+		}
+		return &r
+	}
+
+	t.Run("removes every variant", func(t *testing.T) {
+		type out struct {
+			Out
+
+			B       *Plugin `name:"b"`
+			Version string  `name:"version"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() *Plugin { return &Plugin{id: "default"} }))
+		require.NoError(t, c.Provide(func() *Plugin { return &Plugin{id: "a"} }, Name("a")))
+		require.NoError(t, c.Provide(func() *Plugin { return &Plugin{id: "g"} }, Group("plugins")))
+		require.NoError(t, c.Provide(func() out { return out{B: &Plugin{id: "b"}, Version: "1"} }))
+		require.NoError(t, c.Provide(func() string { return "unrelated" }))
+		require.NoError(t, c.Provide(newRegistry))
+
+		require.NoError(t, c.Invoke(func(r *Registry) {
+			assert.Equal(t, []string{"default", "a", "g"}, r.plugins)
+		}))
+
+		removed, err := c.RemoveAll((**Plugin)(nil))
+		require.NoError(t, err)
+		assert.Equal(t, []Key{
+			{Type: reflect.TypeOf(&Plugin{})},
+			{Type: reflect.TypeOf(&Plugin{}), Group: "plugins"},
+			{Type: reflect.TypeOf(&Plugin{}), Name: "a"},
+			{Type: reflect.TypeOf(&Plugin{}), Name: "b"},
+		}, removed)
+		assert.Empty(t, c.NamesFor((**Plugin)(nil)))
+		assert.Len(t, Graph(c).Ctors, 3, "constructors with other results must be kept")
+
+		require.NoError(t, c.Invoke(func(r *Registry) {
+			assert.Empty(t, r.plugins, "registry must be rebuilt")
+		}))
+
+		type version struct {
+			In
+
+			Version string `name:"version"`
+		}
+		require.NoError(t, c.Invoke(func(v version, s string) {
+			assert.Equal(t, "1", v.Version)
+			assert.Equal(t, "unrelated", s)
+		}))
+
+		err = c.Invoke(func(*Plugin) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "type *dig.Plugin is not in the container")
+	})
+
+	t.Run("group values of kept constructors", func(t *testing.T) {
+		type out struct {
+			Out
+
+			Plugin *Plugin `group:"plugins"`
+			Name   string  `group:"names"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() out { return out{Plugin: &Plugin{id: "g"}, Name: "g"} }))
+		require.NoError(t, c.Provide(newRegistry))
+
+		type names struct {
+			In
+
+			Names []string `group:"names"`
+		}
+		require.NoError(t, c.Invoke(func(r *Registry, n names) {
+			assert.Equal(t, []string{"g"}, r.plugins)
+			assert.Equal(t, []string{"g"}, n.Names)
+		}))
+
+		_, err := c.RemoveAll((**Plugin)(nil))
+		require.NoError(t, err)
+		require.NoError(t, c.Invoke(func(r *Registry, n names) {
+			assert.Empty(t, r.plugins)
+			assert.Equal(t, []string{"g"}, n.Names)
+		}))
+	})
+
+	t.Run("values of scopes are invalidated", func(t *testing.T) {
+		type Registry struct{ plugins int }
+		type Handler struct{ registry *Registry }
+		type in struct {
+			In
+
+			A *Plugin `name:"a" optional:"true"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() *Plugin { return &Plugin{id: "a"} }, Name("a")))
+		require.NoError(t, c.Provide(func() string { return "unrelated" }))
+
+		child := c.Scope("child")
+		require.NoError(t, child.Provide(func(i in) *Registry {
+			if i.A == nil {
+				return &Registry{}
+			}
+			return &Registry{plugins: 1}
+		}))
+		var handlerCalls, countCalls int
+		require.NoError(t, child.Provide(func(s string) int {
+			countCalls++
+			return len(s)
+		}))
+
+		grandchild := child.Scope("grandchild")
+		require.NoError(t, grandchild.Provide(func(r *Registry) *Handler {
+			handlerCalls++
+			return &Handler{registry: r}
+		}))
+
+		require.NoError(t, grandchild.Invoke(func(h *Handler, _ int) {
+			assert.Equal(t, 1, h.registry.plugins)
+		}))
+
+		require.NoError(t, c.RemoveNamed((**Plugin)(nil), "a"))
+		require.NoError(t, grandchild.Invoke(func(h *Handler, _ int) {
+			assert.Equal(t, 0, h.registry.plugins, "values of all scopes must be rebuilt")
+		}))
+		require.NoError(t, child.Invoke(func(r *Registry) {
+			assert.Equal(t, 0, r.plugins)
+		}))
+		assert.Equal(t, 2, handlerCalls)
+		assert.Equal(t, 1, countCalls, "unrelated values must be kept")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *Plugin { return nil }))
+
+		_, err := c.RemoveAll(Plugin{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "RemoveAll requires a pointer to the type of the values, got dig.Plugin")
+
+		_, err = c.RemoveAll((*Plugin)(nil))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot remove values of type dig.Plugin: none were provided to this container")
+
+		s := c.Scope("child")
+		_, err = s.RemoveAll((**Plugin)(nil))
+		require.Error(t, err, "values provided to parents must not be removed")
+	})
+}
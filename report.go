@@ -0,0 +1,248 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// Report describes a failed Invoke for a bug report. See FailureReport.
+type Report struct {
+	// Message of the error, and of its root cause.
+	Error     string `json:"error"`
+	RootCause string `json:"rootCause"`
+
+	// Constructors called by the last Invoke of the container, in the order
+	// in which they were called.
+	Calls []CallRecord `json:"calls"`
+
+	// Values and value groups that were built when the report was created,
+	// and the values that the error reports as missing.
+	Built   []string `json:"built"`
+	Missing []string `json:"missing"`
+
+	// Dependency graph of the container with the failure highlighted, as
+	// returned by Graph and Visualize.
+	Graph *GraphView `json:"graph"`
+	DOT   string     `json:"dot"`
+}
+
+// CallRecord is a constructor call made by Invoke.
+type CallRecord struct {
+	// Name, package, and location at which the constructor was defined.
+	Name    string `json:"name"`
+	Package string `json:"package"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+
+	// Time spent in the constructor.
+	Duration time.Duration `json:"duration"`
+
+	// Message of the error the constructor failed with, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// callLog records the constructor calls made by the last Invoke of a
+// container, including those made by Invokes nested inside constructors.
+// Each scope has its own log so that scopes may be invoked concurrently:
+// constructors of parents called by an Invoke of a scope are recorded in the
+// log of the scope. See invocationStore.
+type callLog struct {
+	calls []CallRecord
+
+	// Number of Invokes that are running.
+	depth int
+
+	// Innermost function being invoked, if provenance is tracked. See
+	// TrackProvenance.
+	invoke *InvokeInfo
+
+	// Audit of the innermost Invoke called with AuditTrail, if any.
+	audit *InvokeAudit
+
+	// Version of the last constructor provided before the innermost Invoke
+	// started building its arguments, if versioned is set. See
+	// getGroupContributors.
+	version   uint64
+	versioned bool
+
+	// Constructors being called by the Invokes.
+	constructing constructionStack
+}
+
+// enter starts recording an Invoke, discarding the calls of the last one
+// unless this Invoke is nested in another.
+func (l *callLog) enter() {
+	if l.depth == 0 {
+		l.calls = l.calls[:0]
+	}
+	l.depth++
+}
+
+func (l *callLog) exit() {
+	l.depth--
+}
+
+// setInvoke marks the given function as being invoked, and returns a
+// function that restores the previous one.
+func (l *callLog) setInvoke(info *InvokeInfo) (restore func()) {
+	prev := l.invoke
+	l.invoke = info
+	return func() { l.invoke = prev }
+}
+
+// setAudit makes Invokes record how they obtain values in the given audit,
+// and returns a function that restores the previous one.
+func (l *callLog) setAudit(a *InvokeAudit) (restore func()) {
+	prev := l.audit
+	l.audit = a
+	return func() { l.audit = prev }
+}
+
+// setGroupVersion makes Invokes consume the value groups of the constructors
+// with the given version or lower, and returns a function that restores the
+// previous version.
+func (l *callLog) setGroupVersion(v uint64) (restore func()) {
+	prev, prevOK := l.version, l.versioned
+	l.version, l.versioned = v, true
+	return func() { l.version, l.versioned = prev, prevOK }
+}
+
+// groupVersion returns the version set with setGroupVersion, if any.
+func (l *callLog) groupVersion() (uint64, bool) {
+	return l.version, l.versioned
+}
+
+func (l *callLog) record(fn *digreflect.Func, d time.Duration, err error) {
+	r := CallRecord{
+		Name:     fn.Name,
+		Package:  fn.Package,
+		File:     fn.File,
+		Line:     fn.Line,
+		Duration: d,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	l.calls = append(l.calls, r)
+}
+
+// FailureReport collects the information needed to reproduce an error
+// returned by the last Invoke of the container into a single Report.
+//
+//   if err := c.Invoke(run); err != nil {
+//     if r, rerr := dig.FailureReport(c, err); rerr == nil {
+//       r.WriteTo(os.Stderr)
+//     }
+//   }
+//
+// The report must be created before the container is used again, since the
+// container only remembers the constructors called by its last Invoke. For
+// errors returned by an Invoke of a scope, pass the scope: each scope
+// remembers its own Invokes.
+func FailureReport(c *Container, err error) (*Report, error) {
+	if cerr := c.checkInitialized(); cerr != nil {
+		return nil, cerr
+	}
+	if err == nil {
+		return nil, errors.New("cannot report on a nil error")
+	}
+
+	opts := []VisualizeOption{VisualizeError(err)}
+	var dot bytes.Buffer
+	if verr := renderGraph(&dot, c.snapshotGraph(), opts); verr != nil {
+		return nil, errWrapf(verr, "failed to visualize the graph")
+	}
+
+	r := &Report{
+		Error:     err.Error(),
+		RootCause: RootCause(err).Error(),
+		Calls:     append([]CallRecord(nil), c.calls.calls...),
+		Graph:     Graph(c, opts...),
+		DOT:       dot.String(),
+	}
+	for _, k := range c.KnownKeys() {
+		if k.Built {
+			r.Built = append(r.Built, k.Key.String())
+		}
+	}
+	for _, k := range missingKeys(err) {
+		r.Missing = append(r.Missing, k.String())
+	}
+	return r, nil
+}
+
+// missingKeys returns the keys of all values that the given error reports
+// as missing.
+func missingKeys(err error) []key {
+	var keys []key
+	for err != nil {
+		switch e := err.(type) {
+		case errMissingType:
+			keys = append(keys, e.Key)
+		case MultiError:
+			for _, err := range e.errs {
+				keys = append(keys, missingKeys(err)...)
+			}
+		}
+		c, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = c.cause()
+	}
+	return keys
+}
+
+// WriteTo writes the report to w as a text document.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	b := new(bytes.Buffer)
+	fmt.Fprintf(b, "error: %v\n", r.Error)
+	fmt.Fprintf(b, "root cause: %v\n", r.RootCause)
+
+	fmt.Fprintf(b, "\nconstructors called (%d):\n", len(r.Calls))
+	for _, call := range r.Calls {
+		fmt.Fprintf(b, "\t%q.%v (%v:%v) in %v", call.Package, call.Name, call.File, call.Line, call.Duration)
+		if call.Error != "" {
+			fmt.Fprintf(b, ", failed: %v", call.Error)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(b, "\nvalues built (%d):\n", len(r.Built))
+	for _, k := range r.Built {
+		fmt.Fprintf(b, "\t%v\n", k)
+	}
+
+	fmt.Fprintf(b, "\nvalues missing (%d):\n", len(r.Missing))
+	for _, k := range r.Missing {
+		fmt.Fprintf(b, "\t%v\n", k)
+	}
+
+	fmt.Fprintf(b, "\ngraph:\n%v\n", r.DOT)
+	return b.WriteTo(w)
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureReport(t *testing.T) {
+	type A struct{}
+	type B struct{}
+	type C struct{}
+
+	t.Run("failed constructor", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Provide(func(*A) (*B, error) { return nil, errors.New("great sadness") }))
+
+		err := c.Invoke(func(*B) {})
+		require.Error(t, err)
+
+		r, err := FailureReport(c, err)
+		require.NoError(t, err)
+		assert.Equal(t, "great sadness", r.RootCause)
+		assert.Contains(t, r.Error, "great sadness")
+		assert.Equal(t, []string{"*dig.A"}, r.Built)
+		assert.Empty(t, r.Missing)
+		assert.Contains(t, r.DOT, "digraph")
+		require.NotNil(t, r.Graph)
+
+		require.Len(t, r.Calls, 2)
+		assert.Empty(t, r.Calls[0].Error)
+		assert.Equal(t, "great sadness", r.Calls[1].Error)
+		assert.Equal(t, "go.uber.org/dig", r.Calls[1].Package)
+
+		var buf bytes.Buffer
+		_, err = r.WriteTo(&buf)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "root cause: great sadness")
+		assert.Contains(t, buf.String(), "constructors called (2):")
+		assert.Contains(t, buf.String(), "values built (1):")
+	})
+
+	t.Run("missing types", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func(*A, *B) *C { return &C{} }))
+
+		err := c.Invoke(func(*C) {})
+		require.Error(t, err)
+
+		r, err := FailureReport(c, err)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"*dig.A", "*dig.B"}, r.Missing)
+		assert.Empty(t, r.Calls)
+		assert.Empty(t, r.Built)
+	})
+
+	t.Run("only the last invoke is reported", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Invoke(func(*A) {}))
+		require.Error(t, c.Invoke(func(*B) {}))
+
+		r, err := FailureReport(c, errors.New("great sadness"))
+		require.NoError(t, err)
+		assert.Empty(t, r.Calls)
+	})
+
+	t.Run("scope invokes are recorded", func(t *testing.T) {
+		type B struct{}
+
+		c := New()
+		require.NoError(t, c.Provide(func() *B { return &B{} }))
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func(*B) (*A, error) { return nil, errors.New("great sadness") }))
+
+		invokeErr := s.Invoke(func(*A) {})
+		require.Error(t, invokeErr)
+
+		r, err := FailureReport(s, invokeErr)
+		require.NoError(t, err)
+		require.Len(t, r.Calls, 2, "calls to constructors of parents must be recorded")
+		assert.Empty(t, r.Calls[0].Error)
+		assert.Contains(t, r.Calls[1].Error, "great sadness")
+
+		r, err = FailureReport(c, invokeErr)
+		require.NoError(t, err)
+		assert.Empty(t, r.Calls, "scopes must not share their log with their parents")
+	})
+
+	t.Run("nil error", func(t *testing.T) {
+		_, err := FailureReport(New(), nil)
+		assert.EqualError(t, err, "cannot report on a nil error")
+	})
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// GenericResolver returns a constructor of values of the given type, or false
+// if it doesn't support the type. See ProvideGeneric.
+type GenericResolver func(t reflect.Type) (constructor interface{}, ok bool)
+
+// ProvideGeneric registers a resolver that supplies constructors for types
+// that no constructor provides. This allows providing generic constructors
+// once rather than once for every instantiation.
+//
+//   c.ProvideGeneric(func(t reflect.Type) (interface{}, bool) {
+//     switch t {
+//     case reflect.TypeOf((*Repo[User])(nil)):
+//       return NewRepo[User], true
+//     case reflect.TypeOf((*Repo[Order])(nil)):
+//       return NewRepo[Order], true
+//     }
+//     return nil, false
+//   })
+//
+// When a value without a name is requested and nothing provides its type,
+// the resolvers of the container and its parents are consulted in the order
+// in which they were registered. The constructor returned by the first one
+// that supports the type is provided to the container the resolver was
+// registered with, as if with Provide, before the value is built. It's then
+// cached, visualized, and checked for cycles like any other constructor.
+//
+// The constructor must be a function that returns the requested type as one
+// of its results. Resolvers aren't consulted for named values and value
+// groups.
+func (c *Container) ProvideGeneric(resolver GenericResolver) error {
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+	if err := c.checkMutable(); err != nil {
+		return err
+	}
+	if resolver == nil {
+		return errors.New("cannot register a nil generic resolver")
+	}
+	c.genericResolvers = append(c.genericResolvers, resolver)
+	return nil
+}
+
+// resolveGeneric provides the constructor of t returned by the first generic
+// resolver of this container or its parents that supports t, or the
+// constructor of the ambient value of type t if there is none, and reports
+// whether there was one.
+func (c *Container) resolveGeneric(t reflect.Type) (bool, error) {
+	for s := c; s != nil; s = s.parent {
+		for _, resolve := range s.genericResolvers {
+			ctor, ok := resolve(t)
+			if !ok {
+				continue
+			}
+			if err := checkGenericConstructor(t, ctor); err != nil {
+				return false, err
+			}
+
+			options, err := s.provideOptions(nil)
+			if err != nil {
+				return false, err
+			}
+			if err := s.provide(ctor, options); err != nil {
+				return false, errProvide{
+					Func:   digreflect.InspectFunc(ctor),
+					Reason: err,
+				}
+			}
+			return true, nil
+		}
+	}
+	return c.resolveAmbient(t)
+}
+
+// resolveGenericDependencies provides the constructors of the required
+// dependencies in pl that nothing provides from the generic resolvers, so
+// that they're built like other values.
+func resolveGenericDependencies(c containerStore, pl paramList) error {
+	var err error
+	resolve := func(p param, path paramPath) {
+		ps, ok := p.(paramSingle)
+		if !ok || err != nil || ps.Name == "" && ps.Optional {
+			return
+		}
+		if len(c.getValueProviders(ps.Name, ps.Type)) > 0 || len(c.getImplementations(ps.Name, ps.Type)) > 0 {
+			return
+		}
+		if ps.Name != "" {
+			_, err = c.resolveBridge(ps.Name, ps.Type)
+			return
+		}
+		_, err = c.resolveGeneric(ps.Type)
+	}
+
+	for i, p := range pl.Params {
+		walkParamPaths(p, paramPath{Arg: i + 1}, resolve)
+	}
+	for _, pr := range pl.Populated {
+		walkParamPaths(pr.Object, paramPath{}, resolve)
+	}
+	return err
+}
+
+// checkGenericConstructor returns an error if the constructor returned by a
+// generic resolver for t doesn't produce t.
+func checkGenericConstructor(t reflect.Type, ctor interface{}) error {
+	ctype := reflect.TypeOf(ctor)
+	if ctype == nil || ctype.Kind() != reflect.Func {
+		return fmt.Errorf("generic resolver for %v returned %v (type %v), which is not a function", t, ctor, ctype)
+	}
+	for i := 0; i < ctype.NumOut(); i++ {
+		if ctype.Out(i) == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("generic resolver for %v returned %v (type %v), which does not return %v",
+		t, digreflect.InspectFunc(ctor), ctype, t)
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvideGeneric(t *testing.T) {
+	type DB struct{}
+	type UserRepo struct{ db *DB }
+	type OrderRepo struct{ db *DB }
+
+	var calls int
+	newResolver := func() GenericResolver {
+		return func(t reflect.Type) (interface{}, bool) {
+			calls++
+			switch t {
+			case reflect.TypeOf(&UserRepo{}):
+				return func(db *DB) *UserRepo { return &UserRepo{db: db} }, true
+			case reflect.TypeOf(&OrderRepo{}):
+				return func(db *DB) *OrderRepo { return &OrderRepo{db: db} }, true
+			}
+			return nil, false
+		}
+	}
+
+	newContainer := func(t *testing.T) *Container {
+		calls = 0
+		c := New()
+		require.NoError(t, c.Provide(func() *DB { return &DB{} }))
+		require.NoError(t, c.ProvideGeneric(newResolver()))
+		return c
+	}
+
+	t.Run("resolves missing types", func(t *testing.T) {
+		c := newContainer(t)
+
+		var first *UserRepo
+		require.NoError(t, c.Invoke(func(u *UserRepo, o *OrderRepo, db *DB) {
+			first = u
+			assert.True(t, db == u.db)
+			assert.True(t, db == o.db)
+		}))
+		require.NoError(t, c.Invoke(func(u *UserRepo) {
+			assert.True(t, first == u, "values must be cached")
+		}))
+		assert.Equal(t, 2, calls, "resolvers must be consulted once per type")
+
+		var buf bytes.Buffer
+		require.NoError(t, Visualize(c, &buf))
+		assert.Contains(t, buf.String(), "*dig.UserRepo")
+	})
+
+	t.Run("dependencies of constructors", func(t *testing.T) {
+		type Service struct{ repo *UserRepo }
+
+		c := newContainer(t)
+		require.NoError(t, c.Provide(func(r *UserRepo) *Service { return &Service{repo: r} }))
+		require.NoError(t, c.Invoke(func(s *Service) {
+			assert.NotNil(t, s.repo)
+		}))
+	})
+
+	t.Run("optional dependencies", func(t *testing.T) {
+		c := newContainer(t)
+		require.NoError(t, c.Invoke(func(p struct {
+			In
+
+			Users *UserRepo `optional:"true"`
+			Name  string    `optional:"true"`
+		}) {
+			assert.NotNil(t, p.Users)
+			assert.Empty(t, p.Name)
+		}))
+	})
+
+	t.Run("unsupported types", func(t *testing.T) {
+		c := newContainer(t)
+		err := c.Invoke(func(string) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "type string is not in the container")
+	})
+
+	t.Run("named values", func(t *testing.T) {
+		c := newContainer(t)
+		err := c.Invoke(func(struct {
+			In
+
+			Users *UserRepo `name:"users"`
+		}) {
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `*dig.UserRepo[name="users"] is not in the container`)
+		assert.Zero(t, calls)
+	})
+
+	t.Run("scope", func(t *testing.T) {
+		c := newContainer(t)
+		s := c.Scope("child")
+
+		var fromScope *UserRepo
+		require.NoError(t, s.Invoke(func(u *UserRepo) { fromScope = u }))
+		require.NoError(t, c.Invoke(func(u *UserRepo) {
+			assert.True(t, fromScope == u, "constructors must be provided to the resolver's container")
+		}))
+	})
+
+	t.Run("cycles", func(t *testing.T) {
+		type A struct{}
+		type B struct{}
+
+		c := New()
+		require.NoError(t, c.Provide(func(*B) *A { return &A{} }))
+		require.NoError(t, c.ProvideGeneric(func(t reflect.Type) (interface{}, bool) {
+			return func(*A) *B { return &B{} }, t == reflect.TypeOf(&B{})
+		}))
+
+		err := c.Invoke(func(*A) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "this function introduces a cycle")
+	})
+
+	t.Run("constructor of the wrong type", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.ProvideGeneric(func(t reflect.Type) (interface{}, bool) {
+			return func() *OrderRepo { return nil }, true
+		}))
+
+		err := c.Invoke(func(*UserRepo) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "generic resolver for *dig.UserRepo returned")
+		assert.Contains(t, err.Error(), "which does not return *dig.UserRepo")
+	})
+
+	t.Run("not a function", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.ProvideGeneric(func(t reflect.Type) (interface{}, bool) {
+			return &UserRepo{}, true
+		}))
+
+		err := c.Invoke(func(*UserRepo) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(),
+			"generic resolver for *dig.UserRepo returned &{<nil>} (type *dig.UserRepo), which is not a function")
+	})
+
+	t.Run("nil resolver", func(t *testing.T) {
+		err := New().ProvideGeneric(nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot register a nil generic resolver")
+	})
+}
@@ -24,6 +24,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"go.uber.org/dig/internal/dot"
 )
@@ -39,10 +41,11 @@ import (
 //                 group.
 type result interface {
 	// Extracts the values for this result from the provided value and
-	// stores them into the provided containerWriter.
+	// stores them into the provided containerWriter. This fails if the
+	// containerWriter rejects one of the values.
 	//
 	// This MAY panic if the result does not consume a single value.
-	Extract(containerWriter, reflect.Value)
+	Extract(containerWriter, reflect.Value) error
 
 	// DotResult returns a slice of dot.Result(s).
 	DotResult() []*dot.Result
@@ -60,15 +63,42 @@ type resultOptions struct {
 	//
 	// For Result Objects, name:".." tags on fields override this.
 	Name string
+
+	// Whether Name may apply to more than one result of a constructor. See
+	// NameAllResults.
+	NameAll bool
+
+	// Names of the results of a constructor at specific positions. See
+	// NameResult.
+	Names []resultName
+
+	// If set, the associated result value is submitted to each of these
+	// value groups. This cannot be combined with Name.
+	Groups []string
+
+	// If set, the associated result value is submitted to each of these
+	// value groups as an interface it implements. This cannot be combined
+	// with Name.
+	AsGroups []groupAs
+
+	// If set, this is prepended to the names of all named results. Prefixes
+	// of nested result objects are added to this with nameprefix:".." tags.
+	NamePrefix string
 }
 
 // newResult builds a result from the given type.
 func newResult(t reflect.Type, opts resultOptions) (result, error) {
 	switch {
+	case embedsInAndOut(t):
+		return nil, fmt.Errorf(
+			"cannot provide %v: it embeds both dig.In and dig.Out, expected only dig.Out: "+
+				"remove the dig.In embed to use it as a result object", t)
 	case IsIn(t) || (t.Kind() == reflect.Ptr && IsIn(t.Elem())) || embedsType(t, _inPtrType):
-		return nil, fmt.Errorf("cannot provide parameter objects: %v embeds a dig.In", t)
+		return nil, fmt.Errorf(
+			"cannot provide parameter objects: %v embeds a dig.In, expected a dig.Out: "+
+				"embed dig.Out instead of dig.In to use it as a result object", t)
 	case isError(t):
-		return nil, fmt.Errorf("cannot return an error here, return it from the constructor instead")
+		return nil, checkErrorResult(t)
 	case IsOut(t):
 		return newResultObject(t, opts)
 	case embedsType(t, _outPtrType):
@@ -79,6 +109,23 @@ func newResult(t reflect.Type, opts resultOptions) (result, error) {
 		return nil, fmt.Errorf(
 			"cannot return a pointer to a result object, use a value instead: "+
 				"%v is a pointer to a struct that embeds dig.Out", t)
+	case t == _warningType:
+		// Warnings always go to their own group. See Warning.
+		return resultGrouped{Type: t, Groups: []string{_warningsGroup}}, nil
+	case len(opts.AsGroups) > 0:
+		for _, as := range opts.AsGroups {
+			if err := checkErrorResult(as.Type); err != nil {
+				return nil, err
+			}
+			if !t.Implements(as.Type) {
+				return nil, fmt.Errorf(
+					"cannot provide %v as %v in value group %q: %v does not implement %v",
+					t, as.Type, as.Group, t, as.Type)
+			}
+		}
+		return resultGrouped{Type: t, Groups: opts.Groups, As: opts.AsGroups}, nil
+	case len(opts.Groups) > 0:
+		return resultGrouped{Type: t, Groups: opts.Groups}, nil
 	default:
 		return resultSingle{Type: t, Name: opts.Name}, nil
 	}
@@ -170,28 +217,51 @@ type resultList struct {
 
 func (rl resultList) DotResult() []*dot.Result {
 	var types []*dot.Result
-	for _, result := range rl.Results {
-		types = append(types, result.DotResult()...)
+	for i, result := range rl.Results {
+		for _, r := range result.DotResult() {
+			r.Path = joinDotPath(strconv.Itoa(i), "/", r.Path)
+			types = append(types, r)
+		}
 	}
 	return types
 }
 
+// joinDotPath prepends prefix to the path of a dot.Result.
+func joinDotPath(prefix, sep, path string) string {
+	if path == "" {
+		return prefix
+	}
+	return prefix + sep + path
+}
+
 func newResultList(ctype reflect.Type, opts resultOptions) (resultList, error) {
 	rl := resultList{
 		ctype:         ctype,
 		Results:       make([]result, 0, ctype.NumOut()),
 		resultIndexes: make([]int, ctype.NumOut()),
 	}
+	if err := checkResultNames(ctype, opts); err != nil {
+		return rl, err
+	}
+
+	names := make(map[int]string, len(opts.Names))
+	for _, rn := range opts.Names {
+		names[rn.Index] = rn.Name
+	}
 
 	resultIdx := 0
 	for i := 0; i < ctype.NumOut(); i++ {
 		t := ctype.Out(i)
-		if isError(t) {
+		if isError(t) && i == ctype.NumOut()-1 {
 			rl.resultIndexes[i] = -1
 			continue
 		}
 
-		r, err := newResult(t, opts)
+		ropts := opts
+		if name, ok := names[i]; ok {
+			ropts.Name = name
+		}
+		r, err := newResult(t, ropts)
 		if err != nil {
 			return rl, errWrapf(err, "bad result %d", i+1)
 		}
@@ -204,25 +274,84 @@ func newResultList(ctype reflect.Type, opts resultOptions) (resultList, error) {
 	return rl, nil
 }
 
-func (resultList) Extract(containerWriter, reflect.Value) {
+// resultName is a name given to the result of a constructor at a position
+// with NameResult.
+type resultName struct {
+	Index int
+	Name  string
+}
+
+// checkResultNames verifies that the names in opts can be applied to the
+// results of the constructor ctype.
+func checkResultNames(ctype reflect.Type, opts resultOptions) error {
+	var results []reflect.Type
+	for i := 0; i < ctype.NumOut(); i++ {
+		if t := ctype.Out(i); !isError(t) || i < ctype.NumOut()-1 {
+			results = append(results, t)
+		}
+	}
+
+	if opts.Name != "" && !opts.NameAll && len(results) > 1 {
+		return fmt.Errorf(
+			"cannot apply dig.Name(%q) to the %d results of %v: %v; "+
+				"use dig.NameResult to name one of them, or dig.NameAllResults to name all of them",
+			opts.Name, len(results), ctype, joinTypes(results))
+	}
+
+	for _, rn := range opts.Names {
+		switch {
+		case rn.Index >= ctype.NumOut():
+			return fmt.Errorf("cannot apply dig.NameResult(%d, %q) to %v: it returns %d values",
+				rn.Index, rn.Name, ctype, ctype.NumOut())
+		case isError(ctype.Out(rn.Index)) && rn.Index == ctype.NumOut()-1:
+			return fmt.Errorf("cannot apply dig.NameResult(%d, %q) to %v: errors cannot be named",
+				rn.Index, rn.Name, ctype)
+		}
+	}
+	return nil
+}
+
+// joinTypes lists the given types separated by commas.
+func joinTypes(types []reflect.Type) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func (resultList) Extract(containerWriter, reflect.Value) error {
 	panic("It looks like you have found a bug in dig. " +
 		"Please file an issue at https://github.com/uber-go/dig/issues/ " +
 		"and provide the following message: " +
 		"resultList.Extract() must never be called")
 }
 
-func (rl resultList) ExtractList(cw containerWriter, values []reflect.Value) error {
+// ConstructorError returns the error returned by the constructor among the
+// given values it returned, if any.
+func (rl resultList) ConstructorError(values []reflect.Value) error {
 	for i, v := range values {
-		if resultIdx := rl.resultIndexes[i]; resultIdx >= 0 {
-			rl.Results[resultIdx].Extract(cw, v)
+		if rl.resultIndexes[i] >= 0 {
 			continue
 		}
-
 		if err, _ := v.Interface().(error); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
+// ExtractList stores the values returned by the constructor into the
+// provided containerWriter. The constructor must not have returned an
+// error; see ConstructorError.
+func (rl resultList) ExtractList(cw containerWriter, values []reflect.Value) error {
+	for i, v := range values {
+		if resultIdx := rl.resultIndexes[i]; resultIdx >= 0 {
+			if err := rl.Results[resultIdx].Extract(cw, v); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -233,6 +362,9 @@ func (rl resultList) ExtractList(cw containerWriter, values []reflect.Value) err
 type resultSingle struct {
 	Name string
 	Type reflect.Type
+
+	// Description of the value from the `doc:".."` tag, if any.
+	Doc string
 }
 
 func (rs resultSingle) DotResult() []*dot.Result {
@@ -242,12 +374,13 @@ func (rs resultSingle) DotResult() []*dot.Result {
 				Type: rs.Type,
 				Name: rs.Name,
 			},
+			Doc: rs.Doc,
 		},
 	}
 }
 
-func (rs resultSingle) Extract(cw containerWriter, v reflect.Value) {
-	cw.setValue(rs.Name, rs.Type, v)
+func (rs resultSingle) Extract(cw containerWriter, v reflect.Value) error {
+	return cw.setValue(rs.Name, rs.Type, v)
 }
 
 // resultObject is a dig.Out struct where each field is another result.
@@ -257,6 +390,9 @@ func (rs resultSingle) Extract(cw containerWriter, v reflect.Value) {
 type resultObject struct {
 	Type   reflect.Type
 	Fields []resultObjectField
+
+	// Error fields of the struct that report failures for other fields.
+	ErrorFields []resultErrorField
 }
 
 func (ro resultObject) DotResult() []*dot.Result {
@@ -273,6 +409,10 @@ func newResultObject(t reflect.Type, opts resultOptions) (resultObject, error) {
 		return ro, fmt.Errorf(
 			"cannot specify a name for result objects: %v embeds dig.Out", t)
 	}
+	if len(opts.Groups) > 0 || len(opts.AsGroups) > 0 {
+		return ro, fmt.Errorf(
+			"cannot specify a group for result objects: %v embeds dig.Out", t)
+	}
 
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
@@ -281,6 +421,15 @@ func newResultObject(t reflect.Type, opts resultOptions) (resultObject, error) {
 			continue
 		}
 
+		if f.Tag.Get(_errorForTag) != "" {
+			ref, err := newResultErrorField(i, f)
+			if err != nil {
+				return ro, errWrapf(err, "bad field %q of %v", f.Name, t)
+			}
+			ro.ErrorFields = append(ro.ErrorFields, ref)
+			continue
+		}
+
 		rof, err := newResultObjectField(i, f, opts)
 		if err != nil {
 			return ro, errWrapf(err, "bad field %q of %v", f.Name, t)
@@ -288,13 +437,118 @@ func newResultObject(t reflect.Type, opts resultOptions) (resultObject, error) {
 
 		ro.Fields = append(ro.Fields, rof)
 	}
+
+	if err := ro.resolveErrorFields(); err != nil {
+		return ro, err
+	}
 	return ro, nil
 }
 
-func (ro resultObject) Extract(cw containerWriter, v reflect.Value) {
+// resolveErrorFields verifies that the error fields of this resultObject
+// refer to valid sibling fields and records their indexes.
+func (ro resultObject) resolveErrorFields() error {
+	targets := make(map[string]string, len(ro.ErrorFields))
+	for i, ef := range ro.ErrorFields {
+		if other, ok := targets[ef.Target]; ok {
+			return fmt.Errorf(
+				"bad field %q of %v: field %q already has its errors reported by %q",
+				ef.FieldName, ro.Type, ef.Target, other)
+		}
+		targets[ef.Target] = ef.FieldName
+
+		found := false
+		for _, f := range ro.Fields {
+			if f.FieldName != ef.Target {
+				continue
+			}
+			if _, ok := f.Result.(resultSingle); !ok {
+				return fmt.Errorf(
+					"bad field %q of %v: errorfor:%q must refer to a field that produces a single value",
+					ef.FieldName, ro.Type, ef.Target)
+			}
+			ro.ErrorFields[i].TargetIndex = f.FieldIndex
+			found = true
+			break
+		}
+		if !found {
+			return fmt.Errorf(
+				"bad field %q of %v: errorfor:%q does not refer to a field of %v",
+				ef.FieldName, ro.Type, ef.Target, ro.Type)
+		}
+	}
+	return nil
+}
+
+func (ro resultObject) Extract(cw containerWriter, v reflect.Value) error {
+	var failed map[int]error
+	for _, ef := range ro.ErrorFields {
+		if err, _ := v.Field(ef.FieldIndex).Interface().(error); err != nil {
+			if failed == nil {
+				failed = make(map[int]error)
+			}
+			failed[ef.TargetIndex] = errWrapf(err,
+				"field %v of %v failed", ef.Target, ro.Type)
+		}
+	}
+
 	for _, f := range ro.Fields {
-		f.Result.Extract(cw, v.Field(f.FieldIndex))
+		if err, ok := failed[f.FieldIndex]; ok {
+			// Failed fields are never committed. resolveErrorFields
+			// guarantees that these are single values.
+			rs := f.Result.(resultSingle)
+			cw.setValueError(rs.Name, rs.Type, err)
+			continue
+		}
+		if err := f.Result.Extract(cw, v.Field(f.FieldIndex)); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// resultErrorField is an error field inside a dig.Out struct which reports
+// the failure to build another field of the same struct.
+//
+//   type Tenants struct {
+//     dig.Out
+//
+//     Foo    *Tenant `name:"foo"`
+//     FooErr error   `errorfor:"Foo"`
+//   }
+type resultErrorField struct {
+	// Name of the field in the struct.
+	FieldName string
+
+	// Index of the field in the struct.
+	FieldIndex int
+
+	// Name and index of the field whose failure this field reports.
+	Target      string
+	TargetIndex int
+}
+
+// newResultErrorField(i, f) builds a resultErrorField from the field f at
+// index i.
+func newResultErrorField(idx int, f reflect.StructField) (resultErrorField, error) {
+	ref := resultErrorField{
+		FieldName:  f.Name,
+		FieldIndex: idx,
+		Target:     f.Tag.Get(_errorForTag),
+	}
+
+	switch {
+	case f.PkgPath != "":
+		return ref, fmt.Errorf(
+			"unexported fields not allowed in dig.Out, did you mean to export %q (%v)?", f.Name, f.Type)
+	case f.Type != _errType:
+		return ref, fmt.Errorf(
+			"fields tagged with %v:%q must be of type error, got %v", _errorForTag, ref.Target, f.Type)
+	case f.Tag.Get(_nameTag) != "" || f.Tag.Get(_groupTag) != "":
+		return ref, fmt.Errorf(
+			"fields tagged with %v:%q cannot be named or grouped", _errorForTag, ref.Target)
+	}
+
+	return ref, nil
 }
 
 // resultObjectField is a single field inside a dig.Out struct.
@@ -313,7 +567,11 @@ type resultObjectField struct {
 }
 
 func (rof resultObjectField) DotResult() []*dot.Result {
-	return rof.Result.DotResult()
+	results := rof.Result.DotResult()
+	for _, r := range results {
+		r.Path = joinDotPath(rof.FieldName, ".", r.Path)
+	}
+	return results
 }
 
 // newResultObjectField(i, f, opts) builds a resultObjectField from the field
@@ -330,6 +588,16 @@ func newResultObjectField(idx int, f reflect.StructField, opts resultOptions) (r
 		return rof, fmt.Errorf(
 			"unexported fields not allowed in dig.Out, did you mean to export %q (%v)?", f.Name, f.Type)
 
+	case f.Tag.Get(_prefixTag) != "" && !IsOut(f.Type):
+		return rof, fmt.Errorf(
+			"%v:%q can only be used on fields that are dig.Out structs, got %v",
+			_prefixTag, f.Tag.Get(_prefixTag), f.Type)
+
+	case f.Tag.Get(_indexTag) != "" && f.Tag.Get(_groupTag) == "":
+		return rof, fmt.Errorf(
+			"%v:%q can only be used on fields of value groups: field %q has no %v tag",
+			_indexTag, f.Tag.Get(_indexTag), f.Name, _groupTag)
+
 	case f.Tag.Get(_groupTag) != "":
 		var err error
 		r, err = newResultGrouped(f)
@@ -339,60 +607,135 @@ func newResultObjectField(idx int, f reflect.StructField, opts resultOptions) (r
 
 	default:
 		var err error
+		// can modify in-place because options are passed-by-value.
 		if name := f.Tag.Get(_nameTag); len(name) > 0 {
-			// can modify in-place because options are passed-by-value.
-			opts.Name = name
+			opts.Name = opts.NamePrefix + name
 		}
+		opts.NamePrefix += f.Tag.Get(_prefixTag)
 		r, err = newResult(f.Type, opts)
 		if err != nil {
 			return rof, err
 		}
 	}
 
+	if doc := f.Tag.Get(_docTag); doc != "" {
+		rs, ok := r.(resultSingle)
+		if !ok {
+			return rof, fmt.Errorf(
+				"%v:%q can only be used on fields that produce a single value: field %q is %v",
+				_docTag, doc, f.Name, f.Type)
+		}
+		rs.Doc = doc
+		r = rs
+	}
+
 	rof.Result = r
 	return rof, nil
 }
 
-// resultGrouped is a value produced by a constructor that is part of a result
-// group.
+// resultGrouped is a value produced by a constructor that is part of one or
+// more result groups.
 //
-// These will be produced as fields of a dig.Out struct.
+// These will be produced as fields of a dig.Out struct, or by constructors
+// provided with the dig.Group option.
 type resultGrouped struct {
-	// Name of the group as specified in the `group:".."` tag.
-	Group string
+	// Names of the groups as specified in the `group:".."` tag or with
+	// dig.Group options. The same value is submitted to each group.
+	Groups []string
 
 	// Type of value produced.
 	Type reflect.Type
+
+	// Value groups to which the value is submitted as one of the interfaces
+	// it implements. These are specified with dig.AsGroup options.
+	As []groupAs
+
+	// Whether the value has a position in the first of Groups, specified
+	// with the `index:".."` tag. See indexedGroup.
+	Indexed bool
+	Index   int
+}
+
+// groupAs is a value group of an interface type. See AsGroup.
+type groupAs struct {
+	Group string
+	Type  reflect.Type // interface implemented by the value
 }
 
 func (rt resultGrouped) DotResult() []*dot.Result {
-	return []*dot.Result{
-		{
+	results := make([]*dot.Result, 0, len(rt.Groups)+len(rt.As))
+	for i, g := range rt.Groups {
+		results = append(results, &dot.Result{
 			Node: &dot.Node{
 				Type:  rt.Type,
-				Group: rt.Group,
+				Group: g,
 			},
-		},
+			Indexed: rt.Indexed && i == 0,
+			Index:   rt.Index,
+		})
+	}
+	for _, as := range rt.As {
+		results = append(results, &dot.Result{
+			Node: &dot.Node{
+				Type:  as.Type,
+				Group: as.Group,
+			},
+		})
 	}
+	return results
 }
 
 // newResultGrouped(f) builds a new resultGrouped from the provided field.
 func newResultGrouped(f reflect.StructField) (resultGrouped, error) {
-	rg := resultGrouped{Group: f.Tag.Get(_groupTag), Type: f.Type}
+	group := f.Tag.Get(_groupTag)
+	rg := resultGrouped{Groups: []string{group}, Type: f.Type}
 
 	name := f.Tag.Get(_nameTag)
 	optional, _ := isFieldOptional(f)
 	switch {
 	case name != "":
 		return rg, fmt.Errorf(
-			"cannot use named values with value groups: name:%q provided with group:%q", name, rg.Group)
+			"cannot use named values with value groups: name:%q provided with group:%q", name, group)
 	case optional:
 		return rg, errors.New("value groups cannot be optional")
 	}
 
+	if err := checkErrorResult(f.Type); err != nil {
+		return rg, err
+	}
+	if err := checkWarningsGroup(group, f.Type); err != nil {
+		return rg, err
+	}
+	if f.Type == _warningType && group != _warningsGroup {
+		rg.Groups = append(rg.Groups, _warningsGroup)
+	}
+
+	if tag := f.Tag.Get(_indexTag); tag != "" {
+		i, err := strconv.Atoi(tag)
+		if err != nil || i < 0 {
+			return rg, fmt.Errorf(
+				"invalid value %q for %q tag: indexes must be non-negative integers", tag, _indexTag)
+		}
+		if group == _warningsGroup {
+			return rg, fmt.Errorf("cannot use %q tag with the %q value group", _indexTag, _warningsGroup)
+		}
+		rg.Indexed = true
+		rg.Index = i
+	}
+
 	return rg, nil
 }
 
-func (rt resultGrouped) Extract(cw containerWriter, v reflect.Value) {
-	cw.submitGroupedValue(rt.Group, rt.Type, v)
+func (rt resultGrouped) Extract(cw containerWriter, v reflect.Value) error {
+	for _, g := range rt.Groups {
+		if err := cw.submitGroupedValue(g, rt.Type, v); err != nil {
+			return err
+		}
+	}
+	for _, as := range rt.As {
+		if err := cw.submitGroupedValue(as.Group, as.Type, v.Convert(as.Type)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -34,10 +34,12 @@ func TestNewResultListErrors(t *testing.T) {
 	tests := []struct {
 		desc string
 		give interface{}
+		err  string
 	}{
 		{
 			desc: "returns dig.In",
 			give: func() struct{ In } { panic("invalid") },
+			err:  "cannot provide parameter objects: struct { dig.In } embeds a dig.In, expected a dig.Out",
 		},
 		{
 			desc: "returns dig.Out+dig.In",
@@ -47,6 +49,7 @@ func TestNewResultListErrors(t *testing.T) {
 			} {
 				panic("invalid")
 			},
+			err: "it embeds both dig.In and dig.Out, expected only dig.Out",
 		},
 	}
 
@@ -56,8 +59,7 @@ func TestNewResultListErrors(t *testing.T) {
 			require.Error(t, err)
 			assertErrorMatches(t, err,
 				"bad result 1:",
-				"cannot provide parameter objects:",
-				"embeds a dig.In")
+				tt.err)
 		})
 	}
 }
@@ -72,6 +74,52 @@ func TestResultListExtractFails(t *testing.T) {
 	})
 }
 
+// failingContainerWriter is a containerWriter that rejects all values.
+type failingContainerWriter struct{ err error }
+
+var _ containerWriter = failingContainerWriter{}
+
+func (w failingContainerWriter) setValue(string, reflect.Type, reflect.Value) error {
+	return w.err
+}
+
+func (w failingContainerWriter) submitGroupedValue(string, reflect.Type, reflect.Value) error {
+	return w.err
+}
+
+func (failingContainerWriter) setValueError(string, reflect.Type, error) {}
+
+func TestResultExtractStoreErrors(t *testing.T) {
+	type out struct {
+		Out
+
+		Writer io.Writer `name:"w"`
+	}
+
+	tests := []struct {
+		desc string
+		give interface{}
+		opts resultOptions
+	}{
+		{desc: "single", give: func() io.Writer { return nil }},
+		{desc: "object", give: func() out { return out{} }},
+		{desc: "grouped", give: func() io.Writer { return nil }, opts: resultOptions{Groups: []string{"g"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ft := reflect.TypeOf(tt.give)
+			rl, err := newResultList(ft, tt.opts)
+			require.NoError(t, err)
+
+			results := reflect.ValueOf(tt.give).Call(nil)
+			err = rl.ExtractList(failingContainerWriter{err: fmt.Errorf("great sadness")}, results)
+			require.Error(t, err)
+			assert.Equal(t, "great sadness", err.Error())
+		})
+	}
+}
+
 func TestNewResultErrors(t *testing.T) {
 	type outPtr struct{ *Out }
 	type out struct{ Out }
@@ -99,7 +147,7 @@ func TestNewResultErrors(t *testing.T) {
 		},
 		{
 			give: inOut{},
-			err:  "cannot provide parameter objects: dig.inOut embeds a dig.In",
+			err:  "cannot provide dig.inOut: it embeds both dig.In and dig.Out, expected only dig.Out",
 		},
 	}
 
@@ -178,7 +226,23 @@ func TestNewResultObject(t *testing.T) {
 				{
 					FieldName:  "Writer",
 					FieldIndex: 1,
-					Result:     resultGrouped{Group: "writers", Type: typeOfWriter},
+					Result:     resultGrouped{Groups: []string{"writers"}, Type: typeOfWriter},
+				},
+			},
+		},
+		{
+			desc: "errorfor tag",
+			give: struct {
+				Out
+
+				Writer io.Writer
+				Err    error `errorfor:"Writer"`
+			}{},
+			wantFields: []resultObjectField{
+				{
+					FieldName:  "Writer",
+					FieldIndex: 1,
+					Result:     resultSingle{Type: typeOfWriter},
 				},
 			},
 		},
@@ -279,6 +343,86 @@ func TestNewResultObjectErrors(t *testing.T) {
 			opts: resultOptions{Name: "foo"},
 			err:  `cannot specify a name for result objects`,
 		},
+		{
+			desc: "error field without errorfor",
+			give: struct {
+				Out
+
+				Reader io.Reader
+				Err    error
+			}{},
+			err: "cannot return an error here",
+		},
+		{
+			desc: "errorfor on non-error field",
+			give: struct {
+				Out
+
+				Reader io.Reader
+				Err    string `errorfor:"Reader"`
+			}{},
+			err: `fields tagged with errorfor:"Reader" must be of type error, got string`,
+		},
+		{
+			desc: "errorfor with name",
+			give: struct {
+				Out
+
+				Reader io.Reader
+				Err    error `errorfor:"Reader" name:"foo"`
+			}{},
+			err: `fields tagged with errorfor:"Reader" cannot be named or grouped`,
+		},
+		{
+			desc: "errorfor unknown field",
+			give: struct {
+				Out
+
+				Reader io.Reader
+				Err    error `errorfor:"Writer"`
+			}{},
+			err: `bad field "Err" of struct { dig.Out; Reader io.Reader; Err error "errorfor:\"Writer\"" }: ` +
+				`errorfor:"Writer" does not refer to a field`,
+		},
+		{
+			desc: "errorfor grouped field",
+			give: struct {
+				Out
+
+				Writer io.Writer `group:"writers"`
+				Err    error     `errorfor:"Writer"`
+			}{},
+			err: `errorfor:"Writer" must refer to a field that produces a single value`,
+		},
+		{
+			desc: "errorfor same field twice",
+			give: struct {
+				Out
+
+				Reader io.Reader
+				Err1   error `errorfor:"Reader"`
+				Err2   error `errorfor:"Reader"`
+			}{},
+			err: `bad field "Err2"`,
+		},
+		{
+			desc: "nameprefix on a non-result object",
+			give: struct {
+				Out
+
+				Reader io.Reader `nameprefix:"foo."`
+			}{},
+			err: `nameprefix:"foo." can only be used on fields that are dig.Out structs, got io.Reader`,
+		},
+		{
+			desc: "doc on a value group",
+			give: struct {
+				Out
+
+				Reader io.Reader `group:"readers" doc:"input"`
+			}{},
+			err: `doc:"input" can only be used on fields that produce a single value: field "Reader" is io.Reader`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,219 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import "fmt"
+
+// Scope creates a child scope of the container with the given name.
+//
+// Scopes see all values and constructors of their parents, but constructors
+// provided to a scope are not visible to its parent or sibling scopes. Values
+// built by constructors provided to a scope are cached in that scope, while
+// constructors provided to a parent are always called against the parent,
+// so they are built at most once for all scopes.
+//
+//   func handle(w http.ResponseWriter, r *http.Request) {
+//     s := c.Scope("request")
+//     s.Provide(dig.Value(r))
+//     s.Invoke(serve)
+//   }
+//
+// A scope cannot provide values for types already provided to one of its
+// parents. If a parent starts providing a type after the scope did, values of
+// that type fail to build in the scope with an AmbiguousProviderError.
+//
+// Scopes inherit the options of their parents, except for the source of
+// randomness. Metrics are shared with the root container.
+//
+// Containers are not safe for concurrent use, but different scopes of a
+// container may be invoked from multiple goroutines at once, also while
+// constructors are provided to their parents. The constructors of the
+// parents are called by one of those Invokes at a time, so a constructor of
+// a parent must not wait for an Invoke of another scope. The parents
+// themselves must not be invoked meanwhile.
+func (c *Container) Scope(name string) *Container {
+	if c == nil {
+		// Methods of the nil scope report the problem.
+		return nil
+	}
+
+	s := New()
+	s.parent = c
+	s.name = name
+	s.deferAcyclicVerification = c.deferAcyclicVerification
+	s.lastProviderWins = c.lastProviderWins
+	s.resolveByImplementation = c.resolveByImplementation
+	s.allowAssignableTypes = c.allowAssignableTypes
+	s.noGroupShuffle = c.noGroupShuffle
+	s.distinctNamedValues = c.distinctNamedValues
+	s.duplicateParamsHandler = c.duplicateParamsHandler
+	s.defaultProvideOptions = c.defaultProvideOptions
+	s.metrics = c.metrics
+	s.metricsSink = c.metricsSink
+	s.events = c.events
+	s.versions = c.versions
+	s.invokeParams = c.invokeParams
+	s.parentCalls = c.parentCalls
+	s.hidden = c.hidden
+	s.disallowNestedInvokes = c.disallowNestedInvokes
+	s.globallyUniqueNames = c.globallyUniqueNames
+	s.trace = c.trace
+	s.fallback = c.fallback
+	s.env = c.env
+	s.strictEnv = c.strictEnv
+	s.maxDepth = c.maxDepth
+	s.invokeInterceptors = c.invokeInterceptors
+	s.valueCommittedHooks = c.valueCommittedHooks
+	s.valueInterceptors = c.valueInterceptors
+	s.groupSizeLimit = c.groupSizeLimit
+	s.attachGraphToErrors = c.attachGraphToErrors
+	s.recoverFromPanics = c.recoverFromPanics
+	s.captureArgsOnError = c.captureArgsOnError
+	s.redactArg = c.redactArg
+	s.errorVerbosity = c.errorVerbosity
+	s.freezeOnInvoke = c.freezeOnInvoke
+	s.freezeScopes = c.freezeScopes
+	s.trackProvenance = c.trackProvenance
+	s.ambientValues = c.ambientValues
+	s.startTime = c.startTime
+	return s
+}
+
+// scopeChain returns this container and all its parents, starting with the
+// root container.
+func (c *Container) scopeChain() []*Container {
+	var chain []*Container
+	for s := c; s != nil; s = s.parent {
+		chain = append(chain, s)
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// chainNodes returns the constructors provided to this container and its
+// parents, starting with those of the root container. It may be called while
+// constructors are provided from other goroutines.
+func (c *Container) chainNodes() []*node {
+	var nodes []*node
+	for _, s := range c.scopeChain() {
+		s.nodesMu.RLock()
+		nodes = append(nodes, s.nodes...)
+		s.nodesMu.RUnlock()
+	}
+	return nodes
+}
+
+// checkUsable returns an error if this container may no longer be used.
+// Pooled scopes among this container and its parents drop the constructors
+// they kept from an earlier use that weren't provided again.
+func (c *Container) checkUsable() error {
+	if err := c.checkReleased(); err != nil {
+		return err
+	}
+	return c.dropUnprovided()
+}
+
+// checkReleased returns an error if this container wasn't created with New
+// or was returned to its pool.
+func (c *Container) checkReleased() error {
+	if err := c.checkInitialized(); err != nil {
+		return err
+	}
+	if c.released {
+		return fmt.Errorf("cannot use scope %q after it was returned to its pool", c.name)
+	}
+	return nil
+}
+
+// reset discards the values built in this container, along with its
+// constructors unless it can keep them for its next use, while retaining its
+// allocated storage.
+func (c *Container) reset() {
+	c.valuesMu.Lock()
+	for k := range c.values {
+		delete(c.values, k)
+	}
+	for k := range c.groups {
+		delete(c.groups, k)
+	}
+	for k := range c.valueErrors {
+		delete(c.valueErrors, k)
+	}
+	c.valuesMu.Unlock()
+	for i := range c.invokes {
+		c.invokes[i] = invokeRecord{}
+	}
+	c.invokes = c.invokes[:0]
+	for k := range c.invokesSeen {
+		delete(c.invokesSeen, k)
+	}
+	c.onClose = nil
+	for k := range c.provenance {
+		delete(c.provenance, k)
+	}
+	c.frozenAt = nil
+
+	if c.keepsProviders() {
+		for i := range c.kept {
+			c.kept[i].release()
+		}
+		c.reprovided = 0
+		return
+	}
+
+	c.clearProviders()
+	for i := range c.kept {
+		c.kept[i] = keptProvider{}
+	}
+	c.kept = c.kept[:0]
+	c.reprovided = 0
+}
+
+// clearProviders removes all constructors from this container.
+func (c *Container) clearProviders() {
+	c.providersMu.Lock()
+	for k := range c.providers {
+		delete(c.providers, k)
+	}
+	c.providersMu.Unlock()
+	c.nodesMu.Lock()
+	for i := range c.nodes {
+		c.nodes[i] = nil
+	}
+	c.nodes = c.nodes[:0]
+	c.nodesMu.Unlock()
+	c.appliedEntries = c.appliedEntries[:0]
+	c.mounts = c.mounts[:0]
+	for k := range c.copyOnInject {
+		delete(c.copyOnInject, k)
+	}
+	c.genericResolvers = nil
+	c.bridges = nil
+	c.providersMu.Lock()
+	for k := range c.groupTypes {
+		delete(c.groupTypes, k)
+	}
+	c.providersVersion++
+	c.isVerifiedAcyclic = false
+	c.providersMu.Unlock()
+}
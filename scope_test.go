@@ -0,0 +1,148 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScope(t *testing.T) {
+	type request struct{ id int }
+	type session struct{ req *request }
+
+	t.Run("scopes see parent values", func(t *testing.T) {
+		c := New()
+		calls := 0
+		require.NoError(t, c.Provide(func() *bytes.Buffer {
+			calls++
+			return new(bytes.Buffer)
+		}))
+
+		var bufs []*bytes.Buffer
+		for i := 0; i < 2; i++ {
+			s := c.Scope("child")
+			require.NoError(t, s.Invoke(func(b *bytes.Buffer) {
+				bufs = append(bufs, b)
+			}))
+		}
+
+		require.NoError(t, c.Invoke(func(b *bytes.Buffer) {
+			bufs = append(bufs, b)
+		}))
+
+		assert.Equal(t, 1, calls, "parent constructor must be called once")
+		assert.True(t, bufs[0] == bufs[1] && bufs[1] == bufs[2], "all scopes must share the value")
+	})
+
+	t.Run("scope values are isolated", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func(r *request) *session {
+			return &session{req: r}
+		}))
+
+		s1 := c.Scope("s1")
+		require.NoError(t, s1.Provide(Value(&request{id: 1})))
+		s2 := c.Scope("s2")
+		require.NoError(t, s2.Provide(Value(&request{id: 2})))
+
+		require.NoError(t, s1.Invoke(func(r *request) {
+			assert.Equal(t, 1, r.id)
+		}))
+		require.NoError(t, s2.Invoke(func(r *request) {
+			assert.Equal(t, 2, r.id)
+		}))
+		require.Error(t, c.Invoke(func(*request) {}), "parent must not see scope values")
+
+		// The parent constructor for session resolves its dependencies
+		// from the parent.
+		require.Error(t, s1.Invoke(func(*session) {}))
+	})
+
+	t.Run("scope constructors consume parent values", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *request { return &request{id: 42} }))
+
+		s := c.Scope("child")
+		calls := 0
+		require.NoError(t, s.Provide(func(r *request) *session {
+			calls++
+			return &session{req: r}
+		}))
+
+		require.NoError(t, s.Invoke(func(s *session) {
+			assert.Equal(t, 42, s.req.id)
+		}))
+		require.NoError(t, s.Invoke(func(*session) {}))
+		assert.Equal(t, 1, calls, "scope constructor must be called once")
+	})
+
+	t.Run("value groups span scopes", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() int { return 1 }, Group("ints")))
+
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func() int { return 2 }, Group("ints")))
+
+		type in struct {
+			In
+
+			Ints []int `group:"ints"`
+		}
+
+		require.NoError(t, s.Invoke(func(i in) {
+			assert.ElementsMatch(t, []int{1, 2}, i.Ints)
+		}))
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Equal(t, []int{1}, i.Ints)
+		}))
+	})
+
+	t.Run("cannot provide parent types", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *request { return nil }))
+
+		err := c.Scope("child").Provide(func() *request { return nil })
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already provided by")
+	})
+
+	t.Run("graph includes parents", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *request { return nil }))
+
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func(*request) *session { return nil }))
+
+		assert.Len(t, Graph(c).Ctors, 1)
+		assert.False(t, Graph(c).Ctors[0].Inherited)
+
+		ctors := Graph(s).Ctors
+		require.Len(t, ctors, 2)
+		assert.True(t, ctors[0].Inherited)
+		assert.Equal(t, "", ctors[0].Scope)
+		assert.False(t, ctors[1].Inherited)
+		assert.Equal(t, "child", ctors[1].Scope)
+	})
+}
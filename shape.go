@@ -0,0 +1,293 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// _defaultReportMaxParams is the number of parameters above which Report
+// lists a constructor unless ReportMaxParams is used.
+const _defaultReportMaxParams = 8
+
+// ShapeReport summarizes the size and shape of the dependency graph of a
+// container. See Container.Report.
+type ShapeReport struct {
+	// Number of constructors provided from each package, sorted by
+	// package.
+	Packages []PackageShape `json:"packages"`
+
+	// Constructors that consume more values than allowed by
+	// ReportMaxParams, in the order in which they were provided.
+	LargeConstructors []LargeConstructor `json:"largeConstructors"`
+
+	// Values and value groups that are provided but not consumed by any
+	// constructor or invoked function, sorted.
+	Unconsumed []string `json:"unconsumed"`
+
+	// Value groups that only one constructor contributes to, sorted. These
+	// are often better served by a single value.
+	SingleContributorGroups []string `json:"singleContributorGroups"`
+}
+
+// PackageShape describes the constructors provided from a single package.
+type PackageShape struct {
+	Package string `json:"package"`
+
+	// Number of constructors, and the average number of values and value
+	// groups they consume.
+	Providers int     `json:"providers"`
+	AvgParams float64 `json:"avgParams"`
+}
+
+// LargeConstructor is a constructor listed in a ShapeReport because it
+// consumes too many values.
+type LargeConstructor struct {
+	// Name, package, and location at which the constructor was defined.
+	Name    string `json:"name"`
+	Package string `json:"package"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+
+	// Number of values and value groups consumed by the constructor.
+	Params int `json:"params"`
+}
+
+// A ReportOption modifies the behavior of Container.Report.
+type ReportOption interface {
+	applyReportOption(*reportOptions)
+}
+
+type reportOptions struct {
+	maxParams int
+	json      bool
+
+	// Report written by an earlier run, and by how much its problem
+	// counts may grow.
+	baseline io.Reader
+	maxDelta int
+}
+
+type reportOptionFunc func(*reportOptions)
+
+func (f reportOptionFunc) applyReportOption(o *reportOptions) { f(o) }
+
+// ReportMaxParams is a ReportOption that lists the constructors consuming
+// more than n values and value groups. The default is 8.
+func ReportMaxParams(n int) ReportOption {
+	return reportOptionFunc(func(o *reportOptions) {
+		o.maxParams = n
+	})
+}
+
+// ReportJSON is a ReportOption that writes the report as a JSON-encoded
+// ShapeReport instead of text. Such reports may be checked in and compared
+// against with ReportBaseline.
+func ReportJSON() ReportOption {
+	return reportOptionFunc(func(o *reportOptions) {
+		o.json = true
+	})
+}
+
+// ReportBaseline is a ReportOption that compares the report against a
+// report written earlier with ReportJSON, read from r. Report fails if the
+// number of large constructors, unconsumed values, or single contributor
+// groups grew by more than maxDelta since the baseline.
+//
+//   f, err := os.Open("testdata/shape.json")
+//   ...
+//   err = c.Report(os.Stdout, dig.ReportBaseline(f, 0))
+func ReportBaseline(r io.Reader, maxDelta int) ReportOption {
+	return reportOptionFunc(func(o *reportOptions) {
+		o.baseline = r
+		o.maxDelta = maxDelta
+	})
+}
+
+// Report writes a summary of the size and shape of the dependency graph of
+// the container to w, meant to keep constructors in check during code
+// review: the number of constructors provided from each package and the
+// average number of values they consume, the constructors that consume the
+// most values, the values that are provided but never consumed, and the value
+// groups with a single contributor.
+//
+//   if err := c.Report(os.Stdout, dig.ReportMaxParams(10)); err != nil {
+//     ...
+//   }
+//
+// Values consumed by functions passed to Invoke count as consumed. Scopes
+// include the constructors provided to their parents.
+//
+// If a baseline is specified with ReportBaseline, the report is written
+// before the baseline is checked.
+func (c *Container) Report(w io.Writer, opts ...ReportOption) error {
+	if err := c.checkInitialized(); err != nil {
+		return err
+	}
+
+	options := reportOptions{maxParams: _defaultReportMaxParams}
+	for _, o := range opts {
+		o.applyReportOption(&options)
+	}
+
+	r := newShapeReport(c.wiring(), options.maxParams)
+	var err error
+	if options.json {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	} else {
+		err = r.writeText(w, options.maxParams)
+	}
+	if err != nil {
+		return err
+	}
+
+	if options.baseline == nil {
+		return nil
+	}
+	var baseline ShapeReport
+	if err := json.NewDecoder(options.baseline).Decode(&baseline); err != nil {
+		return errWrapf(err, "failed to read baseline report")
+	}
+	return r.checkBaseline(baseline, options.maxDelta)
+}
+
+func newShapeReport(doc wiring, maxParams int) *ShapeReport {
+	l := newWiringLinter(doc)
+	r := ShapeReport{
+		Packages:                []PackageShape{},
+		LargeConstructors:       []LargeConstructor{},
+		Unconsumed:              []string{},
+		SingleContributorGroups: []string{},
+	}
+
+	packages := make(map[string]int) // index into r.Packages
+	consumed := make(map[wiringKey]struct{})
+	for _, ctor := range doc.Provides {
+		deps := l.dependencies(ctor)
+		for _, k := range deps {
+			consumed[k] = struct{}{}
+		}
+
+		i, ok := packages[ctor.Package]
+		if !ok {
+			i = len(r.Packages)
+			packages[ctor.Package] = i
+			r.Packages = append(r.Packages, PackageShape{Package: ctor.Package})
+		}
+		p := &r.Packages[i]
+		p.AvgParams = (p.AvgParams*float64(p.Providers) + float64(len(deps))) / float64(p.Providers+1)
+		p.Providers++
+
+		if len(deps) > maxParams {
+			r.LargeConstructors = append(r.LargeConstructors, LargeConstructor{
+				Name:    ctor.Name,
+				Package: ctor.Package,
+				File:    ctor.File,
+				Line:    ctor.Line,
+				Params:  len(deps),
+			})
+		}
+	}
+	for _, inv := range doc.Invokes {
+		for _, k := range l.dependencies(inv) {
+			consumed[k] = struct{}{}
+		}
+	}
+
+	for k, providers := range l.providers {
+		if k.Group == _warningsGroup {
+			// Warnings are collected with Container.Warnings.
+			continue
+		}
+		if _, ok := consumed[k]; !ok {
+			r.Unconsumed = append(r.Unconsumed, k.String())
+		}
+		if k.Group != "" && len(providers) == 1 {
+			r.SingleContributorGroups = append(r.SingleContributorGroups, k.String())
+		}
+	}
+
+	sort.Slice(r.Packages, func(i, j int) bool { return r.Packages[i].Package < r.Packages[j].Package })
+	sort.Strings(r.Unconsumed)
+	sort.Strings(r.SingleContributorGroups)
+	return &r
+}
+
+func (r *ShapeReport) writeText(w io.Writer, maxParams int) error {
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	printf("providers by package:\n")
+	for _, p := range r.Packages {
+		printf("\t%v: %d providers, %.2f params on average\n", p.Package, p.Providers, p.AvgParams)
+	}
+	if len(r.LargeConstructors) > 0 {
+		printf("constructors with more than %d params:\n", maxParams)
+		for _, lc := range r.LargeConstructors {
+			printf("\t%q.%v (%v:%v): %d params\n", lc.Package, lc.Name, lc.File, lc.Line, lc.Params)
+		}
+	}
+	if len(r.Unconsumed) > 0 {
+		printf("provided but never consumed:\n")
+		for _, k := range r.Unconsumed {
+			printf("\t%v\n", k)
+		}
+	}
+	if len(r.SingleContributorGroups) > 0 {
+		printf("value groups with a single contributor:\n")
+		for _, k := range r.SingleContributorGroups {
+			printf("\t%v\n", k)
+		}
+	}
+	return err
+}
+
+// checkBaseline returns an error if the problems listed in this report grew
+// by more than maxDelta since the given baseline.
+func (r *ShapeReport) checkBaseline(baseline ShapeReport, maxDelta int) error {
+	var errs []error
+	check := func(what string, got, want int) {
+		if got-want > maxDelta {
+			errs = append(errs, fmt.Errorf("%v grew from %d to %d, by more than %d", what, want, got, maxDelta))
+		}
+	}
+	check("large constructors", len(r.LargeConstructors), len(baseline.LargeConstructors))
+	check("values provided but never consumed", len(r.Unconsumed), len(baseline.Unconsumed))
+	check("value groups with a single contributor",
+		len(r.SingleContributorGroups), len(baseline.SingleContributorGroups))
+
+	if len(errs) == 0 {
+		return nil
+	}
+	err := newMultiError(errs)
+	err.header = "the container regressed from the baseline report:"
+	err.prefix = "\n\t"
+	return err
+}
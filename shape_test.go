@@ -0,0 +1,117 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	type A struct{}
+	type B struct{}
+	type C struct{}
+	type Handler struct{}
+
+	newContainer := func(t *testing.T) *Container {
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Provide(func(*A) *B { return &B{} }))
+		require.NoError(t, c.Provide(func(*A, *B) *C { return &C{} }))
+		require.NoError(t, c.Provide(func() Handler { return Handler{} }, Group("handlers")))
+		require.NoError(t, c.Invoke(func(*B) {}))
+		return c
+	}
+
+	t.Run("json", func(t *testing.T) {
+		c := newContainer(t)
+
+		var buf bytes.Buffer
+		require.NoError(t, c.Report(&buf, ReportJSON(), ReportMaxParams(1)))
+
+		var r ShapeReport
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &r))
+		require.Len(t, r.Packages, 1)
+		assert.Equal(t, "go.uber.org/dig", r.Packages[0].Package)
+		assert.Equal(t, 4, r.Packages[0].Providers)
+		assert.Equal(t, 0.75, r.Packages[0].AvgParams)
+
+		require.Len(t, r.LargeConstructors, 1)
+		assert.Equal(t, 2, r.LargeConstructors[0].Params)
+		assert.Contains(t, r.LargeConstructors[0].File, "shape_test.go")
+
+		assert.Equal(t, []string{"*dig.C", `dig.Handler[group="handlers"]`}, r.Unconsumed)
+		assert.Equal(t, []string{`dig.Handler[group="handlers"]`}, r.SingleContributorGroups)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		c := newContainer(t)
+
+		var buf bytes.Buffer
+		require.NoError(t, c.Report(&buf, ReportMaxParams(1)))
+		out := buf.String()
+		assert.Contains(t, out, "go.uber.org/dig: 4 providers, 0.75 params on average")
+		assert.Contains(t, out, "constructors with more than 1 params:")
+		assert.Contains(t, out, "provided but never consumed:\n\t*dig.C\n")
+		assert.Contains(t, out, "value groups with a single contributor:\n\tdig.Handler[group=\"handlers\"]\n")
+	})
+
+	t.Run("default threshold", func(t *testing.T) {
+		c := newContainer(t)
+
+		var buf bytes.Buffer
+		require.NoError(t, c.Report(&buf))
+		assert.NotContains(t, buf.String(), "constructors with more than")
+	})
+
+	t.Run("baseline", func(t *testing.T) {
+		c := newContainer(t)
+
+		var baseline bytes.Buffer
+		require.NoError(t, c.Report(&baseline, ReportJSON()))
+
+		require.NoError(t, c.Provide(func() *Handler { return &Handler{} }))
+		require.NoError(t, c.Provide(func() string { return "" }))
+
+		var buf bytes.Buffer
+		require.NoError(t, c.Report(&buf, ReportBaseline(bytes.NewReader(baseline.Bytes()), 2)),
+			"two new unconsumed values are within the delta")
+
+		err := c.Report(&buf, ReportBaseline(bytes.NewReader(baseline.Bytes()), 1))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the container regressed from the baseline report:")
+		assert.Contains(t, err.Error(), "values provided but never consumed grew from 2 to 4, by more than 1")
+	})
+
+	t.Run("bad baseline", func(t *testing.T) {
+		c := newContainer(t)
+
+		var buf bytes.Buffer
+		err := c.Report(&buf, ReportBaseline(strings.NewReader("{"), 0))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read baseline report")
+	})
+}
@@ -28,6 +28,10 @@ import (
 
 // String representation of the entire Container
 func (c *Container) String() string {
+	if c == nil {
+		return "<nil>"
+	}
+
 	b := &bytes.Buffer{}
 	fmt.Fprintln(b, "nodes: {")
 	for k, vs := range c.providers {
@@ -38,14 +42,16 @@ func (c *Container) String() string {
 	fmt.Fprintln(b, "}")
 
 	fmt.Fprintln(b, "values: {")
+	c.valuesMu.RLock()
 	for k, v := range c.values {
 		fmt.Fprintln(b, "\t", k, "=>", v)
 	}
 	for k, vs := range c.groups {
 		for _, v := range vs {
-			fmt.Fprintln(b, "\t", k, "=>", v)
+			fmt.Fprintln(b, "\t", k, "=>", v.Value)
 		}
 	}
+	c.valuesMu.RUnlock()
 	fmt.Fprintln(b, "}")
 
 	return b.String()
@@ -100,6 +106,15 @@ func (op paramObject) String() string {
 	return strings.Join(fields, " ")
 }
 
+func (pn paramNamedSlice) String() string {
+	// io.Reader[names="a,b"] refers to the io.Readers named 'a' and 'b'
+	names := make([]string, len(pn.Params))
+	for i, ps := range pn.Params {
+		names[i] = ps.Name
+	}
+	return fmt.Sprintf("%v[names=%q]", pn.Type.Elem(), strings.Join(names, ","))
+}
+
 func (pt paramGroupedSlice) String() string {
 	// io.Reader[group="foo"] refers to a group of io.Readers called 'foo'
 	return fmt.Sprintf("%v[group=%q]", pt.Type.Elem(), pt.Group)
@@ -0,0 +1,376 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package dig
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// TraceConstruction is an Option that writes every decision the container
+// and its scopes make while building values to w, as JSON objects, one per
+// line.
+//
+//   c := dig.New(dig.TraceConstruction(traceFile))
+//
+// The trace looks like this:
+//
+//   {"event":"requested","key":"*http.Server",...}
+//   {"event":"provider","key":"*http.Server","name":"NewServer",...}
+//   {"event":"started","name":"NewServer",...}
+//   {"event":"finished","name":"NewServer","keys":["*http.Server"],...}
+//
+// The trace lists the values requested and whether they were cached, the
+// constructor chosen for each value that wasn't, or that none was found,
+// when each constructor started and finished, and the constructors that
+// contributed to each value group that was assembled. Constructors are
+// identified by a fingerprint of their name and signature so that
+// ValidateTrace can compare traces with the constructors of another build.
+//
+// Errors writing to w stop the trace but never fail the container.
+func TraceConstruction(w io.Writer) Option {
+	return optionFunc(func(c *Container) {
+		c.trace = &constructionTracer{enc: json.NewEncoder(w)}
+	})
+}
+
+// traceLine is a line of the trace written by TraceConstruction.
+type traceLine struct {
+	Event       string        `json:"event"`
+	Time        time.Time     `json:"time"`
+	Key         string        `json:"key,omitempty"`
+	Name        string        `json:"name,omitempty"`
+	Package     string        `json:"package,omitempty"`
+	File        string        `json:"file,omitempty"`
+	Line        int           `json:"line,omitempty"`
+	Fingerprint string        `json:"fingerprint,omitempty"`
+	Duration    int64         `json:"durationNanos,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	Keys        []string      `json:"keys,omitempty"`
+	Members     []traceMember `json:"members,omitempty"`
+}
+
+// traceMember is a constructor that contributed values to a value group.
+type traceMember struct {
+	Name        string `json:"name"`
+	Package     string `json:"package"`
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	Fingerprint string `json:"fingerprint"`
+	Count       int    `json:"count"`
+}
+
+// Events of the lines of a trace.
+const (
+	_traceRequested = "requested"
+	_traceCached    = "cached"
+	_traceProvider  = "provider"
+	_traceMissing   = "missing"
+	_traceStarted   = "started"
+	_traceFinished  = "finished"
+	_traceGroup     = "group"
+)
+
+// constructionTracer writes the trace of a container and its scopes. See
+// TraceConstruction. Methods of the nil tracer do nothing.
+type constructionTracer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+
+	// First error writing the trace. Nothing is written after it.
+	err error
+}
+
+func (t *constructionTracer) write(line traceLine) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return
+	}
+	line.Time = time.Now()
+	t.err = t.enc.Encode(line)
+}
+
+func (t *constructionTracer) requested(k key) {
+	if t != nil {
+		t.write(traceLine{Event: _traceRequested, Key: k.String()})
+	}
+}
+
+func (t *constructionTracer) cached(k key) {
+	if t != nil {
+		t.write(traceLine{Event: _traceCached, Key: k.String()})
+	}
+}
+
+func (t *constructionTracer) missing(k key) {
+	if t != nil {
+		t.write(traceLine{Event: _traceMissing, Key: k.String()})
+	}
+}
+
+func (t *constructionTracer) provider(k key, p provider) {
+	if t == nil {
+		return
+	}
+	line := traceLine{Event: _traceProvider, Key: k.String()}
+	line.setFunc(p.Location(), p.ParamList().ctype)
+	t.write(line)
+}
+
+func (t *constructionTracer) started(n *node) {
+	if t == nil {
+		return
+	}
+	line := traceLine{Event: _traceStarted}
+	line.setFunc(n.location, n.ctype)
+	t.write(line)
+}
+
+// finished records that the constructor of the given node returned. sr
+// holds the values it produced, if any.
+func (t *constructionTracer) finished(n *node, d time.Duration, err error, sr *stagingContainerWriter) {
+	if t == nil {
+		return
+	}
+	line := traceLine{Event: _traceFinished, Duration: int64(d)}
+	line.setFunc(n.location, n.ctype)
+	if err != nil {
+		line.Error = err.Error()
+	}
+	if sr != nil {
+		for _, k := range sr.sortedKeys() {
+			// Value groups are traced when they're assembled.
+			if k.group == "" {
+				line.Keys = append(line.Keys, k.String())
+			}
+		}
+	}
+	t.write(line)
+}
+
+// group records that the value group with the given key was assembled from
+// the values of the given constructors.
+func (t *constructionTracer) group(k key, providers []provider) {
+	if t == nil {
+		return
+	}
+	line := traceLine{Event: _traceGroup, Key: k.String()}
+	for _, p := range providers {
+		fn := p.Location()
+		line.Members = append(line.Members, traceMember{
+			Name:        fn.Name,
+			Package:     fn.Package,
+			File:        fn.File,
+			Line:        fn.Line,
+			Fingerprint: fingerprint(fn, p.ParamList().ctype),
+			Count:       len(p.GroupValues(k.group, k.t)),
+		})
+	}
+	t.write(line)
+}
+
+func (l *traceLine) setFunc(fn *digreflect.Func, ctype reflect.Type) {
+	l.Name, l.Package, l.File, l.Line = fn.Name, fn.Package, fn.File, fn.Line
+	l.Fingerprint = fingerprint(fn, ctype)
+}
+
+// fingerprint identifies a constructor by its name and signature, but not
+// by its location, so that it survives unrelated changes to its file.
+func fingerprint(fn *digreflect.Func, ctype reflect.Type) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%q.%v %v", fn.Package, fn.Name, ctype)))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (c *Container) tracer() *constructionTracer {
+	return c.trace
+}
+
+// FindingKind identifies an anomaly found by ValidateTrace.
+type FindingKind int
+
+const (
+	// FindingMalformed is reported for lines that aren't part of a trace
+	// written by TraceConstruction.
+	FindingMalformed FindingKind = iota + 1
+
+	// FindingConstructedTwice is reported when a value was produced by more
+	// than one successful constructor call.
+	FindingConstructedTwice
+
+	// FindingFingerprintMismatch is reported when the trace chose a
+	// constructor that none of the constructors of the container given to
+	// ValidateTraceAgainst matches.
+	FindingFingerprintMismatch
+)
+
+func (k FindingKind) String() string {
+	switch k {
+	case FindingMalformed:
+		return "malformed"
+	case FindingConstructedTwice:
+		return "constructed twice"
+	case FindingFingerprintMismatch:
+		return "fingerprint mismatch"
+	default:
+		return "unknown"
+	}
+}
+
+// Finding is an anomaly in a trace found by ValidateTrace.
+type Finding struct {
+	Kind FindingKind
+
+	// Line of the trace the finding is about, starting at 1.
+	Line int
+
+	// Description of the anomaly.
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d: %v: %v", f.Line, f.Kind, f.Message)
+}
+
+// A ValidateTraceOption modifies the default behavior of ValidateTrace.
+type ValidateTraceOption interface {
+	applyValidateTraceOption(*validateTraceOptions)
+}
+
+type validateTraceOptions struct {
+	// Container whose constructors the trace is compared with.
+	Container *Container
+}
+
+type validateTraceOptionFunc func(*validateTraceOptions)
+
+func (f validateTraceOptionFunc) applyValidateTraceOption(opts *validateTraceOptions) { f(opts) }
+
+// ValidateTraceAgainst is a ValidateTraceOption that compares the
+// constructors chosen in the trace with the constructors provided to the
+// given container and its parents, and reports those that no longer match
+// any of them because they were renamed, removed, or changed signature.
+func ValidateTraceAgainst(c *Container) ValidateTraceOption {
+	return validateTraceOptionFunc(func(opts *validateTraceOptions) {
+		opts.Container = c
+	})
+}
+
+// ValidateTrace reads a trace written by TraceConstruction from r and
+// returns the anomalies it finds in it, in the order of the lines of the
+// trace. These include values that were constructed more than once.
+//
+//   f, _ := os.Open("trace.jsonl")
+//   for _, finding := range dig.ValidateTrace(f, dig.ValidateTraceAgainst(c)) {
+//     log.Print(finding)
+//   }
+//
+// Errors reading from r are reported as a FindingMalformed at the line
+// where reading stopped.
+func ValidateTrace(r io.Reader, opts ...ValidateTraceOption) []Finding {
+	var options validateTraceOptions
+	for _, o := range opts {
+		o.applyValidateTraceOption(&options)
+	}
+
+	var known map[string]struct{}
+	if c := options.Container; c != nil && c.checkInitialized() == nil {
+		known = make(map[string]struct{})
+		for _, n := range c.chainNodes() {
+			known[fingerprint(n.location, n.ctype)] = struct{}{}
+		}
+	}
+
+	var (
+		findings []Finding
+		lineNo   int
+	)
+	constructed := make(map[string]int) // key => line
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		lineNo++
+		var line traceLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			findings = append(findings, Finding{
+				Kind:    FindingMalformed,
+				Line:    lineNo,
+				Message: fmt.Sprintf("cannot read trace line: %v", err),
+			})
+			continue
+		}
+		if line.Event == "" {
+			findings = append(findings, Finding{
+				Kind:    FindingMalformed,
+				Line:    lineNo,
+				Message: "cannot read trace line: it has no event",
+			})
+			continue
+		}
+
+		switch line.Event {
+		case _traceFinished:
+			if line.Error != "" {
+				continue
+			}
+			for _, k := range line.Keys {
+				if prev, ok := constructed[k]; ok {
+					findings = append(findings, Finding{
+						Kind:    FindingConstructedTwice,
+						Line:    lineNo,
+						Message: fmt.Sprintf("%v was constructed again by %q.%v, after line %d", k, line.Package, line.Name, prev),
+					})
+				}
+				constructed[k] = lineNo
+			}
+		case _traceProvider:
+			if known == nil {
+				continue
+			}
+			if _, ok := known[line.Fingerprint]; !ok {
+				findings = append(findings, Finding{
+					Kind:    FindingFingerprintMismatch,
+					Line:    lineNo,
+					Message: fmt.Sprintf("%q.%v (%v:%v) was chosen for %v but matches no constructor of the container", line.Package, line.Name, line.File, line.Line, line.Key),
+				})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		findings = append(findings, Finding{
+			Kind:    FindingMalformed,
+			Line:    lineNo + 1,
+			Message: fmt.Sprintf("cannot read trace: %v", err),
+		})
+	}
+	return findings
+}
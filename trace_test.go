@@ -0,0 +1,206 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package dig
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceConstruction(t *testing.T) {
+	type config struct{}
+	type server struct{}
+	type handler struct{}
+	type serverParams struct {
+		In
+
+		Config   *config
+		Handlers []*handler    `group:"handlers"`
+		Logger   *bytes.Buffer `optional:"true"`
+	}
+
+	readTrace := func(t *testing.T, buf *bytes.Buffer) []traceLine {
+		var lines []traceLine
+		scanner := bufio.NewScanner(buf)
+		for scanner.Scan() {
+			var line traceLine
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			assert.False(t, line.Time.IsZero(), "lines must have a time")
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	events := func(lines []traceLine) []string {
+		var got []string
+		for _, l := range lines {
+			got = append(got, l.Event+" "+l.Key)
+		}
+		return got
+	}
+
+	t.Run("decisions", func(t *testing.T) {
+		var buf bytes.Buffer
+		c := New(TraceConstruction(&buf))
+		require.NoError(t, c.Provide(func() *config { return &config{} }))
+		require.NoError(t, c.Provide(func() *handler { return &handler{} }, Group("handlers")))
+		require.NoError(t, c.Provide(func(serverParams) *server { return &server{} }))
+		require.NoError(t, c.Invoke(func(*server, *config) {}))
+
+		lines := readTrace(t, &buf)
+		assert.Equal(t, []string{
+			"requested *dig.server",
+			"provider *dig.server",
+			"requested *dig.config",
+			"provider *dig.config",
+			"started ",
+			"finished ",
+			"started ",
+			"finished ",
+			"group *dig.handler[group=\"handlers\"]",
+			"requested *bytes.Buffer",
+			"missing *bytes.Buffer",
+			"started ",
+			"finished ",
+			"requested *dig.config",
+			"cached *dig.config",
+		}, events(lines))
+
+		provider := lines[1]
+		assert.Contains(t, provider.Name, "TestTraceConstruction")
+		assert.Equal(t, "go.uber.org/dig", provider.Package)
+		assert.Contains(t, provider.File, "trace_test.go")
+		assert.Len(t, provider.Fingerprint, 16)
+
+		finished := lines[12]
+		assert.Equal(t, []string{"*dig.server"}, finished.Keys)
+		assert.Equal(t, provider.Fingerprint, finished.Fingerprint)
+
+		group := lines[8]
+		require.Len(t, group.Members, 1)
+		assert.Equal(t, 1, group.Members[0].Count)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		var buf bytes.Buffer
+		c := New(TraceConstruction(&buf))
+		require.NoError(t, c.Provide(func() (*config, error) {
+			return nil, errors.New("great sadness")
+		}))
+		require.Error(t, c.Invoke(func(*config) {}))
+
+		lines := readTrace(t, &buf)
+		require.Len(t, lines, 4)
+		assert.Equal(t, "finished", lines[3].Event)
+		assert.Equal(t, "great sadness", lines[3].Error)
+		assert.Empty(t, lines[3].Keys)
+	})
+
+	t.Run("shared with scopes", func(t *testing.T) {
+		var buf bytes.Buffer
+		c := New(TraceConstruction(&buf))
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func() *config { return &config{} }))
+		require.NoError(t, s.Invoke(func(*config) {}))
+		assert.Len(t, readTrace(t, &buf), 4)
+	})
+
+	t.Run("write errors are ignored", func(t *testing.T) {
+		w := &failingWriter{}
+		c := New(TraceConstruction(w))
+		require.NoError(t, c.Provide(func() *config { return &config{} }))
+		require.NoError(t, c.Invoke(func(*config) {}))
+		assert.Equal(t, 1, w.writes, "tracing must stop after the first error")
+	})
+}
+
+type failingWriter struct{ writes int }
+
+func (w *failingWriter) Write([]byte) (int, error) {
+	w.writes++
+	return 0, errors.New("disk full")
+}
+
+func TestValidateTrace(t *testing.T) {
+	type config struct{}
+
+	t.Run("valid trace", func(t *testing.T) {
+		var buf bytes.Buffer
+		c := New(TraceConstruction(&buf))
+		require.NoError(t, c.Provide(func() *config { return &config{} }))
+		require.NoError(t, c.Invoke(func(*config) {}))
+		require.NoError(t, c.Invoke(func(*config) {}))
+
+		assert.Empty(t, ValidateTrace(&buf, ValidateTraceAgainst(c)))
+	})
+
+	t.Run("constructed twice", func(t *testing.T) {
+		var buf bytes.Buffer
+		newConfig := func() *config { return &config{} }
+		c := New(TraceConstruction(&buf))
+		require.NoError(t, c.Provide(newConfig))
+		require.NoError(t, c.Invoke(func(*config) {}))
+		_, err := c.RemoveAll((**config)(nil))
+		require.NoError(t, err)
+		require.NoError(t, c.Provide(newConfig))
+		require.NoError(t, c.Invoke(func(*config) {}))
+
+		findings := ValidateTrace(&buf)
+		require.Len(t, findings, 1)
+		assert.Equal(t, FindingConstructedTwice, findings[0].Kind)
+		assert.Equal(t, 8, findings[0].Line)
+		assert.Regexp(t, `^line 8: constructed twice: \*dig.config was constructed again by `+
+			`"go.uber.org/dig".TestValidateTrace\S+, after line 4$`, findings[0].String())
+	})
+
+	t.Run("fingerprint mismatch", func(t *testing.T) {
+		var buf bytes.Buffer
+		c := New(TraceConstruction(&buf))
+		require.NoError(t, c.Provide(func() *config { return &config{} }))
+		require.NoError(t, c.Invoke(func(*config) {}))
+		trace := buf.String()
+
+		other := New()
+		require.NoError(t, other.Provide(func() (*config, error) { return &config{}, nil }))
+
+		assert.Empty(t, ValidateTrace(strings.NewReader(trace)),
+			"fingerprints must only be checked against a container")
+		findings := ValidateTrace(strings.NewReader(trace), ValidateTraceAgainst(other))
+		require.Len(t, findings, 1)
+		assert.Equal(t, FindingFingerprintMismatch, findings[0].Kind)
+		assert.Equal(t, 2, findings[0].Line)
+		assert.Contains(t, findings[0].Message, "was chosen for *dig.config but matches no constructor of the container")
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		findings := ValidateTrace(strings.NewReader("{\"event\":\"cached\"}\nnot json\n{}\n"))
+		require.Len(t, findings, 2)
+		assert.Equal(t, FindingMalformed, findings[0].Kind)
+		assert.Equal(t, 2, findings[0].Line)
+		assert.Equal(t, "line 3: malformed: cannot read trace line: it has no event", findings[1].String())
+	})
+}
@@ -22,16 +22,19 @@ package dig
 
 import (
 	"container/list"
+	"fmt"
 	"reflect"
 )
 
 var (
-	_noValue    reflect.Value
-	_errType    = reflect.TypeOf((*error)(nil)).Elem()
-	_inPtrType  = reflect.TypeOf((*In)(nil))
-	_inType     = reflect.TypeOf(In{})
-	_outPtrType = reflect.TypeOf((*Out)(nil))
-	_outType    = reflect.TypeOf(Out{})
+	_noValue            reflect.Value
+	_errType            = reflect.TypeOf((*error)(nil)).Elem()
+	_interfaceType      = reflect.TypeOf((*interface{})(nil)).Elem()
+	_inPtrType          = reflect.TypeOf((*In)(nil))
+	_inType             = reflect.TypeOf(In{})
+	_outPtrType         = reflect.TypeOf((*Out)(nil))
+	_outType            = reflect.TypeOf(Out{})
+	_unresolvedKeysType = reflect.TypeOf(UnresolvedKeys(nil))
 )
 
 // Special interface embedded inside dig sentinel values (dig.In, dig.Out) to
@@ -80,10 +83,52 @@ type In struct{ digSentinel }
 //               information.
 type Out struct{ digSentinel }
 
+// UnresolvedKeys may be used as the type of a field of a dig.In struct to
+// learn which optional fields of the struct could not be resolved and hold
+// their zero value instead. Optional fields of nested dig.In structs are
+// included.
+//
+//   type HandlerParams struct {
+//     dig.In
+//
+//     Cache   *Cache  `optional:"true"`
+//     Tracer  *Tracer `optional:"true"`
+//     Missing dig.UnresolvedKeys
+//   }
+//
+// The field is filled in by dig and is not a dependency of the struct. A
+// dig.In struct may have at most one such field.
+type UnresolvedKeys []Key
+
 func isError(t reflect.Type) bool {
 	return t.Implements(_errType)
 }
 
+// Errors may only be returned as the last result of constructors and invoked
+// functions, where they report failure. They cannot be provided to or
+// consumed from the container like other values. The following functions
+// reject errors elsewhere.
+
+// checkErrorParam returns an error if values of type t can't be consumed
+// from the container because t is an error.
+func checkErrorParam(t reflect.Type) error {
+	if !isError(t) {
+		return nil
+	}
+	return fmt.Errorf("cannot depend on %v: errors cannot be consumed from the container, "+
+		"wrap the error in a field of a named struct type instead", t)
+}
+
+// checkErrorResult returns an error if values of type t can't be provided
+// to the container because t is an error.
+func checkErrorResult(t reflect.Type) error {
+	if !isError(t) {
+		return nil
+	}
+	return fmt.Errorf("cannot return an error here, return it from the constructor instead: "+
+		"%v cannot be provided to the container", t)
+}
+
 // IsIn checks whether the given struct is a dig.In struct. A struct qualifies
 // as a dig.In struct if it embeds the dig.In type or if any struct that it
 // embeds is a dig.In struct. The parameter may be the reflect.Type of the
@@ -112,6 +157,67 @@ func IsOut(o interface{}) bool {
 	return embedsType(o, _outType)
 }
 
+// groupIterElem returns the type of the values produced by t if t is an
+// iterator over a value group. Iterators have one of the following shapes.
+//
+//   func(yield func(T) bool)
+//   func(yield func(T) bool) error
+func groupIterElem(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Func || t.IsVariadic() || t.NumIn() != 1 || t.NumOut() > 1 {
+		return nil, false
+	}
+	if t.NumOut() == 1 && t.Out(0) != _errType {
+		return nil, false
+	}
+
+	yield := t.In(0)
+	if yield.Kind() != reflect.Func || yield.IsVariadic() ||
+		yield.NumIn() != 1 || yield.NumOut() != 1 || yield.Out(0).Kind() != reflect.Bool {
+		return nil, false
+	}
+	return yield.In(0), true
+}
+
+// embedsInAndOut reports whether t or its element type embeds dig.In and
+// dig.Out. Params and results are built when constructors are provided and
+// once per type of invoked function, so this only walks the embedded fields
+// once for both.
+func embedsInAndOut(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	var in, out bool
+	types := []reflect.Type{t}
+	for len(types) > 0 {
+		t := types[0]
+		types = types[1:]
+
+		switch t {
+		case _inType, _inPtrType:
+			in = true
+		case _outType, _outPtrType:
+			out = true
+		}
+		if in && out {
+			return true
+		}
+
+		if t.Kind() != reflect.Struct {
+			continue
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.Anonymous {
+				types = append(types, f.Type)
+			}
+		}
+	}
+	return false
+}
+
 // Returns true if t embeds e or if any of the types embedded by t embed e.
 func embedsType(i interface{}, e reflect.Type) bool {
 	// TODO: this function doesn't consider e being a pointer.
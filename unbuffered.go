@@ -0,0 +1,67 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import "reflect"
+
+// AllowUnbuffered is a ProvideOption that marks the unbuffered channels
+// produced by the constructor as intended.
+//
+//   c.Provide(NewShutdownSignal, dig.AllowUnbuffered())
+//
+// A channel provided to the container is shared by all of its consumers, and
+// sending to an unbuffered channel blocks until another consumer receives
+// from it. This is a common source of deadlocks, so LintWiring reports
+// unbuffered channels produced by constructors without this option.
+func AllowUnbuffered() ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.AllowUnbuffered = true
+	})
+}
+
+// isUnbuffered reports whether the value with the given key was recorded as
+// an unbuffered channel by recordUnbuffered.
+func (n *node) isUnbuffered(k key) bool {
+	n.unbufferedMu.Lock()
+	defer n.unbufferedMu.Unlock()
+	_, ok := n.unbuffered[k]
+	return ok
+}
+
+// recordUnbuffered records the keys of the unbuffered channels staged by a
+// call to this node's constructor, unless they're intended. Channels
+// submitted to value groups are not recorded.
+func (n *node) recordUnbuffered(sr *stagingContainerWriter) {
+	if n.allowUnbuffered {
+		return
+	}
+	n.unbufferedMu.Lock()
+	defer n.unbufferedMu.Unlock()
+	for k, v := range sr.values {
+		if v.Kind() != reflect.Chan || v.IsNil() || v.Cap() > 0 {
+			continue
+		}
+		if n.unbuffered == nil {
+			n.unbuffered = make(map[key]struct{})
+		}
+		n.unbuffered[k] = struct{}{}
+	}
+}
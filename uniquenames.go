@@ -0,0 +1,120 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"sort"
+)
+
+// GloballyUniqueNames is an Option that makes names unique across types:
+// Provide fails if a constructor produces a named value under a name that
+// values of another type are already provided under by the container or its
+// parents.
+//
+//   c := dig.New(dig.GloballyUniqueNames())
+//   c.Provide(NewPrimaryDB, dig.Name("primary"))
+//   c.Provide(NewPrimaryCache, dig.Name("primary")) // fails
+//
+// By default, names only need to be unique among the values of one type.
+// Use LookupByName to find the values provided under a name.
+func GloballyUniqueNames() Option {
+	return optionFunc(func(c *Container) {
+		c.globallyUniqueNames = true
+	})
+}
+
+// LookupByName returns the keys of the values provided under the given name
+// to the container or its parents, sorted by type. Unless the container was
+// created with GloballyUniqueNames, values of several types may share a
+// name.
+//
+//   keys := c.LookupByName("primary")
+func (c *Container) LookupByName(name string) []Key {
+	if c.checkInitialized() != nil || name == "" {
+		return nil
+	}
+
+	var keys []key
+	for _, s := range c.scopeChain() {
+		keys = append(keys, s.keysNamed(name)...)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+
+	exported := make([]Key, len(keys))
+	for i, k := range keys {
+		exported[i] = k.exported()
+	}
+	return exported
+}
+
+// keysNamed returns the keys of the values provided under the given name to
+// this container.
+func (c *Container) keysNamed(name string) []key {
+	c.providersMu.RLock()
+	defer c.providersMu.RUnlock()
+
+	var keys []key
+	for k := range c.providers {
+		if k.name == name {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// checkUniqueNames returns an error if the given keys produced by a
+// constructor share a name with each other or with the values of other
+// types provided to this container or its parents. It does nothing unless
+// the container was created with GloballyUniqueNames.
+func (c *Container) checkUniqueNames(keys map[key]struct{}) error {
+	if !c.globallyUniqueNames {
+		return nil
+	}
+
+	named := make(map[string]key)
+	for k := range keys {
+		if k.name == "" {
+			continue
+		}
+		if prev, ok := named[k.name]; ok {
+			if keyLess(k, prev) {
+				k, prev = prev, k
+			}
+			return fmt.Errorf("cannot provide %v and %v: names must be unique across types", prev, k)
+		}
+		named[k.name] = k
+	}
+
+	for _, s := range c.scopeChain() {
+		for name, k := range named {
+			for _, other := range s.keysNamed(name) {
+				if other.t == k.t {
+					continue
+				}
+				ps := s.getProviders(other)
+				return fmt.Errorf("cannot provide %v: name %q is already used by %v, provided by %v",
+					k, name, other, ps[0].Location())
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGloballyUniqueNames(t *testing.T) {
+	type db struct{}
+	type cache struct{}
+	newDB := func() *db { return &db{} }
+	newCache := func() *cache { return &cache{} }
+
+	t.Run("disabled", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newDB, Name("primary")))
+		require.NoError(t, c.Provide(newCache, Name("primary")))
+
+		assert.Equal(t, []Key{
+			{Type: reflect.TypeOf(&cache{}), Name: "primary"},
+			{Type: reflect.TypeOf(&db{}), Name: "primary"},
+		}, c.LookupByName("primary"))
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		c := New(GloballyUniqueNames())
+		require.NoError(t, c.Provide(newDB, Name("primary")))
+		require.NoError(t, c.Provide(newDB, Name("secondary")))
+		require.NoError(t, c.Provide(newCache))
+
+		err := c.Provide(newCache, Name("primary"))
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`function "go.uber.org/dig".TestGloballyUniqueNames\S+ \(\S+/uniquenames_test.go:\d+\) cannot be provided: `+
+				`cannot provide \*dig.cache\[name="primary"\]: name "primary" is already used by \*dig.db\[name="primary"\], `+
+				`provided by "go.uber.org/dig".TestGloballyUniqueNames\S+ \(\S+/uniquenames_test.go:\d+\)`)
+
+		assert.Equal(t, []Key{{Type: reflect.TypeOf(&db{}), Name: "primary"}}, c.LookupByName("primary"))
+	})
+
+	t.Run("same constructor", func(t *testing.T) {
+		type out struct {
+			Out
+
+			DB    *db    `name:"primary"`
+			Cache *cache `name:"primary"`
+		}
+
+		c := New(GloballyUniqueNames())
+		err := c.Provide(func() out { return out{} })
+		require.Error(t, err)
+		assert.Contains(t, err.Error(),
+			`cannot provide *dig.cache[name="primary"] and *dig.db[name="primary"]: names must be unique across types`)
+	})
+
+	t.Run("parent scope", func(t *testing.T) {
+		c := New(GloballyUniqueNames())
+		require.NoError(t, c.Provide(newDB, Name("primary")))
+
+		s := c.Scope("child")
+		require.Error(t, s.Provide(newCache, Name("primary")))
+		assert.Len(t, s.LookupByName("primary"), 1)
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		c := New(GloballyUniqueNames())
+		require.NoError(t, c.Provide(newDB, Name("primary")))
+		require.NoError(t, c.RemoveNamed(new(*db), "primary"))
+
+		assert.Empty(t, c.LookupByName("primary"))
+		require.NoError(t, c.Provide(newCache, Name("primary")))
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		c := New()
+		assert.Empty(t, c.LookupByName("primary"))
+		assert.Empty(t, c.LookupByName(""))
+	})
+}
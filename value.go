@@ -0,0 +1,79 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// providedValue is a value wrapped by Value, to be provided to the container
+// as-is.
+type providedValue struct {
+	value interface{}
+}
+
+// Value wraps a value so that it may be passed to Provide in place of a
+// constructor. The value is provided as if by a constructor that accepts no
+// arguments and returns it, and all ProvideOptions apply to it as usual.
+//
+//   c.Provide(dig.Value(cfg))
+//   c.Provide(dig.Value(roConn), dig.Name("ro"))
+//
+// This is convenient for declarative lists of providers that mix
+// constructors and constants.
+//
+//   for _, p := range []interface{}{dig.Value(cfg), NewLogger, NewServer} {
+//     if err := c.Provide(p); err != nil {
+//       // ...
+//     }
+//   }
+//
+// Errors reported for such values point to the call to Provide.
+func Value(v interface{}) interface{} {
+	return &providedValue{value: v}
+}
+
+func (c *Container) provideValue(pv *providedValue, opts provideOptions, caller *digreflect.Func) error {
+	vtype := reflect.TypeOf(pv.value)
+	if vtype == nil {
+		return errors.New("can't provide an untyped nil with dig.Value")
+	}
+	if isError(vtype) {
+		return fmt.Errorf("can't provide %v with dig.Value: "+
+			"values that implement error cannot be provided", vtype)
+	}
+
+	v := reflect.ValueOf(pv.value)
+	ctor := reflect.MakeFunc(
+		reflect.FuncOf(nil /* in */, []reflect.Type{vtype}, false /* variadic */),
+		func([]reflect.Value) []reflect.Value { return []reflect.Value{v} },
+	)
+
+	opts.Location = caller
+	if err := c.provide(ctor.Interface(), opts); err != nil {
+		return errProvide{Func: caller, Reason: err}
+	}
+	return nil
+}
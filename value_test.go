@@ -0,0 +1,135 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvideValue(t *testing.T) {
+	t.Run("value is provided as-is", func(t *testing.T) {
+		c := New()
+		buf := new(bytes.Buffer)
+		require.NoError(t, c.Provide(Value(buf)))
+		require.NoError(t, c.Invoke(func(got *bytes.Buffer) {
+			assert.True(t, got == buf, "invoke got wrong buffer")
+		}))
+	})
+
+	t.Run("mixed with constructors", func(t *testing.T) {
+		type config struct{ Prefix string }
+
+		c := New()
+		for _, p := range []interface{}{
+			func(cfg config) string { return cfg.Prefix + "bar" },
+			Value(config{Prefix: "foo"}),
+		} {
+			require.NoError(t, c.Provide(p))
+		}
+		require.NoError(t, c.Invoke(func(s string) {
+			assert.Equal(t, "foobar", s)
+		}))
+	})
+
+	t.Run("name option", func(t *testing.T) {
+		type in struct {
+			In
+
+			RO string `name:"ro"`
+			RW string `name:"rw"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(Value("foo"), Name("ro")))
+		require.NoError(t, c.Provide(Value("bar"), Name("rw")))
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Equal(t, "foo", i.RO)
+			assert.Equal(t, "bar", i.RW)
+		}))
+	})
+
+	t.Run("functions are values", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(Value(strings.ToUpper)))
+		require.NoError(t, c.Invoke(func(f func(string) string) {
+			assert.Equal(t, "FOO", f("foo"))
+		}))
+	})
+
+	t.Run("result objects", func(t *testing.T) {
+		type out struct {
+			Out
+
+			Ints []int `group:"ints"`
+		}
+
+		type in struct {
+			In
+
+			Ints [][]int `group:"ints"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(Value(out{Ints: []int{1, 2}})))
+		require.NoError(t, c.Invoke(func(i in) {
+			assert.Equal(t, [][]int{{1, 2}}, i.Ints)
+		}))
+	})
+
+	t.Run("location is the call to Provide", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(Value(42)))
+		err := c.Provide(Value(43))
+		require.Error(t, err, "int must not be provided twice")
+		assert.Contains(t, err.Error(), `"go.uber.org/dig".TestProvideValue.func6 (`)
+		assert.Contains(t, err.Error(), "value_test.go:")
+		assert.Contains(t, err.Error(), "cannot provide int from [0]: already provided by")
+		assert.NotContains(t, err.Error(), "reflect")
+	})
+
+	t.Run("untyped nil", func(t *testing.T) {
+		c := New()
+		err := c.Provide(Value(nil))
+		require.Error(t, err)
+		assert.Equal(t, "can't provide an untyped nil with dig.Value", err.Error())
+	})
+
+	t.Run("error value", func(t *testing.T) {
+		c := New()
+		err := c.Provide(Value(errors.New("great sadness")))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "can't provide *errors.errorString with dig.Value")
+		assert.Contains(t, err.Error(), "values that implement error cannot be provided")
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		c := New()
+		err := c.Provide(Value(42), Name("foo`bar"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "names cannot contain backquotes")
+	})
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// Verbosity specifies how much context is included in the errors returned
+// by Invoke. See ErrorVerbosity.
+type Verbosity int
+
+const (
+	// Normal errors describe every function and value between the invoked
+	// function and the failure. This is the default.
+	Normal Verbosity = iota
+
+	// Short errors only name the constructor that failed and its error.
+	// The Normal error can be retrieved with errors.Unwrap.
+	Short
+
+	// Verbose errors are Normal errors followed by the dependency path from
+	// the invoked function to the failure.
+	Verbose
+)
+
+func (v Verbosity) String() string {
+	switch v {
+	case Short:
+		return "short"
+	case Verbose:
+		return "verbose"
+	default:
+		return "normal"
+	}
+}
+
+// ErrorVerbosity is an Option that changes how much context is included in
+// the errors returned by Invoke.
+//
+//   c := dig.New(dig.ErrorVerbosity(dig.Short))
+//
+// Whatever the verbosity, formatting an error with %+v includes all of its
+// context, and the other properties of errors, such as their RootCause, are
+// unchanged.
+func ErrorVerbosity(v Verbosity) Option {
+	return optionFunc(func(c *Container) {
+		c.errorVerbosity = v
+	})
+}
+
+// withVerbosity adapts an error returned by Invoke to the verbosity of the
+// container.
+func (c *Container) withVerbosity(err error) error {
+	switch c.errorVerbosity {
+	case Short:
+		if e, ok := failedConstructor(err); ok {
+			return errShort{Func: e.Func, Reason: e.Reason, err: err}
+		}
+	case Verbose:
+		if path := dependencyPath(err); len(path) > 1 {
+			return errVerbose{Path: path, err: err}
+		}
+	}
+	return err
+}
+
+// failedConstructor returns the innermost constructor failure in the chain
+// of the given error.
+func failedConstructor(err error) (e errConstructorFailed, ok bool) {
+	for err != nil {
+		if cf, isFailure := err.(errConstructorFailed); isFailure {
+			e, ok = cf, true
+		}
+		c, isCauser := err.(causer)
+		if !isCauser {
+			break
+		}
+		err = c.cause()
+	}
+	return e, ok
+}
+
+// dependencyPath returns the functions and values between the invoked
+// function and the innermost failure in the chain of the given error.
+func dependencyPath(err error) []string {
+	var path []string
+	add := func(s string) {
+		if len(path) == 0 || path[len(path)-1] != s {
+			path = append(path, s)
+		}
+	}
+	for err != nil {
+		switch e := err.(type) {
+		case errArgumentsFailed:
+			add(funcName(e.Func))
+		case errMissingDependencies:
+			add(funcName(e.Func))
+		case errConstructorFailed:
+			add(funcName(e.Func))
+		case errParamSingleFailed:
+			add(e.Key.String())
+		case errParamGroupFailed:
+			add(e.Key.String())
+		}
+		c, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = c.cause()
+	}
+	return path
+}
+
+// funcName returns the package-qualified name of the given function.
+func funcName(fn *digreflect.Func) string {
+	return fn.Package + "." + fn.Name
+}
+
+// errShort is returned by Invoke instead of err for containers with Short
+// verbosity.
+type errShort struct {
+	Func   *digreflect.Func
+	Reason error
+
+	err error
+}
+
+func (e errShort) cause() error  { return e.err }
+func (e errShort) Unwrap() error { return e.err }
+
+func (e errShort) Error() string {
+	return fmt.Sprintf("constructor %v failed: %v", funcName(e.Func), e.Reason)
+}
+
+// Format implements fmt.Formatter. The %+v verb formats the Normal error.
+func (e errShort) Format(w fmt.State, c rune) {
+	if c == 'v' && w.Flag('+') {
+		fmt.Fprintf(w, "%+v", e.err)
+		return
+	}
+	io.WriteString(w, e.Error())
+}
+
+// errVerbose is returned by Invoke instead of err for containers with
+// Verbose verbosity.
+type errVerbose struct {
+	Path []string
+
+	err error
+}
+
+func (e errVerbose) cause() error  { return e.err }
+func (e errVerbose) Unwrap() error { return e.err }
+
+func (e errVerbose) Error() string {
+	return fmt.Sprintf("%v\n\tdependency path: %v", e.err, strings.Join(e.Path, " -> "))
+}
+
+// Format implements fmt.Formatter. The %+v verb formats the Normal error
+// with %+v as well.
+func (e errVerbose) Format(w fmt.State, c rune) {
+	if c == 'v' && w.Flag('+') {
+		fmt.Fprintf(w, "%+v\n\tdependency path: %v", e.err, strings.Join(e.Path, " -> "))
+		return
+	}
+	io.WriteString(w, e.Error())
+}
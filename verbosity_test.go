@@ -0,0 +1,100 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorVerbosity(t *testing.T) {
+	type A struct{}
+	type B struct{}
+
+	sadness := errors.New("great sadness")
+	newContainer := func(t *testing.T, v Verbosity) *Container {
+		c := New(ErrorVerbosity(v))
+		require.NoError(t, c.Provide(func() (*A, error) { return nil, sadness }))
+		require.NoError(t, c.Provide(func(*A) *B { return &B{} }))
+		return c
+	}
+	invoke := func(*B) {}
+
+	t.Run("normal", func(t *testing.T) {
+		err := newContainer(t, Normal).Invoke(invoke)
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "dependency path")
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestErrorVerbosity\S+`,
+			`failed to build \*dig.B:`,
+			`failed to build \*dig.A:`,
+			`function "go.uber.org/dig".TestErrorVerbosity\S+ \(\S+\) returned a non-nil error:`,
+			`great sadness`)
+	})
+
+	t.Run("short", func(t *testing.T) {
+		c := newContainer(t, Short)
+		err := c.Invoke(invoke)
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`^constructor go.uber.org/dig.TestErrorVerbosity.func[\d.]+ failed: great sadness$`)
+		assert.Equal(t, sadness, RootCause(err))
+		assert.True(t, errors.Is(err, sadness))
+
+		normal := newContainer(t, Normal).Invoke(invoke)
+		assert.Equal(t, normal.Error(), errors.Unwrap(err).Error(), "must unwrap to the normal error")
+		assert.Equal(t, normal.Error(), fmt.Sprintf("%+v", err))
+
+		s := c.Scope("child")
+		err = s.Invoke(invoke)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "constructor go.uber.org/dig.TestErrorVerbosity", "scopes must inherit the verbosity")
+	})
+
+	t.Run("short without constructor failure", func(t *testing.T) {
+		c := New(ErrorVerbosity(Short))
+		err := c.Invoke(func(*A) {})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing dependencies for function")
+	})
+
+	t.Run("verbose", func(t *testing.T) {
+		err := newContainer(t, Verbose).Invoke(invoke)
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`could not build arguments for function "go.uber.org/dig".TestErrorVerbosity\S+`,
+			`great sadness`,
+			`\n\tdependency path: go.uber.org/dig.TestErrorVerbosity.func[\d.]+ -> \*dig.B -> `+
+				`go.uber.org/dig.TestErrorVerbosity.func[\d.]+ -> \*dig.A -> go.uber.org/dig.TestErrorVerbosity.func[\d.]+$`)
+		assert.Equal(t, sadness, RootCause(err))
+		assert.Equal(t, err.Error(), fmt.Sprintf("%+v", err))
+	})
+
+	t.Run("String", func(t *testing.T) {
+		assert.Equal(t, "normal", Normal.String())
+		assert.Equal(t, "short", Short.String())
+		assert.Equal(t, "verbose", Verbose.String())
+	})
+}
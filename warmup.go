@@ -0,0 +1,187 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// A WarmUpOption modifies the default behavior of WarmUp.
+type WarmUpOption interface {
+	applyWarmUpOption(*warmUpOptions)
+}
+
+type warmUpOptions struct {
+	// If set, filled with the constructor calls made by WarmUp.
+	Calls *[]CallRecord
+}
+
+type warmUpOptionFunc func(*warmUpOptions)
+
+func (f warmUpOptionFunc) applyWarmUpOption(opts *warmUpOptions) { f(opts) }
+
+// WarmUpCalls is a WarmUpOption that fills the given slice with the
+// constructor calls made by WarmUp, slowest first.
+//
+//   var calls []dig.CallRecord
+//   err := c.WarmUp(ctx, dig.WarmUpCalls(&calls))
+//   for _, call := range calls[:3] {
+//     log.Printf("%v took %v", call.Name, call.Duration)
+//   }
+//
+// The slice is filled even if WarmUp fails.
+func WarmUpCalls(calls *[]CallRecord) WarmUpOption {
+	return warmUpOptionFunc(func(opts *warmUpOptions) {
+		opts.Calls = calls
+	})
+}
+
+// WarmUp calls every constructor of the container and its parents that
+// hasn't been called yet, so that later Invokes find all values already
+// built. Use it to pay the cost of construction at startup.
+//
+// Constructors are called one at a time from the calling goroutine, as they
+// would be by Invoke: at most once and after their dependencies. WarmUp
+// doesn't stop at the first failure: it returns a MultiError describing
+// every constructor that failed.
+//
+// WarmUp checks ctx before calling each constructor, and stops calling them
+// once ctx is done, reporting a CancellationError. A constructor that is
+// already running when ctx is done is not interrupted: WarmUp returns once
+// it does. Calling WarmUp again afterwards picks up where it stopped.
+func (c *Container) WarmUp(ctx context.Context, opts ...WarmUpOption) error {
+	if err := c.checkInitialized(); err != nil {
+		return err
+	}
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+
+	var options warmUpOptions
+	for _, o := range opts {
+		o.applyWarmUpOption(&options)
+	}
+
+	if err := c.verifyChainAcyclic(); err != nil {
+		return err
+	}
+
+	c.calls.enter()
+	defer c.calls.exit()
+	if c.calls.depth == 1 {
+		// As for Invoke, values built from removed values are discarded
+		// first so that they're built again.
+		c.parentCalls.lock(c.calls)
+		c.discardRemovedValues()
+		err := c.evaluateConditions()
+		c.parentCalls.unlock()
+		if err != nil {
+			return err
+		}
+	}
+	start := len(c.calls.calls)
+
+	errs := c.warmUp(ctx)
+	if options.Calls != nil {
+		calls := append([]CallRecord(nil), c.calls.calls[start:]...)
+		sort.SliceStable(calls, func(i, j int) bool {
+			return calls[i].Duration > calls[j].Duration
+		})
+		*options.Calls = calls
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	err := newMultiError(errs)
+	err.header = "failed to warm up the container:"
+	err.prefix = "\n\t"
+	return err
+}
+
+// CancellationError is returned by WarmUp when its context is done before
+// it called every constructor. Use errors.As to retrieve it from the error
+// returned by WarmUp. Its Unwrap method returns the error of the context.
+type CancellationError struct {
+	// Constructors that were called by WarmUp before the context was done,
+	// including those called to build the dependencies of others.
+	Completed []*digreflect.Func
+
+	// Constructors that were not called yet when the context was done.
+	// Constructors that failed are not included.
+	NotStarted []*digreflect.Func
+
+	err error
+}
+
+func (e CancellationError) Error() string {
+	return fmt.Sprintf("cannot finish warming up: %d constructors were called, %d were not: %v",
+		len(e.Completed), len(e.NotStarted), e.err)
+}
+
+// Unwrap returns the error of the context.
+func (e CancellationError) Unwrap() error { return e.err }
+
+// warmUp calls the constructors of the container and its parents, and
+// returns their failures.
+func (c *Container) warmUp(ctx context.Context) []error {
+	nodes := c.chainNodes()
+	var pending []*node
+	for _, n := range nodes {
+		if !n.called && !n.disabled() {
+			pending = append(pending, n)
+		}
+	}
+
+	var errs []error
+	failed := make(map[*node]struct{})
+	for _, n := range nodes {
+		if err := ctx.Err(); err != nil {
+			return append(errs, newCancellationError(err, pending, failed))
+		}
+		if n.disabled() {
+			continue
+		}
+		if err := callProvider(c, n); err != nil {
+			failed[n] = struct{}{}
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// newCancellationError reports which of the constructors that weren't called
+// before WarmUp have been called since.
+func newCancellationError(err error, pending []*node, failed map[*node]struct{}) CancellationError {
+	cerr := CancellationError{err: err}
+	for _, n := range pending {
+		if n.called {
+			cerr.Completed = append(cerr.Completed, n.location)
+		} else if _, ok := failed[n]; !ok {
+			cerr.NotStarted = append(cerr.NotStarted, n.location)
+		}
+	}
+	return cerr
+}
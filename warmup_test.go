@@ -0,0 +1,237 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmUp(t *testing.T) {
+	type A struct{}
+	type B struct{}
+	type C struct{}
+
+	t.Run("constructs everything once", func(t *testing.T) {
+		calls := make(map[string]int)
+		c := New()
+		require.NoError(t, c.Provide(func(*A) *B {
+			calls["B"]++
+			return &B{}
+		}))
+		require.NoError(t, c.Provide(func() *A {
+			calls["A"]++
+			return &A{}
+		}))
+		require.NoError(t, c.Provide(func() string {
+			calls["group"]++
+			return "x"
+		}, Group("names")))
+
+		require.NoError(t, c.WarmUp(context.Background()))
+		assert.Equal(t, map[string]int{"A": 1, "B": 1, "group": 1}, calls)
+
+		var report PreflightReport
+		require.NoError(t, c.Invoke(func(*A, *B, struct {
+			In
+
+			Names []string `group:"names"`
+		}) {
+		}, Preflight(&report)))
+		assert.Empty(t, report.Constructed, "everything must be cached")
+		assert.Equal(t, map[string]int{"A": 1, "B": 1, "group": 1}, calls)
+	})
+
+	t.Run("reports all failures", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() (*A, error) { return nil, errors.New("great sadness") }))
+		require.NoError(t, c.Provide(func() *B { return &B{} }))
+		require.NoError(t, c.Provide(func(*string) *C { return &C{} }))
+
+		err := c.WarmUp(context.Background())
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`failed to warm up the container:`,
+			`\n\tfunction "go.uber.org/dig".TestWarmUp\S+ \(\S+\) returned a non-nil error: great sadness`,
+			`\n\tmissing dependencies for function "go.uber.org/dig".TestWarmUp\S+ \(\S+\):`,
+			`type \*string is not in the container`)
+
+		var merr MultiError
+		require.True(t, errors.As(err, &merr))
+		assert.Len(t, merr.Unwrap(), 2)
+		require.NoError(t, c.Invoke(func(*B) {}), "B must have been built")
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		var calls int
+		c := New()
+		require.NoError(t, c.Provide(func() *A {
+			calls++
+			return &A{}
+		}))
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func(*A) *B {
+			calls++
+			return &B{}
+		}))
+
+		require.NoError(t, s.WarmUp(context.Background()))
+		assert.Equal(t, 2, calls)
+		require.NoError(t, c.Invoke(func(*A) {}))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("removed values", func(t *testing.T) {
+		type in struct {
+			In
+
+			A *A `name:"a" optional:"true"`
+		}
+
+		var calls int
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }, Name("a")))
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func(i in) *B {
+			calls++
+			if i.A == nil {
+				return nil
+			}
+			return &B{}
+		}))
+
+		require.NoError(t, s.WarmUp(context.Background()))
+		require.NoError(t, c.RemoveNamed((**A)(nil), "a"))
+		require.NoError(t, s.WarmUp(context.Background()))
+		assert.Equal(t, 2, calls, "values built from removed values must be built again")
+		require.NoError(t, s.Invoke(func(b *B) {
+			assert.Nil(t, b)
+		}))
+	})
+
+	t.Run("concurrent Provide", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 50; i++ {
+				assert.NoError(t, c.Provide(func() int { return i }, Group("ints")))
+			}
+		}()
+		for i := 0; i < 50; i++ {
+			assert.NoError(t, c.WarmUp(context.Background()))
+		}
+		<-done
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		var calls int
+		c := New()
+		ctx, cancel := context.WithCancel(context.Background())
+		require.NoError(t, c.Provide(func() *A {
+			calls++
+			cancel()
+			return &A{}
+		}))
+		require.NoError(t, c.Provide(func() *B {
+			calls++
+			return &B{}
+		}))
+
+		err := c.WarmUp(ctx)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, context.Canceled), "expected context.Canceled, got %v", err)
+		assert.Equal(t, 1, calls)
+
+		var cerr CancellationError
+		require.True(t, errors.As(err, &cerr))
+		require.Len(t, cerr.Completed, 1)
+		require.Len(t, cerr.NotStarted, 1)
+		assert.Contains(t, cerr.Completed[0].Name, "TestWarmUp.func")
+		assert.NotEqual(t, cerr.Completed[0].String(), cerr.NotStarted[0].String())
+		assert.Contains(t, err.Error(), "cannot finish warming up: 1 constructors were called, 1 were not: context canceled")
+
+		require.NoError(t, c.WarmUp(context.Background()), "retrying must succeed")
+		assert.Equal(t, 2, calls, "only the constructors that were not called must be called")
+	})
+
+	t.Run("cancelled with failures", func(t *testing.T) {
+		c := New()
+		ctx, cancel := context.WithCancel(context.Background())
+		require.NoError(t, c.Provide(func() (*A, error) { return nil, errors.New("great sadness") }))
+		require.NoError(t, c.Provide(func() *B {
+			cancel()
+			return &B{}
+		}))
+		require.NoError(t, c.Provide(func() *C { return &C{} }))
+
+		err := c.WarmUp(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "great sadness")
+
+		var cerr CancellationError
+		require.True(t, errors.As(err, &cerr))
+		assert.Len(t, cerr.Completed, 1)
+		assert.Len(t, cerr.NotStarted, 1, "failed constructors must not be reported as not started")
+	})
+
+	t.Run("calls", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Provide(func() *B {
+			time.Sleep(10 * time.Millisecond)
+			return &B{}
+		}))
+		require.NoError(t, c.Invoke(func(*A) {}))
+
+		var calls []CallRecord
+		require.NoError(t, c.WarmUp(context.Background(), WarmUpCalls(&calls)))
+		require.Len(t, calls, 1, "A was already built")
+		assert.True(t, calls[0].Duration >= 10*time.Millisecond)
+
+		c = New()
+		require.NoError(t, c.Provide(func() *A { return &A{} }))
+		require.NoError(t, c.Provide(func() *B {
+			time.Sleep(10 * time.Millisecond)
+			return &B{}
+		}))
+		require.NoError(t, c.WarmUp(context.Background(), WarmUpCalls(&calls)))
+		require.Len(t, calls, 2)
+		assert.True(t, calls[0].Duration >= calls[1].Duration, "expected the slowest call first")
+	})
+
+	t.Run("cycles", func(t *testing.T) {
+		c := New(DeferAcyclicVerification())
+		require.NoError(t, c.Provide(func(*B) *A { return &A{} }))
+		require.NoError(t, c.Provide(func(*A) *B { return &B{} }))
+
+		err := c.WarmUp(context.Background())
+		require.Error(t, err)
+		assert.True(t, IsCycleDetected(err))
+	})
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// _warningsGroup is the value group that all Warnings are submitted to.
+// Other values may not be submitted to it.
+const _warningsGroup = "dig.warnings"
+
+var _warningType = reflect.TypeOf(Warning{})
+
+// Warning is a non-fatal condition reported by a constructor, such as a
+// feature that was disabled because it wasn't configured. Constructors report
+// warnings by producing Warning values, either as results or as fields of
+// dig.Out structs.
+//
+//   type CacheResult struct {
+//     dig.Out
+//
+//     Cache   *Cache
+//     Warning dig.Warning
+//   }
+//
+//   func NewCache(cfg *Config) CacheResult {
+//     if cfg.Redis == "" {
+//       return CacheResult{
+//         Cache:   noopCache(),
+//         Warning: dig.Warnf("cache disabled: no redis configured"),
+//       }
+//     }
+//     ...
+//   }
+//
+// Warnings are submitted to the "dig.warnings" value group regardless of
+// their names and groups, and may be retrieved with Container.Warnings once
+// their constructors were called. Warnings without an error are discarded,
+// so constructors with nothing to report may leave the Warning empty.
+type Warning struct {
+	// Condition reported by the constructor.
+	Err error
+
+	// Name, package, and location of the constructor that reported the
+	// warning. These are set by the container.
+	Name    string
+	Package string
+	File    string
+	Line    int
+}
+
+// Warnf builds a Warning with an error formatted like fmt.Errorf.
+func Warnf(format string, args ...interface{}) Warning {
+	return Warning{Err: fmt.Errorf(format, args...)}
+}
+
+func (w Warning) String() string {
+	if w.File == "" {
+		return fmt.Sprint(w.Err)
+	}
+	return fmt.Sprintf("%v (reported by %q.%v (%v:%v))", w.Err, w.Package, w.Name, w.File, w.Line)
+}
+
+// Warnings returns the warnings reported by the constructors of this
+// container and its parents that were called so far, in the order in which
+// the constructors were called, starting with those of the root container.
+//
+//   if err := c.Invoke(run); err != nil {
+//     ...
+//   }
+//   for _, w := range c.Warnings() {
+//     log.Print(w)
+//   }
+func (c *Container) Warnings() []Warning {
+	if c.checkInitialized() != nil {
+		return nil
+	}
+
+	var warnings []Warning
+	for _, s := range c.scopeChain() {
+		s.valuesMu.RLock()
+		for _, v := range s.groups[key{group: _warningsGroup, t: _warningType}] {
+			warnings = append(warnings, v.Value.Interface().(Warning))
+		}
+		s.valuesMu.RUnlock()
+	}
+	return warnings
+}
+
+// checkWarningsGroup returns an error if a value of the given type may not
+// be submitted to the given group because the group is reserved for
+// Warnings.
+func checkWarningsGroup(group string, t reflect.Type) error {
+	if group == _warningsGroup && t != _warningType {
+		return fmt.Errorf("cannot submit %v to value group %q: the group is reserved for dig.Warning", t, group)
+	}
+	return nil
+}
+
+// annotateWarnings sets the location of the Warnings staged in this writer
+// to that of the given constructor, and discards Warnings without an error.
+func (sr *stagingContainerWriter) annotateWarnings(fn *digreflect.Func) {
+	k := key{group: _warningsGroup, t: _warningType}
+	values, ok := sr.groups[k]
+	if !ok {
+		return
+	}
+
+	var annotated []reflect.Value
+	for _, v := range values {
+		w := v.Interface().(Warning)
+		if w.Err == nil {
+			continue
+		}
+		w.Name, w.Package, w.File, w.Line = fn.Name, fn.Package, fn.File, fn.Line
+		annotated = append(annotated, reflect.ValueOf(w))
+	}
+	if len(annotated) == 0 {
+		delete(sr.groups, k)
+		return
+	}
+	sr.groups[k] = annotated
+}
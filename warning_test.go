@@ -0,0 +1,128 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarnings(t *testing.T) {
+	type Cache struct{}
+	type Queue struct{}
+
+	type cacheResult struct {
+		Out
+
+		Cache   *Cache
+		Warning Warning
+	}
+
+	newCache := func() cacheResult {
+		return cacheResult{Cache: &Cache{}, Warning: Warnf("cache disabled: %v", "no redis configured")}
+	}
+
+	t.Run("collected after invoke", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newCache))
+		require.NoError(t, c.Provide(func() (*Queue, Warning) {
+			return &Queue{}, Warning{Err: errors.New("queue is in-memory")}
+		}))
+
+		assert.Empty(t, c.Warnings(), "constructors weren't called yet")
+		require.NoError(t, c.Invoke(func(*Cache, *Queue) {}))
+
+		warnings := c.Warnings()
+		require.Len(t, warnings, 2)
+		assert.EqualError(t, warnings[0].Err, "cache disabled: no redis configured")
+		assert.Contains(t, warnings[0].Name, "TestWarnings")
+		assert.Contains(t, warnings[0].File, "warning_test.go")
+		assert.NotZero(t, warnings[0].Line)
+		assert.EqualError(t, warnings[1].Err, "queue is in-memory")
+		assert.Contains(t, warnings[1].String(), "queue is in-memory (reported by")
+	})
+
+	t.Run("empty warnings are discarded", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() cacheResult { return cacheResult{Cache: &Cache{}} }))
+		require.NoError(t, c.Invoke(func(*Cache) {}))
+		assert.Empty(t, c.Warnings())
+	})
+
+	t.Run("scopes include parent warnings", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newCache))
+		s := c.Scope("child")
+		require.NoError(t, s.Provide(func() (*Queue, Warning) { return &Queue{}, Warnf("child") }))
+		require.NoError(t, s.Invoke(func(*Cache, *Queue) {}))
+
+		warnings := s.Warnings()
+		require.Len(t, warnings, 2)
+		assert.EqualError(t, warnings[0].Err, "cache disabled: no redis configured")
+		assert.EqualError(t, warnings[1].Err, "child")
+		assert.Len(t, c.Warnings(), 1)
+	})
+
+	t.Run("consumed as a group", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newCache))
+
+		type in struct {
+			In
+
+			Warnings []Warning `group:"dig.warnings"`
+		}
+		require.NoError(t, c.Invoke(func(p in) {
+			require.Len(t, p.Warnings, 1)
+			assert.NotEmpty(t, p.Warnings[0].File)
+		}))
+	})
+
+	t.Run("reserved group", func(t *testing.T) {
+		c := New()
+		err := c.Provide(func() *Cache { return &Cache{} }, Group("dig.warnings"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid dig.Group("dig.warnings"): the group is reserved for dig.Warning`)
+
+		type out struct {
+			Out
+
+			Cache *Cache `group:"dig.warnings"`
+		}
+		err = c.Provide(func() out { return out{} })
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `cannot submit *dig.Cache to value group "dig.warnings"`)
+	})
+
+	t.Run("excluded from report", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(newCache))
+		require.NoError(t, c.Invoke(func(*Cache) {}))
+
+		var buf bytes.Buffer
+		require.NoError(t, c.Report(&buf))
+		assert.NotContains(t, buf.String(), "dig.warnings")
+	})
+}
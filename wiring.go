@@ -0,0 +1,469 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.uber.org/dig/internal/digreflect"
+)
+
+// _wiringVersion is the version of the format written by DumpWiring.
+const _wiringVersion = 1
+
+// wiring is the document written by DumpWiring. Constructors and invoked
+// functions are described with the same types as Graph uses.
+type wiring struct {
+	Version  int
+	Provides []GraphCtor
+	Invokes  []GraphCtor
+
+	// Number of times each value was constructed. See ConstructionCounts.
+	Constructions []wiringConstruction `json:",omitempty"`
+
+	// Dependencies consumed through nested Invokes. See HiddenEdges.
+	HiddenEdges []wiringEdge `json:",omitempty"`
+}
+
+// wiringConstruction is the number of times a value or the values of a
+// value group were constructed.
+type wiringConstruction struct {
+	Type  string
+	Name  string `json:",omitempty"`
+	Group string `json:",omitempty"`
+	Count int
+}
+
+// wiringEdge is a dependency of a constructor consumed through an Invoke
+// made from inside it.
+type wiringEdge struct {
+	Package string
+	Func    string
+	File    string
+	Line    int
+
+	Type  string
+	Name  string `json:",omitempty"`
+	Group string `json:",omitempty"`
+}
+
+// invokeRecord is a function that was passed to Invoke.
+type invokeRecord struct {
+	fn     *digreflect.Func
+	params paramList
+}
+
+// recordInvoke records a function passed to Invoke for DumpWiring. Each
+// function is recorded only once.
+func (c *Container) recordInvoke(function interface{}, pl paramList) {
+	ptr := reflect.ValueOf(function).Pointer()
+	if _, ok := c.invokesSeen[ptr]; ok {
+		return
+	}
+	if c.invokesSeen == nil {
+		c.invokesSeen = make(map[uintptr]struct{})
+	}
+	c.invokesSeen[ptr] = struct{}{}
+	c.invokes = append(c.invokes, invokeRecord{
+		fn:     digreflect.InspectFunc(function),
+		params: pl,
+	})
+}
+
+// DumpWiring writes a JSON description of the constructors provided to the
+// container and the functions invoked on it to w. The description includes
+// the location of every function and the values it consumes and produces,
+// but no values.
+//
+// Users may attach this description to bug reports about containers that
+// fail to build. LintWiring validates the description without the original
+// functions.
+func (c *Container) DumpWiring(w io.Writer) error {
+	if err := c.checkInitialized(); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c.wiring())
+}
+
+// wiring describes the constructors provided to this container and its
+// parents, and the functions invoked on them.
+func (c *Container) wiring() wiring {
+	doc := wiring{
+		Version:  _wiringVersion,
+		Provides: Graph(c).Ctors,
+	}
+	for _, s := range c.scopeChain() {
+		for _, inv := range s.invokes {
+			doc.Invokes = append(doc.Invokes, newInvokeGraphCtor(inv))
+		}
+	}
+
+	for _, e := range c.hidden.list() {
+		doc.HiddenEdges = append(doc.HiddenEdges, wiringEdge{
+			Package: e.Package,
+			Func:    e.Name,
+			File:    e.File,
+			Line:    e.Line,
+			Type:    e.Key.Type.String(),
+			Name:    e.Key.Name,
+			Group:   e.Key.Group,
+		})
+	}
+
+	c.metrics.mu.Lock()
+	defer c.metrics.mu.Unlock()
+	keys := make([]key, 0, len(c.metrics.constructions))
+	for k := range c.metrics.constructions {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+	for _, k := range keys {
+		doc.Constructions = append(doc.Constructions, wiringConstruction{
+			Type:  k.t.String(),
+			Name:  k.name,
+			Group: k.group,
+			Count: c.metrics.constructions[k],
+		})
+	}
+	return doc
+}
+
+func newInvokeGraphCtor(inv invokeRecord) GraphCtor {
+	gc := GraphCtor{
+		Name:    inv.fn.Name,
+		Package: inv.fn.Package,
+		File:    inv.fn.File,
+		Line:    inv.fn.Line,
+		NumArgs: len(inv.params.Params),
+	}
+	for _, p := range inv.params.DotParam() {
+		if p.Group != "" {
+			gc.GroupParams = append(gc.GroupParams, GraphGroupRef{
+				Type: p.Type.Elem().String(),
+				Name: p.Group,
+			})
+			continue
+		}
+		gc.Params = append(gc.Params, GraphParam{
+			GraphNode: newGraphNode(p.Node, 0),
+			Optional:  p.Optional,
+		})
+	}
+	return gc
+}
+
+// LintWiring reads a description written by DumpWiring from r and verifies
+// that the wiring it describes is valid: no value is provided twice, the
+// constructors don't depend on each other in a cycle, and all dependencies of
+// constructors and invoked functions are provided. Unbuffered channels
+// produced by constructors that weren't provided with AllowUnbuffered, and
+// dependencies that constructors consumed through Invokes made from inside
+// them, are reported too, if the constructors were called before the
+// description was written.
+//
+// All problems found are reported in the returned error. Additional checks
+// may be enabled with LintOptions.
+func LintWiring(r io.Reader, opts ...LintOption) error {
+	var doc wiring
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return errWrapf(err, "failed to read wiring")
+	}
+	if doc.Version != _wiringVersion {
+		return fmt.Errorf("unsupported wiring version %d, expected %d", doc.Version, _wiringVersion)
+	}
+
+	l := newWiringLinter(doc)
+	for _, o := range opts {
+		o.applyLintOption(l)
+	}
+	l.checkConflicts()
+	l.checkCycles()
+	l.checkMissing()
+	l.checkNumArgs()
+	l.checkUnbuffered()
+	l.checkHiddenEdges()
+	if l.constructedOnce {
+		l.checkConstructions()
+	}
+	if len(l.problems) > 0 {
+		err := newMultiError(l.problems)
+		err.header = fmt.Sprintf("found %d problems with the wiring:", len(l.problems))
+		err.prefix = "\n\t"
+		return err
+	}
+	return nil
+}
+
+// A LintOption enables additional checks in LintWiring.
+type LintOption interface {
+	applyLintOption(*wiringLinter)
+}
+
+type lintOptionFunc func(*wiringLinter)
+
+func (f lintOptionFunc) applyLintOption(l *wiringLinter) { f(l) }
+
+// MaxArgs is a LintOption that reports constructors and invoked functions
+// that accept more than n positional arguments. Such functions are easier to
+// maintain if their arguments are grouped in a dig.In struct.
+//
+//   err := dig.LintWiring(r, dig.MaxArgs(8))
+func MaxArgs(n int) LintOption {
+	return lintOptionFunc(func(l *wiringLinter) {
+		l.maxArgs = n
+	})
+}
+
+// ConstructedOnce is a LintOption that reports values that were constructed
+// more than once before the description was written, for example because
+// multiple scopes provide their own constructor for a value that should be
+// shared. Value groups are not checked. See Container.ConstructionCounts.
+//
+//   err := dig.LintWiring(r, dig.ConstructedOnce())
+//
+// Values that are expected to be built once per scope, such as those of
+// request scopes, are reported too. Dump the wiring of a container whose
+// scopes were not used yet to check the values of its scopes.
+func ConstructedOnce() LintOption {
+	return lintOptionFunc(func(l *wiringLinter) {
+		l.constructedOnce = true
+	})
+}
+
+// wiringKey identifies a value or a value group in a wiring description.
+type wiringKey struct {
+	Type, Name, Group string
+}
+
+func (k wiringKey) String() string {
+	switch {
+	case k.Name != "":
+		return fmt.Sprintf("%v[name=%q]", k.Type, k.Name)
+	case k.Group != "":
+		return fmt.Sprintf("%v[group=%q]", k.Type, k.Group)
+	default:
+		return k.Type
+	}
+}
+
+type wiringLinter struct {
+	doc       wiring
+	providers map[wiringKey][]int // indexes into doc.Provides
+	problems  []error
+
+	// Maximum number of positional arguments, or zero for no limit.
+	maxArgs int
+
+	// Report values constructed more than once.
+	constructedOnce bool
+}
+
+func newWiringLinter(doc wiring) *wiringLinter {
+	l := &wiringLinter{doc: doc, providers: make(map[wiringKey][]int)}
+	for i, ctor := range doc.Provides {
+		for _, r := range ctor.Results {
+			k := wiringKey{Type: r.Type, Name: r.Name, Group: r.Group}
+			if len(l.providers[k]) > 0 && l.providers[k][len(l.providers[k])-1] == i {
+				// Multiple values of the same group from one constructor.
+				continue
+			}
+			l.providers[k] = append(l.providers[k], i)
+		}
+	}
+	return l
+}
+
+func (l *wiringLinter) location(i int) string {
+	ctor := l.doc.Provides[i]
+	return fmt.Sprintf("%q.%v (%v:%v)", ctor.Package, ctor.Name, ctor.File, ctor.Line)
+}
+
+// dependencies returns the keys of the values and value groups consumed by
+// the given constructor or invoked function that may form cycles. Like Invoke,
+// it leaves out optional values: see isOptionalEdge.
+func (l *wiringLinter) dependencies(ctor GraphCtor) []wiringKey {
+	var keys []wiringKey
+	for _, p := range ctor.Params {
+		if p.Optional {
+			continue
+		}
+		keys = append(keys, wiringKey{Type: p.Type, Name: p.Name})
+	}
+	for _, g := range ctor.GroupParams {
+		keys = append(keys, wiringKey{Type: g.Type, Group: g.Name})
+	}
+	return keys
+}
+
+func (l *wiringLinter) checkConflicts() {
+	for i, ctor := range l.doc.Provides {
+		for _, r := range ctor.Results {
+			k := wiringKey{Type: r.Type, Name: r.Name}
+			if r.Group != "" {
+				continue
+			}
+			if ps := l.providers[k]; ps[0] != i {
+				l.problems = append(l.problems, fmt.Errorf(
+					"cannot provide %v from %v: already provided by %v",
+					k, l.location(i), l.location(ps[0])))
+			}
+		}
+	}
+}
+
+func (l *wiringLinter) checkCycles() {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(l.doc.Provides))
+
+	var path []string
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		switch state[i] {
+		case visiting:
+			path = append(path, l.location(i))
+			return true
+		case visited:
+			return false
+		}
+
+		state[i] = visiting
+		path = append(path, l.location(i))
+		for _, k := range l.dependencies(l.doc.Provides[i]) {
+			for _, j := range l.providers[k] {
+				if visit(j) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		return false
+	}
+
+	for i := range l.doc.Provides {
+		path = path[:0]
+		if visit(i) {
+			// Trim the path to the cycle itself.
+			last := path[len(path)-1]
+			for j, loc := range path {
+				if loc == last {
+					path = path[j:]
+					break
+				}
+			}
+			l.problems = append(l.problems, fmt.Errorf(
+				"cycle detected: %v", strings.Join(path, " depends on ")))
+			return
+		}
+	}
+}
+
+func (l *wiringLinter) checkMissing() {
+	check := func(ctor GraphCtor, kind string) {
+		var missing []string
+		for _, p := range ctor.Params {
+			k := wiringKey{Type: p.Type, Name: p.Name}
+			if !p.Optional && len(l.providers[k]) == 0 {
+				missing = append(missing, k.String())
+			}
+		}
+		if len(missing) > 0 {
+			l.problems = append(l.problems, fmt.Errorf(
+				"missing dependencies for %v %q.%v (%v:%v): %v",
+				kind, ctor.Package, ctor.Name, ctor.File, ctor.Line, strings.Join(missing, ", ")))
+		}
+	}
+
+	for _, ctor := range l.doc.Provides {
+		check(ctor, "function")
+	}
+	for _, inv := range l.doc.Invokes {
+		check(inv, "invoked function")
+	}
+}
+
+func (l *wiringLinter) checkNumArgs() {
+	if l.maxArgs <= 0 {
+		return
+	}
+
+	check := func(ctor GraphCtor, kind string) {
+		if ctor.NumArgs > l.maxArgs {
+			l.problems = append(l.problems, fmt.Errorf(
+				"%v %q.%v (%v:%v) has %d arguments, more than %d: "+
+					"consider grouping them in a dig.In struct",
+				kind, ctor.Package, ctor.Name, ctor.File, ctor.Line, ctor.NumArgs, l.maxArgs))
+		}
+	}
+
+	for _, ctor := range l.doc.Provides {
+		check(ctor, "function")
+	}
+	for _, inv := range l.doc.Invokes {
+		check(inv, "invoked function")
+	}
+}
+
+func (l *wiringLinter) checkConstructions() {
+	for _, c := range l.doc.Constructions {
+		// Value groups collect values from many constructor calls.
+		if c.Group != "" || c.Count <= 1 {
+			continue
+		}
+		l.problems = append(l.problems, fmt.Errorf(
+			"%v was constructed %d times, expected once",
+			wiringKey{Type: c.Type, Name: c.Name}, c.Count))
+	}
+}
+
+func (l *wiringLinter) checkUnbuffered() {
+	for i, ctor := range l.doc.Provides {
+		for _, r := range ctor.Results {
+			if r.Unbuffered {
+				l.problems = append(l.problems, fmt.Errorf(
+					"function %v provides %v, an unbuffered channel shared by all of its consumers: "+
+						"consider buffering it or providing the function with dig.AllowUnbuffered",
+					l.location(i), wiringKey{Type: r.Type, Name: r.Name}))
+			}
+		}
+	}
+}
+
+func (l *wiringLinter) checkHiddenEdges() {
+	for _, e := range l.doc.HiddenEdges {
+		l.problems = append(l.problems, fmt.Errorf(
+			"function %q.%v (%v:%v) consumes %v through an Invoke made from inside it: "+
+				"accept it as a parameter instead",
+			e.Package, e.Func, e.File, e.Line, wiringKey{Type: e.Type, Name: e.Name, Group: e.Group}))
+	}
+}
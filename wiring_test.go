@@ -0,0 +1,253 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpWiring(t *testing.T) {
+	type A struct{}
+	type B struct{}
+
+	c := New()
+	require.NoError(t, c.Provide(func() A { return A{} }))
+	require.NoError(t, c.Provide(func(A) B { return B{} }, Name("b")))
+
+	type in struct {
+		In
+
+		B B `name:"b"`
+	}
+	invoke := func(in) {}
+	require.NoError(t, c.Invoke(invoke))
+	require.NoError(t, c.Invoke(invoke))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.DumpWiring(&buf))
+
+	var doc wiring
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, _wiringVersion, doc.Version)
+
+	require.Len(t, doc.Provides, 2)
+	assert.Equal(t, "go.uber.org/dig", doc.Provides[0].Package)
+	assert.Contains(t, doc.Provides[0].File, "wiring_test.go")
+	assert.Equal(t, []GraphNode{{Type: "dig.A"}}, doc.Provides[0].Results)
+	assert.Equal(t, []GraphNode{{Type: "dig.B", Name: "b"}}, doc.Provides[1].Results)
+
+	require.Len(t, doc.Invokes, 1, "invoked functions must be recorded once")
+	assert.Equal(t, []GraphParam{{GraphNode: GraphNode{Type: "dig.B", Name: "b"}}}, doc.Invokes[0].Params)
+
+	assert.NoError(t, LintWiring(&buf))
+}
+
+func TestLintWiring(t *testing.T) {
+	type A struct{}
+	type B struct{}
+
+	dump := func(t *testing.T, c *Container) *bytes.Buffer {
+		var buf bytes.Buffer
+		require.NoError(t, c.DumpWiring(&buf))
+		return &buf
+	}
+
+	t.Run("missing dependencies", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func(A) B { return B{} }))
+		require.Error(t, c.Invoke(func(B, string) {}))
+
+		err := LintWiring(dump(t, c))
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`found 2 problems with the wiring:`,
+			`missing dependencies for function "go.uber.org/dig".TestLintWiring\S+ \(\S+\): dig.A`,
+			`missing dependencies for invoked function "go.uber.org/dig".TestLintWiring\S+ \(\S+\): string`)
+
+		var merr MultiError
+		require.True(t, errors.As(err, &merr), "must be a MultiError")
+		assert.Len(t, merr.Unwrap(), 2)
+	})
+
+	t.Run("optional dependencies", func(t *testing.T) {
+		type in struct {
+			In
+
+			A A `optional:"true"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func(in) B { return B{} }))
+		assert.NoError(t, LintWiring(dump(t, c)))
+	})
+
+	t.Run("cycles", func(t *testing.T) {
+		c := New(DeferAcyclicVerification())
+		require.NoError(t, c.Provide(func(B) A { return A{} }))
+		require.NoError(t, c.Provide(func(A) B { return B{} }))
+
+		err := LintWiring(dump(t, c))
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`cycle detected: "go.uber.org/dig".TestLintWiring\S+ \(\S+\)`,
+			`depends on "go.uber.org/dig".TestLintWiring\S+ \(\S+\)`,
+			`depends on "go.uber.org/dig".TestLintWiring\S+ \(\S+\)`)
+	})
+
+	t.Run("cycles through optional dependencies", func(t *testing.T) {
+		type Node struct{ next *Node }
+		type in struct {
+			In
+
+			Next *Node `optional:"true"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func(i in) *Node { return &Node{next: i.Next} }))
+		require.NoError(t, c.Invoke(func(*Node) {}))
+		assert.NoError(t, LintWiring(dump(t, c)))
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() A { return A{} }))
+
+		// Conflicts can't be provided, so forge them in the dump.
+		var doc wiring
+		require.NoError(t, json.NewDecoder(dump(t, c)).Decode(&doc))
+		doc.Provides = append(doc.Provides, doc.Provides[0])
+		doc.Provides[1].Line++
+
+		var buf bytes.Buffer
+		require.NoError(t, json.NewEncoder(&buf).Encode(doc))
+
+		err := LintWiring(&buf)
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`cannot provide dig.A from "go.uber.org/dig".TestLintWiring\S+ \(\S+\):`,
+			`already provided by "go.uber.org/dig".TestLintWiring\S+`)
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		type in struct {
+			In
+
+			As []A `group:"as"`
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() A { return A{} }, Group("as")))
+		require.NoError(t, c.Provide(func() A { return A{} }, Group("as")))
+		require.NoError(t, c.Provide(func(in) B { return B{} }))
+		assert.NoError(t, LintWiring(dump(t, c)))
+	})
+
+	t.Run("too many arguments", func(t *testing.T) {
+		type in struct {
+			In
+
+			A A
+			B B
+			C string
+		}
+
+		c := New()
+		require.NoError(t, c.Provide(func() A { return A{} }))
+		require.NoError(t, c.Provide(func() B { return B{} }))
+		require.NoError(t, c.Provide(func() string { return "" }))
+		require.NoError(t, c.Provide(func(A, B, string) int { return 0 }))
+		require.NoError(t, c.Provide(func(in) float64 { return 0 }))
+		require.NoError(t, c.Invoke(func(A, B, string, int) {}))
+
+		assert.NoError(t, LintWiring(dump(t, c)), "must be disabled by default")
+		assert.NoError(t, LintWiring(dump(t, c), MaxArgs(4)))
+
+		err := LintWiring(dump(t, c), MaxArgs(2))
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`found 2 problems with the wiring:`,
+			`function "go.uber.org/dig".TestLintWiring\S+ \(\S+\) has 3 arguments, more than 2: `,
+			`consider grouping them in a dig.In struct`,
+			`invoked function "go.uber.org/dig".TestLintWiring\S+ \(\S+\) has 4 arguments, more than 2`)
+	})
+
+	t.Run("unbuffered channels", func(t *testing.T) {
+		type Event struct{}
+
+		c := New()
+		require.NoError(t, c.Provide(func() chan Event { return make(chan Event) }))
+		require.NoError(t, c.Provide(func() chan A { return make(chan A) }, AllowUnbuffered()))
+		require.NoError(t, c.Provide(func() chan B { return make(chan B, 1) }))
+		assert.NoError(t, LintWiring(dump(t, c)), "channels must be checked once built")
+
+		require.NoError(t, c.Invoke(func(chan Event, chan A, chan B) {}))
+		err := LintWiring(dump(t, c))
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`found 1 problems with the wiring:`,
+			`function "go.uber.org/dig".TestLintWiring\S+ \(\S+\) provides chan dig.Event, `+
+				`an unbuffered channel shared by all of its consumers`)
+	})
+
+	t.Run("constructed once", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Provide(func() A { return A{} }))
+		require.NoError(t, c.Provide(func() string { return "x" }, Group("names")))
+		require.NoError(t, c.Provide(func() string { return "y" }, Group("names")))
+		for _, name := range []string{"first", "second"} {
+			s := c.Scope(name)
+			require.NoError(t, s.Provide(func(A) B { return B{} }))
+			require.NoError(t, s.Invoke(func(B, struct {
+				In
+
+				Names []string `group:"names"`
+			}) {
+			}))
+		}
+
+		assert.NoError(t, LintWiring(dump(t, c)), "constructions must only be checked with ConstructedOnce")
+		err := LintWiring(dump(t, c), ConstructedOnce())
+		require.Error(t, err)
+		assertErrorMatches(t, err,
+			`found 1 problems with the wiring:`,
+			`dig.B was constructed 2 times, expected once`)
+
+		c.ResetConstructionCounts()
+		assert.NoError(t, LintWiring(dump(t, c), ConstructedOnce()))
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		err := LintWiring(strings.NewReader("not json"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read wiring")
+
+		err = LintWiring(strings.NewReader(`{"Version": 42}`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported wiring version 42, expected 1")
+	})
+}